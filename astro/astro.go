@@ -17,8 +17,11 @@
 package astro
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/logger"
@@ -26,6 +29,10 @@ import (
 	"github.com/uber/astro/astro/utils"
 )
 
+// ErrNoModules is returned by Plan and Apply if the configuration doesn't
+// define any modules.
+var ErrNoModules = errors.New("no modules defined; add at least one module under modules: in the configuration")
+
 // Project is a collection of Terraform modules, based on configuration.
 //
 // Modules may be invoked with various parameters, which are either
@@ -41,34 +48,152 @@ import (
 type Project struct {
 	config            *conf.Project
 	sessions          *SessionRepo
-	terraformVersions *tvm.VersionRepo
+	terraformVersions TerraformVersionResolver
+
+	// the default tvm repos of flavors other than Terraform, e.g. OpenTofu;
+	// only set up when terraformVersions isn't injected
+	flavorVersions map[tvm.Flavor]TerraformVersionResolver
+
+	// the versions that couldn't be fetched during the run
+	unavailableVersions unavailableVersions
+
+	// limits concurrent Terraform init by version; see
+	// conf.Project.VersionConcurrency
+	initConcurrency *versionConcurrency
+
+	// why the configured session repo couldn't be used, if sessions is a
+	// temporary one
+	sessionRepoErr error
+
+	// downloadProgress is passed to the default tvm repo; see
+	// WithTerraformDownloadProgress
+	downloadProgress tvm.DownloadProgress
+
+	// skipHookRequirements disables checkHookRequirements; see
+	// WithoutHookRequirements
+	skipHookRequirements bool
+
+	// runID replaces the session ID as the run ID; see WithRunID
+	runID string
+
+	// liveOutput receives the output of Terraform as it runs; see
+	// WithLiveOutput
+	liveOutput *liveOutput
+
+	// logger receives the logs of the project; see WithLogger
+	logger logger.Logger
+
+	// closed when Stop is called
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// TerraformVersionResolver finds the Terraform binary to use for a version.
+// By default, astro uses a tvm.VersionRepo, which downloads binaries to
+// ~/.tvm as they are needed.
+type TerraformVersionResolver interface {
+	// Get returns the path to the Terraform binary for the version.
+	Get(version string) (string, error)
+}
+
+// initVersionRepos sets up the default tvm repos: one for Terraform, and one
+// for each other flavor that the project's modules use. The download mirror
+// only applies to Terraform.
+func (project *Project) initVersionRepos() error {
+	flavors := map[tvm.Flavor]bool{}
+	for _, moduleConf := range project.config.Modules {
+		if flavor := moduleConf.Terraform.TVMFlavor(); flavor != tvm.FlavorTerraform {
+			flavors[flavor] = true
+		}
+	}
+
+	newRepo := func(opts ...tvm.VersionRepoOption) (*tvm.VersionRepo, error) {
+		opts = append(opts, tvm.WithLogger(project.logger))
+		if project.config.TerraformDefaults.Offline {
+			opts = append(opts, tvm.WithOffline())
+		}
+		if project.downloadProgress != nil {
+			opts = append(opts, tvm.WithDownloadProgress(project.downloadProgress))
+		}
+		return tvm.NewVersionRepoForCurrentSystem("", opts...)
+	}
+
+	terraformOpts := []tvm.VersionRepoOption{}
+	if mirror := project.config.TerraformDefaults.DownloadMirror; mirror != "" {
+		terraformOpts = append(terraformOpts, tvm.WithDownloadMirror(mirror))
+	}
+	versionRepo, err := newRepo(terraformOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tvm: %v", err)
+	}
+	project.terraformVersions = versionRepo
+
+	project.flavorVersions = map[tvm.Flavor]TerraformVersionResolver{}
+	for flavor := range flavors {
+		versionRepo, err := newRepo(tvm.WithFlavor(flavor))
+		if err != nil {
+			return fmt.Errorf("failed to initialize tvm for %s: %v", flavor, err)
+		}
+		project.flavorVersions[flavor] = versionRepo
+	}
+
+	return nil
+}
+
+// versionResolver returns the resolver for binaries of the flavor. An
+// injected resolver is used for all flavors.
+func (project *Project) versionResolver(flavor tvm.Flavor) TerraformVersionResolver {
+	if resolver, ok := project.flavorVersions[flavor]; ok {
+		return resolver
+	}
+	return project.terraformVersions
 }
 
 // NewProject returns a new instance of Project.
 func NewProject(opts ...Option) (*Project, error) {
-	project := &Project{}
-
-	logger.Trace.Println("astro: initializing")
+	project := &Project{
+		stopped: make(chan struct{}),
+		logger:  logger.Default(),
+	}
 
 	if err := project.applyOptions(opts...); err != nil {
 		return nil, err
 	}
 
-	versionRepo, err := tvm.NewVersionRepoForCurrentSystem("")
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize tvm: %v", err)
+	project.logger.Debugf("astro: initializing")
+
+	if !project.skipHookRequirements {
+		if err := checkHookRequirements(project.config); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, warning := range project.config.TerraformExitCodes.Warnings() {
+		project.logger.Warningf("%s", warning)
+	}
+
+	project.initConcurrency = newVersionConcurrency(project.config.VersionConcurrencyLimits(), project.logger)
+
+	if project.terraformVersions == nil {
+		if err := project.initVersionRepos(); err != nil {
+			return nil, err
+		}
 	}
-	project.terraformVersions = versionRepo
 
 	sessionRepoPath := filepath.Join(project.config.SessionRepoDir, ".astro")
 	sessions, err := NewSessionRepo(project, sessionRepoPath, utils.ULIDString)
+	if err != nil && !project.config.RequireSessionRepo {
+		project.logger.Debugf("astro: falling back to a temporary session repository: %v", err)
+		project.sessionRepoErr = err
+		sessions, err = newTemporarySessionRepo(project, utils.ULIDString)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize session repository: %v", err)
 	}
 	project.sessions = sessions
 
 	// check dependency graph is all good
-	if _, err := project.executions(NoExecutionParameters()).graph(); err != nil {
+	if _, err := project.executions(NoExecutionParameters()).graph(project.config.Modules, project.logger); err != nil {
 		return nil, err
 	}
 
@@ -80,8 +205,8 @@ func NewProject(opts ...Option) (*Project, error) {
 	if err != nil {
 		return nil, err
 	}
-	for _, hook := range project.config.Hooks.Startup {
-		if err := runCommandkAndSetEnvironment(session.path, hook); err != nil {
+	for i, hook := range project.config.Hooks.Startup {
+		if err := session.runHook(hook, fmt.Sprintf("startup-hook-%d", i)); err != nil {
 			return nil, fmt.Errorf("error running Startup hook: %v", err)
 		}
 	}
@@ -89,23 +214,74 @@ func NewProject(opts ...Option) (*Project, error) {
 	return project, nil
 }
 
+// TemporarySessionRepo returns the path to the temporary session repo used
+// when the configured one isn't writable, and the reason it isn't. The path
+// is empty if the configured session repo is used.
+func (c *Project) TemporarySessionRepo() (string, error) {
+	if !c.sessions.Temporary() {
+		return "", nil
+	}
+	return c.sessions.Path(), c.sessionRepoErr
+}
+
+// RunID returns the ID of the run, which is the ID of the current session
+// unless another one was set with WithRunID. It is empty if no session has
+// been started.
+func (c *Project) RunID() string {
+	if c.runID != "" {
+		return c.runID
+	}
+	if c.sessions.current == nil {
+		return ""
+	}
+	return c.sessions.current.RunID()
+}
+
+// SessionID returns the ID of the current session, or an empty string if no
+// session has been started.
+func (c *Project) SessionID() string {
+	if c.sessions.current == nil {
+		return ""
+	}
+	return c.sessions.current.ID()
+}
+
+// Stop gracefully stops any plan or apply that is in progress. No new
+// executions are started, but executions that are already running are
+// allowed to finish. It is safe to call Stop more than once.
+func (c *Project) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopped)
+	})
+}
+
 // executions returns a set of executions for modules registered in this
 // project.
 func (c *Project) executions(parameters ExecutionParameters) executionSet {
 	results := executionSet{}
 	for _, m := range c.modules(parameters.ModuleNames) {
-		results = append(results, m.executions(parameters)...)
+		results = append(results, m.executions(parameters, c.logger)...)
 	}
 	return results
 }
 
+// ModuleExecutionCounts returns the number of executions of each module that
+// a plan or apply with the specified parameters would run.
+func (c *Project) ModuleExecutionCounts(parameters ExecutionParameters) map[string]int {
+	counts := map[string]int{}
+	for _, e := range c.executions(parameters) {
+		counts[e.ModuleConfig().Name]++
+	}
+	return counts
+}
+
 // modules creates a list of modules based on the config.
 func (c *Project) modules(moduleNames []string) []*module {
 	results := []*module{}
 	for _, moduleConfig := range c.config.Modules {
 		// skip, if we're filtering and this module doesn't match the filter
 		if moduleNames != nil && !utils.StringSliceContains(moduleNames, moduleConfig.Name) {
-			logger.Trace.Printf("astro: ignoring module %v as it does not match filter", moduleConfig.Name)
+			c.logger.Debugf("astro: ignoring module %v as it does not match filter", moduleConfig.Name)
 			continue
 		}
 		results = append(results, newModule(moduleConfig))
@@ -116,13 +292,26 @@ func (c *Project) modules(moduleNames []string) []*module {
 // Plan does a Terraform plan for every possible execution, in
 // parallel, ignoring dependencies.
 func (c *Project) Plan(parameters PlanExecutionParameters) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro: running Plan")
+	c.logger.Debugf("astro: running Plan")
+
+	if len(c.config.Modules) == 0 {
+		return nil, nil, ErrNoModules
+	}
+
+	if parameters.Detach && c.config.ReadOnly {
+		return nil, nil, errors.New("detach is not allowed in read-only mode")
+	}
 
 	// Binds user vars
-	boundExecutions, err := c.executions(parameters.ExecutionParameters).bindAll(parameters.UserVars.Values)
+	boundExecutions, err := c.executions(parameters.ExecutionParameters).bindAll(parameters.UserVars)
 	if err != nil {
 		return nil, nil, err
 	}
+	if parameters.StrictBinding {
+		if err := checkStrictBinding(boundExecutions); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	// Get session
 	session, err := c.sessions.Current()
@@ -130,7 +319,23 @@ func (c *Project) Plan(parameters PlanExecutionParameters) (<-chan string, <-cha
 		return nil, nil, err
 	}
 
-	return session.plan(boundExecutions, parameters.Detach)
+	status, results, err := session.plan(boundExecutions, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	results = session.recordDurations("plan", results)
+	results = session.recordVariables("plan", results)
+	if c.config.ChangesPolicy.IsSet() {
+		results = applyChangesPolicy(c.config.ChangesPolicy, results, c.logger)
+	}
+	if hasChangeBudgets(c.config) {
+		results = checkChangeBudgets(c.config, results)
+	}
+	if !parameters.OrderedStatus {
+		return status, results, nil
+	}
+	status, results = orderStatus(status, results)
+	return status, results, nil
 }
 
 // Apply does a Terraform apply for every possible execution,
@@ -138,13 +343,29 @@ func (c *Project) Plan(parameters PlanExecutionParameters) (<-chan string, <-cha
 // error if it is unable to start, e.g. due to a missing required
 // variable.
 func (c *Project) Apply(parameters ApplyExecutionParameters) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro: running Apply")
+	c.logger.Debugf("astro: running Apply")
+
+	if len(c.config.Modules) == 0 {
+		return nil, nil, ErrNoModules
+	}
+
+	if c.config.ReadOnly {
+		return nil, nil, errors.New("apply is not allowed in read-only mode")
+	}
 
 	// Bind user vars
-	boundExecutions, err := c.executions(parameters.ExecutionParameters).bindAll(parameters.UserVars.Values)
+	boundExecutions, err := c.executions(parameters.ExecutionParameters).bindAll(parameters.UserVars)
 	if err != nil {
 		return nil, nil, err
 	}
+	if parameters.StrictBinding {
+		if err := checkStrictBinding(boundExecutions); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := checkDuplicateStates(boundExecutions); err != nil {
+		return nil, nil, err
+	}
 
 	// Get session
 	session, err := c.sessions.Current()
@@ -152,12 +373,270 @@ func (c *Project) Apply(parameters ApplyExecutionParameters) (<-chan string, <-c
 		return nil, nil, err
 	}
 
-	var applyFn func([]*boundExecution) (<-chan string, <-chan *Result, error)
+	var applyFn func([]*boundExecution, ApplyExecutionParameters) (<-chan string, <-chan *Result, error)
 	if parameters.ModuleNames != nil {
 		applyFn = session.apply
 	} else {
 		applyFn = session.applyWithGraph
 	}
 
-	return applyFn(boundExecutions)
+	status, results, err := applyFn(boundExecutions, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	results = session.recordDurations("apply", results)
+	results = session.recordVariables("apply", results)
+	results = session.recordVerifications(results)
+	results = session.recordApplies(results)
+	results = session.clearReplacements(results)
+	if !parameters.OrderedStatus {
+		return status, results, nil
+	}
+	status, results = orderStatus(status, results)
+	return status, results, nil
+}
+
+// Refresh updates the state of every possible execution from the real
+// infrastructure, in parallel, without changing the infrastructure. It
+// returns an error if it is unable to start, e.g. due to a missing required
+// variable.
+func (c *Project) Refresh(parameters RefreshExecutionParameters) (<-chan string, <-chan *Result, error) {
+	c.logger.Debugf("astro: running Refresh")
+
+	if len(c.config.Modules) == 0 {
+		return nil, nil, ErrNoModules
+	}
+
+	if c.config.ReadOnly {
+		return nil, nil, errors.New("refresh is not allowed in read-only mode")
+	}
+
+	// Bind user vars
+	boundExecutions, err := c.executions(parameters.ExecutionParameters).bindAll(parameters.UserVars)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parameters.StrictBinding {
+		if err := checkStrictBinding(boundExecutions); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := checkDuplicateStates(boundExecutions); err != nil {
+		return nil, nil, err
+	}
+
+	// Get session
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, results, err := session.refresh(boundExecutions, parameters.SkipStateMigration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !parameters.OrderedStatus {
+		return status, results, nil
+	}
+	status, results = orderStatus(status, results)
+	return status, results, nil
+}
+
+// Import imports an existing resource into the state of a single execution
+// of a module, and then plans it so that the caller can see whether the
+// configuration matches the imported resource. It returns an error if the
+// user variables don't narrow the module down to exactly one execution.
+func (c *Project) Import(parameters ImportExecutionParameters) (<-chan string, <-chan *Result, error) {
+	c.logger.Debugf("astro: running Import")
+
+	if len(c.config.Modules) == 0 {
+		return nil, nil, ErrNoModules
+	}
+
+	if c.config.ReadOnly {
+		return nil, nil, errors.New("import is not allowed in read-only mode")
+	}
+
+	b, err := c.singleExecution("import", parameters.Module, parameters.ExecutionParameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get session
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, results, err := session.importResource(b, parameters.Address, parameters.ID, parameters.SkipStateMigration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !parameters.OrderedStatus {
+		return status, results, nil
+	}
+	status, results = orderStatus(status, results)
+	return status, results, nil
+}
+
+// State runs `terraform state` with the arguments in parameters.Args, e.g.
+// ["mv", "aws_instance.a", "aws_instance.b"], on the state of a single
+// execution of a module. It returns an error if the user variables don't
+// narrow the module down to exactly one execution.
+func (c *Project) State(parameters StateExecutionParameters) (<-chan string, <-chan *Result, error) {
+	c.logger.Debugf("astro: running State")
+
+	if len(c.config.Modules) == 0 {
+		return nil, nil, ErrNoModules
+	}
+
+	if len(parameters.Args) == 0 {
+		return nil, nil, errors.New("missing state subcommand")
+	}
+
+	b, err := c.singleExecution("state", parameters.Module, parameters.ExecutionParameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get session
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, results, err := session.state(b, parameters.Args, parameters.SkipStateMigration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !parameters.OrderedStatus {
+		return status, results, nil
+	}
+	status, results = orderStatus(status, results)
+	return status, results, nil
+}
+
+// Taint marks a resource of a single execution of a module for
+// replacement on its next apply. Terraform 0.15.2 and later deprecate
+// `terraform taint`, so with these versions the resource is recorded in the
+// session repo instead, and the next plan and apply of the execution pass
+// -replace=<address>. It returns an error if the user variables don't
+// narrow the module down to exactly one execution.
+func (c *Project) Taint(parameters TaintExecutionParameters) (<-chan string, <-chan *Result, error) {
+	c.logger.Debugf("astro: running Taint")
+	return c.taint("taint", parameters)
+}
+
+// Untaint reverses Taint.
+func (c *Project) Untaint(parameters TaintExecutionParameters) (<-chan string, <-chan *Result, error) {
+	c.logger.Debugf("astro: running Untaint")
+	return c.taint("untaint", parameters)
+}
+
+func (c *Project) taint(command string, parameters TaintExecutionParameters) (<-chan string, <-chan *Result, error) {
+	if len(c.config.Modules) == 0 {
+		return nil, nil, ErrNoModules
+	}
+
+	if c.config.ReadOnly {
+		return nil, nil, fmt.Errorf("%s is not allowed in read-only mode", command)
+	}
+
+	b, err := c.singleExecution(command, parameters.Module, parameters.ExecutionParameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get session
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, results, err := session.taint(b, command, parameters.Address, parameters.SkipStateMigration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !parameters.OrderedStatus {
+		return status, results, nil
+	}
+	status, results = orderStatus(status, results)
+	return status, results, nil
+}
+
+// Unlock removes the state lock with the ID from the state of a single
+// execution of a module, e.g. a lock left behind by an apply that was
+// killed. It returns an error if the user variables don't narrow the module
+// down to exactly one execution.
+func (c *Project) Unlock(parameters UnlockExecutionParameters) (<-chan string, <-chan *Result, error) {
+	c.logger.Debugf("astro: running Unlock")
+
+	if len(c.config.Modules) == 0 {
+		return nil, nil, ErrNoModules
+	}
+
+	if c.config.ReadOnly {
+		return nil, nil, errors.New("unlock is not allowed in read-only mode")
+	}
+
+	b, err := c.singleExecution("unlock", parameters.Module, parameters.ExecutionParameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get session
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, results, err := session.unlock(b, parameters.LockID, parameters.SkipStateMigration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !parameters.OrderedStatus {
+		return status, results, nil
+	}
+	status, results = orderStatus(status, results)
+	return status, results, nil
+}
+
+// StateLocation returns the ID of the execution that State would run on,
+// and the fields that identify where its state is stored, e.g.
+// ["backend=s3", "bucket=states", "key=app"]. The fields are nil if the
+// state is only kept in the execution's sandbox.
+func (c *Project) StateLocation(parameters StateExecutionParameters) (string, []string, error) {
+	b, err := c.singleExecution("state", parameters.Module, parameters.ExecutionParameters)
+	if err != nil {
+		return "", nil, err
+	}
+	return b.ID(), b.stateIdentity(), nil
+}
+
+// singleExecution returns the only execution of the module that matches the
+// user variables, for commands that operate on the state of one execution.
+// It returns an error naming the command if there isn't exactly one.
+func (c *Project) singleExecution(command string, module string, parameters ExecutionParameters) (*boundExecution, error) {
+	if len(c.modules([]string{module})) == 0 {
+		return nil, fmt.Errorf("unknown module: %q", module)
+	}
+	parameters.ModuleNames = []string{module}
+
+	// Bind user vars
+	boundExecutions, err := c.executions(parameters).bindAll(parameters.UserVars)
+	if err != nil {
+		return nil, err
+	}
+	switch len(boundExecutions) {
+	case 0:
+		return nil, fmt.Errorf("no execution of module %q matches the variables", module)
+	case 1:
+		return boundExecutions[0], nil
+	}
+
+	ids := []string{}
+	for _, b := range boundExecutions {
+		ids = append(ids, b.ID())
+	}
+	return nil, fmt.Errorf("%s needs exactly one execution of module %q, but the variables match %d: %s; set more variables to choose one", command, module, len(ids), strings.Join(ids, ", "))
 }
@@ -18,6 +18,9 @@ package astro
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/uber/astro/astro/utils"
@@ -185,6 +188,179 @@ func TestApplySuccess(t *testing.T) {
 	}, testResultErrs(testReadResults(resultChan)))
 }
 
+func TestRefreshModules(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/foosite.yaml")
+	require.NoError(t, err)
+
+	status, resultChan, err := c.Refresh(RefreshExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users", "mgmt"},
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// only the selected modules are refreshed
+	assert.Equal(t, map[string]error{
+		"mgmt-east1": nil,
+		"users":      nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	messages := []string{}
+	for len(status) > 0 {
+		messages = append(messages, <-status)
+	}
+	assert.Contains(t, messages, "[users] Refreshing...")
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/foosite.yaml")
+	require.NoError(t, err)
+
+	status, resultChan, err := c.Import(ImportExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region":  "east1",
+					"environment": "dev",
+				},
+			},
+		},
+		Module:  "app",
+		Address: "aws_instance.web",
+		ID:      "i-0123456789",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app-east1-dev": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	messages := []string{}
+	for len(status) > 0 {
+		messages = append(messages, <-status)
+	}
+	assert.Contains(t, messages, "[app-east1-dev] Importing aws_instance.web...")
+	assert.Contains(t, messages, "[app-east1-dev] Planning...")
+}
+
+func TestImportNeedsOneExecution(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/foosite.yaml")
+	require.NoError(t, err)
+
+	_, _, err = c.Import(ImportExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+		Module:  "app",
+		Address: "aws_instance.web",
+		ID:      "i-0123456789",
+	})
+	assert.EqualError(t, err, `import needs exactly one execution of module "app", but the variables match 3: app-east1-dev, app-east1-staging, app-east1-prod; set more variables to choose one`)
+
+	_, _, err = c.Import(ImportExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		Module:              "nonexistent",
+	})
+	assert.EqualError(t, err, `unknown module: "nonexistent"`)
+
+	_, _, err = c.Taint(TaintExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+		Module:  "app",
+		Address: "aws_instance.web",
+	})
+	assert.EqualError(t, err, `taint needs exactly one execution of module "app", but the variables match 3: app-east1-dev, app-east1-staging, app-east1-prod; set more variables to choose one`)
+}
+
+func TestState(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/foosite.yaml")
+	require.NoError(t, err)
+
+	parameters := StateExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region":  "east1",
+					"environment": "dev",
+				},
+			},
+		},
+		Module: "app",
+		Args:   []string{"list"},
+	}
+
+	id, location, err := c.StateLocation(parameters)
+	require.NoError(t, err)
+	assert.Equal(t, "app-east1-dev", id)
+	assert.Equal(t, []string{"backend=s3", "bucket=mybucket", "key=east1/app/dev.tfstate", "region=us-east-1"}, location)
+
+	status, resultChan, err := c.State(parameters)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app-east1-dev": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	messages := []string{}
+	for len(status) > 0 {
+		messages = append(messages, <-status)
+	}
+	assert.Contains(t, messages, "[app-east1-dev] Running state list...")
+}
+
+func TestUnlock(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/foosite.yaml")
+	require.NoError(t, err)
+
+	status, resultChan, err := c.Unlock(UnlockExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region":  "east1",
+					"environment": "dev",
+				},
+			},
+		},
+		Module: "app",
+		LockID: "4a0d7c3e",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app-east1-dev": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	messages := []string{}
+	for len(status) > 0 {
+		messages = append(messages, <-status)
+	}
+	assert.Contains(t, messages, "[app-east1-dev] Unlocking state lock 4a0d7c3e...")
+}
+
 func TestApplyFailModule(t *testing.T) {
 	t.Parallel()
 
@@ -202,17 +378,15 @@ func TestApplyFailModule(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	results := testResultErrs(testReadResults(resultChan))
-
-	executionsRan := []string{}
-	for id := range results {
-		executionsRan = append(executionsRan, id)
-	}
+	allResults := testReadResults(resultChan)
+	results := testResultErrs(allResults)
 
 	// users module should have failed
 	assert.Error(t, results["users"])
+	assert.False(t, allResults["users"].Skipped())
 
-	// check that the following modules were skipped
+	// check that the following modules were skipped, and have a result
+	// that says so
 	for _, id := range []string{
 		"app-east1-dev",
 		"app-east1-prod",
@@ -221,8 +395,10 @@ func TestApplyFailModule(t *testing.T) {
 		"database-east1-prod",
 		"database-east1-staging",
 	} {
-		if utils.StringSliceContains(executionsRan, id) {
-			assert.Fail(t, fmt.Sprintf("%s was not skipped", id))
+		if assert.Contains(t, allResults, id) {
+			assert.True(t, allResults[id].Skipped(), "%s was not skipped", id)
+			assert.EqualError(t, results[id], "skipped: dependency users failed", id)
+			assert.Nil(t, allResults[id].TerraformResult(), id)
 		}
 	}
 
@@ -235,6 +411,7 @@ func TestApplyFailModule(t *testing.T) {
 		"mgmt-east1",
 	} {
 		assert.NoError(t, results[id])
+		assert.False(t, allResults[id].Skipped(), id)
 	}
 }
 
@@ -267,3 +444,277 @@ func TestPassVariables(t *testing.T) {
 	assert.Contains(t, results["bar-east1"].TerraformResult().Stderr(), "-var region=east1")
 	assert.NotContains(t, results["foo"].TerraformResult().Stderr(), "-var")
 }
+
+func TestPlanStateMigration(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	state := `{"version": 4, "resources": [{"type": "null_resource", "name": "app"}]}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "old-app.tfstate"), []byte(state), 0644))
+
+	testConfigFilePath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(testConfigFilePath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    remote:
+      backend_config:
+        path: %s/new-{{.environment}}.tfstate
+    state_migration:
+      backend_config:
+        path: %s/old-app.tfstate
+    variables:
+      - name: environment
+        values: [dev]
+`, codeRoot, absolutePath("terraform/fixtures/mock-terraform/state-migration"), tmpdir, tmpdir)), 0644))
+
+	plan := func(parameters PlanExecutionParameters) []string {
+		c, err := NewProjectFromConfigFile(testConfigFilePath)
+		require.NoError(t, err)
+
+		status, resultChan, err := c.Plan(parameters)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]error{
+			"app-dev": nil,
+		}, testResultErrs(testReadResults(resultChan)))
+
+		messages := []string{}
+		for len(status) > 0 {
+			messages = append(messages, <-status)
+		}
+		return messages
+	}
+
+	skip := NoPlanExecutionParameters()
+	skip.SkipStateMigration = true
+	assert.Contains(t, plan(skip), "[app-dev] Skipping state migration")
+	assert.False(t, utils.FileExists(filepath.Join(tmpdir, "new-dev.tfstate")))
+
+	assert.Contains(t, plan(NoPlanExecutionParameters()), "[app-dev] Migrated state from the previous backend location")
+	b, err := ioutil.ReadFile(filepath.Join(tmpdir, "new-dev.tfstate"))
+	require.NoError(t, err)
+	assert.Equal(t, state, string(b))
+
+	assert.Contains(t, plan(NoPlanExecutionParameters()), "[app-dev] WARNING: state has already been migrated; remove state_migration from the module configuration")
+}
+
+func TestReadOnly(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	state := `{"version": 4, "resources": [{"type": "null_resource", "name": "app"}]}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "old-app.tfstate"), []byte(state), 0644))
+
+	testConfigFilePath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(testConfigFilePath, []byte(fmt.Sprintf(`
+read_only: true
+terraform_code_root: %s
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    remote:
+      backend_config:
+        path: %s/new-app.tfstate
+    state_migration:
+      backend_config:
+        path: %s/old-app.tfstate
+`, codeRoot, absolutePath("terraform/fixtures/mock-terraform/state-migration"), tmpdir, tmpdir)), 0644))
+
+	c, err := NewProjectFromConfigFile(testConfigFilePath)
+	require.NoError(t, err)
+
+	_, _, err = c.Apply(ApplyExecutionParameters{ExecutionParameters: NoExecutionParameters()})
+	assert.EqualError(t, err, "apply is not allowed in read-only mode")
+
+	_, _, err = c.Refresh(RefreshExecutionParameters{ExecutionParameters: NoExecutionParameters()})
+	assert.EqualError(t, err, "refresh is not allowed in read-only mode")
+
+	_, _, err = c.Import(ImportExecutionParameters{ExecutionParameters: NoExecutionParameters(), Module: "app"})
+	assert.EqualError(t, err, "import is not allowed in read-only mode")
+
+	detach := NoPlanExecutionParameters()
+	detach.Detach = true
+	_, _, err = c.Plan(detach)
+	assert.EqualError(t, err, "detach is not allowed in read-only mode")
+
+	status, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{
+		"app": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	messages := []string{}
+	for len(status) > 0 {
+		messages = append(messages, <-status)
+	}
+	assert.Contains(t, messages, "[app] WARNING: skipping state migration in read-only mode")
+	assert.False(t, utils.FileExists(filepath.Join(tmpdir, "new-app.tfstate")))
+}
+
+func TestLocalState(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, "remote"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "remote", "main.tf"), []byte(`terraform {
+  backend "s3" {}
+}
+`), 0644))
+
+	testConfigFilePath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(testConfigFilePath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+terraform:
+  path: %s
+modules:
+  - name: ephemeral
+    path: .
+    local_state: ephemeral
+  - name: persist
+    path: .
+    local_state: persist:state/{{.environment}}.tfstate
+    variables:
+      - name: environment
+        values: [dev]
+  - name: remote
+    path: remote
+  - name: undeclared
+    path: .
+`, codeRoot, absolutePath("fixtures/mock-terraform/local-state"))), 0644))
+
+	readStatus := func(status <-chan string) []string {
+		messages := []string{}
+		for len(status) > 0 {
+			messages = append(messages, <-status)
+		}
+		return messages
+	}
+
+	c, err := NewProjectFromConfigFile(testConfigFilePath)
+	require.NoError(t, err)
+
+	status, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{
+		"ephemeral":   nil,
+		"persist-dev": nil,
+		"remote":      nil,
+		"undeclared":  nil,
+	}, testResultErrs(testReadResults(resultChan)))
+	messages := readStatus(status)
+	assert.Contains(t, messages, "[ephemeral] WARNING: local_state is ephemeral; the module's state only exists in this session and will be lost")
+	assert.Contains(t, messages, "[undeclared] WARNING: module has no backend and no local_state; apply will be refused")
+
+	apply := func() map[string]error {
+		c, err := NewProjectFromConfigFile(testConfigFilePath)
+		require.NoError(t, err)
+
+		status, resultChan, err := c.Apply(ApplyExecutionParameters{ExecutionParameters: NoExecutionParameters()})
+		require.NoError(t, err)
+		errs := testResultErrs(testReadResults(resultChan))
+		readStatus(status)
+		return errs
+	}
+
+	errs := apply()
+	assert.NoError(t, errs["ephemeral"])
+	assert.NoError(t, errs["persist-dev"])
+	assert.NoError(t, errs["remote"])
+	if assert.Error(t, errs["undeclared"]) {
+		assert.Contains(t, errs["undeclared"].Error(), "module has no backend")
+	}
+
+	statePath := filepath.Join(tmpdir, "state", "dev.tfstate")
+	b, err := ioutil.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", string(b))
+
+	// the persisted state is restored before the next apply
+	apply()
+	b, err = ioutil.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", string(b))
+	b, err = ioutil.ReadFile(statePath + ".backup")
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", string(b))
+}
+
+func TestSensitiveVariablesAreNotLogged(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	testConfigFilePath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(testConfigFilePath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    remote:
+      backend_config:
+        key: "app-{{.environment}}-{{.token}}.tfstate"
+    variables:
+      - name: environment
+        values: [dev]
+      - name: token
+        sensitive: true
+`, codeRoot, absolutePath("fixtures/mock-terraform/sensitive"))), 0644))
+
+	c, err := NewProjectFromConfigFile(testConfigFilePath)
+	require.NoError(t, err)
+
+	parameters := NoPlanExecutionParameters()
+	parameters.UserVars = &UserVariables{
+		Values: map[string]string{"token": "s3cr3t-t0ken"},
+	}
+
+	status, resultChan, err := c.Plan(parameters)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{
+		"app-dev": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	for len(status) > 0 {
+		assert.NotContains(t, <-status, "s3cr3t-t0ken")
+	}
+
+	logs := 0
+	err = filepath.Walk(filepath.Join(tmpdir, ".astro"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		assert.NotContains(t, path, "s3cr3t-t0ken")
+		if info.Mode().IsRegular() && filepath.Ext(path) == ".log" {
+			logs++
+			b, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			assert.NotContains(t, string(b), "s3cr3t-t0ken", path)
+			if name := filepath.Base(path); name == "init.log" || name == "plan.log" {
+				assert.Contains(t, string(b), "<sensitive>", path)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, logs)
+}
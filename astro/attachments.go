@@ -0,0 +1,145 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+)
+
+// attachmentsDir is the directory in a session that attached files are
+// copied to.
+const attachmentsDir = "attachments"
+
+// currentUsername returns the name of the user running astro, for recording
+// who attached a file.
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// Attach copies the file at source into the attachments directory of the
+// session and records it in the session manifest. If execution is set, the
+// file is attached to that execution of the session, and is copied to a
+// subdirectory named after it. Files larger than the configured
+// max_attachment_size are rejected, and so are files with the same name as
+// an existing attachment.
+func (s *Session) Attach(source, execution, label string) (*Attachment, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%v is not a regular file", source)
+	}
+
+	maxSize := s.repo.project.config.MaxAttachmentSize
+	if maxSize == 0 {
+		maxSize = conf.DefaultMaxAttachmentSize
+	}
+	if info.Size() > maxSize {
+		return nil, fmt.Errorf("%v is %d bytes, larger than the maximum attachment size of %d bytes; see max_attachment_size", source, info.Size(), maxSize)
+	}
+
+	relDir := attachmentsDir
+	if execution != "" {
+		if _, err := s.ExecutionPaths(execution); err != nil {
+			return nil, err
+		}
+		relDir = filepath.Join(attachmentsDir, execution)
+	}
+	relPath := filepath.Join(relDir, filepath.Base(source))
+
+	if err := os.MkdirAll(filepath.Join(s.path, relDir), s.repo.dirMode); err != nil {
+		return nil, err
+	}
+
+	attachment := &Attachment{
+		Path:       relPath,
+		Source:     source,
+		Execution:  execution,
+		Label:      label,
+		AttachedBy: currentUsername(),
+		AttachedAt: time.Now().UTC(),
+		Size:       info.Size(),
+	}
+
+	dst := filepath.Join(s.path, relPath)
+	if err := copyAttachment(source, dst, s.repo.fileMode); err != nil {
+		return nil, err
+	}
+
+	if err := s.updateManifest(func(manifest *Manifest) error {
+		manifest.Attachments = append(manifest.Attachments, *attachment)
+		return nil
+	}); err != nil {
+		os.Remove(dst)
+		if _, ok := err.(*ManifestVersionError); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to update manifest of session %v: %v", s.id, err)
+	}
+
+	return attachment, nil
+}
+
+// copyAttachment copies the file at src to dst, which must not exist.
+func copyAttachment(src, dst string, fileMode os.FileMode) error {
+	if fileMode == 0 {
+		fileMode = 0666
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fileMode)
+	if os.IsExist(err) {
+		return fmt.Errorf("%v is already attached to the session", filepath.Base(dst))
+	} else if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/uber/astro/astro/conf"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// Sources of the values of bound variables.
+const (
+	// ValueFromFlag is a value provided by the user with a flag.
+	ValueFromFlag = "flag"
+	// ValueFromEnv is a value provided by the user with a flag in
+	// ASTRO_FLAGS.
+	ValueFromEnv = "env"
+	// ValueFromDefault is the default value of the variable.
+	ValueFromDefault = "default"
+	// ValueFromValues is one of the predefined values of the variable.
+	ValueFromValues = "values"
+)
+
+// userSource returns where the value the user provided for the variable came
+// from. Values without a source are from the flag named after the variable.
+func userSource(name string, sources map[string]VariableSource) VariableSource {
+	if source, ok := sources[name]; ok {
+		return source
+	}
+	return VariableSource{Kind: ValueFromFlag, Name: "--" + name}
+}
+
+// configSource returns the source of a value of the variable from the module
+// configuration: its default, predefined values or values command.
+func configSource(moduleConf conf.Module, name, kind string) VariableSource {
+	field := "default"
+	if kind == ValueFromValues {
+		field = "values"
+		for _, variable := range moduleConf.Variables {
+			if variable.Name == name && variable.ValuesCommand != "" {
+				field = "values_command"
+			}
+		}
+	}
+	return VariableSource{Kind: kind, Name: fmt.Sprintf("variables.%s.%s", name, field)}
+}
+
+// templatedFields returns the values in the module configuration that are
+// bound as templates, by their path in the configuration.
+func templatedFields(moduleConf conf.Module) map[string]string {
+	fields := map[string]string{}
+	for key, value := range moduleConf.Remote.BackendConfig {
+		fields["remote.backend_config."+key] = value
+	}
+	for name, value := range moduleConf.Env {
+		fields["env."+name] = value
+	}
+	for i, parameter := range moduleConf.AllTerraformParameters() {
+		fields[fmt.Sprintf("terraform_parameters[%d]", i)] = parameter
+	}
+	if moduleConf.LocalState != "" {
+		fields["local_state"] = moduleConf.LocalState
+	}
+	if moduleConf.StateMigration != nil {
+		for key, value := range moduleConf.StateMigration.BackendConfig {
+			fields["state_migration.backend_config."+key] = value
+		}
+	}
+	return fields
+}
+
+// unresolvedReferences returns the template references in the module
+// configuration to variables that the execution has no value for. Templates
+// replace them with "<no value>" rather than failing, so they would
+// otherwise go unnoticed.
+func unresolvedReferences(moduleConf conf.Module, variables map[string]string) []string {
+	unresolved := []string{}
+	for path, value := range templatedFields(moduleConf) {
+		for _, name := range conf.TemplateFieldNames(value) {
+			if _, ok := variables[name]; !ok {
+				unresolved = append(unresolved, fmt.Sprintf("%s references {{.%s}}, which has no value", path, name))
+			}
+		}
+	}
+	sort.Strings(unresolved)
+	return unresolved
+}
+
+// variableProvenance returns the value of each variable of the execution,
+// with sensitive values masked, and where it came from, sorted by name.
+func (b *boundExecution) variableProvenance() []VariableProvenance {
+	sensitive := map[string]bool{}
+	for _, variable := range b.ModuleConfig().Variables {
+		sensitive[variable.Name] = variable.Sensitive
+	}
+
+	names := []string{}
+	for name := range b.provenance {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	provenance := []VariableProvenance{}
+	for _, name := range names {
+		value := b.variables[name]
+		if sensitive[name] {
+			value = "<sensitive>"
+		}
+		provenance = append(provenance, VariableProvenance{
+			Name:       name,
+			Value:      value,
+			Source:     b.provenance[name].Kind,
+			SourceName: b.provenance[name].Name,
+		})
+	}
+	return provenance
+}
+
+// bindingStatus returns status messages with a table of the variable values
+// of the execution and where they came from, and warnings about unresolved
+// references.
+func (b *boundExecution) bindingStatus() []string {
+	messages := []string{}
+	if provenance := b.variableProvenance(); len(provenance) > 0 {
+		var table bytes.Buffer
+		w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+		for _, variable := range provenance {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", variable.Name, variable.Value, variable.Source, variable.SourceName)
+		}
+		w.Flush()
+
+		lines := []string{fmt.Sprintf("[%s] Variables:", b.ID())}
+		for _, row := range strings.Split(strings.TrimRight(table.String(), "\n"), "\n") {
+			lines = append(lines, fmt.Sprintf("[%s]   %s", b.ID(), strings.TrimRight(row, " ")))
+		}
+		messages = append(messages, strings.Join(lines, "\n"))
+	}
+	for _, reference := range b.unresolved {
+		messages = append(messages, fmt.Sprintf("[%s] WARNING: %s", b.ID(), reference))
+	}
+	return messages
+}
+
+// recordVariables passes results through, and records where the values of
+// the variables of the executions came from in the session manifest.
+func (s *Session) recordVariables(command string, results <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(results))
+
+	go func() {
+		defer close(out)
+
+		variables := []ExecutionVariables{}
+		for result := range results {
+			if provenance := result.Provenance(); len(provenance) > 0 && !result.Skipped() {
+				variables = append(variables, ExecutionVariables{
+					ID:        result.ID(),
+					Module:    result.Module(),
+					Command:   command,
+					Variables: provenance,
+				})
+			}
+			out <- result
+		}
+
+		if len(variables) == 0 {
+			return
+		}
+		err := s.updateManifest(func(manifest *Manifest) error {
+			manifest.Variables = append(manifest.Variables, variables...)
+			return nil
+		})
+		if err != nil {
+			s.log().Debugf("astro: unable to record variables in session %v: %v", s.id, err)
+		}
+	}()
+
+	return out
+}
+
+// checkStrictBinding returns an error for every reference in the
+// configuration of the executions that isn't bound to a value.
+func checkStrictBinding(executions []*boundExecution) (errs error) {
+	for _, b := range executions {
+		for _, reference := range b.unresolved {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %s", b.ID(), reference))
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("strict binding: %v", errs)
+	}
+	return nil
+}
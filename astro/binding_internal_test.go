@@ -0,0 +1,89 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+)
+
+func TestBindingProvenance(t *testing.T) {
+	t.Parallel()
+
+	moduleConf := conf.Module{
+		Name: "app",
+		Path: "app",
+		Remote: conf.Remote{
+			BackendConfig: map[string]string{
+				"key": "{{.region}}/{{.environment}}/{{.account}}.tfstate",
+			},
+		},
+		Variables: []conf.Variable{
+			{Name: "environment", Values: []string{"dev", "prod"}, Default: "dev"},
+			{Name: "region"},
+			{Name: "owner", Default: "infra"},
+			{Name: "token", Sensitive: true},
+			{Name: "zone", Values: []string{"a", "b"}},
+			{Name: "team", Default: "infra"},
+		},
+	}
+
+	executions := newModule(moduleConf).executions(NoExecutionParameters(), logger.Discard)
+	require.Len(t, executions, 2)
+
+	b, err := executions[0].(*unboundExecution).bind(map[string]string{"region": "us-east-1", "token": "secret", "team": "payments"}, map[string]VariableSource{
+		"region": {Kind: ValueFromFlag, Name: "--aws-region"},
+		"team":   {Kind: ValueFromEnv, Name: "ASTRO_FLAGS (--team)"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]VariableSource{
+		"environment": {Kind: ValueFromDefault, Name: "variables.environment.default"},
+		"owner":       {Kind: ValueFromDefault, Name: "variables.owner.default"},
+		"region":      {Kind: ValueFromFlag, Name: "--aws-region"},
+		"team":        {Kind: ValueFromEnv, Name: "ASTRO_FLAGS (--team)"},
+		"token":       {Kind: ValueFromFlag, Name: "--token"},
+		"zone":        {Kind: ValueFromValues, Name: "variables.zone.values"},
+	}, b.provenance)
+
+	// sensitive values are masked
+	provenance := b.variableProvenance()
+	require.Len(t, provenance, 6)
+	assert.Equal(t, VariableProvenance{Name: "token", Value: "<sensitive>", Source: ValueFromFlag, SourceName: "--token"}, provenance[4])
+	assert.Equal(t, provenance, newResult(b, nil, nil).Provenance())
+
+	// account isn't a variable of the module, so the template leaves
+	// "<no value>" in the key
+	assert.Equal(t, "us-east-1/dev/<no value>.tfstate", b.ModuleConfig().Remote.BackendConfig["key"])
+	assert.Equal(t, []string{"" +
+		"[app-dev-infra-us-east-1-payments-a] Variables:\n" +
+		"[app-dev-infra-us-east-1-payments-a]   environment  dev          default  variables.environment.default\n" +
+		"[app-dev-infra-us-east-1-payments-a]   owner        infra        default  variables.owner.default\n" +
+		"[app-dev-infra-us-east-1-payments-a]   region       us-east-1    flag     --aws-region\n" +
+		"[app-dev-infra-us-east-1-payments-a]   team         payments     env      ASTRO_FLAGS (--team)\n" +
+		"[app-dev-infra-us-east-1-payments-a]   token        <sensitive>  flag     --token\n" +
+		"[app-dev-infra-us-east-1-payments-a]   zone         a            values   variables.zone.values",
+		"[app-dev-infra-us-east-1-payments-a] WARNING: remote.backend_config.key references {{.account}}, which has no value",
+	}, b.bindingStatus())
+
+	assert.EqualError(t, checkStrictBinding([]*boundExecution{b}), "strict binding: 1 error occurred:\n\n* app-dev-infra-us-east-1-payments-a: remote.backend_config.key references {{.account}}, which has no value")
+}
@@ -0,0 +1,111 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/terraform"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// hasChangeBudgets returns whether the project or any of its modules has a
+// change budget.
+func hasChangeBudgets(config *conf.Project) bool {
+	if config.ChangeBudget.IsSet() {
+		return true
+	}
+	for _, moduleConf := range config.Modules {
+		if moduleConf.ChangeBudget.IsSet() {
+			return true
+		}
+	}
+	return false
+}
+
+// changeBudgets counts the resources changed by the plans of a run, to
+// check them against the change budgets of the project and its modules.
+type changeBudgets struct {
+	project conf.ChangeBudget
+	modules map[string]conf.ChangeBudget
+
+	total        int
+	moduleTotals map[string]int
+}
+
+// check adds the changes of the result to the totals, and returns an error
+// if they exceed a budget. Once a total is exceeded, every later plan with
+// changes exceeds it too.
+func (b *changeBudgets) check(result *Result) (errs error) {
+	planResult, ok := result.TerraformResult().(*terraform.PlanResult)
+	if !ok || planResult == nil || result.Err() != nil {
+		return nil
+	}
+
+	changes, ok := planResult.ResourceChanges()
+	if !ok {
+		return errors.New("unable to count the resources the plan changes")
+	}
+	if changes.Total() == 0 {
+		return nil
+	}
+
+	module := result.Module()
+	b.total += changes.Total()
+	b.moduleTotals[module] += changes.Total()
+
+	moduleBudget := b.modules[module]
+	if max := moduleBudget.MaxChangesPerExecution; max > 0 && changes.Total() > max {
+		errs = multierror.Append(errs, fmt.Errorf("the plan changes %d resources, more than max_changes_per_execution of %d", changes.Total(), max))
+	}
+	if max := moduleBudget.MaxTotalChanges; max > 0 && b.moduleTotals[module] > max {
+		errs = multierror.Append(errs, fmt.Errorf("the plans of module %v change %d resources so far, more than its max_total_changes of %d", module, b.moduleTotals[module], max))
+	}
+	if max := b.project.MaxTotalChanges; max > 0 && b.total > max {
+		errs = multierror.Append(errs, fmt.Errorf("the plans change %d resources so far, more than max_total_changes of %d", b.total, max))
+	}
+
+	return errs
+}
+
+// checkChangeBudgets returns a channel that delivers the results from
+// results, with their ChangeBudgetErr set if their plan exceeds a change
+// budget. It is closed once all results have been delivered.
+func checkChangeBudgets(config *conf.Project, results <-chan *Result) <-chan *Result {
+	budgets := &changeBudgets{
+		project:      config.ChangeBudget,
+		modules:      map[string]conf.ChangeBudget{},
+		moduleTotals: map[string]int{},
+	}
+	for _, moduleConf := range config.Modules {
+		budgets.modules[moduleConf.Name] = moduleConf.ChangeBudget
+	}
+
+	checked := make(chan *Result)
+	go func() {
+		defer close(checked)
+		for result := range results {
+			result.changeBudgetErr = budgets.check(result)
+			checked <- result
+		}
+	}()
+
+	return checked
+}
@@ -0,0 +1,100 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeBudgets(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(`
+commands:
+  plan:
+    exit_code: 2
+    stdout: "Plan: 3 to add, 1 to change, 2 to destroy.\n"
+`), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+change_budget:
+  max_changes_per_execution: 5
+  max_total_changes: 15
+modules:
+  - name: app
+    path: .
+    change_budget:
+      max_changes_per_execution: 10
+  - name: network
+    path: .
+    variables:
+      - name: region
+        values: [east, west]
+`, codeRoot, tmpdir, terraformPath)), 0644))
+
+	c, err := NewProjectFromConfigFile(configPath)
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	require.Len(t, results, 3)
+
+	totalExceeded := 0
+	for id, result := range results {
+		require.NoError(t, result.Err(), id)
+
+		budgetErr := result.ChangeBudgetErr()
+		if result.Module() == "network" {
+			if assert.Error(t, budgetErr, id) {
+				assert.Contains(t, budgetErr.Error(), "the plan changes 6 resources, more than max_changes_per_execution of 5")
+			}
+		} else if budgetErr != nil {
+			assert.NotContains(t, budgetErr.Error(), "max_changes_per_execution")
+		}
+
+		// only the plan that takes the total from 12 to 18 exceeds it
+		if budgetErr != nil && strings.Contains(budgetErr.Error(), "max_total_changes") {
+			assert.Contains(t, budgetErr.Error(), "the plans change 18 resources so far, more than max_total_changes of 15")
+			totalExceeded++
+		}
+	}
+	assert.Equal(t, 1, totalExceeded)
+}
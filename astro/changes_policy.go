@@ -0,0 +1,56 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// countsChanges returns whether a plan with changes of the kinds has
+// changes under the policy.
+func countsChanges(policy conf.ChangesPolicy, kinds terraform.ChangeKinds) bool {
+	if policy.RequireResourceChanges {
+		return kinds.Resources
+	}
+	return kinds.Resources ||
+		(kinds.Outputs && !policy.IgnoreOutputChanges) ||
+		(kinds.Reads && !policy.IgnoreReadChanges)
+}
+
+// applyChangesPolicy returns a channel that delivers the results from
+// results, with whether their plan has changes adjusted to the changes
+// policy. It is closed once all results have been delivered.
+func applyChangesPolicy(policy conf.ChangesPolicy, results <-chan *Result, log logger.Logger) <-chan *Result {
+	adjusted := make(chan *Result)
+	go func() {
+		defer close(adjusted)
+		for result := range results {
+			if planResult, ok := result.TerraformResult().(*terraform.PlanResult); ok && planResult != nil && planResult.HasChanges() {
+				kinds := planResult.ChangeKinds()
+				result.hasChanges = countsChanges(policy, kinds)
+				if !result.hasChanges {
+					log.Debugf("astro: %v: ignoring the changes of the plan under changes_policy: %+v", result.ID(), kinds)
+				}
+			}
+			adjusted <- result
+		}
+	}()
+
+	return adjusted
+}
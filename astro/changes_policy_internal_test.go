@@ -0,0 +1,112 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountsChanges(t *testing.T) {
+	resources := terraform.ChangeKinds{Resources: true, Outputs: true}
+	outputs := terraform.ChangeKinds{Outputs: true}
+	reads := terraform.ChangeKinds{Reads: true}
+	outputsAndReads := terraform.ChangeKinds{Outputs: true, Reads: true}
+
+	tt := []struct {
+		policy   conf.ChangesPolicy
+		expected []bool
+	}{
+		{conf.ChangesPolicy{}, []bool{true, true, true, true}},
+		{conf.ChangesPolicy{IgnoreOutputChanges: true}, []bool{true, false, true, true}},
+		{conf.ChangesPolicy{IgnoreReadChanges: true}, []bool{true, true, false, true}},
+		{conf.ChangesPolicy{IgnoreOutputChanges: true, IgnoreReadChanges: true}, []bool{true, false, false, false}},
+		{conf.ChangesPolicy{RequireResourceChanges: true}, []bool{true, false, false, false}},
+	}
+
+	for _, tc := range tt {
+		for i, kinds := range []terraform.ChangeKinds{resources, outputs, reads, outputsAndReads} {
+			assert.Equal(t, tc.expected[i], countsChanges(tc.policy, kinds), "%+v: %+v", tc.policy, kinds)
+		}
+	}
+}
+
+func TestChangesPolicy(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+	terraformPaths := map[string]string{}
+	for name, stdout := range map[string]string{
+		"resources": `Plan: 1 to add, 0 to change, 0 to destroy.\n`,
+		"outputs":   `\nChanges to Outputs:\n  + foo = \"bar\"\n`,
+	} {
+		specPath := filepath.Join(tmpdir, name+".yaml")
+		require.NoError(t, ioutil.WriteFile(specPath, []byte(fmt.Sprintf(`
+commands:
+  plan:
+    exit_code: 2
+    stdout: "%s"
+`, stdout)), 0644))
+		terraformPaths[name] = mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin-"+name), specPath)
+	}
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+changes_policy:
+  ignore_output_changes: true
+modules:
+  - name: resources
+    path: .
+  - name: outputs
+    path: .
+    terraform:
+      path: %s
+`, codeRoot, tmpdir, terraformPaths["resources"], terraformPaths["outputs"])), 0644))
+
+	c, err := NewProjectFromConfigFile(configPath)
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	require.Len(t, results, 2)
+	for id, result := range results {
+		require.NoError(t, result.Err(), id)
+		assert.True(t, result.RawHasChanges(), id)
+	}
+	assert.True(t, results["resources"].HasChanges())
+	assert.False(t, results["outputs"].HasChanges())
+}
@@ -0,0 +1,159 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/report"
+	"github.com/uber/astro/astro/terraform"
+)
+
+func (cli *AstroCLI) createAuditCmd() {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Audit the state of modules",
+	}
+
+	orphansCmd := &cobra.Command{
+		Use:                   "orphans [flags] [-- [Terraform argument]...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Find resources in state that are no longer in the code",
+		Long: `Plan every execution and report the resources that the plans destroy only
+because they were removed from the Terraform code, or their module was.
+Such orphan resources stay in the state, and keep costing money, until
+someone applies a plan that destroys them.
+
+This requires Terraform 0.12 or later, as the plans are inspected as JSON.
+With --fail-on-orphans, the command fails if any orphan resources are found,
+e.g. for CI.`,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runAuditOrphans,
+	}
+
+	orphansCmd.Flags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to audit")
+	orphansCmd.Flags().StringVar(&cli.flags.auditFormat, "format", "text", "output format: text or json")
+	orphansCmd.Flags().BoolVar(&cli.flags.failOnOrphans, "fail-on-orphans", false, "fail if any orphan resources are found")
+
+	auditCmd.AddCommand(orphansCmd)
+
+	cli.commands.audit = auditCmd
+	cli.commands.auditOrphans = orphansCmd
+}
+
+func (cli *AstroCLI) runAuditOrphans(cmd *cobra.Command, args []string) error {
+	if cli.flags.auditFormat != "text" && cli.flags.auditFormat != "json" {
+		return fmt.Errorf("unknown format: %v; must be one of: text, json", cli.flags.auditFormat)
+	}
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	stopped, done := cli.stopOnHangup()
+	defer done()
+
+	status, results, err := cli.project.Plan(
+		astro.PlanExecutionParameters{
+			ExecutionParameters: astro.ExecutionParameters{
+				ModuleNames:         moduleNames,
+				UserVars:            flagsToUserVariables(cli.flags.projectFlags),
+				TerraformParameters: args,
+				OrderedStatus:       true,
+			},
+			SavePlanJSON: true,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	output := report.Orphans{SchemaVersion: report.SchemaVersion, Executions: []report.OrphanAudit{}}
+	failed := false
+	cli.readResults(status, results, func(result *astro.Result) {
+		audit := report.OrphanAudit{ID: result.ID(), Module: result.Module(), Orphans: []terraform.OrphanResource{}}
+
+		err := result.Err()
+		if planResult, ok := result.TerraformResult().(*terraform.PlanResult); ok && err == nil {
+			var orphans []terraform.OrphanResource
+			if orphans, err = planResult.OrphanResources(); err == nil && orphans != nil {
+				audit.Orphans = orphans
+			}
+		}
+		if err != nil {
+			audit.Error = redact(cli.redactPatterns, err.Error())
+			failed = true
+		}
+
+		output.Total += len(audit.Orphans)
+		output.Executions = append(output.Executions, audit)
+	})
+	sort.Slice(output.Executions, func(i, j int) bool {
+		return output.Executions[i].ID < output.Executions[j].ID
+	})
+
+	if cli.flags.auditFormat == "json" {
+		out, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to encode audit: %v", err)
+		}
+		fmt.Fprintln(cli.stdout, string(out))
+	} else {
+		cli.printOrphans(output)
+	}
+
+	if isStopped(stopped) {
+		return errors.New("Stopped; some modules may not have been audited")
+	}
+	if failed {
+		return errors.New("Done; there were errors")
+	}
+	if output.Total > 0 && cli.flags.failOnOrphans {
+		return fmt.Errorf("Done; found %d orphan resources", output.Total)
+	}
+
+	return nil
+}
+
+// printOrphans prints the orphan resources of each execution, followed by
+// the total.
+func (cli *AstroCLI) printOrphans(output report.Orphans) {
+	executions := 0
+	for _, audit := range output.Executions {
+		if audit.Error != "" {
+			fmt.Fprintf(cli.stderr, "%s: ERROR: %s\n", audit.ID, audit.Error)
+			continue
+		}
+		if len(audit.Orphans) == 0 {
+			fmt.Fprintf(cli.stdout, "%s: no orphan resources\n", audit.ID)
+			continue
+		}
+		executions++
+		fmt.Fprintf(cli.stdout, "%s: %d orphan resources\n", audit.ID, len(audit.Orphans))
+		for _, orphan := range audit.Orphans {
+			fmt.Fprintf(cli.stdout, "  %s (%s)\n", orphan.Address, orphan.Reason)
+		}
+	}
+	fmt.Fprintf(cli.stdout, "Found %d orphan resources in %d executions\n", output.Total, executions)
+}
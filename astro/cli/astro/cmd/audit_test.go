@@ -0,0 +1,76 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestAuditOrphans(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-audit-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "../../../terraform/fixtures/mock-terraform/orphans.yaml")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+`, terraformPath)), 0644))
+
+	result := tests.RunTest(t, []string{"audit", "orphans"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Equal(t, ""+
+		"app: 1 orphan resources\n"+
+		"  aws_instance.old (resource removed from configuration)\n"+
+		"Found 1 orphan resources in 1 executions\n",
+		result.Stdout.String())
+
+	result = tests.RunTest(t, []string{"audit", "orphans", "--format", "json"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	var output struct {
+		Executions []struct {
+			ID      string `json:"id"`
+			Orphans []struct {
+				Address string `json:"address"`
+			} `json:"orphans"`
+		} `json:"executions"`
+		Total int `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(result.Stdout.Bytes(), &output))
+	assert.Equal(t, 1, output.Total)
+	require.Len(t, output.Executions, 1)
+	assert.Equal(t, "aws_instance.old", output.Executions[0].Orphans[0].Address)
+
+	result = tests.RunTest(t, []string{"audit", "orphans", "--fail-on-orphans"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "Done; found 1 orphan resources")
+}
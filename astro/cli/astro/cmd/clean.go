@@ -0,0 +1,110 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+func (cli *AstroCLI) createCleanCmd() {
+	cleanCmd := &cobra.Command{
+		Use:                   "clean [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Delete cached files that are no longer used",
+		Long: `Delete cached files that are no longer used. With --plugins, delete the
+provider plugin versions in the shared plugin cache whose files haven't been
+modified or accessed for --unused-since, e.g. 90d or 720h. This is the
+plugins directory of the session repo, or plugin_cache_dir in the config.
+
+Terraform downloads deleted plugins again when a module needs them. Don't
+run this at the same time as plans or applies that use the cache.`,
+		Args: cobra.NoArgs,
+		RunE: cli.runClean,
+	}
+
+	cleanCmd.Flags().BoolVar(&cli.flags.cleanPlugins, "plugins", false, "delete unused versions from the plugin cache")
+	cleanCmd.Flags().StringVar(&cli.flags.unusedSince, "unused-since", "90d", "delete versions that haven't been used for this long, e.g. 90d or 720h")
+	cleanCmd.Flags().BoolVar(&cli.flags.dryRun, "dry-run", false, "print what would be deleted without deleting it")
+
+	cli.commands.clean = cleanCmd
+}
+
+// parseUnusedSince parses a duration like time.ParseDuration, but also
+// accepts a number of days, e.g. "90d".
+func parseUnusedSince(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid --unused-since: %q", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid --unused-since: %q", value)
+	}
+	return duration, nil
+}
+
+func (cli *AstroCLI) runClean(cmd *cobra.Command, args []string) error {
+	if cli.config == nil {
+		return fmt.Errorf("unable to find config file")
+	}
+	if !cli.flags.cleanPlugins {
+		return fmt.Errorf("nothing to clean; use --plugins")
+	}
+
+	unusedFor, err := parseUnusedSince(cli.flags.unusedSince)
+	if err != nil {
+		return err
+	}
+
+	cache := astro.OpenPluginCache(cli.config)
+	before, err := cache.DiskUsage()
+	if err != nil {
+		return err
+	}
+
+	removed, err := cache.Clean(unusedFor, cli.flags.dryRun)
+	verb := "deleted"
+	if cli.flags.dryRun {
+		verb = "would delete"
+	}
+	for _, entry := range removed {
+		fmt.Fprintf(cli.stdout, "%s %s\n", verb, entry)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cli.flags.dryRun {
+		fmt.Fprintf(cli.stdout, "%d plugin versions would be deleted from %s\n", len(removed), cache.Path())
+		return nil
+	}
+	after, err := cache.DiskUsage()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.stdout, "deleted %d plugin versions from %s, freeing %.1f MB\n", len(removed), cache.Path(), float64(before-after)/(1<<20))
+	return nil
+}
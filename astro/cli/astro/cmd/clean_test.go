@@ -0,0 +1,67 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestCleanPlugins(t *testing.T) {
+	repoPath := createPathFixtureSessions(t)
+	defer os.RemoveAll(repoPath)
+
+	monthAgo := time.Now().Add(-30 * 24 * time.Hour)
+	for _, version := range []string{"2.70.0", "3.0.0"} {
+		path := filepath.Join(repoPath, "plugins/registry.terraform.io/hashicorp/aws", version, "linux_amd64/terraform-provider-aws")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte("plugin"), 0755))
+		if version == "2.70.0" {
+			require.NoError(t, os.Chtimes(path, monthAgo, monthAgo))
+		}
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"clean", "--plugins", "--unused-since=7d", "--dry-run"}, "fixtures/path", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		assert.Contains(t, result.Stdout.String(), "would delete registry.terraform.io/hashicorp/aws/2.70.0\n")
+		assert.DirExists(t, filepath.Join(repoPath, "plugins/registry.terraform.io/hashicorp/aws/2.70.0"))
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"clean", "--plugins", "--unused-since=7d"}, "fixtures/path", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		assert.Contains(t, result.Stdout.String(), "deleted registry.terraform.io/hashicorp/aws/2.70.0\n")
+		_, err := os.Stat(filepath.Join(repoPath, "plugins/registry.terraform.io/hashicorp/aws/2.70.0"))
+		assert.True(t, os.IsNotExist(err))
+		assert.DirExists(t, filepath.Join(repoPath, "plugins/registry.terraform.io/hashicorp/aws/3.0.0"))
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"clean", "--plugins", "--unused-since=soon"}, "fixtures/path", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), `invalid --unused-since: "soon"`)
+	})
+}
@@ -25,17 +25,37 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/uber/astro/astro"
 	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
 
 	"github.com/spf13/cobra"
 )
 
+// exitCodeChangeBudgetExceeded is the exit code of a plan without errors
+// where some plans exceed their change budget.
+const exitCodeChangeBudgetExceeded = 3
+
+// errChangeBudgetExceeded is returned by the plan command when some plans
+// exceed their change budget, to exit with exitCodeChangeBudgetExceeded.
+var errChangeBudgetExceeded = errors.New("Done; some plans exceed their change budget; review them, or use --override-change-budget")
+
+// exitCodePlanChanges is the exit code of a plan with --detailed-exitcode
+// without errors where some plans have changes, like Terraform's.
+const exitCodePlanChanges = 2
+
+// errPlanChanges is returned by the plan command with --detailed-exitcode
+// when some plans have changes, to exit with exitCodePlanChanges.
+var errPlanChanges = errors.New("Done; some plans have changes")
+
 func init() {
-	// silence trace info from terraform/dag by default
+	// silence trace info from terraform/dag until Run routes it through
+	// the logger of the CLI
 	log.SetOutput(ioutil.Discard)
 }
 
@@ -49,13 +69,116 @@ type AstroCLI struct {
 	project *astro.Project
 	config  *conf.Project
 
+	// logger receives the logs of astro; see WithLogger
+	logger logger.Logger
+
+	// envFlagArgs are the command line arguments with the flags from
+	// ASTRO_FLAGS added, if it is set
+	envFlagArgs []string
+	// envFlags are the names of the flags added from ASTRO_FLAGS
+	envFlags map[string]bool
+
+	// changeBudgetExceeded is set when a result whose plan exceeds its
+	// change budget is read
+	changeBudgetExceeded bool
+
+	// planChanges is set when a result of a plan with changes is read,
+	// according to the changes_policy of the project
+	planChanges bool
+
+	// versionUnavailable is set when a result of an execution whose
+	// Terraform version couldn't be fetched is read
+	versionUnavailable bool
+
+	// verificationFailed is set when a result of an apply whose
+	// verification failed is read
+	verificationFailed bool
+
+	// commandName is the name of the command that is run, set in preRun
+	commandName string
+
+	// ciMode is the CI system that output is formatted for, e.g.
+	// ciModeGitHub, or empty for plain output; set in preRun
+	ciMode string
+
+	// runResults are the results that have been read, for the metrics
+	// pushed at the end of the run
+	runResults []*astro.Result
+
+	// runStarted is when Run was called, for the notification of the run
+	runStarted time.Time
+
+	// runLog is the log of the run with --log-file or log_dir, opened once
+	// the run has started, or nil
+	runLog *runLog
+
+	// policyDiffFormat is the format of the policy diffs in plans, from
+	// --policy-diff-format, set in preRun
+	policyDiffFormat terraform.PolicyDiffFormat
+
+	// redactPatterns are the patterns redacted from plans and errors,
+	// from the redact configuration, set in preRun
+	redactPatterns []*regexp.Regexp
+
+	// colors is whether output is colored, set in preRun; see
+	// colorsEnabled
+	colors bool
+
+	// forceColors overrides the detection of whether stdout is a terminal,
+	// if set with WithColors
+	forceColors *bool
+
 	// these values are filled in based on runtime flags
 	flags struct {
+		attachExecution   string
+		attachFile        string
+		attachLabel       string
+		auditFormat       string
+		ciMode            string
+		cleanPlugins      bool
+		compactPlan       bool
+		compatFormat      string
+		configFormat      string
+		dependenciesOf    bool
 		detach            bool
+		detailedExitCode  bool
+		dryRun            bool
+		failOnOrphans     bool
+		groupBy           string
+		history           int
+		impactFormat      string
+		pathAll           bool
+		pathSession       string
+		pathWhat          string
+		lenient           bool
+		logFile           string
+		metricsPushURL    string
+		moduleName        string
 		moduleNamesString string
+		noColor           bool
+		noNotify          bool
+		noSkipUnchanged   bool
+		noStateMigration  bool
+		offlineVariables  bool
+		overrideBudget    bool
+		parallelism       int
+		policyDiffFormat  string
+		quiet             bool
+		readOnly          bool
+		redact            bool
+		repair            bool
+		reportFormat      string
+		runID             string
+		simulateCommand   string
+		skipHookReqs      bool
+		strictBinding     bool
+		targetVersion     string
 		trace             bool
+		unusedSince       string
 		userCfgFile       string
 		verbose           bool
+		verifyAfterApply  bool
+		yes               bool
 
 		// projectFlags are special in that the actual flags are dynamic, based
 		// on the astro project configuration loaded.
@@ -63,10 +186,28 @@ type AstroCLI struct {
 	}
 
 	commands struct {
-		root    *cobra.Command
-		plan    *cobra.Command
-		apply   *cobra.Command
-		version *cobra.Command
+		root         *cobra.Command
+		plan         *cobra.Command
+		apply        *cobra.Command
+		audit        *cobra.Command
+		auditOrphans *cobra.Command
+		clean        *cobra.Command
+		compat       *cobra.Command
+		config       *cobra.Command
+		impact       *cobra.Command
+		importCmd    *cobra.Command
+		path         *cobra.Command
+		plugins      *cobra.Command
+		refresh      *cobra.Command
+		report       *cobra.Command
+		schedule     *cobra.Command
+		schema       *cobra.Command
+		sessions     *cobra.Command
+		state        *cobra.Command
+		taint        *cobra.Command
+		unlock       *cobra.Command
+		untaint      *cobra.Command
+		version      *cobra.Command
 	}
 }
 
@@ -86,57 +227,135 @@ func NewAstroCLI(opts ...Option) (*AstroCLI, error) {
 	cli.createRootCommand()
 	cli.createPlanCmd()
 	cli.createApplyCmd()
+	cli.createAuditCmd()
+	cli.createCleanCmd()
+	cli.createCompatCmd()
+	cli.createConfigCmd()
+	cli.createImpactCmd()
+	cli.createImportCmd()
+	cli.createPathCmd()
+	cli.createPluginsCmd()
+	cli.createRefreshCmd()
+	cli.createReportCmd()
+	cli.createSchemaCmd()
+	cli.createSessionsCmd()
+	cli.createStateCmd()
+	cli.createTaintCmd()
+	cli.createUnlockCmd()
+	cli.createUntaintCmd()
 	cli.createVersionCmd()
 
 	cli.commands.root.AddCommand(
 		cli.commands.plan,
 		cli.commands.apply,
+		cli.commands.audit,
+		cli.commands.clean,
+		cli.commands.compat,
+		cli.commands.config,
+		cli.commands.impact,
+		cli.commands.importCmd,
+		cli.commands.path,
+		cli.commands.plugins,
+		cli.commands.refresh,
+		cli.commands.report,
+		cli.commands.schema,
+		cli.commands.sessions,
+		cli.commands.state,
+		cli.commands.taint,
+		cli.commands.unlock,
+		cli.commands.untaint,
 		cli.commands.version,
 	)
 
-	// Set trace. Note, this will turn tracing on for all instances of astro
-	// running in the same process, as the logger is a singleton. This should
-	// only be of concern during testing.
-	cobra.OnInitialize(func() {
-		if cli.flags.trace {
-			logger.Trace.SetOutput(cli.stderr)
-			log.SetOutput(cli.stderr)
-		}
-	})
-
 	return cli, nil
 }
 
 // Run is the main entry point into the CLI program.
 func (cli *AstroCLI) Run(args []string) (exitCode int) {
+	cli.runStarted = time.Now()
+
+	if envFlags := os.Getenv(astroFlagsEnv); envFlags != "" {
+		merged, err := cli.argsWithEnvFlags(args, envFlags)
+		if err != nil {
+			fmt.Fprintln(cli.stderr, err.Error())
+			return 1
+		}
+		args = merged
+		cli.envFlagArgs = merged
+	}
+
 	cli.commands.root.SetArgs(args)
 	cli.commands.root.SetOutput(cli.stderr)
 
-	userProvidedConfigPath, err := configPathFromArgs(args)
+	userProvidedConfigPath, configOpts, trace, err := configFlagsFromArgs(args)
 	if err != nil {
 		fmt.Fprintln(cli.stderr, err.Error())
 		return 1
 	}
 
+	// Unless a logger was passed with WithLogger, astro logs to stderr, at
+	// the level of ASTRO_LOG, or with debug logs with --trace.
+	if cli.logger == nil {
+		level := logger.DefaultLevel()
+		if trace {
+			level = logger.LevelDebug
+		}
+		cli.logger = logger.New(cli.stderr, level)
+	}
+	// terraform/dag traces to the standard logger; its messages become debug
+	// logs, which the logger timestamps.
+	log.SetFlags(0)
+	log.SetOutput(logger.Writer(cli.logger, logger.LevelDebug))
+	if cli.envFlagArgs != nil {
+		cli.logger.Debugf("cli: args with %s: %v", astroFlagsEnv, cli.envFlagArgs)
+	}
+	configOpts = append(configOpts, astro.WithConfigLogger(cli.logger))
+
 	configFilePath := firstExistingFilePath(
 		append([]string{userProvidedConfigPath}, configFileSearchPaths...)...,
 	)
 
+	pluginPath := cli.pluginForArgs(args)
+
 	if configFilePath != "" {
-		config, err := astro.NewConfigFromFile(configFilePath)
+		// Commands that only inspect the configuration or sessions don't
+		// need Terraform, and neither does astro itself to run plugins.
+		if cmd, _, err := cli.commands.root.Find(args); err == nil && (cmd == cli.commands.clean || cmd == cli.commands.impact || cmd == cli.commands.path || cmd.Parent() == cli.commands.sessions || cmd.Parent() == cli.commands.plugins) {
+			configOpts = append(configOpts, astro.WithoutTerraformDetection())
+		} else if pluginPath != "" {
+			configOpts = append(configOpts, astro.WithoutTerraformDetection())
+		}
+
+		config, err := astro.NewConfigFromFile(configFilePath, configOpts...)
 		if err != nil {
 			fmt.Fprintln(cli.stderr, err.Error())
+
+			// Unknown keys may be from a config written for a newer version
+			// of astro; let the user know how to ignore them.
+			if strings.Contains(err.Error(), "unknown key") {
+				fmt.Fprintln(cli.stderr, "NOTE: Use --lenient to ignore unknown keys in the config file.")
+			}
 			return 1
 		}
 
 		cli.config = config
 	}
 
+	if pluginPath != "" {
+		return cli.runPlugin(pluginPath, configFilePath, args)
+	}
+
 	cli.configureDynamicUserFlags()
 
-	if err := cli.commands.root.Execute(); err != nil {
+	err = cli.commands.root.Execute()
+	if err != nil {
 		fmt.Fprintln(cli.stderr, err.Error())
 		exitCode = 1 // exit with error
+		if err == errChangeBudgetExceeded {
+			exitCode = exitCodeChangeBudgetExceeded
+		} else if err == errPlanChanges {
+			exitCode = exitCodePlanChanges
+		}
 
 		// If we get an unknown flag, it could be because the user expected
 		// config to be loaded but it wasn't. Display a message to the user to
@@ -144,8 +363,24 @@ func (cli *AstroCLI) Run(args []string) (exitCode int) {
 		if cli.config == nil && strings.Contains(err.Error(), "unknown flag") {
 			fmt.Fprintln(cli.stderr, "NOTE: No astro config was loaded.")
 		}
+
+		// Sessions written by a newer version of astro can only be read
+		// by that version; name the command to run with it.
+		if versionErr, ok := err.(*astro.ManifestVersionError); ok {
+			commandPath := "astro"
+			if cmd, _, err := cli.commands.root.Find(args); err == nil {
+				commandPath = cmd.CommandPath()
+			}
+			fmt.Fprintf(cli.stderr, "NOTE: Run \"%s\" with the version of astro that wrote session %s, or a newer one.\n", commandPath, versionErr.Session)
+		}
 	}
 
+	cli.closeRunLog(err)
+
+	// Metrics are pushed even if the run failed, so that failures are seen
+	cli.pushMetrics()
+	cli.notify(err)
+
 	return exitCode
 }
 
@@ -153,10 +388,21 @@ func (cli *AstroCLI) Run(args []string) (exitCode int) {
 // configuration.
 func (cli *AstroCLI) configureDynamicUserFlags() {
 	projectFlags := flagsFromConfig(cli.config)
+	for _, flag := range projectFlags {
+		flag.FromEnv = cli.envFlags[flag.Name]
+	}
 	addProjectFlagsToCommands(projectFlags,
 		cli.commands.plan,
 		cli.commands.apply,
+		cli.commands.refresh,
+		cli.commands.importCmd,
+		cli.commands.taint,
+		cli.commands.unlock,
+		cli.commands.untaint,
+		cli.commands.auditOrphans,
+		cli.commands.schedule,
 	)
+	addProjectFlagsToCommands(projectFlags, cli.commands.state.Commands()...)
 	cli.flags.projectFlags = projectFlags
 }
 
@@ -169,8 +415,17 @@ func (cli *AstroCLI) createRootCommand() {
 	}
 
 	rootCmd.PersistentFlags().BoolVarP(&cli.flags.verbose, "verbose", "v", false, "verbose output")
-	rootCmd.PersistentFlags().BoolVarP(&cli.flags.trace, "trace", "", false, "trace output")
+	rootCmd.PersistentFlags().BoolVarP(&cli.flags.trace, "trace", "", false, "trace output; same as ASTRO_LOG=debug")
+	rootCmd.PersistentFlags().BoolVarP(&cli.flags.quiet, "quiet", "q", false, "only print failures and the summary totals")
 	rootCmd.PersistentFlags().StringVar(&cli.flags.userCfgFile, "config", "", "config file")
+	rootCmd.PersistentFlags().BoolVar(&cli.flags.lenient, "lenient", false, "ignore unknown keys in config file")
+	rootCmd.PersistentFlags().BoolVar(&cli.flags.noColor, "no-color", false, "don't color output; also set by NO_COLOR, and when stdout isn't a terminal")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.ciMode, "ci-mode", ciModeOff, "format output for a CI system: github, auto to detect it, or off")
+	rootCmd.PersistentFlags().BoolVar(&cli.flags.offlineVariables, "offline-variables", false, "use cached values instead of running values_command")
+	rootCmd.PersistentFlags().BoolVar(&cli.flags.readOnly, "read-only", false, "only allow operations that don't write to remote state")
+	rootCmd.PersistentFlags().BoolVar(&cli.flags.skipHookReqs, "skip-hook-requirements", false, "don't check that the commands required by hooks are installed")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.runID, "run-id", "", "ID of the run, e.g. a CI pipeline ID, for hooks and modules with inject_run_id; defaults to the session ID")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.metricsPushURL, "metrics-push-url", "", "URL of a Prometheus pushgateway to push a summary of the run to")
 
 	cli.commands.root = rootCmd
 }
@@ -185,6 +440,14 @@ func (cli *AstroCLI) createApplyCmd() {
 	}
 
 	applyCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to apply")
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.noSkipUnchanged, "no-skip-unchanged", false, "apply modules with skip_unchanged_applies even if unchanged since their last apply")
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.noStateMigration, "no-state-migration", false, "don't migrate state for modules with state_migration")
+	applyCmd.PersistentFlags().StringVar(&cli.flags.groupBy, "group-by", "", "group results by: module")
+	applyCmd.PersistentFlags().StringVar(&cli.flags.logFile, "log-file", "", "write a log of the run, with the output of every execution, to this file; overrides log_dir")
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.noNotify, "no-notify", false, "don't send the notification of the run configured in notifications")
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.strictBinding, "strict-binding", false, "fail if a module's configuration references variables without a value")
+	applyCmd.PersistentFlags().StringVar(&cli.flags.policyDiffFormat, "policy-diff-format", "", "format of IAM policy diffs: unified, side-by-side or html (default unified)")
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.verifyAfterApply, "verify-after-apply", false, "plan again after each apply and fail if changes remain")
 
 	cli.commands.apply = applyCmd
 }
@@ -198,28 +461,130 @@ func (cli *AstroCLI) createPlanCmd() {
 		RunE:                  cli.runPlan,
 	}
 
+	planCmd.PersistentFlags().BoolVar(&cli.flags.compactPlan, "compact-plan", false, "only show the resource changes and summary of plans, without refreshes and boilerplate")
 	planCmd.PersistentFlags().BoolVar(&cli.flags.detach, "detach", false, "disconnect remote state before planning")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.detailedExitCode, "detailed-exitcode", false, "exit with 2 if some plans have changes, 0 if none do, and 1 on errors")
 	planCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to plan")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.noStateMigration, "no-state-migration", false, "don't migrate state for modules with state_migration")
+	planCmd.PersistentFlags().StringVar(&cli.flags.groupBy, "group-by", "", "group results by: module")
+	planCmd.PersistentFlags().StringVar(&cli.flags.logFile, "log-file", "", "write a log of the run, with the output of every execution, to this file; overrides log_dir")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.noNotify, "no-notify", false, "don't send the notification of the run configured in notifications")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.strictBinding, "strict-binding", false, "fail if a module's configuration references variables without a value")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.overrideBudget, "override-change-budget", false, "don't fail when plans exceed their change budget")
+	planCmd.PersistentFlags().StringVar(&cli.flags.policyDiffFormat, "policy-diff-format", "", "format of IAM policy diffs: unified, side-by-side or html (default unified)")
 
 	cli.commands.plan = planCmd
 }
 
 func (cli *AstroCLI) preRun(cmd *cobra.Command, args []string) error {
-	logger.Trace.Println("cli: in preRun")
+	cli.logger.Debugf("cli: in preRun")
 
 	if cli.config == nil {
 		return fmt.Errorf("unable to find config file")
 	}
+	if cli.flags.quiet && cli.flags.verbose {
+		return fmt.Errorf("--quiet and --verbose can't be used together")
+	}
+	if cli.flags.groupBy != "" && cli.flags.groupBy != groupByModule {
+		return fmt.Errorf("invalid --group-by: %q; must be: %s", cli.flags.groupBy, groupByModule)
+	}
+	policyDiffFormat, err := terraform.ParsePolicyDiffFormat(cli.flags.policyDiffFormat)
+	if err != nil {
+		return fmt.Errorf("invalid --policy-diff-format: %v", err)
+	}
+	if !terraform.CanDisplayReadableTerraformPolicyChanges(policyDiffFormat) {
+		fmt.Fprintf(cli.stderr, "WARNING: side-by-side policy diffs need diff, or a differ in ASTRO_POLICY_DIFFER, that supports -y; using unified\n")
+		policyDiffFormat = terraform.PolicyDiffUnified
+	}
+	cli.policyDiffFormat = policyDiffFormat
+	ciMode, err := resolveCIMode(cli.flags.ciMode)
+	if err != nil {
+		return fmt.Errorf("invalid --ci-mode: %v", err)
+	}
+	cli.ciMode = ciMode
+	cli.colors = cli.colorsEnabled()
+	if cli.flags.readOnly {
+		cli.config.ReadOnly = true
+	}
+	if cli.flags.metricsPushURL != "" {
+		if err := conf.ValidateMetricsPushURL(cli.flags.metricsPushURL); err != nil {
+			return fmt.Errorf("invalid --metrics-push-url: %v", err)
+		}
+		cli.config.MetricsPush.URL = cli.flags.metricsPushURL
+	}
+	cli.commandName = cmd.Name()
+	// Download progress is a status update, so it isn't printed with
+	// --quiet
+	var progressOut io.Writer = cli.stderr
+	if cli.flags.quiet {
+		progressOut = ioutil.Discard
+	}
 	// Load astro from config
-	project, err := astro.NewProject(astro.WithConfig(*cli.config))
+	opts := []astro.Option{
+		astro.WithConfig(*cli.config),
+		astro.WithLogger(cli.logger),
+		astro.WithTerraformDownloadProgress(newDownloadProgressPrinter(progressOut).progress),
+	}
+	if cli.flags.skipHookReqs {
+		opts = append(opts, astro.WithoutHookRequirements())
+	}
+	if cli.flags.runID != "" {
+		opts = append(opts, astro.WithRunID(cli.flags.runID))
+	}
+	// Terraform output is streamed along with the status updates. Its
+	// redact patterns are set once the project has validated them.
+	var liveOutput *redactingWriter
+	if cli.flags.verbose {
+		stdout := &syncWriter{w: cli.stdout}
+		cli.stdout = stdout
+		liveOutput = &redactingWriter{w: stdout}
+		opts = append(opts, astro.WithLiveOutput(liveOutput))
+	}
+	project, err := astro.NewProject(opts...)
 	if err != nil {
 		return err
 	}
 	cli.project = project
 
+	redactPatterns, err := conf.CompileRedactPatterns(cli.config.Redact)
+	if err != nil {
+		return fmt.Errorf("invalid redact configuration: %v", err)
+	}
+	cli.redactPatterns = redactPatterns
+	if liveOutput != nil {
+		liveOutput.patterns = redactPatterns
+	}
+
+	if cli.flags.verbose {
+		suggestTVMPrune(cli.stderr, cli.logger)
+		suggestPluginCacheClean(cli.stderr, cli.config, cli.logger)
+	}
+
+	if path, err := project.TemporarySessionRepo(); path != "" {
+		fmt.Fprintf(cli.stderr, "WARNING: unable to use the session repository: %v\n", err)
+		fmt.Fprintf(cli.stderr, "WARNING: using the temporary session repository %s instead; collect any logs and plans from it before it is cleaned up\n", path)
+	}
+
 	return nil
 }
 
+// printDone prints the line that ends a successful plan or apply. With
+// --quiet, the summary totals end the run instead.
+func (cli *AstroCLI) printDone() {
+	if !cli.flags.quiet {
+		fmt.Fprintln(cli.stdout, "Done")
+	}
+}
+
+// versionUnavailableNote returns a note for the final error of a run in
+// which some Terraform versions couldn't be fetched, or an empty string.
+func (cli *AstroCLI) versionUnavailableNote() string {
+	if !cli.versionUnavailable {
+		return ""
+	}
+	return " (some Terraform versions are unavailable; their executions and those that depend on them were skipped)"
+}
+
 // processError interprets certain astro errors and embellishes them for
 // display on the CLI.
 func (cli *AstroCLI) processError(err error) error {
@@ -240,31 +605,50 @@ func (cli *AstroCLI) runApply(cmd *cobra.Command, args []string) error {
 		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
 	}
 
+	start := time.Now()
+	stopped, done := cli.stopOnHangup()
+	defer done()
+
+	parameters := astro.ExecutionParameters{
+		ModuleNames:         moduleNames,
+		UserVars:            vars,
+		TerraformParameters: args,
+		SkipStateMigration:  cli.flags.noStateMigration,
+		StrictBinding:       cli.flags.strictBinding,
+		OrderedStatus:       true,
+	}
+
 	status, results, err := cli.project.Apply(
 		astro.ApplyExecutionParameters{
-			ExecutionParameters: astro.ExecutionParameters{
-				ModuleNames:         moduleNames,
-				UserVars:            vars,
-				TerraformParameters: args,
-			},
+			ExecutionParameters: parameters,
+			VerifyAfterApply:    cli.flags.verifyAfterApply,
+			NoSkipUnchanged:     cli.flags.noSkipUnchanged,
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("ERROR: %v", cli.processError(err))
 	}
+	cli.openRunLog(args)
 
-	err = cli.printExecStatus(status, results)
+	err = cli.printResults(status, results, parameters)
+	cli.printSummary(time.Since(start))
+	if isStopped(stopped) {
+		return errors.New("Stopped; some modules may not have been applied")
+	}
 	if err != nil {
-		return fmt.Errorf("Done; there were errors; some modules may not have been applied")
+		return fmt.Errorf("Done; there were errors%s; some modules may not have been applied", cli.versionUnavailableNote())
+	}
+	if cli.verificationFailed {
+		return errors.New("Done; some applies failed verification: their plans still have changes")
 	}
 
-	fmt.Fprintln(cli.stdout, "Done")
+	cli.printDone()
 
 	return nil
 }
 
 func (cli *AstroCLI) runPlan(cmd *cobra.Command, args []string) error {
-	logger.Trace.Printf("cli: plan args: %s\n", args)
+	cli.logger.Debugf("cli: plan args: %s", args)
 
 	vars := flagsToUserVariables(cli.flags.projectFlags)
 
@@ -273,26 +657,46 @@ func (cli *AstroCLI) runPlan(cmd *cobra.Command, args []string) error {
 		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
 	}
 
+	start := time.Now()
+	stopped, done := cli.stopOnHangup()
+	defer done()
+
+	parameters := astro.ExecutionParameters{
+		ModuleNames:         moduleNames,
+		UserVars:            vars,
+		TerraformParameters: args,
+		SkipStateMigration:  cli.flags.noStateMigration,
+		StrictBinding:       cli.flags.strictBinding,
+		OrderedStatus:       true,
+	}
+
 	status, results, err := cli.project.Plan(
 		astro.PlanExecutionParameters{
-			ExecutionParameters: astro.ExecutionParameters{
-				ModuleNames:         moduleNames,
-				UserVars:            vars,
-				TerraformParameters: args,
-			},
-			Detach: cli.flags.detach,
+			ExecutionParameters: parameters,
+			Detach:              cli.flags.detach,
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("ERROR: %v", cli.processError(err))
 	}
+	cli.openRunLog(args)
 
-	err = cli.printExecStatus(status, results)
+	err = cli.printResults(status, results, parameters)
+	cli.printSummary(time.Since(start))
+	if isStopped(stopped) {
+		return errors.New("Stopped; some modules may not have been planned")
+	}
 	if err != nil {
-		return errors.New("Done; there were errors")
+		return fmt.Errorf("Done; there were errors%s", cli.versionUnavailableNote())
+	}
+	if cli.changeBudgetExceeded && !cli.flags.overrideBudget {
+		return errChangeBudgetExceeded
+	}
+	if cli.planChanges && cli.flags.detailedExitCode {
+		return errPlanChanges
 	}
 
-	fmt.Fprintln(cli.stdout, "Done")
+	cli.printDone()
 
 	return nil
 }
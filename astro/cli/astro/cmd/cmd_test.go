@@ -0,0 +1,115 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestWarningsGoToStderr(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-cmd-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(`
+terraform_exit_codes:
+  apply: [0, 3]
+modules:
+  - name: app
+    path: .
+`), 0644))
+
+	// terraform_exit_codes unmarshals itself, so the unknown keys check
+	// doesn't know its keys
+	result := tests.RunTest(t, []string{"report", "schedule", "--lenient"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stderr.String(), "[WARNING] ")
+	assert.Contains(t, result.Stderr.String(), "terraform_exit_codes: exit code 3 of terraform apply is treated as success")
+	assert.NotContains(t, result.Stdout.String(), "WARNING")
+}
+
+func TestPlanDetailedExitCode(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-cmd-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	// the plans of the modules: no changes, resource changes, output
+	// changes only, and an error
+	terraformPaths := []interface{}{}
+	for i, plan := range []string{
+		`exit_code: 0`,
+		`exit_code: 2, stdout: "Plan: 1 to add, 0 to change, 0 to destroy.\n"`,
+		`exit_code: 2, stdout: "\nChanges to Outputs:\n  + foo = 1\n"`,
+		`exit_code: 1, stderr: "Error: plan failed\n"`,
+	} {
+		specPath := filepath.Join(tmpdir, fmt.Sprintf("spec%d.yaml", i))
+		require.NoError(t, ioutil.WriteFile(specPath, []byte(fmt.Sprintf("commands:\n  plan: {%s}\n", plan)), 0644))
+		terraformPaths = append(terraformPaths, mockterraform.InstallForTest(t, filepath.Join(tmpdir, fmt.Sprintf("bin%d", i)), specPath))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+changes_policy:
+  ignore_output_changes: true
+modules:
+  - name: clean
+    path: .
+    local_state: ephemeral
+  - name: changes
+    path: .
+    local_state: ephemeral
+    terraform:
+      path: %s
+  - name: outputs
+    path: .
+    local_state: ephemeral
+    terraform:
+      path: %s
+  - name: broken
+    path: .
+    local_state: ephemeral
+    terraform:
+      path: %s
+`, terraformPaths...)), 0644))
+
+	for _, tt := range []struct {
+		modules  string
+		exitCode int
+	}{
+		{"clean", 0},
+		{"clean,outputs", 0},
+		{"clean,changes", 2},
+		{"changes,broken", 1},
+	} {
+		result := tests.RunTest(t, []string{"plan", "--detailed-exitcode", "--modules", tt.modules}, tmpdir, tests.VERSION_LATEST)
+		assert.Equal(t, tt.exitCode, result.ExitCode, "%s: %s", tt.modules, result.Stderr.String())
+	}
+
+	// without the flag, plans with changes exit with 0
+	result := tests.RunTest(t, []string{"plan", "--modules", "changes"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 0, result.ExitCode, result.Stderr.String())
+}
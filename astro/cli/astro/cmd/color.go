@@ -0,0 +1,52 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io"
+	"os"
+)
+
+// noColorEnv is the environment variable that disables colors when set to
+// any value; see https://no-color.org.
+const noColorEnv = "NO_COLOR"
+
+// colorsEnabled returns whether output is colored: not with --no-color or
+// NO_COLOR, and otherwise only if stdout is a terminal, unless overridden
+// with WithColors.
+func (cli *AstroCLI) colorsEnabled() bool {
+	if cli.flags.noColor || os.Getenv(noColorEnv) != "" {
+		return false
+	}
+	if cli.forceColors != nil {
+		return *cli.forceColors
+	}
+	return isTerminal(cli.stdout)
+}
+
+// isTerminal returns whether the writer is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
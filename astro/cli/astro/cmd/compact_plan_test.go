@@ -0,0 +1,62 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestCompactPlan(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-compact-plan-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	specPath, err := filepath.Abs("fixtures/compact-plan/refresh.yaml")
+	require.NoError(t, err)
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+`, terraformPath)), 0644))
+
+	result := tests.RunTest(t, []string{"plan"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "aws_iam_role.app: Refreshing state...")
+
+	result = tests.RunTest(t, []string{"plan", "--compact-plan"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	stdout := result.Stdout.String()
+	assert.Contains(t, stdout, "~ aws_iam_role.app\n      max_session_duration: \"3600\" => \"7200\"")
+	assert.Contains(t, stdout, "Plan: 0 to add, 1 to change, 0 to destroy.")
+	assert.NotContains(t, stdout, "Refreshing")
+	assert.NotContains(t, stdout, "execution plan")
+	assert.NotContains(t, stdout, "-----")
+}
@@ -0,0 +1,113 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+func (cli *AstroCLI) createCompatCmd() {
+	compatCmd := &cobra.Command{
+		Use:                   "compat [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Check whether modules are ready for a Terraform version",
+		Long: `Check whether each module can be used with the Terraform version in
+--target-version, e.g. before changing terraform.version in the config, and
+report the modules that are ready and the problems found in the others.
+
+The checks are: the required_version constraints in the code; the syntax of
+the code, with terraform 0.12checklist when upgrading from 0.11.14 to 0.12
+or later, and with terraform validate under the target version otherwise;
+and backends configured with remote.backend, which Terraform 0.9 and later
+don't support.
+
+The code is checked in sandboxes that are initialized without a backend, so
+remote state isn't touched, and nothing is planned or applied.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runCompat,
+	}
+
+	compatCmd.Flags().StringVar(&cli.flags.targetVersion, "target-version", "", "Terraform version to check the modules against, e.g. 0.12.31")
+	compatCmd.Flags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to check")
+	compatCmd.Flags().StringVar(&cli.flags.compatFormat, "format", "text", "output format: text or json")
+
+	cli.commands.compat = compatCmd
+}
+
+func (cli *AstroCLI) runCompat(cmd *cobra.Command, args []string) error {
+	if cli.flags.compatFormat != "text" && cli.flags.compatFormat != "json" {
+		return fmt.Errorf("unknown format: %v; must be one of: text, json", cli.flags.compatFormat)
+	}
+	if cli.flags.targetVersion == "" {
+		return fmt.Errorf("--target-version is required")
+	}
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	report, err := cli.project.Compat(astro.CompatParameters{
+		ModuleNames:   moduleNames,
+		TargetVersion: cli.flags.targetVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	if cli.flags.compatFormat == "json" {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to encode report: %v", err)
+		}
+		_, err = fmt.Fprintln(cli.stdout, string(out))
+		return err
+	}
+
+	cli.printCompatReport(report)
+	return nil
+}
+
+// printCompatReport prints the compatibility report as text.
+func (cli *AstroCLI) printCompatReport(report *astro.CompatReport) {
+	fmt.Fprintf(cli.stdout, "Compatibility with Terraform %s:\n", report.TargetVersion)
+
+	ready := 0
+	for _, module := range report.Modules {
+		name := module.Module
+		if module.CurrentVersion != "" {
+			name = fmt.Sprintf("%s (%s)", name, module.CurrentVersion)
+		}
+		if module.Ready {
+			ready++
+			fmt.Fprintf(cli.stdout, "  %s: ready\n", name)
+			continue
+		}
+		fmt.Fprintf(cli.stdout, "  %s: not ready\n", name)
+		for _, finding := range module.Findings {
+			fmt.Fprintf(cli.stdout, "    - %s: %s\n", finding.Check, finding.Message)
+		}
+	}
+
+	fmt.Fprintf(cli.stdout, "%d of %d modules are ready\n", ready, len(report.Modules))
+}
@@ -0,0 +1,59 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestCompatRequiredVersion(t *testing.T) {
+	result := tests.RunTest(t, []string{"compat", "--target-version=0.14.11"}, "fixtures/compat", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "Compatibility with Terraform 0.14.11:\n")
+	assert.Contains(t, result.Stdout.String(), "  app (0.14.11): not ready\n    - required_version: required_version \"< 0.14\" doesn't allow 0.14.11\n")
+	assert.Contains(t, result.Stdout.String(), "0 of 1 modules are ready\n")
+}
+
+func TestCompatJSON(t *testing.T) {
+	result := tests.RunTest(t, []string{"compat", "--target-version=0.12.6", "--format=json"}, "fixtures/compat", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+
+	var output struct {
+		TargetVersion string `json:"target_version"`
+		Modules       []struct {
+			Module string `json:"module"`
+			Ready  bool   `json:"ready"`
+		} `json:"modules"`
+	}
+	require.NoError(t, json.Unmarshal(result.Stdout.Bytes(), &output))
+	assert.Equal(t, "0.12.6", output.TargetVersion)
+	require.Len(t, output.Modules, 1)
+	assert.Equal(t, "app", output.Modules[0].Module)
+	assert.True(t, output.Modules[0].Ready)
+}
+
+func TestCompatTargetVersionRequired(t *testing.T) {
+	result := tests.RunTest(t, []string{"compat"}, "fixtures/compat", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "--target-version is required")
+}
@@ -17,9 +17,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
 
+	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/utils"
 )
 
@@ -32,10 +37,32 @@ var configFileSearchPaths = []string{
 	"terraform/astro.yml",
 }
 
-// configPathFromArgs reads the command line arguments and returns the value of
-// the config option. It returns an empty string if there is no path in the
-// args.
-func configPathFromArgs(args []string) (configFilePath string, err error) {
+// matches backend_config keys whose values are likely to be secrets, e.g.
+// "access_key" or "token".
+var reSensitiveBackendConfigKey = regexp.MustCompile(`(?i)(secret|password|token|credential|access_key|private_key)`)
+
+// redactedValue replaces sensitive values in `astro config show --redact`.
+const redactedValue = "REDACTED"
+
+// resolvedConfig is the configuration shown by `astro config show`. It adds
+// the code root of each module, which is resolved by astro and can't be set
+// in the config file.
+type resolvedConfig struct {
+	conf.Project
+	Modules []resolvedModule `json:"modules"`
+}
+
+type resolvedModule struct {
+	conf.Module
+	TerraformCodeRoot  string   `json:"terraform_code_root"`
+	TerraformCodeRoots []string `json:"terraform_code_roots,omitempty"`
+}
+
+// configFlagsFromArgs reads the command line arguments and returns the config
+// path and the options for loading the config that they specify, and
+// whether --trace is set, as the config is logged while it loads. It returns
+// an empty path if there is no config path in the args.
+func configFlagsFromArgs(args []string) (configFilePath string, opts []astro.ConfigOption, trace bool, err error) {
 	// this is a special cobra command so that we can parse just the config
 	// flag early in the program lifecycle.
 	findConfig := &cobra.Command{
@@ -56,17 +83,29 @@ func configPathFromArgs(args []string) (configFilePath string, err error) {
 		finalArgs = append(finalArgs, arg)
 	}
 
+	var lenient, offlineVariables bool
+
 	// Do an early first parse of the config flag before the main command,
 	findConfig.PersistentFlags().StringVar(&configFilePath, "config", "", "config file")
+	findConfig.PersistentFlags().BoolVar(&lenient, "lenient", false, "ignore unknown keys in config file")
+	findConfig.PersistentFlags().BoolVar(&offlineVariables, "offline-variables", false, "use cached values instead of running values_command")
+	findConfig.PersistentFlags().BoolVar(&trace, "trace", false, "trace output")
 	if err := findConfig.ParseFlags(finalArgs); err != nil {
-		return "", err
+		return "", nil, false, err
 	}
 
 	if configFilePath != "" && !utils.FileExists(configFilePath) {
-		return "", fmt.Errorf("%v: file does not exist", configFilePath)
+		return "", nil, false, fmt.Errorf("%v: file does not exist", configFilePath)
 	}
 
-	return configFilePath, nil
+	if lenient {
+		opts = append(opts, astro.WithLenientConfig())
+	}
+	if offlineVariables {
+		opts = append(opts, astro.WithOfflineVariables())
+	}
+
+	return configFilePath, opts, trace, nil
 }
 
 // firstExistingFilePath takes a list of paths and returns the first one
@@ -79,3 +118,104 @@ func firstExistingFilePath(paths ...string) string {
 	}
 	return ""
 }
+
+func (cli *AstroCLI) createConfigCmd() {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the astro project configuration",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:                   "validate",
+		DisableFlagsInUseLine: true,
+		Short:                 "Check the project configuration for problems",
+		Args:                  cobra.NoArgs,
+		RunE:                  cli.runConfigValidate,
+	}
+
+	showCmd := &cobra.Command{
+		Use:                   "show [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print the fully resolved project configuration",
+		Args:                  cobra.NoArgs,
+		RunE:                  cli.runConfigShow,
+	}
+
+	showCmd.Flags().StringVar(&cli.flags.configFormat, "format", "yaml", "output format: yaml or json")
+	showCmd.Flags().BoolVar(&cli.flags.redact, "redact", false, "mask sensitive-looking backend_config values")
+
+	configCmd.AddCommand(validateCmd, showCmd)
+
+	cli.commands.config = configCmd
+}
+
+func (cli *AstroCLI) runConfigValidate(cmd *cobra.Command, args []string) error {
+	if cli.config == nil {
+		return fmt.Errorf("unable to find config file")
+	}
+
+	problems := cli.config.Problems()
+	if len(problems) == 0 {
+		fmt.Fprintln(cli.stdout, "Configuration is valid")
+		return nil
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(cli.stdout, problem.Error())
+	}
+
+	return fmt.Errorf("configuration is invalid: found %d problem(s)", len(problems))
+}
+
+func (cli *AstroCLI) runConfigShow(cmd *cobra.Command, args []string) error {
+	if cli.config == nil {
+		return fmt.Errorf("unable to find config file")
+	}
+
+	config := resolvedConfig{Project: *cli.config}
+	for _, module := range cli.config.Modules {
+		if cli.flags.redact {
+			module.Remote.BackendConfig = redactBackendConfig(module.Remote.BackendConfig)
+		}
+		config.Modules = append(config.Modules, resolvedModule{
+			Module:             module,
+			TerraformCodeRoot:  module.TerraformCodeRoot,
+			TerraformCodeRoots: module.TerraformCodeRoots,
+		})
+	}
+
+	var out []byte
+	var err error
+
+	switch cli.flags.configFormat {
+	case "yaml":
+		out, err = yaml.Marshal(config)
+	case "json":
+		out, err = json.MarshalIndent(config, "", "  ")
+		out = append(out, '\n')
+	default:
+		return fmt.Errorf("unknown format: %v; must be one of: yaml, json", cli.flags.configFormat)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to encode configuration: %v", err)
+	}
+
+	_, err = cli.stdout.Write(out)
+	return err
+}
+
+// redactBackendConfig returns a copy of the backend config with the values
+// of sensitive-looking keys masked.
+func redactBackendConfig(backendConfig map[string]string) map[string]string {
+	if backendConfig == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(backendConfig))
+	for key, val := range backendConfig {
+		if reSensitiveBackendConfigKey.MatchString(key) {
+			val = redactedValue
+		}
+		redacted[key] = val
+	}
+	return redacted
+}
@@ -0,0 +1,161 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestConfigValidateSuccess(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"config",
+		"validate",
+	}, "fixtures/config-simple", tests.VERSION_LATEST)
+	assert.Equal(t, "Configuration is valid\n", result.Stdout.String())
+	assert.Empty(t, result.Stderr.String())
+	assert.Equal(t, 0, result.ExitCode)
+}
+
+func TestConfigValidateReportsAllProblems(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"config",
+		"validate",
+	}, "fixtures/config-invalid", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, ""+
+		"modules[2].name: duplicate module name \"network\", also used by modules[1]\n"+
+		"modules[0].deps[1].module: unknown module \"vpc\"\n"+
+		"modules[0].remote.backend_config.key: references variable \"region\" that is not defined by the module\n"+
+		"flags.region: flag \"aws-region\" maps to unknown variable \"region\"\n",
+		result.Stdout.String())
+	assert.Contains(t, result.Stderr.String(), "found 4 problem(s)")
+}
+
+func TestConfigEmpty(t *testing.T) {
+	for _, args := range [][]string{{"plan"}, {"config", "validate"}} {
+		result := tests.RunTest(t, args, "fixtures/config-empty", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode, args)
+		assert.Contains(t, result.Stderr.String(), "astro.yaml: the configuration is empty", args)
+		assert.Contains(t, result.Stderr.String(), "modules:\n    - name: app\n      path: app\n", args)
+	}
+}
+
+func TestConfigValidateNoModules(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"config",
+		"validate",
+	}, "fixtures/config-no-modules", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "modules: no modules defined\n", result.Stdout.String())
+
+	// the configuration can still be shown while it is being written
+	result = tests.RunTest(t, []string{
+		"config",
+		"show",
+	}, "fixtures/config-no-modules", tests.VERSION_LATEST)
+	assert.Equal(t, 0, result.ExitCode)
+
+	result = tests.RunTest(t, []string{"plan"}, "fixtures/config-no-modules", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "no modules defined")
+}
+
+func TestConfigShowYAML(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"config",
+		"show",
+	}, "fixtures/config-show", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+
+	fixturePath, err := filepath.Abs("fixtures/config-show")
+	require.NoError(t, err)
+
+	stdout := result.Stdout.String()
+	assert.Contains(t, stdout, fmt.Sprintf("terraform_code_root: %s\n", fixturePath))
+	assert.Contains(t, stdout, "version: 0.8.8\n")
+	assert.Contains(t, stdout, "secret_key: hunter2\n")
+}
+
+func TestConfigShowJSONRedact(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"config",
+		"show",
+		"--format=json",
+		"--redact",
+	}, "fixtures/config-show", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+
+	var config struct {
+		Modules []struct {
+			Name   string
+			Remote struct {
+				BackendConfig map[string]string `json:"backend_config"`
+			}
+			Terraform struct{ Path, Version string }
+		}
+	}
+	require.NoError(t, json.Unmarshal(result.Stdout.Bytes(), &config))
+	require.Len(t, config.Modules, 1)
+
+	module := config.Modules[0]
+	assert.Equal(t, "app", module.Name)
+	assert.Equal(t, map[string]string{
+		"bucket":     "terraform-state",
+		"key":        "app.tfstate",
+		"secret_key": "REDACTED",
+	}, module.Remote.BackendConfig)
+	assert.Equal(t, "0.8.8", module.Terraform.Version)
+	assert.True(t, filepath.IsAbs(module.Terraform.Path))
+}
+
+func TestConfigShowUnknownFormat(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"config",
+		"show",
+		"--format=toml",
+	}, "fixtures/config-show", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "unknown format: toml")
+}
+
+func TestConfigUnknownKeys(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"config",
+		"validate",
+	}, "fixtures/config-unknown-keys", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "unknown key in configuration: modules[0].hooks.pre_module_hook")
+	assert.Contains(t, result.Stderr.String(), "--lenient")
+}
+
+func TestConfigUnknownKeysLenient(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"--lenient",
+		"config",
+		"validate",
+	}, "fixtures/config-unknown-keys", tests.VERSION_LATEST)
+	assert.Equal(t, "Configuration is valid\n", result.Stdout.String())
+	assert.Equal(t, 0, result.ExitCode)
+}
@@ -17,9 +17,13 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/uber/astro/astro"
 	"github.com/uber/astro/astro/terraform"
@@ -28,90 +32,369 @@ import (
 	"github.com/logrusorgru/aurora"
 )
 
-// printExecStatus takes channels for status updates and exec results
-// and prints them on screen as they arrive.
-func (cli *AstroCLI) printExecStatus(status <-chan string, results <-chan *astro.Result) (errors error) {
-	// Print status updates to stdout as they arrive
-	if status != nil {
-		go func() {
-			var out io.Writer
-
-			if cli.flags.verbose {
-				out = cli.stdout
-			} else {
-				out = ioutil.Discard
+// groupByModule is the --group-by value that groups results by module.
+const groupByModule = "module"
+
+// resultView is how the result of an execution is displayed.
+type resultView struct {
+	// failed is whether the execution failed, or its apply failed verification.
+	failed bool
+	// changes is whether the execution was a plan with changes.
+	changes bool
+	// summary is the status of the execution, e.g. "OK Changes (10s)".
+	summary string
+	// details is shown below the summary, e.g. the plan or errors.
+	details string
+}
+
+// newResultView returns the view of the result. Policy diffs in plans are
+// shown in the format, plans are compacted if compactPlan is set, and the
+// matches of the redact patterns are redacted from the details.
+func newResultView(result *astro.Result, policyDiffFormat terraform.PolicyDiffFormat, redactPatterns []*regexp.Regexp, compactPlan, colors bool) resultView {
+	au := aurora.NewAurora(colors)
+	var resultType, changesInfo, runtimeInfo string
+	var details bytes.Buffer
+
+	terraformResult := result.TerraformResult()
+
+	// Check to see if this result is from a plan
+	planResult, _ := terraformResult.(*terraform.PlanResult)
+
+	if skipped, ok := result.Err().(*astro.SkippedError); ok {
+		resultType = au.Brown("SKIPPED").String()
+		if skipped.FailedDependency != "" {
+			changesInfo = au.Sprintf(au.Brown(" (dependency %s failed)"), skipped.FailedDependency)
+		} else {
+			changesInfo = au.Brown(" (the apply was stopped before it started)").String()
+		}
+	} else if result.UnchangedSince() != "" {
+		resultType = au.Gray("UNCHANGED").String()
+		changesInfo = au.Sprintf(au.Gray(" (skipped; unchanged since the apply in session %s)"), result.UnchangedSince())
+	} else if result.Err() == nil {
+		resultType = au.Green("OK").String()
+	} else if result.VersionUnavailable() {
+		resultType = au.Red("VERSION UNAVAILABLE").String()
+	} else {
+		resultType = au.Red("ERROR").String()
+	}
+
+	// If this is a plan, show whether it has changes or not
+	if planResult != nil {
+		if result.HasChanges() {
+			changesInfo = au.Brown(" Changes").String()
+		} else if planResult.HasChanges() {
+			changesInfo = au.Gray(" Changes ignored by changes_policy").String()
+		} else {
+			changesInfo = au.Gray(" No changes").String()
+		}
+	}
+
+	if terraformResult != nil {
+		runtimeInfo = au.Sprintf(au.Gray(" (%s)"), terraformResult.Runtime())
+	}
+
+	// Flag plans that exceed their change budget before the plan itself, so
+	// that they aren't missed
+	if err := result.ChangeBudgetErr(); err != nil {
+		changesInfo += au.Red(" CHANGE BUDGET EXCEEDED").String()
+		fmt.Fprintf(&details, "\n%s\n", au.Red(fmt.Sprintf("CHANGE BUDGET EXCEEDED: %v", changeBudgetMessage(err))))
+	}
+
+	// Flag plans that secrets were redacted from, so that reviewers know
+	// to look at the session's copy
+	if planResult != nil && len(planResult.RedactedSecrets()) > 0 {
+		changesInfo += au.Red(" SECRETS REDACTED").String()
+		fmt.Fprintf(&details, "\n%s\n", au.Red(fmt.Sprintf("SECRETS REDACTED: the plan output matched: %s", strings.Join(planResult.RedactedSecrets(), ", "))))
+	}
+
+	// Flag applies whose plan still has changes, with the changes, so that
+	// the difference with the reviewed plan can be looked into
+	if v := result.Verification(); v != nil && v.Failed() {
+		changesInfo += au.Red(" VERIFY FAILED").String()
+		if v.Err() != nil {
+			fmt.Fprintf(&details, "\n%s\n", au.Red(fmt.Sprintf("VERIFY FAILED: unable to plan after apply: %v", v.Err())))
+		} else {
+			fmt.Fprintf(&details, "\n%s\n%s\n", au.Red("VERIFY FAILED: the plan after apply still has changes:"), v.Changes())
+		}
+	} else if v != nil {
+		changesInfo += au.Green(" Verified").String()
+	}
+
+	// Flag executions whose providers weren't selected from the lock file
+	// in the code, so that it gets updated
+	if result.LockFileChanged() {
+		fmt.Fprintf(&details, "\n%s\n", au.Brown(fmt.Sprintf("WARNING: terraform init changed %s; update the lock file of module %s in the code for reproducible provider selection", terraform.LockFileName, result.Module())))
+	}
+
+	// If this was a plan, show the plan
+	if planResult != nil && planResult.HasChanges() {
+		if warning := planResult.ParseWarning(); warning != "" {
+			fmt.Fprintf(&details, "\n%s\n", au.Brown("WARNING: "+warning))
+		}
+		planOutput := planResult.Changes()
+		if compactPlan {
+			planOutput = terraform.CompactPlan(planOutput) + "\n"
+		}
+		if terraform.CanDisplayReadableTerraformPolicyChanges(policyDiffFormat) {
+			var err error
+			planOutput, err = terraform.ReadableTerraformPolicyChanges(planOutput, policyDiffFormat, colors)
+			if err != nil {
+				fmt.Fprintf(&details, "\n%s", err)
 			}
+		}
+		fmt.Fprintf(&details, "\n%s", planOutput)
+	}
+
+	// If there is a stderr, show it
+	if terraformResult != nil {
+		fmt.Fprint(&details, terraformResult.Stderr())
+	} else if result.Err() != nil && !result.Skipped() {
+		fmt.Fprintln(&details, result.Err())
+	}
+
+	// Show why a plan_check hook rejected the plan
+	if output := result.PlanCheckOutput(); output != "" {
+		fmt.Fprintf(&details, "%s\n%s", au.Red("plan_check output:"), output)
+	}
+
+	// Show how to release a state lock that Terraform couldn't acquire,
+	// e.g. one left behind by an apply that was killed
+	if terraformResult != nil && result.Err() != nil {
+		if lockID := terraform.LockID(terraformResult.Stderr()); lockID != "" {
+			fmt.Fprintf(&details, "\n%s\n", au.Brown(fmt.Sprintf("The state is locked by lock ID %s. If no other Terraform run holds it, release it with:\n  %s", lockID, unlockCommand(result, lockID))))
+		}
+	}
+
+	// Point to the full output of failed executions, of which stderr is
+	// often only the last part
+	if result.Err() != nil && result.LogFile() != "" {
+		fmt.Fprintf(&details, "%s\n", au.Gray("full log: "+result.LogFile()))
+	}
 
-			for update := range status {
-				fmt.Fprintln(out, update)
+	return resultView{
+		failed:  (result.Err() != nil && !result.Skipped()) || (result.Verification() != nil && result.Verification().Failed()),
+		changes: result.HasChanges(),
+		summary: resultType + changesInfo + runtimeInfo,
+		details: redact(redactPatterns, details.String()),
+	}
+}
+
+// unlockCommand returns the astro unlock command that releases the state
+// lock with the ID in the execution of the result.
+func unlockCommand(result *astro.Result, lockID string) string {
+	args := []string{"astro", "unlock", "--module", result.Module()}
+
+	names := []string{}
+	for name := range result.Variables() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, fmt.Sprintf("--%s=%s", name, result.Variables()[name]))
+	}
+
+	return strings.Join(append(args, lockID), " ")
+}
+
+// readResults calls fn with each result as it arrives. Status updates are
+// printed to stdout as they arrive, if verbose output is enabled, and both
+// are written to the run log, if there is one. Both channels are read from
+// this goroutine, so that with ExecutionParameters.OrderedStatus, the
+// updates of an execution are always printed before its result.
+func (cli *AstroCLI) readResults(status <-chan string, results <-chan *astro.Result, fn func(*astro.Result)) {
+	var out io.Writer = ioutil.Discard
+	if cli.flags.verbose {
+		out = cli.stdout
+	}
+
+	for {
+		select {
+		case update, ok := <-status:
+			if !ok {
+				// a nil channel blocks forever, so only results are read
+				status = nil
+				continue
+			}
+			fmt.Fprintln(out, update)
+			cli.runLog.status(update)
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			if result.ChangeBudgetErr() != nil {
+				cli.changeBudgetExceeded = true
 			}
-		}()
+			if result.HasChanges() {
+				cli.planChanges = true
+			}
+			if result.VersionUnavailable() {
+				cli.versionUnavailable = true
+			}
+			if v := result.Verification(); v != nil && v.Failed() {
+				cli.verificationFailed = true
+			}
+			cli.runResults = append(cli.runResults, result)
+			cli.runLog.result(result)
+			fn(result)
+		}
 	}
+}
 
-	for result := range results {
-		var resultType, changesInfo, runtimeInfo string
-		var out = cli.stdout
+// printResults prints the results of a plan or apply, in the display mode
+// selected with --quiet, --ci-mode or --group-by.
+func (cli *AstroCLI) printResults(status <-chan string, results <-chan *astro.Result, parameters astro.ExecutionParameters) error {
+	if cli.flags.quiet {
+		return cli.printExecStatusQuiet(status, results)
+	}
+	if cli.ciMode == ciModeGitHub {
+		return cli.printExecStatusGitHub(status, results)
+	}
+	if cli.flags.groupBy == groupByModule {
+		return cli.printExecStatusByModule(status, results, cli.project.ModuleExecutionCounts(parameters))
+	}
+	return cli.printExecStatus(status, results)
+}
 
+// printExecStatus takes channels for status updates and exec results
+// and prints them on screen as they arrive.
+func (cli *AstroCLI) printExecStatus(status <-chan string, results <-chan *astro.Result) (errors error) {
+	cli.readResults(status, results, func(result *astro.Result) {
 		// If this was an error, append it to the list of errors to
-		// return.
-		if result.Err() != nil {
+		// return. Executions skipped because of a failed dependency
+		// aren't errors of their own.
+		if result.Err() != nil && !result.Skipped() {
 			errors = multierror.Append(errors, result.Err())
 		}
 
-		terraformResult := result.TerraformResult()
-
-		// Check to see if this result is from a plan
-		planResult, _ := terraformResult.(*terraform.PlanResult)
+		view := newResultView(result, cli.policyDiffFormat, cli.redactPatterns, cli.flags.compactPlan, cli.colors)
 
-		if result.Err() == nil {
-			resultType = aurora.Green("OK").String()
-		} else {
-			resultType = aurora.Red("ERROR").String()
+		out := cli.stdout
+		if view.failed {
 			out = cli.stderr
 		}
 
-		// If this is a plan, show whether it has changes or not
-		if planResult != nil {
-			if planResult.HasChanges() {
-				changesInfo = aurora.Brown(" Changes").String()
-			} else {
-				changesInfo = aurora.Gray(" No changes").String()
-			}
+		fmt.Fprintf(out, "%s: %s\n", result.ID(), view.summary)
+		fmt.Fprint(out, view.details)
+	})
+
+	return errors
+}
+
+// printExecStatusQuiet is like printExecStatus, but only prints the
+// executions that failed or exceed their change budget, to stderr.
+func (cli *AstroCLI) printExecStatusQuiet(status <-chan string, results <-chan *astro.Result) (errors error) {
+	cli.readResults(status, results, func(result *astro.Result) {
+		if result.Err() != nil && !result.Skipped() {
+			errors = multierror.Append(errors, result.Err())
 		}
 
-		if terraformResult != nil {
-			runtimeInfo = terraformResult.Runtime()
-			runtimeInfo = aurora.Sprintf(aurora.Gray(" (%s)"), result.TerraformResult().Runtime())
-		}
-
-		// Print status line
-		fmt.Fprintf(out, "%s: %s%s%s\n",
-			result.ID(),
-			resultType,
-			changesInfo,
-			runtimeInfo,
-		)
-
-		// If this was a plan, print the plan
-		if planResult != nil && planResult.HasChanges() {
-			planOutput := planResult.Changes()
-			if terraform.CanDisplayReadableTerraformPolicyChanges() {
-				var err error
-				planOutput, err = terraform.ReadableTerraformPolicyChanges(planOutput)
-				if err != nil {
-					fmt.Fprintf(out, "\n%s", err)
-				}
-			}
-			fmt.Fprintf(out, "\n%s", planOutput)
+		view := newResultView(result, cli.policyDiffFormat, cli.redactPatterns, cli.flags.compactPlan, cli.colors)
+		if !view.failed && result.ChangeBudgetErr() == nil {
+			return
+		}
+
+		fmt.Fprintf(cli.stderr, "%s: %s\n", result.ID(), view.summary)
+		fmt.Fprint(cli.stderr, view.details)
+	})
+
+	return errors
+}
+
+// printExecStatusByModule is like printExecStatus, but buffers the results
+// of each module and prints them together, with one line per execution,
+// once all of its executions have finished. expected is the number of
+// executions of each module; modules with executions that never finish,
+// e.g. because they depend on a failed execution, are printed at the end.
+func (cli *AstroCLI) printExecStatusByModule(status <-chan string, results <-chan *astro.Result, expected map[string]int) (errors error) {
+	groups := map[string][]*astro.Result{}
+	order := []string{}
+
+	cli.readResults(status, results, func(result *astro.Result) {
+		if result.Err() != nil && !result.Skipped() {
+			errors = multierror.Append(errors, result.Err())
+		}
+
+		module := result.Module()
+		if _, ok := groups[module]; !ok {
+			order = append(order, module)
 		}
+		groups[module] = append(groups[module], result)
 
-		// If there is a stderr, print it
-		if terraformResult != nil {
-			fmt.Fprintf(out, terraformResult.Stderr())
-		} else if result.Err() != nil {
-			fmt.Fprintln(out, result.Err())
+		if len(groups[module]) >= expected[module] {
+			cli.printModuleGroup(module, groups[module])
+			delete(groups, module)
+		}
+	})
+
+	for _, module := range order {
+		if group, ok := groups[module]; ok {
+			cli.printModuleGroup(module, group)
 		}
 	}
 
 	return errors
 }
+
+// printModuleGroup prints the results of the executions of a module: one
+// line per execution, followed by the plans of executions with changes and
+// the errors of failed executions.
+func (cli *AstroCLI) printModuleGroup(module string, results []*astro.Result) {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID() < results[j].ID()
+	})
+
+	views := make([]resultView, len(results))
+	for i, result := range results {
+		views[i] = newResultView(result, cli.policyDiffFormat, cli.redactPatterns, cli.flags.compactPlan, cli.colors)
+	}
+
+	fmt.Fprintf(cli.stdout, "%s:\n", aurora.NewAurora(cli.colors).Bold(module))
+	for i, result := range results {
+		fmt.Fprintf(cli.stdout, "  %s: %s\n", variablesLabel(result), views[i].summary)
+	}
+
+	for i, result := range results {
+		if !views[i].failed && !views[i].changes {
+			continue
+		}
+		out := cli.stdout
+		if views[i].failed {
+			out = cli.stderr
+		}
+		fmt.Fprintf(out, "\n%s:\n%s", result.ID(), views[i].details)
+	}
+}
+
+// variablesLabel returns the variable values of the execution of the result,
+// e.g. "environment=dev region=us-east-1", or the execution ID if it has no
+// variables.
+func variablesLabel(result *astro.Result) string {
+	names := []string{}
+	for name := range result.Variables() {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return result.ID()
+	}
+	sort.Strings(names)
+
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, result.Variables()[name]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// changeBudgetMessage returns the reasons in a change budget error on one
+// line, rather than in the multierror list format.
+func changeBudgetMessage(err error) string {
+	merr, ok := err.(*multierror.Error)
+	if !ok {
+		return err.Error()
+	}
+	reasons := []string{}
+	for _, err := range merr.Errors {
+		reasons = append(reasons, err.Error())
+	}
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,122 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/spf13/cobra"
+)
+
+// astroFlagsEnv is the environment variable that contains flags to add to
+// every astro invocation, e.g. in CI templates.
+const astroFlagsEnv = "ASTRO_FLAGS"
+
+// flagName returns the name of the flag in an argument, e.g. "config" for
+// "--config=astro.yaml".
+func flagName(arg string) string {
+	name := strings.TrimLeft(arg, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// commandPathEnd returns the index in args after the names of the command
+// and subcommands, e.g. 2 for ["config", "show", "--format=json"], skipping
+// root flags before them.
+func (cli *AstroCLI) commandPathEnd(args []string) int {
+	end := 0
+	cmd := cli.commands.root
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			// Skip the values of root flags, e.g. "--config astro.yaml"
+			if flag := cli.commands.root.PersistentFlags().Lookup(flagName(arg)); flag != nil && flag.NoOptDefVal == "" && !strings.Contains(arg, "=") {
+				i++
+			}
+			continue
+		}
+
+		var next *cobra.Command
+		for _, sub := range cmd.Commands() {
+			if sub.Name() == arg {
+				next = sub
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		cmd = next
+		end = i + 1
+	}
+
+	return end
+}
+
+// argsWithEnvFlags returns args with the flags from envFlags, the value of
+// ASTRO_FLAGS, added after the command names. Flags that are also in args
+// are left out, and the others come before the flags in args, so that
+// explicit flags take precedence. envFlags may only contain flags, with
+// values in the --flag=value form.
+func (cli *AstroCLI) argsWithEnvFlags(args []string, envFlags string) ([]string, error) {
+	if strings.TrimSpace(envFlags) == "" {
+		return args, nil
+	}
+
+	tokens, err := shellquote.Split(envFlags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", astroFlagsEnv, err)
+	}
+
+	explicit := map[string]bool{}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			explicit[flagName(arg)] = true
+		}
+	}
+
+	flags := []string{}
+	for _, token := range tokens {
+		if token == "--" || !strings.HasPrefix(token, "-") || flagName(token) == "" {
+			return nil, fmt.Errorf("invalid %s: %q is not a flag; only flags are allowed, with values as --flag=value", astroFlagsEnv, token)
+		}
+		if !explicit[flagName(token)] {
+			flags = append(flags, token)
+			if cli.envFlags == nil {
+				cli.envFlags = map[string]bool{}
+			}
+			cli.envFlags[flagName(token)] = true
+		}
+	}
+
+	end := cli.commandPathEnd(args)
+
+	merged := append([]string{}, args[:end]...)
+	merged = append(merged, flags...)
+	return append(merged, args[end:]...), nil
+}
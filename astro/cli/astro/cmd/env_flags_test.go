@@ -0,0 +1,67 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestAstroFlagsEnv(t *testing.T) {
+	defer os.Unsetenv("ASTRO_FLAGS")
+
+	t.Run("flags are added", func(t *testing.T) {
+		os.Setenv("ASTRO_FLAGS", "--format=json")
+		result := tests.RunTest(t, []string{"impact", "network"}, "fixtures/impact", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		assert.Contains(t, result.Stdout.String(), `"query": "dependents"`)
+	})
+
+	t.Run("explicit flags take precedence", func(t *testing.T) {
+		os.Setenv("ASTRO_FLAGS", "--format=json --dependencies-of")
+		result := tests.RunTest(t, []string{"impact", "--format", "text", "dashboard"}, "fixtures/impact", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		assert.Contains(t, result.Stdout.String(), "Executions that dashboard depends on:\n")
+	})
+
+	t.Run("config path is used to load the config", func(t *testing.T) {
+		os.Setenv("ASTRO_FLAGS", "--config=custom.yaml")
+		result := tests.RunTest(t, []string{"--trace", "impact", "network"}, "fixtures/env-flags", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		assert.Equal(t, "Executions that depend on network:\n  depth 1: app\n", result.Stdout.String())
+		assert.Contains(t, result.Stderr.String(), "cli: args with ASTRO_FLAGS: [--trace impact --config=custom.yaml network]")
+	})
+
+	t.Run("explicit config path takes precedence", func(t *testing.T) {
+		os.Setenv("ASTRO_FLAGS", "--config=custom.yaml")
+		result := tests.RunTest(t, []string{"--config", "missing.yaml", "impact", "network"}, "fixtures/env-flags", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), "missing.yaml: file does not exist")
+	})
+
+	t.Run("only flags are allowed", func(t *testing.T) {
+		os.Setenv("ASTRO_FLAGS", "--verbose apply")
+		result := tests.RunTest(t, []string{"impact", "network"}, "fixtures/impact", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), `invalid ASTRO_FLAGS: "apply" is not a flag`)
+	})
+}
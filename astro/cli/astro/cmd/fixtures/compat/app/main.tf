@@ -0,0 +1,11 @@
+terraform {
+  required_version = "< 0.14"
+}
+
+variable "name" {
+  default = "app"
+}
+
+output "name" {
+  value = var.name
+}
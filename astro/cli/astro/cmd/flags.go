@@ -48,14 +48,38 @@ type projectFlag struct {
 	Variable string
 	// AllowedValues is the list of valid values for this flag
 	AllowedValues []string
+	// Default is the value used by the variable when the flag isn't passed.
+	// It is only shown in --help; astro fills it in when binding variables.
+	Default string
+	// Type is the type of the variable, e.g. list. List and map flags can
+	// be repeated, and Value is set to the encoded items.
+	Type string
+	// FromEnv is set if the flag was passed in ASTRO_FLAGS rather than on
+	// the command line.
+	FromEnv bool
+}
+
+// usage returns the text shown next to the flag in --help.
+func (flag *projectFlag) usage() string {
+	if flag.Default == "" {
+		return flag.Description
+	}
+	if flag.Description == "" {
+		return fmt.Sprintf("(default: %s)", flag.Default)
+	}
+	return fmt.Sprintf("%s (default: %s)", flag.Description, flag.Default)
 }
 
 // AddToFlagSet adds the flag to the specified flag set.
 func (flag *projectFlag) AddToFlagSet(flags *pflag.FlagSet) {
-	if len(flag.AllowedValues) > 0 {
-		flags.Var(&stringEnum{flag: flag}, flag.Name, flag.Description)
+	if flag.Type == conf.VariableTypeList {
+		flags.Var(&stringList{flag: flag}, flag.Name, flag.usage())
+	} else if flag.Type == conf.VariableTypeMap {
+		flags.Var(&stringMap{flag: flag}, flag.Name, flag.usage())
+	} else if len(flag.AllowedValues) > 0 {
+		flags.Var(&stringEnum{flag: flag}, flag.Name, flag.usage())
 	} else {
-		flags.StringVar(&flag.Value, flag.Name, "", flag.Description)
+		flags.StringVar(&flag.Value, flag.Name, "", flag.usage())
 	}
 }
 
@@ -88,6 +112,69 @@ func (s *stringEnum) Type() string {
 	return "string"
 }
 
+// stringList implements pflag.Value interface for list variables. The flag
+// can be repeated, and each value can be a comma-separated list of items.
+type stringList struct {
+	flag  *projectFlag
+	items []string
+}
+
+// String returns the items as a comma-separated list
+func (s *stringList) String() string {
+	return strings.Join(s.items, ",")
+}
+
+// Set adds the comma-separated items to the list
+func (s *stringList) Set(value string) error {
+	s.items = append(s.items, strings.Split(value, ",")...)
+	s.flag.Value = conf.EncodeListValue(s.items)
+	return nil
+}
+
+// Type is the type of Value.
+func (s *stringList) Type() string {
+	return "list"
+}
+
+// stringMap implements pflag.Value interface for map variables. The flag can
+// be repeated, and each value can be a comma-separated list of key=value
+// pairs.
+type stringMap struct {
+	flag    *projectFlag
+	entries map[string]string
+}
+
+// String returns the entries as a comma-separated list of key=value pairs
+func (s *stringMap) String() string {
+	pairs := []string{}
+	for key, val := range s.entries {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, val))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set adds the comma-separated key=value pairs to the map
+func (s *stringMap) Set(value string) error {
+	if s.entries == nil {
+		s.entries = map[string]string{}
+	}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("expected key=value, got: %q", pair)
+		}
+		s.entries[parts[0]] = parts[1]
+	}
+	s.flag.Value = conf.EncodeMapValue(s.entries)
+	return nil
+}
+
+// Type is the type of Value.
+func (s *stringMap) Type() string {
+	return "map"
+}
+
 // addProjectFlagsToCommands adds the user flags to the specified Cobra commands.
 func addProjectFlagsToCommands(flags []*projectFlag, cmds ...*cobra.Command) {
 	if len(flags) == 0 {
@@ -143,11 +230,16 @@ func flagsFromConfig(config *conf.Project) (flags []*projectFlag) {
 			if flag, ok := flagMap[flagName]; ok {
 				// aggregate values from all variables in the config
 				flag.AllowedValues = uniqueStrings(append(flag.AllowedValues, variableConf.Values...))
+				if flag.Default == "" {
+					flag.Default = variableConf.Default
+				}
 			} else {
 				flag := &projectFlag{
 					Name:        flagName,
 					Description: flagConf.Description,
 					Variable:    variableConf.Name,
+					Default:     variableConf.Default,
+					Type:        variableConf.Type,
 				}
 				flag.AllowedValues = make([]string, len(variableConf.Values))
 				copy(flag.AllowedValues, variableConf.Values)
@@ -170,6 +262,7 @@ func flagsFromConfig(config *conf.Project) (flags []*projectFlag) {
 func flagsToUserVariables(projectFlags []*projectFlag) *astro.UserVariables {
 	values := make(map[string]string)
 	filters := make(map[string]bool)
+	sources := make(map[string]astro.VariableSource)
 
 	for _, flag := range projectFlags {
 		if flag.Value != "" {
@@ -177,13 +270,23 @@ func flagsToUserVariables(projectFlags []*projectFlag) *astro.UserVariables {
 			if len(flag.AllowedValues) > 0 {
 				filters[flag.Variable] = true
 			}
+			sources[flag.Variable] = flag.source()
 		}
 	}
 
 	return &astro.UserVariables{
 		Values:  values,
 		Filters: filters,
+		Sources: sources,
+	}
+}
+
+// source returns where the value of the flag came from.
+func (flag *projectFlag) source() astro.VariableSource {
+	if flag.FromEnv {
+		return astro.VariableSource{Kind: astro.ValueFromEnv, Name: fmt.Sprintf("%s (--%s)", astroFlagsEnv, flag.Name)}
 	}
+	return astro.VariableSource{Kind: astro.ValueFromFlag, Name: "--" + flag.Name}
 }
 
 // Converts a list of projectFlags to a pflag.flagSet.
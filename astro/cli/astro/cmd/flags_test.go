@@ -17,9 +17,11 @@
 package cmd_test
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/uber/astro/astro/tests"
 )
@@ -131,3 +133,85 @@ func TestPlanFailOnNotAllowedValue(t *testing.T) {
 	assert.Contains(t, result.Stderr.String(), "invalid argument")
 	assert.Contains(t, result.Stderr.String(), "allowed values")
 }
+
+func TestHelpShowsDefaults(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"--config=defaults.yaml",
+		"plan",
+		"--help",
+	}, "fixtures/flags", tests.VERSION_LATEST)
+	assert.Contains(t, result.Stderr.String(), "AWS region (default: us-east-1)")
+	assert.Contains(t, result.Stderr.String(), "(default: dev)")
+}
+
+func TestPlanUsesDefaults(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"--config=defaults.yaml",
+		"plan",
+	}, "fixtures/flags", tests.VERSION_LATEST)
+	assert.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "app-dev-us-east-1")
+	assert.NotContains(t, result.Stdout.String(), "app-prod")
+}
+
+func TestPlanOverridesDefaults(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"--config=defaults.yaml",
+		"plan",
+		"--region=eu-west-1",
+		"--environment=prod",
+	}, "fixtures/flags", tests.VERSION_LATEST)
+	assert.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "app-prod-eu-west-1")
+	assert.NotContains(t, result.Stdout.String(), "app-dev")
+}
+
+func TestPlanListAndMapFlags(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"--config=list_map.yaml",
+		"plan",
+		"--zones=us-east-1a,us-east-1b",
+		"--zones=us-east-1c",
+		"--tags=team=core",
+		"--tags=owner=infra",
+	}, "fixtures/flags", tests.VERSION_LATEST)
+	assert.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "app-owner=infra+team=core-us-east-1a+us-east-1b+us-east-1c")
+}
+
+func TestPlanInvalidMapFlag(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"--config=list_map.yaml",
+		"plan",
+		"--zones=us-east-1a",
+		"--tags=team",
+	}, "fixtures/flags", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), `expected key=value, got: "team"`)
+}
+
+func TestPlanVariableProvenance(t *testing.T) {
+	defer os.Unsetenv("ASTRO_FLAGS")
+	os.Setenv("ASTRO_FLAGS", "--environment=prod")
+
+	result := tests.RunTest(t, []string{
+		"--config=defaults.yaml",
+		"plan",
+		"--verbose",
+	}, "fixtures/flags", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "[app-prod-us-east-1] Variables:\n")
+	assert.Regexp(t, `\[app-prod-us-east-1\]   environment +prod +env +ASTRO_FLAGS \(--environment\)\n`, result.Stdout.String())
+	assert.Regexp(t, `\[app-prod-us-east-1\]   region +us-east-1 +default +variables\.region\.default\n`, result.Stdout.String())
+
+	result = tests.RunTest(t, []string{
+		"--config=defaults.yaml",
+		"plan",
+		"--verbose",
+		"--environment=dev",
+		"--region=eu-west-1",
+	}, "fixtures/flags", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Regexp(t, `\[app-dev-eu-west-1\]   environment +dev +flag +--environment\n`, result.Stdout.String())
+	assert.Regexp(t, `\[app-dev-eu-west-1\]   region +eu-west-1 +flag +--region\n`, result.Stdout.String())
+}
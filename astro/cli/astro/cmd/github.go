@@ -0,0 +1,191 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/terraform"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Values of --ci-mode.
+const (
+	ciModeOff    = "off"
+	ciModeAuto   = "auto"
+	ciModeGitHub = "github"
+)
+
+// githubActionsEnv is set to "true" in the steps of GitHub Actions
+// workflows.
+const githubActionsEnv = "GITHUB_ACTIONS"
+
+// githubStepSummaryEnv is the path of the file whose markdown GitHub
+// Actions shows on the summary page of the run.
+const githubStepSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+// resolveCIMode returns the CI system to format the output for with the
+// --ci-mode, or an empty string for plain output. With auto, it is detected
+// from the environment.
+func resolveCIMode(mode string) (string, error) {
+	switch mode {
+	case "", ciModeOff:
+		return "", nil
+	case ciModeGitHub:
+		return ciModeGitHub, nil
+	case ciModeAuto:
+		if os.Getenv(githubActionsEnv) == "true" {
+			return ciModeGitHub, nil
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("%q; must be one of: %s, %s, %s", mode, ciModeOff, ciModeAuto, ciModeGitHub)
+}
+
+// printExecStatusGitHub is like printExecStatus, for GitHub Actions: the
+// details of each execution, e.g. its plan, are in a collapsible group
+// below its status, and its failure and the warnings of Terraform are
+// annotated, so that they show up on the summary page of the run.
+func (cli *AstroCLI) printExecStatusGitHub(status <-chan string, results <-chan *astro.Result) (errors error) {
+	cli.readResults(status, results, func(result *astro.Result) {
+		if result.Err() != nil && !result.Skipped() {
+			errors = multierror.Append(errors, result.Err())
+		}
+
+		view := newResultView(result, cli.policyDiffFormat, cli.redactPatterns, cli.flags.compactPlan, cli.colors)
+
+		out := cli.stdout
+		if view.failed {
+			out = cli.stderr
+		}
+
+		fmt.Fprintf(out, "%s: %s\n", result.ID(), view.summary)
+		if details := strings.Trim(view.details, "\n"); details != "" {
+			fmt.Fprintf(out, "::group::%s\n%s\n::endgroup::\n", result.ID(), details)
+		}
+		for _, annotation := range cli.githubAnnotations(result, view.failed) {
+			fmt.Fprintln(out, annotation)
+		}
+	})
+
+	return errors
+}
+
+// githubAnnotations returns the workflow commands that annotate the result:
+// an error if it failed, one per error of Terraform if it printed any, and a
+// warning per warning of Terraform. Diagnostics with a location are
+// annotated on their file, relative to the current directory.
+func (cli *AstroCLI) githubAnnotations(result *astro.Result, failed bool) []string {
+	var diagnostics []terraform.Diagnostic
+	if terraformResult := result.TerraformResult(); terraformResult != nil {
+		diagnostics = terraform.ParseDiagnostics(terraformResult.Stdout() + "\n" + terraformResult.Stderr())
+	}
+
+	annotations := []string{}
+	annotatedError := false
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == terraform.DiagnosticError {
+			if !failed {
+				continue
+			}
+			annotatedError = true
+		}
+		properties := []string{}
+		if diagnostic.File != "" {
+			properties = append(properties, "file="+githubEscapeProperty(cli.moduleFilePath(result.Module(), diagnostic.File)))
+			if diagnostic.Line > 0 {
+				properties = append(properties, fmt.Sprintf("line=%d", diagnostic.Line))
+			}
+		}
+		properties = append(properties, "title="+githubEscapeProperty(result.ID()))
+		annotations = append(annotations, fmt.Sprintf("::%s %s::%s", diagnostic.Severity, strings.Join(properties, ","), githubEscapeData(redact(cli.redactPatterns, diagnostic.Summary))))
+	}
+
+	if failed && !annotatedError {
+		message := "the plan after apply still has changes"
+		if result.Err() != nil {
+			message = result.Err().Error()
+		} else if v := result.Verification(); v != nil && v.Err() != nil {
+			message = fmt.Sprintf("unable to plan after apply: %v", v.Err())
+		}
+		annotations = append(annotations, fmt.Sprintf("::error title=%s::%s", githubEscapeProperty(result.ID()), githubEscapeData(redact(cli.redactPatterns, message))))
+	}
+
+	return annotations
+}
+
+// moduleFilePath returns the path of the file of the module, relative to
+// the current directory if possible.
+func (cli *AstroCLI) moduleFilePath(module, file string) string {
+	for _, moduleConf := range cli.config.Modules {
+		if moduleConf.Name != module {
+			continue
+		}
+		path := filepath.Join(moduleConf.TerraformCodeRoot, moduleConf.Path, file)
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, path); err == nil {
+				return rel
+			}
+		}
+		return path
+	}
+	return file
+}
+
+// writeGitHubStepSummary appends the summary of the run, as a markdown
+// table, to the file in GITHUB_STEP_SUMMARY, if it is set.
+func (cli *AstroCLI) writeGitHubStepSummary(rows []summaryRow, totals string) error {
+	path := os.Getenv(githubStepSummaryEnv)
+	if path == "" {
+		return nil
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "### astro %s\n\n", cli.commandName)
+	fmt.Fprintln(&summary, "| Execution | Status | Changes | Runtime |")
+	fmt.Fprintln(&summary, "| --- | --- | --- | --- |")
+	for _, row := range rows {
+		fmt.Fprintf(&summary, "| `%s` | %s | %s | %s |\n", row.id, row.status, strings.Replace(row.changes, "|", "\\|", -1), row.runtime)
+	}
+	fmt.Fprintf(&summary, "\n%s\n", totals)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write step summary: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(summary.String()); err != nil {
+		return fmt.Errorf("unable to write step summary: %v", err)
+	}
+	return nil
+}
+
+// githubEscapeData escapes the message of a workflow command.
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubEscapeProperty escapes the value of a property of a workflow
+// command, e.g. its title.
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
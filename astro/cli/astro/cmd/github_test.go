@@ -0,0 +1,106 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubCIMode(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-github-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	okSpec := filepath.Join(tmpdir, "ok.yaml")
+	require.NoError(t, ioutil.WriteFile(okSpec, []byte(`
+commands:
+  plan:
+    exit_code: 2
+    stdout: "Warning: Deprecated attribute\n\n  on main.tf line 1:\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n"
+  show:
+    stdout: "+ null_resource.foo\n"
+`), 0644))
+	failSpec := filepath.Join(tmpdir, "fail.yaml")
+	require.NoError(t, ioutil.WriteFile(failSpec, []byte(`
+commands:
+  plan:
+    exit_code: 1
+    stderr: "Error: Unsupported argument\n\n  on main.tf line 3, in resource \"null_resource\" \"foo\":\n"
+`), 0644))
+	okTerraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "ok"), okSpec)
+	failTerraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "fail"), failSpec)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+modules:
+  - name: web
+    path: .
+    local_state: ephemeral
+  - name: app
+    path: .
+    local_state: ephemeral
+    terraform:
+      path: %s
+`, okTerraform, failTerraform)), 0644))
+
+	summaryPath := filepath.Join(tmpdir, "summary.md")
+	os.Setenv("GITHUB_STEP_SUMMARY", summaryPath)
+	defer os.Unsetenv("GITHUB_STEP_SUMMARY")
+
+	result := tests.RunTest(t, []string{"plan", "--ci-mode", "github", "--no-color"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+
+	stdout := result.Stdout.String()
+	assert.Contains(t, stdout, "web: OK Changes")
+	assert.Regexp(t, "::group::web\n\\+ null_resource.foo\n::endgroup::\n", stdout)
+	assert.Contains(t, stdout, "::warning file=main.tf,line=1,title=web::Deprecated attribute\n")
+
+	stderr := result.Stderr.String()
+	assert.Contains(t, stderr, "app: ERROR")
+	assert.Contains(t, stderr, "::error file=main.tf,line=3,title=app::Unsupported argument\n")
+
+	summary, err := ioutil.ReadFile(summaryPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(summary), "### astro plan\n")
+	assert.Contains(t, string(summary), "| `web` | OK | 1 to add, 0 to change, 0 to destroy |")
+	assert.Contains(t, string(summary), "| `app` | ERROR | - |")
+	assert.Contains(t, string(summary), "1 ok, 1 failed, 0 skipped in")
+
+	// auto only formats for GitHub Actions when it runs there
+	result = tests.RunTest(t, []string{"plan", "--ci-mode", "auto", "--no-color"}, tmpdir, tests.VERSION_LATEST)
+	assert.NotContains(t, result.Stdout.String(), "::group::")
+
+	os.Setenv("GITHUB_ACTIONS", "true")
+	defer os.Unsetenv("GITHUB_ACTIONS")
+	result = tests.RunTest(t, []string{"plan", "--ci-mode", "auto", "--no-color"}, tmpdir, tests.VERSION_LATEST)
+	assert.Contains(t, result.Stdout.String(), "::group::web\n")
+
+	result = tests.RunTest(t, []string{"plan", "--ci-mode", "gitlab"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), `invalid --ci-mode: "gitlab"; must be one of: off, auto, github`)
+}
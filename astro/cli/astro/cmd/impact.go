@@ -0,0 +1,113 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/report"
+)
+
+func (cli *AstroCLI) createImpactCmd() {
+	impactCmd := &cobra.Command{
+		Use:                   "impact [flags] <module-or-execution-id>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Show the executions affected by changes to a module",
+		Long: `Show the executions that depend on a module or execution, directly or
+transitively, grouped by how far away they are in the dependency graph. With
+--dependencies-of, show the executions it depends on instead.
+
+This only reads the configuration; it doesn't run Terraform.`,
+		Args: cobra.ExactArgs(1),
+		RunE: cli.runImpact,
+	}
+
+	impactCmd.Flags().BoolVar(&cli.flags.dependenciesOf, "dependencies-of", false, "show what the module depends on, instead of what depends on it")
+	impactCmd.Flags().StringVar(&cli.flags.impactFormat, "format", "text", "output format: text or json")
+
+	cli.commands.impact = impactCmd
+}
+
+func (cli *AstroCLI) runImpact(cmd *cobra.Command, args []string) error {
+	if cli.config == nil {
+		return fmt.Errorf("unable to find config file")
+	}
+
+	if cli.flags.impactFormat != "text" && cli.flags.impactFormat != "json" {
+		return fmt.Errorf("unknown format: %v; must be one of: text, json", cli.flags.impactFormat)
+	}
+
+	graph, err := astro.NewDependencyGraph(*cli.config)
+	if err != nil {
+		return err
+	}
+
+	name := args[0]
+	query := "dependents"
+	if cli.flags.dependenciesOf {
+		query = "dependencies"
+	}
+
+	var impact *astro.Impact
+	if cli.flags.dependenciesOf {
+		impact, err = graph.Dependencies(name)
+	} else {
+		impact, err = graph.Dependents(name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cli.flags.impactFormat == "json" {
+		out, err := json.MarshalIndent(report.Impact{
+			SchemaVersion: report.SchemaVersion,
+			Name:          name,
+			Query:         query,
+			Executions:    impact.Executions,
+			Levels:        impact.Levels,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to encode impact: %v", err)
+		}
+		_, err = fmt.Fprintln(cli.stdout, string(out))
+		return err
+	}
+
+	if len(impact.Levels) == 0 {
+		if cli.flags.dependenciesOf {
+			fmt.Fprintf(cli.stdout, "%s does not depend on any executions\n", name)
+		} else {
+			fmt.Fprintf(cli.stdout, "No executions depend on %s\n", name)
+		}
+		return nil
+	}
+
+	if cli.flags.dependenciesOf {
+		fmt.Fprintf(cli.stdout, "Executions that %s depends on:\n", name)
+	} else {
+		fmt.Fprintf(cli.stdout, "Executions that depend on %s:\n", name)
+	}
+	for i, level := range impact.Levels {
+		fmt.Fprintf(cli.stdout, "  depth %d: %s\n", i+1, strings.Join(level, ", "))
+	}
+
+	return nil
+}
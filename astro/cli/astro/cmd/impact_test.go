@@ -0,0 +1,74 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestImpact(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"impact",
+		"network",
+	}, "fixtures/impact", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Equal(t, ""+
+		"Executions that depend on network:\n"+
+		"  depth 1: app-dev, app-prod\n"+
+		"  depth 2: dashboard\n",
+		result.Stdout.String())
+}
+
+func TestImpactDependenciesOfJSON(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"impact",
+		"--dependencies-of",
+		"--format=json",
+		"dashboard",
+	}, "fixtures/impact", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+
+	var output struct {
+		Name       string     `json:"name"`
+		Query      string     `json:"query"`
+		Executions []string   `json:"executions"`
+		Levels     [][]string `json:"levels"`
+	}
+	require.NoError(t, json.Unmarshal(result.Stdout.Bytes(), &output))
+	assert.Equal(t, "dashboard", output.Name)
+	assert.Equal(t, "dependencies", output.Query)
+	assert.Equal(t, []string{"dashboard"}, output.Executions)
+	assert.Equal(t, [][]string{
+		{"app-dev", "app-prod"},
+		{"network-dev", "network-prod"},
+	}, output.Levels)
+}
+
+func TestImpactUnknownName(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"impact",
+		"network-staging",
+	}, "fixtures/impact", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), `unknown module or execution: "network-staging"; did you mean one of: network-dev, network-prod`)
+}
@@ -0,0 +1,88 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+func (cli *AstroCLI) createImportCmd() {
+	importCmd := &cobra.Command{
+		Use:                   "import --module <name> [flags] <address> <id>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Import an existing resource into a module's state",
+		Long: `Import the existing resource with the id into the resource address, e.g.
+aws_instance.web, in the state of a module. The variable flags must narrow
+the module down to a single execution.
+
+After importing, the module is planned, so that you can see whether its
+configuration matches the imported resource.`,
+		Args:              cobra.ExactArgs(2),
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runImport,
+	}
+
+	importCmd.PersistentFlags().StringVar(&cli.flags.moduleName, "module", "", "module to import into (required)")
+	importCmd.PersistentFlags().BoolVar(&cli.flags.noStateMigration, "no-state-migration", false, "don't migrate state for modules with state_migration")
+
+	cli.commands.importCmd = importCmd
+}
+
+func (cli *AstroCLI) runImport(cmd *cobra.Command, args []string) error {
+	if cli.flags.moduleName == "" {
+		return errors.New("ERROR: --module is required")
+	}
+
+	vars := flagsToUserVariables(cli.flags.projectFlags)
+
+	stopped, done := cli.stopOnHangup()
+	defer done()
+
+	parameters := astro.ExecutionParameters{
+		UserVars:           vars,
+		SkipStateMigration: cli.flags.noStateMigration,
+		OrderedStatus:      true,
+	}
+
+	status, results, err := cli.project.Import(
+		astro.ImportExecutionParameters{
+			ExecutionParameters: parameters,
+			Module:              cli.flags.moduleName,
+			Address:             args[0],
+			ID:                  args[1],
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	err = cli.printResults(status, results, parameters)
+	if isStopped(stopped) {
+		return errors.New("Stopped; the resource may not have been imported")
+	}
+	if err != nil {
+		return fmt.Errorf("Done; there were errors%s", cli.versionUnavailableNote())
+	}
+
+	fmt.Fprintln(cli.stdout, "Done")
+
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerboseStreamsTerraformOutput(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-live-output-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(`
+commands:
+  plan:
+    stdout: "aws_instance.web: Refreshing state... (password: hunter2)\n"
+`), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+redact:
+  - "password: (\\w+)"
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+`, terraformPath)), 0644))
+
+	result := tests.RunTest(t, []string{"plan"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.NotContains(t, result.Stdout.String(), "Refreshing state")
+
+	result = tests.RunTest(t, []string{"plan", "--verbose"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "[app] aws_instance.web: Refreshing state... (password: [REDACTED])\n")
+	assert.NotContains(t, result.Stdout.String(), "hunter2")
+}
@@ -0,0 +1,128 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/metrics"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// metricsAuthorizationEnv is the environment variable with the value of the
+// Authorization header of metrics pushes, e.g. "Bearer <token>".
+const metricsAuthorizationEnv = "ASTRO_METRICS_PUSH_AUTHORIZATION"
+
+// pushMetrics pushes a summary of the results of the run to the
+// pushgateway, if one is configured. Failing to push only prints a warning,
+// so that it doesn't change the outcome of the run.
+func (cli *AstroCLI) pushMetrics() {
+	if cli.config == nil || cli.config.MetricsPush.URL == "" || cli.project == nil || len(cli.runResults) == 0 {
+		return
+	}
+
+	job := cli.config.MetricsPush.Job
+	if job == "" {
+		job = filepath.Base(cli.config.TerraformCodeRoot)
+	}
+
+	opts := []metrics.PusherOption{}
+	if authorization := os.Getenv(metricsAuthorizationEnv); authorization != "" {
+		opts = append(opts, metrics.WithAuthorization(authorization))
+	}
+
+	pusher := metrics.NewPusher(cli.config.MetricsPush.URL, job, cli.project.RunID(), opts...)
+	if err := pusher.Push(runMetrics(cli.commandName, cli.runResults, time.Now())); err != nil {
+		fmt.Fprintf(cli.stderr, "WARNING: %v\n", err)
+	}
+}
+
+// runMetrics returns the metrics of the results of a run of the command.
+func runMetrics(command string, results []*astro.Result, now time.Time) []metrics.Metric {
+	executions := map[string]float64{"ok": 0, "error": 0}
+	durations := metrics.Metric{
+		Name: "astro_execution_duration_seconds",
+		Help: "How long each execution took.",
+	}
+	successes := metrics.Metric{
+		Name: "astro_execution_success",
+		Help: "Whether each execution succeeded (1) or failed (0).",
+	}
+	changes := metrics.Metric{
+		Name: "astro_plan_changes",
+		Help: "Whether the plan of each execution has changes (1) or not (0), as counted by changes_policy.",
+	}
+
+	for _, result := range results {
+		// Executions that were skipped weren't run
+		if result.Skipped() {
+			continue
+		}
+
+		labels := map[string]string{
+			"command":   command,
+			"execution": result.ID(),
+			"module":    result.Module(),
+		}
+
+		success := 1.0
+		if result.Err() != nil {
+			success = 0
+			executions["error"]++
+		} else {
+			executions["ok"]++
+		}
+		successes.Samples = append(successes.Samples, metrics.Sample{Labels: labels, Value: success})
+		durations.Samples = append(durations.Samples, metrics.Sample{Labels: labels, Value: result.Duration().Seconds()})
+
+		if _, ok := result.TerraformResult().(*terraform.PlanResult); ok && result.Err() == nil {
+			hasChanges := 0.0
+			if result.HasChanges() {
+				hasChanges = 1
+			}
+			changes.Samples = append(changes.Samples, metrics.Sample{Labels: labels, Value: hasChanges})
+		}
+	}
+
+	all := []metrics.Metric{
+		{
+			Name: "astro_executions",
+			Help: "Number of executions in the run, by result.",
+			Samples: []metrics.Sample{
+				{Labels: map[string]string{"command": command, "result": "ok"}, Value: executions["ok"]},
+				{Labels: map[string]string{"command": command, "result": "error"}, Value: executions["error"]},
+			},
+		},
+		successes,
+		durations,
+	}
+	if len(changes.Samples) > 0 {
+		all = append(all, changes)
+	}
+	all = append(all, metrics.Metric{
+		Name: "astro_last_run_timestamp_seconds",
+		Help: "When the run finished, in seconds since the epoch.",
+		Samples: []metrics.Sample{
+			{Labels: map[string]string{"command": command}, Value: float64(now.Unix())},
+		},
+	})
+	return all
+}
@@ -0,0 +1,76 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestMetricsPush(t *testing.T) {
+	var path, authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		authorization = r.Header.Get("Authorization")
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	os.Setenv("ASTRO_METRICS_PUSH_AUTHORIZATION", "Bearer s3cr3t")
+	defer os.Unsetenv("ASTRO_METRICS_PUSH_AUTHORIZATION")
+
+	result := tests.RunTest(t, []string{
+		"plan",
+		"--region=us-east-1",
+		"--run-id=pipeline-42",
+		"--metrics-push-url=" + server.URL,
+	}, "fixtures/group-by", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+
+	assert.Equal(t, "/metrics/job/group-by/instance/pipeline-42", path)
+	assert.Equal(t, "Bearer s3cr3t", authorization)
+	assert.Contains(t, body, "astro_executions{command=\"plan\",result=\"ok\"} 3\n")
+	assert.Contains(t, body, "astro_executions{command=\"plan\",result=\"error\"} 0\n")
+	assert.Contains(t, body, "astro_execution_success{command=\"plan\",execution=\"network-dev-us-east-1\",module=\"network\"} 1\n")
+	assert.Contains(t, body, "astro_plan_changes{command=\"plan\",execution=\"app\",module=\"app\"} 0\n")
+}
+
+func TestMetricsPushFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	result := tests.RunTest(t, []string{
+		"plan",
+		"--region=us-east-1",
+		"--metrics-push-url=" + server.URL,
+	}, "fixtures/group-by", tests.VERSION_LATEST)
+
+	// Failing to push doesn't fail the run
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "WARNING: unable to push metrics: pushgateway returned 503 Service Unavailable: unavailable")
+}
@@ -0,0 +1,93 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uber/astro/astro/notify"
+)
+
+// notify sends the summary of the run to the webhook in notifications, if
+// runs of the command are notified and --no-notify isn't set. err is the
+// error of the run, if any. Like pushing metrics, failing to send only
+// prints a warning, so that it doesn't change the outcome of the run.
+func (cli *AstroCLI) notify(err error) {
+	if cli.config == nil || cli.project == nil || cli.flags.noNotify || len(cli.runResults) == 0 {
+		return
+	}
+	notifications := cli.config.Notifications
+	if !notifications.Notifies(cli.commandName) {
+		return
+	}
+
+	opts := []notify.WebhookOption{}
+	// The template was checked when the config was loaded
+	if tmpl, _ := notifications.ParseTemplate(); tmpl != nil {
+		opts = append(opts, notify.WithTemplate(tmpl))
+	}
+
+	webhook := notify.NewWebhook(notifications.WebhookURL, opts...)
+	if err := webhook.Send(cli.runSummary(err, time.Since(cli.runStarted))); err != nil {
+		fmt.Fprintf(cli.stderr, "WARNING: %v\n", err)
+	}
+}
+
+// runSummary returns the summary of the results that have been read, for
+// the notification of the run. Errors are redacted like on screen.
+func (cli *AstroCLI) runSummary(err error, elapsed time.Duration) notify.Summary {
+	summary := notify.Summary{
+		Command:    cli.commandName,
+		RunID:      cli.project.RunID(),
+		SessionID:  cli.project.SessionID(),
+		Succeeded:  err == nil,
+		Duration:   elapsed.Seconds(),
+		Failed:     []string{},
+		Executions: []notify.Execution{},
+	}
+	if err != nil {
+		summary.Error = redact(cli.redactPatterns, err.Error())
+	}
+
+	for _, result := range cli.runResults {
+		row := newSummaryRow(result)
+		execution := notify.Execution{
+			ID:       result.ID(),
+			Module:   result.Module(),
+			Status:   row.status,
+			Duration: result.Duration().Seconds(),
+		}
+		if row.changes != "-" {
+			execution.Changes = row.changes
+		}
+
+		switch row.status {
+		case summaryOK:
+			summary.Counts.OK++
+		case summarySkipped:
+			summary.Counts.Skipped++
+		case summaryError:
+			summary.Counts.Failed++
+			summary.Failed = append(summary.Failed, result.ID())
+			execution.Error = redact(cli.redactPatterns, result.Err().Error())
+		}
+		summary.Executions = append(summary.Executions, execution)
+	}
+
+	return summary
+}
@@ -0,0 +1,147 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/notify"
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestNotify(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-notify-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	okSpec := filepath.Join(tmpdir, "ok.yaml")
+	require.NoError(t, ioutil.WriteFile(okSpec, []byte(`
+commands:
+  apply:
+    stdout: "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.\n"
+`), 0644))
+	failSpec := filepath.Join(tmpdir, "fail.yaml")
+	require.NoError(t, ioutil.WriteFile(failSpec, []byte(`
+commands:
+  apply:
+    exit_code: 1
+    stderr: "Error: apply failed\n"
+`), 0644))
+	okTerraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "ok"), okSpec)
+	failTerraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "fail"), failSpec)
+
+	requests := 0
+	var summary notify.Summary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		summary = notify.Summary{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&summary))
+	}))
+	defer server.Close()
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+notifications:
+  webhook_url: %s
+modules:
+  - name: db
+    path: .
+    local_state: ephemeral
+  - name: network
+    path: .
+    local_state: ephemeral
+    terraform:
+      path: %s
+`, okTerraform, server.URL, failTerraform)), 0644))
+
+	// Failed applies are notified, with the failed executions
+	result := tests.RunTest(t, []string{"apply", "--run-id", "ci-42"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	require.Equal(t, 1, requests)
+	assert.Equal(t, "apply", summary.Command)
+	assert.Equal(t, "ci-42", summary.RunID)
+	assert.NotEmpty(t, summary.SessionID)
+	assert.False(t, summary.Succeeded)
+	assert.Equal(t, notify.Counts{OK: 1, Failed: 1}, summary.Counts)
+	assert.Equal(t, []string{"network"}, summary.Failed)
+	require.Len(t, summary.Executions, 2)
+	for _, execution := range summary.Executions {
+		if execution.ID == "db" {
+			assert.Equal(t, "OK", execution.Status)
+			assert.Equal(t, "1 added, 0 changed, 0 destroyed", execution.Changes)
+		} else {
+			assert.Equal(t, "ERROR", execution.Status)
+			assert.Contains(t, execution.Error, "apply failed")
+		}
+	}
+
+	// Plans aren't notified by default
+	tests.RunTest(t, []string{"plan"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, requests)
+
+	// --no-notify turns notifications off
+	result = tests.RunTest(t, []string{"apply", "--no-notify"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, 1, requests)
+}
+
+func TestNotifyFailure(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-notify-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	spec := filepath.Join(tmpdir, "ok.yaml")
+	require.NoError(t, ioutil.WriteFile(spec, []byte(`
+commands:
+  apply:
+    stdout: "Apply complete! Resources: 0 added, 0 changed, 0 destroyed.\n"
+`), 0644))
+	terraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "ok"), spec)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+notifications:
+  webhook_url: %s
+modules:
+  - name: db
+    path: .
+    local_state: ephemeral
+`, terraform, server.URL)), 0644))
+
+	// Failing to notify doesn't fail the run
+	result := tests.RunTest(t, []string{"apply"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stderr.String(), "WARNING: unable to send the notification: webhook returned 503 Service Unavailable: unavailable")
+}
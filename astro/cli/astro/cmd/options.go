@@ -19,6 +19,8 @@ package cmd
 import (
 	"io"
 
+	"github.com/uber/astro/astro/logger"
+
 	multierror "github.com/hashicorp/go-multierror"
 )
 
@@ -58,3 +60,21 @@ func WithStdin(stdin io.Reader) Option {
 		return nil
 	}
 }
+
+// WithColors colors output, or not, regardless of whether stdout is a
+// terminal. --no-color and NO_COLOR still disable colors.
+func WithColors(colors bool) Option {
+	return func(cli *AstroCLI) error {
+		cli.forceColors = &colors
+		return nil
+	}
+}
+
+// WithLogger makes astro log to l, instead of to stderr at the level of
+// ASTRO_LOG and --trace.
+func WithLogger(l logger.Logger) Option {
+	return func(cli *AstroCLI) error {
+		cli.logger = l
+		return nil
+	}
+}
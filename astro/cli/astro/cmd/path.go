@@ -0,0 +1,105 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/utils"
+)
+
+func (cli *AstroCLI) createPathCmd() {
+	pathCmd := &cobra.Command{
+		Use:                   "path [flags] <execution-id>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print the path of an execution's files in a session",
+		Long: `Print the absolute path of the sandbox, logs or plan file of an execution in
+a session, by default the most recent one. With --all, print a tab-separated
+list of the paths of every execution in the session, with the columns:
+execution ID, sandbox, logs and plan. The plan column is empty for executions
+without a plan file.
+
+This only reads the configuration and the session directory; it doesn't run
+Terraform or create a new session.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cli.flags.pathAll {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: cli.runPath,
+	}
+
+	pathCmd.Flags().StringVar(&cli.flags.pathSession, "session", "", "ID of the session (default: most recent)")
+	pathCmd.Flags().StringVar(&cli.flags.pathWhat, "what", "sandbox", "path to print: "+strings.Join(astro.ExecutionPathTypes, ", "))
+	pathCmd.Flags().BoolVar(&cli.flags.pathAll, "all", false, "print the paths of every execution in the session")
+
+	cli.commands.path = pathCmd
+}
+
+func (cli *AstroCLI) runPath(cmd *cobra.Command, args []string) error {
+	if cli.config == nil {
+		return fmt.Errorf("unable to find config file")
+	}
+
+	if !utils.StringSliceContains(astro.ExecutionPathTypes, cli.flags.pathWhat) {
+		return fmt.Errorf("invalid --what: %q; must be one of: %s", cli.flags.pathWhat, strings.Join(astro.ExecutionPathTypes, ", "))
+	}
+
+	repo, err := astro.OpenSessionRepo(cli.config)
+	if err != nil {
+		return err
+	}
+
+	var session *astro.Session
+	if cli.flags.pathSession != "" {
+		session, err = repo.Open(cli.flags.pathSession)
+	} else {
+		session, err = repo.Latest()
+	}
+	if err != nil {
+		return err
+	}
+
+	if cli.flags.pathAll {
+		all, err := session.AllExecutionPaths()
+		if err != nil {
+			return err
+		}
+		for _, paths := range all {
+			fmt.Fprintf(cli.stdout, "%s\t%s\t%s\t%s\n", paths.ID, paths.Sandbox, paths.Logs, paths.Plan)
+		}
+		return nil
+	}
+
+	paths, err := session.ExecutionPaths(args[0])
+	if err != nil {
+		return err
+	}
+
+	path, err := paths.Path(cli.flags.pathWhat)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.stdout, path)
+
+	return nil
+}
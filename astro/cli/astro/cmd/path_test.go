@@ -0,0 +1,109 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+// createPathFixtureSessions creates two sessions in the fixture's session
+// repo, with the directory layout of executions that were planned.
+func createPathFixtureSessions(t *testing.T) (repoPath string) {
+	repoPath, err := filepath.Abs("fixtures/path/.astro")
+	require.NoError(t, err)
+
+	for _, dir := range []string{
+		"01CJ5ZB0J9R9ZK9BQBX5BXH5TK/app-dev/logs",
+		"01CJ5ZB0J9R9ZK9BQBX5BXH5TK/app-dev/sandbox/app",
+		"01CJ61AKPDBJ59A4RRNRY7TPF3/app-dev/logs",
+		"01CJ61AKPDBJ59A4RRNRY7TPF3/app-dev/sandbox/app",
+		"01CJ61AKPDBJ59A4RRNRY7TPF3/app-db/logs",
+		"01CJ61AKPDBJ59A4RRNRY7TPF3/app-db/sandbox/db",
+		"01CJ61AKPDBJ59A4RRNRY7TPF3/.tmp",
+		"plugins",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(repoPath, dir), 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoPath, "01CJ61AKPDBJ59A4RRNRY7TPF3/app-dev/sandbox/app/app-dev.plan"), nil, 0644))
+
+	return repoPath
+}
+
+func TestPath(t *testing.T) {
+	repoPath := createPathFixtureSessions(t)
+	defer os.RemoveAll(repoPath)
+
+	t.Run("latest session", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"path", "app-db"}, "fixtures/path", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		assert.Equal(t, filepath.Join(repoPath, "01CJ61AKPDBJ59A4RRNRY7TPF3/app-db/sandbox/db")+"\n", result.Stdout.String())
+	})
+
+	t.Run("plan", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"path", "--what=plan", "app-dev"}, "fixtures/path", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		assert.Equal(t, filepath.Join(repoPath, "01CJ61AKPDBJ59A4RRNRY7TPF3/app-dev/sandbox/app/app-dev.plan")+"\n", result.Stdout.String())
+	})
+
+	t.Run("older session", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"path", "--session=01CJ5ZB0J9R9ZK9BQBX5BXH5TK", "--what=logs", "app-dev"}, "fixtures/path", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		assert.Equal(t, filepath.Join(repoPath, "01CJ5ZB0J9R9ZK9BQBX5BXH5TK/app-dev/logs")+"\n", result.Stdout.String())
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"path", "--what=plan", "app-db"}, "fixtures/path", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), "execution app-db has no plan file")
+	})
+
+	t.Run("not in session", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"path", "--session=01CJ5ZB0J9R9ZK9BQBX5BXH5TK", "app-db"}, "fixtures/path", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), "execution app-db not found in session 01CJ5ZB0J9R9ZK9BQBX5BXH5TK")
+	})
+
+	t.Run("unknown execution", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"path", "web-dev"}, "fixtures/path", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), `"web-dev" is not an execution of any module in the configuration`)
+	})
+
+	t.Run("all", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"path", "--all"}, "fixtures/path", tests.VERSION_LATEST)
+		require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+		session := filepath.Join(repoPath, "01CJ61AKPDBJ59A4RRNRY7TPF3")
+		assert.Equal(t, ""+
+			"app-db\t"+session+"/app-db/sandbox/db\t"+session+"/app-db/logs\t\n"+
+			"app-dev\t"+session+"/app-dev/sandbox/app\t"+session+"/app-dev/logs\t"+session+"/app-dev/sandbox/app/app-dev.plan\n",
+			result.Stdout.String())
+	})
+}
+
+func TestPathNoSessions(t *testing.T) {
+	result := tests.RunTest(t, []string{"path", "app-db"}, "fixtures/path", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "no sessions found")
+}
@@ -0,0 +1,245 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// pluginPrefix is the prefix of the names of plugin executables. Running
+// `astro foo`, where foo isn't an astro command, runs `astro-foo` from PATH.
+const pluginPrefix = "astro-"
+
+// The environment variables that plugins are run with, so that they don't
+// have to find the configuration themselves. Variables that don't apply,
+// e.g. ASTRO_CONFIG when there is no config file, are set to an empty
+// string, so that values inherited from a parent astro aren't used.
+const (
+	// pluginEnvBin is the path to the astro executable, for plugins that
+	// run astro commands.
+	pluginEnvBin = "ASTRO_BIN"
+	// pluginEnvConfig is the absolute path to the config file that astro
+	// found, either with --config or in the default locations.
+	pluginEnvConfig = "ASTRO_CONFIG"
+	// pluginEnvCodeRoot is the absolute path to the Terraform code root of
+	// the project.
+	pluginEnvCodeRoot = "ASTRO_CODE_ROOT"
+	// pluginEnvSessionRepoDir is the absolute path to the directory that
+	// the .astro session repo is in; see session_repo_dir.
+	pluginEnvSessionRepoDir = "ASTRO_SESSION_REPO_DIR"
+	// pluginEnvModules is the comma-separated list of modules selected with
+	// --modules, if any.
+	pluginEnvModules = "ASTRO_MODULES"
+)
+
+// pluginForArgs returns the path of the plugin that runs the command in
+// args, or an empty string if the command is an astro command or there is
+// no plugin for it. The command must be the first argument.
+func (cli *AstroCLI) pluginForArgs(args []string) string {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return ""
+	}
+	if cmd, _, err := cli.commands.root.Find(args); err == nil && cmd != cli.commands.root {
+		return ""
+	}
+	path, err := exec.LookPath(pluginPrefix + args[0])
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// pluginEnv returns the environment that plugins are run with; see
+// pluginEnvConfig and the others.
+func (cli *AstroCLI) pluginEnv(configFilePath string, args []string) ([]string, error) {
+	env := map[string]string{}
+
+	if bin, err := os.Executable(); err == nil {
+		env[pluginEnvBin] = bin
+	}
+
+	env[pluginEnvConfig] = ""
+	if configFilePath != "" {
+		abs, err := filepath.Abs(configFilePath)
+		if err != nil {
+			return nil, err
+		}
+		env[pluginEnvConfig] = abs
+	}
+
+	env[pluginEnvCodeRoot] = ""
+	env[pluginEnvSessionRepoDir] = ""
+	if cli.config != nil {
+		env[pluginEnvCodeRoot] = cli.config.TerraformCodeRoot
+		sessionRepoDir, err := filepath.Abs(cli.config.SessionRepoDir)
+		if err != nil {
+			return nil, err
+		}
+		env[pluginEnvSessionRepoDir] = sessionRepoDir
+	}
+
+	// Parse --modules like configFlagsFromArgs parses --config, ignoring
+	// the plugin's own flags; the plugin still gets all the arguments.
+	var modules string
+	findModules := &cobra.Command{
+		FParseErrWhitelist: cobra.FParseErrWhitelist{
+			UnknownFlags: true,
+		},
+	}
+	findModules.Flags().StringVar(&modules, "modules", "", "list of modules")
+	if err := findModules.ParseFlags(args); err != nil {
+		return nil, err
+	}
+	env[pluginEnvModules] = modules
+
+	environ := os.Environ()
+	for name, value := range env {
+		environ = append(environ, name+"="+value)
+	}
+	return environ, nil
+}
+
+// runPlugin runs the plugin at path with the arguments after the command
+// name, and returns its exit code.
+func (cli *AstroCLI) runPlugin(path string, configFilePath string, args []string) int {
+	env, err := cli.pluginEnv(configFilePath, args[1:])
+	if err != nil {
+		fmt.Fprintf(cli.stderr, "unable to run plugin %v: %v\n", path, err)
+		return 1
+	}
+
+	plugin := exec.Command(path, args[1:]...)
+	plugin.Stdin = cli.stdin
+	plugin.Stdout = cli.stdout
+	plugin.Stderr = cli.stderr
+	plugin.Env = env
+
+	if err := plugin.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Exited() {
+				return status.ExitStatus()
+			}
+		}
+		fmt.Fprintf(cli.stderr, "plugin %v failed: %v\n", path, err)
+		return 1
+	}
+	return 0
+}
+
+// plugin is an executable on PATH that extends astro with a command.
+type plugin struct {
+	// name is the command, e.g. "foo" for astro-foo.
+	name string
+	// path is the path to the executable.
+	path string
+}
+
+// findPlugins returns the plugins on PATH, sorted by name. Like commands,
+// plugins in earlier PATH directories take precedence.
+func findPlugins() []plugin {
+	seen := map[string]bool{}
+	plugins := []plugin{}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			name := strings.TrimPrefix(file.Name(), pluginPrefix)
+			if name == file.Name() || name == "" || seen[name] {
+				continue
+			}
+			path := filepath.Join(dir, file.Name())
+			// follow symlinks, e.g. from a package manager's bin directory
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, plugin{name: name, path: path})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].name < plugins[j].name
+	})
+	return plugins
+}
+
+func (cli *AstroCLI) createPluginsCmd() {
+	pluginsCmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "Manage the plugins that extend astro with commands",
+		Long: `Plugins are executables on PATH named astro-<command>. Running
+"astro <command> [argument]...", where <command> isn't an astro command, runs
+the plugin with the arguments. Plugins get the same stdin, stdout and stderr,
+and astro exits with the plugin's exit code.
+
+Plugins are run with these environment variables, so that they don't have
+to find the configuration themselves:
+
+  ASTRO_BIN               path to the astro executable
+  ASTRO_CONFIG            absolute path to the config file, if one was found
+  ASTRO_CODE_ROOT         absolute path to the Terraform code root
+  ASTRO_SESSION_REPO_DIR  absolute path to the directory of the .astro session repo
+  ASTRO_MODULES           the value of --modules, if it was passed`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the plugins on PATH",
+		Long: `Print a tab-separated list of the plugins on PATH, with the columns: command
+and path. Plugins with the same name as an astro command are never run; they
+are listed with "(shadowed)" after the path.`,
+		Args: cobra.NoArgs,
+		RunE: cli.runPluginsList,
+	}
+
+	pluginsCmd.AddCommand(listCmd)
+
+	cli.commands.plugins = pluginsCmd
+}
+
+func (cli *AstroCLI) runPluginsList(cmd *cobra.Command, args []string) error {
+	plugins := findPlugins()
+	if len(plugins) == 0 {
+		fmt.Fprintf(cli.stdout, "No plugins found; plugins are executables on PATH named %s<command>\n", pluginPrefix)
+		return nil
+	}
+
+	for _, plugin := range plugins {
+		shadowed := ""
+		if command, _, err := cli.commands.root.Find([]string{plugin.name}); err == nil && command != cli.commands.root {
+			shadowed = " (shadowed)"
+		}
+		fmt.Fprintf(cli.stdout, "%s\t%s%s\n", plugin.name, plugin.path, shadowed)
+	}
+	return nil
+}
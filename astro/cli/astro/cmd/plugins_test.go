@@ -0,0 +1,92 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+// withPluginsOnPath adds the fixture plugins to PATH for the duration of
+// the test.
+func withPluginsOnPath(t *testing.T) (binDir string, restore func()) {
+	binDir, err := filepath.Abs("fixtures/plugins/bin")
+	require.NoError(t, err)
+
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", binDir, oldPath))
+	return binDir, func() { os.Setenv("PATH", oldPath) }
+}
+
+func TestPlugin(t *testing.T) {
+	_, restore := withPluginsOnPath(t)
+	defer restore()
+
+	fixtureDir, err := filepath.Abs("fixtures/plugins")
+	require.NoError(t, err)
+
+	result := tests.RunTest(t, []string{"hello", "--modules=app", "--plugin-flag", "arg"}, "fixtures/plugins", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Equal(t, fmt.Sprintf(`args: --modules=app --plugin-flag arg
+config: %s/astro.yaml
+code root: %s
+session repo dir: %s
+modules: app
+`, fixtureDir, fixtureDir, fixtureDir), result.Stdout.String())
+
+	// the plugin's exit code is astro's
+	os.Setenv("HELLO_EXIT_CODE", "3")
+	defer os.Unsetenv("HELLO_EXIT_CODE")
+	result = tests.RunTest(t, []string{"hello"}, "fixtures/plugins", tests.VERSION_LATEST)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Contains(t, result.Stdout.String(), "modules: \n")
+}
+
+func TestPluginShadowedByCommand(t *testing.T) {
+	_, restore := withPluginsOnPath(t)
+	defer restore()
+
+	result := tests.RunTest(t, []string{"plan", "--help"}, "fixtures/plugins", tests.VERSION_LATEST)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.NotContains(t, result.Stdout.String(), "plugin plan")
+}
+
+func TestUnknownCommandWithoutPlugin(t *testing.T) {
+	_, restore := withPluginsOnPath(t)
+	defer restore()
+
+	result := tests.RunTest(t, []string{"no-such-command"}, "fixtures/plugins", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), `unknown command "no-such-command" for "astro"`)
+}
+
+func TestPluginsList(t *testing.T) {
+	binDir, restore := withPluginsOnPath(t)
+	defer restore()
+
+	result := tests.RunTest(t, []string{"plugins", "list"}, "fixtures/plugins", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), fmt.Sprintf("hello\t%s/astro-hello\n", binDir))
+	assert.Contains(t, result.Stdout.String(), fmt.Sprintf("plan\t%s/astro-plan (shadowed)\n", binDir))
+}
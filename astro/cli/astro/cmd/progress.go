@@ -0,0 +1,116 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/tvm"
+)
+
+// progressInterval is how often the progress of a download is printed.
+const progressInterval = time.Second
+
+// largeTVMRepoSize is the size of the tvm repo, in bytes, above which
+// verbose output suggests pruning it.
+const largeTVMRepoSize = 2 << 30
+
+// downloadProgressPrinter prints the progress of Terraform downloads, e.g.
+// "downloading terraform 1.5.7... 42%", when they start and finish, and
+// every progressInterval in between.
+type downloadProgressPrinter struct {
+	w io.Writer
+
+	mu          sync.Mutex
+	lastPrinted map[string]time.Time
+}
+
+func newDownloadProgressPrinter(w io.Writer) *downloadProgressPrinter {
+	return &downloadProgressPrinter{
+		w:           w,
+		lastPrinted: map[string]time.Time{},
+	}
+}
+
+// progress implements tvm.DownloadProgress.
+func (p *downloadProgressPrinter) progress(version string, downloaded, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if total < 0 {
+		// the size isn't known, so only the start can be reported
+		if downloaded == 0 {
+			fmt.Fprintf(p.w, "downloading terraform %s...\n", version)
+		}
+		return
+	}
+
+	done := downloaded == total
+	if downloaded > 0 && !done && time.Since(p.lastPrinted[version]) < progressInterval {
+		return
+	}
+	p.lastPrinted[version] = time.Now()
+
+	var percent int64 = 100
+	if total > 0 {
+		percent = downloaded * 100 / total
+	}
+	fmt.Fprintf(p.w, "downloading terraform %s... %d%%\n", version, percent)
+}
+
+// suggestTVMPrune prints a pointer to `tvm prune` if the tvm repo has grown
+// large, e.g. on shared build hosts that have used many Terraform versions.
+func suggestTVMPrune(w io.Writer, log logger.Logger) {
+	repo, err := tvm.NewVersionRepoForCurrentSystem("", tvm.WithLogger(log))
+	if err != nil {
+		log.Debugf("cli: unable to open the tvm repo: %v", err)
+		return
+	}
+	size, err := repo.DiskUsage()
+	if err != nil {
+		log.Debugf("cli: unable to get the size of the tvm repo: %v", err)
+		return
+	}
+	if size > largeTVMRepoSize {
+		fmt.Fprintf(w, "The tvm repo takes %.1f GB; remove the Terraform versions you no longer use with `tvm prune`\n", float64(size)/(1<<30))
+	}
+}
+
+// suggestPluginCacheClean prints the size of the shared plugin cache, and a
+// pointer to `astro clean --plugins`, if it is larger than
+// plugin_cache_warn_size.
+func suggestPluginCacheClean(w io.Writer, config *conf.Project, log logger.Logger) {
+	warnSize := config.PluginCacheWarnSize
+	if warnSize == 0 {
+		warnSize = conf.DefaultPluginCacheWarnSize
+	}
+	cache := astro.OpenPluginCache(config)
+	size, err := cache.DiskUsage()
+	if err != nil {
+		log.Debugf("cli: unable to get the size of the plugin cache: %v", err)
+		return
+	}
+	if size > warnSize {
+		fmt.Fprintf(w, "The plugin cache in %s takes %.1f GB; remove the plugins you no longer use with `astro clean --plugins`\n", cache.Path(), float64(size)/(1<<30))
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces the text that matches a redact pattern.
+const redacted = "[REDACTED]"
+
+// redact replaces the matches of the patterns in s with redacted: the
+// first capture group of patterns that have one, or else the whole match.
+func redact(patterns []*regexp.Regexp, s string) string {
+	for _, re := range patterns {
+		group := 0
+		if re.NumSubexp() > 0 {
+			group = 1
+		}
+
+		var out strings.Builder
+		last := 0
+		for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+			start, end := match[2*group], match[2*group+1]
+			if start < 0 {
+				continue
+			}
+			out.WriteString(s[last:start])
+			out.WriteString(redacted)
+			last = end
+		}
+		out.WriteString(s[last:])
+		s = out.String()
+	}
+	return s
+}
+
+// redactingWriter redacts the matches of the patterns from each write
+// before passing it on to w. It expects whole lines, like the live output
+// of Terraform, since matches split between writes aren't redacted.
+type redactingWriter struct {
+	w        io.Writer
+	patterns []*regexp.Regexp
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, redact(r.patterns, string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
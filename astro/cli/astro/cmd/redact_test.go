@@ -0,0 +1,112 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+// setupRedactTest writes a project to tmpdir with a module whose Terraform
+// is mocked with the spec in fixtures/redact.
+func setupRedactTest(t *testing.T, tmpdir, spec string) {
+	specPath, err := filepath.Abs(filepath.Join("fixtures", "redact", spec))
+	require.NoError(t, err)
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+redact:
+  - 'password: +"[^"]*" => "([^"]*)"'
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+`, terraformPath)), 0644))
+}
+
+func TestRedactPlanChanges(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-redact-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	setupRedactTest(t, tmpdir, "changes.yaml")
+
+	result := tests.RunTest(t, []string{"plan"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+
+	stdout := result.Stdout.String()
+	assert.Contains(t, stdout, `password: "" => "[REDACTED]"`)
+	assert.Contains(t, stdout, `AWS_SECRET_ACCESS_KEY: "" => "[REDACTED]"`)
+	assert.NotContains(t, stdout, "hunter2-db-password")
+	assert.NotContains(t, stdout, "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY")
+	assert.Contains(t, stdout, "Plan: 1 to add, 1 to change, 0 to destroy.")
+}
+
+func TestRedactErrors(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-redact-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	setupRedactTest(t, tmpdir, "error.yaml")
+
+	result := tests.RunTest(t, []string{"plan"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	stderr := result.Stderr.String()
+	assert.Contains(t, stderr, "Error: tls_private_key.deploy: invalid key:\n[REDACTED]")
+	assert.NotContains(t, stderr, "MIIEowIBAAKCAQEA")
+
+	result = tests.RunTest(t, []string{"audit", "orphans", "--format", "json"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	var output struct {
+		Executions []struct {
+			ID    string `json:"id"`
+			Error string `json:"error"`
+		} `json:"executions"`
+	}
+	require.NoError(t, json.Unmarshal(result.Stdout.Bytes(), &output), result.Stdout.String())
+	require.Len(t, output.Executions, 1)
+	assert.Contains(t, output.Executions[0].Error, "[REDACTED]")
+	assert.NotContains(t, output.Executions[0].Error, "MIIEowIBAAKCAQEA")
+}
+
+func TestRedactInvalidPattern(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-redact-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(`
+redact: ["(unclosed"]
+modules:
+  - name: app
+    path: .
+`), 0644))
+
+	result := tests.RunTest(t, []string{"plan"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "Redact: pattern \"(unclosed\"")
+}
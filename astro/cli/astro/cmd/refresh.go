@@ -0,0 +1,95 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+func (cli *AstroCLI) createRefreshCmd() {
+	refreshCmd := &cobra.Command{
+		Use:                   "refresh [flags] [-- [Terraform argument]...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Update the state of modules from the real infrastructure",
+		Long: `Update the state of modules from the real infrastructure, without planning or
+making any changes to it. This runs terraform refresh, or
+terraform apply -refresh-only -auto-approve on Terraform 0.15.4 and later,
+where refresh is deprecated.
+
+Refreshing writes state, so it isn't allowed in read-only mode, and modules
+without a backend need local_state, as for apply.`,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runRefresh,
+	}
+
+	refreshCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to refresh")
+	refreshCmd.PersistentFlags().BoolVar(&cli.flags.noStateMigration, "no-state-migration", false, "don't migrate state for modules with state_migration")
+	refreshCmd.PersistentFlags().StringVar(&cli.flags.groupBy, "group-by", "", "group results by: module")
+	refreshCmd.PersistentFlags().StringVar(&cli.flags.logFile, "log-file", "", "write a log of the run, with the output of every execution, to this file; overrides log_dir")
+	refreshCmd.PersistentFlags().BoolVar(&cli.flags.noNotify, "no-notify", false, "don't send the notification of the run configured in notifications")
+	refreshCmd.PersistentFlags().BoolVar(&cli.flags.strictBinding, "strict-binding", false, "fail if a module's configuration references variables without a value")
+
+	cli.commands.refresh = refreshCmd
+}
+
+func (cli *AstroCLI) runRefresh(cmd *cobra.Command, args []string) error {
+	vars := flagsToUserVariables(cli.flags.projectFlags)
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	stopped, done := cli.stopOnHangup()
+	defer done()
+
+	parameters := astro.ExecutionParameters{
+		ModuleNames:         moduleNames,
+		UserVars:            vars,
+		TerraformParameters: args,
+		SkipStateMigration:  cli.flags.noStateMigration,
+		StrictBinding:       cli.flags.strictBinding,
+		OrderedStatus:       true,
+	}
+
+	status, results, err := cli.project.Refresh(
+		astro.RefreshExecutionParameters{
+			ExecutionParameters: parameters,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+	cli.openRunLog(args)
+
+	err = cli.printResults(status, results, parameters)
+	if isStopped(stopped) {
+		return errors.New("Stopped; some modules may not have been refreshed")
+	}
+	if err != nil {
+		return fmt.Errorf("Done; there were errors%s; some modules may not have been refreshed", cli.versionUnavailableNote())
+	}
+
+	fmt.Fprintln(cli.stdout, "Done")
+
+	return nil
+}
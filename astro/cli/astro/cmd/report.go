@@ -0,0 +1,153 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+func (cli *AstroCLI) createReportCmd() {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report on past sessions",
+	}
+
+	scheduleCmd := &cobra.Command{
+		Use:                   "schedule [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Suggest how to shorten runs, based on past durations",
+		Long: `Simulate running plan or apply on the executions of the project, using how
+long they took in recent sessions, and report the critical path, the
+priorities that would start the longest chains of executions first, and how
+the run time changes with more or less parallelism.
+
+This only reads the configuration and the session repository; it doesn't
+run Terraform or change how astro runs executions. Durations are recorded
+by every plan and apply.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runReportSchedule,
+	}
+
+	scheduleCmd.Flags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to simulate")
+	scheduleCmd.Flags().StringVar(&cli.flags.simulateCommand, "command", "apply", "command to simulate: plan or apply")
+	scheduleCmd.Flags().IntVar(&cli.flags.parallelism, "parallelism", 0, "executions to run at the same time; defaults to the command's")
+	scheduleCmd.Flags().IntVar(&cli.flags.history, "history", 10, "number of recent sessions to take durations from")
+	scheduleCmd.Flags().StringVar(&cli.flags.reportFormat, "format", "text", "output format: text or json")
+
+	reportCmd.AddCommand(scheduleCmd)
+
+	cli.commands.report = reportCmd
+	cli.commands.schedule = scheduleCmd
+}
+
+func (cli *AstroCLI) runReportSchedule(cmd *cobra.Command, args []string) error {
+	if cli.flags.reportFormat != "text" && cli.flags.reportFormat != "json" {
+		return fmt.Errorf("unknown format: %v; must be one of: text, json", cli.flags.reportFormat)
+	}
+	if cli.flags.history < 1 {
+		return fmt.Errorf("invalid --history: %d; must be at least 1", cli.flags.history)
+	}
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	report, err := cli.project.ScheduleReport(astro.ScheduleParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			ModuleNames: moduleNames,
+			UserVars:    flagsToUserVariables(cli.flags.projectFlags),
+		},
+		Command:     cli.flags.simulateCommand,
+		Parallelism: cli.flags.parallelism,
+		History:     cli.flags.history,
+	})
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	if cli.flags.reportFormat == "json" {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to encode report: %v", err)
+		}
+		_, err = fmt.Fprintln(cli.stdout, string(out))
+		return err
+	}
+
+	cli.printScheduleReport(report)
+	return nil
+}
+
+// printScheduleReport prints the schedule report as text.
+func (cli *AstroCLI) printScheduleReport(report *astro.ScheduleReport) {
+	seconds := func(s float64) time.Duration {
+		return time.Duration(s * float64(time.Second))
+	}
+	parallelism := func(p int) string {
+		if p == 0 {
+			return "unlimited"
+		}
+		return fmt.Sprint(p)
+	}
+
+	// Without any history, there is nothing to estimate
+	if report.Sessions == 0 {
+		for _, caveat := range report.Caveats {
+			fmt.Fprintf(cli.stdout, "NOTE: %s\n", caveat)
+		}
+		return
+	}
+
+	fmt.Fprintf(cli.stdout, "Estimated %s time: %v (parallelism: %s), from %d sessions\n", report.Command, seconds(report.EstimatedSeconds), parallelism(report.Parallelism), report.Sessions)
+	if report.Parallelism != 0 {
+		fmt.Fprintf(cli.stdout, "With the suggested priorities: %v\n", seconds(report.SuggestedSeconds))
+	}
+	fmt.Fprintf(cli.stdout, "Critical path: %v: %s\n", seconds(report.CriticalPathSeconds), strings.Join(report.CriticalPath, " -> "))
+
+	fmt.Fprintln(cli.stdout, "\nSuggested priorities:")
+	for _, e := range report.Executions {
+		duration := "no history"
+		if e.Runs > 0 {
+			duration = seconds(e.Seconds).String()
+		}
+		criticalPath := ""
+		if e.CriticalPath {
+			criticalPath = ", critical path"
+		}
+		fmt.Fprintf(cli.stdout, "  %4d  %s (%s%s)\n", e.SuggestedPriority, e.ID, duration, criticalPath)
+	}
+
+	fmt.Fprintln(cli.stdout, "\nParallelism:")
+	for _, estimate := range report.Parallelisms {
+		fmt.Fprintf(cli.stdout, "  %9s  %v\n", parallelism(estimate.Parallelism), seconds(estimate.Seconds))
+	}
+
+	if len(report.Caveats) > 0 {
+		fmt.Fprintln(cli.stdout, "\nNotes:")
+		for _, caveat := range report.Caveats {
+			fmt.Fprintf(cli.stdout, "  - %s\n", caveat)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestReportScheduleWithoutHistory(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"report",
+		"schedule",
+	}, "fixtures/impact", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Equal(t, "NOTE: no durations of apply were found in the session repository; they are recorded by every apply\n", result.Stdout.String())
+}
+
+func TestReportScheduleJSON(t *testing.T) {
+	result := tests.RunTest(t, []string{
+		"report",
+		"schedule",
+		"--command=plan",
+		"--format=json",
+	}, "fixtures/impact", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+
+	var output struct {
+		Command        string   `json:"command"`
+		Parallelism    int      `json:"parallelism"`
+		MissingHistory []string `json:"missing_history"`
+	}
+	require.NoError(t, json.Unmarshal(result.Stdout.Bytes(), &output))
+	assert.Equal(t, "plan", output.Command)
+	assert.Equal(t, 10, output.Parallelism)
+	assert.Equal(t, []string{"network-dev", "app-dev", "app-prod", "dashboard"}, output.MissingHistory)
+}
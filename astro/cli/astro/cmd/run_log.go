@@ -0,0 +1,216 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/uber/astro/astro"
+)
+
+// unsafeFileNameRe matches the characters of a run ID that aren't used in
+// the name of its log file in log_dir, e.g. the slashes of a CI job path.
+var unsafeFileNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// runLogEvent is a line of the run log.
+type runLogEvent struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+
+	// start
+	Command string        `json:"command,omitempty"`
+	Args    []string      `json:"args,omitempty"`
+	RunID   string        `json:"run_id,omitempty"`
+	Config  *runLogConfig `json:"config,omitempty"`
+
+	// status
+	Message string `json:"message,omitempty"`
+
+	// result
+	Execution string            `json:"execution,omitempty"`
+	Module    string            `json:"module,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	Changes   string            `json:"changes,omitempty"`
+	Duration  string            `json:"duration,omitempty"`
+	LogDir    string            `json:"log_dir,omitempty"`
+	LogFile   string            `json:"log_file,omitempty"`
+	Stdout    string            `json:"stdout,omitempty"`
+	Stderr    string            `json:"stderr,omitempty"`
+	PlanCheck string            `json:"plan_check_output,omitempty"`
+
+	// result and end
+	Error string `json:"error,omitempty"`
+}
+
+// runLogConfig is the summary of the configuration in the start event of
+// the run log.
+type runLogConfig struct {
+	TerraformCodeRoot string   `json:"terraform_code_root"`
+	SessionRepoDir    string   `json:"session_repo_dir"`
+	TerraformVersion  string   `json:"terraform_version,omitempty"`
+	Modules           []string `json:"modules"`
+}
+
+// runLog writes the log of a run with --log-file or log_dir: one JSON
+// object per line, for the start of the run with a summary of the
+// configuration, each status update, each result with the full output of
+// its execution, and the end of the run. It is written regardless of
+// --verbose and --quiet. Output and errors are redacted like on screen.
+type runLog struct {
+	path     string
+	file     *os.File
+	enc      *json.Encoder
+	patterns []*regexp.Regexp
+}
+
+// runLogPath returns the path of the log of the run: --log-file if set,
+// otherwise a file named after the run ID in log_dir, or an empty string
+// if neither is set.
+func (cli *AstroCLI) runLogPath() string {
+	if cli.flags.logFile != "" {
+		return cli.flags.logFile
+	}
+	if cli.config.LogDir == "" {
+		return ""
+	}
+	name := unsafeFileNameRe.ReplaceAllString(cli.project.RunID(), "_")
+	return filepath.Join(cli.config.LogDir, fmt.Sprintf("astro-%s-%s.log", cli.commandName, name))
+}
+
+// openRunLog starts the log of the run, if one is configured, once the
+// session of the run has been created. Failing to open it only prints a
+// warning, so that it doesn't change the outcome of the run.
+func (cli *AstroCLI) openRunLog(args []string) {
+	path := cli.runLogPath()
+	if path == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintf(cli.stderr, "WARNING: unable to create the run log: %v\n", err)
+		return
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(cli.stderr, "WARNING: unable to create the run log: %v\n", err)
+		return
+	}
+
+	config := &runLogConfig{
+		TerraformCodeRoot: cli.config.TerraformCodeRoot,
+		SessionRepoDir:    cli.config.SessionRepoDir,
+		Modules:           []string{},
+	}
+	if cli.config.TerraformDefaults.Version != nil {
+		config.TerraformVersion = cli.config.TerraformDefaults.Version.String()
+	}
+	for _, module := range cli.config.Modules {
+		config.Modules = append(config.Modules, module.Name)
+	}
+
+	cli.runLog = &runLog{
+		path:     path,
+		file:     file,
+		enc:      json.NewEncoder(file),
+		patterns: cli.redactPatterns,
+	}
+	cli.runLog.write(runLogEvent{
+		Event:   "start",
+		Command: cli.commandName,
+		Args:    args,
+		RunID:   cli.project.RunID(),
+		Config:  config,
+	})
+}
+
+// closeRunLog ends the log of the run with its error, if any, and prints
+// where it is: after the error of a failed run, or at the end of the
+// output of a successful one.
+func (cli *AstroCLI) closeRunLog(err error) {
+	if cli.runLog == nil {
+		return
+	}
+
+	event := runLogEvent{Event: "end"}
+	if err != nil {
+		event.Error = redact(cli.runLog.patterns, err.Error())
+	}
+	cli.runLog.write(event)
+	if closeErr := cli.runLog.file.Close(); closeErr != nil {
+		fmt.Fprintf(cli.stderr, "WARNING: unable to write the run log: %v\n", closeErr)
+	}
+
+	if err != nil {
+		fmt.Fprintf(cli.stderr, "NOTE: The log of the run is in %s.\n", cli.runLog.path)
+	} else if !cli.flags.quiet {
+		fmt.Fprintf(cli.stdout, "Run log: %s\n", cli.runLog.path)
+	}
+	cli.runLog = nil
+}
+
+// status logs a status update. It does nothing on a nil runLog.
+func (l *runLog) status(update string) {
+	if l == nil {
+		return
+	}
+	l.write(runLogEvent{Event: "status", Message: redact(l.patterns, update)})
+}
+
+// result logs a result, with the output of its execution. It does nothing
+// on a nil runLog.
+func (l *runLog) result(result *astro.Result) {
+	if l == nil {
+		return
+	}
+
+	row := newSummaryRow(result)
+	event := runLogEvent{
+		Event:     "result",
+		Execution: result.ID(),
+		Module:    result.Module(),
+		Variables: result.Variables(),
+		Status:    row.status,
+		Changes:   row.changes,
+		LogDir:    result.LogDir(),
+		LogFile:   result.LogFile(),
+	}
+	if result.Duration() > 0 {
+		event.Duration = result.Duration().String()
+	}
+	if result.Err() != nil {
+		event.Error = redact(l.patterns, result.Err().Error())
+	}
+	if terraformResult := result.TerraformResult(); terraformResult != nil {
+		event.Stdout = redact(l.patterns, terraformResult.Stdout())
+		event.Stderr = redact(l.patterns, terraformResult.Stderr())
+	}
+	event.PlanCheck = redact(l.patterns, result.PlanCheckOutput())
+	l.write(event)
+}
+
+// write writes an event, timestamped now. Errors are ignored, so that e.g.
+// a full disk doesn't change the outcome of the run.
+func (l *runLog) write(event runLogEvent) {
+	event.Time = time.Now().UTC()
+	l.enc.Encode(event)
+}
@@ -0,0 +1,138 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+// readRunLog returns the events in the run log at path.
+func readRunLog(t *testing.T, path string) []map[string]interface{} {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	events := []map[string]interface{}{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+	return events
+}
+
+func TestRunLog(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-run-log-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	okSpec := filepath.Join(tmpdir, "ok.yaml")
+	require.NoError(t, ioutil.WriteFile(okSpec, []byte(`
+commands:
+  apply:
+    stdout: "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.\n"
+`), 0644))
+	failSpec := filepath.Join(tmpdir, "fail.yaml")
+	require.NoError(t, ioutil.WriteFile(failSpec, []byte(`
+commands:
+  apply:
+    exit_code: 1
+    stderr: "Error: apply failed\n"
+`), 0644))
+	okTerraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "ok"), okSpec)
+	failTerraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "fail"), failSpec)
+
+	config := `
+terraform:
+  path: %s
+log_dir: logs
+modules:
+  - name: db
+    path: .
+    local_state: ephemeral
+  - name: network
+    path: .
+    local_state: ephemeral
+    terraform:
+      path: %s
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(config, okTerraform, okTerraform)), 0644))
+
+	// --log-file is written to regardless of verbosity
+	logFile := filepath.Join(tmpdir, "run.log")
+	result := tests.RunTest(t, []string{"apply", "--log-file", logFile, "--run-id", "ci/42", "--no-color"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "Done\nRun log: "+logFile+"\n")
+
+	events := readRunLog(t, logFile)
+	require.True(t, len(events) >= 4)
+	assert.Equal(t, "start", events[0]["event"])
+	assert.Equal(t, "apply", events[0]["command"])
+	assert.Equal(t, "ci/42", events[0]["run_id"])
+	assert.Equal(t, []interface{}{"db", "network"}, events[0]["config"].(map[string]interface{})["modules"])
+	assert.Equal(t, "end", events[len(events)-1]["event"])
+	assert.Nil(t, events[len(events)-1]["error"])
+
+	statuses := 0
+	for _, event := range events {
+		switch event["event"] {
+		case "status":
+			statuses++
+		case "result":
+			assert.Equal(t, "OK", event["status"])
+			assert.Equal(t, "1 added, 0 changed, 0 destroyed", event["changes"])
+			assert.Contains(t, event["stdout"], "Apply complete!")
+		}
+	}
+	assert.NotZero(t, statuses)
+
+	// log_dir is used without --log-file, and failures point to it
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(config, okTerraform, failTerraform)), 0644))
+	result = tests.RunTest(t, []string{"apply", "--run-id", "ci/43", "--no-color"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+
+	logFile = filepath.Join(tmpdir, "logs", "astro-apply-ci_43.log")
+	assert.Contains(t, result.Stderr.String(), "NOTE: The log of the run is in "+logFile+".\n")
+
+	events = readRunLog(t, logFile)
+	assert.Contains(t, events[len(events)-1]["error"], "Done; there were errors")
+	found := false
+	for _, event := range events {
+		if event["event"] == "result" && event["module"] == "network" {
+			found = true
+			assert.Equal(t, "ERROR", event["status"])
+			assert.Contains(t, event["stderr"], "Error: apply failed")
+			assert.Regexp(t, `/network/logs/apply\.log$`, event["log_file"])
+		}
+	}
+	assert.True(t, found)
+}
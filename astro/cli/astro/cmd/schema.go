@@ -0,0 +1,65 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro/report"
+)
+
+func (cli *AstroCLI) createSchemaCmd() {
+	schemaCmd := &cobra.Command{
+		Use:                   "schema [document-type]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print the JSON Schema of astro's machine-readable output",
+		Long: `Print the JSON Schema of a document that astro writes for other tools to
+read, e.g. the output of a command with --format json, so that the tools
+can validate it. Without a document type, list the document types.
+
+Every document has a schema_version. Its minor version is bumped when
+fields are added, and its major version when fields are removed or change
+meaning.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: cli.runSchema,
+	}
+
+	cli.commands.schema = schemaCmd
+}
+
+func (cli *AstroCLI) runSchema(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		for _, name := range report.DocumentTypes() {
+			fmt.Fprintln(cli.stdout, name)
+		}
+		return nil
+	}
+
+	schema, err := report.SchemaFor(args[0])
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode schema: %v", err)
+	}
+	_, err = fmt.Fprintln(cli.stdout, string(out))
+	return err
+}
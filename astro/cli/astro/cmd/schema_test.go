@@ -0,0 +1,112 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/report"
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+// assertValidDocument asserts that the JSON document validates against the
+// schema of the document type.
+func assertValidDocument(t *testing.T, documentType string, document []byte) {
+	schema, err := report.SchemaFor(documentType)
+	require.NoError(t, err)
+	assert.NoError(t, report.Validate(schema, document), string(document))
+
+	var header struct {
+		SchemaVersion interface{} `json:"schema_version"`
+	}
+	require.NoError(t, json.Unmarshal(document, &header))
+	assert.NotEmpty(t, header.SchemaVersion, documentType)
+}
+
+func TestSchema(t *testing.T) {
+	result := tests.RunTest(t, []string{"schema"}, "fixtures/impact", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Equal(t, "compat\nimpact\nmanifest\norphans\nschedule\n", result.Stdout.String())
+
+	result = tests.RunTest(t, []string{"schema", "impact"}, "fixtures/impact", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(result.Stdout.Bytes(), &schema))
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", schema["$schema"])
+	assert.Equal(t, "impact", schema["title"])
+	assert.Contains(t, schema["properties"], "levels")
+
+	result = tests.RunTest(t, []string{"schema", "plan"}, "fixtures/impact", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "unknown document type: plan")
+}
+
+func TestSchemaMatchesOutput(t *testing.T) {
+	tt := []struct {
+		documentType string
+		args         []string
+		dir          string
+	}{
+		{documentType: "compat", args: []string{"compat", "--target-version=0.12.6", "--format=json"}, dir: "fixtures/compat"},
+		{documentType: "impact", args: []string{"impact", "--format=json", "network"}, dir: "fixtures/impact"},
+		{documentType: "schedule", args: []string{"report", "schedule", "--format=json"}, dir: "fixtures/impact"},
+	}
+
+	for _, test := range tt {
+		t.Run(test.documentType, func(t *testing.T) {
+			result := tests.RunTest(t, test.args, test.dir, tests.VERSION_LATEST)
+			require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+			assertValidDocument(t, test.documentType, result.Stdout.Bytes())
+		})
+	}
+}
+
+func TestSchemaMatchesOrphansAndManifest(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-schema-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "../../../terraform/fixtures/mock-terraform/orphans.yaml")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+`, terraformPath)), 0644))
+
+	result := tests.RunTest(t, []string{"audit", "orphans", "--format", "json"}, tmpdir, tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assertValidDocument(t, "orphans", result.Stdout.Bytes())
+
+	manifests, err := filepath.Glob(filepath.Join(tmpdir, ".astro", "*", "manifest.json"))
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	manifest, err := ioutil.ReadFile(manifests[0])
+	require.NoError(t, err)
+	assertValidDocument(t, "manifest", manifest)
+}
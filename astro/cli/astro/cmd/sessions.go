@@ -0,0 +1,215 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+func (cli *AstroCLI) createSessionsCmd() {
+	sessionsCmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Inspect the sessions in the session repo",
+	}
+
+	verifyCmd := &cobra.Command{
+		Use:                   "verify [flags] <session-id>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Compare the sandboxes of a session with the Terraform code",
+		Long: `Compare the sandbox of every execution in a session with the Terraform code
+it was cloned from. Sandbox files are hard links to the code, which editors
+that save files by replacing them break. For each execution, this reports
+which files are still linked, which have diverged, and which only exist on
+one side.
+
+With --repair, diverged files are replaced with links to the code, after
+confirmation.`,
+		Args: cobra.ExactArgs(1),
+		RunE: cli.runSessionsVerify,
+	}
+
+	verifyCmd.Flags().BoolVar(&cli.flags.repair, "repair", false, "re-link diverged files to the Terraform code")
+
+	showCmd := &cobra.Command{
+		Use:                   "show <session-id>",
+		DisableFlagsInUseLine: true,
+		Short:                 "List the executions and attachments of a session",
+		Args:                  cobra.ExactArgs(1),
+		RunE:                  cli.runSessionsShow,
+	}
+
+	attachCmd := &cobra.Command{
+		Use:                   "attach [flags] <session-id>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Attach a file to a session",
+		Long: `Copy a file, e.g. a change ticket or the output of a manual check, into the
+attachments directory of a session, so that it is kept along with the logs
+and plans of the run. The session manifest records who attached the file,
+when, and the optional label.
+
+With --execution, the file is attached to that execution of the session.
+Files larger than max_attachment_size in the config (10 MiB by default) are
+rejected.`,
+		Args: cobra.ExactArgs(1),
+		RunE: cli.runSessionsAttach,
+	}
+
+	attachCmd.Flags().StringVar(&cli.flags.attachFile, "file", "", "path of the file to attach")
+	attachCmd.Flags().StringVar(&cli.flags.attachExecution, "execution", "", "ID of the execution to attach the file to")
+	attachCmd.Flags().StringVar(&cli.flags.attachLabel, "label", "", "description of the file")
+
+	sessionsCmd.AddCommand(verifyCmd, showCmd, attachCmd)
+
+	cli.commands.sessions = sessionsCmd
+}
+
+func (cli *AstroCLI) runSessionsVerify(cmd *cobra.Command, args []string) error {
+	if cli.config == nil {
+		return fmt.Errorf("unable to find config file")
+	}
+
+	repo, err := astro.OpenSessionRepo(cli.config)
+	if err != nil {
+		return err
+	}
+
+	session, err := repo.Open(args[0])
+	if err != nil {
+		return err
+	}
+
+	verifications, err := session.VerifySandboxes()
+	if err != nil {
+		return err
+	}
+
+	diverged := 0
+	for _, v := range verifications {
+		fmt.Fprintf(cli.stdout, "%s: %d linked, %d copied, %d diverged, %d only in source, %d only in sandbox\n",
+			v.ID, len(v.Linked), len(v.Copied), len(v.Diverged), len(v.SourceOnly), len(v.SandboxOnly))
+		for _, path := range v.Diverged {
+			fmt.Fprintf(cli.stdout, "  diverged: %s\n", path)
+		}
+		for _, path := range v.SourceOnly {
+			fmt.Fprintf(cli.stdout, "  only in source: %s\n", path)
+		}
+		for _, path := range v.SandboxOnly {
+			fmt.Fprintf(cli.stdout, "  only in sandbox: %s\n", path)
+		}
+		diverged += len(v.Diverged)
+	}
+
+	if !cli.flags.repair || diverged == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(cli.stdout, "Replace %d diverged file(s) with links to the Terraform code? [y/N] ", diverged)
+	answer, _ := bufio.NewReader(cli.stdin).ReadString('\n')
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		fmt.Fprintln(cli.stdout, "Not repairing")
+		return nil
+	}
+
+	for _, v := range verifications {
+		if err := v.Repair(); err != nil {
+			return fmt.Errorf("unable to repair sandbox of %s: %v", v.ID, err)
+		}
+	}
+	fmt.Fprintf(cli.stdout, "Repaired %d file(s)\n", diverged)
+
+	return nil
+}
+
+func (cli *AstroCLI) runSessionsShow(cmd *cobra.Command, args []string) error {
+	if cli.config == nil {
+		return fmt.Errorf("unable to find config file")
+	}
+
+	repo, err := astro.OpenSessionRepo(cli.config)
+	if err != nil {
+		return err
+	}
+
+	session, err := repo.Open(args[0])
+	if err != nil {
+		return err
+	}
+
+	executions, err := session.AllExecutionPaths()
+	if err != nil {
+		return err
+	}
+	manifest, err := session.Manifest()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.stdout, "Session %s\n", session.ID())
+	if manifest.RunID != "" {
+		fmt.Fprintf(cli.stdout, "Run ID: %s\n", manifest.RunID)
+	}
+	fmt.Fprintln(cli.stdout, "Executions:")
+	for _, paths := range executions {
+		fmt.Fprintf(cli.stdout, "  %s\n", paths.ID)
+	}
+	fmt.Fprintln(cli.stdout, "Attachments:")
+	for _, attachment := range manifest.Attachments {
+		fmt.Fprintf(cli.stdout, "  %s (%d bytes), attached by %s at %s",
+			attachment.Path, attachment.Size, attachment.AttachedBy, attachment.AttachedAt.Format(time.RFC3339))
+		if attachment.Label != "" {
+			fmt.Fprintf(cli.stdout, ": %s", attachment.Label)
+		}
+		fmt.Fprintln(cli.stdout)
+	}
+
+	return nil
+}
+
+func (cli *AstroCLI) runSessionsAttach(cmd *cobra.Command, args []string) error {
+	if cli.config == nil {
+		return fmt.Errorf("unable to find config file")
+	}
+
+	if cli.flags.attachFile == "" {
+		return fmt.Errorf("--file is required")
+	}
+
+	repo, err := astro.OpenSessionRepo(cli.config)
+	if err != nil {
+		return err
+	}
+
+	session, err := repo.Open(args[0])
+	if err != nil {
+		return err
+	}
+
+	attachment, err := session.Attach(cli.flags.attachFile, cli.flags.attachExecution, cli.flags.attachLabel)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.stdout, "Attached %s to session %s as %s\n", attachment.Source, session.ID(), attachment.Path)
+
+	return nil
+}
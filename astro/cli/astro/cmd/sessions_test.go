@@ -0,0 +1,121 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestSessionsVerify(t *testing.T) {
+	sandbox, err := filepath.Abs("fixtures/sessions-verify/.astro/01CJ61AKPDBJ59A4RRNRY7TPF3/app/sandbox")
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Join(sandbox, "../../.."))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(sandbox, "app"), 0755))
+	require.NoError(t, os.Link("fixtures/sessions-verify/astro.yaml", filepath.Join(sandbox, "astro.yaml")))
+	require.NoError(t, os.Link("fixtures/sessions-verify/app/main.tf", filepath.Join(sandbox, "app/main.tf")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sandbox, "app/outputs.tf"), []byte("# changed\n"), 0644))
+
+	result := tests.RunTest(t, []string{"sessions", "verify", "01CJ61AKPDBJ59A4RRNRY7TPF3"}, "fixtures/sessions-verify", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Equal(t, ""+
+		"app: 2 linked, 0 copied, 1 diverged, 0 only in source, 0 only in sandbox\n"+
+		"  diverged: app/outputs.tf\n",
+		result.Stdout.String())
+}
+
+func TestSessionsVerifyUnknownSession(t *testing.T) {
+	sessionPath, err := filepath.Abs("fixtures/sessions-verify/.astro/01CJ61AKPDBJ59A4RRNRY7TPF3")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(sessionPath, 0755))
+	defer os.RemoveAll(filepath.Dir(sessionPath))
+
+	result := tests.RunTest(t, []string{"sessions", "verify", "01CJ5ZB0J9R9ZK9BQBX5BXH5TK"}, "fixtures/sessions-verify", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "session 01CJ5ZB0J9R9ZK9BQBX5BXH5TK not found")
+}
+
+func TestSessionsAttach(t *testing.T) {
+	sessionPath, err := filepath.Abs("fixtures/sessions-attach/.astro/01CJ61AKPDBJ59A4RRNRY7TPF3")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(sessionPath, "app/sandbox/app"), 0755))
+	defer os.RemoveAll(filepath.Dir(sessionPath))
+
+	ticket, err := filepath.Abs("fixtures/sessions-attach/ticket.txt")
+	require.NoError(t, err)
+
+	result := tests.RunTest(t, []string{"sessions", "attach", "--file", ticket, "--execution", "app", "--label", "change ticket", "01CJ61AKPDBJ59A4RRNRY7TPF3"}, "fixtures/sessions-attach", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Equal(t, "Attached "+ticket+" to session 01CJ61AKPDBJ59A4RRNRY7TPF3 as attachments/app/ticket.txt\n", result.Stdout.String())
+
+	b, err := ioutil.ReadFile(filepath.Join(sessionPath, "attachments/app/ticket.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "CHANGE-1234 approved\n", string(b))
+
+	result = tests.RunTest(t, []string{"sessions", "show", "01CJ61AKPDBJ59A4RRNRY7TPF3"}, "fixtures/sessions-attach", tests.VERSION_LATEST)
+	require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+	assert.Contains(t, result.Stdout.String(), "Executions:\n  app\n")
+	assert.Regexp(t, `Attachments:\n  attachments/app/ticket.txt \(21 bytes\), attached by .+ at .+: change ticket\n`, result.Stdout.String())
+
+	t.Run("already attached", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"sessions", "attach", "--file", ticket, "--execution", "app", "01CJ61AKPDBJ59A4RRNRY7TPF3"}, "fixtures/sessions-attach", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), "ticket.txt is already attached to the session")
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"sessions", "attach", "--file", "large.txt", "01CJ61AKPDBJ59A4RRNRY7TPF3"}, "fixtures/sessions-attach", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), "larger than the maximum attachment size of 64 bytes")
+	})
+
+	t.Run("unknown execution", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"sessions", "attach", "--file", ticket, "--execution", "db", "01CJ61AKPDBJ59A4RRNRY7TPF3"}, "fixtures/sessions-attach", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), `"db" is not an execution of any module in the configuration`)
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		result := tests.RunTest(t, []string{"sessions", "attach", "--file", ticket, "01CJ5ZB0J9R9ZK9BQBX5BXH5TK"}, "fixtures/sessions-attach", tests.VERSION_LATEST)
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, result.Stderr.String(), "session 01CJ5ZB0J9R9ZK9BQBX5BXH5TK not found")
+	})
+}
+
+func TestSessionsShowNewerManifest(t *testing.T) {
+	sessionPath, err := filepath.Abs("fixtures/sessions-verify/.astro/01CJ61AKPDBJ59A4RRNRY7TPF3")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(sessionPath, 0755))
+	defer os.RemoveAll(filepath.Dir(sessionPath))
+
+	manifest, err := ioutil.ReadFile("../../../fixtures/manifests/v2.json")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sessionPath, "manifest.json"), manifest, 0644))
+
+	result := tests.RunTest(t, []string{"sessions", "show", "01CJ61AKPDBJ59A4RRNRY7TPF3"}, "fixtures/sessions-verify", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "session 01CJ61AKPDBJ59A4RRNRY7TPF3 was written by a newer version of astro: its manifest has schema version 2.0, and this version of astro only reads schema version 1.x")
+	assert.Contains(t, result.Stderr.String(), `NOTE: Run "astro sessions show" with the version of astro that wrote session 01CJ61AKPDBJ59A4RRNRY7TPF3, or a newer one.`)
+}
@@ -0,0 +1,65 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// stopOnHangup gracefully stops the project when the process receives a
+// SIGHUP, e.g. when the terminal running astro is closed: no new modules are
+// started, but modules that are already running are allowed to finish. It
+// returns a channel that is closed once that happens, and a function that
+// stops listening for the signal.
+//
+// astro has no watch mode yet; once it does, SIGHUP there should reload the
+// configuration for the next iteration instead.
+func (cli *AstroCLI) stopOnHangup() (stopped <-chan struct{}, done func()) {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGHUP)
+
+	stoppedChan := make(chan struct{})
+	doneChan := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signalChan:
+			fmt.Fprintf(cli.stdout, "\nReceived signal: %s, waiting for running operations to finish...\n", sig)
+			cli.project.Stop()
+			close(stoppedChan)
+		case <-doneChan:
+		}
+	}()
+
+	return stoppedChan, func() {
+		signal.Stop(signalChan)
+		close(doneChan)
+	}
+}
+
+// isStopped returns whether the stopped channel has been closed.
+func isStopped(stopped <-chan struct{}) bool {
+	select {
+	case <-stopped:
+		return true
+	default:
+		return false
+	}
+}
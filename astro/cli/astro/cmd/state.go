@@ -0,0 +1,137 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+// stateSubcommands are the `terraform state` subcommands that astro state
+// passes through to, and whether they write to the state.
+var stateSubcommands = []struct {
+	name        string
+	short       string
+	writesState bool
+}{
+	{"list", "List the resources in the state", false},
+	{"mv", "Move an item in the state", true},
+	{"pull", "Print the state", false},
+	{"push", "Replace the state with a local state file", true},
+	{"rm", "Remove items from the state", true},
+}
+
+func (cli *AstroCLI) createStateCmd() {
+	stateCmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and modify the Terraform state of a module",
+		Long: `Run terraform state on the state of a module, with the backend configuration
+astro would use. The variable flags must narrow the module down to a single
+execution.
+
+The subcommands that modify the state, mv, push and rm, print where the state
+is stored and require --yes.`,
+		PersistentPreRunE: cli.preRun,
+	}
+
+	stateCmd.PersistentFlags().StringVar(&cli.flags.moduleName, "module", "", "module whose state to operate on (required)")
+	stateCmd.PersistentFlags().BoolVar(&cli.flags.noStateMigration, "no-state-migration", false, "don't migrate state for modules with state_migration")
+
+	for _, subcommand := range stateSubcommands {
+		subcommandCmd := &cobra.Command{
+			Use:                   fmt.Sprintf("%s --module <name> [flags] [-- [Terraform argument]...]", subcommand.name),
+			DisableFlagsInUseLine: true,
+			Short:                 subcommand.short,
+			RunE:                  cli.runState(subcommand.name, subcommand.writesState),
+		}
+		if subcommand.writesState {
+			subcommandCmd.Flags().BoolVar(&cli.flags.yes, "yes", false, "confirm that the state should be modified")
+		}
+		stateCmd.AddCommand(subcommandCmd)
+	}
+
+	cli.commands.state = stateCmd
+}
+
+func (cli *AstroCLI) runState(subcommand string, writesState bool) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cli.flags.moduleName == "" {
+			return errors.New("ERROR: --module is required")
+		}
+
+		// Terraform runs in the sandbox, so the state file to push must be
+		// an absolute path
+		if subcommand == "push" && len(args) > 0 && args[len(args)-1] != "-" {
+			path, err := filepath.Abs(args[len(args)-1])
+			if err != nil {
+				return fmt.Errorf("ERROR: %v", err)
+			}
+			args[len(args)-1] = path
+		}
+
+		parameters := astro.StateExecutionParameters{
+			ExecutionParameters: astro.ExecutionParameters{
+				UserVars:           flagsToUserVariables(cli.flags.projectFlags),
+				SkipStateMigration: cli.flags.noStateMigration,
+				OrderedStatus:      true,
+			},
+			Module: cli.flags.moduleName,
+			Args:   append([]string{subcommand}, args...),
+		}
+
+		if writesState {
+			id, location, err := cli.project.StateLocation(parameters)
+			if err != nil {
+				return fmt.Errorf("ERROR: %v", cli.processError(err))
+			}
+			if location == nil {
+				location = []string{"the execution's sandbox"}
+			}
+			fmt.Fprintf(cli.stderr, "State of %s: %s\n", id, strings.Join(location, ", "))
+			if !cli.flags.yes {
+				return fmt.Errorf("ERROR: state %s modifies the state of %s; pass --yes to confirm", subcommand, id)
+			}
+		}
+
+		status, results, err := cli.project.State(parameters)
+		if err != nil {
+			return fmt.Errorf("ERROR: %v", cli.processError(err))
+		}
+
+		failed := false
+		cli.readResults(status, results, func(result *astro.Result) {
+			if result.Err() != nil {
+				failed = true
+				view := newResultView(result, cli.policyDiffFormat, cli.redactPatterns, cli.flags.compactPlan, cli.colors)
+				fmt.Fprintf(cli.stderr, "%s: %s\n", result.ID(), view.summary)
+				fmt.Fprint(cli.stderr, view.details)
+				return
+			}
+			fmt.Fprint(cli.stdout, result.TerraformResult().Stdout())
+		})
+		if failed {
+			return fmt.Errorf("ERROR: terraform state %s failed%s", subcommand, cli.versionUnavailableNote())
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestStateModifyRequiresYes(t *testing.T) {
+	result := tests.RunTest(t, []string{"state", "rm", "--module=app", "--environment=dev", "null_resource.foo"}, "fixtures/state", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "State of app-dev: backend=local, path=/tmp/terraform-tests/state-dev.tfstate\n")
+	assert.Contains(t, result.Stderr.String(), "state rm modifies the state of app-dev; pass --yes to confirm")
+}
+
+func TestStateNeedsOneExecution(t *testing.T) {
+	result := tests.RunTest(t, []string{"state", "list", "--module=app"}, "fixtures/state", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), `state needs exactly one execution of module "app", but the variables match 2: app-dev, app-prod`)
+}
+
+func TestStateModuleRequired(t *testing.T) {
+	result := tests.RunTest(t, []string{"state", "list", "--environment=dev"}, "fixtures/state", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "--module is required")
+}
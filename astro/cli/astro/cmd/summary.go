@@ -0,0 +1,162 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/terraform"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// Statuses of executions in the summary.
+const (
+	summaryOK      = "OK"
+	summaryError   = "ERROR"
+	summarySkipped = "SKIPPED"
+)
+
+// applySummaryRe matches the resources changed in the output of `terraform
+// apply`, e.g. "Apply complete! Resources: 1 added, 0 changed, 0 destroyed."
+var applySummaryRe = regexp.MustCompile(`Apply complete! Resources: ([^.]+)\.`)
+
+// summaryRow is a row of the summary of a run.
+type summaryRow struct {
+	id      string
+	status  string
+	changes string
+	runtime string
+	logs    string
+}
+
+// newSummaryRow returns the row of the summary for the result.
+func newSummaryRow(result *astro.Result) summaryRow {
+	row := summaryRow{
+		id:      result.ID(),
+		status:  summaryOK,
+		changes: "-",
+		runtime: "-",
+		logs:    result.LogDir(),
+	}
+
+	switch {
+	case result.Skipped():
+		row.status = summarySkipped
+		row.changes = "Not run"
+		if skipped := result.Err().(*astro.SkippedError); skipped.FailedDependency != "" {
+			row.changes = fmt.Sprintf("Not run: dependency %s failed", skipped.FailedDependency)
+		}
+	case result.UnchangedSince() != "":
+		row.status = summarySkipped
+		row.changes = fmt.Sprintf("Unchanged since session %s", result.UnchangedSince())
+	case result.Err() != nil:
+		row.status = summaryError
+	}
+
+	if planResult, ok := result.TerraformResult().(*terraform.PlanResult); ok && planResult != nil && result.Err() == nil {
+		if !planResult.HasChanges() {
+			row.changes = "No changes"
+		} else if !result.HasChanges() {
+			row.changes = "Ignored by changes_policy"
+		} else if counts, ok := planResult.ResourceChanges(); ok {
+			row.changes = fmt.Sprintf("%d to add, %d to change, %d to destroy", counts.Add, counts.Change, counts.Destroy)
+		} else {
+			row.changes = "Changes"
+		}
+	} else if result.TerraformResult() != nil && result.Err() == nil {
+		if match := applySummaryRe.FindStringSubmatch(result.TerraformResult().Stdout()); match != nil {
+			row.changes = match[1]
+		}
+	}
+
+	if result.Duration() > 0 {
+		row.runtime = result.Duration().Truncate(time.Second).String()
+	}
+	if row.logs == "" {
+		row.logs = "-"
+	}
+
+	return row
+}
+
+// summaryOrder is the order of the statuses in the summary: failures last,
+// so that they are next to the prompt.
+var summaryOrder = map[string]int{summaryOK: 0, summarySkipped: 1, summaryError: 2}
+
+// printSummary prints a table of the results that have been read, with the
+// status, changes, runtime and logs of each execution, and a line with the
+// totals, e.g. "28 ok, 1 failed, 1 skipped in 6m32s". On GitHub Actions, it
+// is also written to the step summary. With --quiet, only the totals are
+// printed.
+func (cli *AstroCLI) printSummary(elapsed time.Duration) {
+	if len(cli.runResults) == 0 {
+		return
+	}
+	au := aurora.NewAurora(cli.colors)
+
+	rows := []summaryRow{}
+	counts := map[string]int{}
+	for _, result := range cli.runResults {
+		row := newSummaryRow(result)
+		rows = append(rows, row)
+		counts[row.status]++
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if summaryOrder[rows[i].status] != summaryOrder[rows[j].status] {
+			return summaryOrder[rows[i].status] < summaryOrder[rows[j].status]
+		}
+		return rows[i].id < rows[j].id
+	})
+
+	var out io.Writer = cli.stdout
+	if cli.flags.quiet {
+		out = ioutil.Discard
+	}
+
+	fmt.Fprintf(out, "\n%s\n", au.Bold("Summary:"))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  EXECUTION\tSTATUS\tCHANGES\tRUNTIME\tLOGS")
+	for _, row := range rows {
+		// Every status is colored, so that the escape codes don't throw
+		// the alignment off
+		status := au.Green(row.status)
+		if row.status == summaryError {
+			status = au.Red(row.status)
+		} else if row.status == summarySkipped {
+			status = au.Gray(row.status)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", row.id, status, row.changes, row.runtime, row.logs)
+	}
+	w.Flush()
+
+	totals := fmt.Sprintf("%d ok, %d failed, %d skipped in %s", counts[summaryOK], counts[summaryError], counts[summarySkipped], elapsed.Truncate(time.Second))
+	fmt.Fprintln(cli.stdout, totals)
+
+	if cli.ciMode == ciModeGitHub {
+		if err := cli.writeGitHubStepSummary(rows, totals); err != nil {
+			fmt.Fprintf(cli.stderr, "WARNING: %v\n", err)
+		}
+	}
+}
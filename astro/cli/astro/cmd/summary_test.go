@@ -0,0 +1,89 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestSummary(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-summary-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	okSpec := filepath.Join(tmpdir, "ok.yaml")
+	require.NoError(t, ioutil.WriteFile(okSpec, []byte(`
+commands:
+  apply:
+    stdout: "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.\n"
+`), 0644))
+	failSpec := filepath.Join(tmpdir, "fail.yaml")
+	require.NoError(t, ioutil.WriteFile(failSpec, []byte(`
+commands:
+  apply:
+    exit_code: 1
+    stderr: "Error: apply failed\n"
+`), 0644))
+	okTerraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "ok"), okSpec)
+	failTerraform := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "fail"), failSpec)
+
+	// app depends on network, whose apply fails, so it is skipped
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+modules:
+  - name: db
+    path: .
+    local_state: ephemeral
+  - name: network
+    path: .
+    local_state: ephemeral
+    terraform:
+      path: %s
+  - name: app
+    path: .
+    local_state: ephemeral
+    deps:
+      - module: network
+`, okTerraform, failTerraform)), 0644))
+
+	result := tests.RunTest(t, []string{"apply", "--no-color"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+
+	stdout := result.Stdout.String()
+	assert.Contains(t, stdout, "app: SKIPPED (dependency network failed)\n")
+	assert.Regexp(t, `\nSummary:\n`+
+		`  EXECUTION +STATUS +CHANGES +RUNTIME +LOGS\n`+
+		`  db +OK +1 added, 0 changed, 0 destroyed +\d+s +.+/db/logs\n`+
+		`  app +SKIPPED +Not run: dependency network failed +- +-\n`+
+		`  network +ERROR +- +\d+s +.+/network/logs\n`+
+		`1 ok, 1 failed, 1 skipped in \d+s\n`, stdout)
+
+	// the failed execution points to its full log
+	assert.Regexp(t, `network: ERROR.*\n(?s:.*)Error: apply failed\nfull log: .+/network/logs/apply\.log\n`, result.Stderr.String())
+	assert.NotContains(t, stdout, "full log:")
+}
@@ -0,0 +1,128 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+func (cli *AstroCLI) createTaintCmd() {
+	taintCmd := &cobra.Command{
+		Use:                   "taint --module <name> [flags] <address>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Mark a resource of a module for replacement",
+		Long: `Mark the resource address, e.g. aws_instance.web, in the state of a module
+for replacement on its next apply. The variable flags must narrow the module
+down to a single execution.
+
+Terraform 0.15.2 and later deprecate taint. With these versions, astro
+doesn't change the state; instead, it passes -replace=<address> to the next
+plan and apply of the execution.`,
+		Args:              cobra.ExactArgs(1),
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runTaint("taint"),
+	}
+
+	taintCmd.PersistentFlags().StringVar(&cli.flags.moduleName, "module", "", "module of the resource (required)")
+	taintCmd.PersistentFlags().BoolVar(&cli.flags.noStateMigration, "no-state-migration", false, "don't migrate state for modules with state_migration")
+
+	cli.commands.taint = taintCmd
+}
+
+func (cli *AstroCLI) createUntaintCmd() {
+	untaintCmd := &cobra.Command{
+		Use:                   "untaint --module <name> [flags] <address>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Undo taint of a resource of a module",
+		Long: `Unmark the resource address, e.g. aws_instance.web, in the state of a module,
+so that its next apply doesn't replace it. The variable flags must narrow the
+module down to a single execution.`,
+		Args:              cobra.ExactArgs(1),
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runTaint("untaint"),
+	}
+
+	untaintCmd.PersistentFlags().StringVar(&cli.flags.moduleName, "module", "", "module of the resource (required)")
+	untaintCmd.PersistentFlags().BoolVar(&cli.flags.noStateMigration, "no-state-migration", false, "don't migrate state for modules with state_migration")
+
+	cli.commands.untaint = untaintCmd
+}
+
+func (cli *AstroCLI) runTaint(command string) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cli.flags.moduleName == "" {
+			return errors.New("ERROR: --module is required")
+		}
+
+		parameters := astro.TaintExecutionParameters{
+			ExecutionParameters: astro.ExecutionParameters{
+				UserVars:           flagsToUserVariables(cli.flags.projectFlags),
+				SkipStateMigration: cli.flags.noStateMigration,
+				OrderedStatus:      true,
+			},
+			Module:  cli.flags.moduleName,
+			Address: args[0],
+		}
+
+		run := cli.project.Taint
+		if command == "untaint" {
+			run = cli.project.Untaint
+		}
+
+		status, results, err := run(parameters)
+		if err != nil {
+			return fmt.Errorf("ERROR: %v", cli.processError(err))
+		}
+
+		var errs error
+		cli.readResults(status, results, func(result *astro.Result) {
+			if result.Err() != nil {
+				errs = multierror.Append(errs, result.Err())
+			}
+
+			view := newResultView(result, cli.policyDiffFormat, cli.redactPatterns, cli.flags.compactPlan, cli.colors)
+
+			out := cli.stdout
+			if view.failed {
+				out = cli.stderr
+			}
+
+			fmt.Fprintf(out, "%s: %s\n", result.ID(), view.summary)
+			fmt.Fprint(out, view.details)
+
+			if result.ReplacementRecorded() {
+				if command == "untaint" {
+					fmt.Fprintf(out, "%s will no longer be replaced on the next apply of %s\n", args[0], result.ID())
+				} else {
+					fmt.Fprintf(out, "Terraform deprecates taint; %s will be replaced with -replace=%s on the next plan and apply of %s\n", args[0], args[0], result.ID())
+				}
+			}
+		})
+		if errs != nil {
+			return fmt.Errorf("Done; there were errors%s", cli.versionUnavailableNote())
+		}
+
+		fmt.Fprintln(cli.stdout, "Done")
+
+		return nil
+	}
+}
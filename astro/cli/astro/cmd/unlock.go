@@ -0,0 +1,79 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro"
+)
+
+func (cli *AstroCLI) createUnlockCmd() {
+	unlockCmd := &cobra.Command{
+		Use:                   "unlock --module <name> [flags] <lock-id>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Release a stuck state lock of a module",
+		Long: `Release the state lock with the lock ID in the state of a module, e.g. a
+lock left behind by an apply that was killed, with terraform force-unlock.
+The variable flags must narrow the module down to a single execution.
+
+When a plan or apply fails because the state is locked, astro prints the
+lock ID and the unlock command to run. Only release a lock if no other
+Terraform run holds it.`,
+		Args:              cobra.ExactArgs(1),
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runUnlock,
+	}
+
+	unlockCmd.PersistentFlags().StringVar(&cli.flags.moduleName, "module", "", "module whose state to unlock (required)")
+	unlockCmd.PersistentFlags().BoolVar(&cli.flags.noStateMigration, "no-state-migration", false, "don't migrate state for modules with state_migration")
+
+	cli.commands.unlock = unlockCmd
+}
+
+func (cli *AstroCLI) runUnlock(cmd *cobra.Command, args []string) error {
+	if cli.flags.moduleName == "" {
+		return errors.New("ERROR: --module is required")
+	}
+
+	parameters := astro.ExecutionParameters{
+		UserVars:           flagsToUserVariables(cli.flags.projectFlags),
+		SkipStateMigration: cli.flags.noStateMigration,
+		OrderedStatus:      true,
+	}
+
+	status, results, err := cli.project.Unlock(
+		astro.UnlockExecutionParameters{
+			ExecutionParameters: parameters,
+			Module:              cli.flags.moduleName,
+			LockID:              args[0],
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	if err := cli.printResults(status, results, parameters); err != nil {
+		return fmt.Errorf("Done; there were errors%s", cli.versionUnavailableNote())
+	}
+
+	fmt.Fprintln(cli.stdout, "Done")
+
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests"
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestLockedStateShowsUnlockCommand(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-unlock-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(`
+commands:
+  plan:
+    exit_code: 1
+    stderr: "Error: Error acquiring the state lock\n\nLock Info:\n  ID:        4a0d7c3e\n  Operation: OperationTypeApply\n"
+`), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, "astro.yaml"), []byte(fmt.Sprintf(`
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+    variables:
+      - name: environment
+        values: [dev]
+`, terraformPath)), 0644))
+
+	result := tests.RunTest(t, []string{"plan"}, tmpdir, tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "The state is locked by lock ID 4a0d7c3e")
+	assert.Contains(t, result.Stderr.String(), "astro unlock --module app --environment=dev 4a0d7c3e")
+}
+
+func TestUnlockNeedsOneExecution(t *testing.T) {
+	result := tests.RunTest(t, []string{"unlock", "--module=app", "4a0d7c3e"}, "fixtures/state", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), `unlock needs exactly one execution of module "app", but the variables match 2: app-dev, app-prod`)
+}
+
+func TestUnlockModuleRequired(t *testing.T) {
+	result := tests.RunTest(t, []string{"unlock", "--environment=dev", "4a0d7c3e"}, "fixtures/state", tests.VERSION_LATEST)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, result.Stderr.String(), "--module is required")
+}
@@ -0,0 +1,243 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+
+	version "github.com/burl/go-version"
+)
+
+// The checks that compatibility findings come from.
+const (
+	// CompatCheckRequiredVersion checks the required_version constraints in
+	// the module's code against the target version.
+	CompatCheckRequiredVersion = "required_version"
+	// CompatCheckSyntax checks the module's code with `terraform
+	// 0.12checklist`, or `terraform validate` under the target version.
+	CompatCheckSyntax = "syntax"
+	// CompatCheckBackend checks that the module's backend is configured in
+	// a way that the target version supports.
+	CompatCheckBackend = "backend"
+)
+
+// compatErrorLines is the number of lines of the error output of a failed
+// check that findings include; the rest is in the session's logs.
+const compatErrorLines = 3
+
+// CompatParameters selects the modules to check and the Terraform version to
+// check them against.
+type CompatParameters struct {
+	ModuleNames   []string
+	TargetVersion string
+}
+
+// Compat checks whether the modules can be used with the target Terraform
+// version, and returns a report with the findings of each module, in the
+// order of the configuration. It checks required_version, the syntax of the
+// code and the backend configuration. Code is checked in sandboxes that are
+// initialized without a backend, so remote state isn't touched, and nothing
+// is planned or applied.
+func (c *Project) Compat(parameters CompatParameters) (*CompatReport, error) {
+	c.logger.Debugf("astro: running Compat")
+
+	if len(c.config.Modules) == 0 {
+		return nil, ErrNoModules
+	}
+
+	target, err := version.NewVersion(parameters.TargetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target version: %v", err)
+	}
+
+	modules := c.modules(parameters.ModuleNames)
+	if len(modules) == 0 {
+		return nil, errors.New("no modules match the filter")
+	}
+
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CompatReport{
+		SchemaVersion: reportSchemaVersion,
+		TargetVersion: target.String(),
+		Modules:       make([]ModuleCompat, len(modules)),
+	}
+
+	var mu sync.Mutex
+	fns := []func(){}
+	for i, m := range modules {
+		i, moduleConfig := i, m.config // save for use inside the loop
+		fns = append(fns, func() {
+			compat := session.moduleCompat(moduleConfig, target)
+			mu.Lock()
+			report.Modules[i] = compat
+			mu.Unlock()
+		})
+	}
+
+	ctx, cancel := session.context()
+	defer cancel()
+	utils.Parallel(ctx, executionParallelism, fns...)
+
+	return report, nil
+}
+
+// moduleCompat runs the compatibility checks of a module.
+func (s *Session) moduleCompat(moduleConfig *conf.Module, target *version.Version) ModuleCompat {
+	compat := ModuleCompat{
+		Module:   moduleConfig.Name,
+		Findings: []CompatFinding{},
+	}
+	current := moduleConfig.Terraform.Version
+	if current != nil {
+		compat.CurrentVersion = current.String()
+	}
+
+	add := func(check, message string) {
+		compat.Findings = append(compat.Findings, CompatFinding{Check: check, Message: message})
+	}
+
+	moduleDir := filepath.Join(moduleConfig.TerraformCodeRoot, moduleConfig.Path)
+	if constraint, err := terraform.RequiredVersion(moduleDir); err != nil {
+		add(CompatCheckRequiredVersion, fmt.Sprintf("unable to read required_version: %v", err))
+	} else if constraint != "" {
+		if constraints, err := version.NewConstraint(constraint); err != nil {
+			add(CompatCheckRequiredVersion, fmt.Sprintf("invalid required_version %q: %v", constraint, err))
+		} else if !constraints.Check(target) {
+			add(CompatCheckRequiredVersion, fmt.Sprintf("required_version %q doesn't allow %s", constraint, target))
+		}
+	}
+
+	// Terraform 0.9 replaced `terraform remote config` with backend blocks
+	if moduleConfig.Remote.Backend != "" && terraform.VersionMatches(target, ">= 0.9") {
+		add(CompatCheckBackend, fmt.Sprintf("the %s backend is configured with remote.backend, which Terraform 0.9 and later don't support; declare it in a backend block in the code instead", moduleConfig.Remote.Backend))
+	}
+
+	for _, message := range s.checkCompatSyntax(moduleConfig, current, target) {
+		add(CompatCheckSyntax, message)
+	}
+
+	compat.Ready = len(compat.Findings) == 0
+	return compat
+}
+
+// checkCompatSyntax checks the code of the module for syntax that the target
+// version doesn't support, and returns the problems found. Modules upgraded
+// from before 0.12 to 0.12 or later are checked with `terraform
+// 0.12checklist` if their current version has it; others are validated
+// with the target version.
+func (s *Session) checkCompatSyntax(moduleConfig *conf.Module, current, target *version.Version) []string {
+	checkVersion := target
+	checklist := current != nil &&
+		terraform.VersionMatches(current, ">= 0.11.14, < 0.12") &&
+		terraform.VersionMatches(target, ">= 0.12")
+	if checklist {
+		checkVersion = current
+	}
+
+	session, err := s.newCompatTerraformSession(moduleConfig, checkVersion)
+	if err != nil {
+		return []string{fmt.Sprintf("unable to check the code: %v", err)}
+	}
+
+	if checklist {
+		items, result, err := session.Checklist012()
+		if err != nil {
+			return []string{fmt.Sprintf("terraform 0.12checklist failed: %v", compatErrorSummary(result, err))}
+		}
+		return items
+	}
+
+	if result, err := session.Validate(); err != nil {
+		return []string{fmt.Sprintf("terraform %s validate failed: %v", target, compatErrorSummary(result, err))}
+	}
+	return nil
+}
+
+// newCompatTerraformSession returns a Terraform session with a sandbox of
+// the module and the Terraform version to check it with. It has no
+// variables, and is read-only as a safeguard, as nothing should write state.
+func (s *Session) newCompatTerraformSession(moduleConfig *conf.Module, terraformVersion *version.Version) (*terraform.Session, error) {
+	id := fmt.Sprintf("compat-%s-%s", moduleConfig.Name, terraformVersion)
+
+	basePath, modulePath, sandboxInclude := moduleConfig.SandboxLayout()
+	sandboxExclude, err := moduleConfig.SandboxExclude()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read sandbox exclusions: %v", err)
+	}
+
+	terraformPath, err := s.repo.project.terraformBinary(moduleConfig.Terraform.TVMFlavor(), terraformVersion.String())
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := s.tmpDir(id, "terraform")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary directory: %v", err)
+	}
+
+	pluginDir, err := s.sharedPluginDir(terraformVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return terraform.NewTerraformSession(id, filepath.Join(s.path, id), terraform.Config{
+		Name:            moduleConfig.Name,
+		BasePath:        basePath,
+		ModulePath:      modulePath,
+		SandboxInclude:  sandboxInclude,
+		SandboxExclude:  sandboxExclude,
+		Env:             moduleConfig.Env,
+		SensitiveEnv:    moduleConfig.SensitiveEnv,
+		TerraformPath:   terraformPath,
+		TempDir:         tmpDir,
+		SharedPluginDir: pluginDir,
+		DirMode:         s.repo.dirMode,
+		FileMode:        s.repo.fileMode,
+		ReadOnly:        true,
+		Logger:          s.log(),
+	})
+}
+
+// compatErrorSummary returns the first lines of the error output of a failed
+// Terraform command, joined with "; ", or the error if there is none.
+func compatErrorSummary(result terraform.Result, err error) string {
+	if result == nil {
+		return err.Error()
+	}
+	lines := []string{}
+	for _, line := range strings.Split(result.Stderr(), "\n") {
+		if line = strings.TrimSpace(line); line != "" && len(lines) < compatErrorLines {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return err.Error()
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,164 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompat(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-compat-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	code := map[string]string{
+		"ready":  "terraform {\n  backend \"s3\" {}\n}\n",
+		"pinned": "terraform {\n  required_version = \"< 0.13\"\n  backend \"s3\" {}\n}\n\noutput \"region\" {\n  value = var.region\n}\n",
+		"legacy": "terraform {\n  backend \"s3\" {}\n}\n",
+		"remote": "resource \"null_resource\" \"foo\" {}\n",
+	}
+	for module, content := range code {
+		require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, module), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, module, "main.tf"), []byte(content), 0644))
+	}
+
+	// 0.11.14 is only run for 0.12checklist; the other versions record how
+	// they were run
+	specs := map[string]string{
+		"0.11.14": `version: 0.11.14
+commands:
+  0.12checklist:
+    stdout: |
+      After analyzing this configuration and working directory, we have identified some necessary steps:
+
+      - [ ] Upgrade provider "aws" to version 2.7.0 or newer.
+      - [ ] Provider "template" is not compatible with Terraform v0.12 and
+        must be upgraded first.
+
+      Taking these steps before upgrading will simplify the process.
+`,
+		"0.13.7": `version: 0.13.7
+default:
+  write:
+    '{{.Dir}}/terraform-{{.Subcommand}}': "{{join .Args \" \"}}"
+`,
+	}
+	terraformPaths := map[string]string{}
+	for version, spec := range specs {
+		specPath := filepath.Join(tmpdir, version+".yaml")
+		require.NoError(t, ioutil.WriteFile(specPath, []byte(spec), 0644))
+		terraformPaths[version] = mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin", version), specPath)
+	}
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  version: 0.12.6
+modules:
+  - name: ready
+    path: ready
+  - name: pinned
+    path: pinned
+  - name: legacy
+    path: legacy
+    terraform:
+      version: 0.11.14
+  - name: remote
+    path: remote
+    terraform:
+      version: 0.8.8
+    remote:
+      backend: s3
+`, codeRoot, tmpdir)), 0644))
+
+	c, err := NewProjectFromConfigFile(configPath, WithTerraformVersionResolver(versionResolverFunc(func(version string) (string, error) {
+		if path, ok := terraformPaths[version]; ok {
+			return path, nil
+		}
+		return "", fmt.Errorf("unexpected version: %v", version)
+	})))
+	require.NoError(t, err)
+
+	_, err = c.Compat(CompatParameters{TargetVersion: "latest"})
+	assert.EqualError(t, err, "invalid target version: Malformed version: latest")
+
+	report, err := c.Compat(CompatParameters{TargetVersion: "0.13.7"})
+	require.NoError(t, err)
+	assert.Equal(t, &CompatReport{
+		SchemaVersion: reportSchemaVersion,
+		TargetVersion: "0.13.7",
+		Modules: []ModuleCompat{
+			{
+				Module:         "ready",
+				CurrentVersion: "0.12.6",
+				Ready:          true,
+				Findings:       []CompatFinding{},
+			},
+			{
+				Module:         "pinned",
+				CurrentVersion: "0.12.6",
+				Findings: []CompatFinding{
+					{Check: CompatCheckRequiredVersion, Message: `required_version "< 0.13" doesn't allow 0.13.7`},
+				},
+			},
+			{
+				Module:         "legacy",
+				CurrentVersion: "0.11.14",
+				Findings: []CompatFinding{
+					{Check: CompatCheckSyntax, Message: `Upgrade provider "aws" to version 2.7.0 or newer.`},
+					{Check: CompatCheckSyntax, Message: `Provider "template" is not compatible with Terraform v0.12 and must be upgraded first.`},
+				},
+			},
+			{
+				Module:         "remote",
+				CurrentVersion: "0.8.8",
+				Findings: []CompatFinding{
+					{Check: CompatCheckBackend, Message: "the s3 backend is configured with remote.backend, which Terraform 0.9 and later don't support; declare it in a backend block in the code instead"},
+				},
+			},
+		},
+	}, report)
+
+	// Code is validated with the target version, without a backend
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	moduleDir := filepath.Join(session.path, "compat-ready-0.13.7", "sandbox", "ready")
+	b, err := ioutil.ReadFile(filepath.Join(moduleDir, "terraform-init"))
+	require.NoError(t, err)
+	assert.Equal(t, "init -backend=false -input=false", string(b))
+	b, err = ioutil.ReadFile(filepath.Join(moduleDir, "terraform-validate"))
+	require.NoError(t, err)
+	assert.Equal(t, "validate -no-color", string(b))
+
+	// Modules can be selected
+	report, err = c.Compat(CompatParameters{ModuleNames: []string{"pinned"}, TargetVersion: "0.12.31"})
+	require.NoError(t, err)
+	require.Len(t, report.Modules, 1)
+	assert.Equal(t, "pinned", report.Modules[0].Module)
+}
@@ -19,21 +19,141 @@ package conf
 import (
 	"fmt"
 
+	"github.com/uber/astro/astro/utils"
+
+	version "github.com/burl/go-version"
 	multierror "github.com/hashicorp/go-multierror"
 )
 
+// DefaultVersionConcurrency serializes the initialization of Terraform
+// versions before 0.13, which race when populating a shared plugin cache.
+// Later versions are only limited by the number of executions that run in
+// parallel.
+var DefaultVersionConcurrency = map[string]int{"< 0.13": 1}
+
+// DefaultMaxAttachmentSize is the largest file, in bytes, that can be
+// attached to a session if MaxAttachmentSize is not set.
+const DefaultMaxAttachmentSize int64 = 10 << 20
+
+// DefaultPluginCacheWarnSize is the size, in bytes, above which verbose runs
+// print the size of the plugin cache if PluginCacheWarnSize is not set.
+const DefaultPluginCacheWarnSize int64 = 5 << 30
+
 // Project represents the structure of the YAML configuration for astro.
 type Project struct {
+	// ChangeBudget limits the number of resources that plans can change.
+	// Modules can override the per-execution limit and set their own total.
+	ChangeBudget ChangeBudget `json:"change_budget"`
+
+	// ChangesPolicy configures which changes of a plan count when deciding
+	// whether it has changes. By default, all of them do.
+	ChangesPolicy ChangesPolicy `json:"changes_policy,omitempty"`
+
+	// ClockSkewTolerance is how far apart the clocks of the machines
+	// sharing the session repo can be before astro warns that sessions are
+	// out of order. Defaults to DefaultClockSkewTolerance.
+	ClockSkewTolerance ClockSkewTolerance `json:"clock_skew_tolerance,omitempty"`
+
+	// Env is a map of environment variables to set for Terraform in every
+	// module. Modules can override them with their own.
+	Env map[string]string `json:"env,omitempty"`
+
+	// SensitiveEnv is a list of names of environment variables in Env whose
+	// values are masked in logs.
+	SensitiveEnv []string `json:"sensitive_env,omitempty"`
+
+	// Exclude is a list of gitignore-style patterns of paths, relative to
+	// the code root, to leave out of the sandboxes that modules run in, e.g.
+	// "node_modules/" or "/docs". Patterns can also be put in a .astroignore
+	// file at the code root. .terraform, .astro and terraform.tfstate* are
+	// always left out.
+	Exclude []string `json:"exclude,omitempty"`
+
 	// Flags is a mapping of module variable names to user flags, e.g. for on
 	// the CLI.
-	Flags map[string]Flag
+	Flags map[string]Flag `json:"flags"`
 
 	// Hooks contains configuration of hooks that can be invoked at various
 	// stages of the CLI lifecycle.
-	Hooks Hooks
+	Hooks Hooks `json:"hooks"`
+
+	// Includes is a list of other config files to include, relative to this
+	// one. Their modules, flags and hooks are merged into this
+	// configuration, with later files overriding earlier ones and this file
+	// overriding all of them. Modules with the same name are replaced.
+	Includes []string `json:"includes,omitempty"`
+
+	// InitCache caches the result of `terraform init` in the session repo,
+	// keyed by module path, backend configuration and Terraform version,
+	// and restores it in later sessions instead of running init again.
+	// Changes to a module's Terraform files invalidate its cache entries.
+	// Not used for modules with a state_migration block.
+	InitCache bool `json:"init_cache,omitempty"`
+
+	// LockRetry retries init, plan and apply when they fail because the
+	// state is locked. Disabled by default.
+	LockRetry LockRetry `json:"lock_retry,omitempty"`
+
+	// LogDir is the directory where a log of each plan, apply and refresh
+	// is written, named after the run ID, e.g. for CI runners to archive.
+	// Relative paths are relative to the config file. --log-file overrides
+	// it.
+	LogDir string `json:"log_dir,omitempty"`
+
+	// MetricsPush pushes a summary of each plan, apply or refresh to a
+	// Prometheus pushgateway at the end of the run.
+	MetricsPush MetricsPush `json:"metrics_push,omitempty"`
 
 	// Modules is a list of Terraform modules.
-	Modules []Module
+	Modules []Module `json:"modules"`
+
+	// Notifications sends a summary of each run to a webhook at its end.
+	Notifications Notifications `json:"notifications,omitempty"`
+
+	// ReadOnly only allows operations that don't write to remote state,
+	// such as plan, for configurations that are only used to check for
+	// drift. It can also be enabled with --read-only.
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// Redact is a list of regular expressions to redact from the plans and
+	// errors astro displays, in addition to DefaultRedactPatterns, e.g. for
+	// attributes that Terraform 0.11 shows in cleartext. If a pattern has a
+	// capture group, only the first group is redacted.
+	Redact []string `json:"redact,omitempty"`
+
+	// PluginCacheDir is the directory that Terraform 0.10 and later cache
+	// provider plugins in, shared by all sessions. Relative paths are
+	// relative to the config file. Defaults to the plugins directory of the
+	// session repo. Not used if TF_PLUGIN_CACHE_DIR is set.
+	PluginCacheDir string `json:"plugin_cache_dir,omitempty"`
+
+	// TerraformCLIConfig is the Terraform CLI configuration that astro
+	// generates for each session and points Terraform to with
+	// TF_CLI_CONFIG_FILE, e.g. for the credentials of private registries.
+	TerraformCLIConfig TerraformCLIConfig `json:"terraform_cli_config,omitempty"`
+
+	// PluginCacheWarnSize is the size, in bytes, above which verbose runs
+	// print the size of the plugin cache and how to clean it. Defaults to
+	// DefaultPluginCacheWarnSize.
+	PluginCacheWarnSize int64 `json:"plugin_cache_warn_size,omitempty"`
+
+	// Remote is the default remote configuration of modules. Modules that
+	// don't set a backend use this one, and get the backend_config
+	// parameters they don't set. Modules with local_state don't use it.
+	Remote Remote `json:"remote"`
+
+	// RequireSessionRepo makes astro fail if the session repo in
+	// SessionRepoDir can't be written to. By default, astro falls back to a
+	// session repo in a temporary directory, e.g. on read-only checkouts.
+	RequireSessionRepo bool `json:"require_session_repo,omitempty"`
+
+	// MaxAttachmentSize is the largest file, in bytes, that can be attached
+	// to a session with `astro sessions attach`. Defaults to
+	// DefaultMaxAttachmentSize.
+	MaxAttachmentSize int64 `json:"max_attachment_size,omitempty"`
+
+	// SecretScanning configures the redaction of secrets from plan output.
+	SecretScanning SecretScanning `json:"secret_scanning,omitempty"`
 
 	// SessionRepoDir is the path to the directory where astro
 	// will create the .astro session repo that stores log files and
@@ -41,10 +161,53 @@ type Project struct {
 	// file.
 	SessionRepoDir string `json:"session_repo_dir"`
 
+	// SessionDirMode is the mode that directories in the session repo,
+	// including the .astro directory itself, are created with. Defaults to
+	// 0755. The user's umask is applied.
+	SessionDirMode FileMode `json:"session_dir_mode,omitempty"`
+
+	// SessionFileMode is the mode that files in the session repo, such as
+	// logs and plans, are created with. The user's umask is applied, except
+	// to plan files, which Terraform writes and astro then changes to this
+	// mode. If not set, files are created with 0666 and plan files are left
+	// as Terraform writes them.
+	SessionFileMode FileMode `json:"session_file_mode,omitempty"`
+
+	// SharedSandbox clones the Terraform code once per module in a session,
+	// instead of once per execution, and gives each execution its own
+	// Terraform data directory. Executions that need their own sandbox,
+	// e.g. of modules without a backend or plans that detach, still get
+	// one.
+	SharedSandbox bool `json:"shared_sandbox,omitempty"`
+
 	// TerraformCodeRoot is the path to the root of the Terraform code for this
 	// Project. Defaults to the same directory as the config file.
 	TerraformCodeRoot string `json:"terraform_code_root"`
 
+	// TerraformExitCodes overrides the exit codes that mean a Terraform
+	// subcommand succeeded, e.g. for wrapper scripts that shift them.
+	TerraformExitCodes TerraformExitCodes `json:"terraform_exit_codes,omitempty"`
+
+	// TerraformCodeRoots is a list of paths to Terraform code roots, for
+	// projects whose code is spread over several directories, e.g. sibling
+	// repos. The first is the primary root, which module paths are relative
+	// to; all of them are cloned into sandboxes, keeping their relative
+	// layout. It replaces TerraformCodeRoot, which defaults to the first.
+	TerraformCodeRoots []string `json:"terraform_code_roots,omitempty"`
+
+	// Variables is a list of variables that are added to every module. A
+	// module's own definition of a variable with the same name takes
+	// precedence, and modules can opt out of specific variables with
+	// ExcludeProjectVariables.
+	Variables []Variable `json:"variables,omitempty"`
+
+	// VersionConcurrency limits how many executions can initialize
+	// Terraform at the same time, by Terraform version constraint, e.g.
+	// {"< 0.13": 1}. Older versions of Terraform race when several
+	// processes populate the same plugin cache. Defaults to
+	// DefaultVersionConcurrency.
+	VersionConcurrency map[string]int `json:"version_concurrency,omitempty"`
+
 	// Default Terraform configuration for this project. This
 	// configuration is used when executing Terraform. Modules can
 	// override this configuration with their own.
@@ -56,11 +219,88 @@ func (conf *Project) Validate() (errs error) {
 	if err := conf.TerraformDefaults.Validate(); err != nil {
 		errs = multierror.Append(errs, fmt.Errorf("TerraformDefaults: %v", err))
 	}
+	for _, err := range validateEnv(conf.Env, conf.SensitiveEnv) {
+		errs = multierror.Append(errs, fmt.Errorf("Env: %v", err))
+	}
+	for _, pattern := range conf.Exclude {
+		if err := validateExcludePattern(pattern); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("Exclude: %v", err))
+		}
+	}
+	roots := conf.TerraformCodeRoots
+	if len(roots) == 0 {
+		roots = []string{conf.TerraformCodeRoot}
+	}
+	for _, root := range roots {
+		if _, err := readAstroIgnore(root); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("Exclude: %v", err))
+		}
+	}
+	if len(conf.TerraformCodeRoots) > 0 && conf.TerraformCodeRoot != conf.TerraformCodeRoots[0] {
+		errs = multierror.Append(errs, fmt.Errorf("TerraformCodeRoots: terraform_code_root and terraform_code_roots cannot both be set"))
+	}
+	for _, root := range conf.TerraformCodeRoots {
+		if !utils.IsDirectory(root) {
+			errs = multierror.Append(errs, fmt.Errorf("TerraformCodeRoots: code root does not exist: %v", root))
+		}
+	}
 	for _, moduleConf := range conf.Modules {
 		if err := moduleConf.Validate(); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("Module[%v]: %v", moduleConf.Name, err))
 		}
 	}
+	if conf.MaxAttachmentSize < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("MaxAttachmentSize: must not be negative"))
+	}
+	if conf.PluginCacheWarnSize < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("PluginCacheWarnSize: must not be negative"))
+	}
+	if err := conf.ChangeBudget.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("ChangeBudget: %v", err))
+	}
+	for constraint, limit := range conf.VersionConcurrency {
+		if _, err := version.NewConstraint(constraint); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("VersionConcurrency: invalid version constraint %q: %v", constraint, err))
+		}
+		if limit < 1 {
+			errs = multierror.Append(errs, fmt.Errorf("VersionConcurrency: limit for %q must be at least 1", constraint))
+		}
+	}
+	if err := conf.TerraformCLIConfig.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("TerraformCLIConfig: %v", err))
+	}
+	if conf.TerraformCLIConfig.IsSet() {
+		if conf.TerraformCLIConfig.PluginCacheDir != "" && conf.PluginCacheDir != "" {
+			errs = multierror.Append(errs, fmt.Errorf("TerraformCLIConfig: plugin_cache_dir and terraform_cli_config.plugin_cache_dir cannot both be set"))
+		}
+	}
+	if err := conf.ClockSkewTolerance.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("ClockSkewTolerance: %v", err))
+	}
+	if err := conf.LockRetry.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("LockRetry: %v", err))
+	}
+	if err := conf.MetricsPush.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("MetricsPush: %v", err))
+	}
+	if err := conf.Notifications.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("Notifications: %v", err))
+	}
+	if err := validateRedactPatterns(conf.Redact); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("Redact: %v", err))
+	}
+	if err := conf.SecretScanning.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("SecretScanning: %v", err))
+	}
+	if err := conf.TerraformExitCodes.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("TerraformExitCodes: %v", err))
+	}
+	if err := conf.SessionDirMode.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("SessionDirMode: %v", err))
+	}
+	if err := conf.SessionFileMode.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("SessionFileMode: %v", err))
+	}
 	for _, hook := range conf.Hooks.Startup {
 		if err := hook.Validate(); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("Startup Hook: %v", err))
@@ -71,5 +311,19 @@ func (conf *Project) Validate() (errs error) {
 			errs = multierror.Append(errs, fmt.Errorf("PreModuleRun Hook: %v", err))
 		}
 	}
+	for _, hook := range conf.Hooks.PlanCheck {
+		if err := hook.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("PlanCheck Hook: %v", err))
+		}
+	}
 	return errs
 }
+
+// VersionConcurrencyLimits returns the limits on concurrent initialization
+// by Terraform version constraint; see VersionConcurrency.
+func (conf *Project) VersionConcurrencyLimits() map[string]int {
+	if conf.VersionConcurrency == nil {
+		return DefaultVersionConcurrency
+	}
+	return conf.VersionConcurrency
+}
@@ -0,0 +1,62 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+)
+
+// ChangeBudget limits the number of resources that plans can change, to
+// catch runaway changes, such as a provider upgrade that wants to replace
+// every resource. Changes are resources to add, change or destroy. A limit
+// of 0 means there is no limit.
+type ChangeBudget struct {
+	// MaxChangesPerExecution is the most changes the plan of a single
+	// execution can have. Modules get the project's value unless they set
+	// their own.
+	MaxChangesPerExecution int `json:"max_changes_per_execution,omitempty"`
+
+	// MaxTotalChanges is the most changes the plans of all executions can
+	// have together: all executions of the run in the project
+	// configuration, or all executions of the module in a module's.
+	MaxTotalChanges int `json:"max_total_changes,omitempty"`
+}
+
+// IsSet returns whether the budget limits anything.
+func (conf *ChangeBudget) IsSet() bool {
+	return conf.MaxChangesPerExecution > 0 || conf.MaxTotalChanges > 0
+}
+
+// ApplyDefaultsFrom copies the per-execution limit from the project's
+// budget. The project's total applies to the whole run, not to each module,
+// so it isn't copied.
+func (conf *ChangeBudget) ApplyDefaultsFrom(defaults ChangeBudget) {
+	if conf.MaxChangesPerExecution == 0 {
+		conf.MaxChangesPerExecution = defaults.MaxChangesPerExecution
+	}
+}
+
+// Validate checks the change budget is good.
+func (conf *ChangeBudget) Validate() error {
+	if conf.MaxChangesPerExecution < 0 {
+		return errors.New("max_changes_per_execution must not be negative")
+	}
+	if conf.MaxTotalChanges < 0 {
+		return errors.New("max_total_changes must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+// ChangesPolicy configures which changes count when deciding whether a plan
+// has changes, e.g. for drift alerts that shouldn't fire on plans that only
+// change outputs. It affects what astro derives from plans, such as the
+// changes shown in summaries, the exit code of `astro plan
+// --detailed-exitcode` and the astro_plan_changes metric, but not the plans
+// themselves.
+type ChangesPolicy struct {
+	// IgnoreOutputChanges doesn't count changes to outputs.
+	IgnoreOutputChanges bool `json:"ignore_output_changes,omitempty"`
+
+	// IgnoreReadChanges doesn't count data sources that are read, or
+	// changes that only refresh the state.
+	IgnoreReadChanges bool `json:"ignore_read_changes,omitempty"`
+
+	// RequireResourceChanges only counts plans that add, change or destroy
+	// at least one resource, which implies both of the above.
+	RequireResourceChanges bool `json:"require_resource_changes,omitempty"`
+}
+
+// IsSet returns whether the policy ignores any changes.
+func (conf *ChangesPolicy) IsSet() bool {
+	return conf.IgnoreOutputChanges || conf.IgnoreReadChanges || conf.RequireResourceChanges
+}
@@ -0,0 +1,58 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultClockSkewTolerance is how far apart the clocks of the machines
+// sharing a session repo can be before astro warns about it, unless
+// ClockSkewTolerance is set.
+const DefaultClockSkewTolerance = 5 * time.Second
+
+// ClockSkewTolerance is how far apart timestamps recorded by different
+// machines, or by the same machine before and after its clock was changed,
+// can be before astro treats them as out of order, e.g. "30s". Session IDs
+// are ULIDs, which embed the time they were created at, so a clock that is
+// behind makes new sessions sort before older ones.
+type ClockSkewTolerance string
+
+// Validate checks that the tolerance is a duration that isn't negative.
+func (conf ClockSkewTolerance) Validate() error {
+	if conf == "" {
+		return nil
+	}
+	tolerance, err := time.ParseDuration(string(conf))
+	if err != nil {
+		return fmt.Errorf("invalid duration: %v", err)
+	}
+	if tolerance < 0 {
+		return fmt.Errorf("must not be negative")
+	}
+	return nil
+}
+
+// Duration returns the tolerance.
+func (conf ClockSkewTolerance) Duration() time.Duration {
+	tolerance, err := time.ParseDuration(string(conf))
+	if err != nil || tolerance < 0 {
+		return DefaultClockSkewTolerance
+	}
+	return tolerance
+}
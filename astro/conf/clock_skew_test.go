@@ -0,0 +1,36 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockSkewTolerance(t *testing.T) {
+	assert.NoError(t, ClockSkewTolerance("").Validate())
+	assert.NoError(t, ClockSkewTolerance("0s").Validate())
+	assert.NoError(t, ClockSkewTolerance("1m").Validate())
+	assert.EqualError(t, ClockSkewTolerance("-1s").Validate(), "must not be negative")
+	assert.Error(t, ClockSkewTolerance("30").Validate())
+
+	assert.Equal(t, DefaultClockSkewTolerance, ClockSkewTolerance("").Duration())
+	assert.Equal(t, time.Duration(0), ClockSkewTolerance("0s").Duration())
+	assert.Equal(t, time.Minute, ClockSkewTolerance("1m").Duration())
+}
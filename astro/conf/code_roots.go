@@ -0,0 +1,88 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"path/filepath"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+// CodeRoots returns the Terraform code roots of the module. The first one
+// is the primary root that the module path is relative to.
+func (m *Module) CodeRoots() []string {
+	if len(m.TerraformCodeRoots) > 0 {
+		return m.TerraformCodeRoots
+	}
+	return []string{m.TerraformCodeRoot}
+}
+
+// isWithinCodeRoots returns whether the path is within any of the module's
+// code roots.
+func (m *Module) isWithinCodeRoots(path string) bool {
+	for _, root := range m.CodeRoots() {
+		if utils.IsWithinPath(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// SandboxLayout returns the base path, module path and include paths that
+// the module's code is cloned into a sandbox with. With a single code root,
+// these are the module's own settings. With several, the base path is the
+// closest directory that contains all of them, and each root is cloned to
+// the same path relative to it, so that relative module sources pointing
+// from one root into another keep working.
+func (m *Module) SandboxLayout() (basePath, modulePath string, include []string) {
+	roots := m.CodeRoots()
+	if len(roots) == 1 {
+		return m.TerraformCodeRoot, m.Path, m.SandboxInclude
+	}
+
+	basePath = commonDir(roots)
+	primary, _ := filepath.Rel(basePath, roots[0])
+	modulePath = filepath.Join(primary, m.Path)
+
+	if len(m.SandboxInclude) > 0 {
+		for _, path := range m.SandboxInclude {
+			include = append(include, filepath.Join(primary, path))
+		}
+		return basePath, modulePath, include
+	}
+	for _, root := range roots {
+		rel, _ := filepath.Rel(basePath, root)
+		include = append(include, rel)
+	}
+	return basePath, modulePath, include
+}
+
+// commonDir returns the closest directory that contains all of the absolute
+// paths.
+func commonDir(paths []string) string {
+	dir := filepath.Clean(paths[0])
+	for _, path := range paths[1:] {
+		for !utils.IsWithinPath(dir, filepath.Clean(path)) {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return dir
+}
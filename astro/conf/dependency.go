@@ -19,9 +19,9 @@ package conf
 // Dependency is static config representing the dependency of a module
 type Dependency struct {
 	// Module is the name of the module we're depending on.
-	Module string
+	Module string `json:"module"`
 	// Variables is an optional map of specific parameters to narrow down the
 	// dependency to a specific execution. If this is nil, and the module has
 	// many different possible executions, we'll depend on all of them.
-	Variables map[string]string
+	Variables map[string]string `json:"variables,omitempty"`
 }
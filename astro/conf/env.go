@@ -0,0 +1,73 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+// ReservedEnvNames are the environment variables astro sets for Terraform
+// itself, which can't be set with env.
+var ReservedEnvNames = []string{CLIConfigFileEnv, "TF_DATA_DIR", "TF_PLUGIN_CACHE_DIR", "TMPDIR"}
+
+// validateEnv checks the names of environment variables and that the
+// sensitive ones are set.
+func validateEnv(env map[string]string, sensitiveEnv []string) (errs []error) {
+	for _, name := range sortedKeys(env) {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			errs = append(errs, fmt.Errorf("invalid environment variable name: %q", name))
+		} else if utils.StringSliceContains(ReservedEnvNames, name) {
+			errs = append(errs, fmt.Errorf("%v is set by astro and cannot be overridden", name))
+		}
+	}
+	for _, name := range sensitiveEnv {
+		if _, ok := env[name]; !ok {
+			errs = append(errs, fmt.Errorf("sensitive environment variable %v is not set", name))
+		}
+	}
+	return errs
+}
+
+// mergeEnv returns the environment variables in env with those in defaults
+// added, except for those env sets itself.
+func mergeEnv(defaults, env map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return env
+	}
+	merged := map[string]string{}
+	for name, value := range defaults {
+		merged[name] = value
+	}
+	for name, value := range env {
+		merged[name] = value
+	}
+	return merged
+}
+
+// ApplyEnvDefaultsFrom adds the project-level environment variables to the
+// module's. The module's own values take precedence.
+func (m *Module) ApplyEnvDefaultsFrom(project Project) {
+	m.Env = mergeEnv(project.Env, m.Env)
+	for _, name := range project.SensitiveEnv {
+		if !utils.StringSliceContains(m.SensitiveEnv, name) {
+			m.SensitiveEnv = append(m.SensitiveEnv, name)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// DefaultPlanChangesExitCode is the exit code of `terraform plan
+// -detailed-exitcode` when the plan has changes.
+const DefaultPlanChangesExitCode = 2
+
+// planChangesKey is the key of the plan changes exit code in the
+// terraform_exit_codes configuration.
+const planChangesKey = "plan_changes"
+
+// DefaultTerraformExitCodes are the exit codes that mean a Terraform
+// subcommand succeeded, for the subcommands that don't only succeed with 0.
+var DefaultTerraformExitCodes = map[string][]int{
+	"plan": {0, DefaultPlanChangesExitCode},
+}
+
+// TerraformExitCodes overrides the exit codes that mean a Terraform
+// subcommand succeeded, e.g. for wrapper scripts that shift them. In the
+// configuration, it maps subcommands to lists of exit codes, e.g.
+// {"apply": [0], "plan": [0, 3], "plan_changes": 3}, where plan_changes is
+// the exit code that means a plan has changes.
+type TerraformExitCodes struct {
+	// Success maps subcommands, e.g. "plan", to the exit codes that mean
+	// they succeeded, overriding DefaultTerraformExitCodes.
+	Success map[string][]int
+	// PlanChanges is the exit code of plans with changes. Defaults to
+	// DefaultPlanChangesExitCode.
+	PlanChanges int
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (codes *TerraformExitCodes) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*codes = TerraformExitCodes{}
+	for subcommand, value := range raw {
+		if subcommand == planChangesKey {
+			if err := json.Unmarshal(value, &codes.PlanChanges); err != nil {
+				return fmt.Errorf("invalid %s: %s", planChangesKey, value)
+			}
+			continue
+		}
+		var exitCodes []int
+		if err := json.Unmarshal(value, &exitCodes); err != nil {
+			return fmt.Errorf("invalid exit codes for %s: %s; must be a list of numbers", subcommand, value)
+		}
+		if codes.Success == nil {
+			codes.Success = map[string][]int{}
+		}
+		codes.Success[subcommand] = exitCodes
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, in the same format that
+// UnmarshalJSON reads.
+func (codes TerraformExitCodes) MarshalJSON() ([]byte, error) {
+	raw := map[string]interface{}{}
+	for subcommand, exitCodes := range codes.Success {
+		raw[subcommand] = exitCodes
+	}
+	if codes.PlanChanges != 0 {
+		raw[planChangesKey] = codes.PlanChanges
+	}
+	return json.Marshal(raw)
+}
+
+// IsSet returns whether any exit codes are overridden.
+func (codes TerraformExitCodes) IsSet() bool {
+	return len(codes.Success) > 0 || codes.PlanChanges != 0
+}
+
+// PlanChangesCode returns the exit code of plans with changes.
+func (codes TerraformExitCodes) PlanChangesCode() int {
+	if codes.PlanChanges != 0 {
+		return codes.PlanChanges
+	}
+	return DefaultPlanChangesExitCode
+}
+
+// SuccessCodes returns the exit codes that mean the subcommand succeeded.
+// If only plan_changes is set, plans succeed with 0 and that code.
+func (codes TerraformExitCodes) SuccessCodes(subcommand string) []int {
+	if exitCodes, ok := codes.Success[subcommand]; ok {
+		return exitCodes
+	}
+	if subcommand == "plan" {
+		return []int{0, codes.PlanChangesCode()}
+	}
+	if exitCodes, ok := DefaultTerraformExitCodes[subcommand]; ok {
+		return exitCodes
+	}
+	return []int{0}
+}
+
+// Validate checks the exit codes are valid, and that plans with changes
+// still succeed.
+func (codes TerraformExitCodes) Validate() (errs error) {
+	for _, subcommand := range codes.subcommands() {
+		if len(codes.Success[subcommand]) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("%s: must list at least one exit code", subcommand))
+		}
+		for _, code := range codes.Success[subcommand] {
+			if code < 0 || code > 255 {
+				errs = multierror.Append(errs, fmt.Errorf("%s: invalid exit code %d; must be between 0 and 255", subcommand, code))
+			}
+		}
+	}
+	if codes.PlanChanges < 0 || codes.PlanChanges > 255 {
+		errs = multierror.Append(errs, fmt.Errorf("%s: invalid exit code %d; must be between 1 and 255", planChangesKey, codes.PlanChanges))
+	}
+	if _, ok := codes.Success["plan"]; ok && !containsExitCode(codes.Success["plan"], codes.PlanChangesCode()) {
+		errs = multierror.Append(errs, fmt.Errorf("plan: must include %d, the exit code of plans with changes; set %s to remap it", codes.PlanChangesCode(), planChangesKey))
+	}
+	return errs
+}
+
+// Warnings returns a warning for every subcommand other than plan that is
+// configured to succeed with a nonzero exit code, as failed applies would
+// then be reported as successful.
+func (codes TerraformExitCodes) Warnings() (warnings []string) {
+	for _, subcommand := range codes.subcommands() {
+		if subcommand == "plan" {
+			continue
+		}
+		for _, code := range codes.Success[subcommand] {
+			if code != 0 {
+				warnings = append(warnings, fmt.Sprintf("terraform_exit_codes: exit code %d of terraform %s is treated as success; failures that exit with it will be reported as successful", code, subcommand))
+			}
+		}
+	}
+	return warnings
+}
+
+// subcommands returns the overridden subcommands, sorted.
+func (codes TerraformExitCodes) subcommands() []string {
+	subcommands := []string{}
+	for subcommand := range codes.Success {
+		subcommands = append(subcommands, subcommand)
+	}
+	sort.Strings(subcommands)
+	return subcommands
+}
+
+func containsExitCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
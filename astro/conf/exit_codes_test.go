@@ -0,0 +1,76 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerraformExitCodes(t *testing.T) {
+	var codes TerraformExitCodes
+	require.NoError(t, json.Unmarshal([]byte(`{"apply": [0, 1], "plan": [0, 3], "plan_changes": 3}`), &codes))
+	require.NoError(t, codes.Validate())
+
+	assert.Equal(t, []int{0, 1}, codes.SuccessCodes("apply"))
+	assert.Equal(t, []int{0, 3}, codes.SuccessCodes("plan"))
+	assert.Equal(t, []int{0}, codes.SuccessCodes("init"))
+	assert.Equal(t, 3, codes.PlanChangesCode())
+	assert.Equal(t, []string{
+		"terraform_exit_codes: exit code 1 of terraform apply is treated as success; failures that exit with it will be reported as successful",
+	}, codes.Warnings())
+
+	b, err := json.Marshal(codes)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"apply": [0, 1], "plan": [0, 3], "plan_changes": 3}`, string(b))
+}
+
+func TestTerraformExitCodesDefaults(t *testing.T) {
+	codes := TerraformExitCodes{}
+	assert.Equal(t, []int{0, 2}, codes.SuccessCodes("plan"))
+	assert.Equal(t, []int{0}, codes.SuccessCodes("apply"))
+	assert.Equal(t, 2, codes.PlanChangesCode())
+	assert.Empty(t, codes.Warnings())
+
+	// Remapping only the changes exit code keeps plans with changes
+	// successful
+	codes.PlanChanges = 4
+	assert.Equal(t, []int{0, 4}, codes.SuccessCodes("plan"))
+}
+
+func TestTerraformExitCodesValidation(t *testing.T) {
+	tests := []struct {
+		codes TerraformExitCodes
+		err   string
+	}{
+		{codes: TerraformExitCodes{Success: map[string][]int{"plan": {0}}}, err: "plan: must include 2, the exit code of plans with changes; set plan_changes to remap it"},
+		{codes: TerraformExitCodes{Success: map[string][]int{"plan": {0, 2}}, PlanChanges: 3}, err: "plan: must include 3"},
+		{codes: TerraformExitCodes{Success: map[string][]int{"apply": {}}}, err: "apply: must list at least one exit code"},
+		{codes: TerraformExitCodes{Success: map[string][]int{"apply": {256}}}, err: "apply: invalid exit code 256"},
+		{codes: TerraformExitCodes{PlanChanges: -1}, err: "plan_changes: invalid exit code -1"},
+	}
+
+	for _, tt := range tests {
+		err := tt.codes.Validate()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// DefaultSessionDirMode is the mode session directories are created with if
+// SessionDirMode is not set.
+const DefaultSessionDirMode FileMode = 0755
+
+// FileMode is a set of Unix permission bits. In the config, it can be written
+// as an octal string, e.g. "0700", or as a YAML octal number, e.g. 0700.
+type FileMode os.FileMode
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *FileMode) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		// YAML octal numbers, e.g. 0700, are already converted to numbers
+		var n uint32
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("invalid file mode: %s", b)
+		}
+		*m = FileMode(n)
+		return nil
+	}
+
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode: %q", s)
+	}
+	*m = FileMode(n)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The mode is written as an octal
+// string, e.g. "0700".
+func (m FileMode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%04o", uint32(m)))
+}
+
+// Validate checks the mode only contains permission bits.
+func (m FileMode) Validate() error {
+	if os.FileMode(m)&^os.ModePerm != 0 {
+		return fmt.Errorf("invalid file mode: %04o; only permission bits (0777) can be set", uint32(m))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileModeUnmarshal(t *testing.T) {
+	tt := []struct {
+		json        string
+		expected    FileMode
+		expectedErr string
+	}{
+		{json: `"0700"`, expected: 0700},
+		{json: `"640"`, expected: 0640},
+		{json: `448`, expected: 0700},
+		{json: `"0800"`, expectedErr: `invalid file mode: "0800"`},
+		{json: `"rwx"`, expectedErr: `invalid file mode: "rwx"`},
+		{json: `-1`, expectedErr: `invalid file mode: -1`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.json, func(t *testing.T) {
+			var mode FileMode
+			err := json.Unmarshal([]byte(tc.json), &mode)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, mode)
+		})
+	}
+}
+
+func TestFileModeValidate(t *testing.T) {
+	assert.NoError(t, FileMode(0).Validate())
+	assert.NoError(t, FileMode(0750).Validate())
+	assert.EqualError(t, FileMode(04755).Validate(), "invalid file mode: 4755; only permission bits (0777) can be set")
+}
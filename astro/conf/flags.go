@@ -19,7 +19,7 @@ package conf
 // Flag is a user
 type Flag struct {
 	// Name of the flag that is visible to the user, e.g. on the CLI.
-	Name string
+	Name string `json:"name"`
 	// Description is an optional description to show to the user.
-	Description string
+	Description string `json:"description,omitempty"`
 }
@@ -29,29 +29,50 @@ import (
 // will be parsed by Astro and set as environment variables.
 type Hook struct {
 	// Command is the shell command to be executed
-	Command string
+	Command string `json:"command"`
 
 	// If set, hook output will be parsed for "KEY=VAL" pairs, which will
 	// be set as environment variables
 	SetEnv bool `json:"set_env"`
+
+	// Requires lists commands, e.g. "jq", that the hook needs. They are
+	// looked up in $PATH when the project is loaded, so that a missing one
+	// fails the run before anything is executed.
+	Requires []string `json:"requires,omitempty"`
+
+	// PlanOnStdin writes the plan to the standard input of plan_check
+	// hooks, instead of passing the path of the plan file as their last
+	// argument.
+	PlanOnStdin bool `json:"plan_on_stdin,omitempty"`
 }
 
 // Hooks holds information for shared hooks
 type Hooks struct {
 	// Startup hooks are executed at CLI startup, after configuration has been
 	// validated but before an operation like plan or apply is run.
-	Startup []Hook
+	Startup []Hook `json:"startup,omitempty"`
 
 	// PreModuleRun sets the default for the prehook for a module execution.
 	// See the docs on ModuleHooks below.
-	PreModuleRun []Hook `json:"pre_module_run"`
+	PreModuleRun []Hook `json:"pre_module_run,omitempty"`
+
+	// PlanCheck sets the default plan checks of modules. See the docs on
+	// ModuleHooks below.
+	PlanCheck []Hook `json:"plan_check,omitempty"`
 }
 
 // ModuleHooks contains configuration for user hooks that should run for a
 // given module execution.
 type ModuleHooks struct {
 	// PreModuleRun hooks are run before a module executes.
-	PreModuleRun []Hook `json:"pre_module_run"`
+	PreModuleRun []Hook `json:"pre_module_run,omitempty"`
+
+	// PlanCheck hooks check the plan of each execution of an apply before
+	// it is applied, e.g. against organizational policies. The execution
+	// is planned first, and each hook is passed the plan: as JSON for
+	// Terraform 0.12 and later, and as text otherwise. If a hook exits
+	// with a non-zero code, the execution fails without being applied.
+	PlanCheck []Hook `json:"plan_check,omitempty"`
 }
 
 // ApplyDefaultsFrom copies the default values from the Hook configuration to
@@ -60,6 +81,9 @@ func (conf *ModuleHooks) ApplyDefaultsFrom(defaultHooks Hooks) {
 	if conf.PreModuleRun == nil {
 		conf.PreModuleRun = defaultHooks.PreModuleRun
 	}
+	if conf.PlanCheck == nil {
+		conf.PlanCheck = defaultHooks.PlanCheck
+	}
 }
 
 // Validate checks the hook configuration is good
@@ -67,5 +91,10 @@ func (hook *Hook) Validate() error {
 	if hook.Command == "" {
 		return errors.New("Missing hook command")
 	}
+	for _, command := range hook.Requires {
+		if command == "" {
+			return errors.New("Empty command in hook requires")
+		}
+	}
 	return nil
 }
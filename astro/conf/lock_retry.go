@@ -0,0 +1,68 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultLockRetryDelay is how long astro waits before retrying a
+// Terraform command that failed because the state was locked, unless
+// LockRetry.Delay is set.
+const DefaultLockRetryDelay = 30 * time.Second
+
+// LockRetry retries init, plan and apply when they fail because the state
+// is locked, e.g. by another execution whose backend shares the lock table
+// entry, or by a lock of a previous run that is still expiring. Other
+// failures aren't retried.
+type LockRetry struct {
+	// Attempts is the number of times a command is retried. Commands are
+	// not retried if it is 0.
+	Attempts int `json:"attempts,omitempty"`
+	// Delay is how long to wait before each retry, e.g. "30s". Defaults to
+	// DefaultLockRetryDelay.
+	Delay string `json:"delay,omitempty"`
+}
+
+// Validate checks that the number of attempts isn't negative, and that the
+// delay is a positive duration.
+func (conf LockRetry) Validate() error {
+	if conf.Attempts < 0 {
+		return fmt.Errorf("attempts must not be negative")
+	}
+	if conf.Delay == "" {
+		return nil
+	}
+	delay, err := time.ParseDuration(conf.Delay)
+	if err != nil {
+		return fmt.Errorf("invalid delay: %v", err)
+	}
+	if delay <= 0 {
+		return fmt.Errorf("delay must be positive")
+	}
+	return nil
+}
+
+// DelayDuration returns how long to wait before each retry.
+func (conf LockRetry) DelayDuration() time.Duration {
+	delay, err := time.ParseDuration(conf.Delay)
+	if err != nil || delay <= 0 {
+		return DefaultLockRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,35 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockRetry(t *testing.T) {
+	assert.NoError(t, LockRetry{}.Validate())
+	assert.NoError(t, LockRetry{Attempts: 3, Delay: "10s"}.Validate())
+	assert.EqualError(t, LockRetry{Attempts: -1}.Validate(), "attempts must not be negative")
+	assert.EqualError(t, LockRetry{Attempts: 3, Delay: "0s"}.Validate(), "delay must be positive")
+	assert.Error(t, LockRetry{Attempts: 3, Delay: "10"}.Validate())
+
+	assert.Equal(t, DefaultLockRetryDelay, LockRetry{Attempts: 3}.DelayDuration())
+	assert.Equal(t, 10*time.Second, LockRetry{Attempts: 3, Delay: "10s"}.DelayDuration())
+}
@@ -0,0 +1,53 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// MetricsPush configures pushing a summary of each run to a Prometheus
+// pushgateway, for CI runners that are gone before they could be scraped.
+type MetricsPush struct {
+	// URL is the URL of the pushgateway, e.g. "http://pushgateway:9091".
+	// Metrics are only pushed if it is set, here or with --metrics-push-url.
+	URL string `json:"url,omitempty"`
+	// Job is the job label of the metrics. Defaults to the name of the
+	// directory of the Terraform code root.
+	Job string `json:"job,omitempty"`
+}
+
+// Validate checks that the URL, if set, is an HTTP URL.
+func (conf MetricsPush) Validate() error {
+	if conf.URL == "" {
+		return nil
+	}
+	return ValidateMetricsPushURL(conf.URL)
+}
+
+// ValidateMetricsPushURL checks that the pushgateway URL is an HTTP URL.
+func ValidateMetricsPushURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %v", rawURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q; must be an http or https URL, e.g. http://pushgateway:9091", rawURL)
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/uber/astro/astro/utils"
 
@@ -28,26 +29,95 @@ import (
 
 // Module is the static configuration of a Terraform module.
 type Module struct {
+	// ChangeBudget limits the number of resources that the plans of the
+	// module's executions can change, in addition to the project's budget.
+	ChangeBudget ChangeBudget `json:"change_budget"`
+	// ConfigFile is the configuration file the module is declared in. Users
+	// cannot set this; it is filled in when the configuration is loaded.
+	ConfigFile string `json:"-"`
 	// Deps is a list of Terraform modules that need to be run before this one
 	// can run.
-	Deps []Dependency
+	Deps []Dependency `json:"deps,omitempty"`
+	// ExcludeProjectVariables is a list of names of project-level variables
+	// that should not be added to this module.
+	ExcludeProjectVariables []string `json:"exclude_project_variables,omitempty"`
+	// EnabledWhen is an optional map of variable names to the values for
+	// which the module is enabled. Executions with other values are not
+	// run, e.g. a bastion module that only exists in the mgmt environment.
+	EnabledWhen map[string][]string `json:"enabled_when,omitempty"`
+	// Env is a map of environment variables to set for Terraform, in
+	// addition to the project-level ones. Like backend_config, values can
+	// reference the module's variables.
+	Env map[string]string `json:"env,omitempty"`
+	// SensitiveEnv is a list of names of environment variables in Env whose
+	// values are masked in logs.
+	SensitiveEnv []string `json:"sensitive_env,omitempty"`
+	// Exclusive runs each of the module's executions alone: astro waits
+	// for the executions that are running to finish, runs it, then resumes
+	// running executions in parallel, e.g. for a module that manages the
+	// state bucket itself.
+	Exclusive bool `json:"exclusive,omitempty"`
 	// Hooks contains the module-specific hooks that can run.
-	Hooks ModuleHooks
+	Hooks ModuleHooks `json:"hooks"`
+	// InjectRunID passes the ID of the run to Terraform as the
+	// astro_run_id variable, e.g. to tag resources with the run that
+	// created them. The module's code must declare the variable.
+	InjectRunID bool `json:"inject_run_id,omitempty"`
+	// LocalState declares what happens to the state of a module without a
+	// backend, which is otherwise kept in the session sandbox: either
+	// "ephemeral", to accept that it is lost, or "persist:<path>", to copy
+	// it to the path after apply and back into the sandbox before the next
+	// run. Apply is refused for such modules if it isn't set.
+	LocalState string `json:"local_state,omitempty"`
 	// Name is a unique name for this Terraform module.
-	Name string
+	Name string `json:"name"`
 	// Path is the path to the module, relative to the code root.
-	Path string
+	Path string `json:"path"`
 	// Remote is the Terraform remote for this module.
-	Remote Remote
+	Remote Remote `json:"remote"`
+	// StateMigration is the previous location of the module's state. If set,
+	// state is copied from there when there is no state at the current
+	// location yet. It should be removed once the state has been migrated.
+	StateMigration *StateMigration `json:"state_migration,omitempty"`
+	// SandboxInclude is an optional list of paths, relative to the code root,
+	// that should be cloned into the session sandbox for this module. The
+	// module's own path is always included. If empty, the whole code root is
+	// cloned. Relative module sources that are left out of the sandbox are
+	// reported as warnings; other references, such as a
+	// terraform_remote_state using the local backend, are not checked.
+	SandboxInclude []string `json:"sandbox_include,omitempty"`
+	// SkipUnchangedApplies skips applying an execution when its code,
+	// variables and Terraform version are all the same as in its last
+	// successful apply, as recorded in the session manifests. Other changes,
+	// such as to the infrastructure itself, are not detected; use
+	// --no-skip-unchanged to apply anyway.
+	SkipUnchangedApplies bool `json:"skip_unchanged_applies,omitempty"`
+	// Exclude is the project's list of patterns of paths to leave out of
+	// sandboxes; see SandboxExclude. Users cannot set this; instead they
+	// should set it on the project configuration.
+	Exclude []string `json:"-"`
 	// TerraformCodeRoot is the base path to the Terraform code. Users cannot
 	// set this; instead they should set it on the project configuration.
 	TerraformCodeRoot string `json:"-"`
+	// TerraformCodeRoots are all the code roots of the project, if it has
+	// more than one. The first is TerraformCodeRoot. Users cannot set this
+	// either.
+	TerraformCodeRoots []string `json:"-"`
 	// Terraform stores Terraform configuration that should be used when
 	// running this module.
-	Terraform Terraform
+	Terraform Terraform `json:"terraform"`
+	// TerraformParameters are additional Terraform parameters for the
+	// module's executions, added after the ones from the command line and
+	// Terraform.Parameters. Like backend_config, they can reference the
+	// module's variables, e.g. "-var-file={{.environment}}.tfvars".
+	TerraformParameters []string `json:"terraform_parameters,omitempty"`
 	// Variables is a list of Terraform variables and possible values that this
 	// module accepts.
-	Variables []Variable
+	Variables []Variable `json:"variables,omitempty"`
+	// VerifyAfterApply overrides --verify-after-apply for the module: if
+	// false, its applies are never verified, e.g. because its providers are
+	// known not to converge immediately; if true, they always are.
+	VerifyAfterApply *bool `json:"verify_after_apply,omitempty"`
 }
 
 // Validate validates whether the configuration is good. Returns any validation
@@ -58,22 +128,130 @@ func (m *Module) Validate() (errs error) {
 	} else {
 		fullModulePath := filepath.Join(m.TerraformCodeRoot, m.Path)
 
-		if !utils.IsWithinPath(m.TerraformCodeRoot, fullModulePath) {
-			errs = multierror.Append(errs, fmt.Errorf("module path cannot be outside code root: module path: %v; code root: %v", fullModulePath, m.TerraformCodeRoot))
+		if !m.isWithinCodeRoots(fullModulePath) {
+			errs = multierror.Append(errs, fmt.Errorf("module path cannot be outside code root: module path: %v; code root: %v", fullModulePath, strings.Join(m.CodeRoots(), ", ")))
 		}
 
 		if !utils.IsDirectory(fullModulePath) {
 			errs = multierror.Append(errs, fmt.Errorf("module directory does not exist: %v", fullModulePath))
 		}
 	}
+	for _, include := range m.SandboxInclude {
+		fullIncludePath := filepath.Join(m.TerraformCodeRoot, include)
+
+		if filepath.IsAbs(include) {
+			errs = multierror.Append(errs, fmt.Errorf("sandbox include path must be relative to code root: %v", include))
+		} else if !m.isWithinCodeRoots(fullIncludePath) {
+			errs = multierror.Append(errs, fmt.Errorf("sandbox include path cannot be outside code root: %v", include))
+		} else if !utils.FileExists(fullIncludePath) {
+			errs = multierror.Append(errs, fmt.Errorf("sandbox include path does not exist: %v", fullIncludePath))
+		}
+	}
 	if err := m.Terraform.Validate(); err != nil {
 		errs = multierror.Append(errs, fmt.Errorf("Terraform: %v", err))
 	}
+	if m.Terraform.DownloadMirror != "" {
+		errs = multierror.Append(errs, errors.New("Terraform: download_mirror can only be set in the project's terraform configuration"))
+	}
+	if m.Terraform.Offline {
+		errs = multierror.Append(errs, errors.New("Terraform: offline can only be set in the project's terraform configuration"))
+	}
+	for _, err := range validateEnv(m.Env, m.SensitiveEnv) {
+		errs = multierror.Append(errs, fmt.Errorf("env: %v", err))
+	}
+	if err := m.ChangeBudget.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("change_budget: %v", err))
+	}
+	if err := m.validateLocalState(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("local_state: %v", err))
+	}
+	if m.StateMigration != nil {
+		if err := m.StateMigration.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("state_migration: %v", err))
+		}
+	}
+	for _, variable := range m.Variables {
+		if err := variable.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("variable %v: %v", variable.Name, err))
+		}
+		if m.InjectRunID && variable.Name == RunIDVariable {
+			errs = multierror.Append(errs, fmt.Errorf("inject_run_id: variable %v is set by astro and can't be configured", RunIDVariable))
+		}
+	}
 	for _, hook := range m.Hooks.PreModuleRun {
 		if err := hook.Validate(); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("PreModuleRun Hook: %v", err))
 		}
 	}
+	for _, hook := range m.Hooks.PlanCheck {
+		if err := hook.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("PlanCheck Hook: %v", err))
+		}
+	}
 
 	return errs
 }
+
+// AllTerraformParameters returns the Terraform parameters from the module's
+// configuration, in the order they are passed to Terraform.
+func (m *Module) AllTerraformParameters() []string {
+	parameters := append([]string{}, m.Terraform.Parameters...)
+	return append(parameters, m.TerraformParameters...)
+}
+
+// IsEnabledFor returns whether the module is enabled for the specified
+// variable values, according to EnabledWhen. Variables that are missing from
+// values are assumed to match, since their value isn't known yet.
+func (m *Module) IsEnabledFor(values map[string]string) bool {
+	for name, allowedValues := range m.EnabledWhen {
+		value, ok := values[name]
+		if ok && !utils.StringSliceContains(allowedValues, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Values of Module.LocalState.
+const (
+	// LocalStateEphemeral keeps local state in the session sandbox only, so
+	// it is lost when the session is removed.
+	LocalStateEphemeral = "ephemeral"
+
+	// localStatePersistPrefix is followed by the path local state is
+	// persisted to, e.g. "persist:state/app.tfstate".
+	localStatePersistPrefix = "persist:"
+)
+
+// LocalStatePersist returns the local_state value that persists local state
+// to the specified path.
+func LocalStatePersist(path string) string {
+	return localStatePersistPrefix + path
+}
+
+// LocalStatePersistPath returns the path local state is persisted to, or an
+// empty string if it isn't persisted.
+func (m *Module) LocalStatePersistPath() string {
+	if !strings.HasPrefix(m.LocalState, localStatePersistPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(m.LocalState, localStatePersistPrefix)
+}
+
+// RunIDVariable is the Terraform variable that the run ID is passed in to
+// modules with InjectRunID.
+const RunIDVariable = "astro_run_id"
+
+// validateLocalState checks the local_state value is one of the known forms.
+func (m *Module) validateLocalState() error {
+	if m.LocalState == "" {
+		return nil
+	}
+	if m.LocalState != LocalStateEphemeral && m.LocalStatePersistPath() == "" {
+		return errors.New(`must be "ephemeral" or "persist:<path>"`)
+	}
+	if m.Remote.Backend != "" {
+		return errors.New("cannot be used with a remote backend")
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	version "github.com/burl/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleSandboxIncludeValidation(t *testing.T) {
+	codeRoot, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(codeRoot)
+
+	for _, dir := range []string{"app", "modules", "-delete"} {
+		require.NoError(t, os.Mkdir(filepath.Join(codeRoot, dir), 0755))
+	}
+
+	terraformVersion, err := version.NewVersion("0.11.7")
+	require.NoError(t, err)
+
+	tests := []struct {
+		include []string
+		err     string
+	}{
+		{include: []string{"modules"}},
+		{include: []string{"./modules/", "-delete"}},
+		{include: []string{"/etc"}, err: "sandbox include path must be relative to code root: /etc"},
+		{include: []string{"../modules"}, err: "sandbox include path cannot be outside code root: ../modules"},
+		{include: []string{"missing"}, err: "sandbox include path does not exist"},
+	}
+
+	for _, tt := range tests {
+		module := &Module{
+			Name:              "app",
+			Path:              "app",
+			SandboxInclude:    tt.include,
+			TerraformCodeRoot: codeRoot,
+			Terraform: Terraform{
+				Version: terraformVersion,
+			},
+		}
+
+		err := module.Validate()
+		if tt.err == "" {
+			assert.NoError(t, err, "include: %v", tt.include)
+		} else if assert.Error(t, err, "include: %v", tt.include) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+}
+
+func TestModuleLocalStateValidation(t *testing.T) {
+	codeRoot, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(codeRoot)
+
+	terraformVersion, err := version.NewVersion("0.11.7")
+	require.NoError(t, err)
+
+	tests := []struct {
+		localState string
+		backend    string
+		err        string
+	}{
+		{localState: ""},
+		{localState: "ephemeral"},
+		{localState: "persist:/state/app.tfstate"},
+		{localState: "persist:", err: `local_state: must be "ephemeral" or "persist:<path>"`},
+		{localState: "keep", err: `local_state: must be "ephemeral" or "persist:<path>"`},
+		{localState: "ephemeral", backend: "s3", err: "local_state: cannot be used with a remote backend"},
+	}
+
+	for _, tt := range tests {
+		module := &Module{
+			Name:              "app",
+			Path:              ".",
+			LocalState:        tt.localState,
+			Remote:            Remote{Backend: tt.backend},
+			TerraformCodeRoot: codeRoot,
+			Terraform: Terraform{
+				Version: terraformVersion,
+			},
+		}
+
+		err := module.Validate()
+		if tt.err == "" {
+			assert.NoError(t, err, "local_state: %v", tt.localState)
+		} else if assert.Error(t, err, "local_state: %v", tt.localState) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+
+	module := &Module{LocalState: "persist:/state/app.tfstate"}
+	assert.Equal(t, "/state/app.tfstate", module.LocalStatePersistPath())
+	assert.Equal(t, module.LocalState, LocalStatePersist("/state/app.tfstate"))
+}
+
+func TestModuleEnvValidation(t *testing.T) {
+	codeRoot, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(codeRoot)
+
+	terraformVersion, err := version.NewVersion("0.11.7")
+	require.NoError(t, err)
+
+	tests := []struct {
+		env          map[string]string
+		sensitiveEnv []string
+		err          string
+	}{
+		{env: map[string]string{"AWS_PROFILE": "{{.environment}}"}},
+		{env: map[string]string{"VAULT_TOKEN": "secret"}, sensitiveEnv: []string{"VAULT_TOKEN"}},
+		{env: map[string]string{"TF_PLUGIN_CACHE_DIR": "/tmp"}, err: "env: TF_PLUGIN_CACHE_DIR is set by astro and cannot be overridden"},
+		{env: map[string]string{"TF_CLI_CONFIG_FILE": "/tmp/terraformrc"}, err: "env: TF_CLI_CONFIG_FILE is set by astro and cannot be overridden"},
+		{env: map[string]string{"TF_DATA_DIR": "/tmp/data"}, err: "env: TF_DATA_DIR is set by astro and cannot be overridden"},
+		{env: map[string]string{"A=B": "c"}, err: `env: invalid environment variable name: "A=B"`},
+		{sensitiveEnv: []string{"VAULT_TOKEN"}, err: "env: sensitive environment variable VAULT_TOKEN is not set"},
+	}
+
+	for _, tt := range tests {
+		module := &Module{
+			Name:              "app",
+			Path:              ".",
+			Env:               tt.env,
+			SensitiveEnv:      tt.sensitiveEnv,
+			TerraformCodeRoot: codeRoot,
+			Terraform: Terraform{
+				Version: terraformVersion,
+			},
+		}
+
+		err := module.Validate()
+		if tt.err == "" {
+			assert.NoError(t, err, "env: %v", tt.env)
+		} else if assert.Error(t, err, "env: %v", tt.env) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+}
+
+func TestModuleApplyEnvDefaults(t *testing.T) {
+	module := &Module{
+		Env:          map[string]string{"AWS_PROFILE": "app"},
+		SensitiveEnv: []string{},
+	}
+	module.ApplyEnvDefaultsFrom(Project{
+		Env:          map[string]string{"AWS_PROFILE": "default", "VAULT_TOKEN": "secret"},
+		SensitiveEnv: []string{"VAULT_TOKEN"},
+	})
+
+	assert.Equal(t, map[string]string{"AWS_PROFILE": "app", "VAULT_TOKEN": "secret"}, module.Env)
+	assert.Equal(t, []string{"VAULT_TOKEN"}, module.SensitiveEnv)
+}
+
+func TestModuleDownloadMirrorValidation(t *testing.T) {
+	codeRoot, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(codeRoot)
+	require.NoError(t, os.Mkdir(filepath.Join(codeRoot, "app"), 0755))
+
+	terraformVersion, err := version.NewVersion("0.11.7")
+	require.NoError(t, err)
+
+	module := &Module{
+		Name:              "app",
+		Path:              "app",
+		TerraformCodeRoot: codeRoot,
+		Terraform: Terraform{
+			Version:        terraformVersion,
+			DownloadMirror: "https://mirror/terraform-{version}.zip",
+		},
+	}
+	err = module.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "download_mirror can only be set in the project's terraform configuration")
+
+	project := &Project{
+		TerraformDefaults: Terraform{
+			Version:        terraformVersion,
+			DownloadMirror: "https://mirror/terraform-{verison}.zip",
+		},
+	}
+	err = project.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown placeholder {verison}")
+}
+
+func TestModuleFlavorValidation(t *testing.T) {
+	codeRoot, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(codeRoot)
+	require.NoError(t, os.Mkdir(filepath.Join(codeRoot, "app"), 0755))
+
+	terraformVersion, err := version.NewVersion("1.6.0")
+	require.NoError(t, err)
+
+	module := &Module{
+		Name:              "app",
+		Path:              "app",
+		TerraformCodeRoot: codeRoot,
+		Terraform: Terraform{
+			Version: terraformVersion,
+			Flavor:  "opentofu",
+		},
+	}
+	assert.NoError(t, module.Validate())
+	assert.Equal(t, "tofu", module.Terraform.TVMFlavor().BinaryName())
+
+	module.Terraform.Flavor = "tofu"
+	err = module.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown flavor: "tofu"`)
+}
+
+func TestModuleLockFileValidation(t *testing.T) {
+	codeRoot, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(codeRoot)
+	require.NoError(t, os.Mkdir(filepath.Join(codeRoot, "app"), 0755))
+
+	terraformVersion, err := version.NewVersion("0.14.11")
+	require.NoError(t, err)
+
+	module := &Module{
+		Name:              "app",
+		Path:              "app",
+		TerraformCodeRoot: codeRoot,
+		Terraform: Terraform{
+			Version:  terraformVersion,
+			LockFile: LockFileReadonly,
+		},
+	}
+	assert.NoError(t, module.Validate())
+
+	module.Terraform.LockFile = "read-only"
+	err = module.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid lockfile "read-only"`)
+}
+
+func TestModuleInjectRunIDValidation(t *testing.T) {
+	codeRoot, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(codeRoot)
+	require.NoError(t, os.Mkdir(filepath.Join(codeRoot, "app"), 0755))
+
+	terraformVersion, err := version.NewVersion("0.12.6")
+	require.NoError(t, err)
+
+	module := &Module{
+		Name:              "app",
+		Path:              "app",
+		TerraformCodeRoot: codeRoot,
+		Terraform:         Terraform{Version: terraformVersion},
+		InjectRunID:       true,
+		Variables:         []Variable{{Name: "environment", Values: []string{"dev"}}},
+	}
+	assert.NoError(t, module.Validate())
+
+	module.Variables = append(module.Variables, Variable{Name: RunIDVariable, Values: []string{"1"}})
+	err = module.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "inject_run_id: variable astro_run_id is set by astro")
+}
+
+func TestModuleCodeRoots(t *testing.T) {
+	base, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(base)
+	for _, dir := range []string{"repos/roots/app", "repos/shared/vpc", "elsewhere"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(base, dir), 0755))
+	}
+
+	terraformVersion, err := version.NewVersion("0.11.7")
+	require.NoError(t, err)
+
+	roots := []string{filepath.Join(base, "repos/roots"), filepath.Join(base, "repos/shared")}
+	module := &Module{
+		Name:               "app",
+		Path:               "app",
+		TerraformCodeRoot:  roots[0],
+		TerraformCodeRoots: roots,
+		Terraform:          Terraform{Version: terraformVersion},
+	}
+	assert.NoError(t, module.Validate())
+
+	basePath, modulePath, include := module.SandboxLayout()
+	assert.Equal(t, filepath.Join(base, "repos"), basePath)
+	assert.Equal(t, "roots/app", modulePath)
+	assert.Equal(t, []string{"roots", "shared"}, include)
+
+	// sandbox_include is relative to the primary root, but can reach into
+	// the others
+	module.SandboxInclude = []string{"../shared/vpc"}
+	assert.NoError(t, module.Validate())
+	_, _, include = module.SandboxLayout()
+	assert.Equal(t, []string{"shared/vpc"}, include)
+
+	module.SandboxInclude = []string{"../../elsewhere"}
+	err = module.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sandbox include path cannot be outside code root: ../../elsewhere")
+}
+
+func TestModuleSandboxExclude(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	infra := filepath.Join(tmpdir, "infra")
+	shared := filepath.Join(tmpdir, "shared")
+	for _, dir := range []string{infra, shared} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(infra, AstroIgnoreFile), []byte(`
+# lambda sources
+lambda/**/node_modules/
+/docs
+!docs/README.md
+`), 0644))
+
+	module := &Module{
+		Name:               "app",
+		Path:               "app",
+		Exclude:            []string{".git", "*.zip"},
+		TerraformCodeRoot:  infra,
+		TerraformCodeRoots: []string{infra, shared},
+	}
+
+	patterns, err := module.SandboxExclude()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/infra/**/.git",
+		"/infra/**/*.zip",
+		"/infra/lambda/**/node_modules/",
+		"/infra/docs",
+		"!/infra/docs/README.md",
+		"/shared/**/.git",
+		"/shared/**/*.zip",
+	}, patterns)
+
+	// with a single code root, patterns are relative to it
+	module.TerraformCodeRoots = nil
+	patterns, err = module.SandboxExclude()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/**/.git",
+		"/**/*.zip",
+		"/lambda/**/node_modules/",
+		"/docs",
+		"!/docs/README.md",
+	}, patterns)
+}
+
+func TestValidateExcludePattern(t *testing.T) {
+	for _, pattern := range []string{"node_modules/", "/docs", "!docs/README.md", "**/*.zip", `\#notes`} {
+		assert.NoError(t, validateExcludePattern(pattern), pattern)
+	}
+	for pattern, expected := range map[string]string{
+		"/":         "matches nothing",
+		"!":         "matches nothing",
+		"docs/[a-":  "syntax error in pattern",
+		"../shared": "cannot refer to parent directories",
+	} {
+		err := validateExcludePattern(pattern)
+		if assert.Error(t, err, pattern) {
+			assert.Contains(t, err.Error(), expected, pattern)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"text/template"
+)
+
+// DefaultNotificationCommands are the commands whose runs are notified if
+// Notifications.Commands isn't set.
+var DefaultNotificationCommands = []string{"apply"}
+
+// Notifications configures a webhook that is sent a summary of each run at
+// its end, e.g. to post the results of applies to Slack.
+type Notifications struct {
+	// WebhookURL is the URL that the summary of each run is POSTed to.
+	// Notifications are only sent if it is set.
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// Template is a Go template of the body of the request, which is
+	// executed with the summary, e.g. to send the payload of a Slack
+	// incoming webhook. The json function encodes a value as JSON. If it
+	// isn't set, the summary is sent as JSON.
+	Template string `json:"template,omitempty"`
+	// Commands are the commands whose runs are notified: plan, apply or
+	// refresh. Defaults to DefaultNotificationCommands.
+	Commands []string `json:"commands,omitempty"`
+}
+
+// notificationTemplateFuncs are the functions that notification templates
+// can use.
+var notificationTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Validate checks that the webhook URL, if set, is an HTTP URL, that the
+// template parses and that the commands can be notified.
+func (conf Notifications) Validate() error {
+	if conf.WebhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(conf.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL %q: %v", conf.WebhookURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q; must be an http or https URL", conf.WebhookURL)
+	}
+	if _, err := conf.ParseTemplate(); err != nil {
+		return err
+	}
+	for _, command := range conf.Commands {
+		if command != "plan" && command != "apply" && command != "refresh" {
+			return fmt.Errorf("invalid command %q; must be plan, apply or refresh", command)
+		}
+	}
+	return nil
+}
+
+// ParseTemplate returns the parsed template, or nil if it isn't set.
+func (conf Notifications) ParseTemplate() (*template.Template, error) {
+	if conf.Template == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("notification").Funcs(notificationTemplateFuncs).Parse(conf.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// Notifies returns true if runs of the command are notified.
+func (conf Notifications) Notifies(command string) bool {
+	if conf.WebhookURL == "" {
+		return false
+	}
+	commands := conf.Commands
+	if len(commands) == 0 {
+		commands = DefaultNotificationCommands
+	}
+	for _, c := range commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
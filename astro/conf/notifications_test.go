@@ -0,0 +1,41 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifications(t *testing.T) {
+	assert.NoError(t, Notifications{}.Validate())
+	assert.NoError(t, Notifications{WebhookURL: "https://hooks.slack.com/services/T0/B0/x", Commands: []string{"plan", "apply"}}.Validate())
+	assert.EqualError(t, Notifications{WebhookURL: "hooks.slack.com"}.Validate(), `invalid webhook URL "hooks.slack.com"; must be an http or https URL`)
+	assert.EqualError(t, Notifications{WebhookURL: "http://hooks", Commands: []string{"state"}}.Validate(), `invalid command "state"; must be plan, apply or refresh`)
+	assert.Error(t, Notifications{WebhookURL: "http://hooks", Template: "{{.Command"}.Validate())
+
+	assert.False(t, Notifications{}.Notifies("apply"))
+	assert.True(t, Notifications{WebhookURL: "http://hooks"}.Notifies("apply"))
+	assert.False(t, Notifications{WebhookURL: "http://hooks"}.Notifies("plan"))
+	assert.True(t, Notifications{WebhookURL: "http://hooks", Commands: []string{"plan"}}.Notifies("plan"))
+
+	tmpl, err := Notifications{WebhookURL: "http://hooks", Template: `{"text": {{json .}}}`}.ParseTemplate()
+	require.NoError(t, err)
+	assert.NotNil(t, tmpl)
+}
@@ -0,0 +1,251 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/utils"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+var (
+	// matches template actions, e.g. "{{.aws_region}}"
+	reTemplateAction = regexp.MustCompile(`\{\{(.*?)\}\}`)
+	// matches fields referenced in a template action, e.g. ".aws_region"
+	reTemplateField = regexp.MustCompile(`(?:^|[^\w.\]])\.([A-Za-z_]\w*)`)
+)
+
+// Problem is an issue found in the project configuration.
+type Problem struct {
+	// Path is the location of the problem in the configuration, e.g.
+	// "modules[3].deps[0].module".
+	Path string
+	// Message describes the problem.
+	Message string
+}
+
+// Error implements the error interface.
+func (p Problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// Problems returns every problem found in the configuration. In addition to
+// the checks done by Validate, it checks references within the
+// configuration, e.g. that dependencies point to modules that exist.
+func (conf *Project) Problems() (problems []Problem) {
+	add := func(path string, err error) {
+		if err == nil {
+			return
+		}
+		if merr, ok := err.(*multierror.Error); ok {
+			for _, err := range merr.Errors {
+				problems = append(problems, Problem{Path: path, Message: err.Error()})
+			}
+			return
+		}
+		problems = append(problems, Problem{Path: path, Message: err.Error()})
+	}
+
+	if len(conf.Modules) == 0 {
+		add("modules", fmt.Errorf("no modules defined"))
+	}
+	add("terraform", conf.TerraformDefaults.Validate())
+	for _, err := range validateEnv(conf.Env, conf.SensitiveEnv) {
+		add("env", err)
+	}
+	add("change_budget", conf.ChangeBudget.Validate())
+	if conf.MaxAttachmentSize < 0 {
+		add("max_attachment_size", fmt.Errorf("must not be negative"))
+	}
+	if conf.PluginCacheWarnSize < 0 {
+		add("plugin_cache_warn_size", fmt.Errorf("must not be negative"))
+	}
+	add("clock_skew_tolerance", conf.ClockSkewTolerance.Validate())
+	add("lock_retry", conf.LockRetry.Validate())
+	add("metrics_push", conf.MetricsPush.Validate())
+	add("notifications", conf.Notifications.Validate())
+	add("redact", validateRedactPatterns(conf.Redact))
+	add("session_dir_mode", conf.SessionDirMode.Validate())
+	add("session_file_mode", conf.SessionFileMode.Validate())
+	add("terraform_exit_codes", conf.TerraformExitCodes.Validate())
+	add("terraform_cli_config", conf.TerraformCLIConfig.Validate())
+	if conf.TerraformCLIConfig.IsSet() {
+		if conf.TerraformCLIConfig.PluginCacheDir != "" && conf.PluginCacheDir != "" {
+			add("terraform_cli_config.plugin_cache_dir", fmt.Errorf("cannot be set along with plugin_cache_dir"))
+		}
+	}
+
+	for i, hook := range conf.Hooks.Startup {
+		add(fmt.Sprintf("hooks.startup[%d]", i), hook.Validate())
+	}
+	for i, hook := range conf.Hooks.PreModuleRun {
+		add(fmt.Sprintf("hooks.pre_module_run[%d]", i), hook.Validate())
+	}
+	for i, hook := range conf.Hooks.PlanCheck {
+		add(fmt.Sprintf("hooks.plan_check[%d]", i), hook.Validate())
+	}
+
+	moduleIndexes := map[string]int{}
+	variableNames := map[string]bool{}
+	projectVariableNames := map[string]bool{}
+
+	for _, variable := range conf.Variables {
+		projectVariableNames[variable.Name] = true
+		variableNames[variable.Name] = true
+	}
+
+	for i, moduleConf := range conf.Modules {
+		add(fmt.Sprintf("modules[%d]", i), moduleConf.Validate())
+
+		if j, ok := moduleIndexes[moduleConf.Name]; ok {
+			add(fmt.Sprintf("modules[%d].name", i), fmt.Errorf("duplicate module name %q, also used by modules[%d]", moduleConf.Name, j))
+		} else {
+			moduleIndexes[moduleConf.Name] = i
+		}
+
+		for _, variable := range moduleConf.Variables {
+			variableNames[variable.Name] = true
+		}
+	}
+
+	for i, moduleConf := range conf.Modules {
+		for j, dep := range moduleConf.Deps {
+			if _, ok := moduleIndexes[dep.Module]; !ok {
+				add(fmt.Sprintf("modules[%d].deps[%d].module", i, j), fmt.Errorf("unknown module %q", dep.Module))
+			}
+		}
+
+		for j, name := range moduleConf.ExcludeProjectVariables {
+			if !projectVariableNames[name] {
+				add(fmt.Sprintf("modules[%d].exclude_project_variables[%d]", i, j), fmt.Errorf("unknown project variable %q", name))
+			}
+		}
+
+		moduleVariables := map[string]bool{}
+		for _, variable := range moduleConf.Variables {
+			moduleVariables[variable.Name] = true
+		}
+
+		for _, name := range sortedMapKeys(moduleConf.EnabledWhen) {
+			path := fmt.Sprintf("modules[%d].enabled_when.%s", i, name)
+			if !moduleVariables[name] {
+				add(path, fmt.Errorf("references variable %q that is not defined by the module", name))
+				continue
+			}
+			for _, variable := range moduleConf.Variables {
+				if variable.Name != name || len(variable.Values) == 0 {
+					continue
+				}
+				for j, value := range moduleConf.EnabledWhen[name] {
+					if !utils.StringSliceContains(variable.Values, value) {
+						add(fmt.Sprintf("%s[%d]", path, j), fmt.Errorf("%q is not one of the allowed values: %s", value, strings.Join(variable.Values, ", ")))
+					}
+				}
+			}
+		}
+
+		checkBackendConfig := func(path string, backendConfig map[string]string) {
+			for _, key := range sortedKeys(backendConfig) {
+				for _, name := range TemplateFieldNames(backendConfig[key]) {
+					if !moduleVariables[name] {
+						add(fmt.Sprintf("%s.%s", path, key), fmt.Errorf("references variable %q that is not defined by the module", name))
+					}
+				}
+				// Braces outside of template actions are never replaced,
+				// e.g. a misspelled "{module_nme}".
+				if strings.ContainsAny(reTemplateAction.ReplaceAllString(backendConfig[key], ""), "{}") {
+					add(fmt.Sprintf("%s.%s", path, key), fmt.Errorf("unresolved placeholder in %q", backendConfig[key]))
+				}
+			}
+		}
+
+		checkBackendConfig(fmt.Sprintf("modules[%d].remote.backend_config", i), moduleConf.Remote.BackendConfig)
+		checkBackendConfig(fmt.Sprintf("modules[%d].env", i), moduleConf.Env)
+		for _, name := range TemplateFieldNames(moduleConf.LocalState) {
+			if !moduleVariables[name] {
+				add(fmt.Sprintf("modules[%d].local_state", i), fmt.Errorf("references variable %q that is not defined by the module", name))
+			}
+		}
+		for j, parameter := range moduleConf.Terraform.Parameters {
+			for _, name := range TemplateFieldNames(parameter) {
+				if !moduleVariables[name] {
+					add(fmt.Sprintf("modules[%d].terraform.parameters[%d]", i, j), fmt.Errorf("references variable %q that is not defined by the module", name))
+				}
+			}
+		}
+		for j, parameter := range moduleConf.TerraformParameters {
+			for _, name := range TemplateFieldNames(parameter) {
+				if !moduleVariables[name] {
+					add(fmt.Sprintf("modules[%d].terraform_parameters[%d]", i, j), fmt.Errorf("references variable %q that is not defined by the module", name))
+				}
+			}
+		}
+		if moduleConf.StateMigration != nil {
+			checkBackendConfig(fmt.Sprintf("modules[%d].state_migration.backend_config", i), moduleConf.StateMigration.BackendConfig)
+		}
+	}
+
+	flagVariables := []string{}
+	for variable := range conf.Flags {
+		flagVariables = append(flagVariables, variable)
+	}
+	sort.Strings(flagVariables)
+
+	for _, variable := range flagVariables {
+		if !variableNames[variable] {
+			add(fmt.Sprintf("flags.%s", variable), fmt.Errorf("flag %q maps to unknown variable %q", conf.Flags[variable].Name, variable))
+		}
+	}
+
+	return problems
+}
+
+// TemplateFieldNames returns the names of the fields referenced by template
+// actions in s, e.g. ["aws_region"] for "{{.aws_region}}/app.tfstate".
+func TemplateFieldNames(s string) (names []string) {
+	for _, action := range reTemplateAction.FindAllStringSubmatch(s, -1) {
+		for _, field := range reTemplateField.FindAllStringSubmatch(action[1], -1) {
+			names = append(names, field[1])
+		}
+	}
+	return names
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedMapKeys returns the keys of the map in sorted order.
+func sortedMapKeys(m map[string][]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
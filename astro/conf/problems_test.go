@@ -0,0 +1,36 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateFieldNames(t *testing.T) {
+	tests := map[string][]string{
+		"global/users.tfstate":                         nil,
+		"{{.aws_region}}/app-{{.environment}}.tfstate": {"aws_region", "environment"},
+		"{{ .region | printf \"%s\" }}":                {"region"},
+		"{{if .prod}}prod{{else}}{{$.env}}{{end}}":     {"prod", "env"},
+	}
+
+	for input, expected := range tests {
+		assert.Equal(t, expected, TemplateFieldNames(input), input)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultRedactPatterns are the regular expressions that are always
+// redacted from the plans and errors astro displays, in addition to the
+// ones in Project.Redact. As with those, if a pattern has a capture group,
+// only the first group is redacted.
+var DefaultRedactPatterns = []string{
+	// AWS secret access keys, on the line of an attribute or variable
+	// named like one, e.g. `secret_key: "" => "wJalr..."` in Terraform 0.11
+	// plans
+	`(?i)secret_?(?:access_?)?key[^\n]*?\b([A-Za-z0-9/+]{40})\b`,
+	// PEM private keys; the whole key if its end is in the output
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----(?s:.*?-----END [A-Z ]*PRIVATE KEY-----)?`,
+}
+
+// CompileRedactPatterns returns the compiled default redact patterns,
+// followed by the patterns.
+func CompileRedactPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := []*regexp.Regexp{}
+	for _, pattern := range append(append([]string{}, DefaultRedactPatterns...), patterns...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// validateRedactPatterns checks that the patterns are valid regular
+// expressions that don't match an empty string.
+func validateRedactPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %v", pattern, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("pattern %q: must not match an empty string", pattern)
+		}
+	}
+	return nil
+}
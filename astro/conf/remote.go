@@ -16,10 +16,63 @@
 
 package conf
 
+import (
+	"errors"
+	"strings"
+)
+
+// ModuleNamePlaceholder is replaced with the name of the module in
+// backend_config values, e.g. "{module_name}.tfstate".
+const ModuleNamePlaceholder = "{module_name}"
+
 // Remote is the static configuration of a remote for a Terraform module.
 type Remote struct {
 	// Backend is the backend type.
-	Backend string
+	Backend string `json:"backend,omitempty"`
 	// BackendConfig is a map of backend configuration parameters.
+	BackendConfig map[string]string `json:"backend_config,omitempty"`
+}
+
+// ApplyDefaultsFrom takes the default remote configuration and fills in the
+// backend if it isn't set, and any backend_config parameters that aren't set.
+func (r *Remote) ApplyDefaultsFrom(defaults Remote) {
+	if r.Backend == "" {
+		r.Backend = defaults.Backend
+	}
+	if len(defaults.BackendConfig) == 0 {
+		return
+	}
+	backendConfig := map[string]string{}
+	for key, value := range defaults.BackendConfig {
+		backendConfig[key] = value
+	}
+	for key, value := range r.BackendConfig {
+		backendConfig[key] = value
+	}
+	r.BackendConfig = backendConfig
+}
+
+// ReplaceModuleName replaces ModuleNamePlaceholder in backend_config values
+// with the name of the module.
+func (r *Remote) ReplaceModuleName(name string) {
+	for key, value := range r.BackendConfig {
+		r.BackendConfig[key] = strings.Replace(value, ModuleNamePlaceholder, name, -1)
+	}
+}
+
+// StateMigration describes where the state of a module was stored before its
+// backend configuration was changed, so that it can be moved to the new
+// location.
+type StateMigration struct {
+	// BackendConfig is the previous backend configuration. Parameters that
+	// aren't set here are taken from the module's current configuration.
 	BackendConfig map[string]string `json:"backend_config"`
 }
+
+// Validate checks the state migration configuration is good.
+func (m *StateMigration) Validate() error {
+	if len(m.BackendConfig) == 0 {
+		return errors.New("backend_config cannot be empty")
+	}
+	return nil
+}
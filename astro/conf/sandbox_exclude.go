@@ -0,0 +1,129 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AstroIgnoreFile is the name of the file at the top of a code root with
+// gitignore-style patterns of paths, relative to the code root, to leave
+// out of sandboxes.
+const AstroIgnoreFile = ".astroignore"
+
+// SandboxExclude returns the gitignore-style patterns of paths to leave out
+// of the module's sandboxes, relative to the base path of SandboxLayout:
+// the project's Exclude list and the .astroignore file of each code root,
+// each applied to every code root.
+func (m *Module) SandboxExclude() ([]string, error) {
+	basePath, _, _ := m.SandboxLayout()
+
+	patterns := []string{}
+	for _, root := range m.CodeRoots() {
+		rel, err := filepath.Rel(basePath, root)
+		if err != nil {
+			return nil, err
+		}
+
+		ignored, err := readAstroIgnore(root)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pattern := range append(append([]string{}, m.Exclude...), ignored...) {
+			patterns = append(patterns, scopeExcludePattern(filepath.ToSlash(rel), pattern))
+		}
+	}
+
+	return patterns, nil
+}
+
+// readAstroIgnore returns the patterns in the .astroignore file of the code
+// root, if it has one. Blank lines and comments are skipped.
+func readAstroIgnore(root string) ([]string, error) {
+	path := filepath.Join(root, AstroIgnoreFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		pattern := strings.TrimRight(scanner.Text(), " \t\r")
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		if err := validateExcludePattern(pattern); err != nil {
+			return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return patterns, nil
+}
+
+// validateExcludePattern checks that the gitignore-style pattern can be
+// matched.
+func validateExcludePattern(pattern string) error {
+	body := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(pattern, "!"), "/"), "/")
+	if body == "" {
+		return fmt.Errorf("invalid exclude pattern %q: matches nothing", pattern)
+	}
+	for _, segment := range strings.Split(body, "/") {
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %v", pattern, err)
+		}
+		if segment == ".." {
+			return fmt.Errorf("invalid exclude pattern %q: cannot refer to parent directories", pattern)
+		}
+	}
+	return nil
+}
+
+// scopeExcludePattern returns the pattern, relative to the code root at dir,
+// as a pattern relative to the base path that dir is relative to. Patterns
+// without a slash match at any depth below dir.
+func scopeExcludePattern(dir, pattern string) string {
+	negate := strings.HasPrefix(pattern, "!")
+	pattern = strings.TrimPrefix(pattern, "!")
+
+	if strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		pattern = "**/" + pattern
+	}
+	if dir != "." {
+		pattern = dir + "/" + pattern
+	}
+	pattern = "/" + pattern
+
+	if negate {
+		return "!" + pattern
+	}
+	return pattern
+}
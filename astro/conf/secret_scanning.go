@@ -0,0 +1,114 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// DefaultSecretPatterns are the regular expressions that secret scanning
+// uses unless they are disabled, by name.
+var DefaultSecretPatterns = map[string]string{
+	// AWS access key IDs, for long-term and temporary credentials
+	"aws_access_key_id": `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	// PEM private keys; the whole key if its end is in the output
+	"private_key": `-----BEGIN [A-Z ]*PRIVATE KEY-----(?s:.*?-----END [A-Z ]*PRIVATE KEY-----)?`,
+	// bearer tokens, e.g. in Authorization headers
+	"bearer_token": `(?i)\bbearer\s+[a-z0-9\-._~+/]{16,}=*`,
+}
+
+// SecretScanning configures the scanning of plan output for secrets, such
+// as provider attributes that aren't marked as sensitive. Matches are
+// redacted from the plans astro shows.
+type SecretScanning struct {
+	// Enabled turns on secret scanning.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Patterns are more regular expressions to scan for, by name. A
+	// pattern with the name of a default one replaces it.
+	Patterns map[string]string `json:"patterns,omitempty"`
+
+	// DisableDefaults is a list of names of default patterns not to use.
+	DisableDefaults []string `json:"disable_defaults,omitempty"`
+
+	// RedactLogs redacts the logs and JSON plans in the session repo too.
+	// By default, they keep the original output, as the secured copy for
+	// reviewers.
+	RedactLogs bool `json:"redact_logs,omitempty"`
+}
+
+// CompiledPatterns returns the names of the patterns to scan for, sorted,
+// and their compiled regular expressions. It returns nothing if scanning
+// isn't enabled.
+func (conf *SecretScanning) CompiledPatterns() ([]string, map[string]*regexp.Regexp, error) {
+	if !conf.Enabled {
+		return nil, nil, nil
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range conf.DisableDefaults {
+		disabled[name] = true
+	}
+
+	patterns := map[string]string{}
+	for name, pattern := range DefaultSecretPatterns {
+		if !disabled[name] {
+			patterns[name] = pattern
+		}
+	}
+	for name, pattern := range conf.Patterns {
+		patterns[name] = pattern
+	}
+
+	names := []string{}
+	compiled := map[string]*regexp.Regexp{}
+	for name, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("pattern %v: %v", name, err)
+		}
+		names = append(names, name)
+		compiled[name] = re
+	}
+	sort.Strings(names)
+
+	return names, compiled, nil
+}
+
+// Validate checks the secret scanning configuration is good.
+func (conf *SecretScanning) Validate() error {
+	for _, name := range conf.DisableDefaults {
+		if _, ok := DefaultSecretPatterns[name]; !ok {
+			return fmt.Errorf("disable_defaults: unknown default pattern: %v", name)
+		}
+	}
+	for name, pattern := range conf.Patterns {
+		if name == "" {
+			return fmt.Errorf("patterns: name cannot be empty")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %v: %v", name, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("pattern %v: must not match an empty string", name)
+		}
+	}
+	return nil
+}
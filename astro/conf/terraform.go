@@ -17,11 +17,11 @@
 package conf
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os/exec"
 
-	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/tvm"
 
 	version "github.com/burl/go-version"
@@ -33,10 +33,122 @@ type Terraform struct {
 	// Path is the path to the Terraform binary. Astro will use this
 	// if set, otherwise it will automatically download the version in
 	// Version below.
-	Path string
+	Path string `json:"path,omitempty"`
 	// Terraform version to use. If Path is empty, Astro will
 	// download this version automatically.
-	Version *version.Version
+	Version *version.Version `json:"version,omitempty"`
+	// VersionSpec is the version from the configuration if it is "latest"
+	// or a partial version, e.g. "0.12". Version is set to the release it
+	// resolves to when the configuration is loaded.
+	VersionSpec string `json:"-"`
+	// VersionFromCode makes astro use the version required by the
+	// required_version setting in the module's Terraform code. If Version is
+	// also set, it must meet that requirement.
+	VersionFromCode bool `json:"version_from_code,omitempty"`
+	// Parameters are additional Terraform parameters for every execution,
+	// added after the ones passed on the command line. They can reference
+	// variables, e.g. "-var-file={{.environment}}.tfvars".
+	Parameters []string `json:"parameters,omitempty"`
+	// DownloadMirror is a URL template to download Terraform from instead
+	// of releases.hashicorp.com, with {version}, {platform} and {arch}
+	// placeholders. It can only be set for the project, and takes
+	// precedence over TVM_MIRROR_URL.
+	DownloadMirror string `json:"download_mirror,omitempty"`
+	// Offline makes astro fail instead of downloading Terraform versions
+	// that aren't installed. It can only be set for the project; setting
+	// TVM_OFFLINE has the same effect.
+	Offline bool `json:"offline,omitempty"`
+	// Flavor is the distribution of Terraform to download: "terraform",
+	// the default, or "opentofu". Their command line interfaces are
+	// compatible, so executions run the same way with either.
+	Flavor string `json:"flavor,omitempty"`
+	// LockFile is how `terraform init` treats the module's dependency lock
+	// file, .terraform.lock.hcl, in Terraform 0.14 and later. By default,
+	// init may add to it; with "readonly", init uses the providers it
+	// records and fails if they don't meet the module's requirements, for
+	// reproducible provider selection.
+	LockFile string `json:"lockfile,omitempty"`
+}
+
+// LockFileReadonly is the LockFile value for reproducible provider
+// selection.
+const LockFileReadonly = "readonly"
+
+// UnmarshalJSON implements json.Unmarshaler. Besides full versions, e.g.
+// "0.11.7", the version can be "latest" or a partial version, e.g. "0.12",
+// which is kept in VersionSpec. As YAML reads an unquoted 0.12 as a number,
+// numbers are accepted too.
+func (conf *Terraform) UnmarshalJSON(b []byte) error {
+	// terraform has the fields, but not the methods, of Terraform
+	type terraform Terraform
+	var raw struct {
+		terraform
+		Version json.RawMessage `json:"version,omitempty"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*conf = Terraform(raw.terraform)
+
+	if len(raw.Version) == 0 || string(raw.Version) == "null" {
+		return nil
+	}
+	var versionString string
+	if err := json.Unmarshal(raw.Version, &versionString); err != nil {
+		var number json.Number
+		if json.Unmarshal(raw.Version, &number) != nil {
+			return fmt.Errorf("invalid Terraform version: %s", raw.Version)
+		}
+		versionString = number.String()
+	}
+
+	if tvm.IsVersionSpec(versionString) {
+		conf.VersionSpec = versionString
+		return nil
+	}
+	v, err := version.NewVersion(versionString)
+	if err != nil {
+		return fmt.Errorf("invalid Terraform version %q; must be a version, e.g. 0.11.7, a partial version, e.g. 0.12, or %s", versionString, tvm.LatestVersion)
+	}
+	conf.Version = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The version is written in the same
+// format it is read in, e.g. "0.11.7". If it was resolved from a version
+// spec, the spec is written as version_spec.
+func (conf Terraform) MarshalJSON() ([]byte, error) {
+	var versionString string
+	if conf.Version != nil {
+		versionString = conf.Version.String()
+	} else {
+		versionString = conf.VersionSpec
+	}
+	var versionSpec string
+	if conf.Version != nil {
+		versionSpec = conf.VersionSpec
+	}
+	return json.Marshal(struct {
+		Path            string   `json:"path,omitempty"`
+		Version         string   `json:"version,omitempty"`
+		VersionSpec     string   `json:"version_spec,omitempty"`
+		VersionFromCode bool     `json:"version_from_code,omitempty"`
+		Parameters      []string `json:"parameters,omitempty"`
+		DownloadMirror  string   `json:"download_mirror,omitempty"`
+		Offline         bool     `json:"offline,omitempty"`
+		Flavor          string   `json:"flavor,omitempty"`
+		LockFile        string   `json:"lockfile,omitempty"`
+	}{
+		Path:            conf.Path,
+		Version:         versionString,
+		VersionSpec:     versionSpec,
+		VersionFromCode: conf.VersionFromCode,
+		Parameters:      conf.Parameters,
+		DownloadMirror:  conf.DownloadMirror,
+		Offline:         conf.Offline,
+		Flavor:          conf.Flavor,
+		LockFile:        conf.LockFile,
+	})
 }
 
 // ApplyDefaultsFrom takes a Terraform struct representation the default
@@ -45,8 +157,21 @@ func (conf *Terraform) ApplyDefaultsFrom(defaultConf Terraform) {
 	if conf.Path == "" {
 		conf.Path = defaultConf.Path
 	}
-	if conf.Version == nil {
+	if conf.Version == nil && conf.VersionSpec == "" {
 		conf.Version = defaultConf.Version
+		conf.VersionSpec = defaultConf.VersionSpec
+	}
+	if conf.Parameters == nil {
+		conf.Parameters = defaultConf.Parameters
+	}
+	if conf.Flavor == "" {
+		conf.Flavor = defaultConf.Flavor
+	}
+	if conf.LockFile == "" {
+		conf.LockFile = defaultConf.LockFile
+	}
+	if defaultConf.VersionFromCode {
+		conf.VersionFromCode = true
 	}
 }
 
@@ -55,12 +180,11 @@ func (conf *Terraform) ApplyDefaultsFrom(defaultConf Terraform) {
 func (conf *Terraform) SetDefaultPath() error {
 	// If the existing project config doesn't specify a Terraform path,
 	// search for it in the current environment.
-	terraformPath, err := exec.LookPath("terraform")
+	terraformPath, err := exec.LookPath(conf.TVMFlavor().BinaryName())
 	if err != nil {
 		return err
 	}
 
-	logger.Trace.Printf("conf/terraform: setting Terraform path to: %v", terraformPath)
 	conf.Path = terraformPath
 
 	return nil
@@ -73,7 +197,6 @@ func (conf *Terraform) SetVersionFromBinary() error {
 		return fmt.Errorf("unable to detect Terraform version: %v", err)
 	}
 
-	logger.Trace.Printf("conf/terraform: set Terraform version to: %v", version)
 	conf.Version = version
 	return nil
 }
@@ -82,9 +205,32 @@ func (conf *Terraform) SetVersionFromBinary() error {
 func (conf *Terraform) Validate() (errs error) {
 	// Version must be set by the time astro runs; however, in the config it
 	// can be left blank and astro will detect and autofill the version from
-	// the Terraform in the user's environment.
-	if conf.Version == nil {
+	// the Terraform in the user's environment. With VersionFromCode, the
+	// version of each module comes from its code instead.
+	if conf.Version == nil && conf.VersionSpec == "" && !conf.VersionFromCode {
 		errs = multierror.Append(errs, errors.New("Version is not set"))
 	}
+	if conf.DownloadMirror != "" {
+		if err := tvm.ValidateMirrorURL(conf.DownloadMirror); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if conf.Flavor != "" {
+		if err := tvm.Flavor(conf.Flavor).Validate(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if conf.LockFile != "" && conf.LockFile != LockFileReadonly {
+		errs = multierror.Append(errs, fmt.Errorf("invalid lockfile %q; must be %q or unset", conf.LockFile, LockFileReadonly))
+	}
 	return errs
 }
+
+// TVMFlavor returns the tvm flavor of the configuration, which defaults to
+// Terraform.
+func (conf *Terraform) TVMFlavor() tvm.Flavor {
+	if conf.Flavor == "" {
+		return tvm.FlavorTerraform
+	}
+	return tvm.Flavor(conf.Flavor)
+}
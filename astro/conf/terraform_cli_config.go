@@ -0,0 +1,125 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// CLIConfigFileEnv is the environment variable that points Terraform to
+// its CLI configuration file.
+const CLIConfigFileEnv = "TF_CLI_CONFIG_FILE"
+
+// envNamePattern matches the names of environment variables that
+// credentials can be read from.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// TerraformCLIConfig is the part of the Terraform CLI configuration, the
+// .terraformrc file, that astro generates for every Terraform command of a
+// session, so that developers don't have to configure it themselves.
+type TerraformCLIConfig struct {
+	// Credentials are the API tokens of private registries and Terraform
+	// Cloud, by hostname. Tokens are read from environment variables; they
+	// can't be set in the config.
+	Credentials map[string]TerraformCredentials `json:"credentials,omitempty"`
+	// CredentialsHelper is the program that Terraform gets credentials
+	// from.
+	CredentialsHelper *TerraformCredentialsHelper `json:"credentials_helper,omitempty"`
+	// ProviderInstallation are the methods that Terraform 0.13 and later
+	// install providers with, in order.
+	ProviderInstallation []TerraformProviderInstallation `json:"provider_installation,omitempty"`
+	// PluginCacheDir is the plugin_cache_dir of the CLI configuration. It
+	// can't be set along with the project's plugin_cache_dir.
+	PluginCacheDir string `json:"plugin_cache_dir,omitempty"`
+}
+
+// TerraformCredentials are the credentials of a host.
+type TerraformCredentials struct {
+	// TokenEnv is the environment variable that holds the API token.
+	TokenEnv string `json:"token_env"`
+}
+
+// TerraformCredentialsHelper is a credentials_helper block.
+type TerraformCredentialsHelper struct {
+	// Name is the name of the helper, i.e. the program
+	// terraform-credentials-<name>.
+	Name string `json:"name"`
+	// Args are the arguments of the helper.
+	Args []string `json:"args,omitempty"`
+}
+
+// TerraformProviderInstallation is a method of a provider_installation
+// block.
+type TerraformProviderInstallation struct {
+	// Method is filesystem_mirror, network_mirror or direct.
+	Method string `json:"method"`
+	// Path is the directory of a filesystem_mirror.
+	Path string `json:"path,omitempty"`
+	// URL is the URL of a network_mirror.
+	URL string `json:"url,omitempty"`
+	// Include and Exclude are the provider address patterns that the
+	// method is used and not used for.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// IsSet returns whether any of the CLI configuration is set, i.e. whether
+// astro generates a CLI configuration file.
+func (conf TerraformCLIConfig) IsSet() bool {
+	return len(conf.Credentials) > 0 || conf.CredentialsHelper != nil || len(conf.ProviderInstallation) > 0 || conf.PluginCacheDir != ""
+}
+
+// Validate checks that credentials come from environment variables, and
+// that the provider installation methods are complete.
+func (conf TerraformCLIConfig) Validate() (errs error) {
+	hosts := []string{}
+	for host := range conf.Credentials {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		tokenEnv := conf.Credentials[host].TokenEnv
+		if tokenEnv == "" {
+			errs = multierror.Append(errs, fmt.Errorf("credentials of %s: token_env is required; tokens must be passed in environment variables, not in the config", host))
+		} else if !envNamePattern.MatchString(tokenEnv) {
+			errs = multierror.Append(errs, fmt.Errorf("credentials of %s: invalid environment variable name: %q", host, tokenEnv))
+		}
+	}
+	if conf.CredentialsHelper != nil && conf.CredentialsHelper.Name == "" {
+		errs = multierror.Append(errs, fmt.Errorf("credentials_helper: name is required"))
+	}
+	for i, method := range conf.ProviderInstallation {
+		switch method.Method {
+		case "filesystem_mirror":
+			if method.Path == "" {
+				errs = multierror.Append(errs, fmt.Errorf("provider_installation[%d]: filesystem_mirror needs a path", i))
+			}
+		case "network_mirror":
+			if method.URL == "" {
+				errs = multierror.Append(errs, fmt.Errorf("provider_installation[%d]: network_mirror needs a url", i))
+			}
+		case "direct":
+		default:
+			errs = multierror.Append(errs, fmt.Errorf("provider_installation[%d]: unknown method %q; must be one of: filesystem_mirror, network_mirror, direct", i, method.Method))
+		}
+	}
+	return errs
+}
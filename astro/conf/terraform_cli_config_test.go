@@ -0,0 +1,55 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerraformCLIConfigValidation(t *testing.T) {
+	valid := TerraformCLIConfig{
+		Credentials:       map[string]TerraformCredentials{"registry.example.com": {TokenEnv: "REGISTRY_TOKEN"}},
+		CredentialsHelper: &TerraformCredentialsHelper{Name: "vault", Args: []string{"--path=tf"}},
+		ProviderInstallation: []TerraformProviderInstallation{
+			{Method: "network_mirror", URL: "https://mirror.example.com/", Include: []string{"registry.terraform.io/*/*"}},
+			{Method: "direct", Exclude: []string{"registry.terraform.io/*/*"}},
+		},
+	}
+	assert.True(t, valid.IsSet())
+	assert.NoError(t, valid.Validate())
+	assert.False(t, TerraformCLIConfig{}.IsSet())
+
+	tests := []struct {
+		conf TerraformCLIConfig
+		err  string
+	}{
+		{conf: TerraformCLIConfig{Credentials: map[string]TerraformCredentials{"app.terraform.io": {}}}, err: "credentials of app.terraform.io: token_env is required; tokens must be passed in environment variables, not in the config"},
+		{conf: TerraformCLIConfig{Credentials: map[string]TerraformCredentials{"app.terraform.io": {TokenEnv: "$TOKEN"}}}, err: `credentials of app.terraform.io: invalid environment variable name: "$TOKEN"`},
+		{conf: TerraformCLIConfig{CredentialsHelper: &TerraformCredentialsHelper{}}, err: "credentials_helper: name is required"},
+		{conf: TerraformCLIConfig{ProviderInstallation: []TerraformProviderInstallation{{Method: "filesystem_mirror"}}}, err: "provider_installation[0]: filesystem_mirror needs a path"},
+		{conf: TerraformCLIConfig{ProviderInstallation: []TerraformProviderInstallation{{Method: "mirror"}}}, err: `provider_installation[0]: unknown method "mirror"`},
+	}
+
+	for _, tt := range tests {
+		err := tt.conf.Validate()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+}
@@ -16,14 +16,71 @@
 
 package conf
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+// Variable types. Values of list and map variables are encoded as JSON, see
+// EncodeListValue and EncodeMapValue.
+const (
+	VariableTypeString = "string"
+	VariableTypeList   = "list"
+	VariableTypeMap    = "map"
+)
+
 // Variable represents a variable that can be passed into a
 // Terraform module.
 type Variable struct {
 	// Name is the name/key of the variable.
-	Name string
+	Name string `json:"name"`
+	// Type is the type of the variable: string, list or map. Defaults to
+	// string.
+	Type string `json:"type,omitempty"`
 	// Values is a list of possible values for the variable. A value of nil
 	// means the possible values are unbound.
-	Values []string
+	Values []string `json:"values,omitempty"`
+	// ValuesCommand is a shell-like command whose output lines are the
+	// possible values for the variable. It cannot be used together with
+	// Values; when the configuration is loaded, its output is filled into
+	// Values.
+	ValuesCommand string `json:"values_command,omitempty"`
+	// Default is the value used when the user doesn't provide one. For a
+	// variable with Values, it must be one of them, and only executions for
+	// that value are run unless the user asks for another one.
+	Default string `json:"default,omitempty"`
+	// Sensitive variables are left out of execution IDs, and their values
+	// are masked in logs and status messages. They cannot have Values.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// Validate checks that the type is known, that the default value, if any, is
+// one of the possible values, and that sensitive, list and map variables
+// don't have values.
+func (v *Variable) Validate() error {
+	switch v.Type {
+	case "", VariableTypeString:
+	case VariableTypeList, VariableTypeMap:
+		if len(v.Values) > 0 {
+			return fmt.Errorf("%s variables cannot have values", v.Type)
+		}
+		if v.Default != "" {
+			return fmt.Errorf("%s variables cannot have a default", v.Type)
+		}
+	default:
+		return fmt.Errorf("unknown type %q; must be one of: %s, %s, %s", v.Type, VariableTypeString, VariableTypeList, VariableTypeMap)
+	}
+	if v.Sensitive && len(v.Values) > 0 {
+		return errors.New("sensitive variables cannot have values")
+	}
+	if v.Default != "" && len(v.Values) > 0 && !utils.StringSliceContains(v.Values, v.Default) {
+		return fmt.Errorf("default %q is not one of the allowed values: %s", v.Default, strings.Join(v.Values, ", "))
+	}
+	return nil
 }
 
 // IsFilter returns true if the command-line parameter acts as a filter
@@ -32,3 +89,28 @@ type Variable struct {
 func (v *Variable) IsFilter() bool {
 	return len(v.Values) > 0
 }
+
+// IsString returns true if the variable is a string variable.
+func (v *Variable) IsString() bool {
+	return v.Type == "" || v.Type == VariableTypeString
+}
+
+// EncodeListValue returns the value of a list variable with the specified
+// items.
+func EncodeListValue(items []string) string {
+	if items == nil {
+		items = []string{}
+	}
+	b, _ := json.Marshal(items)
+	return string(b)
+}
+
+// EncodeMapValue returns the value of a map variable with the specified
+// entries. Keys are sorted, so that the value is stable.
+func EncodeMapValue(entries map[string]string) string {
+	if entries == nil {
+		entries = map[string]string{}
+	}
+	b, _ := json.Marshal(entries)
+	return string(b)
+}
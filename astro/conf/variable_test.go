@@ -0,0 +1,73 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariableValidation(t *testing.T) {
+	tests := []struct {
+		variable Variable
+		err      string
+	}{
+		{variable: Variable{Name: "region", Default: "us-east-1"}},
+		{variable: Variable{Name: "env", Values: []string{"dev", "prod"}}},
+		{variable: Variable{Name: "env", Values: []string{"dev", "prod"}, Default: "dev"}},
+		{
+			variable: Variable{Name: "env", Values: []string{"dev", "prod"}, Default: "staging"},
+			err:      `default "staging" is not one of the allowed values: dev, prod`,
+		},
+		{variable: Variable{Name: "token", Sensitive: true}},
+		{
+			variable: Variable{Name: "token", Values: []string{"a"}, Sensitive: true},
+			err:      "sensitive variables cannot have values",
+		},
+		{variable: Variable{Name: "zones", Type: "list"}},
+		{variable: Variable{Name: "tags", Type: "map", Sensitive: true}},
+		{
+			variable: Variable{Name: "zones", Type: "list", Values: []string{"a"}},
+			err:      "list variables cannot have values",
+		},
+		{
+			variable: Variable{Name: "tags", Type: "map", Default: "a=b"},
+			err:      "map variables cannot have a default",
+		},
+		{
+			variable: Variable{Name: "zones", Type: "set"},
+			err:      `unknown type "set"; must be one of: string, list, map`,
+		},
+	}
+
+	for _, tt := range tests {
+		err := tt.variable.Validate()
+		if tt.err == "" {
+			assert.NoError(t, err, "variable: %+v", tt.variable)
+		} else {
+			assert.EqualError(t, err, tt.err, "variable: %+v", tt.variable)
+		}
+	}
+}
+
+func TestEncodeVariableValues(t *testing.T) {
+	assert.Equal(t, `[]`, EncodeListValue(nil))
+	assert.Equal(t, `["b","a"]`, EncodeListValue([]string{"b", "a"}))
+	assert.Equal(t, `{}`, EncodeMapValue(nil))
+	assert.Equal(t, `{"a":"1","b":"2"}`, EncodeMapValue(map[string]string{"b": "2", "a": "1"}))
+}
@@ -17,6 +17,7 @@
 package astro
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -25,85 +26,482 @@ import (
 
 	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/tvm"
+	"github.com/uber/astro/astro/utils"
 
 	"github.com/ghodss/yaml"
 )
 
+// errEmptyConfig is returned for configuration without any settings, e.g.
+// a new config file that only has comments. It explains the minimum
+// configuration, instead of failing on whatever is missing first.
+var errEmptyConfig = errors.New(`the configuration is empty; astro needs at least one module, e.g.:
+
+  modules:
+    - name: app
+      path: app
+
+where path is the directory of the module's Terraform code, relative to the
+config file. The terraform binary in PATH is used unless terraform.version
+is set; see the README for the other settings`)
+
+// ConfigOption is an option for loading project configuration.
+type ConfigOption func(*configOptions)
+
+type configOptions struct {
+	lenient          bool
+	offlineVariables bool
+	withoutTerraform bool
+
+	// lists the versions of a flavor available for version_from_code
+	installedTerraformVersions func(flavor tvm.Flavor) ([]string, error)
+
+	// resolves version specs of a flavor, e.g. "latest"
+	resolveTerraformVersion func(flavor tvm.Flavor, spec string) (string, error)
+
+	// receives the logs of loading the configuration; see WithConfigLogger
+	logger logger.Logger
+}
+
+// log returns the logger that loading the configuration logs to.
+func (o configOptions) log() logger.Logger {
+	if o.logger == nil {
+		return logger.Default()
+	}
+	return o.logger
+}
+
+// WithLenientConfig ignores keys in the configuration that astro doesn't know
+// about, instead of returning an error. This allows using configuration
+// written for a newer version of astro.
+func WithLenientConfig() ConfigOption {
+	return func(o *configOptions) {
+		o.lenient = true
+	}
+}
+
+// WithOfflineVariables uses the values that values_command last produced,
+// which are cached in the session repo, instead of running the commands.
+func WithOfflineVariables() ConfigOption {
+	return func(o *configOptions) {
+		o.offlineVariables = true
+	}
+}
+
+// WithoutTerraformDetection skips looking for the Terraform binary and
+// detecting its version. The resulting configuration can be used to inspect
+// the modules and their dependencies, but not to run Terraform.
+func WithoutTerraformDetection() ConfigOption {
+	return func(o *configOptions) {
+		o.withoutTerraform = true
+	}
+}
+
+// WithConfigLogger makes loading the configuration log to l instead of
+// logger.Default().
+func WithConfigLogger(l logger.Logger) ConfigOption {
+	return func(o *configOptions) {
+		o.logger = l
+	}
+}
+
 // NewConfigFromFile parses the configuration in the specified config file
-func NewConfigFromFile(configFilePath string) (*conf.Project, error) {
+func NewConfigFromFile(configFilePath string, opts ...ConfigOption) (*conf.Project, error) {
+	var options configOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.log().Debugf("config: reading config from file: \"%v\"", configFilePath)
+
 	yamlBytes, err := ioutil.ReadFile(configFilePath)
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := configFromYAML(yamlBytes, filepath.Dir(configFilePath))
+	config, err := configFromYAML(yamlBytes, filepath.Dir(configFilePath), opts...)
+	if err == errEmptyConfig {
+		return nil, fmt.Errorf("%s: %v", configFilePath, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load YAML from file: %s; %v", configFilePath, err)
 	}
+
+	// Modules from included files already have their file set
+	if absConfigFilePath, err := filepath.Abs(configFilePath); err == nil {
+		configFilePath = absConfigFilePath
+	}
+	for i := range config.Modules {
+		if config.Modules[i].ConfigFile == "" {
+			config.Modules[i].ConfigFile = configFilePath
+		}
+	}
+
 	return config, nil
 }
 
 // NewProjectFromConfigFile creates a new Project based on the specified
-// config file.
-func NewProjectFromConfigFile(configFilePath string) (*Project, error) {
-	logger.Trace.Printf("config: reading config from file: \"%v\"", configFilePath)
-
+// config file. Additional options are passed to NewProject.
+func NewProjectFromConfigFile(configFilePath string, opts ...Option) (*Project, error) {
 	config, err := NewConfigFromFile(configFilePath)
 	if err != nil {
 		return nil, err
 	}
-	return NewProject(WithConfig(*config))
+	return NewProject(append([]Option{WithConfig(*config)}, opts...)...)
 }
 
 // NewProjectFromYAML creates a new Project based on the specified YAML
-// config.
-func NewProjectFromYAML(yamlBytes []byte) (*Project, error) {
+// config. Additional options are passed to NewProject.
+func NewProjectFromYAML(yamlBytes []byte, opts ...Option) (*Project, error) {
 	config, err := configFromYAML(yamlBytes, "")
 	if err != nil {
 		return nil, err
 	}
 
-	return NewProject(WithConfig(*config))
+	return NewProject(append([]Option{WithConfig(*config)}, opts...)...)
 }
 
 // configFromYAML takes YAML bytes and returns a Project configuration
 // struct.
-func configFromYAML(yamlBytes []byte, rootPath string) (*conf.Project, error) {
-	var config conf.Project
+func configFromYAML(yamlBytes []byte, rootPath string, opts ...ConfigOption) (*conf.Project, error) {
+	var options configOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	log := options.log()
 
-	err := yaml.Unmarshal(yamlBytes, &config)
-	if err != nil {
-		return nil, err
+	if isEmptyConfig(yamlBytes) {
+		return nil, errEmptyConfig
 	}
 
 	// Convert rootPath to absolute
-	rootPath, err = filepath.Abs(rootPath)
+	rootPath, err := filepath.Abs(rootPath)
 	if err != nil {
 		return nil, err
 	}
 
-	// Rewrite paths to absolute
-	if err := rewriteConfigPaths(rootPath, &config); err != nil {
-		return nil, fmt.Errorf("failed to resolve relative paths in config file: %s; %v", rootPath, err)
+	config, err := loadConfig(yamlBytes, rootPath, options, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	// Set configuration defaults
-	if err := setDefaults(&config, rootPath); err != nil {
+	configuredPath := config.TerraformDefaults.Path
+	if err := setDefaults(config, rootPath, !options.withoutTerraform, log); err != nil {
+		return nil, err
+	}
+
+	// Resolve versions such as "latest" or "0.12". This has to be done
+	// after Terraform defaults are set, and before versions are resolved
+	// from code, which keeps configured versions.
+	if !options.withoutTerraform {
+		resolve := options.resolveTerraformVersion
+		if resolve == nil {
+			resolve = tvmVersionResolver(config)
+		}
+		if err := resolveTerraformVersionSpecs(config, resolve, log); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve versions required by the Terraform code. This has to be done
+	// after module paths and Terraform defaults are set.
+	if !options.withoutTerraform {
+		var pathFromEnv string
+		if configuredPath == "" {
+			pathFromEnv = config.TerraformDefaults.Path
+		}
+		listInstalled := options.installedTerraformVersions
+		if listInstalled == nil {
+			listInstalled = installedTerraformVersions
+		}
+		if err := setTerraformVersionsFromCode(config, pathFromEnv, listInstalled, log); err != nil {
+			return nil, err
+		}
+	}
+
+	// Run values commands. This has to be done after project variables are
+	// merged into modules.
+	if err := resolveVariableValues(config, rootPath, options.offlineVariables, log); err != nil {
 		return nil, err
 	}
 
 	// Fill in Terraform versions. This has to be done after paths are
 	// rewritten.
-	if err := setTerraformVersionFields(&config); err != nil {
+	if !options.withoutTerraform {
+		if err := setTerraformVersionFields(config, log); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// isEmptyConfig returns whether the YAML configuration has no settings,
+// i.e. it is empty or only has comments. Invalid YAML isn't empty, so that
+// it fails to load with the parse error.
+func isEmptyConfig(yamlBytes []byte) bool {
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(yamlBytes, &settings); err != nil {
+		return false
+	}
+	return len(settings) == 0
+}
+
+// loadConfig unmarshals YAML configuration, rewrites its relative paths to be
+// relative to rootPath, and merges in the configuration of any files it
+// includes. includeStack is the list of files currently being included, used
+// to detect cycles.
+func loadConfig(yamlBytes []byte, rootPath string, options configOptions, includeStack []string) (*conf.Project, error) {
+	var config conf.Project
+
+	err := yaml.Unmarshal(yamlBytes, &config)
+	if err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	if !options.lenient {
+		if err := checkUnknownConfigKeys(yamlBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(includeStack) > 0 {
+		for i := range config.Modules {
+			config.Modules[i].ConfigFile = includeStack[len(includeStack)-1]
+		}
+	}
+
+	// Rewrite paths to absolute
+	if err := rewriteConfigPaths(rootPath, &config, options.log()); err != nil {
+		return nil, fmt.Errorf("failed to resolve relative paths in config file: %s; %v", rootPath, err)
+	}
+
+	if len(config.Includes) == 0 {
+		return &config, nil
+	}
+
+	// Included files are merged in order, followed by this file, so that
+	// later files override earlier ones.
+	merged := &conf.Project{}
+	for _, includePath := range config.Includes {
+		if utils.StringSliceContains(includeStack, includePath) {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(includeStack, includePath), " -> "))
+		}
+
+		options.log().Debugf("config: including file: \"%v\"", includePath)
+
+		includeBytes, err := ioutil.ReadFile(includePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read included file: %v", err)
+		}
+
+		included, err := loadConfig(includeBytes, filepath.Dir(includePath), options, append(includeStack, includePath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load included file: %s; %v", includePath, err)
+		}
+
+		mergeConfig(merged, included)
+	}
+	mergeConfig(merged, &config)
+
+	merged.Includes = config.Includes
+
+	return merged, nil
 }
 
-// setDefaults fills in a bunch of default values for the config.
-func setDefaults(config *conf.Project, rootPath string) error {
-	logger.Trace.Printf("config: setting defaults, rootPath: \"%v\"", rootPath)
+// mergeConfig merges the src configuration into dst. Modules in src replace
+// modules in dst with the same name; other modules are appended. Flags in src
+// replace flags in dst for the same variable, environment variables and
+// backend_config parameters replace those with the same name, hooks are
+// appended and other settings are replaced if they are set in src.
+func mergeConfig(dst, src *conf.Project) {
+	if src.Flags != nil && dst.Flags == nil {
+		dst.Flags = map[string]conf.Flag{}
+	}
+	for variable, flag := range src.Flags {
+		dst.Flags[variable] = flag
+	}
+
+	if src.Env != nil && dst.Env == nil {
+		dst.Env = map[string]string{}
+	}
+	for name, value := range src.Env {
+		dst.Env[name] = value
+	}
+	for _, name := range src.SensitiveEnv {
+		if !utils.StringSliceContains(dst.SensitiveEnv, name) {
+			dst.SensitiveEnv = append(dst.SensitiveEnv, name)
+		}
+	}
+
+	dst.Hooks.Startup = append(dst.Hooks.Startup, src.Hooks.Startup...)
+	dst.Hooks.PreModuleRun = append(dst.Hooks.PreModuleRun, src.Hooks.PreModuleRun...)
+	dst.Hooks.PlanCheck = append(dst.Hooks.PlanCheck, src.Hooks.PlanCheck...)
+
+	moduleIndexes := map[string]int{}
+	for i, moduleConf := range dst.Modules {
+		moduleIndexes[moduleConf.Name] = i
+	}
+	for _, moduleConf := range src.Modules {
+		if i, ok := moduleIndexes[moduleConf.Name]; ok {
+			dst.Modules[i] = moduleConf
+		} else {
+			dst.Modules = append(dst.Modules, moduleConf)
+		}
+	}
+
+	variableIndexes := map[string]int{}
+	for i, variable := range dst.Variables {
+		variableIndexes[variable.Name] = i
+	}
+	for _, variable := range src.Variables {
+		if i, ok := variableIndexes[variable.Name]; ok {
+			dst.Variables[i] = variable
+		} else {
+			dst.Variables = append(dst.Variables, variable)
+		}
+	}
+
+	if src.ChangeBudget.MaxChangesPerExecution != 0 {
+		dst.ChangeBudget.MaxChangesPerExecution = src.ChangeBudget.MaxChangesPerExecution
+	}
+	if src.ChangeBudget.MaxTotalChanges != 0 {
+		dst.ChangeBudget.MaxTotalChanges = src.ChangeBudget.MaxTotalChanges
+	}
+	if src.ChangesPolicy.IsSet() {
+		dst.ChangesPolicy = src.ChangesPolicy
+	}
+	dst.Exclude = append(dst.Exclude, src.Exclude...)
+	if src.MaxAttachmentSize != 0 {
+		dst.MaxAttachmentSize = src.MaxAttachmentSize
+	}
+	if src.ClockSkewTolerance != "" {
+		dst.ClockSkewTolerance = src.ClockSkewTolerance
+	}
+	if src.LockRetry.Attempts != 0 {
+		dst.LockRetry.Attempts = src.LockRetry.Attempts
+	}
+	if src.LockRetry.Delay != "" {
+		dst.LockRetry.Delay = src.LockRetry.Delay
+	}
+	if src.LogDir != "" {
+		dst.LogDir = src.LogDir
+	}
+	if src.MetricsPush.URL != "" {
+		dst.MetricsPush.URL = src.MetricsPush.URL
+	}
+	if src.MetricsPush.Job != "" {
+		dst.MetricsPush.Job = src.MetricsPush.Job
+	}
+	if src.Notifications.WebhookURL != "" {
+		dst.Notifications = src.Notifications
+	}
+	if src.PluginCacheDir != "" {
+		dst.PluginCacheDir = src.PluginCacheDir
+	}
+	if src.PluginCacheWarnSize != 0 {
+		dst.PluginCacheWarnSize = src.PluginCacheWarnSize
+	}
+	if src.TerraformCLIConfig.IsSet() {
+		dst.TerraformCLIConfig = src.TerraformCLIConfig
+	}
+	if src.ReadOnly {
+		dst.ReadOnly = true
+	}
+	if src.TerraformExitCodes.Success != nil && dst.TerraformExitCodes.Success == nil {
+		dst.TerraformExitCodes.Success = map[string][]int{}
+	}
+	for subcommand, exitCodes := range src.TerraformExitCodes.Success {
+		dst.TerraformExitCodes.Success[subcommand] = exitCodes
+	}
+	if src.TerraformExitCodes.PlanChanges != 0 {
+		dst.TerraformExitCodes.PlanChanges = src.TerraformExitCodes.PlanChanges
+	}
+	if src.RequireSessionRepo {
+		dst.RequireSessionRepo = true
+	}
+	if src.Remote.Backend != "" {
+		dst.Remote.Backend = src.Remote.Backend
+	}
+	if src.Remote.BackendConfig != nil && dst.Remote.BackendConfig == nil {
+		dst.Remote.BackendConfig = map[string]string{}
+	}
+	for key, value := range src.Remote.BackendConfig {
+		dst.Remote.BackendConfig[key] = value
+	}
+	if src.VersionConcurrency != nil && dst.VersionConcurrency == nil {
+		dst.VersionConcurrency = map[string]int{}
+	}
+	for constraint, limit := range src.VersionConcurrency {
+		dst.VersionConcurrency[constraint] = limit
+	}
+	dst.Redact = append(dst.Redact, src.Redact...)
+	if src.SecretScanning.Enabled {
+		dst.SecretScanning.Enabled = true
+	}
+	if src.SecretScanning.Patterns != nil && dst.SecretScanning.Patterns == nil {
+		dst.SecretScanning.Patterns = map[string]string{}
+	}
+	for name, pattern := range src.SecretScanning.Patterns {
+		dst.SecretScanning.Patterns[name] = pattern
+	}
+	dst.SecretScanning.DisableDefaults = append(dst.SecretScanning.DisableDefaults, src.SecretScanning.DisableDefaults...)
+	if src.SecretScanning.RedactLogs {
+		dst.SecretScanning.RedactLogs = true
+	}
+	if src.SharedSandbox {
+		dst.SharedSandbox = true
+	}
+	if src.InitCache {
+		dst.InitCache = true
+	}
+	if src.SessionRepoDir != "" {
+		dst.SessionRepoDir = src.SessionRepoDir
+	}
+	if src.SessionDirMode != 0 {
+		dst.SessionDirMode = src.SessionDirMode
+	}
+	if src.SessionFileMode != 0 {
+		dst.SessionFileMode = src.SessionFileMode
+	}
+	if src.TerraformCodeRoot != "" {
+		dst.TerraformCodeRoot = src.TerraformCodeRoot
+	}
+	if src.TerraformCodeRoots != nil {
+		dst.TerraformCodeRoots = src.TerraformCodeRoots
+	}
+	if src.TerraformDefaults.Path != "" {
+		dst.TerraformDefaults.Path = src.TerraformDefaults.Path
+	}
+	if src.TerraformDefaults.Version != nil || src.TerraformDefaults.VersionSpec != "" {
+		dst.TerraformDefaults.Version = src.TerraformDefaults.Version
+		dst.TerraformDefaults.VersionSpec = src.TerraformDefaults.VersionSpec
+	}
+	if src.TerraformDefaults.Parameters != nil {
+		dst.TerraformDefaults.Parameters = src.TerraformDefaults.Parameters
+	}
+	if src.TerraformDefaults.DownloadMirror != "" {
+		dst.TerraformDefaults.DownloadMirror = src.TerraformDefaults.DownloadMirror
+	}
+	if src.TerraformDefaults.Offline {
+		dst.TerraformDefaults.Offline = true
+	}
+	if src.TerraformDefaults.VersionFromCode {
+		dst.TerraformDefaults.VersionFromCode = true
+	}
+	if src.TerraformDefaults.Flavor != "" {
+		dst.TerraformDefaults.Flavor = src.TerraformDefaults.Flavor
+	}
+	if src.TerraformDefaults.LockFile != "" {
+		dst.TerraformDefaults.LockFile = src.TerraformDefaults.LockFile
+	}
+}
+
+// setDefaults fills in a bunch of default values for the config. If
+// findTerraform is set, the Terraform binary is looked up in PATH when no
+// path or version is configured.
+func setDefaults(config *conf.Project, rootPath string, findTerraform bool, log logger.Logger) error {
+	log.Debugf("config: setting defaults, rootPath: \"%v\"", rootPath)
 
 	// For cases where we're creating a new project that is not from a
 	// configuration file (e.g. in tests), we'll use the current working
@@ -113,14 +511,20 @@ func setDefaults(config *conf.Project, rootPath string) error {
 		return err
 	}
 
-	if config.TerraformDefaults.Path == "" && config.TerraformDefaults.Version == nil {
+	// With version_from_code, Terraform is only looked up in PATH for modules
+	// whose code doesn't require a version.
+	if findTerraform && config.TerraformDefaults.Path == "" && config.TerraformDefaults.Version == nil && config.TerraformDefaults.VersionSpec == "" && !config.TerraformDefaults.VersionFromCode {
 		if err := config.TerraformDefaults.SetDefaultPath(); err != nil {
 			return err
 		}
+		log.Debugf("config: setting Terraform path to: %v", config.TerraformDefaults.Path)
 	}
 
 	// Terraform code root is the root path of the config file (if it was
 	// loaded from a file) otherwise is set to the current working dir.
+	if config.TerraformCodeRoot == "" && len(config.TerraformCodeRoots) > 0 {
+		config.TerraformCodeRoot = config.TerraformCodeRoots[0]
+	}
 	if config.TerraformCodeRoot == "" {
 		if rootPath != "" {
 			absRootPath, err := filepath.Abs(rootPath)
@@ -143,59 +547,134 @@ func setDefaults(config *conf.Project, rootPath string) error {
 
 	// Fill in module defaults
 	for i := range config.Modules {
-		logger.Trace.Printf("config: applying default TerraformCodeRoot: \"%v\"", config.TerraformCodeRoot)
+		log.Debugf("config: applying default TerraformCodeRoot: \"%v\"", config.TerraformCodeRoot)
 		config.Modules[i].Hooks.ApplyDefaultsFrom(config.Hooks)
+		config.Modules[i].ChangeBudget.ApplyDefaultsFrom(config.ChangeBudget)
 		config.Modules[i].TerraformCodeRoot = config.TerraformCodeRoot
+		config.Modules[i].TerraformCodeRoots = config.TerraformCodeRoots
+		config.Modules[i].Exclude = config.Exclude
 		config.Modules[i].Terraform.ApplyDefaultsFrom(config.TerraformDefaults)
+		config.Modules[i].ApplyEnvDefaultsFrom(*config)
+		if config.Modules[i].LocalState == "" {
+			config.Modules[i].Remote.ApplyDefaultsFrom(config.Remote)
+		}
+		config.Modules[i].Remote.ReplaceModuleName(config.Modules[i].Name)
+		config.Modules[i].Variables = mergeProjectVariables(config.Modules[i], config.Variables)
 	}
 
 	return nil
 }
 
+// mergeProjectVariables returns the variables of the module with the
+// project-level variables added, except for those the module defines itself
+// or excludes.
+func mergeProjectVariables(moduleConf conf.Module, projectVariables []conf.Variable) []conf.Variable {
+	variables := moduleConf.Variables
+	for _, variable := range projectVariables {
+		if utils.StringSliceContains(moduleConf.ExcludeProjectVariables, variable.Name) {
+			continue
+		}
+		defined := false
+		for _, moduleVariable := range moduleConf.Variables {
+			if moduleVariable.Name == variable.Name {
+				defined = true
+				break
+			}
+		}
+		if !defined {
+			variables = append(variables, variable)
+		}
+	}
+	return variables
+}
+
 // setTerraformVersionFields detects the Terraform version for any version
 // fields that are unset and fills it in.
-func setTerraformVersionFields(config *conf.Project) error {
-	if config.TerraformDefaults.Version == nil {
+func setTerraformVersionFields(config *conf.Project, log logger.Logger) error {
+	// With version_from_code, there may be no default binary to inspect
+	if config.TerraformDefaults.Version == nil && (config.TerraformDefaults.Path != "" || !config.TerraformDefaults.VersionFromCode) {
 		if err := config.TerraformDefaults.SetVersionFromBinary(); err != nil {
 			return err
 		}
+		log.Debugf("config: set Terraform version to: %v", config.TerraformDefaults.Version)
 	}
 	for i := range config.Modules {
 		if config.Modules[i].Terraform.Version == nil {
 			if err := config.Modules[i].Terraform.SetVersionFromBinary(); err != nil {
 				return err
 			}
+			log.Debugf("config: module %v: set Terraform version to: %v", config.Modules[i].Name, config.Modules[i].Terraform.Version)
 		}
 	}
 	return nil
 }
 
 // Rewrite relative paths in the config file to be absolute paths.
-func rewriteConfigPaths(rootPath string, config *conf.Project) error {
-	if err := rewriteRelPaths(rootPath, false,
+func rewriteConfigPaths(rootPath string, config *conf.Project, log logger.Logger) error {
+	if err := rewriteRelPaths(log, rootPath, false,
+		&config.LogDir,
+		&config.PluginCacheDir,
 		&config.SessionRepoDir,
 		&config.TerraformCodeRoot,
-		&config.TerraformDefaults.Path); err != nil {
+		&config.TerraformDefaults.Path,
+		&config.TerraformCLIConfig.PluginCacheDir); err != nil {
 		return err
 	}
 
-	if err := rewriteRelPathsInSlices(rootPath, config.Hooks.Startup, config.Hooks.PreModuleRun); err != nil {
+	for i := range config.TerraformCLIConfig.ProviderInstallation {
+		if err := rewriteRelPaths(log, rootPath, false, &config.TerraformCLIConfig.ProviderInstallation[i].Path); err != nil {
+			return err
+		}
+	}
+
+	// Each code root is resolved on its own, as they need not share a parent
+	for i := range config.TerraformCodeRoots {
+		if err := rewriteRelPaths(log, rootPath, false, &config.TerraformCodeRoots[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range config.Includes {
+		if err := rewriteRelPaths(log, rootPath, false, &config.Includes[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := rewriteRelPathsInSlices(log, rootPath, config.Hooks.Startup, config.Hooks.PreModuleRun, config.Hooks.PlanCheck); err != nil {
 		return err
 	}
 
-	for _, moduleConfig := range config.Modules {
-		if err := rewriteRelPathsInSlices(rootPath, moduleConfig.Hooks.PreModuleRun); err != nil {
+	for i := range config.Variables {
+		if err := rewriteRelPaths(log, rootPath, true, &config.Variables[i].ValuesCommand); err != nil {
 			return err
 		}
 	}
 
+	for i := range config.Modules {
+		moduleConfig := &config.Modules[i]
+		if err := rewriteRelPathsInSlices(log, rootPath, moduleConfig.Hooks.PreModuleRun, moduleConfig.Hooks.PlanCheck); err != nil {
+			return err
+		}
+		if persistPath := moduleConfig.LocalStatePersistPath(); persistPath != "" {
+			if err := rewriteRelPaths(log, rootPath, false, &persistPath); err != nil {
+				return err
+			}
+			moduleConfig.LocalState = conf.LocalStatePersist(persistPath)
+		}
+		for i := range moduleConfig.Variables {
+			if err := rewriteRelPaths(log, rootPath, true, &moduleConfig.Variables[i].ValuesCommand); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
 // rewriteRelPaths rewrites all relative paths to be absolute - relative to
 // the specified root dir. If the path is already absolute, it is left
 // untouched. If a path is empty, it is left empty.
-func rewriteRelPaths(root string, isCommand bool, relpaths ...*string) error {
+func rewriteRelPaths(log logger.Logger, root string, isCommand bool, relpaths ...*string) error {
 	for _, path := range relpaths {
 		if *path == "" {
 			continue
@@ -216,17 +695,17 @@ func rewriteRelPaths(root string, isCommand bool, relpaths ...*string) error {
 		}
 
 		newPath := filepath.Join(rootAbsPath, *path)
-		logger.Trace.Printf("config: rewriting path \"%v\" to \"%v\"", *path, newPath)
+		log.Debugf("config: rewriting path \"%v\" to \"%v\"", *path, newPath)
 		*path = newPath
 	}
 
 	return nil
 }
 
-func rewriteRelPathsInSlices(root string, relpaths ...[]conf.Hook) error {
+func rewriteRelPathsInSlices(log logger.Logger, root string, relpaths ...[]conf.Hook) error {
 	for i := range relpaths {
 		for j := range relpaths[i] {
-			if err := rewriteRelPaths(root, true, &relpaths[i][j].Command); err != nil {
+			if err := rewriteRelPaths(log, root, true, &relpaths[i][j].Command); err != nil {
 				return err
 			}
 		}
@@ -17,12 +17,16 @@
 package astro
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/tvm"
 	"github.com/uber/astro/astro/utils"
 
 	version "github.com/burl/go-version"
@@ -76,7 +80,7 @@ func TestRewritePathsInternal(t *testing.T) {
 
 	for i := range tests {
 		expected := absolutePath(tests[i])
-		rewriteRelPaths(absolutePath(""), false, &tests[i])
+		rewriteRelPaths(logger.Discard, absolutePath(""), false, &tests[i])
 		assert.Equal(t, expected, tests[i])
 	}
 }
@@ -105,6 +109,48 @@ func TestSessionRepoDir(t *testing.T) {
 	}
 }
 
+func TestSessionRepoDirNotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("skipping test since root can write to read-only directories")
+	}
+
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(tmpdir)
+
+	testConfigFilePath := filepath.Join(tmpdir, "test-session-repo-dir.yaml")
+	err = os.Link("fixtures/test-session-repo-dir/astro.yaml", testConfigFilePath)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Chmod(tmpdir, 0500))
+	defer os.Chmod(tmpdir, 0700)
+
+	config, err := NewConfigFromFile(testConfigFilePath)
+	require.NoError(t, err)
+
+	// falls back to a temporary session repo
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	path, reason := c.TemporarySessionRepo()
+	defer os.RemoveAll(path)
+
+	assert.NotEmpty(t, path)
+	assert.False(t, utils.IsWithinPath(tmpdir, path))
+	assert.Error(t, reason)
+	assert.False(t, utils.FileExists(filepath.Join(tmpdir, ".astro")))
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	assert.True(t, utils.IsWithinPath(path, session.path))
+
+	// unless the session repo is required
+	config.RequireSessionRepo = true
+	_, err = NewProject(WithConfig(*config))
+	assert.Error(t, err)
+}
+
 func TestUnmarshalTerraformVersion(t *testing.T) {
 	c, err := NewProjectFromConfigFile("fixtures/foosite.yaml")
 	require.NoError(t, err)
@@ -114,3 +160,540 @@ func TestUnmarshalTerraformVersion(t *testing.T) {
 
 	assert.Equal(t, expectedObj, c.config.TerraformDefaults.Version)
 }
+
+func TestUnknownConfigKeys(t *testing.T) {
+	tt := []struct {
+		name        string
+		yaml        string
+		expectedErr string
+	}{
+		{
+			name: "known keys",
+			yaml: `
+flags:
+  region:
+    name: aws-region
+    description: AWS region
+hooks:
+  pre_module_run:
+    - command: echo
+modules:
+  - name: foo
+    path: .
+    hooks:
+      pre_module_run:
+        - command: echo
+          set_env: true
+    Remote:
+      backend: s3
+      backend_config:
+        any_key: value
+    terraform:
+      version: 0.11.7
+`,
+		},
+		{
+			name: "top-level key",
+			yaml: `
+modules: []
+module_defaults: {}
+`,
+			expectedErr: "unknown key in configuration: module_defaults",
+		},
+		{
+			name: "module keys",
+			yaml: `
+modules:
+  - name: foo
+    path: .
+    terraform_code_root: /tmp
+  - name: bar
+    path: .
+    hooks:
+      pre_module_hook:
+        - command: echo
+    deps:
+      - module: foo
+        varaibles: {}
+`,
+			expectedErr: "unknown keys in configuration: modules[0].terraform_code_root, modules[1].deps[0].varaibles, modules[1].hooks.pre_module_hook",
+		},
+		{
+			name: "hook keys",
+			yaml: `
+hooks:
+  startup:
+    - command: echo
+      setenv: true
+  pre_module_hook:
+    - command: echo
+`,
+			expectedErr: "unknown keys in configuration: hooks.pre_module_hook, hooks.startup[0].setenv",
+		},
+		{
+			name: "terraform keys",
+			yaml: `
+terraform:
+  version: latest
+  versoin: 0.12
+`,
+			expectedErr: "unknown key in configuration: terraform.versoin",
+		},
+		{
+			name: "flag keys",
+			yaml: `
+flags:
+  region:
+    name: aws-region
+    desc: AWS region
+`,
+			expectedErr: "unknown key in configuration: flags.region.desc",
+		},
+		{
+			name: "anchors",
+			yaml: `
+module-config: &module-config
+  path: .
+modules:
+  - name: foo
+    <<: *module-config
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkUnknownConfigKeys([]byte(tc.yaml))
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestLenientConfigIgnoresUnknownKeys(t *testing.T) {
+	yaml := []byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: foo
+    path: .
+    hooks:
+      pre_module_hook:
+        - command: echo
+`)
+
+	_, err := configFromYAML(yaml, "")
+	assert.EqualError(t, err, "unknown key in configuration: modules[0].hooks.pre_module_hook")
+
+	config, err := configFromYAML(yaml, "", WithLenientConfig())
+	require.NoError(t, err)
+	assert.Equal(t, "foo", config.Modules[0].Name)
+}
+
+func TestConfigIncludes(t *testing.T) {
+	config, err := NewConfigFromFile("fixtures/test-includes/astro.yaml")
+	require.NoError(t, err)
+
+	moduleNames := []string{}
+	for _, moduleConf := range config.Modules {
+		moduleNames = append(moduleNames, moduleConf.Name)
+	}
+	// modules from later files replace earlier ones with the same name
+	assert.Equal(t, []string{"network", "app", "database"}, moduleNames)
+	assert.Equal(t, []string{"prod"}, config.Modules[1].Variables[0].Values)
+
+	assert.Equal(t, map[string]conf.Flag{
+		"environment": {Name: "env"},
+		"region":      {Name: "region"},
+	}, config.Flags)
+
+	// relative paths are resolved against the file they are in
+	assert.Equal(t, []conf.Hook{
+		{Command: absolutePath("fixtures/test-includes/common/hooks/startup")},
+	}, config.Hooks.Startup)
+	assert.Equal(t, absolutePath("fixtures/mock-terraform/success"), config.TerraformDefaults.Path)
+
+	// the code root and session repo are relative to the top-level file
+	assert.Equal(t, absolutePath("fixtures/test-includes"), config.TerraformCodeRoot)
+	assert.Equal(t, absolutePath("fixtures/test-includes"), config.SessionRepoDir)
+
+	assert.Equal(t, conf.FileMode(0700), config.SessionDirMode)
+	assert.Equal(t, conf.ChangesPolicy{IgnoreOutputChanges: true}, config.ChangesPolicy)
+}
+
+func TestConfigIncludeCycle(t *testing.T) {
+	_, err := NewConfigFromFile("fixtures/test-includes-cycle/astro.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("include cycle: %s -> %s -> %s",
+		absolutePath("fixtures/test-includes-cycle/a.yaml"),
+		absolutePath("fixtures/test-includes-cycle/b.yaml"),
+		absolutePath("fixtures/test-includes-cycle/a.yaml"),
+	))
+}
+
+func TestSessionModes(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	testConfigFilePath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(testConfigFilePath, []byte(fmt.Sprintf(`
+session_dir_mode: "0700"
+session_file_mode: "0600"
+terraform_code_root: %s
+terraform:
+  path: %s
+modules:
+  - name: foo
+    path: .
+`, codeRoot, absolutePath("fixtures/mock-terraform/success"))), 0644))
+
+	c, err := NewProjectFromConfigFile(testConfigFilePath)
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{
+		"foo": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	var dirs, files int
+	err = filepath.Walk(filepath.Join(tmpdir, ".astro"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir() && info.Name() == "sandbox":
+			// the sandbox contains links to the Terraform code
+			return filepath.SkipDir
+		case info.IsDir():
+			dirs++
+			assert.Equal(t, os.FileMode(0700), info.Mode().Perm(), path)
+		case info.Mode().IsRegular():
+			files++
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// .astro, the session, the execution and its logs directory
+	assert.True(t, dirs >= 4)
+	// at least the plan log
+	assert.True(t, files >= 1)
+
+	planFiles, err := filepath.Glob(filepath.Join(tmpdir, ".astro", "*", "foo", "sandbox", "foo.plan"))
+	require.NoError(t, err)
+	for _, planFile := range planFiles {
+		info, err := os.Stat(planFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), planFile)
+	}
+}
+
+func TestProjectVariables(t *testing.T) {
+	config, err := configFromYAML([]byte(`
+variables:
+  - name: owner
+  - name: region
+    values: [us-east-1, us-west-2]
+modules:
+  - name: app
+    path: .
+  - name: network
+    path: .
+    variables:
+      - name: region
+        values: [us-east-1]
+  - name: users
+    path: .
+    exclude_project_variables: [region]
+  - name: legacy
+    path: .
+    exclude_project_variables: [zone]
+`), "", WithoutTerraformDetection())
+	require.NoError(t, err)
+
+	assert.Equal(t, []conf.Variable{
+		{Name: "owner"},
+		{Name: "region", Values: []string{"us-east-1", "us-west-2"}},
+	}, config.Modules[0].Variables)
+
+	// module-level definitions win
+	assert.Equal(t, []conf.Variable{
+		{Name: "region", Values: []string{"us-east-1"}},
+		{Name: "owner"},
+	}, config.Modules[1].Variables)
+
+	assert.Equal(t, []conf.Variable{
+		{Name: "owner"},
+	}, config.Modules[2].Variables)
+
+	assert.Contains(t, config.Problems(), conf.Problem{
+		Path:    "modules[3].exclude_project_variables[0]",
+		Message: `unknown project variable "zone"`,
+	})
+}
+
+func TestEnabledWhenProblems(t *testing.T) {
+	config, err := configFromYAML([]byte(`
+modules:
+  - name: bastion
+    path: .
+    variables:
+      - name: environment
+        values: [mgmt, dev]
+    enabled_when:
+      environment: [mgmt, staging]
+      region: [us-east-1]
+`), "", WithoutTerraformDetection())
+	require.NoError(t, err)
+
+	problems := config.Problems()
+	assert.Contains(t, problems, conf.Problem{
+		Path:    "modules[0].enabled_when.environment[1]",
+		Message: `"staging" is not one of the allowed values: mgmt, dev`,
+	})
+	assert.Contains(t, problems, conf.Problem{
+		Path:    "modules[0].enabled_when.region",
+		Message: `references variable "region" that is not defined by the module`,
+	})
+}
+
+func TestTerraformParameters(t *testing.T) {
+	config, err := configFromYAML([]byte(`
+terraform:
+  parameters: [-compact-warnings]
+modules:
+  - name: app
+    path: .
+    terraform_parameters: ["-var-file={{.environment}}.tfvars"]
+    variables:
+      - name: environment
+  - name: network
+    path: .
+    terraform:
+      parameters: []
+    terraform_parameters: ["-var-file={{.region}}.tfvars"]
+`), "", WithoutTerraformDetection())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"-compact-warnings", "-var-file={{.environment}}.tfvars"}, config.Modules[0].AllTerraformParameters())
+	// module-level Terraform parameters replace the defaults
+	assert.Equal(t, []string{"-var-file={{.region}}.tfvars"}, config.Modules[1].AllTerraformParameters())
+
+	assert.Contains(t, config.Problems(), conf.Problem{
+		Path:    "modules[1].terraform_parameters[0]",
+		Message: `references variable "region" that is not defined by the module`,
+	})
+}
+
+func TestProjectRemote(t *testing.T) {
+	config, err := configFromYAML([]byte(`
+remote:
+  backend: s3
+  backend_config:
+    bucket: terraform-state
+    key: "{module_name}/{{.environment}}.tfstate"
+    region: us-east-1
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: environment
+  - name: users
+    path: .
+    remote:
+      backend_config:
+        key: global/users.tfstate
+        region: us-west-2
+  - name: scratch
+    path: .
+    local_state: ephemeral
+  - name: typo
+    path: .
+    remote:
+      backend_config:
+        key: "{module_nme}.tfstate"
+`), "", WithoutTerraformDetection())
+	require.NoError(t, err)
+
+	assert.Equal(t, conf.Remote{
+		Backend: "s3",
+		BackendConfig: map[string]string{
+			"bucket": "terraform-state",
+			"key":    "app/{{.environment}}.tfstate",
+			"region": "us-east-1",
+		},
+	}, config.Modules[0].Remote)
+
+	// module-level parameters win
+	assert.Equal(t, conf.Remote{
+		Backend: "s3",
+		BackendConfig: map[string]string{
+			"bucket": "terraform-state",
+			"key":    "global/users.tfstate",
+			"region": "us-west-2",
+		},
+	}, config.Modules[1].Remote)
+
+	// modules with local state don't use the default remote
+	assert.Equal(t, conf.Remote{}, config.Modules[2].Remote)
+
+	assert.Contains(t, config.Problems(), conf.Problem{
+		Path:    "modules[3].remote.backend_config.key",
+		Message: `unresolved placeholder in "{module_nme}.tfstate"`,
+	})
+}
+
+func TestTerraformVersionFromCode(t *testing.T) {
+	installed := func(o *configOptions) {
+		o.installedTerraformVersions = func(flavor tvm.Flavor) ([]string, error) {
+			return []string{"0.11.7", "0.12.1", "0.12.31", "0.13.0"}, nil
+		}
+	}
+
+	config, err := NewConfigFromFile("fixtures/test-version-from-code/astro.yaml", installed)
+	require.NoError(t, err)
+
+	// the newest installed version that meets "~> 0.12.0"
+	assert.Equal(t, "0.12.31", config.Modules[0].Terraform.Version.String())
+	// no required_version, so the configured version is used
+	assert.Equal(t, "0.11.7", config.Modules[1].Terraform.Version.String())
+	// an exact version is used even if it isn't installed yet
+	assert.Equal(t, "0.12.6", config.Modules[2].Terraform.Version.String())
+
+	_, err = NewConfigFromFile("fixtures/test-version-from-code/conflict.yaml", installed)
+	assert.Contains(t, fmt.Sprint(err), `module app: Terraform version 0.11.7 conflicts with required_version "~> 0.12.0" in its code`)
+}
+
+func TestTerraformVersionSpec(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`
+terraform:
+  version: 0.12
+modules:
+  - name: app
+    path: .
+  - name: network
+    path: .
+    terraform:
+      version: latest
+  - name: legacy
+    path: .
+    terraform:
+      version: 0.11.7
+`), 0644))
+
+	resolved := []string{}
+	resolver := func(o *configOptions) {
+		o.resolveTerraformVersion = func(flavor tvm.Flavor, spec string) (string, error) {
+			resolved = append(resolved, spec)
+			return map[string]string{"0.12": "0.12.31", "latest": "1.5.7"}[spec], nil
+		}
+	}
+
+	config, err := NewConfigFromFile(configPath, resolver)
+	require.NoError(t, err)
+
+	assert.Equal(t, "0.12.31", config.TerraformDefaults.Version.String())
+	assert.Equal(t, "0.12.31", config.Modules[0].Terraform.Version.String())
+	assert.Equal(t, "1.5.7", config.Modules[1].Terraform.Version.String())
+	assert.Equal(t, "latest", config.Modules[1].Terraform.VersionSpec)
+	assert.Equal(t, "0.11.7", config.Modules[2].Terraform.Version.String())
+	assert.Equal(t, "", config.Modules[2].Terraform.VersionSpec)
+	assert.Equal(t, []string{"0.12", "0.12", "latest"}, resolved)
+
+	// the spec is shown along with the version it resolved to
+	b, err := json.Marshal(config.Modules[1].Terraform)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"version":"1.5.7","version_spec":"latest"`)
+
+	// without Terraform detection, specs are left unresolved
+	config, err = NewConfigFromFile(configPath, WithoutTerraformDetection())
+	require.NoError(t, err)
+	assert.Nil(t, config.Modules[1].Terraform.Version)
+	assert.Equal(t, "latest", config.Modules[1].Terraform.VersionSpec)
+
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`
+terraform:
+  version: 0.12.x
+`), 0644))
+	_, err = NewConfigFromFile(configPath, resolver)
+	assert.Contains(t, fmt.Sprint(err), `invalid Terraform version "0.12.x"`)
+}
+
+func TestVariableValuesCommand(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	configYAML := func(command string) []byte {
+		return []byte(fmt.Sprintf(`
+variables:
+  - name: region
+    values_command: %s
+modules:
+  - name: app
+    path: .
+  - name: network
+    path: .
+    variables:
+      - name: region
+        values_command: %s
+`, command, command))
+	}
+
+	regions := absolutePath("fixtures/mock-values-command/regions")
+
+	// offline without a cache
+	_, err = configFromYAML(configYAML(regions), tmpdir, WithoutTerraformDetection(), WithOfflineVariables())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `variable "region" of project: no cached values for values_command`)
+
+	config, err := configFromYAML(configYAML(regions), tmpdir, WithoutTerraformDetection())
+	require.NoError(t, err)
+	for _, moduleConf := range config.Modules {
+		assert.Equal(t, []string{"us-east-1", "us-west-2"}, moduleConf.Variables[0].Values, moduleConf.Name)
+	}
+
+	// the command is only run once per load
+	calls, err := ioutil.ReadFile(filepath.Join(tmpdir, "values-command-calls"))
+	require.NoError(t, err)
+	assert.Equal(t, "called\n", string(calls))
+
+	// offline with the cache from the previous load
+	config, err = configFromYAML(configYAML(regions), tmpdir, WithoutTerraformDetection(), WithOfflineVariables())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"us-east-1", "us-west-2"}, config.Modules[1].Variables[0].Values)
+
+	_, err = configFromYAML(configYAML(absolutePath("fixtures/mock-values-command/empty")), tmpdir, WithoutTerraformDetection())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `variable "region" of project: unable to get values from values_command: command produced no values`)
+
+	_, err = configFromYAML(configYAML(absolutePath("fixtures/mock-values-command/fail")), tmpdir, WithoutTerraformDetection())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "service unavailable; consider setting static values instead")
+
+	_, err = configFromYAML([]byte(`
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: region
+        values: [us-east-1]
+        values_command: `+regions), tmpdir, WithoutTerraformDetection())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `variable "region" of module "app": values and values_command cannot both be set`)
+}
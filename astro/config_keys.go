@@ -0,0 +1,168 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/ghodss/yaml"
+)
+
+// matches top-level keys that define a YAML anchor, e.g.
+// "module-config: &module-config". These are commonly used to share settings
+// between modules and are not part of the configuration itself.
+var reTopLevelAnchorKey = regexp.MustCompile(`(?m)^([^\s#'"&*][^:]*?)\s*:\s*&\S+`)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// checkUnknownConfigKeys returns an error naming every key in the YAML
+// configuration that astro doesn't know about, e.g. because of a typo.
+func checkUnknownConfigKeys(yamlBytes []byte) error {
+	jsonBytes, err := yaml.YAMLToJSON(yamlBytes)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(jsonBytes, &value); err != nil {
+		return err
+	}
+
+	anchorKeys := map[string]bool{}
+	for _, match := range reTopLevelAnchorKey.FindAllSubmatch(yamlBytes, -1) {
+		anchorKeys[string(match[1])] = true
+	}
+
+	keys := []string{}
+	for _, key := range unknownKeys(value, reflect.TypeOf(conf.Project{}), "") {
+		if !anchorKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	switch len(keys) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("unknown key in configuration: %s", keys[0])
+	default:
+		return fmt.Errorf("unknown keys in configuration: %s", strings.Join(keys, ", "))
+	}
+}
+
+// unknownKeys walks the decoded JSON value and returns the paths of the keys
+// that don't match a field when it is unmarshalled into a value of type t,
+// e.g. "modules[0].hooks.pre_module_hook".
+func unknownKeys(value interface{}, t reflect.Type, path string) (keys []string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// Types that unmarshal themselves can't have unknown keys, except for
+	// structs like conf.Terraform that still decode objects into their
+	// fields. Other structs, e.g. versions, don't decode from objects.
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) && t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedObjectKeys(object) {
+			keyPath := joinKeyPath(path, key)
+			field, ok := structFieldForKey(t, key)
+			if !ok {
+				keys = append(keys, keyPath)
+				continue
+			}
+			keys = append(keys, unknownKeys(object[key], field.Type, keyPath)...)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedObjectKeys(object) {
+			keys = append(keys, unknownKeys(object[key], t.Elem(), joinKeyPath(path, key))...)
+		}
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range list {
+			keys = append(keys, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return keys
+}
+
+// structFieldForKey returns the field of the struct that a JSON key is
+// unmarshalled into. Like encoding/json, keys are matched case-insensitively.
+func structFieldForKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if tagName := strings.Split(tag, ",")[0]; tagName != "" {
+				name = tagName
+			}
+		}
+
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// joinKeyPath appends a key to a path in the configuration.
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedObjectKeys returns the keys of the decoded JSON object in sorted
+// order.
+func sortedObjectKeys(object map[string]interface{}) []string {
+	keys := []string{}
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/uber/astro/astro"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -37,3 +38,34 @@ func TestLoadInvalidYAML(t *testing.T) {
 	_, err := astro.NewProjectFromYAML([]byte(`invalid yaml: {`))
 	require.Error(t, err)
 }
+
+func TestLoadEmptyConfig(t *testing.T) {
+	t.Parallel()
+
+	for name, yaml := range map[string]string{
+		"empty":         "",
+		"comments only": "# astro configuration\n---\n# TODO: add modules\n",
+	} {
+		_, err := astro.NewProjectFromYAML([]byte(yaml))
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "the configuration is empty", name)
+		assert.Contains(t, err.Error(), "- name: app", name)
+	}
+}
+
+func TestPlanWithoutModules(t *testing.T) {
+	t.Parallel()
+
+	c, err := astro.NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules: []
+`))
+	require.NoError(t, err)
+
+	_, _, err = c.Plan(astro.NoPlanExecutionParameters())
+	assert.Equal(t, astro.ErrNoModules, err)
+
+	_, _, err = c.Apply(astro.ApplyExecutionParameters{ExecutionParameters: astro.NoExecutionParameters()})
+	assert.Equal(t, astro.ErrNoModules, err)
+}
@@ -0,0 +1,70 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"github.com/uber/astro/astro/report"
+)
+
+// reportSchemaVersion is the schema_version of the reports that the
+// project returns.
+const reportSchemaVersion = report.SchemaVersion
+
+// The documents that astro writes for other tools to read are defined in
+// the report package, which generates their JSON Schemas.
+type (
+	// Manifest is the metadata astro records about a session.
+	Manifest = report.Manifest
+	// Attachment is an external file attached to a session.
+	Attachment = report.Attachment
+	// ExecutionDuration is how long an execution took in a session.
+	ExecutionDuration = report.ExecutionDuration
+	// ExecutionVerification is the outcome of the verification of an
+	// apply, as recorded in the session manifest.
+	ExecutionVerification = report.ExecutionVerification
+	// ExecutionApply is what an apply of an execution applied, as recorded
+	// in the session manifest.
+	ExecutionApply = report.ExecutionApply
+	// ExecutionSkip is an apply that was skipped because the execution was
+	// unchanged, or an execution it depends on failed.
+	ExecutionSkip = report.ExecutionSkip
+	// ExecutionVariables are where the values of the variables of an
+	// execution came from, as recorded in the session manifest.
+	ExecutionVariables = report.ExecutionVariables
+	// VariableProvenance is the value of a variable and where it came
+	// from.
+	VariableProvenance = report.VariableProvenance
+
+	// CompatReport is the compatibility of the modules of a project with
+	// a Terraform version.
+	CompatReport = report.Compat
+	// ModuleCompat is the compatibility of a module with the target
+	// version.
+	ModuleCompat = report.ModuleCompat
+	// CompatFinding is a problem that keeps a module from being used with
+	// the target Terraform version.
+	CompatFinding = report.CompatFinding
+
+	// ScheduleReport is an estimate of how long running a command on the
+	// executions of a project takes.
+	ScheduleReport = report.Schedule
+	// ScheduledExecution is an execution in a ScheduleReport.
+	ScheduledExecution = report.ScheduledExecution
+	// ParallelismEstimate is the estimated duration of a run with a
+	// different parallelism.
+	ParallelismEstimate = report.ParallelismEstimate
+)
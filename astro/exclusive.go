@@ -0,0 +1,45 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"sync"
+)
+
+// exclusiveBarrier makes the executions of modules with `exclusive: true`
+// run alone. Other executions hold a read lock while they run, and
+// exclusive ones the write lock, so an exclusive execution waits for the
+// running executions to finish, and executions that become ready in the
+// meantime wait for it. Exclusive executions run one after another.
+type exclusiveBarrier struct {
+	mu sync.RWMutex
+}
+
+// acquire waits until the execution can run, alone if its module is
+// exclusive, and returns the function to call once it is done.
+func (e *exclusiveBarrier) acquire(status chan<- string, b *boundExecution) func() {
+	if !b.ModuleConfig().Exclusive {
+		e.mu.RLock()
+		return e.mu.RUnlock
+	}
+
+	status <- fmt.Sprintf("[%s] Waiting for other executions to finish, as the module is exclusive...", b.ID())
+	e.mu.Lock()
+	status <- fmt.Sprintf("[%s] Running exclusively; other executions are paused", b.ID())
+	return e.mu.Unlock
+}
@@ -0,0 +1,153 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+// installTimingTerraform installs a Terraform whose plans and applies take a
+// while, and record when they started and finished in a file in runs named
+// after the module in the MODULE environment variable.
+func installTimingTerraform(t *testing.T, dir string) (terraformPath, runs string) {
+	runs = filepath.Join(dir, "runs")
+	spec := fmt.Sprintf(`
+version: 0.12.6
+commands:
+  plan:
+    sleep: 200ms
+    touch: ['{{flag "out"}}']
+    write:
+      '%[1]s/{{env "MODULE"}}': '{{env "MODULE"}} {{.Start.UnixNano}} {{(now).UnixNano}}'
+  apply:
+    sleep: 200ms
+    write:
+      '%[1]s/{{env "MODULE"}}': '{{env "MODULE"}} {{.Start.UnixNano}} {{(now).UnixNano}}'
+`, runs)
+	specPath := filepath.Join(dir, "terraform.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(spec), 0644))
+	return mockterraform.InstallForTest(t, filepath.Join(dir, "bin"), specPath), runs
+}
+
+// interval is when a Terraform command of a module ran.
+type interval struct {
+	module     string
+	start, end int64
+}
+
+func readIntervals(t *testing.T, runs string) []interval {
+	files, err := ioutil.ReadDir(runs)
+	require.NoError(t, err)
+
+	intervals := []interval{}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(filepath.Join(runs, file.Name()))
+		require.NoError(t, err)
+		fields := strings.Fields(string(b))
+		require.Len(t, fields, 3)
+		start, err := strconv.ParseInt(fields[1], 10, 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseInt(fields[2], 10, 64)
+		require.NoError(t, err)
+		intervals = append(intervals, interval{module: fields[0], start: start, end: end})
+	}
+	return intervals
+}
+
+func TestExclusiveModules(t *testing.T) {
+	for _, command := range []string{"plan", "apply"} {
+		t.Run(command, func(t *testing.T) {
+			tmpdir, err := ioutil.TempDir("", "")
+			require.NoError(t, err)
+			defer os.RemoveAll(tmpdir)
+
+			codeRoot := filepath.Join(tmpdir, "code")
+			require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+			terraformPath, runs := installTimingTerraform(t, tmpdir)
+
+			config := fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+modules:
+`, codeRoot, tmpdir, terraformPath)
+			exclusive := map[string]bool{"bucket": true, "iam": true}
+			for _, module := range []string{"app", "bucket", "db", "iam", "web"} {
+				config += fmt.Sprintf(`
+  - name: %s
+    path: .
+    local_state: ephemeral
+    exclusive: %v
+    env:
+      MODULE: %s
+`, module, exclusive[module], module)
+			}
+			configPath := filepath.Join(tmpdir, "astro.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(config), 0644))
+
+			c, err := NewProjectFromConfigFile(configPath)
+			require.NoError(t, err)
+
+			var status <-chan string
+			var resultChan <-chan *Result
+			if command == "plan" {
+				status, resultChan, err = c.Plan(NoPlanExecutionParameters())
+			} else {
+				status, resultChan, err = c.Apply(ApplyExecutionParameters{ExecutionParameters: NoExecutionParameters()})
+			}
+			require.NoError(t, err)
+			for id, result := range testReadResults(resultChan) {
+				assert.NoError(t, result.Err(), id)
+			}
+
+			intervals := readIntervals(t, runs)
+			require.Len(t, intervals, 5)
+			for _, e := range intervals {
+				if !exclusive[e.module] {
+					continue
+				}
+				for _, other := range intervals {
+					if other.module == e.module {
+						continue
+					}
+					assert.True(t, other.end <= e.start || other.start >= e.end,
+						"%s ran while exclusive module %s was running", other.module, e.module)
+				}
+			}
+
+			messages := []string{}
+			for len(status) > 0 {
+				messages = append(messages, <-status)
+			}
+			assert.Contains(t, messages, "[bucket] Running exclusively; other executions are paused")
+			assert.Contains(t, messages, "[iam] Waiting for other executions to finish, as the module is exclusive...")
+		})
+	}
+}
@@ -16,6 +16,13 @@
 
 package exec2
 
+import (
+	"io"
+	"os"
+
+	"github.com/uber/astro/astro/logger"
+)
+
 // Cmd is the configuration struct for a process.
 type Cmd struct {
 	// Args is a list of arguments to provide to the process.
@@ -23,13 +30,30 @@ type Cmd struct {
 	// CombinedOutputLogFile is the path to a file where the process's
 	// stdout and stderr should be logged.
 	CombinedOutputLogFile string
+	// CombinedOutputLogFileMode is the mode the combined output log file is
+	// created with, before the umask is applied. Defaults to 0666.
+	CombinedOutputLogFileMode os.FileMode
 	// Command is the path to the process that you want to run
 	Command string
 	// Environment variables to use. If empty, set to current process's env.
 	Env []string
+	// LiveOutput receives the process's stdout and stderr while it runs, a
+	// whole line at a time, with sensitive values replaced. Lines of stdout
+	// and stderr are written from different goroutines, so it must be safe
+	// for concurrent use.
+	LiveOutput io.Writer
+	// Logger receives debug messages about the process, e.g. its command
+	// line and exit code. Defaults to logger.Default().
+	Logger logger.Logger
+	// LogEnv is a list of environment variables, in "NAME=value" form, that
+	// are written before the command line in the combined output log.
+	LogEnv []string
 	// ExpectedSuccessCodes is a list of exit codes the process will return if
 	// it completes successfully.
 	ExpectedSuccessCodes []int
+	// SensitiveValues are replaced with "<sensitive>" in the command line
+	// and environment variables written to the log file and to debug logs.
+	SensitiveValues []string
 	// WorkingDir is the working directory of the process.
 	WorkingDir string
 }
@@ -0,0 +1,57 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec2
+
+import (
+	"bytes"
+	"io"
+)
+
+// lineWriter passes what is written to it on to w a whole line at a time,
+// with redact applied, so that the lines of processes writing to the same
+// writer aren't mixed. Errors writing to w are ignored, as they must not
+// fail the process.
+type lineWriter struct {
+	w      io.Writer
+	redact func(string) string
+	buf    []byte
+}
+
+func newLineWriter(w io.Writer, redact func(string) string) *lineWriter {
+	return &lineWriter{w: w, redact: redact}
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.w.Write([]byte(l.redact(string(l.buf[:i+1]))))
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes the last line, if the process didn't end it with a newline.
+func (l *lineWriter) flush() {
+	if len(l.buf) > 0 {
+		l.w.Write([]byte(l.redact(string(l.buf)) + "\n"))
+		l.buf = nil
+	}
+}
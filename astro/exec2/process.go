@@ -23,6 +23,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -47,6 +48,7 @@ type Process struct {
 	execCmd      *exec.Cmd
 	stdoutBuffer *bytes.Buffer
 	stderrBuffer *bytes.Buffer
+	liveWriters  []*lineWriter
 	time         time.Duration
 }
 
@@ -58,7 +60,12 @@ func (p *Process) configureOutputs() error {
 	stderrWriters := []io.Writer{p.stderrBuffer}
 
 	if p.config.CombinedOutputLogFile != "" {
-		combinedOutputLog, err := os.Create(p.config.CombinedOutputLogFile)
+		mode := p.config.CombinedOutputLogFileMode
+		if mode == 0 {
+			mode = 0666
+		}
+
+		combinedOutputLog, err := os.OpenFile(p.config.CombinedOutputLogFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
 		if err != nil {
 			return err
 		}
@@ -66,7 +73,20 @@ func (p *Process) configureOutputs() error {
 		stdoutWriters = append(stdoutWriters, combinedOutputLog)
 		stderrWriters = append(stderrWriters, combinedOutputLog)
 
-		fmt.Fprintf(combinedOutputLog, "+ %s %s\n", p.config.Command, p.config.Args)
+		header := fmt.Sprintf("%s %s", p.config.Command, p.config.Args)
+		if len(p.config.LogEnv) > 0 {
+			header = fmt.Sprintf("%s %s", strings.Join(p.config.LogEnv, " "), header)
+		}
+		fmt.Fprintf(combinedOutputLog, "+ %s\n", p.redact(header))
+	}
+
+	if p.config.LiveOutput != nil {
+		p.liveWriters = []*lineWriter{
+			newLineWriter(p.config.LiveOutput, p.redact),
+			newLineWriter(p.config.LiveOutput, p.redact),
+		}
+		stdoutWriters = append(stdoutWriters, p.liveWriters[0])
+		stderrWriters = append(stderrWriters, p.liveWriters[1])
 	}
 
 	p.execCmd.Stdout = io.MultiWriter(stdoutWriters...)
@@ -75,6 +95,16 @@ func (p *Process) configureOutputs() error {
 	return nil
 }
 
+// redact replaces the sensitive values in s.
+func (p *Process) redact(s string) string {
+	for _, value := range p.config.SensitiveValues {
+		if value != "" {
+			s = strings.Replace(s, value, "<sensitive>", -1)
+		}
+	}
+	return s
+}
+
 // Process returns the Process field of underlying exec command
 // This allows us to interact with it, i.e. for sending signals
 func (p *Process) Process() *os.Process {
@@ -104,12 +134,20 @@ func (p *Process) Exited() bool {
 	return p.execCmd.ProcessState.Exited()
 }
 
+// log returns the logger of the process.
+func (p *Process) log() logger.Logger {
+	if p.config.Logger == nil {
+		return logger.Default()
+	}
+	return p.config.Logger
+}
+
 // Run runs the process.
 func (p *Process) Run() error {
 	command := p.config.Command
 	args := p.config.Args
 
-	logger.Trace.Printf("exec2: running command: %v; args: %v\n", command, args)
+	p.log().Debugf("exec2: running command: %v; args: %v\n", command, p.redact(fmt.Sprint(args)))
 	p.execCmd = exec.Command(command, args...)
 
 	// Apply options
@@ -148,14 +186,17 @@ func (p *Process) Run() error {
 				isInterrupted = true
 				errors = multierror.Append(fmt.Errorf("signal received: %s", sig))
 				process := p.execCmd.Process
-				logger.Trace.Printf("Signal: %s, process: %d\n", sig, process.Pid)
+				p.log().Debugf("Signal: %s, process: %d\n", sig, process.Pid)
 				if err := process.Signal(sig); err != nil {
 					errors = multierror.Append(errors, err)
 				}
 			case err := <-waitCh:
 				// Record run time
 				p.time = time.Since(started)
-				logger.Trace.Printf("exec2: command exit code: %v\n", p.ExitCode())
+				for _, w := range p.liveWriters {
+					w.flush()
+				}
+				p.log().Debugf("exec2: command exit code: %v\n", p.ExitCode())
 				// Return an error, if the command didn't exit with a success code
 				if !p.Success() {
 					errors = multierror.Append(errors, err)
@@ -182,6 +223,12 @@ func (p *Process) Stderr() *bytes.Buffer {
 	return p.stderrBuffer
 }
 
+// CombinedOutputLogFile returns the path of the file that the combined
+// output of the process is logged to, or an empty string if it isn't.
+func (p *Process) CombinedOutputLogFile() string {
+	return p.config.CombinedOutputLogFile
+}
+
 // Success returns whether or not the process has exited and if it
 // exited with a success code.
 func (p *Process) Success() bool {
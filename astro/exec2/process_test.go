@@ -17,14 +17,16 @@
 package exec2_test
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/uber/astro/astro/exec2"
-	"github.com/uber/astro/astro/utils"
+	"github.com/uber/astro/astro/tests/mockterraform"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -102,8 +104,11 @@ func TestExited(t *testing.T) {
 }
 
 func TestProcessInterrupted(t *testing.T) {
-	fakeTerraformPath := "../tests/fixtures/terraform"
-	require.True(t, utils.FileExists(fakeTerraformPath))
+	tmpdir, err := ioutil.TempDir("", "astro-exec2-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	fakeTerraformPath := mockterraform.InstallForTest(t, tmpdir, "fixtures/mock-terraform/interrupted.yaml")
 
 	process := exec2.NewProcess(exec2.Cmd{
 		Command: fakeTerraformPath,
@@ -135,3 +140,35 @@ func TestProcessInterrupted(t *testing.T) {
 	assert.True(t, process.Success())
 	assert.Equal(t, "Trapped: INT\n", process.Stdout().String())
 }
+
+// lockedBuffer is a bytes.Buffer that is safe for concurrent use.
+type lockedBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+	writes []string
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	b.writes = append(b.writes, string(p))
+	return b.Buffer.Write(p)
+}
+
+func TestLiveOutput(t *testing.T) {
+	live := &lockedBuffer{}
+	process := exec2.NewProcess(exec2.Cmd{
+		Command:         "/bin/sh",
+		Args:            []string{"-c", "printf 'Creating...'; sleep 0.1; echo ' hunter2'; echo uhoh! >&2; printf done"},
+		LiveOutput:      live,
+		SensitiveValues: []string{"hunter2"},
+	})
+
+	require.NoError(t, process.Run())
+
+	// whole lines are written, and the last one is ended
+	assert.ElementsMatch(t, []string{"Creating... <sensitive>\n", "uhoh!\n", "done\n"}, live.writes)
+
+	// the output of the process itself isn't redacted
+	assert.Equal(t, "Creating... hunter2\ndone", process.Stdout().String())
+}
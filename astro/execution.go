@@ -17,13 +17,23 @@
 package astro
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/uber/astro/astro/conf"
+
+	version "github.com/burl/go-version"
 )
 
+// matches characters that can't be used in execution IDs, which are used in
+// session paths
+var reUnsafeIDChars = regexp.MustCompile(`[^A-Za-z0-9._=+-]`)
+
 // MissingRequiredVarsError is an error type that is returned from plan or
 // apply when there are variables that need to be provided at run time that are
 // missing.
@@ -82,21 +92,28 @@ func (e *execution) ID() string {
 	// For boundExecutions, the ID should be:
 	// {modulename}-{variableValue1}-{variableValue2}-{and so on...}
 	// Where variableValues are the values of the runtime variables.
+	// Sensitive variables are left out, as the ID is used in status
+	// messages and paths in the session.
 
 	values := []string{}
 
 	// Since runtime variables may have values that don't directly
 	// pertain to this module/execution, we need to extract only the
 	// variable names that are relevant to this module.
+	variables := map[string]conf.Variable{}
 	keys := []string{}
 	for _, v := range e.ModuleConfig().Variables {
+		if v.Sensitive {
+			continue
+		}
+		variables[v.Name] = v
 		keys = append(keys, v.Name)
 	}
 
 	sort.Strings(keys)
 
 	for _, key := range keys {
-		values = append(values, e.variables[key])
+		values = append(values, idValue(variables[key], e.variables[key]))
 	}
 
 	// construct the ID
@@ -108,6 +125,41 @@ func (e *execution) ID() string {
 	return id
 }
 
+// idValue returns the value of a variable as it appears in execution IDs.
+// Items of list and map variables are joined with "+", e.g. "a+b" or
+// "k1=a+k2=b". If that isn't safe to use in a path, the unsafe characters are
+// replaced and a hash of the value is added, so that the ID stays unique.
+func idValue(variable conf.Variable, value string) string {
+	var items []string
+
+	switch variable.Type {
+	case conf.VariableTypeList:
+		if err := json.Unmarshal([]byte(value), &items); err != nil {
+			// not bound yet, e.g. "{{.zones}}"
+			return value
+		}
+	case conf.VariableTypeMap:
+		entries := map[string]string{}
+		if err := json.Unmarshal([]byte(value), &entries); err != nil {
+			return value
+		}
+		for key, val := range entries {
+			items = append(items, fmt.Sprintf("%s=%s", key, val))
+		}
+		sort.Strings(items)
+	default:
+		return value
+	}
+
+	joined := strings.Join(items, "+")
+	safe := reUnsafeIDChars.ReplaceAllString(joined, "_")
+	if safe == joined && safe != "" {
+		return safe
+	}
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("%s_%x", safe, sum[:4])
+}
+
 // ModuleConfig returns a copy of the configuration of the module
 // associated with this execution.
 func (e *execution) ModuleConfig() conf.Module {
@@ -133,24 +185,49 @@ type unboundExecution struct {
 	*execution
 }
 
-// bind takes a map of user-specified variables and returns a
-// boundExecution with variable values replaced. An error is returned if
-// not all required user values were provided.
-func (e *unboundExecution) bind(userVars map[string]string) (*boundExecution, error) {
+// bind takes a map of user-specified variables, and where they came from,
+// and returns a boundExecution with variable values replaced. Variables
+// without predefined values that the user didn't provide fall back to their
+// default values. An error is returned if not all required user values were
+// provided.
+func (e *unboundExecution) bind(userVars map[string]string, sources map[string]VariableSource) (*boundExecution, error) {
+	defaults := make(map[string]string)
+	for _, variable := range e.ModuleConfig().Variables {
+		if variable.Values == nil && variable.Default != "" {
+			defaults[variable.Name] = variable.Default
+		}
+	}
+
 	// boundVars is the map of execution variables bound to the values provided by user
 	boundVars := make(map[string]string)
+	// provenance is where each value in boundVars came from
+	provenance := make(map[string]VariableSource)
+
+	missingVars := []string{}
 
 	for key, val := range e.Variables() {
-		boundVars[key] = val
 		if userVal, ok := userVars[key]; ok {
 			boundVars[key] = userVal
+			provenance[key] = userSource(key, sources)
+			continue
+		}
+		if defaultVal, ok := defaults[key]; ok {
+			boundVars[key] = defaultVal
+			provenance[key] = configSource(e.ModuleConfig(), key, ValueFromDefault)
+			continue
 		}
-	}
 
-	missingVars := []string{}
-	// Check that the user provided variables replace everything that
-	// needs to be replaced.
-	for _, val := range boundVars {
+		boundVars[key] = val
+		provenance[key] = configSource(e.ModuleConfig(), key, ValueFromValues)
+		for _, variable := range e.ModuleConfig().Variables {
+			if variable.Name == key && variable.Default != "" && variable.Default == val {
+				provenance[key] = configSource(e.ModuleConfig(), key, ValueFromDefault)
+			}
+		}
+
+		// Check that the user provided variables replace everything that
+		// needs to be replaced. Values provided by the user aren't
+		// checked, as list and map values contain braces.
 		if err := assertAllVarsReplaced(val); err != nil {
 			missingVars = append(missingVars, extractMissingVarNames(val)...)
 		}
@@ -163,6 +240,7 @@ func (e *unboundExecution) bind(userVars map[string]string) (*boundExecution, er
 	// Create a copy of the config and search attributes for placeholders
 	// to replace with values from the bound vars.
 	boundConfig := e.ModuleConfig()
+	unresolved := unresolvedReferences(boundConfig, boundVars)
 
 	// TODO: Loop over all module configuration using reflection
 
@@ -172,12 +250,47 @@ func (e *unboundExecution) bind(userVars map[string]string) (*boundExecution, er
 	}
 	boundConfig.Remote.BackendConfig = boundBackendConfig
 
+	boundEnv, err := replaceAllVarsInMapValues(boundConfig.Env, boundVars)
+	if err != nil {
+		return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+	}
+	boundConfig.Env = boundEnv
+
+	boundLocalState, err := replaceAllVars(boundConfig.LocalState, boundVars)
+	if err != nil {
+		return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+	}
+	boundConfig.LocalState = boundLocalState
+
+	// The parameters from the module's configuration are at the end, after
+	// the ones passed by the user, which are used as is.
+	boundParameters := append([]string{}, e.TerraformParameters()...)
+	for i := len(boundParameters) - len(boundConfig.AllTerraformParameters()); i < len(boundParameters); i++ {
+		boundParameter, err := replaceAllVars(boundParameters[i], boundVars)
+		if err != nil {
+			return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+		}
+		boundParameters[i] = boundParameter
+	}
+
+	if boundConfig.StateMigration != nil {
+		boundMigrationBackendConfig, err := replaceAllVarsInMapValues(boundConfig.StateMigration.BackendConfig, boundVars)
+		if err != nil {
+			return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+		}
+		boundConfig.StateMigration = &conf.StateMigration{
+			BackendConfig: boundMigrationBackendConfig,
+		}
+	}
+
 	return &boundExecution{
-		&execution{
+		execution: &execution{
 			moduleConf:          &boundConfig,
 			variables:           boundVars,
-			terraformParameters: e.TerraformParameters(),
+			terraformParameters: boundParameters,
 		},
+		provenance: provenance,
+		unresolved: unresolved,
 	}, nil
 }
 
@@ -185,4 +298,32 @@ func (e *unboundExecution) bind(userVars map[string]string) (*boundExecution, er
 // executed.
 type boundExecution struct {
 	*execution
+
+	// provenance is the source of each variable value, e.g. the flag
+	// "--region"
+	provenance map[string]VariableSource
+	// unresolved are references in the module configuration to variables
+	// without a value
+	unresolved []string
+
+	// stateTerraformVersion is the version of Terraform that last wrote the
+	// state of the execution, if known; it is set once the state has been
+	// checked
+	stateTerraformVersion *version.Version
+
+	// lockFileChanged is set if `terraform init` changed the module's
+	// dependency lock file in the sandbox
+	lockFileChanged bool
+
+	// started is when the session started running the execution; see
+	// Result.Duration
+	started time.Time
+
+	// fingerprint is what the execution applies, if its module has
+	// skip_unchanged_applies; see skipUnchanged
+	fingerprint *ExecutionApply
+
+	// logDir is the directory of the Terraform logs of the execution; it is
+	// set once its Terraform session has been created
+	logDir string
 }
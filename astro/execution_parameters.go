@@ -20,15 +20,82 @@ type ExecutionParameters struct {
 	ModuleNames         []string
 	UserVars            *UserVariables
 	TerraformParameters []string
+	// SkipStateMigration disables migrating state for modules with a
+	// state_migration block.
+	SkipStateMigration bool
+	// StrictBinding fails the run if the configuration of a module
+	// references variables that aren't bound to a value.
+	StrictBinding bool
+	// OrderedStatus guarantees that all status updates of an execution are
+	// received before its result. Both channels must then be consumed until
+	// the results channel is closed, as neither is buffered.
+	OrderedStatus bool
 }
 
 type PlanExecutionParameters struct {
 	ExecutionParameters
 	Detach bool
+	// SavePlanJSON saves plans with changes as JSON too, so that their
+	// orphan resources can be found; see terraform.PlanResult.
+	SavePlanJSON bool
 }
 
 type ApplyExecutionParameters struct {
 	ExecutionParameters
+	// VerifyAfterApply runs a plan after each successful apply, and flags
+	// the executions whose plan still has changes; see Result.Verification.
+	// Modules can override it with verify_after_apply.
+	VerifyAfterApply bool
+	// NoSkipUnchanged applies the executions of modules with
+	// skip_unchanged_applies even if they are unchanged since their last
+	// successful apply.
+	NoSkipUnchanged bool
+}
+
+type RefreshExecutionParameters struct {
+	ExecutionParameters
+}
+
+type ImportExecutionParameters struct {
+	ExecutionParameters
+	// Module is the name of the module to import into. The user variables
+	// must narrow it down to a single execution.
+	Module string
+	// Address is the Terraform resource address to import into, e.g.
+	// "aws_instance.web".
+	Address string
+	// ID is the provider-specific ID of the existing resource.
+	ID string
+}
+
+type StateExecutionParameters struct {
+	ExecutionParameters
+	// Module is the name of the module whose state to operate on. The user
+	// variables must narrow it down to a single execution.
+	Module string
+	// Args are the arguments to `terraform state`, starting with its
+	// subcommand, e.g. ["list"].
+	Args []string
+}
+
+type TaintExecutionParameters struct {
+	ExecutionParameters
+	// Module is the name of the module whose resource to taint. The user
+	// variables must narrow it down to a single execution.
+	Module string
+	// Address is the Terraform resource address to taint, e.g.
+	// "aws_instance.web".
+	Address string
+}
+
+type UnlockExecutionParameters struct {
+	ExecutionParameters
+	// Module is the name of the module whose state to unlock. The user
+	// variables must narrow it down to a single execution.
+	Module string
+	// LockID is the ID of the state lock, as printed by the Terraform
+	// command that failed to acquire it.
+	LockID string
 }
 
 func NoExecutionParameters() ExecutionParameters {
@@ -0,0 +1,169 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+)
+
+// matches placeholders in the IDs of unbound executions, after quoting, e.g.
+// `\{aws_region\}`
+var reQuotedPlaceholder = regexp.MustCompile(`\\\{[A-Za-z_]\w*\\\}`)
+
+// ExecutionPaths are the absolute paths of the files of an execution in a
+// session.
+type ExecutionPaths struct {
+	// ID is the ID of the execution.
+	ID string
+	// Sandbox is the directory of the module in the execution's sandbox.
+	Sandbox string
+	// Logs is the directory containing the Terraform logs.
+	Logs string
+	// Plan is the plan file, or empty if the execution didn't save one.
+	Plan string
+
+	// the configuration of the execution's module, and the root of its
+	// sandbox
+	moduleConfig conf.Module
+	sandboxRoot  string
+}
+
+// ExecutionPathTypes are the types of path that can be passed to
+// ExecutionPaths.Path.
+var ExecutionPathTypes = []string{"sandbox", "logs", "plan"}
+
+// Path returns the path of the specified type: "sandbox", "logs" or "plan".
+func (p *ExecutionPaths) Path(what string) (string, error) {
+	switch what {
+	case "sandbox":
+		return p.Sandbox, nil
+	case "logs":
+		return p.Logs, nil
+	case "plan":
+		if p.Plan == "" {
+			return "", fmt.Errorf("execution %v has no plan file", p.ID)
+		}
+		return p.Plan, nil
+	}
+	return "", fmt.Errorf("unknown path type: %q; must be one of: %s", what, strings.Join(ExecutionPathTypes, ", "))
+}
+
+// executionModules returns the configuration of the modules that could have
+// an execution with the ID. There can be more than one when the ID matches
+// the executions of modules with similar names, e.g. "app-{env}" and
+// "app-db".
+func executionModules(config conf.Project, id string) []conf.Module {
+	modules := []conf.Module{}
+	seen := map[string]bool{}
+	for _, e := range allExecutions(config) {
+		pattern := reQuotedPlaceholder.ReplaceAllString(regexp.QuoteMeta(e.ID()), ".+")
+		if !regexp.MustCompile("^" + pattern + "$").MatchString(id) {
+			continue
+		}
+		moduleConfig := e.ModuleConfig()
+		if !seen[moduleConfig.Name] {
+			seen[moduleConfig.Name] = true
+			modules = append(modules, moduleConfig)
+		}
+	}
+	return modules
+}
+
+// ExecutionPaths returns the paths of the execution in the session. The
+// execution ID must match an execution of the configuration and the
+// execution must exist in the session. Only the directory layout of the
+// session is used, so this works with sessions created by other versions of
+// astro.
+func (s *Session) ExecutionPaths(id string) (*ExecutionPaths, error) {
+	modules := executionModules(*s.repo.project.config, id)
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("%q is not an execution of any module in the configuration", id)
+	}
+
+	executionDir, err := filepath.Abs(filepath.Join(s.path, id))
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Base(executionDir) != id || !utils.IsDirectory(executionDir) {
+		return nil, fmt.Errorf("execution %v not found in session %v", id, s.id)
+	}
+
+	for _, moduleConfig := range modules {
+		_, modulePath, _ := moduleConfig.SandboxLayout()
+		sandboxRoot := filepath.Join(executionDir, "sandbox")
+		if !utils.IsDirectory(sandboxRoot) {
+			// The execution used the module's shared sandbox
+			sandboxRoot = s.sharedSandboxDir(moduleConfig.Name)
+		}
+		sandbox := filepath.Join(sandboxRoot, modulePath)
+		if !utils.IsDirectory(sandbox) {
+			continue
+		}
+
+		paths := &ExecutionPaths{
+			ID:           id,
+			Sandbox:      sandbox,
+			Logs:         filepath.Join(executionDir, "logs"),
+			moduleConfig: moduleConfig,
+			sandboxRoot:  sandboxRoot,
+		}
+		if plan := filepath.Join(sandbox, fmt.Sprintf("%s.plan", id)); utils.FileExists(plan) {
+			paths.Plan = plan
+		}
+		return paths, nil
+	}
+
+	return nil, fmt.Errorf("sandbox of execution %v not found in session %v", id, s.id)
+}
+
+// AllExecutionPaths returns the paths of every execution in the session,
+// sorted by ID. Directories in the session that aren't executions of the
+// configuration are ignored.
+func (s *Session) AllExecutionPaths() ([]*ExecutionPaths, error) {
+	entries, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		// Skip files and hidden directories, e.g. ".tmp"
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+
+	all := []*ExecutionPaths{}
+	for _, id := range ids {
+		paths, err := s.ExecutionPaths(id)
+		if err != nil {
+			continue
+		}
+		all = append(all, paths)
+	}
+
+	return all, nil
+}
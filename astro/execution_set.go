@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
 
 	"github.com/hashicorp/terraform/dag"
 )
@@ -30,7 +31,7 @@ type executionSet []terraformExecution
 // bindAll takes a set of unboundExecutions and returns a new set with
 // all executions bound to userVars. An error is thrown if any of the
 // executions in the current set are already bound.
-func (s executionSet) bindAll(userVars map[string]string) ([]*boundExecution, error) {
+func (s executionSet) bindAll(userVars *UserVariables) ([]*boundExecution, error) {
 	results := []*boundExecution{}
 	for _, e := range s {
 		unbound, ok := e.(*unboundExecution)
@@ -38,7 +39,7 @@ func (s executionSet) bindAll(userVars map[string]string) ([]*boundExecution, er
 			return nil, fmt.Errorf("cannot bind executions: %v not of type unboundExecution", e)
 		}
 
-		bound, err := unbound.bind(userVars)
+		bound, err := unbound.bind(userVars.Values, userVars.Sources)
 		if err != nil {
 			return nil, err
 		}
@@ -92,8 +93,11 @@ func (s executionSet) filterByDep(dep conf.Dependency) (executionSet, error) {
 	return dependentExecutions, nil
 }
 
-// graph returns an acyclic graph of executions in this set.
-func (s executionSet) graph() (*dag.AcyclicGraph, error) {
+// graph returns an acyclic graph of executions in this set. moduleConfigs is
+// the configuration of the modules in the project; dependencies on modules
+// that enabled_when disables for the variable values of the dependent
+// execution are left out, rather than being reported as missing.
+func (s executionSet) graph(moduleConfigs []conf.Module, log logger.Logger) (*dag.AcyclicGraph, error) {
 	graph := &dag.AcyclicGraph{}
 
 	// Add all executions to the graph to start off with
@@ -113,6 +117,11 @@ func (s executionSet) graph() (*dag.AcyclicGraph, error) {
 			}
 			dep.Variables = vars
 
+			if !dependencyEnabled(moduleConfigs, e, dep) {
+				log.Debugf("astro: ignoring dependency of %v on %v as it is not enabled", e.ID(), dep.Module)
+				continue
+			}
+
 			dependentExecutions, err := s.filterByDep(dep)
 			if err != nil {
 				return nil, fmt.Errorf("invalid dependency for %s: %v", e.ModuleConfig().Name, err)
@@ -127,3 +136,27 @@ func (s executionSet) graph() (*dag.AcyclicGraph, error) {
 
 	return graph, nil
 }
+
+// dependencyEnabled returns whether the module of the dependency is enabled
+// for the variable values of the dependent execution, overridden by the
+// values of the dependency.
+func dependencyEnabled(moduleConfigs []conf.Module, e terraformExecution, dep conf.Dependency) bool {
+	for _, moduleConfig := range moduleConfigs {
+		if moduleConfig.Name != dep.Module {
+			continue
+		}
+
+		values := map[string]string{}
+		for _, variables := range []map[string]string{e.Variables(), dep.Variables} {
+			for name, value := range variables {
+				// skip placeholders of values that aren't known yet
+				if assertAllVarsReplaced(value) == nil {
+					values[name] = value
+				}
+			}
+		}
+
+		return moduleConfig.IsEnabledFor(values)
+	}
+	return true
+}
@@ -0,0 +1 @@
+module "vpc" { source = "../../shared-modules/vpc" }
@@ -0,0 +1 @@
+# shared VPC module
@@ -0,0 +1 @@
+# not part of any code root
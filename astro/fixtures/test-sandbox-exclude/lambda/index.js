@@ -0,0 +1 @@
+exports.handler = () => {}
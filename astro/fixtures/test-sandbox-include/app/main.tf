@@ -0,0 +1 @@
+module "shared" { source = "../modules/shared" }
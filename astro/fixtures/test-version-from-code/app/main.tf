@@ -0,0 +1,8 @@
+terraform {
+  backend "s3" {}
+  required_version = "~> 0.12.0"
+}
+
+provider "aws" {
+  region = var.aws_region
+}
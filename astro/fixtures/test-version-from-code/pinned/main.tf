@@ -0,0 +1,7 @@
+terraform {
+  required_version = "= 0.12.6"
+}
+
+provider "aws" {
+  region = var.aws_region
+}
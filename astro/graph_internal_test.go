@@ -17,9 +17,12 @@
 package astro
 
 import (
+	"sort"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber/astro/astro/logger"
 )
 
 func TestGraph(t *testing.T) {
@@ -28,7 +31,7 @@ func TestGraph(t *testing.T) {
 	c, err := NewProjectFromConfigFile("fixtures/test-graph/astro.yaml")
 	require.NoError(t, err)
 
-	graph, err := c.executions(NoExecutionParameters()).graph()
+	graph, err := c.executions(NoExecutionParameters()).graph(c.config.Modules, logger.Discard)
 	require.NoError(t, err)
 	require.NoError(t, graph.Validate())
 	graph.TransitiveReduction()
@@ -36,3 +39,151 @@ func TestGraph(t *testing.T) {
 	_, err = graph.Root()
 	require.NoError(t, err)
 }
+
+func TestDependencyGraph(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-graph/astro.yaml", WithoutTerraformDetection())
+	require.NoError(t, err)
+
+	graph, err := NewDependencyGraph(*config)
+	require.NoError(t, err)
+
+	t.Run("dependents of module", func(t *testing.T) {
+		impact, err := graph.Dependents("mgmt-vpc")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"mgmt-vpc-{aws_region}-mgmt"}, impact.Executions)
+		assert.Equal(t, [][]string{
+			{"mgmt-{aws_region}-mgmt"},
+			{
+				"dev-{aws_region}-dev",
+				"prod-{aws_region}-prod",
+				"staging-{aws_region}-staging",
+				"vpc-peering-{aws_region}-dev",
+				"vpc-peering-{aws_region}-mgmt",
+				"vpc-peering-{aws_region}-prod",
+				"vpc-peering-{aws_region}-staging",
+				"vpns-{aws_region}",
+			},
+		}, impact.Levels)
+	})
+
+	t.Run("dependents of execution", func(t *testing.T) {
+		impact, err := graph.Dependents("vpc-{aws_region}-dev")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"dev-{aws_region}-dev"},
+			{
+				"vpc-peering-{aws_region}-dev",
+				"vpc-peering-{aws_region}-mgmt",
+				"vpc-peering-{aws_region}-prod",
+				"vpc-peering-{aws_region}-staging",
+				"vpns-{aws_region}",
+			},
+		}, impact.Levels)
+	})
+
+	t.Run("dependencies", func(t *testing.T) {
+		impact, err := graph.Dependencies("vpns")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{
+				"dev-{aws_region}-dev",
+				"mgmt-{aws_region}-mgmt",
+				"prod-{aws_region}-prod",
+				"staging-{aws_region}-staging",
+			},
+			{
+				"mgmt-vpc-{aws_region}-mgmt",
+				"vpc-{aws_region}-dev",
+				"vpc-{aws_region}-prod",
+				"vpc-{aws_region}-staging",
+			},
+			{"global"},
+		}, impact.Levels)
+	})
+
+	t.Run("nothing reachable", func(t *testing.T) {
+		impact, err := graph.Dependencies("global")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{}, impact.Levels)
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		_, err := graph.Dependents("foo")
+		assert.EqualError(t, err, `unknown module or execution: "foo"`)
+
+		_, err = graph.Dependents("vpc-us-east-1-dev")
+		assert.EqualError(t, err, `unknown module or execution: "vpc-us-east-1-dev"; did you mean one of: `+
+			`vpc-{aws_region}-dev, vpc-{aws_region}-prod, vpc-{aws_region}-staging`)
+
+		_, err = graph.Dependents("peering")
+		assert.EqualError(t, err, `unknown module or execution: "peering"; did you mean one of: `+
+			`vpc-peering-{aws_region}-dev, vpc-peering-{aws_region}-mgmt, vpc-peering-{aws_region}-prod, vpc-peering-{aws_region}-staging`)
+	})
+}
+
+func TestGraphEnabledWhen(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-enabled-when/astro.yaml")
+	require.NoError(t, err)
+
+	ids := func(executions executionSet) []string {
+		ids := []string{}
+		for _, e := range executions {
+			ids = append(ids, e.ID())
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	parameters := func(values map[string]string) ExecutionParameters {
+		filters := map[string]bool{}
+		if _, ok := values["environment"]; ok {
+			filters["environment"] = true
+		}
+		return ExecutionParameters{
+			UserVars: &UserVariables{Values: values, Filters: filters},
+		}
+	}
+
+	tt := []struct {
+		values   map[string]string
+		expected []string
+	}{
+		{
+			values: map[string]string{},
+			expected: []string{
+				"app-dev", "app-mgmt", "app-prod", "bastion-mgmt",
+				"monitoring-mgmt-{region}", "monitoring-prod-{region}",
+			},
+		},
+		{
+			values: map[string]string{"region": "us-east-1"},
+			expected: []string{
+				"app-dev", "app-mgmt", "app-prod", "bastion-mgmt",
+				"monitoring-mgmt-{region}", "monitoring-prod-{region}",
+			},
+		},
+		{
+			values:   map[string]string{"region": "eu-west-1"},
+			expected: []string{"app-dev", "app-mgmt", "app-prod", "bastion-mgmt"},
+		},
+		{
+			values:   map[string]string{"environment": "prod", "region": "us-east-1"},
+			expected: []string{"app-prod", "monitoring-prod-{region}"},
+		},
+	}
+
+	// IDs are shown before binding, so they contain placeholders
+	for _, test := range tt {
+		executions := c.executions(parameters(test.values))
+		assert.Equal(t, test.expected, ids(executions), "values: %v", test.values)
+
+		// dependencies on the disabled bastion executions are left out
+		graph, err := executions.graph(c.config.Modules, logger.Discard)
+		require.NoError(t, err, "values: %v", test.values)
+		require.NoError(t, graph.Validate())
+	}
+}
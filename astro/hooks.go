@@ -27,16 +27,20 @@ import (
 	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/logger"
 
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/kballard/go-shellquote"
 )
 
 // runCommandkAndSetEnvironment runs the specified hook/command.
 //
+// The hook's ASTRO_TMPDIR and TMPDIR environment variables are set to tmpDir,
+// which hooks can use as scratch space, and ASTRO_RUN_ID is set to runID.
+//
 // If parseEnvironment is true, output in the format "KEY=VAL" for
 // hooks is insert into the current process's environment. An error is returned
 // if the hook fails to execute.
-func runCommandkAndSetEnvironment(workingDir string, hook conf.Hook) error {
-	logger.Trace.Printf("astro: running hook: %v", hook.Command)
+func runCommandkAndSetEnvironment(workingDir string, tmpDir string, runID string, hook conf.Hook, log logger.Logger) error {
+	log.Infof("astro: running hook: %v", hook.Command)
 
 	args, err := shellquote.Split(hook.Command)
 	if err != nil {
@@ -52,6 +56,11 @@ func runCommandkAndSetEnvironment(workingDir string, hook conf.Hook) error {
 
 	cmd := exec.Command(prog, args[1:]...)
 	cmd.Dir = workingDir
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("ASTRO_TMPDIR=%s", tmpDir),
+		fmt.Sprintf("TMPDIR=%s", tmpDir),
+		fmt.Sprintf("ASTRO_RUN_ID=%s", runID),
+	)
 
 	// Have to pipe through stderr and stdin so that scripts that prompt, e.g.
 	// for MFA will work.
@@ -72,6 +81,56 @@ func runCommandkAndSetEnvironment(workingDir string, hook conf.Hook) error {
 	return nil
 }
 
+// runPlanCheck runs the plan_check hook on the plan in planFile, and
+// returns its combined output. The path of the plan file is passed as the
+// last argument, or the plan is written to stdin if hook.PlanOnStdin is
+// set, and it is in ASTRO_PLAN_FILE either way. The environment is the same
+// as that of other hooks. An error is returned if the hook fails, e.g.
+// because the plan violates a policy.
+func runPlanCheck(workingDir string, tmpDir string, runID string, hook conf.Hook, planFile string, log logger.Logger) (string, error) {
+	log.Infof("astro: running plan_check hook: %v", hook.Command)
+
+	args, err := shellquote.Split(hook.Command)
+	if err != nil {
+		return "", err
+	}
+
+	prog, err := exec.LookPath(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	args = args[1:]
+	if !hook.PlanOnStdin {
+		args = append(args, planFile)
+	}
+
+	output := &bytes.Buffer{}
+
+	cmd := exec.Command(prog, args...)
+	cmd.Dir = workingDir
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("ASTRO_TMPDIR=%s", tmpDir),
+		fmt.Sprintf("TMPDIR=%s", tmpDir),
+		fmt.Sprintf("ASTRO_RUN_ID=%s", runID),
+		fmt.Sprintf("ASTRO_PLAN_FILE=%s", planFile),
+	)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if hook.PlanOnStdin {
+		plan, err := os.Open(planFile)
+		if err != nil {
+			return "", err
+		}
+		defer plan.Close()
+		cmd.Stdin = plan
+	}
+
+	err = cmd.Run()
+	return output.String(), err
+}
+
 // parseOutputIntoEnv takes stdout of a hook and reads for lines in the format
 // "KEY=VAL". If then sets those as environment variables. It stops processing
 // on the first line that doesn't match this format.
@@ -96,3 +155,45 @@ func parseOutputIntoEnv(buf *bytes.Buffer) error {
 
 	return nil
 }
+
+// checkHookRequirements returns an error listing the commands required by
+// hooks that aren't in $PATH, along with the hooks that need them.
+func checkHookRequirements(config *conf.Project) error {
+	missing := []string{}
+	requiredBy := map[string][]string{}
+
+	check := func(hook conf.Hook, name string) {
+		for _, command := range hook.Requires {
+			if _, err := exec.LookPath(command); err == nil {
+				continue
+			}
+			if _, ok := requiredBy[command]; !ok {
+				missing = append(missing, command)
+			}
+			requiredBy[command] = append(requiredBy[command], name)
+		}
+	}
+
+	for _, hook := range config.Hooks.Startup {
+		check(hook, fmt.Sprintf("startup hook %q", hook.Command))
+	}
+	// Modules have the default pre_module_run and plan_check hooks applied
+	// already
+	for _, moduleConf := range config.Modules {
+		for _, hook := range moduleConf.Hooks.PreModuleRun {
+			check(hook, fmt.Sprintf("pre_module_run hook %q of module %v", hook.Command, moduleConf.Name))
+		}
+		for _, hook := range moduleConf.Hooks.PlanCheck {
+			check(hook, fmt.Sprintf("plan_check hook %q of module %v", hook.Command, moduleConf.Name))
+		}
+	}
+
+	var errs error
+	for _, command := range missing {
+		errs = multierror.Append(errs, fmt.Errorf("%v not found in $PATH; required by %v", command, strings.Join(requiredBy[command], ", ")))
+	}
+	if errs != nil {
+		return fmt.Errorf("commands required by hooks are missing; install them, or use --skip-hook-requirements: %v", errs)
+	}
+	return nil
+}
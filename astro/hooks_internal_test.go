@@ -17,10 +17,15 @@
 package astro
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/uber/astro/astro/tests/mockterraform"
+	"github.com/uber/astro/astro/utils"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -64,3 +69,264 @@ func TestHookInjectEnvVars(t *testing.T) {
 		"test": nil,
 	}, testResultErrs(testReadResults(resultChan)))
 }
+
+func TestHookTmpDir(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-hook-tmpdir/astro.yaml")
+	require.NoError(t, err)
+	require.NotNil(t, c)
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{
+		"test": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	sessionPath, err := filepath.Abs(session.path)
+	require.NoError(t, err)
+
+	for _, tmpDir := range []string{
+		filepath.Join(sessionPath, ".tmp", "startup-hook-0"),
+		filepath.Join(sessionPath, ".tmp", "test", "pre-module-run-hook-0"),
+	} {
+		for _, name := range []string{"astro_tmpdir", "tmpdir"} {
+			b, err := ioutil.ReadFile(filepath.Join(tmpDir, name))
+			require.NoError(t, err)
+			assert.Equal(t, tmpDir+"\n", string(b))
+		}
+	}
+
+	assert.True(t, utils.IsDirectory(filepath.Join(sessionPath, ".tmp", "test", "terraform")))
+}
+
+func TestHookRequires(t *testing.T) {
+	t.Parallel()
+
+	sessionRepoPath := "fixtures/test-hook-requires/.astro"
+	require.NoError(t, os.RemoveAll(sessionRepoPath))
+
+	c, err := NewProjectFromConfigFile("fixtures/test-hook-requires/astro.yaml")
+	require.Nil(t, c)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `astro-test-missing-a not found in $PATH; required by startup hook "`)
+	assert.Contains(t, err.Error(), `mock-hooks/success" of module app`)
+	assert.Contains(t, err.Error(), "astro-test-missing-b not found in $PATH")
+	assert.NotContains(t, err.Error(), "sh not found")
+
+	// the check runs before the session repo is created
+	assert.False(t, utils.FileExists(sessionRepoPath))
+
+	c, err = NewProjectFromConfigFile("fixtures/test-hook-requires/astro.yaml", WithoutHookRequirements())
+	require.NoError(t, err)
+	require.NotNil(t, c)
+}
+
+func TestRunID(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	for _, dir := range []string{"app", "network"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, dir), 0755))
+	}
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(`version: 0.12.6
+commands:
+  plan:
+    write:
+      "{{.Dir}}/plan-args": "{{join .Args \" \"}}"
+`), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+	resolver := versionResolverFunc(func(version string) (string, error) {
+		return terraformPath, nil
+	})
+
+	hookPath := filepath.Join(tmpdir, "hook.sh")
+	require.NoError(t, ioutil.WriteFile(hookPath, []byte("#!/bin/sh\necho $ASTRO_RUN_ID > run-id\n"), 0755))
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  version: 0.12.6
+hooks:
+  startup:
+    - command: %s
+modules:
+  - name: app
+    path: app
+    local_state: ephemeral
+    inject_run_id: true
+  - name: network
+    path: network
+    local_state: ephemeral
+`, codeRoot, tmpdir, hookPath)), 0644))
+
+	planArgs := func(session *Session, id string) string {
+		paths, err := session.ExecutionPaths(id)
+		require.NoError(t, err)
+		b, err := ioutil.ReadFile(filepath.Join(paths.Sandbox, "plan-args"))
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	for name, opts := range map[string][]Option{
+		"session ID": {WithTerraformVersionResolver(resolver)},
+		"--run-id":   {WithTerraformVersionResolver(resolver), WithRunID("build-1234")},
+	} {
+		c, err := NewProjectFromConfigFile(configPath, opts...)
+		require.NoError(t, err, name)
+
+		_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+		require.NoError(t, err, name)
+		assert.Equal(t, map[string]error{
+			"app":     nil,
+			"network": nil,
+		}, testResultErrs(testReadResults(resultChan)), name)
+
+		session, err := c.sessions.Current()
+		require.NoError(t, err, name)
+
+		runID := session.id
+		if c.runID != "" {
+			runID = c.runID
+		}
+		assert.Equal(t, runID, session.RunID(), name)
+
+		b, err := ioutil.ReadFile(filepath.Join(session.path, "run-id"))
+		require.NoError(t, err, name)
+		assert.Equal(t, runID+"\n", string(b), name)
+
+		// only the module that declares the variable gets it
+		assert.Contains(t, planArgs(session, "app"), "-var astro_run_id="+runID, name)
+		assert.NotContains(t, planArgs(session, "network"), "astro_run_id", name)
+
+		manifest, err := session.Manifest()
+		require.NoError(t, err, name)
+		assert.Equal(t, c.runID, manifest.RunID, name)
+	}
+
+	_, err = NewProjectFromConfigFile(configPath, WithTerraformVersionResolver(resolver), WithRunID("build 1234"))
+	assert.Error(t, err)
+}
+
+func TestPlanCheck(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	for _, dir := range []string{"app", "network", "unchanged"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, dir), 0755))
+	}
+
+	// Every plan writes a different plan file, and applies record what they
+	// were run with
+	spec := `version: 0.12.6
+commands:
+  plan:
+    exit_code: %d
+    write:
+      '{{flag "out"}}': '{{(now).UnixNano}}'
+  show:
+    stdout: '{"resource_changes":[{"address":"aws_s3_bucket.logs","change":{"after":{"acl":"public-read"}}}]}'
+  apply:
+    write:
+      "{{.Dir}}/applied": '{{join .Args " "}}'
+`
+	terraformPaths := map[string]string{}
+	for name, exitCode := range map[string]int{"changes": 2, "unchanged": 0} {
+		specPath := filepath.Join(tmpdir, name+".yaml")
+		require.NoError(t, ioutil.WriteFile(specPath, []byte(fmt.Sprintf(spec, exitCode)), 0644))
+		terraformPaths[name] = mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin-"+name), specPath)
+	}
+	resolver := versionResolverFunc(func(version string) (string, error) {
+		return terraformPaths["changes"], nil
+	})
+
+	// The policy check gets the path of the plan; the other one gets the
+	// plan on stdin, keeps the plan file it checked and passes
+	policyPath := filepath.Join(tmpdir, "check-policy.sh")
+	require.NoError(t, ioutil.WriteFile(policyPath, []byte("#!/bin/sh\nif grep -q public-read \"$1\"; then echo \"aws_s3_bucket.logs: buckets must not be public\"; exit 1; fi\n"), 0755))
+	stdinPath := filepath.Join(tmpdir, "check-stdin.sh")
+	require.NoError(t, ioutil.WriteFile(stdinPath, []byte("#!/bin/sh\ncat > \"$ASTRO_TMPDIR/plan\"\ncp \"${ASTRO_PLAN_FILE%.json}\" \"$ASTRO_TMPDIR/checked.plan\"\n"), 0755))
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  version: 0.12.6
+hooks:
+  plan_check:
+    - command: %s
+modules:
+  - name: app
+    path: app
+    local_state: ephemeral
+  - name: network
+    path: network
+    local_state: ephemeral
+    hooks:
+      plan_check:
+        - command: %s
+          plan_on_stdin: true
+  - name: unchanged
+    path: unchanged
+    local_state: ephemeral
+    terraform:
+      path: %s
+`, codeRoot, tmpdir, policyPath, stdinPath, terraformPaths["unchanged"])), 0644))
+
+	c, err := NewProjectFromConfigFile(configPath, WithTerraformVersionResolver(resolver))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{ExecutionParameters: NoExecutionParameters()})
+	require.NoError(t, err)
+	results := testReadResults(resultChan)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	sandbox := func(id string) string {
+		paths, err := session.ExecutionPaths(id)
+		require.NoError(t, err)
+		return paths.Sandbox
+	}
+
+	// The plan that violates the policy isn't applied
+	require.Error(t, results["app"].Err())
+	assert.Contains(t, results["app"].Err().Error(), "plan_check hook")
+	assert.Equal(t, "aws_s3_bucket.logs: buckets must not be public\n", results["app"].PlanCheckOutput())
+	assert.False(t, utils.FileExists(filepath.Join(sandbox("app"), "applied")))
+
+	// The other one is checked with the JSON plan on stdin, then the plan
+	// file that was checked is applied, rather than a new plan
+	require.NoError(t, results["network"].Err())
+	assert.Empty(t, results["network"].PlanCheckOutput())
+	b, err := ioutil.ReadFile(filepath.Join(session.path, ".tmp", "network", "plan-check-hook-0", "plan"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"acl":"public-read"`)
+	applied, err := ioutil.ReadFile(filepath.Join(sandbox("network"), "applied"))
+	require.NoError(t, err)
+	assert.Equal(t, "apply network.plan", string(applied))
+	checked, err := ioutil.ReadFile(filepath.Join(session.path, ".tmp", "network", "plan-check-hook-0", "checked.plan"))
+	require.NoError(t, err)
+	planFile, err := ioutil.ReadFile(filepath.Join(sandbox("network"), "network.plan"))
+	require.NoError(t, err)
+	assert.Equal(t, string(checked), string(planFile))
+
+	// Plans without changes aren't checked, but their plan file is applied
+	// all the same
+	require.NoError(t, results["unchanged"].Err())
+	assert.False(t, utils.FileExists(filepath.Join(session.path, ".tmp", "unchanged", "plan-check-hook-0")))
+	applied, err = ioutil.ReadFile(filepath.Join(sandbox("unchanged"), "applied"))
+	require.NoError(t, err)
+	assert.Equal(t, "apply unchanged.plan", string(applied))
+}
@@ -0,0 +1,173 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// DependencyGraph answers questions about the dependencies between the
+// executions of a project, e.g. what would be affected by changing a module.
+// It only uses the configuration: it doesn't run Terraform or need a session.
+type DependencyGraph struct {
+	executions executionSet
+	graph      *dag.AcyclicGraph
+}
+
+// Impact is a set of executions that are reachable from other executions in
+// the dependency graph.
+type Impact struct {
+	// Executions are the IDs of the executions that the query started from.
+	Executions []string `json:"executions"`
+	// Levels are the IDs of the reachable executions, grouped by how many
+	// dependencies away they are: Levels[0] are direct, Levels[1] are one
+	// step removed, and so on.
+	Levels [][]string `json:"levels"`
+}
+
+// NewDependencyGraph returns the dependency graph of the executions of the
+// modules in the configuration. Variables without predefined values are left
+// as placeholders in execution IDs, e.g. "vpc-{aws_region}-dev".
+func NewDependencyGraph(config conf.Project) (*DependencyGraph, error) {
+	executions := allExecutions(config)
+
+	graph, err := executions.graph(config.Modules, logger.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DependencyGraph{
+		executions: executions,
+		graph:      graph,
+	}, nil
+}
+
+// allExecutions returns every execution of the modules in the configuration,
+// regardless of default values. Variables without predefined values are left
+// as placeholders.
+func allExecutions(config conf.Project) executionSet {
+	executions := executionSet{}
+	for _, moduleConfig := range config.Modules {
+		// Default values would limit the executions to those that run when
+		// no flags are passed; show all of them instead.
+		variables := make([]conf.Variable, len(moduleConfig.Variables))
+		for i, variable := range moduleConfig.Variables {
+			variable.Default = ""
+			variables[i] = variable
+		}
+		moduleConfig.Variables = variables
+
+		executions = append(executions, newModule(moduleConfig).executions(NoExecutionParameters(), logger.Discard)...)
+	}
+	return executions
+}
+
+// Dependents returns the executions that depend on the named module or
+// execution, directly or transitively.
+func (g *DependencyGraph) Dependents(name string) (*Impact, error) {
+	return g.reachable(name, g.graph.UpEdges)
+}
+
+// Dependencies returns the executions that the named module or execution
+// depends on, directly or transitively.
+func (g *DependencyGraph) Dependencies(name string) (*Impact, error) {
+	return g.reachable(name, g.graph.DownEdges)
+}
+
+// reachable does a breadth-first search of the graph from the executions
+// matching name, following the edges returned by next.
+func (g *DependencyGraph) reachable(name string, next func(dag.Vertex) *dag.Set) (*Impact, error) {
+	start, err := g.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	impact := &Impact{
+		Executions: []string{},
+		Levels:     [][]string{},
+	}
+
+	visited := map[dag.Vertex]bool{}
+	frontier := []dag.Vertex{}
+	for _, e := range start {
+		visited[e] = true
+		frontier = append(frontier, e)
+		impact.Executions = append(impact.Executions, e.ID())
+	}
+	sort.Strings(impact.Executions)
+
+	for len(frontier) > 0 {
+		level := []string{}
+		nextFrontier := []dag.Vertex{}
+
+		for _, v := range frontier {
+			for _, w := range next(v).List() {
+				e, ok := w.(terraformExecution)
+				if !ok || visited[e] {
+					continue // the root of the graph, or already seen
+				}
+				visited[e] = true
+				level = append(level, e.ID())
+				nextFrontier = append(nextFrontier, e)
+			}
+		}
+
+		if len(level) == 0 {
+			break
+		}
+
+		sort.Strings(level)
+		impact.Levels = append(impact.Levels, level)
+		frontier = nextFrontier
+	}
+
+	return impact, nil
+}
+
+// resolve returns the executions of the module with the specified name or,
+// if there is no such module, the execution with the specified ID.
+func (g *DependencyGraph) resolve(name string) (executionSet, error) {
+	if executions := g.executions.filterByModule(name); len(executions) > 0 {
+		return executions, nil
+	}
+
+	suggestions := []string{}
+	for _, e := range g.executions {
+		if e.ID() == name {
+			return executionSet{e}, nil
+		}
+		// Suggest executions with similar IDs, and all executions of the
+		// module when an ID has the module's name but different values.
+		if strings.Contains(e.ID(), name) || strings.HasPrefix(name, e.ModuleConfig().Name+"-") {
+			suggestions = append(suggestions, e.ID())
+		}
+	}
+
+	if len(suggestions) > 0 {
+		sort.Strings(suggestions)
+		return nil, fmt.Errorf("unknown module or execution: %q; did you mean one of: %s", name, strings.Join(suggestions, ", "))
+	}
+
+	return nil, fmt.Errorf("unknown module or execution: %q", name)
+}
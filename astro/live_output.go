@@ -0,0 +1,64 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// liveOutput is where the output of Terraform is streamed with
+// WithLiveOutput. Writes for all executions are serialized, so that the
+// lines of executions running in parallel are never interleaved.
+type liveOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// forExecution returns a writer that writes each line written to it to the
+// live output, prefixed with the ID of the execution.
+func (l *liveOutput) forExecution(id string) io.Writer {
+	return &prefixWriter{out: l, prefix: []byte(fmt.Sprintf("[%s] ", id))}
+}
+
+// prefixWriter prefixes every line written to it. It expects whole lines,
+// as exec2.Cmd.LiveOutput gets.
+type prefixWriter struct {
+	out    *liveOutput
+	prefix []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if len(line) > 0 {
+			buf.Write(p.prefix)
+			buf.Write(line)
+		}
+	}
+
+	// one write per call, so that writers that are shared with other
+	// output only need to serialize their writes too
+	p.out.mu.Lock()
+	defer p.out.mu.Unlock()
+	if _, err := p.out.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
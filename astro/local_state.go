@@ -0,0 +1,61 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// checkLocalState checks what happens to the state of modules without a
+// backend, whose state Terraform keeps in the session sandbox. Unless the
+// module declares local_state, apply is refused, since the state would be
+// lost along with the session.
+func checkLocalState(status chan<- string, b *boundExecution, session *terraform.Session, apply bool) error {
+	moduleConfig := b.ModuleConfig()
+
+	// Terraform 0.8 and earlier configure the remote in astro's config
+	if moduleConfig.Remote.Backend != "" {
+		return nil
+	}
+
+	hasBackend, err := session.HasBackend()
+	if err != nil {
+		return fmt.Errorf("unable to check the module for a backend: %v", err)
+	}
+	if hasBackend {
+		if moduleConfig.LocalState != "" {
+			status <- fmt.Sprintf("[%s] WARNING: local_state is ignored, as the module configures a backend", b.ID())
+		}
+		return nil
+	}
+
+	switch {
+	case moduleConfig.LocalState == conf.LocalStateEphemeral:
+		status <- fmt.Sprintf("[%s] WARNING: local_state is ephemeral; the module's state only exists in this session and will be lost", b.ID())
+	case moduleConfig.LocalStatePersistPath() != "":
+		status <- fmt.Sprintf("[%s] Using local state persisted at %s", b.ID(), moduleConfig.LocalStatePersistPath())
+	case apply:
+		return fmt.Errorf("module has no backend, so its state would only be written to the session and lost; set local_state: persist:<path> to keep it, or local_state: ephemeral to accept losing it")
+	default:
+		status <- fmt.Sprintf("[%s] WARNING: module has no backend and no local_state; apply will be refused", b.ID())
+	}
+
+	return nil
+}
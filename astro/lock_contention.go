@@ -0,0 +1,136 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// inRunLockRetryAttempts is the number of times a command that failed on
+// a state lock held by another execution of the same run is retried.
+const inRunLockRetryAttempts = 5
+
+// inRunLockRetryDelay is how long to wait before the first of those
+// retries; it doubles with each retry.
+var inRunLockRetryDelay = time.Second
+
+// lockTableKeys are the backend_config parameters of the table that holds
+// the locks of a backend's states, e.g. the DynamoDB table of S3 states.
+var lockTableKeys = []string{"dynamodb_table"}
+
+// inFlightStates are the state identities of the running executions of a
+// session, to tell state locks held by other executions of the same run,
+// which will be released once they are done, from locks held outside of
+// it.
+type inFlightStates struct {
+	mu          sync.Mutex
+	byExecution map[string][]string
+}
+
+// add registers the execution as running, and returns the function to call
+// once it is done.
+func (f *inFlightStates) add(b *boundExecution) func() {
+	identity := b.stateIdentity()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.byExecution == nil {
+		f.byExecution = map[string][]string{}
+	}
+	f.byExecution[b.ID()] = identity
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.byExecution, b.ID())
+	}
+}
+
+// holder returns the ID of the other running execution that the state lock
+// error in the output of a Terraform command of the execution is about, or
+// an empty string if the lock is held outside of the run.
+//
+// A lock is held by another execution if it was taken by this user on this
+// host for that execution's state. Errors without lock info, e.g. when the
+// lock table is out of capacity, are put down to the other executions that
+// share the lock table.
+func (f *inFlightStates) holder(b *boundExecution, output string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, ok := terraform.ParseLockInfo(output)
+	if ok && info.Who != lockOwner() {
+		return ""
+	}
+	table := stateField(b.stateIdentity(), lockTableKeys)
+
+	for id, identity := range f.byExecution {
+		if id == b.ID() || identity == nil {
+			continue
+		}
+		if ok && stateLocatedAt(identity, info.Path) {
+			return id
+		}
+		if !ok && table != "" && stateField(identity, lockTableKeys) == table {
+			return id
+		}
+	}
+	return ""
+}
+
+// stateField returns the value of the first of the keys in the state
+// identity, or an empty string.
+func stateField(identity []string, keys []string) string {
+	for _, key := range keys {
+		for _, field := range identity {
+			if strings.HasPrefix(field, key+"=") {
+				return strings.TrimPrefix(field, key+"=")
+			}
+		}
+	}
+	return ""
+}
+
+// stateLocatedAt returns whether the lock path of Terraform's lock info,
+// e.g. "bucket/states/app.tfstate", is where the state with the identity is
+// stored.
+func stateLocatedAt(identity []string, path string) bool {
+	if path == "" {
+		return false
+	}
+	location := stateField(identity, append([]string{"local_state"}, stateLocationKeys...))
+	location = strings.Trim(location, "/")
+	return location != "" && strings.Contains("/"+strings.Trim(path, "/")+"/", "/"+location+"/")
+}
+
+// lockOwner returns who Terraform records as the holder of the state locks
+// it takes, e.g. "deploy@ci-1".
+func lockOwner() string {
+	userName := ""
+	if u, err := user.Current(); err == nil {
+		userName = u.Username
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s", userName, host)
+}
@@ -0,0 +1,139 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/conf"
+)
+
+func TestInFlightStatesHolder(t *testing.T) {
+	remote := func(key string) conf.Remote {
+		return conf.Remote{Backend: "s3", BackendConfig: map[string]string{"bucket": "states", "key": key, "dynamodb_table": "locks"}}
+	}
+	app := boundTestExecution(t, conf.Module{Name: "app", Path: ".", Remote: remote("app.tfstate")})
+	db := boundTestExecution(t, conf.Module{Name: "db", Path: ".", Remote: remote("db.tfstate")})
+	ephemeral := boundTestExecution(t, conf.Module{Name: "scratch", Path: ".", LocalState: conf.LocalStateEphemeral})
+
+	lockError := func(path, who string) string {
+		return fmt.Sprintf("Error: Error acquiring the state lock\n\nLock Info:\n  ID:        4a0d7c3e\n  Path:      %s\n  Who:       %s\n", path, who)
+	}
+	capacityError := "Error: Error acquiring the state lock\n\nError message: ProvisionedThroughputExceededException\n"
+
+	inFlight := &inFlightStates{}
+	defer inFlight.add(app)()
+	assert.Equal(t, "", inFlight.holder(app, lockError("states/app.tfstate", lockOwner())), "own state")
+
+	done := inFlight.add(db)
+	assert.Equal(t, "db", inFlight.holder(app, lockError("states/db.tfstate", lockOwner())))
+	assert.Equal(t, "", inFlight.holder(app, lockError("states/db.tfstate", "deploy@elsewhere")), "other host")
+	assert.Equal(t, "", inFlight.holder(app, lockError("states/other-db.tfstate", lockOwner())), "other state")
+	assert.Equal(t, "db", inFlight.holder(app, capacityError), "shared lock table")
+	assert.Equal(t, "", inFlight.holder(ephemeral, capacityError), "no lock table")
+
+	done()
+	assert.Equal(t, "", inFlight.holder(app, lockError("states/db.tfstate", lockOwner())), "done")
+}
+
+func TestInRunLockRetry(t *testing.T) {
+	defer func(delay time.Duration) { inRunLockRetryDelay = delay }(inRunLockRetryDelay)
+	inRunLockRetryDelay = 10 * time.Millisecond
+
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+	// The plan of db holds the lock for a second, and the first plan of
+	// app fails on the lock table in the meantime
+	terraformPath := filepath.Join(tmpdir, "terraform")
+	require.NoError(t, ioutil.WriteFile(terraformPath, []byte(fmt.Sprintf(`#!/bin/sh
+case "$1" in
+version)
+  echo "Terraform v0.12.6"
+  ;;
+plan)
+  if [ "$ROLE" = holder ]; then
+    touch %[1]s/holding
+    sleep 1
+  elif [ ! -e %[1]s/waited ]; then
+    while [ ! -e %[1]s/holding ]; do sleep 0.01; done
+    touch %[1]s/waited
+    echo "Error: Error acquiring the state lock: ProvisionedThroughputExceededException" >&2
+    exit 1
+  fi
+  touch "$(echo "$@" | sed -n 's/.*-out=\([^ ]*\).*/\1/p')"
+  ;;
+esac
+`, tmpdir)), 0755))
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    remote:
+      backend: s3
+      backend_config:
+        bucket: states
+        key: app.tfstate
+        dynamodb_table: locks
+  - name: db
+    path: .
+    env:
+      ROLE: holder
+    remote:
+      backend: s3
+      backend_config:
+        bucket: states
+        key: db.tfstate
+        dynamodb_table: locks
+`, codeRoot, tmpdir, terraformPath)), 0644))
+
+	c, err := NewProjectFromConfigFile(configPath)
+	require.NoError(t, err)
+
+	status, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+	results := testReadResults(resultChan)
+	require.NotNil(t, results["app"])
+	assert.NoError(t, results["app"].Err())
+	assert.NoError(t, results["db"].Err())
+
+	retries := 0
+	for len(status) > 0 {
+		if <-status == "[app] State lock held by db in this run, retrying in 10ms..." {
+			retries++
+		}
+	}
+	assert.Equal(t, 1, retries)
+}
@@ -0,0 +1,62 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// retryOnLock runs fn, a Terraform command of the execution, and retries it
+// as configured by lock_retry for as long as it fails because the state is
+// locked. Locks held by other executions of the same run are waited out
+// with backoff first, without counting towards lock_retry, as they are
+// released once those executions are done. Other failures are returned
+// right away.
+func (s *Session) retryOnLock(status chan<- string, b *boundExecution, fn func() (terraform.Result, error)) (terraform.Result, error) {
+	lockRetry := s.repo.project.config.LockRetry
+	inRunDelay := inRunLockRetryDelay
+
+	for attempt, inRunAttempt := 0, 0; ; {
+		result, err := fn()
+		if err == nil || result == nil || !terraform.IsStateLockError(result.Stderr()) {
+			return result, err
+		}
+
+		var delay time.Duration
+		if holder := s.inFlight.holder(b, result.Stderr()); holder != "" && inRunAttempt < inRunLockRetryAttempts {
+			delay = inRunDelay
+			inRunDelay *= 2
+			inRunAttempt++
+			status <- fmt.Sprintf("[%s] State lock held by %s in this run, retrying in %v...", b.ID(), holder, delay)
+		} else if attempt < lockRetry.Attempts {
+			delay = lockRetry.DelayDuration()
+			attempt++
+			status <- fmt.Sprintf("[%s] State locked, retrying in %v...", b.ID(), delay)
+		} else {
+			return result, err
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-s.repo.project.stopped:
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// installLockedTerraform installs a Terraform that records each plan in
+// plans, and fails the first failures plans with the error.
+func installLockedTerraform(t *testing.T, dir string, failures int, stderr string) (terraformPath, plans string) {
+	plans = filepath.Join(dir, "plans")
+	terraformPath = filepath.Join(dir, "terraform")
+	require.NoError(t, ioutil.WriteFile(terraformPath, []byte(fmt.Sprintf(`#!/bin/sh
+case "$1" in
+version)
+  echo "Terraform v0.12.6"
+  ;;
+plan)
+  echo plan >> %s
+  if [ "$(wc -l < %s)" -le %d ]; then
+    printf '%%s' %q >&2
+    exit 1
+  fi
+  touch "$(echo "$@" | sed -n 's/.*-out=\([^ ]*\).*/\1/p')"
+  ;;
+esac
+`, plans, plans, failures, stderr)), 0755))
+	return terraformPath, plans
+}
+
+func TestLockRetry(t *testing.T) {
+	lockError := "Error: Error acquiring the state lock\n\nLock Info:\n  ID:        4a0d7c3e\n"
+
+	tt := []struct {
+		name     string
+		failures int
+		stderr   string
+		attempts int
+		plans    int
+		failed   bool
+	}{
+		{name: "retried", failures: 2, stderr: lockError, attempts: 3, plans: 3},
+		{name: "out of attempts", failures: 5, stderr: lockError, attempts: 2, plans: 3, failed: true},
+		{name: "disabled", failures: 1, stderr: lockError, attempts: 0, plans: 1, failed: true},
+		{name: "other error", failures: 1, stderr: "Error: Invalid provider configuration\n", attempts: 3, plans: 1, failed: true},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			tmpdir, err := ioutil.TempDir("", "")
+			require.NoError(t, err)
+			defer os.RemoveAll(tmpdir)
+
+			codeRoot := filepath.Join(tmpdir, "code")
+			require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+			terraformPath, plans := installLockedTerraform(t, tmpdir, test.failures, test.stderr)
+
+			configPath := filepath.Join(tmpdir, "astro.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+lock_retry:
+  attempts: %d
+  delay: 10ms
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+`, codeRoot, tmpdir, terraformPath, test.attempts)), 0644))
+
+			c, err := NewProjectFromConfigFile(configPath)
+			require.NoError(t, err)
+
+			status, resultChan, err := c.Plan(NoPlanExecutionParameters())
+			require.NoError(t, err)
+			result := testReadResults(resultChan)["app"]
+			require.NotNil(t, result)
+			assert.Equal(t, test.failed, result.Err() != nil, "%v", result.Err())
+
+			b, err := ioutil.ReadFile(plans)
+			require.NoError(t, err)
+			assert.Equal(t, test.plans, strings.Count(string(b), "plan\n"))
+
+			retries := 0
+			for len(status) > 0 {
+				if <-status == "[app] State locked, retrying in 10ms..." {
+					retries++
+				}
+			}
+			assert.Equal(t, test.plans-1, retries)
+		})
+	}
+}
@@ -14,23 +14,141 @@
  * limitations under the License.
  */
 
+// Package logger contains the leveled logger that astro logs to.
 package logger
 
 import (
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"strings"
 )
 
-// Error is a logger for error output.
-var Error = log.New(os.Stderr, "[ERROR] ", log.LstdFlags)
+// Level is the severity of a log message.
+type Level int
 
-// Trace is a logger containing debug information.
-var Trace = log.New(ioutil.Discard, "[TRACE] ", log.LstdFlags)
+const (
+	// LevelDebug is for the details of what astro does, e.g. the commands
+	// it runs. --trace shows them.
+	LevelDebug Level = iota
+	// LevelInfo is for the main steps of a run, e.g. running hooks.
+	LevelInfo
+	// LevelWarning is for problems that astro works around, e.g. by
+	// falling back to a default.
+	LevelWarning
+	// LevelError is for failures that don't fail the run, e.g. being unable
+	// to record something in the session.
+	LevelError
+)
+
+// levelPrefixes are the prefixes of the messages of each level.
+var levelPrefixes = map[Level]string{
+	LevelDebug:   "[DEBUG] ",
+	LevelInfo:    "[INFO] ",
+	LevelWarning: "[WARNING] ",
+	LevelError:   "[ERROR] ",
+}
+
+// Logger is a leveled logger. Projects and the CLI log to the one passed
+// with their WithLogger options, so that programs that use astro as a
+// library can route its logs into their own logging.
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Warningf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
+
+// Discard is a Logger that discards all messages.
+var Discard Logger = New(ioutil.Discard, LevelError+1)
+
+// Writer returns an io.Writer that logs what is written to it to l at
+// level, one message per write. It is for routing the output of a standard
+// log.Logger, e.g. the trace output of terraform/dag, through l.
+func Writer(l Logger, level Level) io.Writer {
+	logf := map[Level]func(string, ...interface{}){
+		LevelDebug:   l.Debugf,
+		LevelInfo:    l.Infof,
+		LevelWarning: l.Warningf,
+		LevelError:   l.Errorf,
+	}[level]
+	return writerFunc(func(p []byte) (int, error) {
+		logf("%s", strings.TrimSuffix(string(p), "\n"))
+		return len(p), nil
+	})
+}
 
-func init() {
-	if strings.ToLower(os.Getenv("ASTRO_LOG")) == "trace" {
-		Trace.SetOutput(os.Stderr)
+// writerFunc is an io.Writer that calls a function.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// writerLogger is a Logger that writes to an io.Writer.
+type writerLogger struct {
+	level   Level
+	loggers map[Level]*log.Logger
+}
+
+// New returns a Logger that writes the messages of level and above to w,
+// each prefixed with its level, e.g. "[WARNING] ", and timestamped like the
+// standard logger.
+func New(w io.Writer, level Level) Logger {
+	loggers := map[Level]*log.Logger{}
+	for level, prefix := range levelPrefixes {
+		loggers[level] = log.New(w, prefix, log.LstdFlags)
 	}
+	return &writerLogger{level: level, loggers: loggers}
+}
+
+// Default returns the Logger that is used if none is passed: it writes to
+// stderr at DefaultLevel.
+func Default() Logger {
+	return New(os.Stderr, DefaultLevel())
+}
+
+// DefaultLevel returns the level set in the ASTRO_LOG environment variable,
+// i.e. "debug" (or "trace"), "info", "warning" or "error", and
+// LevelWarning if it isn't set.
+func DefaultLevel() Level {
+	switch strings.ToLower(os.Getenv("ASTRO_LOG")) {
+	case "debug", "trace":
+		return LevelDebug
+	case "info":
+		return LevelInfo
+	case "error":
+		return LevelError
+	default:
+		return LevelWarning
+	}
+}
+
+func (l *writerLogger) logf(level Level, format string, v ...interface{}) {
+	if level < l.level {
+		return
+	}
+	l.loggers[level].Output(3, fmt.Sprintf(format, v...))
+}
+
+// Debugf logs a debug message.
+func (l *writerLogger) Debugf(format string, v ...interface{}) {
+	l.logf(LevelDebug, format, v...)
+}
+
+// Infof logs an informational message.
+func (l *writerLogger) Infof(format string, v ...interface{}) {
+	l.logf(LevelInfo, format, v...)
+}
+
+// Warningf logs a warning.
+func (l *writerLogger) Warningf(format string, v ...interface{}) {
+	l.logf(LevelWarning, format, v...)
+}
+
+// Errorf logs an error.
+func (l *writerLogger) Errorf(format string, v ...interface{}) {
+	l.logf(LevelError, format, v...)
 }
@@ -0,0 +1,75 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/uber/astro/astro/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.New(&buf, logger.LevelWarning)
+
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 2)
+	l.Warningf("warning %d", 3)
+	l.Errorf("error %d", 4)
+
+	assert.NotContains(t, buf.String(), "debug 1")
+	assert.NotContains(t, buf.String(), "info 2")
+	assert.Contains(t, buf.String(), "[WARNING] ")
+	assert.Contains(t, buf.String(), "warning 3")
+	assert.Contains(t, buf.String(), "[ERROR] ")
+	assert.Contains(t, buf.String(), "error 4")
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.New(&buf, logger.LevelInfo)
+
+	log.New(logger.Writer(l, logger.LevelDebug), "", 0).Printf("[TRACE] dag/walk: walking %q", "app")
+	assert.Empty(t, buf.String())
+
+	log.New(logger.Writer(l, logger.LevelInfo), "", 0).Printf("[TRACE] dag/walk: walking %q", "app")
+	assert.Contains(t, buf.String(), "[INFO] ")
+	assert.Contains(t, buf.String(), "[TRACE] dag/walk: walking \"app\"\n")
+	assert.NotContains(t, buf.String(), "\n\n")
+}
+
+func TestDefaultLevel(t *testing.T) {
+	defer os.Unsetenv("ASTRO_LOG")
+
+	tt := map[string]logger.Level{
+		"":        logger.LevelWarning,
+		"debug":   logger.LevelDebug,
+		"TRACE":   logger.LevelDebug,
+		"info":    logger.LevelInfo,
+		"error":   logger.LevelError,
+		"unknown": logger.LevelWarning,
+	}
+	for value, expected := range tt {
+		os.Setenv("ASTRO_LOG", value)
+		assert.Equal(t, expected, logger.DefaultLevel(), value)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// manifestFile is the name of the file in a session directory that records
+// metadata about the session.
+const manifestFile = "manifest.json"
+
+// manifestSchemaVersion and manifestSchemaMinorVersion are the version of
+// the manifest format written by this version of astro; see
+// Manifest.SchemaVersion. Manifests with an older version are always read;
+// manifests with the same major version and a newer minor version are read
+// with a warning, without the fields this version doesn't know.
+const (
+	manifestSchemaVersion      = 1
+	manifestSchemaMinorVersion = 2
+)
+
+// ManifestVersionError is returned when the manifest of a session has a
+// newer major schema version than this version of astro reads, i.e. it was
+// written by a newer version of astro that changed the format in a way this
+// one can't read.
+type ManifestVersionError struct {
+	// Session is the ID of the session.
+	Session string
+	// Version is the schema version of its manifest, e.g. "2.0".
+	Version string
+}
+
+func (e *ManifestVersionError) Error() string {
+	return fmt.Sprintf("session %s was written by a newer version of astro: its manifest has schema version %s, and this version of astro only reads schema version %d.x; upgrade astro to use it", e.Session, e.Version, manifestSchemaVersion)
+}
+
+// manifestFields are the names of the fields of the manifest that this
+// version of astro knows.
+var manifestFields = jsonFieldNames(reflect.TypeOf(Manifest{}))
+
+// jsonFieldNames returns the JSON names of the fields of the struct type.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// Manifest returns the manifest of the session. Sessions that don't have
+// one, e.g. because they were created by an older version of astro, have an
+// empty manifest. It returns a *ManifestVersionError if the manifest has a
+// newer major schema version.
+func (s *Session) Manifest() (*Manifest, error) {
+	manifest, _, err := s.readManifest()
+	return manifest, err
+}
+
+// readManifest returns the manifest of the session, along with the fields
+// of a manifest with a newer minor schema version that this version of
+// astro doesn't know, so that they can be written back as they were.
+func (s *Session) readManifest() (*Manifest, map[string]json.RawMessage, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.path, manifestFile))
+	if os.IsNotExist(err) {
+		return &Manifest{SchemaVersion: manifestSchemaVersion, SchemaMinorVersion: manifestSchemaMinorVersion}, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse manifest of session %v: %v", s.id, err)
+	}
+
+	// The version is checked before anything else, as the format of the
+	// rest, or of the version itself, may have changed in a newer major
+	// version
+	major, minor, err := parseManifestVersion(fields["schema_version"], fields["schema_minor_version"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse manifest of session %v: %v", s.id, err)
+	}
+	version := fmt.Sprintf("%d.%d", major, minor)
+	if major > manifestSchemaVersion {
+		return nil, nil, &ManifestVersionError{Session: s.id, Version: version}
+	}
+	delete(fields, "schema_version")
+	delete(fields, "schema_minor_version")
+
+	unknown := map[string]json.RawMessage{}
+	for name, value := range fields {
+		if !manifestFields[name] {
+			unknown[name] = value
+			delete(fields, name)
+		}
+	}
+	if major == manifestSchemaVersion && minor > manifestSchemaMinorVersion {
+		s.repo.warnNewerManifest(s.id, version)
+	}
+
+	known, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(known, manifest); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse manifest of session %v: %v", s.id, err)
+	}
+	manifest.SchemaVersion = major
+	manifest.SchemaMinorVersion = minor
+
+	return manifest, unknown, nil
+}
+
+// parseManifestVersion returns the major and minor schema version of a
+// manifest: integers in schema_version and schema_minor_version, or a
+// "major.minor" string in schema_version. Manifests without a version are
+// from before versioning, which had the format of version 1.0.
+func parseManifestVersion(version, minorVersion json.RawMessage) (int, int, error) {
+	if version == nil {
+		return 1, 0, nil
+	}
+
+	var major, minor int
+	if err := json.Unmarshal(version, &major); err != nil {
+		var s string
+		if json.Unmarshal(version, &s) != nil {
+			return 0, 0, fmt.Errorf("invalid schema_version: %s", version)
+		}
+		parts := strings.SplitN(s, ".", 2)
+		if major, err = strconv.Atoi(parts[0]); err != nil {
+			return 0, 0, fmt.Errorf("invalid schema_version: %s", version)
+		}
+		if len(parts) == 2 {
+			if minor, err = strconv.Atoi(parts[1]); err != nil {
+				return 0, 0, fmt.Errorf("invalid schema_version: %s", version)
+			}
+		}
+		return major, minor, nil
+	}
+
+	if minorVersion != nil {
+		if err := json.Unmarshal(minorVersion, &minor); err != nil {
+			return 0, 0, fmt.Errorf("invalid schema_minor_version: %s", minorVersion)
+		}
+	}
+	return major, minor, nil
+}
+
+// warnNewerManifest warns that the manifest of the session has a newer
+// minor schema version, once per version, as every manifest is read when
+// sessions are listed.
+func (r *SessionRepo) warnNewerManifest(id, version string) {
+	if _, warned := r.newerManifestWarnings.LoadOrStore(version, true); warned {
+		return
+	}
+	r.log().Warningf("astro: session %s was written by a newer version of astro, with manifest schema version %s; this version reads up to %d.%d, and ignores what it doesn't know", id, version, manifestSchemaVersion, manifestSchemaMinorVersion)
+}
+
+// updateManifest reads the manifest of the session, passes it to update and
+// writes it back if update doesn't return an error. Fields of a newer minor
+// schema version are written back unchanged. The manifest is written to a
+// temporary file first, so that it is never left half-written.
+func (s *Session) updateManifest(update func(*Manifest) error) error {
+	manifest, unknown, err := s.readManifest()
+	if err != nil {
+		return err
+	}
+	if err := update(manifest); err != nil {
+		return err
+	}
+	// The manifest may now have the fields of this version
+	if manifest.SchemaVersion == manifestSchemaVersion && manifest.SchemaMinorVersion < manifestSchemaMinorVersion {
+		manifest.SchemaMinorVersion = manifestSchemaMinorVersion
+	}
+
+	b, err := marshalManifest(manifest, unknown)
+	if err != nil {
+		return err
+	}
+
+	fileMode := s.repo.fileMode
+	if fileMode == 0 {
+		fileMode = 0666
+	}
+
+	manifestPath := filepath.Join(s.path, manifestFile)
+	tmpPath := manifestPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b, fileMode); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, manifestPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// marshalManifest returns the JSON of the manifest, with the unknown fields
+// of a newer minor schema version added.
+func marshalManifest(manifest *Manifest, unknown map[string]json.RawMessage) ([]byte, error) {
+	if len(unknown) == 0 {
+		return json.MarshalIndent(manifest, "", "  ")
+	}
+
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range unknown {
+		fields[name] = value
+	}
+	return json.MarshalIndent(fields, "", "  ")
+}
@@ -0,0 +1,186 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The manifests in fixtures/manifests were written by older or newer
+// versions of astro. They must not be changed: every version of astro must
+// keep reading the manifests of older versions.
+const manifestFixtures = "fixtures/manifests"
+
+// sessionWithManifest returns a session in a new repo in tmpdir whose
+// manifest is a copy of the fixture.
+func sessionWithManifest(t *testing.T, tmpdir, fixture string) *Session {
+	id := ulidAt(time.Now())
+	repo, err := NewSessionRepo(&Project{config: &conf.Project{}}, tmpdir, func() string { return id })
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadFile(filepath.Join(manifestFixtures, fixture))
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(tmpdir, id), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpdir, id, manifestFile), b, 0644))
+
+	session, err := repo.Open(id)
+	require.NoError(t, err)
+	return session
+}
+
+func TestManifestReadsUnversionedManifest(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-manifest-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	manifest, err := sessionWithManifest(t, tmpdir, "v1-unversioned.json").Manifest()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, manifest.SchemaVersion)
+	require.Len(t, manifest.Attachments, 1)
+	assert.Equal(t, "attachments/ticket.txt", manifest.Attachments[0].Path)
+	assert.Equal(t, "change ticket", manifest.Attachments[0].Label)
+	assert.Equal(t, int64(12), manifest.Attachments[0].Size)
+}
+
+func TestManifestReadsVersion1Manifest(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-manifest-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	manifest, err := sessionWithManifest(t, tmpdir, "v1.json").Manifest()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, manifest.SchemaVersion)
+	assert.Equal(t, 0, manifest.SchemaMinorVersion)
+	assert.Equal(t, "ci-1234", manifest.RunID)
+	assert.Equal(t, 7, manifest.Sequence)
+	require.NotNil(t, manifest.Created)
+	assert.Equal(t, time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC), manifest.Created.UTC())
+	require.Len(t, manifest.Attachments, 1)
+	assert.Equal(t, "app", manifest.Attachments[0].Execution)
+	assert.Equal(t, []ExecutionDuration{{ID: "app", Module: "app", Command: "apply", Seconds: 12.5}}, manifest.Durations)
+	assert.Equal(t, []ExecutionVerification{{ID: "app", Module: "app", Changes: "~ null_resource.foo", Seconds: 3}}, manifest.Verifications)
+	assert.Equal(t, []ExecutionApply{{ID: "app", Module: "app", Succeeded: true, SourceHash: "3f2a", VariablesHash: "9c1e", TerraformVersion: "0.11.14"}}, manifest.Applies)
+	assert.Equal(t, []ExecutionSkip{{ID: "db", Module: "db", Reason: "source, variables and Terraform version unchanged since the last successful apply", LastApplySession: "01CEWZ1ZJ3CDXB2V5RGWS1N0QD"}}, manifest.Skipped)
+}
+
+func TestManifestReadsNewerMinorVersion(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-manifest-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	var warnings bytes.Buffer
+	session := sessionWithManifest(t, tmpdir, "v1.5.json")
+	session.repo.project.logger = logger.New(&warnings, logger.LevelWarning)
+	manifest, err := session.Manifest()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, manifest.SchemaVersion)
+	assert.Equal(t, 5, manifest.SchemaMinorVersion)
+	assert.Equal(t, "ci-5678", manifest.RunID)
+	assert.Equal(t, []ExecutionDuration{{ID: "app", Module: "app", Command: "plan", Seconds: 2}}, manifest.Durations)
+	assert.Contains(t, warnings.String(), "manifest schema version 1.5")
+
+	// the warning is only printed once
+	_, err = session.Manifest()
+	require.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(warnings.Bytes(), []byte("[WARNING]")))
+}
+
+func TestUpdateManifestKeepsUnknownFields(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-manifest-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	session := sessionWithManifest(t, tmpdir, "v1.5.json")
+	session.repo.project.logger = logger.Discard
+	require.NoError(t, session.updateManifest(func(manifest *Manifest) error {
+		manifest.Sequence = 9
+		return nil
+	}))
+
+	b, err := ioutil.ReadFile(filepath.Join(session.path, manifestFile))
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &fields))
+
+	assert.Equal(t, float64(1), fields["schema_version"])
+	assert.Equal(t, float64(5), fields["schema_minor_version"])
+	assert.Equal(t, float64(9), fields["sequence"])
+	assert.Equal(t, "ci-5678", fields["run_id"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"by": "reviewer", "at": "2019-01-01T00:00:00Z"}}, fields["approvals"])
+}
+
+func TestManifestRejectsNewerMajorVersion(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-manifest-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	session := sessionWithManifest(t, tmpdir, "v2.json")
+	_, err = session.Manifest()
+	require.Error(t, err)
+	assert.Equal(t, &ManifestVersionError{Session: session.ID(), Version: "2.0"}, err)
+	assert.Contains(t, err.Error(), "schema version 2.0")
+	assert.Contains(t, err.Error(), "only reads schema version 1.x")
+
+	// it isn't overwritten either
+	assert.Equal(t, err, session.updateManifest(func(*Manifest) error { return nil }))
+	b, err := ioutil.ReadFile(filepath.Join(session.path, manifestFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"sequence": "9"`)
+}
+
+func TestParseManifestVersion(t *testing.T) {
+	for _, tt := range []struct {
+		version, minorVersion string
+		major, minor          int
+	}{
+		{"", "", 1, 0},
+		{"1", "", 1, 0},
+		{"1", "3", 1, 3},
+		{`"2.1"`, "", 2, 1},
+		{`"3"`, "", 3, 0},
+	} {
+		var version, minorVersion json.RawMessage
+		if tt.version != "" {
+			version = json.RawMessage(tt.version)
+		}
+		if tt.minorVersion != "" {
+			minorVersion = json.RawMessage(tt.minorVersion)
+		}
+		major, minor, err := parseManifestVersion(version, minorVersion)
+		require.NoError(t, err, tt.version)
+		assert.Equal(t, tt.major, major, tt.version)
+		assert.Equal(t, tt.minor, minor, tt.version)
+	}
+
+	_, _, err := parseManifestVersion(json.RawMessage(`"latest"`), nil)
+	assert.EqualError(t, err, `invalid schema_version: "latest"`)
+}
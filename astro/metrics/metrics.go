@@ -0,0 +1,90 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics writes metrics in the Prometheus text format and pushes
+// them to a Prometheus pushgateway, for runs on ephemeral CI runners that
+// can't be scraped.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metric is a gauge with one or more samples.
+type Metric struct {
+	// Name is the name of the metric, e.g. "astro_executions".
+	Name string
+	// Help describes the metric.
+	Help string
+	// Samples are the values of the metric, by their labels.
+	Samples []Sample
+}
+
+// Sample is a value of a metric.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// labelValueEscaper escapes label values as the text format requires.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// helpEscaper escapes help text as the text format requires.
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+// Labels are written sorted by name.
+func WriteText(w io.Writer, metrics []Metric) error {
+	for _, metric := range metrics {
+		if metric.Help != "" {
+			if _, err := fmt.Fprintf(w, "# HELP %s %s\n", metric.Name, helpEscaper.Replace(metric.Help)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "# TYPE %s gauge\n", metric.Name); err != nil {
+			return err
+		}
+		for _, sample := range metric.Samples {
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", metric.Name, formatLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'g', -1, 64)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatLabels returns the labels in the text format, e.g.
+// `{module="app",result="ok"}`, or an empty string if there are none.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := []string{}
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(labels[name])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
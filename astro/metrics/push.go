@@ -0,0 +1,118 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pushTimeout is how long a push may take.
+const pushTimeout = 30 * time.Second
+
+// Pusher pushes metrics to a Prometheus pushgateway, grouped by job and
+// instance.
+type Pusher struct {
+	url           string
+	job           string
+	instance      string
+	authorization string
+	client        *http.Client
+}
+
+// PusherOption is an option for NewPusher.
+type PusherOption func(*Pusher)
+
+// WithAuthorization sets the value of the Authorization header of pushes,
+// e.g. "Bearer <token>".
+func WithAuthorization(authorization string) PusherOption {
+	return func(p *Pusher) {
+		p.authorization = authorization
+	}
+}
+
+// WithHTTPClient sets the HTTP client that pushes with.
+func WithHTTPClient(client *http.Client) PusherOption {
+	return func(p *Pusher) {
+		p.client = client
+	}
+}
+
+// NewPusher returns a Pusher to the pushgateway at the URL, e.g.
+// "http://pushgateway:9091", for the job and instance labels.
+func NewPusher(pushgatewayURL, job, instance string, opts ...PusherOption) *Pusher {
+	p := &Pusher{
+		url:      strings.TrimSuffix(pushgatewayURL, "/"),
+		job:      job,
+		instance: instance,
+		client:   &http.Client{Timeout: pushTimeout},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// groupURL returns the URL of the metrics group of the job and instance.
+func (p *Pusher) groupURL() string {
+	return fmt.Sprintf("%s/metrics/%s/%s", p.url, groupingPath("job", p.job), groupingPath("instance", p.instance))
+}
+
+// groupingPath returns the path segments of a grouping label. Values with a
+// slash, or empty ones, are base64 encoded, as the pushgateway requires.
+func groupingPath(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return fmt.Sprintf("%s@base64/%s", name, base64.RawURLEncoding.EncodeToString([]byte(value)))
+	}
+	return fmt.Sprintf("%s/%s", name, url.PathEscape(value))
+}
+
+// Push replaces the metrics of the job and instance in the pushgateway with
+// these.
+func (p *Pusher) Push(metrics []Metric) error {
+	var body bytes.Buffer
+	if err := WriteText(&body, metrics); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, p.groupURL(), &body)
+	if err != nil {
+		return fmt.Errorf("invalid pushgateway URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	if p.authorization != "" {
+		req.Header.Set("Authorization", p.authorization)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to push metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unable to push metrics: pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPush(t *testing.T) {
+	var method, path, contentType, authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.EscapedPath()
+		contentType = r.Header.Get("Content-Type")
+		authorization = r.Header.Get("Authorization")
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	pusher := NewPusher(server.URL+"/", "infra", "01ARZ3NDEKTSV4RRFFQ69G5FAV", WithAuthorization("Bearer s3cr3t"))
+	require.NoError(t, pusher.Push([]Metric{
+		{
+			Name: "astro_executions",
+			Help: "Number of executions, by result.",
+			Samples: []Sample{
+				{Labels: map[string]string{"result": "ok", "command": "plan"}, Value: 2},
+				{Labels: map[string]string{"result": "error", "command": "plan"}, Value: 0},
+			},
+		},
+		{
+			Name: "astro_execution_duration_seconds",
+			Samples: []Sample{
+				{Labels: map[string]string{"execution": `app "dev"`}, Value: 1.5},
+			},
+		},
+	}))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/infra/instance/01ARZ3NDEKTSV4RRFFQ69G5FAV", path)
+	assert.Equal(t, "text/plain; version=0.0.4", contentType)
+	assert.Equal(t, "Bearer s3cr3t", authorization)
+	assert.Equal(t, `# HELP astro_executions Number of executions, by result.
+# TYPE astro_executions gauge
+astro_executions{command="plan",result="ok"} 2
+astro_executions{command="plan",result="error"} 0
+# TYPE astro_execution_duration_seconds gauge
+astro_execution_duration_seconds{execution="app \"dev\""} 1.5
+`, body)
+}
+
+func TestPushGroupingLabelWithSlash(t *testing.T) {
+	pusher := NewPusher("http://pushgateway:9091", "infra/prod", "run")
+	assert.Equal(t, "http://pushgateway:9091/metrics/job@base64/aW5mcmEvcHJvZA/instance/run", pusher.groupURL())
+}
+
+func TestPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid metric", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := NewPusher(server.URL, "infra", "run").Push(nil)
+	assert.EqualError(t, err, "unable to push metrics: pushgateway returned 400 Bad Request: invalid metric")
+}
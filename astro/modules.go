@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
 )
 
 // module represents a Terraform module.
@@ -35,7 +36,7 @@ func newModule(config conf.Module) *module {
 
 // Executions returns a list of all possible Executions based
 // on the variable names/values.
-func (m *module) executions(parameters ExecutionParameters) executionSet {
+func (m *module) executions(parameters ExecutionParameters, log logger.Logger) executionSet {
 	filterCount := 0
 	for _, variable := range m.config.Variables {
 		if parameters.UserVars.HasFilter(variable.Name) {
@@ -46,6 +47,14 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 		return executionSet{}
 	}
 
+	// Parameters from the module's configuration go after the ones passed
+	// by the user; they are bound along with the rest of the module's
+	// configuration.
+	terraformParameters := parameters.TerraformParameters
+	if moduleParameters := m.config.AllTerraformParameters(); len(moduleParameters) > 0 {
+		terraformParameters = append(append([]string{}, terraformParameters...), moduleParameters...)
+	}
+
 	// If a module doesn't have any variables, then there's just a
 	// single execution.
 	if len(m.config.Variables) < 1 {
@@ -53,7 +62,7 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 			&unboundExecution{
 				&execution{
 					moduleConf:          m.config,
-					terraformParameters: parameters.TerraformParameters,
+					terraformParameters: terraformParameters,
 				},
 			},
 		}
@@ -63,11 +72,18 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 
 	for _, variable := range m.config.Variables {
 		v := []interface{}{}
-		filtered := variable.IsFilter() && parameters.UserVars.Values[variable.Name] != ""
+
+		// Without a value from the user, the default value, if any, acts as
+		// the filter.
+		filterValue := parameters.UserVars.Values[variable.Name]
+		if filterValue == "" {
+			filterValue = variable.Default
+		}
+		filtered := variable.IsFilter() && filterValue != ""
 
 		if variable.Values != nil {
 			for _, value := range variable.Values {
-				if !filtered || value == parameters.UserVars.Values[variable.Name] {
+				if !filtered || value == filterValue {
 					v = append(v, fmt.Sprintf("%s=%s", variable.Name, value))
 				}
 			}
@@ -88,7 +104,7 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 		e := &unboundExecution{
 			&execution{
 				moduleConf:          m.config,
-				terraformParameters: parameters.TerraformParameters,
+				terraformParameters: terraformParameters,
 			},
 		}
 
@@ -98,8 +114,30 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 			e.variables[s[0]] = s[1]
 		}
 
+		if !m.config.IsEnabledFor(m.knownValues(e.variables, parameters.UserVars)) {
+			log.Debugf("astro: skipping execution %v as the module is not enabled for it", e.ID())
+			continue
+		}
+
 		executions = append(executions, e)
 	}
 
 	return executions
 }
+
+// knownValues returns the values of the variables of an execution that are
+// known before binding: predefined values, and values of other variables the
+// user provided, or that have a default.
+func (m *module) knownValues(variables map[string]string, userVars *UserVariables) map[string]string {
+	values := map[string]string{}
+	for _, variable := range m.config.Variables {
+		if value := variables[variable.Name]; assertAllVarsReplaced(value) == nil {
+			values[variable.Name] = value
+		} else if value, ok := userVars.Values[variable.Name]; ok {
+			values[variable.Name] = value
+		} else if variable.Default != "" {
+			values[variable.Name] = variable.Default
+		}
+	}
+	return values
+}
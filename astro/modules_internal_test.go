@@ -22,6 +22,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
 )
 
 func TestModuleExecution(t *testing.T) {
@@ -71,7 +72,7 @@ func TestModuleExecution(t *testing.T) {
 		},
 	}
 
-	assert.EqualValues(t, expected, newModule(conf).executions(NoExecutionParameters()))
+	assert.EqualValues(t, expected, newModule(conf).executions(NoExecutionParameters(), logger.Discard))
 }
 
 func TestModuleExecutionTarget(t *testing.T) {
@@ -102,5 +103,91 @@ func TestModuleExecutionTarget(t *testing.T) {
 	assert.EqualValues(t, expected, newModule(conf).executions(ExecutionParameters{
 		UserVars:            NoUserVariables(),
 		TerraformParameters: []string{"-target", "one.terraform.entity", "-target", "another.terraform.entity"},
+	}, logger.Discard))
+}
+
+func TestExecutionIDWithListAndMapVariables(t *testing.T) {
+	t.Parallel()
+
+	moduleConf := conf.Module{
+		Name: "app",
+		Variables: []conf.Variable{
+			{Name: "tags", Type: conf.VariableTypeMap},
+			{Name: "zones", Type: conf.VariableTypeList},
+		},
+	}
+
+	id := func(variables map[string]string) string {
+		return (&execution{moduleConf: &moduleConf, variables: variables}).ID()
+	}
+
+	assert.Equal(t, "app-owner=infra+team=core-us-east-1a+us-east-1b", id(map[string]string{
+		"tags":  conf.EncodeMapValue(map[string]string{"team": "core", "owner": "infra"}),
+		"zones": conf.EncodeListValue([]string{"us-east-1a", "us-east-1b"}),
+	}))
+
+	// unsafe characters are replaced, and a hash keeps the ID unique
+	slash := id(map[string]string{
+		"tags":  conf.EncodeMapValue(nil),
+		"zones": conf.EncodeListValue([]string{"a/b"}),
+	})
+	underscore := id(map[string]string{
+		"tags":  conf.EncodeMapValue(nil),
+		"zones": conf.EncodeListValue([]string{"a_b"}),
+	})
+	assert.Regexp(t, `^app-_[0-9a-f]{8}-a_b_[0-9a-f]{8}$`, slash)
+	assert.NotEqual(t, slash, underscore)
+
+	// unbound executions show placeholders
+	assert.Equal(t, "app-{tags}-{zones}", id(map[string]string{
+		"tags":  "{tags}",
+		"zones": "{zones}",
 	}))
 }
+
+func TestModuleTerraformParameters(t *testing.T) {
+	t.Parallel()
+
+	moduleConf := conf.Module{
+		Name: "app",
+		Path: "app",
+		Terraform: conf.Terraform{
+			Parameters: []string{"-compact-warnings"},
+		},
+		TerraformParameters: []string{"-var-file={{.environment}}.tfvars", "-refresh=false"},
+		Variables: []conf.Variable{
+			{Name: "environment", Values: []string{"dev", "prod"}},
+		},
+	}
+
+	executions := newModule(moduleConf).executions(ExecutionParameters{
+		UserVars:            NoUserVariables(),
+		TerraformParameters: []string{"-target={{.environment}}"},
+	}, logger.Discard)
+	assert.Len(t, executions, 2)
+
+	bound, err := executions[0].(*unboundExecution).bind(map[string]string{}, nil)
+	assert.NoError(t, err)
+	// parameters from the command line are used as is
+	assert.Equal(t, []string{"-target={{.environment}}", "-compact-warnings", "-var-file=dev.tfvars", "-refresh=false"}, bound.TerraformParameters())
+}
+
+func TestModuleEnv(t *testing.T) {
+	t.Parallel()
+
+	moduleConf := conf.Module{
+		Name: "app",
+		Path: "app",
+		Env:  map[string]string{"AWS_PROFILE": "{{.environment}}-admin"},
+		Variables: []conf.Variable{
+			{Name: "environment"},
+		},
+	}
+
+	executions := newModule(moduleConf).executions(NoExecutionParameters(), logger.Discard)
+	assert.Len(t, executions, 1)
+
+	bound, err := executions[0].(*unboundExecution).bind(map[string]string{"environment": "dev"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"AWS_PROFILE": "dev-admin"}, bound.ModuleConfig().Env)
+}
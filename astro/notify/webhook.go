@@ -0,0 +1,143 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package notify sends the summary of a run of astro to a webhook.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// sendTimeout is how long sending a notification may take.
+const sendTimeout = 10 * time.Second
+
+// Summary is the summary of a run that is sent to the webhook.
+type Summary struct {
+	Command   string `json:"command"`
+	RunID     string `json:"run_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	// Succeeded is false if the run failed, e.g. because an execution
+	// failed; Error is then the error of the run.
+	Succeeded bool   `json:"succeeded"`
+	Error     string `json:"error,omitempty"`
+	// Duration is how long the run took, in seconds.
+	Duration   float64     `json:"duration_seconds"`
+	Counts     Counts      `json:"counts"`
+	Failed     []string    `json:"failed"`
+	Executions []Execution `json:"executions"`
+}
+
+// Counts are the number of executions of a run, by status.
+type Counts struct {
+	OK      int `json:"ok"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Execution is the result of an execution in the summary.
+type Execution struct {
+	ID     string `json:"id"`
+	Module string `json:"module"`
+	// Status is OK, ERROR or SKIPPED, like in the summary of the CLI.
+	Status  string `json:"status"`
+	Changes string `json:"changes,omitempty"`
+	// Duration is how long the execution took, in seconds.
+	Duration float64 `json:"duration_seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Webhook sends summaries to a webhook.
+type Webhook struct {
+	url      string
+	template *template.Template
+	client   *http.Client
+}
+
+// WebhookOption is an option for NewWebhook.
+type WebhookOption func(*Webhook)
+
+// WithTemplate renders the body of requests with the template instead of
+// sending the summary as JSON, e.g. for the payload of a Slack incoming
+// webhook.
+func WithTemplate(tmpl *template.Template) WebhookOption {
+	return func(w *Webhook) {
+		w.template = tmpl
+	}
+}
+
+// WithHTTPClient sets the HTTP client that sends with.
+func WithHTTPClient(client *http.Client) WebhookOption {
+	return func(w *Webhook) {
+		w.client = client
+	}
+}
+
+// NewWebhook returns a Webhook that POSTs to the URL.
+func NewWebhook(webhookURL string, opts ...WebhookOption) *Webhook {
+	w := &Webhook{
+		url:    webhookURL,
+		client: &http.Client{Timeout: sendTimeout},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// body returns the body of the request for the summary.
+func (w *Webhook) body(summary Summary) ([]byte, error) {
+	if w.template == nil {
+		return json.Marshal(summary)
+	}
+	var body bytes.Buffer
+	if err := w.template.Execute(&body, summary); err != nil {
+		return nil, fmt.Errorf("unable to render the notification: %v", err)
+	}
+	return body.Bytes(), nil
+}
+
+// Send POSTs the summary to the webhook.
+func (w *Webhook) Send(summary Summary) error {
+	body, err := w.body(summary)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send the notification: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unable to send the notification: webhook returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSummary = Summary{
+	Command:   "apply",
+	RunID:     "ci-42",
+	SessionID: "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+	Error:     "Done; there were errors",
+	Duration:  12.5,
+	Counts:    Counts{OK: 1, Failed: 1},
+	Failed:    []string{"db"},
+	Executions: []Execution{
+		{ID: "app", Module: "app", Status: "OK", Changes: "1 added, 0 changed, 0 destroyed", Duration: 4},
+		{ID: "db", Module: "db", Status: "ERROR", Duration: 8.5, Error: "apply failed"},
+	},
+}
+
+func TestSend(t *testing.T) {
+	var method, contentType string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	require.NoError(t, NewWebhook(server.URL).Send(testSummary))
+
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, "application/json", contentType)
+	var sent Summary
+	require.NoError(t, json.Unmarshal(body, &sent))
+	assert.Equal(t, testSummary, sent)
+}
+
+func TestSendWithTemplate(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	tmpl := template.Must(template.New("").Parse(`{"text": "astro {{.Command}}: {{.Counts.OK}} ok, {{.Counts.Failed}} failed{{range .Failed}} {{.}}{{end}}"}`))
+	require.NoError(t, NewWebhook(server.URL, WithTemplate(tmpl)).Send(testSummary))
+
+	assert.Equal(t, `{"text": "astro apply: 1 ok, 1 failed db"}`, body)
+}
+
+func TestSendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no_service", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := NewWebhook(server.URL).Send(testSummary)
+	assert.EqualError(t, err, "unable to send the notification: webhook returned 404 Not Found: no_service")
+}
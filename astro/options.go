@@ -17,11 +17,20 @@
 package astro
 
 import (
+	"fmt"
+	"io"
+	"regexp"
+
 	multierror "github.com/hashicorp/go-multierror"
 
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/tvm"
 )
 
+// matches valid run IDs, e.g. "build-1234"; see WithRunID
+var reRunID = regexp.MustCompile(`^[\w.:/-]+$`)
+
 // Option is an option for the c that allows for changing of options or
 // dependency injection for testing.
 type Option func(*Project) error
@@ -45,3 +54,66 @@ func WithConfig(config conf.Project) Option {
 		return nil
 	}
 }
+
+// WithTerraformVersionResolver allows you to change how the Terraform binary
+// for each version is found, e.g. when binaries are managed outside of astro.
+func WithTerraformVersionResolver(resolver TerraformVersionResolver) Option {
+	return func(c *Project) error {
+		c.terraformVersions = resolver
+		return nil
+	}
+}
+
+// WithTerraformDownloadProgress makes the default tvm repo report the
+// progress of Terraform downloads to progress. It has no effect with
+// WithTerraformVersionResolver.
+func WithTerraformDownloadProgress(progress tvm.DownloadProgress) Option {
+	return func(c *Project) error {
+		c.downloadProgress = progress
+		return nil
+	}
+}
+
+// WithoutHookRequirements disables checking that the commands required by
+// hooks are in $PATH, e.g. where they are provided another way.
+func WithoutHookRequirements() Option {
+	return func(c *Project) error {
+		c.skipHookRequirements = true
+		return nil
+	}
+}
+
+// WithRunID sets the ID of the run, e.g. the ID of a CI pipeline, instead of
+// the ID of the session. It is passed to hooks in ASTRO_RUN_ID and to
+// modules with inject_run_id, and recorded in the session manifest.
+func WithRunID(id string) Option {
+	return func(c *Project) error {
+		if !reRunID.MatchString(id) {
+			return fmt.Errorf("invalid run ID %q: must only contain letters, digits, '.', '_', ':', '/' and '-'", id)
+		}
+		c.runID = id
+		return nil
+	}
+}
+
+// WithLogger makes the project log to l instead of logger.Default(), e.g.
+// to route its logs into the logging of the program that uses it.
+func WithLogger(l logger.Logger) Option {
+	return func(c *Project) error {
+		c.logger = l
+		return nil
+	}
+}
+
+// WithLiveOutput streams the output of the long-running Terraform commands,
+// e.g. plan and apply, to w while they run, each line prefixed with the ID
+// of the execution, e.g. "[app-prod] aws_instance.web: Creating...". Lines
+// of executions running in parallel are written whole, one at a time, and
+// the sensitive values and secrets that are redacted from logs and plans
+// are redacted from them as well.
+func WithLiveOutput(w io.Writer) Option {
+	return func(c *Project) error {
+		c.liveOutput = &liveOutput{w: w}
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// checkPlan plans an execution that is about to be applied and runs the
+// plan_check hooks of its module on the plan. It returns the function that
+// applies the execution: an apply of the checked plan file, so that nothing
+// but what was checked is applied, or a plain apply if the module has no
+// plan_check hooks. If the plan or a hook fails, it returns the failed result
+// of the execution instead, which must not be applied. Plans without changes
+// aren't checked, as applying them changes nothing.
+func (s *Session) checkPlan(status chan<- string, b *boundExecution, session *terraform.Session) (func() (terraform.Result, error), *Result) {
+	hooks := b.ModuleConfig().Hooks.PlanCheck
+	if len(hooks) == 0 {
+		return session.Apply, nil
+	}
+
+	status <- fmt.Sprintf("[%s] Planning for plan_check...", b.ID())
+	result, err := s.retryOnLock(status, b, session.Plan)
+	if err != nil {
+		return nil, newResult(b, result, err)
+	}
+	plan, ok := result.(*terraform.PlanResult)
+	if !ok || !plan.HasChanges() {
+		return session.ApplyPlan, nil
+	}
+
+	planFile, err := session.WritePlanForCheck(plan)
+	if err != nil {
+		return nil, newResult(b, nil, err)
+	}
+
+	for i, hook := range hooks {
+		status <- fmt.Sprintf("[%s] Running plan_check hook...", b.ID())
+		tmpDir, err := s.tmpDir(b.ID(), fmt.Sprintf("plan-check-hook-%d", i))
+		if err != nil {
+			return nil, newResult(b, nil, fmt.Errorf("unable to create temporary directory: %v", err))
+		}
+		output, err := runPlanCheck(s.path, tmpDir, s.RunID(), hook, planFile, s.log())
+		if err != nil {
+			failed := newResult(b, nil, fmt.Errorf("plan_check hook %q failed: %v", hook.Command, err))
+			failed.planCheckOutput = output
+			return nil, failed
+		}
+	}
+
+	return session.ApplyPlan, nil
+}
@@ -0,0 +1,214 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/tvm"
+	"github.com/uber/astro/astro/utils"
+)
+
+// cacheDirTagName is the name of the file that marks a directory as a cache,
+// so that backup and archiving tools that support it, e.g.
+// `tar --exclude-caches`, borg and restic, skip it.
+const cacheDirTagName = "CACHEDIR.TAG"
+
+// cacheDirTag is the content of the cache directory tag; see
+// https://bford.info/cachedir/.
+const cacheDirTag = `Signature: 8a477f597d28d172789f06886806bc55
+# This file is a cache directory tag created by astro.
+# It holds Terraform provider plugins, which are downloaded again if needed.
+# For information about cache directory tags, see https://bford.info/cachedir/
+`
+
+// legacyPluginPlatformRe matches the platform directories, e.g.
+// linux_amd64, that Terraform before 0.13 caches plugins in. Later versions
+// cache them in directories named after the registry host, which can't
+// contain underscores.
+var legacyPluginPlatformRe = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+
+// PluginCache is the directory that Terraform caches provider plugins in,
+// shared by all sessions of a project.
+type PluginCache struct {
+	path string
+}
+
+// OpenPluginCache returns the plugin cache of a project configuration:
+// plugin_cache_dir if it is set, or the plugins directory of the session
+// repo. The directory doesn't need to exist.
+func OpenPluginCache(config *conf.Project) *PluginCache {
+	if config.PluginCacheDir != "" {
+		return &PluginCache{path: config.PluginCacheDir}
+	}
+	return &PluginCache{path: filepath.Join(config.SessionRepoDir, ".astro", "plugins")}
+}
+
+// Path returns the path to the plugin cache.
+func (c *PluginCache) Path() string {
+	return c.path
+}
+
+// createPluginCache creates the plugin cache directory at path, if needed,
+// and tags it as a cache directory so that it isn't archived along with the
+// sessions.
+func createPluginCache(path string, mode os.FileMode) error {
+	if err := os.MkdirAll(path, mode); err != nil {
+		return err
+	}
+	tagPath := filepath.Join(path, cacheDirTagName)
+	if utils.FileExists(tagPath) {
+		return nil
+	}
+	return ioutil.WriteFile(tagPath, []byte(cacheDirTag), 0666)
+}
+
+// DiskUsage returns the total size, in bytes, of the files in the plugin
+// cache, or 0 if it doesn't exist.
+func (c *PluginCache) DiskUsage() (int64, error) {
+	var size int64
+	err := filepath.Walk(c.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return size, err
+}
+
+// entries returns the paths, relative to the cache, of the plugin versions
+// in it: host/namespace/type/version directories for Terraform 0.13 and
+// later, and platform/file plugin binaries for earlier versions.
+func (c *PluginCache) entries() ([]string, error) {
+	dirs, err := ioutil.ReadDir(c.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	entries := []string{}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		pattern := filepath.Join(c.path, dir.Name(), "*", "*", "*")
+		if legacyPluginPlatformRe.MatchString(dir.Name()) {
+			pattern = filepath.Join(c.path, dir.Name(), "*")
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(c.path, match)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, rel)
+		}
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// entryLastUsed returns when any file of the plugin version at path was
+// last modified or accessed.
+func entryLastUsed(path string) (time.Time, error) {
+	var lastUsed time.Time
+	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		used, err := tvm.LastUsed(path)
+		if err != nil {
+			return err
+		}
+		if used.After(lastUsed) {
+			lastUsed = used
+		}
+		return nil
+	})
+	return lastUsed, err
+}
+
+// Clean deletes the plugin versions in the cache that haven't been used for
+// unusedFor, and returns their paths relative to the cache, sorted.
+// Directories left empty are deleted too. If dryRun is set, it returns the
+// versions that would be deleted without deleting them.
+//
+// Access times are only a hint on file systems mounted with noatime, so
+// versions that are still in use may be deleted; Terraform downloads them
+// again when it needs them. Clean shouldn't run at the same time as
+// sessions that use the cache.
+func (c *PluginCache) Clean(unusedFor time.Duration, dryRun bool) ([]string, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	stale := []string{}
+	for _, entry := range entries {
+		lastUsed, err := entryLastUsed(filepath.Join(c.path, entry))
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(lastUsed) >= unusedFor {
+			stale = append(stale, entry)
+		}
+	}
+
+	if dryRun {
+		return stale, nil
+	}
+
+	removed := []string{}
+	for _, entry := range stale {
+		if err := os.RemoveAll(filepath.Join(c.path, entry)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, entry)
+		c.removeEmptyParents(entry)
+	}
+	return removed, nil
+}
+
+// removeEmptyParents deletes the parent directories of a removed entry that
+// are now empty, up to the cache directory itself, which is kept.
+func (c *PluginCache) removeEmptyParents(entry string) {
+	for dir := filepath.Dir(entry); dir != "."; dir = filepath.Dir(dir) {
+		// Remove fails on directories that aren't empty.
+		if err := os.Remove(filepath.Join(c.path, dir)); err != nil {
+			return
+		}
+	}
+}
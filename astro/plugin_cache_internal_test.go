@@ -0,0 +1,107 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginCacheClean(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-plugin-cache-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	cache := OpenPluginCache(&conf.Project{SessionRepoDir: tmpdir})
+	assert.Equal(t, filepath.Join(tmpdir, ".astro", "plugins"), cache.Path())
+
+	// a missing cache is empty
+	size, err := cache.DiskUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+	removed, err := cache.Clean(0, false)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+
+	require.NoError(t, createPluginCache(cache.Path(), 0755))
+	tag, err := ioutil.ReadFile(filepath.Join(cache.Path(), cacheDirTagName))
+	require.NoError(t, err)
+	assert.Contains(t, string(tag), "Signature: 8a477f597d28d172789f06886806bc55")
+
+	files := []string{
+		"registry.terraform.io/hashicorp/aws/2.70.0/linux_amd64/terraform-provider-aws_v2.70.0_x4",
+		"registry.terraform.io/hashicorp/aws/3.0.0/linux_amd64/terraform-provider-aws_v3.0.0_x5",
+		"registry.terraform.io/hashicorp/null/2.1.2/linux_amd64/terraform-provider-null_v2.1.2_x4",
+		"linux_amd64/terraform-provider-aws_v2.70.0_x4",
+		"linux_amd64/terraform-provider-null_v2.1.2_x4",
+	}
+	// only the aws 3.0.0 and legacy null plugins were used recently
+	recent := map[string]bool{files[1]: true, files[4]: true}
+	monthAgo := time.Now().Add(-30 * 24 * time.Hour)
+	for _, file := range files {
+		path := filepath.Join(cache.Path(), file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte("plugin"), 0755))
+		if !recent[file] {
+			require.NoError(t, os.Chtimes(path, monthAgo, monthAgo))
+		}
+	}
+
+	size, err = cache.DiskUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(files)*len("plugin")+len(cacheDirTag)), size)
+
+	stale := []string{
+		"linux_amd64/terraform-provider-aws_v2.70.0_x4",
+		"registry.terraform.io/hashicorp/aws/2.70.0",
+		"registry.terraform.io/hashicorp/null/2.1.2",
+	}
+
+	// a dry run deletes nothing
+	removed, err = cache.Clean(7*24*time.Hour, true)
+	require.NoError(t, err)
+	assert.Equal(t, stale, removed)
+	for _, file := range files {
+		assert.True(t, utils.FileExists(filepath.Join(cache.Path(), file)), file)
+	}
+
+	removed, err = cache.Clean(7*24*time.Hour, false)
+	require.NoError(t, err)
+	assert.Equal(t, stale, removed)
+	for _, file := range files {
+		assert.Equal(t, recent[file], utils.FileExists(filepath.Join(cache.Path(), file)), file)
+	}
+
+	// directories left empty are deleted, but not the cache itself
+	_, err = os.Stat(filepath.Join(cache.Path(), "registry.terraform.io", "hashicorp", "null"))
+	assert.True(t, os.IsNotExist(err))
+	assert.True(t, utils.FileExists(filepath.Join(cache.Path(), cacheDirTagName)))
+}
+
+func TestOpenPluginCacheDir(t *testing.T) {
+	cache := OpenPluginCache(&conf.Project{SessionRepoDir: "/repo", PluginCacheDir: "/var/cache/astro"})
+	assert.Equal(t, "/var/cache/astro", cache.Path())
+}
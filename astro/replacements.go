@@ -0,0 +1,164 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+)
+
+// replacementsFile is the name of the file in the session repo that records
+// the resources that the next apply of each execution replaces. Terraform
+// 0.15.2 and later deprecate taint, so astro taint records the resource
+// here instead, and plan and apply pass it as -replace=<address>.
+const replacementsFile = "replacements.json"
+
+// replacements returns the addresses of the resources that the next apply
+// of the execution replaces, sorted.
+func (r *SessionRepo) replacements(id string) ([]string, error) {
+	r.replacementsMu.Lock()
+	defer r.replacementsMu.Unlock()
+
+	all, err := r.readReplacements()
+	if err != nil {
+		return nil, err
+	}
+	return all[id], nil
+}
+
+// updateReplacements changes the resources that the next apply of the
+// execution replaces.
+func (r *SessionRepo) updateReplacements(id string, update func([]string) []string) error {
+	r.replacementsMu.Lock()
+	defer r.replacementsMu.Unlock()
+
+	all, err := r.readReplacements()
+	if err != nil {
+		return err
+	}
+
+	addresses := update(all[id])
+	if len(addresses) == 0 {
+		delete(all, id)
+	} else {
+		sort.Strings(addresses)
+		all[id] = addresses
+	}
+
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fileMode := r.fileMode
+	if fileMode == 0 {
+		fileMode = 0666
+	}
+	return ioutil.WriteFile(filepath.Join(r.path, replacementsFile), b, fileMode)
+}
+
+// recordReplacement records the resource for replacement on the next apply
+// of the execution, for taint, or forgets it, for untaint.
+func (r *SessionRepo) recordReplacement(id, command, address string) error {
+	var err error
+	updateErr := r.updateReplacements(id, func(addresses []string) []string {
+		kept := []string{}
+		for _, a := range addresses {
+			if a != address {
+				kept = append(kept, a)
+			}
+		}
+		switch {
+		case command == "taint":
+			kept = append(kept, address)
+		case len(kept) == len(addresses):
+			err = fmt.Errorf("%s is not marked for replacement in %s", address, id)
+		}
+		return kept
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// clearReplacements forgets the resources that the next apply of the
+// execution replaces, if there are any.
+func (r *SessionRepo) clearReplacements(id string) error {
+	addresses, err := r.replacements(id)
+	if err != nil || len(addresses) == 0 {
+		return err
+	}
+	return r.updateReplacements(id, func([]string) []string { return nil })
+}
+
+// readReplacements reads the replacements of every execution. It must be
+// called with replacementsMu held.
+func (r *SessionRepo) readReplacements() (map[string][]string, error) {
+	path := filepath.Join(r.path, replacementsFile)
+	all := map[string][]string{}
+	if !utils.FileExists(path) {
+		return all, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, fmt.Errorf("unable to read the resources to replace: %v: %v", path, err)
+	}
+	return all, nil
+}
+
+// taintDeprecated returns whether the Terraform version of the session
+// deprecates taint, in which case taint and untaint record the resource to
+// replace instead of running Terraform.
+func taintDeprecated(session *terraform.Session) (bool, error) {
+	v, err := session.Version()
+	if err != nil {
+		return false, fmt.Errorf("unable to detect Terraform version: %v", err)
+	}
+	return terraform.TaintDeprecated(v), nil
+}
+
+// clearReplacements passes the results through, and forgets the resources
+// to replace of each execution that applied successfully, as they have been
+// replaced.
+func (s *Session) clearReplacements(results <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(results))
+
+	go func() {
+		defer close(out)
+
+		for result := range results {
+			if result.Err() == nil && result.TerraformResult() != nil {
+				if err := s.repo.clearReplacements(result.ID()); err != nil {
+					s.log().Errorf("astro: unable to forget the resources replaced by %v: %v", result.ID(), err)
+				}
+			}
+			out <- result
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,139 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaintRecordsReplacement(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+	// Terraform that deprecates taint, and records the arguments of each
+	// subcommand
+	argsDir := filepath.Join(tmpdir, "args")
+	require.NoError(t, os.MkdirAll(argsDir, 0755))
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(fmt.Sprintf(`
+version: 1.0.0
+default:
+  write:
+    '%s/{{.Subcommand}}': "{{join .Args \" \"}}"
+`, argsDir)), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+`, codeRoot, tmpdir, terraformPath)), 0644))
+
+	// Each command runs in a new session, as the replacements are kept
+	// across sessions
+	newProject := func() *Project {
+		c, err := NewProjectFromConfigFile(configPath)
+		require.NoError(t, err)
+		return c
+	}
+
+	parameters := TaintExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		Module:              "app",
+		Address:             "null_resource.foo",
+	}
+
+	_, resultChan, err := newProject().Taint(parameters)
+	require.NoError(t, err)
+	results := testReadResults(resultChan)
+	require.NoError(t, results["app"].Err())
+	assert.True(t, results["app"].ReplacementRecorded())
+
+	// Terraform didn't run taint
+	_, err = os.Stat(filepath.Join(argsDir, "taint"))
+	assert.True(t, os.IsNotExist(err))
+
+	// The next apply replaces the resource, and the one after doesn't
+	for _, expectReplace := range []bool{true, false} {
+		_, resultChan, err = newProject().Apply(ApplyExecutionParameters{ExecutionParameters: NoExecutionParameters()})
+		require.NoError(t, err)
+		require.NoError(t, testReadResults(resultChan)["app"].Err())
+
+		b, err := ioutil.ReadFile(filepath.Join(argsDir, "apply"))
+		require.NoError(t, err)
+		if expectReplace {
+			assert.Contains(t, string(b), "-replace=null_resource.foo")
+		} else {
+			assert.NotContains(t, string(b), "-replace")
+		}
+	}
+
+	// Untainting a resource that isn't recorded fails
+	_, resultChan, err = newProject().Untaint(parameters)
+	require.NoError(t, err)
+	assert.EqualError(t, testReadResults(resultChan)["app"].Err(), "null_resource.foo is not marked for replacement in app")
+}
+
+func TestUntaintForgetsReplacement(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	repo := &SessionRepo{path: tmpdir}
+
+	require.NoError(t, repo.recordReplacement("app", "taint", "null_resource.foo"))
+	require.NoError(t, repo.recordReplacement("app", "taint", "null_resource.bar"))
+	require.NoError(t, repo.recordReplacement("app", "taint", "null_resource.foo"))
+	require.NoError(t, repo.recordReplacement("network", "taint", "null_resource.foo"))
+
+	addresses, err := repo.replacements("app")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"null_resource.bar", "null_resource.foo"}, addresses)
+
+	require.NoError(t, repo.recordReplacement("app", "untaint", "null_resource.foo"))
+	addresses, err = repo.replacements("app")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"null_resource.bar"}, addresses)
+
+	require.NoError(t, repo.clearReplacements("app"))
+	addresses, err = repo.replacements("app")
+	require.NoError(t, err)
+	assert.Empty(t, addresses)
+
+	addresses, err = repo.replacements("network")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"null_resource.foo"}, addresses)
+}
@@ -0,0 +1,45 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package report
+
+// CompatFinding is a problem that keeps a module from being used with the
+// target Terraform version.
+type CompatFinding struct {
+	// Check is the check that found the problem, e.g. "syntax".
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// ModuleCompat is the compatibility of a module with the target version.
+type ModuleCompat struct {
+	Module string `json:"module"`
+	// CurrentVersion is the Terraform version the module is configured
+	// with, if any.
+	CurrentVersion string `json:"current_version,omitempty"`
+	// Ready is set if no check found a problem.
+	Ready    bool            `json:"ready"`
+	Findings []CompatFinding `json:"findings"`
+}
+
+// Compat is the compatibility of the modules of a project with a Terraform
+// version, e.g. before changing terraform.version. It is the output of
+// `astro compat --format json`.
+type Compat struct {
+	SchemaVersion string         `json:"schema_version"`
+	TargetVersion string         `json:"target_version"`
+	Modules       []ModuleCompat `json:"modules"`
+}
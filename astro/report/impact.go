@@ -0,0 +1,33 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package report
+
+// Impact is the output of `astro impact --format json`: the executions
+// reachable from a module or execution in the dependency graph.
+type Impact struct {
+	SchemaVersion string `json:"schema_version"`
+	// Name is the module or execution the query started from.
+	Name string `json:"name"`
+	// Query is "dependents" or "dependencies".
+	Query string `json:"query"`
+	// Executions are the IDs of the executions that the query started from.
+	Executions []string `json:"executions"`
+	// Levels are the IDs of the reachable executions, grouped by how many
+	// dependencies away they are: Levels[0] are direct, Levels[1] are one
+	// step removed, and so on.
+	Levels [][]string `json:"levels"`
+}
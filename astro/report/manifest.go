@@ -0,0 +1,153 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package report
+
+import (
+	"time"
+)
+
+// Manifest is the metadata astro records about a session, in addition to the
+// files of its executions, in the manifest.json of the session directory.
+type Manifest struct {
+	// SchemaVersion is the major version of the manifest format. Unlike
+	// the output of commands, manifests are versioned with integers. It
+	// changes when the format changes in a way that older versions of astro
+	// can't read.
+	SchemaVersion int `json:"schema_version"`
+	// SchemaMinorVersion is the minor version of the manifest format. It
+	// changes when fields are added, which older versions of astro with the
+	// same major version ignore, and keep when they update the manifest.
+	SchemaMinorVersion int `json:"schema_minor_version,omitempty"`
+	// RunID is the ID of the run that created the session, if it was passed
+	// with astro.WithRunID. Otherwise, the run ID is the session ID.
+	RunID string `json:"run_id,omitempty"`
+	// Sequence is one more than the sequence of the most recent session in
+	// the repo when the session was created. Unlike the session ID, it
+	// doesn't depend on the clock, so it orders sessions correctly when the
+	// clocks of the machines sharing the repo disagree. It is 0 for sessions
+	// created by older versions of astro.
+	Sequence int `json:"sequence,omitempty"`
+	// Created is when the session was created, according to the clock of
+	// the machine that created it.
+	Created *time.Time `json:"created,omitempty"`
+	// Attachments are the files attached to the session.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Durations are how long the successful executions of the session
+	// took, for schedule reports.
+	Durations []ExecutionDuration `json:"durations,omitempty"`
+	// Verifications are the outcomes of the plans run after applies that
+	// were verified.
+	Verifications []ExecutionVerification `json:"verifications,omitempty"`
+	// Applies are the fingerprints of the executions applied in the
+	// session, for skip_unchanged_applies.
+	Applies []ExecutionApply `json:"applies,omitempty"`
+	// Skipped are the executions whose apply was skipped because nothing
+	// changed since their last successful apply, or because an execution
+	// they depend on failed.
+	Skipped []ExecutionSkip `json:"skipped,omitempty"`
+	// Variables are where the values of the variables of the executions
+	// of the session came from. Added in schema version 1.1.
+	Variables []ExecutionVariables `json:"variables,omitempty"`
+}
+
+// Attachment is an external file attached to a session, e.g. a change
+// ticket or the output of a manual check, so that it is kept along with the
+// logs and plans of the run.
+type Attachment struct {
+	// Path is the path of the copy, relative to the session directory.
+	Path string `json:"path"`
+	// Source is the absolute path of the file that was attached.
+	Source string `json:"source"`
+	// Execution is the ID of the execution the file is attached to, if any.
+	Execution string `json:"execution,omitempty"`
+	// Label is a free-form description of the file.
+	Label string `json:"label,omitempty"`
+	// AttachedBy is the name of the user who attached the file.
+	AttachedBy string `json:"attached_by"`
+	// AttachedAt is when the file was attached, in UTC.
+	AttachedAt time.Time `json:"attached_at"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+}
+
+// ExecutionDuration is how long an execution took in a session.
+type ExecutionDuration struct {
+	ID      string  `json:"id"`
+	Module  string  `json:"module"`
+	Command string  `json:"command"`
+	Seconds float64 `json:"seconds"`
+}
+
+// ExecutionVerification is the outcome of the verification of an apply.
+type ExecutionVerification struct {
+	ID       string  `json:"id"`
+	Module   string  `json:"module"`
+	Verified bool    `json:"verified"`
+	Changes  string  `json:"changes,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// ExecutionApply is an apply of an execution, with what it applied: the hash
+// of the code cloned into its sandbox, a hash of its variables, environment,
+// backend configuration and Terraform parameters, and the Terraform version.
+type ExecutionApply struct {
+	ID               string `json:"id"`
+	Module           string `json:"module"`
+	Succeeded        bool   `json:"succeeded"`
+	SourceHash       string `json:"source_hash"`
+	VariablesHash    string `json:"variables_hash"`
+	TerraformVersion string `json:"terraform_version,omitempty"`
+}
+
+// ExecutionSkip is an apply that was skipped because the execution was
+// unchanged since its last successful apply, in LastApplySession, or
+// because an execution it depends on failed, FailedDependency.
+type ExecutionSkip struct {
+	ID               string `json:"id"`
+	Module           string `json:"module"`
+	Reason           string `json:"reason"`
+	LastApplySession string `json:"last_apply_session,omitempty"`
+	// FailedDependency is the ID of the execution that failed, for
+	// executions skipped because of it. Added in schema version 1.2.
+	FailedDependency string `json:"failed_dependency,omitempty"`
+}
+
+// ExecutionVariables are the values of the variables of an execution run by
+// a command, and where they came from.
+type ExecutionVariables struct {
+	ID        string               `json:"id"`
+	Module    string               `json:"module"`
+	Command   string               `json:"command"`
+	Variables []VariableProvenance `json:"variables"`
+}
+
+// VariableProvenance is the value of a variable of an execution and where
+// it came from.
+type VariableProvenance struct {
+	// Name is the name of the variable.
+	Name string `json:"name"`
+	// Value is the value of the variable, or "<sensitive>" for sensitive
+	// variables.
+	Value string `json:"value"`
+	// Source is the kind of source of the value: "flag", "env", "default"
+	// or "values".
+	Source string `json:"source"`
+	// SourceName is the name of the source, e.g. the flag "--region" or
+	// the "variables.region.default" of the module configuration.
+	SourceName string `json:"source_name,omitempty"`
+}
@@ -0,0 +1,36 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package report
+
+import (
+	"github.com/uber/astro/astro/terraform"
+)
+
+// OrphanAudit is the orphan resources found in the plan of an execution.
+type OrphanAudit struct {
+	ID      string                     `json:"id"`
+	Module  string                     `json:"module"`
+	Orphans []terraform.OrphanResource `json:"orphans"`
+	Error   string                     `json:"error,omitempty"`
+}
+
+// Orphans is the output of `astro audit orphans --format json`.
+type Orphans struct {
+	SchemaVersion string        `json:"schema_version"`
+	Executions    []OrphanAudit `json:"executions"`
+	Total         int           `json:"total"`
+}
@@ -0,0 +1,62 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package report defines the documents that astro writes for other tools to
+// read, i.e. the JSON output of commands and the manifests of sessions, and
+// generates their JSON Schemas so that the tools can validate them.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaVersion is the version of the format of the JSON output of
+// commands, as "major.minor", in the schema_version of every document. The
+// minor version is bumped when fields are added, and the major version when
+// fields are removed or change meaning.
+const SchemaVersion = "1.0"
+
+// documents are the types of the documents, by name.
+var documents = map[string]interface{}{
+	"compat":   Compat{},
+	"impact":   Impact{},
+	"manifest": Manifest{},
+	"orphans":  Orphans{},
+	"schedule": Schedule{},
+}
+
+// DocumentTypes returns the names of the documents, sorted.
+func DocumentTypes() []string {
+	names := []string{}
+	for name := range documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SchemaFor returns the JSON Schema of the document with the name.
+func SchemaFor(name string) (*Schema, error) {
+	document, ok := documents[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown document type: %v; must be one of: %s", name, strings.Join(DocumentTypes(), ", "))
+	}
+	schema := Generate(document)
+	schema.Title = name
+	return schema, nil
+}
@@ -0,0 +1,79 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package report
+
+// ScheduledExecution is an execution in a Schedule.
+type ScheduledExecution struct {
+	ID     string `json:"id"`
+	Module string `json:"module"`
+	// Seconds is the average duration of the execution in the history, or
+	// 0 if it has none.
+	Seconds float64 `json:"seconds"`
+	// Runs is the number of durations Seconds is the average of.
+	Runs int `json:"runs"`
+	// StartSeconds is when the execution starts in the simulation of the
+	// current order.
+	StartSeconds float64 `json:"start_seconds"`
+	// CriticalPath is set if the execution is on the critical path.
+	CriticalPath bool `json:"critical_path"`
+	// SuggestedPriority orders the executions by how long the longest
+	// chain of dependents starting with them takes: executions with higher
+	// priorities should start first when fewer can run than are ready.
+	SuggestedPriority int `json:"suggested_priority"`
+}
+
+// ParallelismEstimate is the estimated duration of a run with a different
+// parallelism, using the suggested priorities.
+type ParallelismEstimate struct {
+	// Parallelism is the number of executions running at the same time, or
+	// 0 for as many as are ready.
+	Parallelism int     `json:"parallelism"`
+	Seconds     float64 `json:"seconds"`
+}
+
+// Schedule is an estimate of how long running a command on the executions
+// of a project takes, based on the durations recorded in recent sessions,
+// with suggestions to shorten it. It is the output of `astro report
+// schedule --format json`. It is advisory only: nothing in it changes how
+// astro runs executions.
+type Schedule struct {
+	SchemaVersion string `json:"schema_version"`
+	Command       string `json:"command"`
+	// Parallelism is the simulated parallelism, or 0 if unlimited.
+	Parallelism int `json:"parallelism"`
+	// Sessions is the number of sessions durations were found in.
+	Sessions int `json:"sessions"`
+	// EstimatedSeconds is the simulated duration of the run in the current
+	// order.
+	EstimatedSeconds float64 `json:"estimated_seconds"`
+	// SuggestedSeconds is the simulated duration of the run with the
+	// suggested priorities.
+	SuggestedSeconds float64 `json:"suggested_seconds"`
+	// CriticalPath is the longest chain of executions that depend on each
+	// other, which no parallelism or order can make the run shorter than.
+	CriticalPath        []string `json:"critical_path"`
+	CriticalPathSeconds float64  `json:"critical_path_seconds"`
+	// Executions are the simulated executions, highest suggested priority
+	// first.
+	Executions   []ScheduledExecution  `json:"executions"`
+	Parallelisms []ParallelismEstimate `json:"parallelism_estimates"`
+	// MissingHistory are the IDs of the executions without any recorded
+	// durations, which are simulated as taking no time.
+	MissingHistory []string `json:"missing_history"`
+	// Caveats explain the limits of the estimates.
+	Caveats []string `json:"caveats"`
+}
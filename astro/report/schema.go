@@ -0,0 +1,243 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// schemaDraft is the JSON Schema draft that generated schemas follow.
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a JSON Schema, with the keywords that Generate uses.
+type Schema struct {
+	Draft string `json:"$schema,omitempty"`
+	Title string `json:"title,omitempty"`
+	// Type is the name of the JSON type, or a list of them for values that
+	// can also be null.
+	Type   interface{} `json:"type,omitempty"`
+	Format string      `json:"format,omitempty"`
+	// Properties and Required are set for objects. AdditionalProperties is
+	// false for structs, as every field is listed in Properties, and the
+	// schema of the values for maps.
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	// Items is set for arrays.
+	Items *Schema `json:"items,omitempty"`
+}
+
+// timeType is the type of time.Time, which is encoded as a string.
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate returns the JSON Schema of the JSON encoding of v, from the types
+// and JSON tags of its fields. Fields without omitempty are required, and
+// slices, maps and pointers can be null.
+func Generate(v interface{}) *Schema {
+	schema := generate(reflect.TypeOf(v))
+	schema.Draft = schemaDraft
+	return schema
+}
+
+func generate(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Ptr:
+		return nullable(generate(t.Elem()))
+	case t.Kind() == reflect.Struct:
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}, Required: []string{}, AdditionalProperties: false}
+		addFields(schema, t)
+		sort.Strings(schema.Required)
+		return schema
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return nullable(&Schema{Type: "string"})
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return nullable(&Schema{Type: "array", Items: generate(t.Elem())})
+	case t.Kind() == reflect.Map:
+		return nullable(&Schema{Type: "object", AdditionalProperties: generate(t.Elem())})
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	// interfaces can hold anything
+	return &Schema{}
+}
+
+// addFields adds the exported fields of the struct type to the schema of
+// an object, including those of embedded structs, as encoding/json does.
+func addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name, options := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, options = tag[:i], tag[i+1:]
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				addFields(schema, fieldType)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = generate(fieldType)
+		if !strings.Contains(","+options+",", ",omitempty,") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// nullable returns the schema, allowing null values too.
+func nullable(schema *Schema) *Schema {
+	schema.Type = []string{schema.Type.(string), "null"}
+	return schema
+}
+
+// Validate returns an error for every way the JSON document doesn't match
+// the schema, for the keywords that Generate uses.
+func Validate(schema *Schema, document []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	return validate(schema, value, "$")
+}
+
+func validate(schema *Schema, value interface{}, path string) (errs error) {
+	valueType := jsonType(value)
+	if types := schemaTypes(schema); len(types) > 0 && !typeAllowed(types, valueType) {
+		return fmt.Errorf("%s: %s is not %s", path, valueType, strings.Join(types, " or "))
+	}
+
+	switch value := value.(type) {
+	case string:
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%s: invalid date-time: %v", path, err))
+			}
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range value {
+				if err := validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					errs = multierror.Append(errs, err)
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				errs = multierror.Append(errs, fmt.Errorf("%s: missing required property %q", path, name))
+			}
+		}
+		names := []string{}
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				switch additional := schema.AdditionalProperties.(type) {
+				case bool:
+					if !additional {
+						errs = multierror.Append(errs, fmt.Errorf("%s: unknown property %q", path, name))
+					}
+					continue
+				case *Schema:
+					propertySchema = additional
+				default:
+					continue
+				}
+			}
+			if err := validate(propertySchema, value[name], path+"."+name); err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// jsonType returns the JSON type of a decoded value.
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// schemaTypes returns the types that the schema allows, or nil for any.
+func schemaTypes(schema *Schema) []string {
+	switch t := schema.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+// typeAllowed returns whether a value of the JSON type is one of the
+// types. Integers are numbers too.
+func typeAllowed(types []string, valueType string) bool {
+	for _, t := range types {
+		if t == valueType || t == "number" && valueType == "integer" {
+			return true
+		}
+	}
+	return false
+}
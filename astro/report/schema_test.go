@@ -0,0 +1,109 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package report
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEmbedded struct {
+	Embedded string `json:"embedded"`
+}
+
+type testDocument struct {
+	testEmbedded
+	Name     string            `json:"name"`
+	Count    int               `json:"count,omitempty"`
+	Ratio    float64           `json:"ratio"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Created  *time.Time        `json:"created,omitempty"`
+	Internal string            `json:"-"`
+	hidden   string
+}
+
+func TestGenerate(t *testing.T) {
+	schema := Generate(testDocument{})
+
+	b, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"properties": {
+			"embedded": {"type": "string"},
+			"name": {"type": "string"},
+			"count": {"type": "integer"},
+			"ratio": {"type": "number"},
+			"tags": {"type": ["array", "null"], "items": {"type": "string"}},
+			"labels": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+			"created": {"type": ["string", "null"], "format": "date-time"}
+		},
+		"required": ["embedded", "name", "ratio", "tags"],
+		"additionalProperties": false
+	}`, string(b))
+}
+
+func TestValidate(t *testing.T) {
+	schema := Generate(testDocument{})
+
+	assert.NoError(t, Validate(schema, []byte(`{"embedded": "", "name": "app", "ratio": 1, "tags": null, "created": "2018-06-01T10:00:00Z"}`)))
+	assert.NoError(t, Validate(schema, []byte(`{"embedded": "", "name": "app", "ratio": 0.5, "tags": ["a"], "labels": {"a": "b"}}`)))
+
+	err := Validate(schema, []byte(`{"embedded": "", "name": 1, "ratio": 1, "tags": [2], "count": 1.5, "extra": true, "created": "yesterday"}`))
+	require.Error(t, err)
+	for _, message := range []string{
+		`$.name: integer is not string`,
+		`$.tags[0]: integer is not string`,
+		`$.count: number is not integer`,
+		`$: unknown property "extra"`,
+		`$.created: invalid date-time`,
+	} {
+		assert.Contains(t, err.Error(), message)
+	}
+
+	err = Validate(schema, []byte(`{"name": "app"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `$: missing required property "ratio"`)
+
+	assert.Error(t, Validate(schema, []byte(`[]`)))
+	assert.Error(t, Validate(schema, []byte(`{`)))
+}
+
+func TestDocumentSchemas(t *testing.T) {
+	assert.Equal(t, []string{"compat", "impact", "manifest", "orphans", "schedule"}, DocumentTypes())
+
+	// The documents that astro writes validate against their schemas
+	for name, document := range documents {
+		schema, err := SchemaFor(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, schema.Title)
+		assert.Contains(t, schema.Required, "schema_version", name)
+
+		b, err := json.Marshal(document)
+		require.NoError(t, err)
+		assert.NoError(t, Validate(schema, b), name)
+	}
+
+	_, err := SchemaFor("plan")
+	assert.EqualError(t, err, "unknown document type: plan; must be one of: compat, impact, manifest, orphans, schedule")
+}
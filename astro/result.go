@@ -16,13 +16,89 @@
 
 package astro
 
-import "github.com/uber/astro/astro/terraform"
+import (
+	"time"
+
+	"github.com/uber/astro/astro/terraform"
+
+	version "github.com/burl/go-version"
+)
 
 // Result is what is returned from astro execution.
 type Result struct {
 	id              string
+	module          string
+	variables       map[string]string
 	terraformResult terraform.Result
 	err             error
+	duration        time.Duration
+
+	stateTerraformVersion *version.Version
+	lockFileChanged       bool
+
+	// set by taint and untaint when Terraform deprecates taint
+	replacementRecorded bool
+
+	// set for plans; hasChanges is adjusted by applyChangesPolicy
+	rawHasChanges bool
+	hasChanges    bool
+
+	// set by checkChangeBudgets
+	changeBudgetErr error
+
+	// set by applies with VerifyAfterApply
+	verification *Verification
+
+	// set by applies of modules with plan_check hooks that failed
+	planCheckOutput string
+
+	// set by applies of modules with skip_unchanged_applies
+	fingerprint    *ExecutionApply
+	unchangedSince string
+
+	// where the values of the variables came from
+	provenance []VariableProvenance
+	// the directory of the Terraform logs, if the execution ran
+	logDir string
+}
+
+// newResult returns the result of running the execution. Like the
+// execution ID, the variables of the result leave out sensitive variables.
+func newResult(b *boundExecution, terraformResult terraform.Result, err error) *Result {
+	variables := map[string]string{}
+	for _, variable := range b.ModuleConfig().Variables {
+		if !variable.Sensitive {
+			variables[variable.Name] = b.Variables()[variable.Name]
+		}
+	}
+
+	var hasChanges bool
+	if planResult, ok := terraformResult.(*terraform.PlanResult); ok && planResult != nil {
+		hasChanges = planResult.HasChanges()
+	}
+
+	var duration time.Duration
+	if !b.started.IsZero() {
+		duration = time.Since(b.started)
+	}
+
+	return &Result{
+		id:              b.ID(),
+		module:          b.ModuleConfig().Name,
+		variables:       variables,
+		terraformResult: terraformResult,
+		err:             err,
+		duration:        duration,
+		rawHasChanges:   hasChanges,
+		hasChanges:      hasChanges,
+
+		stateTerraformVersion: b.stateTerraformVersion,
+		lockFileChanged:       b.lockFileChanged,
+
+		fingerprint: b.fingerprint,
+		provenance:  b.variableProvenance(),
+		logDir:      b.logDir,
+	}
 }
 
 // ID is a unique name that identifies the execution that run.
@@ -30,13 +106,133 @@ func (r *Result) ID() string {
 	return r.id
 }
 
+// Module is the name of the module of the execution.
+func (r *Result) Module() string {
+	return r.module
+}
+
+// Variables returns the variable values of the execution, other than
+// sensitive ones.
+func (r *Result) Variables() map[string]string {
+	return r.variables
+}
+
 // TerraformResult is the result of the Terraform command, or nil if
 // there wasn't one.
 func (r *Result) TerraformResult() terraform.Result {
 	return r.terraformResult
 }
 
+// LogFile returns the path of the log of the combined output of the
+// Terraform command of the result, e.g. for failed executions, whose
+// stderr doesn't tell the whole story. It is empty if there wasn't one.
+func (r *Result) LogFile() string {
+	if r.terraformResult == nil {
+		return ""
+	}
+	return r.terraformResult.LogFile()
+}
+
 // Err returns the error of the execution, if there was one.
 func (r *Result) Err() error {
 	return r.err
 }
+
+// Duration returns how long the execution took, from cloning its sandbox to
+// the end of the Terraform command, including any hooks and waiting for
+// version_concurrency.
+func (r *Result) Duration() time.Duration {
+	return r.duration
+}
+
+// HasChanges returns whether the execution was a plan with changes that
+// count under the changes_policy of the project; see conf.ChangesPolicy.
+// Without a policy, it is the same as RawHasChanges.
+func (r *Result) HasChanges() bool {
+	return r.hasChanges
+}
+
+// RawHasChanges returns whether the execution was a plan that Terraform
+// reported changes for, regardless of the changes_policy of the project.
+func (r *Result) RawHasChanges() bool {
+	return r.rawHasChanges
+}
+
+// VersionUnavailable returns whether the execution failed because its
+// Terraform version couldn't be fetched.
+func (r *Result) VersionUnavailable() bool {
+	_, ok := r.err.(*versionUnavailableError)
+	return ok
+}
+
+// StateTerraformVersion returns the version of Terraform that last wrote the
+// state of the execution, or nil if it isn't known, e.g. because there is no
+// state yet.
+func (r *Result) StateTerraformVersion() *version.Version {
+	return r.stateTerraformVersion
+}
+
+// ReplacementRecorded returns whether a taint or untaint changed the
+// resources that the next apply of the execution replaces with -replace,
+// instead of running Terraform, because the Terraform version deprecates
+// taint.
+func (r *Result) ReplacementRecorded() bool {
+	return r.replacementRecorded
+}
+
+// LockFileChanged returns whether `terraform init` changed the module's
+// dependency lock file, .terraform.lock.hcl, in the sandbox, i.e. the lock
+// file in the code is missing or out of date, so providers aren't selected
+// reproducibly.
+func (r *Result) LockFileChanged() bool {
+	return r.lockFileChanged
+}
+
+// ChangeBudgetErr returns why the plan of the execution exceeds its change
+// budget, if it does. The plan itself still succeeded, so this isn't
+// returned by Err.
+func (r *Result) ChangeBudgetErr() error {
+	return r.changeBudgetErr
+}
+
+// Verification returns the outcome of the plan run after the apply of the
+// execution, or nil if the apply wasn't verified. A failed verification
+// isn't returned by Err, as the apply itself succeeded.
+func (r *Result) Verification() *Verification {
+	return r.verification
+}
+
+// UnchangedSince returns the ID of the session of the last successful apply
+// of the execution, if its apply was skipped because nothing changed since;
+// see conf.Module.SkipUnchangedApplies. The result then has no Terraform
+// result.
+func (r *Result) UnchangedSince() string {
+	return r.unchangedSince
+}
+
+// Provenance returns the values of the variables of the execution, with
+// sensitive values masked, and where they came from, e.g. a flag or the
+// default in the module configuration.
+func (r *Result) Provenance() []VariableProvenance {
+	return r.provenance
+}
+
+// PlanCheckOutput returns the output of the plan_check hook that failed the
+// execution, if any.
+func (r *Result) PlanCheckOutput() string {
+	return r.planCheckOutput
+}
+
+// LogDir returns the directory of the Terraform logs of the execution in the
+// session, or an empty string if it didn't get to run Terraform.
+func (r *Result) LogDir() string {
+	return r.logDir
+}
+
+// Skipped returns true if the execution wasn't run because an execution it
+// depends on failed, or because the apply was cancelled before it started.
+// Err then returns a *SkippedError.
+func (r *Result) Skipped() bool {
+	_, ok := r.err.(*SkippedError)
+	return ok
+}
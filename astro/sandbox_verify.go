@@ -0,0 +1,69 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"github.com/uber/astro/astro/terraform"
+)
+
+// SandboxVerification is the result of comparing the sandbox of an
+// execution in a session with the Terraform code it was cloned from.
+type SandboxVerification struct {
+	// ID is the ID of the execution.
+	ID string
+	*terraform.SandboxReport
+
+	basePath    string
+	sandboxRoot string
+}
+
+// VerifySandboxes compares the sandbox of every execution in the session
+// with the code it was cloned from, e.g. to find files that are no longer
+// hard links to the source after it was edited.
+func (s *Session) VerifySandboxes() ([]*SandboxVerification, error) {
+	all, err := s.AllExecutionPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	verifications := []*SandboxVerification{}
+	for _, paths := range all {
+		basePath, modulePath, include := paths.moduleConfig.SandboxLayout()
+		exclude, err := paths.moduleConfig.SandboxExclude()
+		if err != nil {
+			return nil, err
+		}
+		report, err := terraform.VerifySandbox(basePath, paths.sandboxRoot, modulePath, include, exclude)
+		if err != nil {
+			return nil, err
+		}
+		verifications = append(verifications, &SandboxVerification{
+			ID:            paths.ID,
+			SandboxReport: report,
+			basePath:      basePath,
+			sandboxRoot:   paths.sandboxRoot,
+		})
+	}
+
+	return verifications, nil
+}
+
+// Repair replaces the diverged files in the sandbox with hard links to the
+// source, or copies if they can't be linked.
+func (v *SandboxVerification) Repair() error {
+	return terraform.RepairSandbox(v.basePath, v.sandboxRoot, v.Diverged)
+}
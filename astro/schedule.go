@@ -0,0 +1,391 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// defaultScheduleHistory is the number of most recent sessions whose
+// durations ScheduleReport uses, unless ScheduleParameters.History is set.
+const defaultScheduleHistory = 10
+
+// ScheduleParameters are the parameters of ScheduleReport.
+type ScheduleParameters struct {
+	ExecutionParameters
+	// Command is the command to simulate: "plan" or "apply".
+	Command string
+	// Parallelism is how many executions to simulate running at the same
+	// time. If 0, the parallelism of the command is used: applies of all
+	// modules start every execution as soon as its dependencies are done,
+	// other commands run 10 executions at a time.
+	Parallelism int
+	// History is the number of most recent sessions to take durations
+	// from. Defaults to 10.
+	History int
+}
+
+// recordDurations passes results through, and records how long the
+// successful executions took in the session manifest once all of them are
+// done, before the returned channel is closed.
+func (s *Session) recordDurations(command string, results <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(results))
+
+	go func() {
+		defer close(out)
+
+		durations := []ExecutionDuration{}
+		for result := range results {
+			if result.Err() == nil && result.Duration() > 0 {
+				durations = append(durations, ExecutionDuration{
+					ID:      result.ID(),
+					Module:  result.Module(),
+					Command: command,
+					Seconds: roundSeconds(result.Duration()),
+				})
+			}
+			out <- result
+		}
+
+		if len(durations) == 0 {
+			return
+		}
+		err := s.updateManifest(func(manifest *Manifest) error {
+			manifest.Durations = append(manifest.Durations, durations...)
+			return nil
+		})
+		if err != nil {
+			s.log().Debugf("astro: unable to record durations in session %v: %v", s.id, err)
+		}
+	}()
+
+	return out
+}
+
+// durationHistory returns the durations of the command recorded in the most
+// recent sessions of the repo, up to history sessions that have any, by
+// execution ID, and the number of sessions they are from. Sessions whose
+// manifest can't be read are skipped.
+func (r *SessionRepo) durationHistory(command string, history int) (map[string][]time.Duration, int, error) {
+	ids, err := r.Sessions()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	durations := map[string][]time.Duration{}
+	sessions := 0
+	for i := len(ids) - 1; i >= 0 && sessions < history; i-- {
+		session, err := r.Open(ids[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		manifest, err := session.Manifest()
+		if err != nil {
+			r.log().Debugf("astro: skipping session %v in schedule report: %v", ids[i], err)
+			continue
+		}
+
+		found := false
+		for _, d := range manifest.Durations {
+			if d.Command != command {
+				continue
+			}
+			durations[d.ID] = append(durations[d.ID], time.Duration(d.Seconds*float64(time.Second)))
+			found = true
+		}
+		if found {
+			sessions++
+		}
+	}
+
+	return durations, sessions, nil
+}
+
+// scheduleNode is an execution in a schedule simulation.
+type scheduleNode struct {
+	duration time.Duration
+	// deps are the indexes of the executions it depends on
+	deps []int
+	// dependents are the indexes of the executions that depend on it
+	dependents []int
+}
+
+// simulateSchedule simulates running the executions with at most
+// parallelism of them at a time, or as many as are ready if it is 0. When
+// fewer executions can start than are ready, those with the lowest rank
+// start first. It returns when each execution starts, and the duration of
+// the run.
+func simulateSchedule(nodes []scheduleNode, parallelism int, rank []int) ([]time.Duration, time.Duration) {
+	start := make([]time.Duration, len(nodes))
+	waiting := make([]int, len(nodes))
+	ready := []int{}
+	for i, node := range nodes {
+		waiting[i] = len(node.deps)
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	var now time.Duration
+	running := []int{}
+	finish := func(i int) time.Duration { return start[i] + nodes[i].duration }
+	for len(ready) > 0 || len(running) > 0 {
+		sort.Slice(ready, func(a, b int) bool { return rank[ready[a]] < rank[ready[b]] })
+		for len(ready) > 0 && (parallelism == 0 || len(running) < parallelism) {
+			start[ready[0]] = now
+			running = append(running, ready[0])
+			ready = ready[1:]
+		}
+
+		// Advance to the next executions to finish
+		now = finish(running[0])
+		for _, i := range running {
+			if finish(i) < now {
+				now = finish(i)
+			}
+		}
+		stillRunning := []int{}
+		for _, i := range running {
+			if finish(i) > now {
+				stillRunning = append(stillRunning, i)
+				continue
+			}
+			for _, j := range nodes[i].dependents {
+				waiting[j]--
+				if waiting[j] == 0 {
+					ready = append(ready, j)
+				}
+			}
+		}
+		running = stillRunning
+	}
+
+	return start, now
+}
+
+// bottomLevels returns, for each execution, how long the longest chain of
+// executions starting with it and following its dependents takes.
+func bottomLevels(nodes []scheduleNode) []time.Duration {
+	levels := make([]time.Duration, len(nodes))
+	done := make([]bool, len(nodes))
+
+	var level func(i int) time.Duration
+	level = func(i int) time.Duration {
+		if done[i] {
+			return levels[i]
+		}
+		var longest time.Duration
+		for _, j := range nodes[i].dependents {
+			if l := level(j); l > longest {
+				longest = l
+			}
+		}
+		levels[i] = nodes[i].duration + longest
+		done[i] = true
+		return levels[i]
+	}
+
+	for i := range nodes {
+		level(i)
+	}
+	return levels
+}
+
+// roundSeconds returns the duration in seconds, rounded to a tenth.
+func roundSeconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*10) / 10
+}
+
+// ScheduleReport simulates running the command on the executions of the
+// project, with the durations recorded by recent sessions, to find the
+// critical path, suggest which executions should start first, and show
+// where more parallelism would or wouldn't help.
+func (c *Project) ScheduleReport(parameters ScheduleParameters) (*ScheduleReport, error) {
+	if parameters.Command != "plan" && parameters.Command != "apply" {
+		return nil, fmt.Errorf("unknown command: %q; must be one of: plan, apply", parameters.Command)
+	}
+	if parameters.Parallelism < 0 {
+		return nil, errors.New("parallelism must not be negative")
+	}
+	history := parameters.History
+	if history == 0 {
+		history = defaultScheduleHistory
+	}
+
+	boundExecutions, err := c.executions(parameters.ExecutionParameters).bindAll(parameters.UserVars)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only applies of all modules follow the dependency graph
+	followsGraph := parameters.Command == "apply" && parameters.ModuleNames == nil
+	parallelism := parameters.Parallelism
+	if parallelism == 0 && !followsGraph {
+		parallelism = executionParallelism
+	}
+
+	durations, sessions, err := c.sessions.durationHistory(parameters.Command, history)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ScheduleReport{
+		SchemaVersion:  reportSchemaVersion,
+		Command:        parameters.Command,
+		Parallelism:    parallelism,
+		Sessions:       sessions,
+		CriticalPath:   []string{},
+		Executions:     []ScheduledExecution{},
+		Parallelisms:   []ParallelismEstimate{},
+		MissingHistory: []string{},
+		Caveats:        []string{},
+	}
+
+	nodes := make([]scheduleNode, len(boundExecutions))
+	index := map[*boundExecution]int{}
+	for i, b := range boundExecutions {
+		index[b] = i
+		report.Executions = append(report.Executions, ScheduledExecution{ID: b.ID(), Module: b.ModuleConfig().Name})
+
+		recorded := durations[b.ID()]
+		if len(recorded) == 0 {
+			report.MissingHistory = append(report.MissingHistory, b.ID())
+			continue
+		}
+		var total time.Duration
+		for _, d := range recorded {
+			total += d
+		}
+		nodes[i].duration = total / time.Duration(len(recorded))
+		report.Executions[i].Seconds = roundSeconds(nodes[i].duration)
+		report.Executions[i].Runs = len(recorded)
+	}
+
+	if followsGraph {
+		executions := make(executionSet, len(boundExecutions))
+		for i, b := range boundExecutions {
+			executions[i] = b
+		}
+		graph, err := executions.graph(c.config.Modules, c.logger)
+		if err != nil {
+			return nil, err
+		}
+		for i, b := range boundExecutions {
+			for _, v := range graph.DownEdges(b).List() {
+				dep, ok := v.(*boundExecution)
+				if !ok {
+					continue // the root of the graph
+				}
+				nodes[i].deps = append(nodes[i].deps, index[dep])
+				nodes[index[dep]].dependents = append(nodes[index[dep]].dependents, i)
+			}
+		}
+	}
+
+	// The current order is the order of the executions in the
+	// configuration; the suggested one starts the executions with the
+	// longest chains of dependents first.
+	currentRank := make([]int, len(nodes))
+	for i := range nodes {
+		currentRank[i] = i
+	}
+	levels := bottomLevels(nodes)
+	order := make([]int, len(nodes))
+	copy(order, currentRank)
+	sort.SliceStable(order, func(a, b int) bool { return levels[order[a]] > levels[order[b]] })
+	suggestedRank := make([]int, len(nodes))
+	for position, i := range order {
+		suggestedRank[i] = position
+		report.Executions[i].SuggestedPriority = len(nodes) - position
+	}
+
+	start, estimated := simulateSchedule(nodes, parallelism, currentRank)
+	_, suggested := simulateSchedule(nodes, parallelism, suggestedRank)
+	report.EstimatedSeconds = roundSeconds(estimated)
+	report.SuggestedSeconds = roundSeconds(suggested)
+	for i := range nodes {
+		report.Executions[i].StartSeconds = roundSeconds(start[i])
+	}
+
+	// Follow the longest chain from the execution with the highest level
+	if len(order) > 0 {
+		i := order[0]
+		report.CriticalPathSeconds = roundSeconds(levels[i])
+		for {
+			report.Executions[i].CriticalPath = true
+			report.CriticalPath = append(report.CriticalPath, boundExecutions[i].ID())
+			next := -1
+			for _, j := range nodes[i].dependents {
+				if next == -1 || levels[j] > levels[next] {
+					next = j
+				}
+			}
+			if next == -1 {
+				break
+			}
+			i = next
+		}
+	}
+
+	// Estimate doubling parallelism until every execution can run at once
+	saturated := 0
+	for p := 1; p < len(nodes); p *= 2 {
+		_, d := simulateSchedule(nodes, p, suggestedRank)
+		report.Parallelisms = append(report.Parallelisms, ParallelismEstimate{Parallelism: p, Seconds: roundSeconds(d)})
+		if saturated == 0 && d == levels[order[0]] {
+			saturated = p
+		}
+	}
+	report.Parallelisms = append(report.Parallelisms, ParallelismEstimate{Parallelism: 0, Seconds: report.CriticalPathSeconds})
+
+	sort.SliceStable(report.Executions, func(a, b int) bool {
+		return report.Executions[a].SuggestedPriority > report.Executions[b].SuggestedPriority
+	})
+
+	report.Caveats = scheduleCaveats(report, len(nodes), parallelism, saturated, followsGraph)
+
+	return report, nil
+}
+
+// scheduleCaveats returns the caveats of the report.
+func scheduleCaveats(report *ScheduleReport, executions, parallelism, saturated int, followsGraph bool) []string {
+	caveats := []string{}
+	if report.Sessions == 0 {
+		caveats = append(caveats, fmt.Sprintf("no durations of %s were found in the session repository; they are recorded by every %s", report.Command, report.Command))
+		return caveats
+	}
+	if len(report.MissingHistory) > 0 {
+		caveats = append(caveats, fmt.Sprintf("%d of %d executions have no recorded durations and are counted as taking no time, so the estimates are too low", len(report.MissingHistory), executions))
+	}
+	caveats = append(caveats, fmt.Sprintf("durations are averages of the last %d sessions, and include waiting for hooks and version_concurrency, which isn't simulated", report.Sessions))
+	if !followsGraph {
+		caveats = append(caveats, "dependencies aren't simulated, as they are ignored by this command")
+	}
+	if parallelism == 0 {
+		caveats = append(caveats, "every execution starts as soon as its dependencies are done, so the order they start in doesn't matter: only shortening the executions on the critical path shortens the run")
+	} else if report.SuggestedSeconds >= report.EstimatedSeconds {
+		caveats = append(caveats, "the suggested priorities don't shorten the run, as the current order is already as good")
+	}
+	if saturated > 0 {
+		caveats = append(caveats, fmt.Sprintf("parallelism above %d doesn't shorten the run, as the critical path takes %v", saturated, time.Duration(report.CriticalPathSeconds*float64(time.Second))))
+	}
+	return caveats
+}
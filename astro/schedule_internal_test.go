@@ -0,0 +1,180 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateSchedule(t *testing.T) {
+	// a (1m) -> b (2m), c (2m) and d (1m) are independent
+	nodes := []scheduleNode{
+		{duration: time.Minute, dependents: []int{1}},
+		{duration: 2 * time.Minute, deps: []int{0}},
+		{duration: 2 * time.Minute},
+		{duration: time.Minute},
+	}
+	inOrder := []int{0, 1, 2, 3}
+
+	start, total := simulateSchedule(nodes, 0, inOrder)
+	assert.Equal(t, 3*time.Minute, total)
+	assert.Equal(t, []time.Duration{0, time.Minute, 0, 0}, start)
+
+	_, total = simulateSchedule(nodes, 1, inOrder)
+	assert.Equal(t, 6*time.Minute, total)
+
+	// With two at a time, starting d before a delays b
+	_, total = simulateSchedule(nodes, 2, []int{3, 1, 2, 0})
+	assert.Equal(t, 4*time.Minute, total)
+	_, total = simulateSchedule(nodes, 2, inOrder)
+	assert.Equal(t, 3*time.Minute, total)
+
+	assert.Equal(t, []time.Duration{3 * time.Minute, 2 * time.Minute, 2 * time.Minute, time.Minute}, bottomLevels(nodes))
+}
+
+// writeScheduleProject writes a project in which b depends on a, and c is
+// independent, and returns it.
+func writeScheduleProject(t *testing.T, tmpdir string) *Project {
+	codeRoot := filepath.Join(tmpdir, "code")
+	for _, module := range []string{"a", "b", "c"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, module), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, module, "main.tf"), []byte("\n"), 0644))
+	}
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte("version: 0.12.6\n"), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  version: 0.12.6
+modules:
+  - name: a
+    path: a
+    local_state: ephemeral
+  - name: b
+    path: b
+    local_state: ephemeral
+    deps:
+      - module: a
+  - name: c
+    path: c
+    local_state: ephemeral
+`, codeRoot, tmpdir)), 0644))
+
+	c, err := NewProjectFromConfigFile(configPath, WithTerraformVersionResolver(versionResolverFunc(func(version string) (string, error) {
+		return terraformPath, nil
+	})))
+	require.NoError(t, err)
+	return c
+}
+
+func TestRecordDurations(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	c := writeScheduleProject(t, tmpdir)
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+	testReadResults(resultChan)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	manifest, err := session.Manifest()
+	require.NoError(t, err)
+
+	ids := []string{}
+	for _, d := range manifest.Durations {
+		assert.Equal(t, "plan", d.Command)
+		ids = append(ids, d.ID)
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, ids)
+}
+
+func TestScheduleReport(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	c := writeScheduleProject(t, tmpdir)
+
+	// Two sessions with durations of a and b, and one with only a plan
+	for _, durations := range [][]ExecutionDuration{
+		{{ID: "a", Command: "apply", Seconds: 50}, {ID: "b", Command: "apply", Seconds: 100}},
+		{{ID: "a", Command: "apply", Seconds: 70}, {ID: "b", Command: "apply", Seconds: 140}},
+		{{ID: "c", Command: "plan", Seconds: 500}},
+	} {
+		session, err := c.sessions.NewSession()
+		require.NoError(t, err)
+		require.NoError(t, session.updateManifest(func(manifest *Manifest) error {
+			manifest.Durations = durations
+			return nil
+		}))
+		time.Sleep(time.Millisecond) // session IDs sort by time
+	}
+
+	report, err := c.ScheduleReport(ScheduleParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		Command:             "apply",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.Sessions)
+	assert.Equal(t, 0, report.Parallelism)
+	assert.Equal(t, 180.0, report.EstimatedSeconds)
+	assert.Equal(t, []string{"a", "b"}, report.CriticalPath)
+	assert.Equal(t, 180.0, report.CriticalPathSeconds)
+	assert.Equal(t, []string{"c"}, report.MissingHistory)
+	assert.Contains(t, report.Caveats, "1 of 3 executions have no recorded durations and are counted as taking no time, so the estimates are too low")
+
+	assert.Equal(t, []ScheduledExecution{
+		{ID: "a", Module: "a", Seconds: 60, Runs: 2, CriticalPath: true, SuggestedPriority: 3},
+		{ID: "b", Module: "b", Seconds: 120, Runs: 2, StartSeconds: 60, CriticalPath: true, SuggestedPriority: 2},
+		{ID: "c", Module: "c", SuggestedPriority: 1},
+	}, report.Executions)
+
+	// Plans ignore dependencies and run 10 at a time
+	report, err = c.ScheduleReport(ScheduleParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		Command:             "plan",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Sessions)
+	assert.Equal(t, 10, report.Parallelism)
+	assert.Equal(t, 500.0, report.EstimatedSeconds)
+	assert.Equal(t, []string{"a", "b"}, report.MissingHistory)
+
+	_, err = c.ScheduleReport(ScheduleParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		Command:             "destroy",
+	})
+	assert.EqualError(t, err, `unknown command: "destroy"; must be one of: plan, apply`)
+}
@@ -0,0 +1,107 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/oklog/ulid"
+)
+
+// sortSessions sorts session IDs oldest first. Session IDs are ULIDs, which
+// sort chronologically as long as the clocks of the machines creating them
+// agree. Since a clock that is behind makes a new session sort before older
+// ones, sessions are ordered by the sequence number recorded in their
+// manifest first. Sessions without one, e.g. because they were created by an
+// older version of astro, have sequence 0 and sort before the others.
+func (r *SessionRepo) sortSessions(ids []string) []string {
+	sort.Strings(ids)
+
+	sequences := map[string]int{}
+	for _, id := range ids {
+		sequences[id] = r.sequence(id)
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return sequences[ids[i]] < sequences[ids[j]]
+	})
+
+	return ids
+}
+
+// sequence returns the sequence number recorded in the manifest of a
+// session, or 0 if it can't be read.
+func (r *SessionRepo) sequence(id string) int {
+	session := &Session{id: id, path: filepath.Join(r.path, id), repo: r}
+	manifest, err := session.Manifest()
+	if err != nil {
+		r.log().Debugf("astro: unable to read the sequence of session %v: %v", id, err)
+		return 0
+	}
+	return manifest.Sequence
+}
+
+// nextSequence returns the sequence number of a new session, and warns if
+// its ID sorts before the most recent session in the repo.
+func (r *SessionRepo) nextSequence(id string) (int, error) {
+	ids, err := r.Sessions()
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 1, nil
+	}
+
+	latest := ids[len(ids)-1]
+	if warning := clockSkewWarning(id, latest, r.project.config.ClockSkewTolerance.Duration()); warning != "" {
+		r.log().Warningf("%s", warning)
+	}
+
+	return r.sequence(latest) + 1, nil
+}
+
+// clockSkewWarning returns a warning if the ID of a new session sorts before
+// the ID of the most recent existing session by more than tolerance, which
+// means that the clock of the machine creating it, or of the one that
+// created the existing session, is wrong. IDs that are out of order by less
+// than that, e.g. because two sessions were created in the same
+// millisecond, are not worth a warning.
+func clockSkewWarning(id, latest string, tolerance time.Duration) string {
+	newID, err := ulid.Parse(id)
+	if err != nil {
+		return ""
+	}
+	latestID, err := ulid.Parse(latest)
+	if err != nil {
+		return ""
+	}
+	if newID.Compare(latestID) >= 0 {
+		return ""
+	}
+
+	skew := ulid.Time(latestID.Time()).Sub(ulid.Time(newID.Time()))
+	if skew <= tolerance {
+		return ""
+	}
+
+	return fmt.Sprintf("WARNING: the clock appears to be %v behind the one that created session %v, "+
+		"so new session %v sorts before it. astro orders sessions by the sequence in their manifest, "+
+		"but anything ordering them by ID or time will get them wrong. Check the clocks of the "+
+		"machines sharing the session repo, or set clock_skew_tolerance.", skew, latest, id)
+}
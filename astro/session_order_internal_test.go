@@ -0,0 +1,95 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ulidAt returns a session ID created at t.
+func ulidAt(t time.Time) string {
+	return ulid.MustNew(ulid.Timestamp(t), rand.New(rand.NewSource(t.UnixNano()))).String()
+}
+
+func TestSessionOrderWithOutOfOrderIDs(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-session-order-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	now := time.Now()
+	// sessions created by an older version of astro, without a sequence
+	legacy := []string{ulidAt(now.Add(-2 * time.Hour)), ulidAt(now.Add(-3 * time.Hour))}
+	for _, id := range legacy {
+		require.NoError(t, os.Mkdir(filepath.Join(tmpdir, id), 0755))
+	}
+
+	// the second new session is created on a machine whose clock is an
+	// hour behind, so its ID sorts before all the others
+	ids := []string{ulidAt(now), ulidAt(now.Add(-4 * time.Hour)), ulidAt(now.Add(time.Second))}
+	next := 0
+	repo, err := NewSessionRepo(&Project{config: &conf.Project{}}, tmpdir, func() string {
+		next++
+		return ids[next-1]
+	})
+	require.NoError(t, err)
+
+	for i, id := range ids {
+		session, err := repo.NewSession()
+		require.NoError(t, err)
+		assert.Equal(t, id, session.ID())
+
+		manifest, err := session.Manifest()
+		require.NoError(t, err)
+		assert.Equal(t, i+1, manifest.Sequence)
+		assert.NotNil(t, manifest.Created)
+
+		latest, err := repo.Latest()
+		require.NoError(t, err)
+		assert.Equal(t, id, latest.ID(), "session %d should be the latest", i)
+	}
+
+	sessions, err := repo.Sessions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{legacy[1], legacy[0], ids[0], ids[1], ids[2]}, sessions)
+}
+
+func TestClockSkewWarning(t *testing.T) {
+	now := time.Now()
+	latest := ulidAt(now)
+
+	// in order
+	assert.Empty(t, clockSkewWarning(ulidAt(now.Add(time.Second)), latest, 0))
+	// out of order within the tolerance
+	assert.Empty(t, clockSkewWarning(ulidAt(now.Add(-time.Second)), latest, 5*time.Second))
+
+	id := ulidAt(now.Add(-time.Minute))
+	warning := clockSkewWarning(id, latest, 5*time.Second)
+	assert.Contains(t, warning, "WARNING: the clock appears to be 1m0s behind")
+	assert.Contains(t, warning, latest)
+	assert.Contains(t, warning, id)
+}
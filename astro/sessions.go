@@ -19,17 +19,28 @@ package astro
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
 	"github.com/uber/astro/astro/utils"
 
 	"github.com/hashicorp/terraform/dag"
+	"github.com/oklog/ulid"
 )
 
+// executionParallelism is how many executions run at the same time, except
+// in applies that follow the dependency graph, which start every execution
+// as soon as its dependencies are done.
+const executionParallelism = 10
+
 // SessionRepo is a parent directory that contains inidividual project
 // sessions.
 type SessionRepo struct {
@@ -38,25 +49,178 @@ type SessionRepo struct {
 	path       string
 	generateID func() string
 
+	// modes for directories and files created in the repo; a file mode of
+	// 0 means the default
+	dirMode  os.FileMode
+	fileMode os.FileMode
+
 	current *Session
+
+	// guards the replacements file; see replacements
+	replacementsMu sync.Mutex
+
+	// the newer manifest schema versions that have been warned about; see
+	// warnNewerManifest
+	newerManifestWarnings sync.Map
+
+	// temporary is set for a repo in a temporary directory, used when the
+	// configured one can't be written to
+	temporary bool
 }
 
 // NewSessionRepo creates or opens a project session repo.
 func NewSessionRepo(project *Project, repoPath string, idGenFunc func() string) (*SessionRepo, error) {
+	dirMode := sessionDirMode(project.config)
+
 	// Create session directory if it doesn't exist
 	if !utils.IsDirectory(repoPath) {
-		if err := os.Mkdir(repoPath, 0755); err != nil {
+		if err := os.Mkdir(repoPath, dirMode); err != nil {
 			return nil, err
 		}
 	}
 
+	if err := checkWritable(repoPath); err != nil {
+		return nil, err
+	}
+
 	return &SessionRepo{
 		project:    project,
 		path:       repoPath,
 		generateID: idGenFunc,
+		dirMode:    dirMode,
+		fileMode:   os.FileMode(project.config.SessionFileMode),
+	}, nil
+}
+
+// sessionDirMode returns the mode to create directories in the session repo
+// with.
+func sessionDirMode(config *conf.Project) os.FileMode {
+	if config.SessionDirMode != 0 {
+		return os.FileMode(config.SessionDirMode)
+	}
+	return os.FileMode(conf.DefaultSessionDirMode)
+}
+
+// newTemporarySessionRepo creates a session repo in a new temporary
+// directory. It is not removed when astro exits, so that logs and plans can
+// still be collected.
+func newTemporarySessionRepo(project *Project, idGenFunc func() string) (*SessionRepo, error) {
+	repoPath, err := ioutil.TempDir("", "astro-sessions-")
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := NewSessionRepo(project, repoPath, idGenFunc)
+	if err != nil {
+		return nil, err
+	}
+	repo.temporary = true
+
+	return repo, nil
+}
+
+// checkWritable returns an error if files can't be created in the directory.
+func checkWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".write-check-")
+	if err != nil {
+		return fmt.Errorf("%v is not writable: %v", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Path returns the path to the session repo.
+func (r *SessionRepo) Path() string {
+	return r.path
+}
+
+// Temporary returns whether the session repo is in a temporary directory
+// rather than the configured session_repo_dir.
+func (r *SessionRepo) Temporary() bool {
+	return r.temporary
+}
+
+// OpenSessionRepo opens the existing session repo of a project
+// configuration, for inspecting the sessions in it. Unlike NewSessionRepo, it
+// doesn't create anything, so sessions opened from it can't be used to run
+// Terraform, though files can be attached to them.
+func OpenSessionRepo(config *conf.Project) (*SessionRepo, error) {
+	repoPath := filepath.Join(config.SessionRepoDir, ".astro")
+	if !utils.IsDirectory(repoPath) {
+		return nil, fmt.Errorf("no sessions found in %v", config.SessionRepoDir)
+	}
+
+	return &SessionRepo{
+		project:  &Project{config: config, stopped: make(chan struct{}), logger: logger.Default()},
+		path:     repoPath,
+		dirMode:  sessionDirMode(config),
+		fileMode: os.FileMode(config.SessionFileMode),
 	}, nil
 }
 
+// log returns the logger of the project of the repo.
+func (r *SessionRepo) log() logger.Logger {
+	if r.project == nil || r.project.logger == nil {
+		return logger.Default()
+	}
+	return r.project.logger
+}
+
+// Sessions returns the IDs of the sessions in the repo, oldest first. Other
+// directories in the repo, e.g. the shared plugin directory, are ignored.
+// Sessions are ordered by the sequence number in their manifest, then by ID;
+// see sortSessions.
+func (r *SessionRepo) Sessions() ([]string, error) {
+	entries, err := ioutil.ReadDir(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// Session IDs are ULIDs, which sort chronologically.
+		if _, err := ulid.Parse(entry.Name()); err != nil {
+			continue
+		}
+		ids = append(ids, entry.Name())
+	}
+
+	return r.sortSessions(ids), nil
+}
+
+// Open returns an existing session in the repo.
+func (r *SessionRepo) Open(id string) (*Session, error) {
+	if _, err := ulid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid session ID: %q", id)
+	}
+
+	sessionPath := filepath.Join(r.path, id)
+	if !utils.IsDirectory(sessionPath) {
+		return nil, fmt.Errorf("session %v not found in %v", id, r.path)
+	}
+
+	return &Session{
+		id:   id,
+		path: sessionPath,
+		repo: r,
+	}, nil
+}
+
+// Latest returns the most recent existing session in the repo.
+func (r *SessionRepo) Latest() (*Session, error) {
+	ids, err := r.Sessions()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no sessions found in %v", r.path)
+	}
+	return r.Open(ids[len(ids)-1])
+}
+
 // Session is a directory containing log output, Terraform state files
 // and plans.
 type Session struct {
@@ -65,6 +229,19 @@ type Session struct {
 	id   string
 	path string
 
+	// the sandboxes that executions share with conf.Project.SharedSandbox
+	sandboxes sharedSandboxes
+
+	// the states of the running executions, for telling state locks held
+	// within the run from others
+	inFlight inFlightStates
+
+	// the Terraform CLI config generated from terraform_cli_config; see
+	// terraformCLIConfigFile
+	cliConfigOnce sync.Once
+	cliConfigPath string
+	cliConfigErr  error
+
 	// for OS signal handling
 	signalChan chan os.Signal
 }
@@ -73,20 +250,46 @@ type Session struct {
 func (r *SessionRepo) NewSession() (*Session, error) {
 	id := r.generateID()
 
+	sequence, err := r.nextSequence(id)
+	if err != nil {
+		return nil, err
+	}
+
 	sessionPath := filepath.Join(r.path, id)
-	if err := os.Mkdir(sessionPath, 0755); err != nil {
+	if err := os.Mkdir(sessionPath, r.dirMode); err != nil {
 		return nil, err
 	}
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGTERM, syscall.SIGINT)
 
-	return &Session{
+	session := &Session{
 		id:         id,
 		path:       sessionPath,
 		repo:       r,
 		signalChan: signalChan,
-	}, nil
+	}
+
+	created := time.Now().UTC()
+	if err := session.updateManifest(func(manifest *Manifest) error {
+		manifest.Sequence = sequence
+		manifest.Created = &created
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to record the session sequence: %v", err)
+	}
+
+	return session, nil
+}
+
+// log returns the logger of the project of the session.
+func (s *Session) log() logger.Logger {
+	return s.repo.log()
+}
+
+// ID returns the ID of the session.
+func (s *Session) ID() string {
+	return s.id
 }
 
 // Current returns the last session created, or creates one if it's the
@@ -101,13 +304,75 @@ func (r *SessionRepo) Current() (*Session, error) {
 		return nil, err
 	}
 
+	if r.project != nil && r.project.runID != "" {
+		if err := session.updateManifest(func(manifest *Manifest) error {
+			manifest.RunID = r.project.runID
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("unable to record the run ID: %v", err)
+		}
+	}
+
 	r.current = session
 
 	return session, nil
 }
 
-func (s *Session) apply(boundExecutions []*boundExecution) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro session: running apply without graph")
+// RunID returns the ID of the run, which is the session ID unless another
+// one was set with WithRunID.
+func (s *Session) RunID() string {
+	if s.repo.project != nil && s.repo.project.runID != "" {
+		return s.repo.project.runID
+	}
+	return s.id
+}
+
+// tmpDir returns the absolute path to a temporary directory in the session,
+// creating it if it doesn't exist. Temporary directories are kept along with
+// the rest of the session, so that their contents can be inspected after a
+// run. They are under ".tmp" so they can't collide with an execution's
+// directory.
+func (s *Session) tmpDir(elem ...string) (string, error) {
+	dir, err := filepath.Abs(filepath.Join(append([]string{s.path, ".tmp"}, elem...)...))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, s.repo.dirMode); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// runHook runs the hook in the session directory, with its own temporary
+// directory at the specified path within the session.
+func (s *Session) runHook(hook conf.Hook, tmpDirElem ...string) error {
+	tmpDir, err := s.tmpDir(tmpDirElem...)
+	if err != nil {
+		return fmt.Errorf("unable to create temporary directory: %v", err)
+	}
+	return runCommandkAndSetEnvironment(s.path, tmpDir, s.RunID(), hook, s.log())
+}
+
+// context returns a context that is cancelled when the session receives an
+// interrupt signal or the project is stopped, so that no new executions are
+// scheduled.
+func (s *Session) context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case sig := <-s.signalChan:
+			fmt.Printf("\nReceived signal: %s, cancelling all operations...\n", sig)
+		case <-s.repo.project.stopped:
+		case <-ctx.Done():
+			return
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+func (s *Session) apply(boundExecutions []*boundExecution, parameters ApplyExecutionParameters) (<-chan string, <-chan *Result, error) {
+	s.log().Debugf("astro session: running apply without graph")
 
 	numberOfExecutions := len(boundExecutions)
 	// Needs to be big enough to buffer log lines from below for tests that
@@ -115,58 +380,72 @@ func (s *Session) apply(boundExecutions []*boundExecution) (<-chan string, <-cha
 	status := make(chan string, numberOfExecutions*10)
 	results := make(chan *Result, numberOfExecutions)
 
-	logger.Trace.Printf("astro: %d executions to apply\n", numberOfExecutions)
+	s.log().Debugf("astro: %d executions to apply\n", numberOfExecutions)
 
+	exclusive := &exclusiveBarrier{}
+	history := s.skipHistory(boundExecutions, parameters)
 	fns := []func(){}
 	for _, e := range boundExecutions {
 		b := e // save for use inside the loop
 		fns = append(fns, func() {
+			if result := s.skipUnchanged(status, b, parameters, history); result != nil {
+				results <- result
+				return
+			}
+
+			defer exclusive.acquire(status, b)()
+			defer s.inFlight.add(b)()
+
+			b.started = time.Now()
 			terraform, err := s.newTerraformSession(b)
 			if err != nil {
-				results <- &Result{
-					id:  b.ID(),
-					err: err,
-				}
+				results <- newResult(b, nil, err)
 				return
 			}
+			for _, message := range b.bindingStatus() {
+				status <- message
+			}
+			sandboxStatus(status, b.ID(), terraform)
 
-			status <- fmt.Sprintf("[%s] Initializing...", b.ID())
-			if result, err := terraform.Init(); err != nil {
-				results <- &Result{
-					id:              b.ID(),
-					terraformResult: result,
-					err:             err,
-				}
+			if err := checkLocalState(status, b, terraform, true); err != nil {
+				results <- newResult(b, nil, err)
+				return
+			}
+
+			if result, err := s.initTerraform(status, b, terraform, parameters.SkipStateMigration); err != nil {
+				results <- newResult(b, result, err)
+				return
+			}
+
+			apply, failed := s.checkPlan(status, b, terraform)
+			if failed != nil {
+				results <- failed
 				return
 			}
 
 			status <- fmt.Sprintf("[%s] Applying...", b.ID())
-			result, err := terraform.Apply()
-			results <- &Result{
-				id:              b.ID(),
-				terraformResult: result,
-				err:             err,
+			applyResult, err := s.retryOnLock(status, b, apply)
+			result := newResult(b, applyResult, err)
+			if err == nil && verifyAfterApply(b, parameters) {
+				result.verification = s.verifyApply(status, b, terraform)
 			}
+			results <- result
 		})
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		sig := <-s.signalChan
-		fmt.Printf("\nReceived signal: %s, cancelling all operations...\n", sig)
-		cancel()
-	}()
+	ctx, cancel := s.context()
 
 	go func() {
 		defer close(results) // signals the end of all executions
-		utils.Parallel(ctx, 10, fns...)
+		defer cancel()
+		utils.Parallel(ctx, executionParallelism, fns...)
 	}()
 
 	return status, results, nil
 }
 
-func (s *Session) applyWithGraph(boundExecutions []*boundExecution) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro session: running apply with graph")
+func (s *Session) applyWithGraph(boundExecutions []*boundExecution, parameters ApplyExecutionParameters) (<-chan string, <-chan *Result, error) {
+	s.log().Debugf("astro session: running apply with graph")
 
 	// Convert unboundExecutions to executionSet
 	executions := make(executionSet, len(boundExecutions))
@@ -175,7 +454,7 @@ func (s *Session) applyWithGraph(boundExecutions []*boundExecution) (<-chan stri
 	}
 
 	// Generate dep graph
-	graph, err := executions.graph()
+	graph, err := executions.graph(s.repo.project.config.Modules, s.log())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -186,67 +465,131 @@ func (s *Session) applyWithGraph(boundExecutions []*boundExecution) (<-chan stri
 	status := make(chan string, numberOfExecutions*10)
 	results := make(chan *Result, numberOfExecutions)
 
+	ctx, cancel := s.context()
+	exclusive := &exclusiveBarrier{}
+	history := s.skipHistory(boundExecutions, parameters)
+
+	// the executions that were walked, and those of them that failed; the
+	// others were skipped
+	var walkedMu sync.Mutex
+	walked := map[*boundExecution]bool{}
+	failed := map[*boundExecution]bool{}
+
 	// Walk the graph and execute
 	go func() {
 		defer close(results)
+		defer cancel()
 
-		graph.Walk(func(vertex dag.Vertex) error {
+		apply := func(vertex dag.Vertex) error {
 			// skip if we've reached the root
 			if _, ok := vertex.(graphNodeRoot); ok {
 				return nil
 			}
 
+			// Don't start any new executions once cancelled. This will
+			// cause any executions that depend on this one to be skipped.
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			b := vertex.(*boundExecution)
+			walkedMu.Lock()
+			walked[b] = true
+			walkedMu.Unlock()
+
+			// Executions that depend on a skipped one still run
+			if result := s.skipUnchanged(status, b, parameters, history); result != nil {
+				results <- result
+				return nil
+			}
+
+			defer exclusive.acquire(status, b)()
+			defer s.inFlight.add(b)()
+
+			b.started = time.Now()
 			terraform, err := s.newTerraformSession(b)
 			if err != nil {
-				results <- &Result{
-					id:  b.ID(),
-					err: err,
-				}
+				results <- newResult(b, nil, err)
+				return err
+			}
+			for _, message := range b.bindingStatus() {
+				status <- message
+			}
+			sandboxStatus(status, b.ID(), terraform)
+
+			if err := checkLocalState(status, b, terraform, true); err != nil {
+				results <- newResult(b, nil, err)
 				return err
 			}
 
-			for _, hook := range b.ModuleConfig().Hooks.PreModuleRun {
+			for i, hook := range b.ModuleConfig().Hooks.PreModuleRun {
 				status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
-				if err := runCommandkAndSetEnvironment(s.path, hook); err != nil {
-					results <- &Result{
-						id:  b.ID(),
-						err: fmt.Errorf("error running PreModuleRun hook: %v", err),
-					}
+				if err := s.runHook(hook, b.ID(), fmt.Sprintf("pre-module-run-hook-%d", i)); err != nil {
+					results <- newResult(b, nil, fmt.Errorf("error running PreModuleRun hook: %v", err))
 					return err
 				}
 			}
 
-			status <- fmt.Sprintf("[%s] Initializing...", b.ID())
-			if result, err := terraform.Init(); err != nil {
-				results <- &Result{
-					id:              b.ID(),
-					terraformResult: result,
-					err:             err,
-				}
+			if result, err := s.initTerraform(status, b, terraform, parameters.SkipStateMigration); err != nil {
+				results <- newResult(b, result, err)
 				return err
 			}
 
+			// Failed plan checks fail the execution, so that the
+			// executions that depend on it are skipped
+			apply, failed := s.checkPlan(status, b, terraform)
+			if failed != nil {
+				results <- failed
+				return failed.Err()
+			}
+
 			status <- fmt.Sprintf("[%s] Applying...", b.ID())
 
-			result, err := terraform.Apply()
-			results <- &Result{
-				id:              b.ID(),
-				terraformResult: result,
-				err:             err,
+			applyResult, err := s.retryOnLock(status, b, apply)
+			result := newResult(b, applyResult, err)
+			if err == nil && verifyAfterApply(b, parameters) {
+				result.verification = s.verifyApply(status, b, terraform)
 			}
+			results <- result
 
 			// This will cause any executions that depend on this one
 			// to be skipped.
 			return err
+		}
+
+		graph.Walk(func(vertex dag.Vertex) error {
+			err := apply(vertex)
+			if b, ok := vertex.(*boundExecution); ok && err != nil {
+				// Executions that weren't started because the apply
+				// was cancelled didn't fail
+				walkedMu.Lock()
+				failed[b] = walked[b]
+				walkedMu.Unlock()
+			}
+			return err
 		})
+
+		// Executions that depend on a failed one, or that weren't started
+		// because the apply was cancelled, have a result too, with the
+		// failed dependency, so that they aren't missed
+		for _, b := range boundExecutions {
+			if !walked[b] {
+				results <- skippedResult(graph, b, failed)
+			}
+		}
 	}()
 
 	return status, results, nil
 }
 
-func (s *Session) plan(boundExecutions []*boundExecution, detach bool) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro session: running plan")
+func (s *Session) plan(boundExecutions []*boundExecution, parameters PlanExecutionParameters) (<-chan string, <-chan *Result, error) {
+	s.log().Debugf("astro session: running plan")
+
+	// Detaching rewrites the code in the sandbox and leaves local state in
+	// it, so each execution needs its own
+	if parameters.Detach {
+		s.sandboxes.disabled = true
+	}
 
 	numberOfExecutions := len(boundExecutions)
 	// Needs to be big enough to buffer log lines from below for tests that
@@ -254,76 +597,358 @@ func (s *Session) plan(boundExecutions []*boundExecution, detach bool) (<-chan s
 	status := make(chan string, numberOfExecutions*10)
 	results := make(chan *Result, numberOfExecutions)
 
-	logger.Trace.Printf("astro: %d executions to plan\n", numberOfExecutions)
+	s.log().Debugf("astro: %d executions to plan\n", numberOfExecutions)
 
 	// Create plan functions
+	exclusive := &exclusiveBarrier{}
 	fns := []func(){}
 	for _, e := range boundExecutions {
 		b := e // save for use inside the loop
 		fns = append(fns, func() {
+			defer exclusive.acquire(status, b)()
+			defer s.inFlight.add(b)()
+
+			b.started = time.Now()
 			terraform, err := s.newTerraformSession(b)
 			if err != nil {
-				results <- &Result{
-					id:  b.ID(),
-					err: err,
-				}
+				results <- newResult(b, nil, err)
 				return
 			}
+			terraform.SetSavePlanJSON(parameters.SavePlanJSON)
+			for _, message := range b.bindingStatus() {
+				status <- message
+			}
+			sandboxStatus(status, b.ID(), terraform)
 
-			for _, hook := range e.ModuleConfig().Hooks.PreModuleRun {
+			if err := checkLocalState(status, b, terraform, false); err != nil {
+				results <- newResult(b, nil, err)
+				return
+			}
+
+			for i, hook := range e.ModuleConfig().Hooks.PreModuleRun {
 				status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
-				if err := runCommandkAndSetEnvironment(s.path, hook); err != nil {
-					results <- &Result{
-						id:  b.ID(),
-						err: fmt.Errorf("error running PreModuleRun hook: %v", err),
-					}
+				if err := s.runHook(hook, b.ID(), fmt.Sprintf("pre-module-run-hook-%d", i)); err != nil {
+					results <- newResult(b, nil, fmt.Errorf("error running PreModuleRun hook: %v", err))
 					return
 				}
 			}
 
-			status <- fmt.Sprintf("[%s] Initializing...", b.ID())
-			if result, err := terraform.Init(); err != nil {
-				results <- &Result{
-					id:              b.ID(),
-					terraformResult: result,
-					err:             err,
-				}
+			if result, err := s.initTerraform(status, b, terraform, parameters.SkipStateMigration); err != nil {
+				results <- newResult(b, result, err)
 				return
 			}
 
-			if detach {
+			if parameters.Detach {
 				status <- fmt.Sprintf("[%s] Disconnecting remote state...", b.ID())
 				if result, err := terraform.Detach(); err != nil {
-					results <- &Result{
-						id:              b.ID(),
-						terraformResult: result,
-						err:             err,
-					}
+					results <- newResult(b, result, err)
 					return
 				}
 			}
 
 			status <- fmt.Sprintf("[%s] Planning...", b.ID())
-			result, err := terraform.Plan()
-			results <- &Result{
-				id:              b.ID(),
-				terraformResult: result,
-				err:             err,
+			result, err := s.retryOnLock(status, b, terraform.Plan)
+			results <- newResult(b, result, err)
+		})
+	}
+
+	ctx, cancel := s.context()
+
+	// Run plans in parallel
+	go func() {
+		defer close(results) // signals the end of all executions
+		defer cancel()
+		utils.Parallel(ctx, executionParallelism, fns...)
+	}()
+
+	return status, results, nil
+}
+
+func (s *Session) refresh(boundExecutions []*boundExecution, skipStateMigration bool) (<-chan string, <-chan *Result, error) {
+	s.log().Debugf("astro session: running refresh")
+
+	numberOfExecutions := len(boundExecutions)
+	// Needs to be big enough to buffer log lines from below for tests that
+	// don't consume from the channel.
+	status := make(chan string, numberOfExecutions*10)
+	results := make(chan *Result, numberOfExecutions)
+
+	s.log().Debugf("astro: %d executions to refresh\n", numberOfExecutions)
+
+	exclusive := &exclusiveBarrier{}
+	fns := []func(){}
+	for _, e := range boundExecutions {
+		b := e // save for use inside the loop
+		fns = append(fns, func() {
+			defer exclusive.acquire(status, b)()
+			defer s.inFlight.add(b)()
+
+			b.started = time.Now()
+			terraform, err := s.newTerraformSession(b)
+			if err != nil {
+				results <- newResult(b, nil, err)
+				return
+			}
+			for _, message := range b.bindingStatus() {
+				status <- message
+			}
+			sandboxStatus(status, b.ID(), terraform)
+
+			// Refreshing writes state, so local state is checked as for apply
+			if err := checkLocalState(status, b, terraform, true); err != nil {
+				results <- newResult(b, nil, err)
+				return
+			}
+
+			for i, hook := range b.ModuleConfig().Hooks.PreModuleRun {
+				status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
+				if err := s.runHook(hook, b.ID(), fmt.Sprintf("pre-module-run-hook-%d", i)); err != nil {
+					results <- newResult(b, nil, fmt.Errorf("error running PreModuleRun hook: %v", err))
+					return
+				}
 			}
+
+			if result, err := s.initTerraform(status, b, terraform, skipStateMigration); err != nil {
+				results <- newResult(b, result, err)
+				return
+			}
+
+			status <- fmt.Sprintf("[%s] Refreshing...", b.ID())
+			result, err := terraform.Refresh()
+			results <- newResult(b, result, err)
 		})
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := s.context()
+
+	// Refreshes only read other modules' state, so they run in parallel
 	go func() {
-		sig := <-s.signalChan
-		fmt.Printf("\nReceived signal: %s, cancelling all operations...\n", sig)
-		cancel()
+		defer close(results) // signals the end of all executions
+		defer cancel()
+		utils.Parallel(ctx, executionParallelism, fns...)
 	}()
 
-	// Run plans in parallel
+	return status, results, nil
+}
+
+// importResource imports the existing resource with the id into the address
+// in the state of a single execution, and then plans it. The result is that
+// of the plan, so that it shows whether the configuration matches what was
+// imported.
+func (s *Session) importResource(b *boundExecution, address, id string, skipStateMigration bool) (<-chan string, <-chan *Result, error) {
+	s.log().Debugf("astro session: running import")
+
+	status := make(chan string, 10)
+	results := make(chan *Result, 1)
+
 	go func() {
 		defer close(results) // signals the end of all executions
-		utils.Parallel(ctx, 10, fns...)
+
+		b.started = time.Now()
+		terraform, err := s.newTerraformSession(b)
+		if err != nil {
+			results <- newResult(b, nil, err)
+			return
+		}
+		for _, message := range b.bindingStatus() {
+			status <- message
+		}
+		sandboxStatus(status, b.ID(), terraform)
+
+		// Importing writes state, so local state is checked as for apply
+		if err := checkLocalState(status, b, terraform, true); err != nil {
+			results <- newResult(b, nil, err)
+			return
+		}
+
+		for i, hook := range b.ModuleConfig().Hooks.PreModuleRun {
+			status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
+			if err := s.runHook(hook, b.ID(), fmt.Sprintf("pre-module-run-hook-%d", i)); err != nil {
+				results <- newResult(b, nil, fmt.Errorf("error running PreModuleRun hook: %v", err))
+				return
+			}
+		}
+
+		if result, err := s.initTerraform(status, b, terraform, skipStateMigration); err != nil {
+			results <- newResult(b, result, err)
+			return
+		}
+
+		status <- fmt.Sprintf("[%s] Importing %s...", b.ID(), address)
+		if result, err := terraform.Import(address, id); err != nil {
+			results <- newResult(b, result, err)
+			return
+		}
+
+		status <- fmt.Sprintf("[%s] Planning...", b.ID())
+		result, err := terraform.Plan()
+		results <- newResult(b, result, err)
+	}()
+
+	return status, results, nil
+}
+
+// state runs `terraform state` with the arguments on the state of a single
+// execution.
+func (s *Session) state(b *boundExecution, args []string, skipStateMigration bool) (<-chan string, <-chan *Result, error) {
+	s.log().Debugf("astro session: running state")
+
+	status := make(chan string, 10)
+	results := make(chan *Result, 1)
+
+	writesState := terraform.IsMutatingCommand(append([]string{"state"}, args...))
+
+	go func() {
+		defer close(results) // signals the end of all executions
+
+		b.started = time.Now()
+		terraform, err := s.newTerraformSession(b)
+		if err != nil {
+			results <- newResult(b, nil, err)
+			return
+		}
+		for _, message := range b.bindingStatus() {
+			status <- message
+		}
+		sandboxStatus(status, b.ID(), terraform)
+
+		// Subcommands that write state are checked as for apply
+		if err := checkLocalState(status, b, terraform, writesState); err != nil {
+			results <- newResult(b, nil, err)
+			return
+		}
+
+		for i, hook := range b.ModuleConfig().Hooks.PreModuleRun {
+			status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
+			if err := s.runHook(hook, b.ID(), fmt.Sprintf("pre-module-run-hook-%d", i)); err != nil {
+				results <- newResult(b, nil, fmt.Errorf("error running PreModuleRun hook: %v", err))
+				return
+			}
+		}
+
+		if result, err := s.initTerraform(status, b, terraform, skipStateMigration); err != nil {
+			results <- newResult(b, result, err)
+			return
+		}
+
+		status <- fmt.Sprintf("[%s] Running state %s...", b.ID(), args[0])
+		result, err := terraform.State(args...)
+		results <- newResult(b, result, err)
+	}()
+
+	return status, results, nil
+}
+
+// unlock runs `terraform force-unlock` of the state lock with the ID in a
+// single execution.
+func (s *Session) unlock(b *boundExecution, lockID string, skipStateMigration bool) (<-chan string, <-chan *Result, error) {
+	s.log().Debugf("astro session: running unlock")
+
+	status := make(chan string, 10)
+	results := make(chan *Result, 1)
+
+	go func() {
+		defer close(results) // signals the end of all executions
+
+		b.started = time.Now()
+		terraform, err := s.newTerraformSession(b)
+		if err != nil {
+			results <- newResult(b, nil, err)
+			return
+		}
+		for _, message := range b.bindingStatus() {
+			status <- message
+		}
+		sandboxStatus(status, b.ID(), terraform)
+
+		for i, hook := range b.ModuleConfig().Hooks.PreModuleRun {
+			status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
+			if err := s.runHook(hook, b.ID(), fmt.Sprintf("pre-module-run-hook-%d", i)); err != nil {
+				results <- newResult(b, nil, fmt.Errorf("error running PreModuleRun hook: %v", err))
+				return
+			}
+		}
+
+		if result, err := s.initTerraform(status, b, terraform, skipStateMigration); err != nil {
+			results <- newResult(b, result, err)
+			return
+		}
+
+		status <- fmt.Sprintf("[%s] Unlocking state lock %s...", b.ID(), lockID)
+		result, err := terraform.ForceUnlock(lockID)
+		results <- newResult(b, result, err)
+	}()
+
+	return status, results, nil
+}
+
+// taint runs `terraform taint` or `terraform untaint`, the command, of the
+// resource address in a single execution. If the Terraform version
+// deprecates taint, it records the resource for replacement on the next
+// apply of the execution instead.
+func (s *Session) taint(b *boundExecution, command, address string, skipStateMigration bool) (<-chan string, <-chan *Result, error) {
+	s.log().Debugf("astro session: running %s\n", command)
+
+	status := make(chan string, 10)
+	results := make(chan *Result, 1)
+
+	go func() {
+		defer close(results) // signals the end of all executions
+
+		b.started = time.Now()
+		terraform, err := s.newTerraformSession(b)
+		if err != nil {
+			results <- newResult(b, nil, err)
+			return
+		}
+		for _, message := range b.bindingStatus() {
+			status <- message
+		}
+		sandboxStatus(status, b.ID(), terraform)
+
+		deprecated, err := taintDeprecated(terraform)
+		if err != nil {
+			results <- newResult(b, nil, err)
+			return
+		}
+		if deprecated {
+			status <- fmt.Sprintf("[%s] Recording %s for replacement...", b.ID(), address)
+			err := s.repo.recordReplacement(b.ID(), command, address)
+			result := newResult(b, nil, err)
+			result.replacementRecorded = err == nil
+			results <- result
+			return
+		}
+
+		// Tainting writes state, so local state is checked as for apply
+		if err := checkLocalState(status, b, terraform, true); err != nil {
+			results <- newResult(b, nil, err)
+			return
+		}
+
+		for i, hook := range b.ModuleConfig().Hooks.PreModuleRun {
+			status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
+			if err := s.runHook(hook, b.ID(), fmt.Sprintf("pre-module-run-hook-%d", i)); err != nil {
+				results <- newResult(b, nil, fmt.Errorf("error running PreModuleRun hook: %v", err))
+				return
+			}
+		}
+
+		if result, err := s.initTerraform(status, b, terraform, skipStateMigration); err != nil {
+			results <- newResult(b, result, err)
+			return
+		}
+
+		if command == "untaint" {
+			status <- fmt.Sprintf("[%s] Untainting %s...", b.ID(), address)
+			result, err := terraform.Untaint(address)
+			results <- newResult(b, result, err)
+			return
+		}
+
+		status <- fmt.Sprintf("[%s] Tainting %s...", b.ID(), address)
+		result, err := terraform.Taint(address)
+		results <- newResult(b, result, err)
 	}()
 
 	return status, results, nil
@@ -0,0 +1,108 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+)
+
+// sharedSandbox is a sandbox that the executions of a module share in a
+// session, with conf.Project.SharedSandbox. It is cloned by the first
+// execution that needs it.
+type sharedSandbox struct {
+	once sync.Once
+	dir  string
+	err  error
+}
+
+// sharedSandboxes are the shared sandboxes of a session, by module name.
+type sharedSandboxes struct {
+	mu       sync.Mutex
+	byModule map[string]*sharedSandbox
+
+	// disabled is set for runs whose executions need their own sandbox,
+	// e.g. plans that detach
+	disabled bool
+}
+
+// sharedSandboxDir returns the path to the shared sandbox of the module in
+// the session. It is hidden, like ".tmp", so that it can't collide with an
+// execution's directory.
+func (s *Session) sharedSandboxDir(module string) string {
+	return filepath.Join(s.path, ".sandbox", module)
+}
+
+// sharedSandbox returns the shared sandbox of the module, cloning it with
+// the config if this is the first execution of the module to use it.
+func (s *Session) sharedSandbox(module string, config terraform.Config) (string, error) {
+	s.sandboxes.mu.Lock()
+	if s.sandboxes.byModule == nil {
+		s.sandboxes.byModule = map[string]*sharedSandbox{}
+	}
+	sandbox, ok := s.sandboxes.byModule[module]
+	if !ok {
+		sandbox = &sharedSandbox{dir: s.sharedSandboxDir(module)}
+		s.sandboxes.byModule[module] = sandbox
+	}
+	s.sandboxes.mu.Unlock()
+
+	sandbox.once.Do(func() {
+		if err := os.MkdirAll(filepath.Dir(sandbox.dir), s.repo.dirMode); err != nil {
+			sandbox.err = err
+			return
+		}
+		sandbox.err = terraform.CloneSandbox(sandbox.dir, config)
+	})
+
+	return sandbox.dir, sandbox.err
+}
+
+// canShareSandbox returns whether the executions of the module can share a
+// sandbox, or why not. Terraform writes local state and, from 0.14, the
+// dependency lock file to the module directory, and only keeps the rest of
+// its data in TF_DATA_DIR from 0.10.
+func canShareSandbox(moduleConfig conf.Module) (bool, string) {
+	terraformVersion := moduleConfig.Terraform.Version
+	if terraformVersion == nil {
+		return false, "the Terraform version isn't set in the config"
+	}
+	if terraform.VersionMatches(terraformVersion, "< 0.10") {
+		return false, fmt.Sprintf("Terraform %v doesn't support TF_DATA_DIR", terraformVersion)
+	}
+
+	moduleDir := filepath.Join(moduleConfig.TerraformCodeRoot, moduleConfig.Path)
+	hasBackend, err := terraform.HasBackend(moduleDir)
+	if err != nil {
+		return false, fmt.Sprintf("unable to check the module for a backend: %v", err)
+	}
+	if !hasBackend {
+		return false, "the module has no backend, so its state is kept in the sandbox"
+	}
+
+	if terraform.VersionMatches(terraformVersion, ">= 0.14") && !utils.FileExists(filepath.Join(moduleDir, ".terraform.lock.hcl")) {
+		return false, "the module has no .terraform.lock.hcl, which Terraform would write to the sandbox"
+	}
+
+	return true, ""
+}
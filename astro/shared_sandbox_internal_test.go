@@ -0,0 +1,136 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSharedSandboxProject writes a project with shared sandboxes to
+// tmpdir, and returns the path to its config and a version resolver for the
+// mock Terraform.
+func writeSharedSandboxProject(t *testing.T, tmpdir string) (string, TerraformVersionResolver) {
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, "app"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, "local"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "app", "main.tf"), []byte(`terraform {
+  backend "s3" {}
+}
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "local", "main.tf"), []byte("\n"), 0644))
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte("version: 0.12.6\n"), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+shared_sandbox: true
+terraform:
+  version: 0.12.6
+modules:
+  - name: app
+    path: app
+    remote:
+      backend: s3
+    variables:
+      - name: region
+        values: [east, west, north]
+  - name: local
+    path: local
+    local_state: ephemeral
+`, codeRoot, tmpdir)), 0644))
+
+	return configPath, versionResolverFunc(func(version string) (string, error) {
+		return terraformPath, nil
+	})
+}
+
+func TestSharedSandbox(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	configPath, resolver := writeSharedSandboxProject(t, tmpdir)
+	c, err := NewProjectFromConfigFile(configPath, WithTerraformVersionResolver(resolver))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app-east":  nil,
+		"app-west":  nil,
+		"app-north": nil,
+		"local":     nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	// The executions of app share one sandbox and each get a data dir
+	assert.True(t, utils.FileExists(filepath.Join(session.sharedSandboxDir("app"), "app/main.tf")))
+	for _, id := range []string{"app-east", "app-west", "app-north"} {
+		assert.False(t, utils.IsDirectory(filepath.Join(session.path, id, "sandbox")), id)
+
+		paths, err := session.ExecutionPaths(id)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(session.sharedSandboxDir("app"), "app"), paths.Sandbox)
+	}
+
+	// Modules without a backend keep their state in the sandbox, so they
+	// get their own
+	assert.False(t, utils.IsDirectory(session.sharedSandboxDir("local")))
+	assert.True(t, utils.FileExists(filepath.Join(session.path, "local", "sandbox", "local/main.tf")))
+}
+
+func TestSharedSandboxDetach(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	configPath, resolver := writeSharedSandboxProject(t, tmpdir)
+	c, err := NewProjectFromConfigFile(configPath, WithTerraformVersionResolver(resolver))
+	require.NoError(t, err)
+
+	parameters := NoPlanExecutionParameters()
+	parameters.Detach = true
+	_, resultChan, err := c.Plan(parameters)
+	require.NoError(t, err)
+	testReadResults(resultChan)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	// Detaching rewrites the sandbox, so every execution gets its own
+	assert.False(t, utils.IsDirectory(session.sharedSandboxDir("app")))
+	for _, id := range []string{"app-east", "app-west", "app-north"} {
+		assert.True(t, utils.FileExists(filepath.Join(session.path, id, "sandbox", "app/main.tf")), id)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// unchangedReason is the reason recorded in the session manifest for the
+// applies skipped with skip_unchanged_applies.
+const unchangedReason = "source, variables and Terraform version unchanged since the last successful apply"
+
+// lastApply is the most recent apply of an execution in the session repo.
+type lastApply struct {
+	ExecutionApply
+	session string
+}
+
+// applyHistory returns the most recent apply recorded in the manifests of
+// the sessions of the repo by execution ID. Sessions whose manifest can't be
+// read are skipped.
+func (r *SessionRepo) applyHistory() (map[string]lastApply, error) {
+	ids, err := r.Sessions()
+	if err != nil {
+		return nil, err
+	}
+
+	history := map[string]lastApply{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		session, err := r.Open(ids[i])
+		if err != nil {
+			return nil, err
+		}
+		manifest, err := session.Manifest()
+		if err != nil {
+			r.log().Debugf("astro: skipping session %v in apply history: %v", ids[i], err)
+			continue
+		}
+
+		// the last record of an execution in a session is the most recent
+		for j := len(manifest.Applies) - 1; j >= 0; j-- {
+			apply := manifest.Applies[j]
+			if _, ok := history[apply.ID]; !ok {
+				history[apply.ID] = lastApply{ExecutionApply: apply, session: ids[i]}
+			}
+		}
+	}
+
+	return history, nil
+}
+
+// skipHistory returns the apply history that skipUnchanged compares the
+// executions with, or nil if none of them can be skipped. If the history
+// can't be read, nothing is skipped.
+func (s *Session) skipHistory(boundExecutions []*boundExecution, parameters ApplyExecutionParameters) map[string]lastApply {
+	if parameters.NoSkipUnchanged {
+		return nil
+	}
+	for _, b := range boundExecutions {
+		if !b.ModuleConfig().SkipUnchangedApplies {
+			continue
+		}
+		history, err := s.repo.applyHistory()
+		if err != nil {
+			s.log().Errorf("astro: unable to read the apply history, not skipping unchanged applies: %v", err)
+			return nil
+		}
+		return history
+	}
+	return nil
+}
+
+// applyFingerprint returns what applying the execution would apply: the
+// hash of the code that is cloned into its sandbox, a hash of everything
+// else that is passed to Terraform, and the Terraform version.
+func applyFingerprint(b *boundExecution) (*ExecutionApply, error) {
+	moduleConfig := b.ModuleConfig()
+
+	basePath, modulePath, include := moduleConfig.SandboxLayout()
+	exclude, err := moduleConfig.SandboxExclude()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read sandbox exclusions: %v", err)
+	}
+	sourceHash, err := terraform.SourceHash(basePath, modulePath, include, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("unable to hash the module's code: %v", err)
+	}
+
+	inputs, err := json.Marshal(struct {
+		Variables           map[string]string `json:"variables"`
+		Env                 map[string]string `json:"env"`
+		Backend             string            `json:"backend"`
+		BackendConfig       map[string]string `json:"backend_config"`
+		TerraformParameters []string          `json:"terraform_parameters"`
+	}{
+		Variables:           b.Variables(),
+		Env:                 moduleConfig.Env,
+		Backend:             moduleConfig.Remote.Backend,
+		BackendConfig:       moduleConfig.Remote.BackendConfig,
+		TerraformParameters: b.TerraformParameters(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	variablesHash := sha256.Sum256(inputs)
+
+	fingerprint := &ExecutionApply{
+		ID:            b.ID(),
+		Module:        moduleConfig.Name,
+		SourceHash:    sourceHash,
+		VariablesHash: hex.EncodeToString(variablesHash[:]),
+	}
+	if moduleConfig.Terraform.Version != nil {
+		fingerprint.TerraformVersion = moduleConfig.Terraform.Version.String()
+	}
+	return fingerprint, nil
+}
+
+// skipUnchanged fingerprints the execution if its module has
+// skip_unchanged_applies, and returns the result of skipping its apply if
+// the fingerprint is the same as in its last successful apply, which is
+// looked up in history. It returns nil if the execution must be applied.
+func (s *Session) skipUnchanged(status chan<- string, b *boundExecution, parameters ApplyExecutionParameters, history map[string]lastApply) *Result {
+	if !b.ModuleConfig().SkipUnchangedApplies {
+		return nil
+	}
+
+	fingerprint, err := applyFingerprint(b)
+	if err != nil {
+		s.log().Errorf("astro: %v: unable to tell whether it changed since the last apply: %v", b.ID(), err)
+		return nil
+	}
+	b.fingerprint = fingerprint
+
+	if parameters.NoSkipUnchanged {
+		return nil
+	}
+
+	last, ok := history[b.ID()]
+	if !ok || !last.Succeeded || fingerprint.TerraformVersion == "" {
+		return nil
+	}
+	if last.SourceHash != fingerprint.SourceHash ||
+		last.VariablesHash != fingerprint.VariablesHash ||
+		last.TerraformVersion != fingerprint.TerraformVersion {
+		return nil
+	}
+
+	// Resources marked for replacement since are a change, too
+	if replace, err := s.repo.replacements(b.ID()); err != nil || len(replace) > 0 {
+		return nil
+	}
+
+	status <- fmt.Sprintf("[%s] Unchanged since the apply in session %s; skipping", b.ID(), last.session)
+	result := newResult(b, nil, nil)
+	result.unchangedSince = last.session
+	return result
+}
+
+// recordApplies passes results through, and records the fingerprints of
+// the applies of modules with skip_unchanged_applies, and the applies that
+// were skipped, because they were unchanged or a dependency failed, in the
+// session manifest once all of them are done, before the returned channel
+// is closed.
+func (s *Session) recordApplies(results <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(results))
+
+	go func() {
+		defer close(out)
+
+		applies := []ExecutionApply{}
+		skipped := []ExecutionSkip{}
+		for result := range results {
+			if result.UnchangedSince() != "" {
+				skipped = append(skipped, ExecutionSkip{
+					ID:               result.ID(),
+					Module:           result.Module(),
+					Reason:           unchangedReason,
+					LastApplySession: result.UnchangedSince(),
+				})
+			} else if skippedErr, ok := result.Err().(*SkippedError); ok {
+				skipped = append(skipped, ExecutionSkip{
+					ID:               result.ID(),
+					Module:           result.Module(),
+					Reason:           skippedErr.Error(),
+					FailedDependency: skippedErr.FailedDependency,
+				})
+			} else if result.fingerprint != nil {
+				apply := *result.fingerprint
+				apply.Succeeded = result.Err() == nil && result.TerraformResult() != nil
+				applies = append(applies, apply)
+			}
+			out <- result
+		}
+
+		if len(applies) == 0 && len(skipped) == 0 {
+			return
+		}
+		err := s.updateManifest(func(manifest *Manifest) error {
+			manifest.Applies = append(manifest.Applies, applies...)
+			manifest.Skipped = append(manifest.Skipped, skipped...)
+			return nil
+		})
+		if err != nil {
+			s.log().Errorf("astro: unable to record applies in session %v: %v", s.id, err)
+		}
+	}()
+
+	return out
+}
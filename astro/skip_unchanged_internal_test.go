@@ -0,0 +1,140 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestSkipUnchangedApplies(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "main.tf"), []byte("main"), 0644))
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte("version: 0.12.6\n"), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	writeConfig := func(region string) {
+		require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+    skip_unchanged_applies: true
+    env:
+      REGION: %s
+  - name: other
+    path: .
+    local_state: ephemeral
+`, codeRoot, tmpdir, terraformPath, region)), 0644))
+	}
+
+	// apply runs the apply in a new session, and returns the results and
+	// the session's manifest
+	apply := func(noSkip bool) (map[string]*Result, *Manifest) {
+		c, err := NewProjectFromConfigFile(configPath)
+		require.NoError(t, err)
+
+		status, resultChan, err := c.Apply(ApplyExecutionParameters{
+			ExecutionParameters: NoExecutionParameters(),
+			NoSkipUnchanged:     noSkip,
+		})
+		require.NoError(t, err)
+		results := testReadResults(resultChan)
+		for len(status) > 0 {
+			<-status
+		}
+		for id, result := range results {
+			require.NoError(t, result.Err(), id)
+		}
+
+		session, err := c.sessions.Current()
+		require.NoError(t, err)
+		manifest, err := session.Manifest()
+		require.NoError(t, err)
+		return results, manifest
+	}
+
+	writeConfig("us-east-1")
+	results, manifest := apply(false)
+	assert.Empty(t, results["app"].UnchangedSince())
+	require.Len(t, manifest.Applies, 1)
+	first := manifest.Applies[0]
+	assert.Equal(t, "app", first.ID)
+	assert.True(t, first.Succeeded)
+	assert.Equal(t, "0.12.6", first.TerraformVersion)
+	assert.NotEmpty(t, first.SourceHash)
+	assert.NotEmpty(t, first.VariablesHash)
+
+	// nothing changed, so only the module without skip_unchanged_applies
+	// is applied
+	results, manifest = apply(false)
+	firstSession := results["app"].UnchangedSince()
+	assert.NotEmpty(t, firstSession)
+	assert.Nil(t, results["app"].TerraformResult())
+	assert.Empty(t, results["other"].UnchangedSince())
+	assert.NotNil(t, results["other"].TerraformResult())
+	assert.Empty(t, manifest.Applies)
+	assert.Equal(t, []ExecutionSkip{{
+		ID:               "app",
+		Module:           "app",
+		Reason:           unchangedReason,
+		LastApplySession: firstSession,
+	}}, manifest.Skipped)
+
+	results, manifest = apply(true)
+	assert.Empty(t, results["app"].UnchangedSince())
+	assert.Empty(t, manifest.Skipped)
+	require.Len(t, manifest.Applies, 1)
+	assert.Equal(t, first.SourceHash, manifest.Applies[0].SourceHash)
+
+	// changing the code or the environment is a change
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "main.tf"), []byte("main changed"), 0644))
+	results, manifest = apply(false)
+	assert.Empty(t, results["app"].UnchangedSince())
+	require.Len(t, manifest.Applies, 1)
+	assert.NotEqual(t, first.SourceHash, manifest.Applies[0].SourceHash)
+	assert.Equal(t, first.VariablesHash, manifest.Applies[0].VariablesHash)
+
+	writeConfig("us-west-2")
+	results, manifest = apply(false)
+	assert.Empty(t, results["app"].UnchangedSince())
+	require.Len(t, manifest.Applies, 1)
+	assert.NotEqual(t, first.VariablesHash, manifest.Applies[0].VariablesHash)
+
+	results, _ = apply(false)
+	assert.NotEmpty(t, results["app"].UnchangedSince())
+}
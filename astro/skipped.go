@@ -0,0 +1,61 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// SkippedError is the error of the result of an execution that wasn't run
+// by an apply, because an execution it depends on failed, or because the
+// apply was stopped before it started.
+type SkippedError struct {
+	// FailedDependency is the ID of the execution it depends on, directly
+	// or not, that failed, or empty if none of them did.
+	FailedDependency string
+}
+
+func (e *SkippedError) Error() string {
+	if e.FailedDependency == "" {
+		return "skipped: the apply was stopped before it started"
+	}
+	return fmt.Sprintf("skipped: dependency %s failed", e.FailedDependency)
+}
+
+// skippedResult returns the result of an execution that the walk of the
+// graph didn't visit, with the first of the executions it depends on that
+// failed, by ID.
+func skippedResult(graph *dag.AcyclicGraph, b *boundExecution, failed map[*boundExecution]bool) *Result {
+	failedDeps := []string{}
+	if deps, err := graph.Ancestors(b); err == nil {
+		for _, v := range deps.List() {
+			if dep, ok := v.(*boundExecution); ok && failed[dep] {
+				failedDeps = append(failedDeps, dep.ID())
+			}
+		}
+	}
+	sort.Strings(failedDeps)
+
+	err := &SkippedError{}
+	if len(failedDeps) > 0 {
+		err.FailedDependency = failedDeps[0]
+	}
+	return newResult(b, nil, err)
+}
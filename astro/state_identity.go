@@ -0,0 +1,119 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+
+	"github.com/uber/astro/astro/conf"
+)
+
+// backend_config parameters that locate the state within a backend, e.g. the
+// key of an S3 object. Without one of these, including when there is no
+// backend_config at all, the location is set in the module's code.
+var stateLocationKeys = []string{"key", "name", "path", "prefix"}
+
+// stateIdentity returns the fields that identify where the state of the
+// execution is stored, e.g. ["backend=s3", "bucket=states", "key=app"], or
+// nil if the state is kept in the execution's own sandbox.
+func (b *boundExecution) stateIdentity() []string {
+	moduleConf := b.ModuleConfig()
+
+	if path := moduleConf.LocalStatePersistPath(); path != "" {
+		return []string{fmt.Sprintf("local_state=%s", resolvedPath(path))}
+	}
+	if moduleConf.LocalState == conf.LocalStateEphemeral {
+		return nil
+	}
+
+	fields := []string{}
+	if moduleConf.Remote.Backend != "" {
+		fields = append(fields, fmt.Sprintf("backend=%s", moduleConf.Remote.Backend))
+	}
+
+	hasLocation := false
+	keys := []string{}
+	for key := range moduleConf.Remote.BackendConfig {
+		keys = append(keys, key)
+		for _, locationKey := range stateLocationKeys {
+			if key == locationKey {
+				hasLocation = true
+			}
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fields = append(fields, fmt.Sprintf("%s=%s", key, moduleConf.Remote.BackendConfig[key]))
+	}
+
+	// The rest of the location is in the module's code, so only executions
+	// of the same code share the state. Symlinks are resolved so that a
+	// module directory linked into two places is recognized.
+	if !hasLocation {
+		fields = append(fields, fmt.Sprintf("module=%s", resolvedPath(filepath.Join(moduleConf.TerraformCodeRoot, moduleConf.Path))))
+	}
+
+	return fields
+}
+
+// resolvedPath returns the path with any symlinks evaluated, or the path
+// itself if they can't be.
+func resolvedPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// checkDuplicateStates returns an error for every pair of executions whose
+// state is stored in the same place. Applying them in the same run would
+// corrupt the state or deadlock on its lock.
+func checkDuplicateStates(executions []*boundExecution) (errs error) {
+	owners := map[string]*boundExecution{}
+	for _, b := range executions {
+		fields := b.stateIdentity()
+		if fields == nil {
+			continue
+		}
+		identity := strings.Join(fields, ", ")
+		owner, ok := owners[identity]
+		if !ok {
+			owners[identity] = b
+			continue
+		}
+		errs = multierror.Append(errs, fmt.Errorf("%s (%s) and %s (%s) use the same state: %s",
+			owner.ID(), configFileName(owner), b.ID(), configFileName(b), identity))
+	}
+	if errs != nil {
+		return fmt.Errorf("duplicate state: %v", errs)
+	}
+	return nil
+}
+
+// configFileName returns the configuration file the module of the execution
+// is declared in, for error messages.
+func configFileName(b *boundExecution) string {
+	if configFile := b.ModuleConfig().ConfigFile; configFile != "" {
+		return configFile
+	}
+	return "<unknown config>"
+}
@@ -0,0 +1,99 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+)
+
+func boundTestExecution(t *testing.T, moduleConf conf.Module) *boundExecution {
+	executions := newModule(moduleConf).executions(NoExecutionParameters(), logger.Discard)
+	require.Len(t, executions, 1)
+	b, err := executions[0].(*unboundExecution).bind(nil, nil)
+	require.NoError(t, err)
+	return b
+}
+
+func TestCheckDuplicateStates(t *testing.T) {
+	t.Parallel()
+
+	codeRoot, err := ioutil.TempDir("", "astro-state-identity")
+	require.NoError(t, err)
+	defer os.RemoveAll(codeRoot)
+
+	require.NoError(t, os.Mkdir(filepath.Join(codeRoot, "app"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(codeRoot, "db"), 0755))
+	require.NoError(t, os.Symlink("app", filepath.Join(codeRoot, "app-link")))
+
+	remote := func(backendConfig map[string]string) conf.Remote {
+		return conf.Remote{Backend: "s3", BackendConfig: backendConfig}
+	}
+
+	app := conf.Module{Name: "app", Path: "app", ConfigFile: "/a/astro.yaml", TerraformCodeRoot: codeRoot, Remote: remote(map[string]string{"bucket": "states", "key": "app"})}
+	appCopy := conf.Module{Name: "app2", Path: "db", ConfigFile: "/b/astro.yaml", TerraformCodeRoot: codeRoot, Remote: remote(map[string]string{"bucket": "states", "key": "app"})}
+	db := conf.Module{Name: "db", Path: "db", ConfigFile: "/a/astro.yaml", TerraformCodeRoot: codeRoot, Remote: remote(map[string]string{"bucket": "states", "key": "db"})}
+
+	// the key is set in the code of the module
+	appInCode := conf.Module{Name: "app-code", Path: "app", TerraformCodeRoot: codeRoot, Remote: remote(map[string]string{"bucket": "states"})}
+	appLinkInCode := conf.Module{Name: "app-link", Path: "app-link", ConfigFile: "/b/astro.yaml", TerraformCodeRoot: codeRoot, Remote: remote(map[string]string{"bucket": "states"})}
+	dbInCode := conf.Module{Name: "db-code", Path: "db", TerraformCodeRoot: codeRoot, Remote: remote(map[string]string{"bucket": "states"})}
+
+	// the whole backend is set in the code of the module
+	dbNoConfig := conf.Module{Name: "db-noconfig", Path: "db", ConfigFile: "/b/astro.yaml", TerraformCodeRoot: codeRoot}
+
+	ephemeral := conf.Module{Name: "scratch", Path: "app", TerraformCodeRoot: codeRoot, LocalState: conf.LocalStateEphemeral}
+
+	assert.NoError(t, checkDuplicateStates([]*boundExecution{
+		boundTestExecution(t, app),
+		boundTestExecution(t, db),
+		boundTestExecution(t, appInCode),
+		boundTestExecution(t, dbInCode),
+		boundTestExecution(t, dbNoConfig),
+		boundTestExecution(t, ephemeral),
+		boundTestExecution(t, ephemeral),
+	}))
+
+	assert.EqualError(t, checkDuplicateStates([]*boundExecution{
+		boundTestExecution(t, app),
+		boundTestExecution(t, db),
+		boundTestExecution(t, appCopy),
+	}), "duplicate state: 1 error occurred:\n\n* app (/a/astro.yaml) and app2 (/b/astro.yaml) use the same state: backend=s3, bucket=states, key=app")
+
+	resolvedCodeRoot, err := filepath.EvalSymlinks(codeRoot)
+	require.NoError(t, err)
+	assert.EqualError(t, checkDuplicateStates([]*boundExecution{
+		boundTestExecution(t, appInCode),
+		boundTestExecution(t, appLinkInCode),
+	}), "duplicate state: 1 error occurred:\n\n* app-code (<unknown config>) and app-link (/b/astro.yaml) use the same state: backend=s3, bucket=states, module="+filepath.Join(resolvedCodeRoot, "app"))
+
+	dbNoConfigCopy := dbNoConfig
+	dbNoConfigCopy.Name = "db-noconfig2"
+	dbNoConfigCopy.ConfigFile = "/a/astro.yaml"
+	assert.EqualError(t, checkDuplicateStates([]*boundExecution{
+		boundTestExecution(t, dbNoConfigCopy),
+		boundTestExecution(t, dbNoConfig),
+	}), "duplicate state: 1 error occurred:\n\n* db-noconfig2 (/a/astro.yaml) and db-noconfig (/b/astro.yaml) use the same state: module="+filepath.Join(resolvedCodeRoot, "db"))
+}
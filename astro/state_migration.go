@@ -0,0 +1,91 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// initTerraform initializes the Terraform session for the execution, once
+// version_concurrency allows it. If the module has a state_migration block,
+// its state is then migrated from the previous backend location, unless
+// skipStateMigration is set. Finally, the state is checked with
+// checkStateVersion.
+func (s *Session) initTerraform(status chan<- string, b *boundExecution, session *terraform.Session, skipStateMigration bool) (terraform.Result, error) {
+	result, err := s.retryOnLock(status, b, func() (terraform.Result, error) {
+		release, err := s.repo.project.initConcurrency.acquire(context.Background(), status, b.ID(), b.ModuleConfig().Terraform.Version)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		status <- fmt.Sprintf("[%s] Initializing...", b.ID())
+		return session.Init()
+	})
+	if err != nil {
+		return result, err
+	}
+	if session.InitCached() {
+		status <- fmt.Sprintf("[%s] Restored initialization from the init cache", b.ID())
+	}
+	if session.LockFileChanged() {
+		b.lockFileChanged = true
+		status <- fmt.Sprintf("[%s] WARNING: terraform init changed %s", b.ID(), terraform.LockFileName)
+	}
+
+	moduleConfig := b.ModuleConfig()
+	if moduleConfig.StateMigration == nil {
+		return nil, checkStateVersion(status, b, session)
+	}
+
+	if skipStateMigration {
+		status <- fmt.Sprintf("[%s] Skipping state migration", b.ID())
+		return nil, checkStateVersion(status, b, session)
+	}
+
+	if s.repo.project.config.ReadOnly {
+		status <- fmt.Sprintf("[%s] WARNING: skipping state migration in read-only mode", b.ID())
+		return nil, checkStateVersion(status, b, session)
+	}
+
+	previousBackendConfig := map[string]string{}
+	for key, val := range moduleConfig.Remote.BackendConfig {
+		previousBackendConfig[key] = val
+	}
+	for key, val := range moduleConfig.StateMigration.BackendConfig {
+		previousBackendConfig[key] = val
+	}
+
+	status <- fmt.Sprintf("[%s] Checking whether state needs to be migrated...", b.ID())
+	migrationStatus, result, err := session.MigrateState(previousBackendConfig)
+	if err != nil {
+		return result, fmt.Errorf("unable to migrate state: %v", err)
+	}
+
+	switch migrationStatus {
+	case terraform.StateMigrated:
+		status <- fmt.Sprintf("[%s] Migrated state from the previous backend location", b.ID())
+	case terraform.StateAlreadyMigrated:
+		status <- fmt.Sprintf("[%s] WARNING: state has already been migrated; remove state_migration from the module configuration", b.ID())
+	case terraform.StateNothingToMigrate:
+		status <- fmt.Sprintf("[%s] WARNING: no state at the previous backend location; nothing to migrate", b.ID())
+	}
+
+	return nil, checkStateVersion(status, b, session)
+}
@@ -0,0 +1,48 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// checkStateVersion reads the version of Terraform that last wrote the state
+// of the execution, and records it in the execution's result. It returns an
+// error if it is newer than the version the module is configured to use, as
+// Terraform refuses to work with state written by a newer version, and only
+// says so deep in its log.
+func checkStateVersion(status chan<- string, b *boundExecution, session *terraform.Session) error {
+	stateVersion, err := session.StateVersion()
+	if err != nil {
+		return fmt.Errorf("unable to check the Terraform version of the state: %v", err)
+	}
+	if stateVersion == nil {
+		return nil
+	}
+	b.stateTerraformVersion = stateVersion
+
+	moduleConfig := b.ModuleConfig()
+	configuredVersion := moduleConfig.Terraform.Version
+	if configuredVersion == nil || !stateVersion.GreaterThan(configuredVersion) {
+		return nil
+	}
+
+	status <- fmt.Sprintf("[%s] UPGRADE REQUIRED: state was written by Terraform %v", b.ID(), stateVersion)
+	return fmt.Errorf("UPGRADE REQUIRED: the state of %s was written by Terraform %v, which is newer than Terraform %v that module %v is configured to use; set terraform.version to %v or later in the module or project configuration", b.ID(), stateVersion, configuredVersion, moduleConfig.Name, stateVersion)
+}
@@ -0,0 +1,84 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateWrittenByNewerTerraform(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+	for _, tt := range []struct {
+		terraformVersion string
+		err              string
+	}{
+		{"0.11.7", "UPGRADE REQUIRED: the state of app was written by Terraform 0.12.6, which is newer than Terraform 0.11.7 that module app is configured to use; set terraform.version to 0.12.6 or later"},
+		{"0.12.6", ""},
+	} {
+		specPath := filepath.Join(tmpdir, "spec-"+tt.terraformVersion+".yaml")
+		require.NoError(t, ioutil.WriteFile(specPath, []byte(fmt.Sprintf(`
+version: %s
+commands:
+  state:
+    stdout: '{"version": 4, "terraform_version": "0.12.6", "resources": []}'
+`, tt.terraformVersion)), 0644))
+		terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin-"+tt.terraformVersion), specPath)
+
+		configPath := filepath.Join(tmpdir, "astro-"+tt.terraformVersion+".yaml")
+		require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+`, codeRoot, tmpdir, terraformPath)), 0644))
+
+		c, err := NewProjectFromConfigFile(configPath)
+		require.NoError(t, err)
+
+		_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+		require.NoError(t, err)
+
+		results := testReadResults(resultChan)
+		require.Contains(t, results, "app")
+		if tt.err == "" {
+			assert.NoError(t, results["app"].Err())
+		} else if assert.Error(t, results["app"].Err()) {
+			assert.Contains(t, results["app"].Err().Error(), tt.err)
+		}
+		if assert.NotNil(t, results["app"].StateTerraformVersion()) {
+			assert.Equal(t, "0.12.6", results["app"].StateTerraformVersion().String())
+		}
+	}
+}
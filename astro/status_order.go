@@ -0,0 +1,65 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+// orderStatus returns channels that deliver the updates and results from
+// status and results, such that every status update an execution sent
+// before its result is received before the result. Updates of different
+// executions can still be interleaved in any order.
+//
+// The ordering holds because executions send their updates and result from
+// a single goroutine: by the time a result is received, the updates sent
+// before it are already buffered in status, and are forwarded first. Both
+// returned channels are unbuffered and must be consumed, ideally from a
+// single goroutine, which then also sees them in order. Both channels are
+// closed once all results have been delivered.
+func orderStatus(status <-chan string, results <-chan *Result) (<-chan string, <-chan *Result) {
+	orderedStatus := make(chan string)
+	orderedResults := make(chan *Result)
+
+	go func() {
+		defer close(orderedStatus)
+		defer close(orderedResults)
+
+		// flush forwards the updates that are already buffered.
+		flush := func() {
+			for {
+				select {
+				case update := <-status:
+					orderedStatus <- update
+				default:
+					return
+				}
+			}
+		}
+
+		for {
+			select {
+			case update := <-status:
+				orderedStatus <- update
+			case result, ok := <-results:
+				flush()
+				if !ok {
+					return
+				}
+				orderedResults <- result
+			}
+		}
+	}()
+
+	return orderedStatus, orderedResults
+}
@@ -0,0 +1,91 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOrderStatus runs many executions that send bursts of status updates
+// before their result, and reads them with a slow consumer, checking that
+// no result is received before the updates of its execution.
+func TestOrderStatus(t *testing.T) {
+	const executions = 50
+	const updatesPerExecution = 20
+
+	// small buffers, like the ones of a session, so that producers block
+	status := make(chan string, 10)
+	results := make(chan *Result, 10)
+
+	go func() {
+		var wg sync.WaitGroup
+		for i := 0; i < executions; i++ {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				for j := 0; j < updatesPerExecution; j++ {
+					status <- fmt.Sprintf("[%s] update %d", id, j)
+				}
+				results <- &Result{id: id}
+			}(fmt.Sprintf("execution-%d", i))
+		}
+		wg.Wait()
+		close(results)
+	}()
+
+	orderedStatus, orderedResults := orderStatus(status, results)
+
+	updates := map[string]int{}
+	resultCount := 0
+	for orderedResults != nil {
+		select {
+		case update, ok := <-orderedStatus:
+			if !ok {
+				orderedStatus = nil
+				continue
+			}
+			id := strings.TrimPrefix(strings.SplitN(update, "]", 2)[0], "[")
+			updates[id]++
+		case result, ok := <-orderedResults:
+			if !ok {
+				orderedResults = nil
+				continue
+			}
+			resultCount++
+			assert.Equal(t, updatesPerExecution, updates[result.ID()], "result of %s received before all of its updates", result.ID())
+		}
+		// slow consumer, so that updates and results pile up
+		if rand.Intn(10) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	assert.Equal(t, executions, resultCount)
+
+	// the status channel is closed along with the results channel
+	if orderedStatus != nil {
+		_, ok := <-orderedStatus
+		assert.False(t, ok)
+	}
+}
@@ -20,33 +20,107 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/terraform"
+
+	version "github.com/burl/go-version"
 )
 
+// terraformVariables returns the variables to pass to Terraform for the
+// execution: its variables and, if the module asks for it, the run ID. The
+// run ID is only passed to modules that declare it, as Terraform rejects
+// undeclared variables.
+func terraformVariables(execution *boundExecution, runID string) map[string]string {
+	if !execution.ModuleConfig().InjectRunID {
+		return execution.Variables()
+	}
+	variables := map[string]string{conf.RunIDVariable: runID}
+	for name, value := range execution.Variables() {
+		variables[name] = value
+	}
+	return variables
+}
+
 // newTerraformSession returns a new Terraform session.
 func (session *Session) newTerraformSession(execution *boundExecution) (*terraform.Session, error) {
 	terraformSessionDir := filepath.Join(session.path, execution.ID())
 
 	moduleConfig := execution.ModuleConfig()
+	basePath, modulePath, sandboxInclude := moduleConfig.SandboxLayout()
+	sandboxExclude, err := moduleConfig.SandboxExclude()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read sandbox exclusions: %v", err)
+	}
+
+	replace, err := session.repo.replacements(execution.ID())
+	if err != nil {
+		return nil, err
+	}
 
 	config := terraform.Config{
 		Name:                moduleConfig.Name,
-		BasePath:            moduleConfig.TerraformCodeRoot,
-		ModulePath:          moduleConfig.Path,
+		BasePath:            basePath,
+		ModulePath:          modulePath,
 		Remote:              moduleConfig.Remote,
-		Variables:           execution.Variables(),
+		SandboxInclude:      sandboxInclude,
+		SandboxExclude:      sandboxExclude,
+		Env:                 moduleConfig.Env,
+		SensitiveEnv:        moduleConfig.SensitiveEnv,
+		Variables:           terraformVariables(execution, session.RunID()),
 		TerraformParameters: execution.TerraformParameters(),
+		Replace:             replace,
+		DirMode:             session.repo.dirMode,
+		FileMode:            session.repo.fileMode,
+		ReadOnly:            session.repo.project.config.ReadOnly,
+		LocalStatePath:      moduleConfig.LocalStatePersistPath(),
+		ExitCodes:           session.repo.project.config.TerraformExitCodes,
+		Logger:              session.log(),
+	}
+
+	secretScanning := session.repo.project.config.SecretScanning
+	names, patterns, err := secretScanning.CompiledPatterns()
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret pattern: %v", err)
+	}
+	for _, name := range names {
+		config.SecretPatterns = append(config.SecretPatterns, terraform.SecretPattern{Name: name, Regexp: patterns[name]})
+	}
+	config.RedactLogs = secretScanning.RedactLogs
+
+	if live := session.repo.project.liveOutput; live != nil {
+		config.LiveOutput = live.forExecution(execution.ID())
+	}
+
+	for _, variable := range moduleConfig.Variables {
+		if variable.Sensitive {
+			config.SensitiveVariables = append(config.SensitiveVariables, variable.Name)
+		}
+		if !variable.IsString() {
+			if config.VariableTypes == nil {
+				config.VariableTypes = map[string]string{}
+			}
+			config.VariableTypes[variable.Name] = variable.Type
+		}
 	}
 
+	// Give Terraform and its providers a temporary directory in the session
+	tmpDir, err := session.tmpDir(execution.ID(), "terraform")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary directory: %v", err)
+	}
+	config.TempDir = tmpDir
+
 	// Fetch the right Terraform version
 	terraformVersion := moduleConfig.Terraform.Version
 
 	if terraformVersion != nil {
-		terraformPath, err := session.repo.project.terraformVersions.Get(terraformVersion.String())
+		flavor := moduleConfig.Terraform.TVMFlavor()
+		terraformPath, err := session.repo.project.terraformBinary(flavor, terraformVersion.String())
 		if err != nil {
-			return nil, fmt.Errorf("unable to activate Terraform %v: %v", terraformVersion.String(), err)
+			return nil, err
 		}
 
 		config.TerraformPath = terraformPath
@@ -58,23 +132,101 @@ func (session *Session) newTerraformSession(execution *boundExecution) (*terrafo
 		config.Remote.Backend = ""
 	}
 
-	// Create a shared plugin directory
-	if terraform.VersionMatches(terraformVersion, ">= 0.10") {
-		if _, exists := os.LookupEnv("TF_PLUGIN_CACHE_DIR"); !exists {
-			pluginDir := filepath.Join(session.repo.path, "plugins")
-			logger.Trace.Printf("astro: creating shared plugin directory: %v", pluginDir)
+	pluginDir, err := session.sharedPluginDir(terraformVersion)
+	if err != nil {
+		return nil, err
+	}
+	config.SharedPluginDir = pluginDir
 
-			if err := os.MkdirAll(pluginDir, 0755); err != nil {
-				return nil, err
-			}
-			config.SharedPluginDir = pluginDir
-		}
+	cliConfigFile, err := session.terraformCLIConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate the Terraform CLI config: %v", err)
 	}
+	config.CLIConfigFile = cliConfigFile
 
 	// If an override path has been specified, use that instead
 	if moduleConfig.Terraform.Path != "" {
 		config.TerraformPath = moduleConfig.Terraform.Path
 	}
 
-	return terraform.NewTerraformSession(execution.ID(), terraformSessionDir, config)
+	config.LockFileReadonly = moduleConfig.Terraform.LockFile == conf.LockFileReadonly
+
+	// Restore and save the result of `terraform init`, if enabled. State
+	// migrations run their own init, so they do not use the cache.
+	if session.repo.project.config.InitCache && moduleConfig.StateMigration == nil {
+		initCacheDir := filepath.Join(session.repo.path, "init-cache")
+		if err := os.MkdirAll(initCacheDir, session.repo.dirMode); err != nil {
+			return nil, err
+		}
+		config.InitCacheDir = initCacheDir
+	}
+
+	// Share a sandbox between the executions of the module, if enabled and
+	// safe
+	if session.repo.project.config.SharedSandbox && !session.sandboxes.disabled {
+		if ok, reason := canShareSandbox(moduleConfig); ok {
+			sandboxDir, err := session.sharedSandbox(moduleConfig.Name, config)
+			if err != nil {
+				return nil, fmt.Errorf("unable to create shared sandbox: %v", err)
+			}
+			config.SharedSandboxDir = sandboxDir
+		} else {
+			session.log().Debugf("astro: %v: not using a shared sandbox: %v", execution.ID(), reason)
+		}
+	}
+
+	terraformSession, err := terraform.NewTerraformSession(execution.ID(), terraformSessionDir, config)
+	if err != nil {
+		return nil, err
+	}
+	execution.logDir = terraformSession.LogDir()
+
+	return terraformSession, nil
+}
+
+// sharedPluginDir creates the plugin directory that sessions of the
+// Terraform version share, in plugin_cache_dir if it is set and in the
+// session repo otherwise, and returns its path. It returns an empty string
+// for versions before 0.10, which don't support a plugin cache, and if
+// TF_PLUGIN_CACHE_DIR is already set.
+func (session *Session) sharedPluginDir(terraformVersion *version.Version) (string, error) {
+	if !terraform.VersionMatches(terraformVersion, ">= 0.10") {
+		return "", nil
+	}
+	if _, exists := os.LookupEnv("TF_PLUGIN_CACHE_DIR"); exists {
+		return "", nil
+	}
+	// TF_PLUGIN_CACHE_DIR would override the one in the CLI config
+	if session.repo.project.config.TerraformCLIConfig.PluginCacheDir != "" {
+		return "", nil
+	}
+
+	pluginDir := session.repo.project.config.PluginCacheDir
+	if pluginDir == "" {
+		pluginDir = filepath.Join(session.repo.path, "plugins")
+	}
+	session.log().Debugf("astro: creating shared plugin directory: %v", pluginDir)
+
+	if err := createPluginCache(pluginDir, session.repo.dirMode); err != nil {
+		return "", err
+	}
+	return pluginDir, nil
+}
+
+// sandboxStatus sends status updates about the sandbox that was cloned for a
+// Terraform session, including any module sources it is missing because of
+// sandbox_include.
+func sandboxStatus(status chan<- string, id string, session *terraform.Session) {
+	if session.SharesSandbox() {
+		status <- fmt.Sprintf("[%s] Using the module's shared sandbox", id)
+	} else {
+		status <- fmt.Sprintf("[%s] Cloned sandbox in %v", id, session.CloneTime().Truncate(time.Millisecond))
+	}
+
+	missing, err := session.ModuleSourcesOutsideSandbox()
+	if err != nil {
+		status <- fmt.Sprintf("[%s] WARNING: unable to check module sources against sandbox_include: %v", id, err)
+	} else if len(missing) > 0 {
+		status <- fmt.Sprintf("[%s] WARNING: module sources not in sandbox_include: %s", id, strings.Join(missing, ", "))
+	}
 }
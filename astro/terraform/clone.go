@@ -0,0 +1,152 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// cloneTree copies the files in existingPath to newPath recursively,
+// using hard links, or copies where files can't be linked, e.g. across file
+// systems. If paths are specified, only those paths (relative to
+// existingPath) are copied, along with their parent directories. Paths that
+// are excluded are left out. Symlinks are copied as symlinks, and modes are
+// preserved.
+func cloneTree(existingPath string, newPath string, excludes sandboxExcludes, paths ...string) error {
+	existingPathDeref, err := filepath.EvalSymlinks(existingPath)
+	if err != nil {
+		return err
+	}
+
+	newPathDeref, err := filepath.EvalSymlinks(newPath)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	// Directories are created writable, so that they can be filled in, and
+	// get their own mode once everything is cloned.
+	dirModes := map[string]os.FileMode{}
+
+	for _, path := range paths {
+		root := filepath.Join(existingPathDeref, path)
+		err := filepath.WalkDir(root, func(src string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(existingPathDeref, src)
+			if err != nil {
+				return err
+			}
+			if rel != "." && excludes.excluded(rel, entry.IsDir()) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			dst := filepath.Join(newPathDeref, rel)
+			if err := mkdirParents(existingPathDeref, newPathDeref, filepath.Dir(rel), dirModes); err != nil {
+				return err
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case entry.IsDir():
+				if rel == "." {
+					return nil
+				}
+				return mkdirForClone(dst, info.Mode(), dirModes)
+			case info.Mode()&os.ModeSymlink != 0:
+				target, err := os.Readlink(src)
+				if err != nil {
+					return err
+				}
+				return os.Symlink(target, dst)
+			default:
+				return linkOrCopy(src, dst)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for dir, mode := range dirModes {
+		if err := os.Chmod(dir, mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mkdirParents creates the directory rel in newPath, and its parents, with
+// the modes of the directories in existingPath, if they don't exist yet.
+func mkdirParents(existingPath, newPath, rel string, dirModes map[string]os.FileMode) error {
+	if rel == "." {
+		return nil
+	}
+	dst := filepath.Join(newPath, rel)
+	if _, err := os.Lstat(dst); err == nil {
+		return nil
+	}
+	if err := mkdirParents(existingPath, newPath, filepath.Dir(rel), dirModes); err != nil {
+		return err
+	}
+	info, err := os.Stat(filepath.Join(existingPath, rel))
+	if err != nil {
+		return err
+	}
+	return mkdirForClone(dst, info.Mode(), dirModes)
+}
+
+// mkdirForClone creates the directory, writable by its owner until
+// cloneTree sets its mode at the end.
+func mkdirForClone(dir string, mode os.FileMode, dirModes map[string]os.FileMode) error {
+	if _, ok := dirModes[dir]; ok {
+		return nil
+	}
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+	dirModes[dir] = mode.Perm()
+	return nil
+}
+
+// linkOrCopy hard links source to target, or copies it if it can't be
+// linked. Dependency lock files are always copied, so that `terraform init`
+// can't change the module's lock file through the sandbox.
+func linkOrCopy(source, target string) error {
+	if filepath.Base(source) == LockFileName {
+		return copyFile(source, target)
+	}
+	if err := os.Link(source, target); err == nil {
+		return nil
+	}
+	return copyFile(source, target)
+}
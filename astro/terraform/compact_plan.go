@@ -0,0 +1,96 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// compactPlanNoiseLines match the lines of plan output that CompactPlan
+// leaves out: refreshes, provider downloads, and the legend and
+// separators around the changes.
+var compactPlanNoiseLines = []*regexp.Regexp{
+	// refreshes and data sources
+	regexp.MustCompile(`^Refreshing Terraform state in-memory prior to plan\.\.\.$`),
+	regexp.MustCompile(`: Refreshing state\.\.\.`),
+	regexp.MustCompile(`: (Reading\.\.\.|Still reading\.\.\.|Read complete after )`),
+	regexp.MustCompile(`^The refreshed state will be used to calculate this plan, but will not be$`),
+	regexp.MustCompile(`^persisted to local or remote state storage\.$`),
+	// provider and module downloads
+	regexp.MustCompile(`^Initializing (the backend|provider plugins|modules)\.\.\.$`),
+	regexp.MustCompile(`^- (Finding|Installing|Installed|Downloading|Reusing previous version|Using previously-installed|Checking for available provider plugins)\b`),
+	regexp.MustCompile(`^Terraform has been successfully initialized!$`),
+	// the legend of the changes
+	regexp.MustCompile(`^An execution plan has been generated and is shown below\.$`),
+	regexp.MustCompile(`^Terraform used the selected providers to generate the following execution$`),
+	regexp.MustCompile(`^plan\. Resource actions are indicated with the following symbols:$`),
+	regexp.MustCompile(`^Resource actions are indicated with the following symbols:$`),
+	regexp.MustCompile(`^(\+|-|~|-/\+|\+/-|<=) (create|destroy|update in-place|destroy and then create replacement|create replacement and then destroy|read \(data resources\))$`),
+	regexp.MustCompile(`^Terraform will perform the following actions:$`),
+	// separators
+	regexp.MustCompile(`^-{72,}$`),
+	regexp.MustCompile(`^─{72,}$`),
+}
+
+// compactPlanNoiseParagraphs match the first line of the paragraphs of plan
+// output that CompactPlan leaves out, up to the next blank line: notes about
+// the plan file and how to apply it.
+var compactPlanNoiseParagraphs = []*regexp.Regexp{
+	regexp.MustCompile(`^Note: You didn't (specify|use) `),
+	regexp.MustCompile(`^This plan was saved to: `),
+	regexp.MustCompile(`^Saved the plan to: `),
+	regexp.MustCompile(`^To perform exactly these actions, run the following command to apply:$`),
+}
+
+// CompactPlan returns the output of a plan, or the changes of a plan, with
+// only the resource action blocks and the summary line: refreshes,
+// provider downloads and boilerplate are left out, as on big modules they
+// bury the changes. Repeated blank lines are collapsed.
+func CompactPlan(output string) string {
+	lines := []string{}
+	skipParagraph := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			skipParagraph = false
+			if len(lines) > 0 && lines[len(lines)-1] != "" {
+				lines = append(lines, "")
+			}
+			continue
+		}
+		if skipParagraph || matchesAny(compactPlanNoiseParagraphs, trimmed) {
+			skipParagraph = true
+			continue
+		}
+		if matchesAny(compactPlanNoiseLines, trimmed) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// matchesAny returns whether any of the regular expressions matches s.
+func matchesAny(regexps []*regexp.Regexp, s string) bool {
+	for _, re := range regexps {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
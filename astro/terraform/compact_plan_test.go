@@ -0,0 +1,75 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactPlan(t *testing.T) {
+	for _, fixture := range []string{"0.12.29.txt", "0.13.7.txt", "0.14.11.txt", "1.0.11.txt", "1.5.7.txt"} {
+		t.Run(fixture, func(t *testing.T) {
+			output, err := ioutil.ReadFile(filepath.Join("fixtures/plan-output", fixture))
+			require.NoError(t, err)
+
+			compact := CompactPlan(string(output))
+			assert.True(t, strings.HasPrefix(strings.TrimSpace(compact), "# null_resource.foo will be created"), compact)
+			assert.Contains(t, compact, `+ resource "null_resource" "foo" {`)
+			assert.Contains(t, compact, "Plan: 1 to add, 0 to change, 0 to destroy.")
+			assert.NotContains(t, compact, "Refreshing")
+			assert.NotContains(t, compact, "symbols")
+			assert.NotContains(t, compact, "-----")
+			assert.NotContains(t, compact, "───")
+			assert.NotContains(t, compact, "test.plan")
+			assert.NotContains(t, compact, "\n\n\n")
+		})
+	}
+}
+
+func TestCompactPlanDropsDownloads(t *testing.T) {
+	output := strings.Join([]string{
+		"Initializing provider plugins...",
+		"- Finding hashicorp/null versions matching \"~> 3.0\"...",
+		"- Installing hashicorp/null v3.2.1...",
+		"- Installed hashicorp/null v3.2.1 (signed by HashiCorp)",
+		"",
+		"data.null_data_source.foo: Reading...",
+		"data.null_data_source.foo: Read complete after 0s [id=static]",
+		"",
+		"  # null_resource.foo will be destroyed",
+		"  - resource \"null_resource\" \"foo\" {",
+		"      - id = \"123\" -> null",
+		"    }",
+		"",
+		"Plan: 0 to add, 0 to change, 1 to destroy.",
+	}, "\n")
+
+	assert.Equal(t, strings.Join([]string{
+		"  # null_resource.foo will be destroyed",
+		"  - resource \"null_resource\" \"foo\" {",
+		"      - id = \"123\" -> null",
+		"    }",
+		"",
+		"Plan: 0 to add, 0 to change, 1 to destroy.",
+	}, "\n"), CompactPlan(output))
+}
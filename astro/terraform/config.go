@@ -18,9 +18,12 @@ package terraform
 
 import (
 	"errors"
+	"io"
+	"os"
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
 )
 
 // Config is the Terraform configuration required to initialize and run
@@ -36,15 +39,117 @@ type Config struct {
 	Remote conf.Remote
 	// Variables is a map of the variable values for execution.
 	Variables map[string]string
+	// VariableTypes is a map of the types of list and map variables. Their
+	// values are JSON encoded, and converted to HCL when passed to
+	// Terraform.
+	VariableTypes map[string]string
+	// SensitiveVariables is a list of names of variables whose values are
+	// masked in logs.
+	SensitiveVariables []string
+	// Env is a map of environment variables to set for Terraform. Astro's
+	// own variables, such as TMPDIR, take precedence.
+	Env map[string]string
+	// SensitiveEnv is a list of names of environment variables in Env whose
+	// values are masked in logs.
+	SensitiveEnv []string
 	// TerraformParameters is a list of additional Terraform command-line parameters
 	TerraformParameters []string
+	// Replace is a list of resource addresses that plan and apply replace,
+	// with -replace=<address>, which requires Terraform 0.15.2 or later.
+	Replace []string
+	// SandboxInclude is a list of paths, relative to the basepath, to clone
+	// into the sandbox. If empty, the whole basepath is cloned.
+	SandboxInclude []string
+	// SandboxExclude is a list of gitignore-style patterns of paths,
+	// relative to the basepath, to leave out of the sandbox.
+	SandboxExclude []string
+	// SharedSandboxDir is the path to a sandbox, cloned with CloneSandbox,
+	// to use instead of cloning one for the session. The session keeps its
+	// Terraform data directory, TF_DATA_DIR, in its own directory, but
+	// Terraform writes local state and the dependency lock file to the
+	// module directory, so it is only safe for modules with a backend, with
+	// Terraform 0.10 or later, and, from 0.14, a .terraform.lock.hcl in the
+	// code. Sessions that detach must have their own sandbox.
+	SharedSandboxDir string
+	// InitCacheDir is a directory to cache the result of `terraform init`
+	// in, so that other sessions with the same Terraform version, module
+	// path, backend configuration and module files restore it instead of
+	// running init again. Only used with Terraform 0.9 and later.
+	InitCacheDir string
+	// LockFileReadonly makes `terraform init` use the provider versions in
+	// the module's .terraform.lock.hcl as they are, with
+	// -lockfile=readonly, and fail if they don't satisfy the module's
+	// requirements. It only applies to Terraform 0.14 and later.
+	LockFileReadonly bool
 
 	// TerraformPath is the path to the Terraform binary
 	TerraformPath string
 
+	// ExitCodes are the exit codes that mean a Terraform subcommand
+	// succeeded, by subcommand, and the exit code of plans with changes.
+	// Unset subcommands use conf.DefaultTerraformExitCodes.
+	ExitCodes conf.TerraformExitCodes
+
 	// SharedPluginDir is the path to a directory that should contain shared
 	// plugins.
 	SharedPluginDir string
+
+	// CLIConfigFile is the path to the Terraform CLI configuration file,
+	// set as TF_CLI_CONFIG_FILE. If empty, Terraform finds its own.
+	CLIConfigFile string
+
+	// DirMode is the mode directories in the session are created with.
+	// Defaults to 0755.
+	DirMode os.FileMode
+	// FileMode is the mode log and plan files in the session are created
+	// with. If 0, log files are created with 0666 and plan files are left
+	// as Terraform writes them.
+	FileMode os.FileMode
+
+	// LocalStatePath is the path the local state of a module without a
+	// backend is persisted to. If set, the state is copied into the sandbox
+	// before plan and apply, and copied back after apply.
+	LocalStatePath string
+
+	// ReadOnly prevents running Terraform commands that can write to remote
+	// state, and disables state locking for plans.
+	ReadOnly bool
+
+	// SavePlanJSON saves plans with changes as JSON as well, next to the
+	// plan file, and fails plans that can't be shown as JSON. Plans are
+	// always read as JSON with Terraform 0.12 or later; this setting is
+	// ignored for earlier versions.
+	SavePlanJSON bool
+
+	// SecretPatterns are the secrets to redact from the output of plans;
+	// see PlanResult.RedactedSecrets. Plans aren't scanned if it is empty.
+	SecretPatterns []SecretPattern
+
+	// RedactLogs redacts the secrets from the logs and JSON plan in the
+	// session too. Otherwise, they keep the original output.
+	RedactLogs bool
+
+	// TempDir is the path to a directory that Terraform should use for
+	// temporary files, set as TMPDIR. If empty, the system default is used.
+	TempDir string
+
+	// LiveOutput receives the output of the long-running Terraform
+	// commands, e.g. plan and apply, a line at a time while they run, with
+	// sensitive values and the matches of SecretPatterns redacted. It must
+	// be safe for concurrent use.
+	LiveOutput io.Writer
+
+	// Logger receives the debug messages and errors of the session, and
+	// is passed to the processes it runs. Defaults to logger.Default().
+	Logger logger.Logger
+}
+
+// log returns the logger of the config.
+func (config *Config) log() logger.Logger {
+	if config.Logger == nil {
+		return logger.Default()
+	}
+	return config.Logger
 }
 
 // Validate validates the Terraform configuration is valid.
@@ -0,0 +1,115 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/exec2"
+)
+
+// crashLogName is the file Terraform writes to its working directory when it
+// panics.
+const crashLogName = "crash.log"
+
+// runTerraform runs a Terraform command. If it fails and Terraform crashed,
+// the crash log it left in the module directory is copied next to the log of
+// the command, <logfileName>.crash.log, and its path and the panic are added
+// to the error, since the output of a crash only says that Terraform
+// crashed.
+func (s *Session) runTerraform(logfileName string, process *exec2.Process) error {
+	// some filesystems only record modification times to the second
+	started := time.Now().Truncate(time.Second)
+
+	err := process.Run()
+	if err == nil {
+		return nil
+	}
+
+	crashLog, panicLine, crashErr := s.collectCrashLog(logfileName, started)
+	if crashErr != nil {
+		s.config.log().Errorf("terraform: unable to collect crash log: %v", crashErr)
+		return err
+	}
+	if crashLog == "" {
+		return err
+	}
+
+	message := fmt.Sprintf("Terraform crashed; crash log: %s", crashLog)
+	if panicLine != "" {
+		message = fmt.Sprintf("Terraform crashed: %s; crash log: %s", panicLine, crashLog)
+	}
+	return fmt.Errorf("%v\n%s", err, message)
+}
+
+// collectCrashLog copies the crash log Terraform wrote to the module
+// directory since started to the log directory, and returns its new path and
+// the first line of the panic in it. The crash log is removed from the
+// module directory, so that later commands in the sandbox don't report it
+// again. It returns an empty path if there is no such crash log.
+func (s *Session) collectCrashLog(logfileName string, started time.Time) (string, string, error) {
+	path := filepath.Join(s.moduleDir, crashLogName)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	} else if err != nil {
+		return "", "", err
+	}
+	// e.g. a crash log of an earlier command, or one in the Terraform code
+	if info.ModTime().Before(started) {
+		return "", "", nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	mode := s.config.FileMode
+	if mode == 0 {
+		mode = 0666
+	}
+	dest := filepath.Join(s.logDir, fmt.Sprintf("%s.%s", logfileName, crashLogName))
+	if err := ioutil.WriteFile(dest, b, mode); err != nil {
+		return "", "", err
+	}
+	if err := os.Remove(path); err != nil {
+		s.config.log().Debugf("terraform: unable to remove %v: %v", path, err)
+	}
+
+	return dest, panicLine(b), nil
+}
+
+// panicLine returns the first line of a crash log that starts with "panic:",
+// or an empty string if there is none.
+func panicLine(crashLog []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(crashLog))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "panic:") {
+			return line
+		}
+	}
+	return ""
+}
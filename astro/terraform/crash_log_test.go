@@ -0,0 +1,68 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrashLog(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-crash-log-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/crash.yaml")
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+	})
+	require.NoError(t, err)
+
+	_, err = session.Get()
+	require.Error(t, err)
+
+	crashLog := filepath.Join(tmpdir, "session", "logs", "get.crash.log")
+	assert.Contains(t, err.Error(), "TERRAFORM CRASH")
+	assert.Contains(t, err.Error(), "Terraform crashed: panic: runtime error: invalid memory address or nil pointer dereference; crash log: "+crashLog)
+
+	b, err := ioutil.ReadFile(crashLog)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "[signal SIGSEGV")
+
+	// the crash log isn't reported again by later commands
+	_, err = os.Stat(filepath.Join(session.moduleDir, crashLogName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPanicLine(t *testing.T) {
+	assert.Equal(t, "panic: boom", panicLine([]byte("some debug output\n  panic: boom\n\ngoroutine 1 [running]:\n")))
+	assert.Equal(t, "", panicLine([]byte("no panic here\n")))
+}
@@ -0,0 +1,96 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severities of diagnostics.
+const (
+	DiagnosticError   = "error"
+	DiagnosticWarning = "warning"
+)
+
+var (
+	// matches the colors in the output of Terraform
+	ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	// matches the first line of a diagnostic, e.g. "Error: Unsupported
+	// argument"
+	diagnosticSummaryRe = regexp.MustCompile(`^(Error|Warning): (.+)$`)
+	// matches the location of a diagnostic, e.g. "  on main.tf line 3, in
+	// resource "null_resource" "foo":"
+	diagnosticLocationRe = regexp.MustCompile(`^\s*on (\S+) line (\d+)`)
+)
+
+// Diagnostic is an error or a warning in the output of Terraform, e.g.
+// "Warning: Deprecated attribute", with the file and line it is about, if
+// Terraform printed them.
+type Diagnostic struct {
+	// Severity is DiagnosticError or DiagnosticWarning.
+	Severity string
+	// Summary is the first line of the diagnostic, without the severity.
+	Summary string
+	// File is the path of the file, relative to the module, or empty if
+	// it isn't known.
+	File string
+	// Line is the line in File, or 0 if it isn't known.
+	Line int
+}
+
+// ParseDiagnostics returns the errors and warnings in the output of
+// Terraform, in order and without duplicates. Terraform 0.12 and later
+// print where diagnostics come from; earlier versions only print the
+// summary, which is parsed on a best-effort basis.
+func ParseDiagnostics(output string) []Diagnostic {
+	lines := strings.Split(ansiEscapeRe.ReplaceAllString(output, ""), "\n")
+	// Terraform 0.15 and later draw a box around each diagnostic
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.TrimLeft(line, "│╷╵ "), " \r")
+	}
+
+	diagnostics := []Diagnostic{}
+	seen := map[Diagnostic]bool{}
+	for i, line := range lines {
+		match := diagnosticSummaryRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		diagnostic := Diagnostic{Severity: strings.ToLower(match[1]), Summary: match[2]}
+
+		// The location, if any, is in the lines that follow, before the
+		// next diagnostic
+		for _, next := range lines[i+1:] {
+			if diagnosticSummaryRe.MatchString(next) {
+				break
+			}
+			if location := diagnosticLocationRe.FindStringSubmatch(next); location != nil {
+				diagnostic.File = location[1]
+				diagnostic.Line, _ = strconv.Atoi(location[2])
+				break
+			}
+		}
+
+		if !seen[diagnostic] {
+			seen[diagnostic] = true
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,87 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	tt := []struct {
+		name     string
+		output   string
+		expected []Diagnostic
+	}{
+		{
+			name: "0.12",
+			output: `
+Warning: Interpolation-only expressions are deprecated
+
+  on main.tf line 2, in resource "null_resource" "foo":
+   2:   triggers = "${var.triggers}"
+
+Error: Unsupported argument
+
+  on modules/app/main.tf line 7, in resource "null_resource" "bar":
+   7:   foo = "bar"
+
+An argument named "foo" is not expected here.
+`,
+			expected: []Diagnostic{
+				{Severity: DiagnosticWarning, Summary: "Interpolation-only expressions are deprecated", File: "main.tf", Line: 2},
+				{Severity: DiagnosticError, Summary: "Unsupported argument", File: "modules/app/main.tf", Line: 7},
+			},
+		},
+		{
+			name: "boxes and colors",
+			output: "\x1b[31m╷\x1b[0m\x1b[0m\n" +
+				"\x1b[31m│\x1b[0m \x1b[0m\x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mReference to undeclared resource\x1b[0m\n" +
+				"\x1b[31m│\x1b[0m \x1b[0m\n" +
+				"\x1b[31m│\x1b[0m \x1b[0m\x1b[0m  on main.tf line 12, in output \"id\":\n" +
+				"\x1b[31m│\x1b[0m \x1b[0m  12:   value = null_resource.missing.id\x1b[0m\n" +
+				"\x1b[31m╵\x1b[0m\x1b[0m\n",
+			expected: []Diagnostic{
+				{Severity: DiagnosticError, Summary: "Reference to undeclared resource", File: "main.tf", Line: 12},
+			},
+		},
+		{
+			name:   "without location",
+			output: "Error: Error parsing /tmp/code/main.tf: At 3:1: expected: IDENT | STRING got: EOF\n",
+			expected: []Diagnostic{
+				{Severity: DiagnosticError, Summary: "Error parsing /tmp/code/main.tf: At 3:1: expected: IDENT | STRING got: EOF"},
+			},
+		},
+		{
+			name:     "duplicates",
+			output:   "Warning: Deprecated\n\n  on main.tf line 1:\n\nWarning: Deprecated\n\n  on main.tf line 1:\n",
+			expected: []Diagnostic{{Severity: DiagnosticWarning, Summary: "Deprecated", File: "main.tf", Line: 1}},
+		},
+		{
+			name:     "none",
+			output:   "No changes. Infrastructure is up-to-date.\n",
+			expected: []Diagnostic{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ParseDiagnostics(tc.output))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandEnv(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-env-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	terraformTmpDir := filepath.Join(tmpdir, "tmp")
+	for _, dir := range []string{codeRoot, terraformTmpDir} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	terraformPath, err := filepath.Abs("fixtures/mock-terraform/env")
+	require.NoError(t, err)
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+		TempDir:       terraformTmpDir,
+		Env: map[string]string{
+			"AWS_PROFILE": "dev",
+			"VAULT_TOKEN": "s3cr3t",
+		},
+		SensitiveEnv: []string{"VAULT_TOKEN"},
+	})
+	require.NoError(t, err)
+
+	process, err := session.terraformCommand([]string{"version"})
+	require.NoError(t, err)
+	require.NoError(t, process.Run())
+
+	env, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-env"))
+	require.NoError(t, err)
+	assert.Contains(t, string(env), "AWS_PROFILE=dev\n")
+	assert.Contains(t, string(env), "VAULT_TOKEN=s3cr3t\n")
+
+	// sensitive values are masked in the log
+	log, err := ioutil.ReadFile(filepath.Join(tmpdir, "session", "logs", "version.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "+ AWS_PROFILE=dev VAULT_TOKEN=<sensitive> "+terraformPath+" [version]\n", string(log))
+}
@@ -0,0 +1,123 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// excludePattern is a gitignore-style pattern of paths to leave out of a
+// sandbox, e.g. "node_modules/", "/docs" or "!docs/README.md".
+type excludePattern struct {
+	// segments are the parts of the pattern between slashes, matched
+	// against those of a path; "**" matches any number of them
+	segments []string
+	// negate is whether a match includes the path again
+	negate bool
+	// dirOnly is whether the pattern only matches directories
+	dirOnly bool
+}
+
+// parseExcludePattern parses a gitignore-style pattern. Like in
+// .gitignore, a pattern without a slash, other than a trailing one, matches
+// names at any depth; others are relative to the base path.
+func parseExcludePattern(pattern string) excludePattern {
+	p := excludePattern{}
+	if strings.HasPrefix(pattern, "!") {
+		p.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		p.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	p.segments = strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	return p
+}
+
+// matches returns whether the pattern matches the path, which is relative
+// to the base path.
+func (p excludePattern) matches(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(p.segments, strings.Split(filepath.ToSlash(rel), "/"))
+}
+
+// matchSegments returns whether the segments of a pattern match those of a
+// path. A trailing "**" matches everything below a directory, but not the
+// directory itself.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return len(path) > 0
+		}
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// sandboxExcludes are the patterns of paths to leave out of a sandbox, in
+// addition to the files Terraform and astro create there.
+type sandboxExcludes []excludePattern
+
+// newSandboxExcludes parses the gitignore-style patterns.
+func newSandboxExcludes(patterns []string) sandboxExcludes {
+	excludes := make(sandboxExcludes, len(patterns))
+	for i, pattern := range patterns {
+		excludes[i] = parseExcludePattern(pattern)
+	}
+	return excludes
+}
+
+// excluded returns whether the path, relative to the base path, is left out
+// of the sandbox. As in .gitignore, the last pattern that matches it wins.
+// Dependency lock files are never excluded.
+func (e sandboxExcludes) excluded(rel string, isDir bool) bool {
+	if !isClonedName(filepath.Base(rel)) {
+		return true
+	}
+	// Without its lock file, Terraform would select providers again
+	if !isDir && filepath.Base(rel) == LockFileName {
+		return false
+	}
+	excluded := false
+	for _, p := range e {
+		if p.matches(rel, isDir) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
@@ -0,0 +1,92 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCodesOverride(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-exit-codes-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	// Mock Terraform behind a wrapper that shifts the exit codes: plans
+	// with changes exit with 3, and applies with 1
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(`
+version: 0.11.14
+commands:
+  plan:
+    exit_code: 3
+  show:
+    stdout: "+ null_resource.foo\n"
+  apply:
+    exit_code: 1
+`), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	newSession := func(t *testing.T, name string, exitCodes conf.TerraformExitCodes) *Session {
+		session, err := NewTerraformSession("app", filepath.Join(tmpdir, name), Config{
+			Name:          "app",
+			BasePath:      codeRoot,
+			ModulePath:    ".",
+			TerraformPath: terraformPath,
+			ExitCodes:     exitCodes,
+		})
+		require.NoError(t, err)
+		return session
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		session := newSession(t, "defaults", conf.TerraformExitCodes{})
+
+		_, err := session.Plan()
+		assert.Error(t, err)
+
+		_, err = session.Apply()
+		assert.Error(t, err)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		session := newSession(t, "override", conf.TerraformExitCodes{
+			Success:     map[string][]int{"apply": {0, 1}},
+			PlanChanges: 3,
+		})
+
+		result, err := session.Plan()
+		require.NoError(t, err)
+		planResult := result.(*PlanResult)
+		assert.True(t, planResult.HasChanges())
+		assert.Contains(t, planResult.Changes(), "+ null_resource.foo")
+
+		_, err = session.Apply()
+		assert.NoError(t, err)
+	})
+}
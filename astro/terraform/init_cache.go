@@ -0,0 +1,175 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/utils"
+
+	version "github.com/burl/go-version"
+)
+
+// terraformDataDir returns the directory where `terraform init` keeps its
+// data: TF_DATA_DIR for sessions with a shared sandbox, or the .terraform
+// directory of the module.
+func (s *Session) terraformDataDir() string {
+	if s.dataDir != "" {
+		return s.dataDir
+	}
+	return filepath.Join(s.moduleDir, ".terraform")
+}
+
+// initCacheKey returns the key of the init cache entry for the session.
+// Everything that `terraform init` depends on is part of the key: the
+// Terraform binary and version, the module path, the backend configuration
+// and the module's Terraform files, so that a change to any of them causes
+// init to run again.
+func (s *Session) initCacheKey(terraformVersion *version.Version) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "terraform=%s\nversion=%s\nmodule=%s\n", s.config.TerraformPath, terraformVersion, s.config.ModulePath)
+	fmt.Fprintf(h, "plugin_dir=%s\nlockfile_readonly=%v\n", s.config.SharedPluginDir, s.config.LockFileReadonly)
+
+	fmt.Fprintf(h, "backend=%s\n", s.config.Remote.Backend)
+	keys := []string{}
+	for key := range s.config.Remote.BackendConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "backend_config.%s=%s\n", key, s.config.Remote.BackendConfig[key])
+	}
+
+	entries, err := ioutil.ReadDir(s.moduleDir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Mode().IsRegular() || !(strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json") || name == LockFileName) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(s.moduleDir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file=%s\n", name)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// restoreInitCache copies the cached result of `terraform init` for the key
+// into the session, and returns whether there was one.
+func (s *Session) restoreInitCache(key string) (bool, error) {
+	cached := filepath.Join(s.config.InitCacheDir, key)
+	if !utils.IsDirectory(cached) {
+		return false, nil
+	}
+
+	s.config.log().Debugf("terraform: restoring init from cache: %v", cached)
+	dataDir := s.terraformDataDir()
+	if err := copyInitData(cached, dataDir); err != nil {
+		os.RemoveAll(dataDir)
+		return false, err
+	}
+	return true, nil
+}
+
+// saveInitCache copies the result of `terraform init` in the session to the
+// cache. It is copied to a temporary directory first, so that other sessions
+// never restore a partial copy.
+func (s *Session) saveInitCache(key string) error {
+	dataDir := s.terraformDataDir()
+	if !utils.IsDirectory(dataDir) {
+		return nil
+	}
+
+	tmpDir, err := ioutil.TempDir(s.config.InitCacheDir, ".tmp-"+key)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	copied := filepath.Join(tmpDir, "data")
+	if err := copyInitData(dataDir, copied); err != nil {
+		return err
+	}
+
+	s.config.log().Debugf("terraform: saving init to cache: %v", filepath.Join(s.config.InitCacheDir, key))
+	if err := os.Rename(copied, filepath.Join(s.config.InitCacheDir, key)); err != nil && !utils.IsDirectory(filepath.Join(s.config.InitCacheDir, key)) {
+		return err
+	}
+	return nil
+}
+
+// copyInitData copies the Terraform data directory source to target. Files
+// at the top of it, such as the backend configuration in terraform.tfstate,
+// are copied, as Terraform rewrites them. Downloaded modules and providers
+// are hard linked where possible.
+func copyInitData(source, target string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(target, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(dst, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dst)
+		case !strings.ContainsRune(rel, filepath.Separator):
+			return copyFile(path, dst)
+		default:
+			return linkOrCopy(path, dst)
+		}
+	})
+}
+
+// InitCached returns whether the session was initialized from the init
+// cache instead of running `terraform init`; see Config.InitCacheDir.
+func (s *Session) InitCached() bool {
+	return s.initCached
+}
@@ -0,0 +1,102 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitCache(t *testing.T) {
+	codeRoot := writeTestTree(t, map[string]string{
+		"app/main.tf": "terraform {\n  backend \"s3\" {}\n}\n",
+	})
+	defer os.RemoveAll(codeRoot)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(`version: 0.12.6
+commands:
+  init:
+    write:
+      "{{.Dir}}/.terraform/init-args": "{{join .Args \" \"}}"
+      "{{.Dir}}/init-ran": ""
+`), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	cacheDir := filepath.Join(tmpdir, "init-cache")
+	require.NoError(t, os.Mkdir(cacheDir, 0755))
+
+	sessions := 0
+	init := func(bucket string) *Session {
+		sessions++
+		session, err := NewTerraformSession("app", filepath.Join(tmpdir, fmt.Sprintf("session-%d", sessions)), Config{
+			Name:          "app",
+			BasePath:      codeRoot,
+			ModulePath:    "app",
+			TerraformPath: terraformPath,
+			Remote: conf.Remote{
+				BackendConfig: map[string]string{"bucket": bucket},
+			},
+			InitCacheDir: cacheDir,
+		})
+		require.NoError(t, err)
+		_, err = session.Init()
+		require.NoError(t, err)
+		return session
+	}
+
+	// The first session runs init and fills the cache
+	first := init("one")
+	assert.False(t, first.InitCached())
+	assert.FileExists(t, filepath.Join(first.moduleDir, "init-ran"))
+
+	// The second one restores it instead
+	second := init("one")
+	assert.True(t, second.InitCached())
+	_, err = os.Stat(filepath.Join(second.moduleDir, "init-ran"))
+	assert.True(t, os.IsNotExist(err))
+	args, err := ioutil.ReadFile(filepath.Join(second.moduleDir, ".terraform", "init-args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-backend-config=bucket=one")
+
+	// A different backend configuration runs init again
+	third := init("two")
+	assert.False(t, third.InitCached())
+	assert.FileExists(t, filepath.Join(third.moduleDir, "init-ran"))
+	args, err = ioutil.ReadFile(filepath.Join(third.moduleDir, ".terraform", "init-args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-backend-config=bucket=two")
+
+	// So does a change to the module's Terraform files
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "app", "main.tf"), []byte("# changed\n"), 0644))
+	fourth := init("one")
+	assert.False(t, fourth.InitCached())
+	assert.FileExists(t, filepath.Join(fourth.moduleDir, "init-ran"))
+}
@@ -28,7 +28,7 @@ func (s *Session) InitLocal() (Result, error) {
 
 	args := []string{"remote", "config", "-disable"}
 
-	process, err := s.terraformCommand(args, []int{0})
+	process, err := s.terraformCommand(args)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,130 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+// matches backend blocks, e.g. `backend "s3" {`
+var terraformBackendBlock = regexp.MustCompile(`\bbackend\s+"[^"]*"\s*\{`)
+
+// localStateFile is the name of the file Terraform keeps local state in.
+const localStateFile = "terraform.tfstate"
+
+// HasBackend returns whether the Terraform files of the module configure a
+// backend. Only the module's own directory is inspected, since a backend can
+// only be configured in the root module.
+func (s *Session) HasBackend() (bool, error) {
+	return HasBackend(s.moduleDir)
+}
+
+// HasBackend returns whether the Terraform files in the module directory
+// configure a backend.
+func HasBackend(moduleDir string) (bool, error) {
+	files, err := filepath.Glob(filepath.Join(moduleDir, "*.tf"))
+	if err != nil {
+		return false, err
+	}
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return false, err
+		}
+		if terraformBackendBlock.Match(b) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// restoreLocalState copies the persisted local state, if any, into the
+// module directory in the sandbox.
+func (s *Session) restoreLocalState() error {
+	if s.config.LocalStatePath == "" || s.localStateRestored {
+		return nil
+	}
+	s.localStateRestored = true
+
+	if !utils.FileExists(s.config.LocalStatePath) {
+		s.config.log().Debugf("terraform: no local state to restore at %v\n", s.config.LocalStatePath)
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(s.config.LocalStatePath)
+	if err != nil {
+		return fmt.Errorf("unable to restore local state: %v", err)
+	}
+
+	// The sandbox is made of hard links to the code root, so remove any
+	// state file that was cloned rather than writing through it.
+	statePath := filepath.Join(s.moduleDir, localStateFile)
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to restore local state: %v", err)
+	}
+
+	s.config.log().Debugf("terraform: restoring local state from %v\n", s.config.LocalStatePath)
+	if err := ioutil.WriteFile(statePath, b, 0600); err != nil {
+		return fmt.Errorf("unable to restore local state: %v", err)
+	}
+
+	return nil
+}
+
+// persistLocalState copies the local state in the module directory to the
+// configured path. The previously persisted state is kept with a .backup
+// suffix.
+func (s *Session) persistLocalState() error {
+	if s.config.LocalStatePath == "" {
+		return nil
+	}
+
+	statePath := filepath.Join(s.moduleDir, localStateFile)
+	if !utils.FileExists(statePath) {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		return fmt.Errorf("unable to persist local state: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.config.LocalStatePath), s.config.DirMode); err != nil {
+		return fmt.Errorf("unable to persist local state: %v", err)
+	}
+
+	if utils.FileExists(s.config.LocalStatePath) {
+		if err := os.Rename(s.config.LocalStatePath, s.config.LocalStatePath+".backup"); err != nil {
+			return fmt.Errorf("unable to back up local state: %v", err)
+		}
+	}
+
+	s.config.log().Debugf("terraform: persisting local state to %v\n", s.config.LocalStatePath)
+	if err := ioutil.WriteFile(s.config.LocalStatePath, b, 0600); err != nil {
+		return fmt.Errorf("unable to persist local state: %v", err)
+	}
+
+	return nil
+}
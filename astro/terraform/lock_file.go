@@ -0,0 +1,46 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LockFileName is the name of the dependency lock file that Terraform 0.14
+// and later write to the module directory, recording the providers that
+// `terraform init` selected.
+const LockFileName = ".terraform.lock.hcl"
+
+// readLockFile returns the contents of the dependency lock file in the
+// module directory of the sandbox, or nil if there isn't one.
+func (s *Session) readLockFile() ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.moduleDir, LockFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// LockFileChanged returns whether `terraform init` has created or changed the
+// dependency lock file in the sandbox, i.e. whether the module's lock file
+// is missing providers or hashes, so that each run may select providers
+// again.
+func (s *Session) LockFileChanged() bool {
+	return s.lockFileChanged
+}
@@ -0,0 +1,81 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFile(t *testing.T) {
+	codeRoot := writeTestTree(t, map[string]string{
+		"app/main.tf":             "",
+		"app/.terraform.lock.hcl": "# old\n",
+	})
+	defer os.RemoveAll(codeRoot)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(`version: 0.14.11
+commands:
+  init:
+    write:
+      "{{.Dir}}/.terraform.lock.hcl": "# new\n"
+      "{{.Dir}}/init-args": "{{join .Args \" \"}}"
+`), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:             "app",
+		BasePath:         codeRoot,
+		ModulePath:       "app",
+		TerraformPath:    terraformPath,
+		SandboxExclude:   []string{"*.hcl"},
+		LockFileReadonly: true,
+	})
+	require.NoError(t, err)
+
+	// The lock file is copied into the sandbox, even if it is excluded
+	sourceInfo, err := os.Stat(filepath.Join(codeRoot, "app", LockFileName))
+	require.NoError(t, err)
+	sandboxInfo, err := os.Stat(filepath.Join(session.moduleDir, LockFileName))
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(sourceInfo, sandboxInfo))
+
+	_, err = session.Init()
+	require.NoError(t, err)
+
+	args, err := ioutil.ReadFile(filepath.Join(session.moduleDir, "init-args"))
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-lockfile=readonly")
+
+	// Changes by init are reported, and don't reach the code
+	assert.True(t, session.LockFileChanged())
+	b, err := ioutil.ReadFile(filepath.Join(codeRoot, "app", LockFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "# old\n", string(b))
+}
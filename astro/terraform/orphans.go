@@ -0,0 +1,139 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Action reasons of `terraform show -json` for resources that are destroyed
+// because their configuration was removed. They were added in Terraform
+// 1.2; for earlier versions, the configuration in the plan is checked.
+const (
+	actionReasonNoResourceConfig = "delete_because_no_resource_config"
+	actionReasonNoModule         = "delete_because_no_module"
+)
+
+// matches the instance keys in a resource address, e.g. `[0]` or `["a"]`
+var resourceAddressKeyRe = regexp.MustCompile(`\[[^\]]*\]`)
+
+// OrphanResource is a resource in the state that a plan destroys because it
+// is no longer in the configuration.
+type OrphanResource struct {
+	// Address is the address of the resource instance, e.g.
+	// `module.app.aws_instance.web[0]`.
+	Address string `json:"address"`
+	// Type is the resource type, e.g. `aws_instance`.
+	Type string `json:"type"`
+	// Reason is why the resource is an orphan: its resource or its module
+	// was removed from the configuration.
+	Reason string `json:"reason"`
+}
+
+// planJSON is the part of the output of `terraform show -json` that is used
+// to find orphan resources and the addresses of changed resources.
+type planJSON struct {
+	ResourceChanges []struct {
+		Address       string `json:"address"`
+		ModuleAddress string `json:"module_address"`
+		Type          string `json:"type"`
+		Change        struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+		ActionReason string `json:"action_reason"`
+	} `json:"resource_changes"`
+	OutputChanges map[string]struct {
+		Actions []string `json:"actions"`
+	} `json:"output_changes"`
+	Configuration struct {
+		RootModule configModuleJSON `json:"root_module"`
+	} `json:"configuration"`
+}
+
+type configModuleJSON struct {
+	Resources []struct {
+		Address string `json:"address"`
+	} `json:"resources"`
+	ModuleCalls map[string]struct {
+		Module configModuleJSON `json:"module"`
+	} `json:"module_calls"`
+}
+
+// addresses adds the addresses of the resources and module calls in the
+// module, prefixed with prefix, to the set.
+func (m configModuleJSON) addresses(prefix string, set map[string]bool) {
+	for _, resource := range m.Resources {
+		set[prefix+resource.Address] = true
+	}
+	for name, call := range m.ModuleCalls {
+		set[prefix+"module."+name] = true
+		call.Module.addresses(prefix+"module."+name+".", set)
+	}
+}
+
+// parseOrphanResources returns the resources that the plan in the output of
+// `terraform show -json` destroys because they are no longer in the
+// configuration, sorted by address. Resources that are destroyed for other
+// reasons, e.g. a lower count, aren't included.
+func parseOrphanResources(in []byte) ([]OrphanResource, error) {
+	var plan planJSON
+	if err := json.Unmarshal(in, &plan); err != nil {
+		return nil, fmt.Errorf("unable to parse JSON plan: %v", err)
+	}
+
+	var configured map[string]bool
+
+	orphans := []OrphanResource{}
+	for _, change := range plan.ResourceChanges {
+		if len(change.Change.Actions) != 1 || change.Change.Actions[0] != "delete" {
+			continue
+		}
+
+		reason := change.ActionReason
+		if reason == "" {
+			// Older versions of Terraform don't give a reason, so look
+			// for the resource in the configuration instead
+			if configured == nil {
+				configured = map[string]bool{}
+				plan.Configuration.RootModule.addresses("", configured)
+			}
+			module := resourceAddressKeyRe.ReplaceAllString(change.ModuleAddress, "")
+			switch {
+			case module != "" && !configured[module]:
+				reason = actionReasonNoModule
+			case !configured[resourceAddressKeyRe.ReplaceAllString(change.Address, "")]:
+				reason = actionReasonNoResourceConfig
+			}
+		}
+
+		switch reason {
+		case actionReasonNoResourceConfig:
+			orphans = append(orphans, OrphanResource{Address: change.Address, Type: change.Type, Reason: "resource removed from configuration"})
+		case actionReasonNoModule:
+			orphans = append(orphans, OrphanResource{Address: change.Address, Type: change.Type, Reason: "module removed from configuration"})
+		}
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Address < orphans[j].Address
+	})
+
+	return orphans, nil
+}
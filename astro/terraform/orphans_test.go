@@ -0,0 +1,123 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+	"github.com/uber/astro/astro/utils"
+)
+
+func TestParseOrphanResources(t *testing.T) {
+	t.Run("action reasons", func(t *testing.T) {
+		orphans, err := parseOrphanResources([]byte(`{
+  "resource_changes": [
+    {"address": "aws_instance.web[1]", "type": "aws_instance", "change": {"actions": ["delete"]}, "action_reason": "delete_because_count_index"},
+    {"address": "aws_instance.old", "type": "aws_instance", "change": {"actions": ["delete"]}, "action_reason": "delete_because_no_resource_config"},
+    {"address": "module.legacy.aws_s3_bucket.logs", "module_address": "module.legacy", "type": "aws_s3_bucket", "change": {"actions": ["delete"]}, "action_reason": "delete_because_no_module"},
+    {"address": "aws_instance.db", "type": "aws_instance", "change": {"actions": ["delete", "create"]}, "action_reason": "replace_because_cannot_update"}
+  ]
+}`))
+		require.NoError(t, err)
+		assert.Equal(t, []OrphanResource{
+			{Address: "aws_instance.old", Type: "aws_instance", Reason: "resource removed from configuration"},
+			{Address: "module.legacy.aws_s3_bucket.logs", Type: "aws_s3_bucket", Reason: "module removed from configuration"},
+		}, orphans)
+	})
+
+	// Terraform before 1.2 doesn't give reasons, so the configuration in
+	// the plan is checked instead
+	t.Run("configuration", func(t *testing.T) {
+		orphans, err := parseOrphanResources([]byte(`{
+  "resource_changes": [
+    {"address": "aws_instance.web[1]", "type": "aws_instance", "change": {"actions": ["delete"]}},
+    {"address": "aws_instance.old", "type": "aws_instance", "change": {"actions": ["delete"]}},
+    {"address": "module.app[\"a\"].aws_instance.web", "module_address": "module.app[\"a\"]", "type": "aws_instance", "change": {"actions": ["delete"]}},
+    {"address": "module.app[\"a\"].aws_eip.web", "module_address": "module.app[\"a\"]", "type": "aws_eip", "change": {"actions": ["delete"]}},
+    {"address": "module.legacy.aws_s3_bucket.logs", "module_address": "module.legacy", "type": "aws_s3_bucket", "change": {"actions": ["delete"]}}
+  ],
+  "configuration": {
+    "root_module": {
+      "resources": [{"address": "aws_instance.web"}],
+      "module_calls": {
+        "app": {"module": {"resources": [{"address": "aws_instance.web"}]}}
+      }
+    }
+  }
+}`))
+		require.NoError(t, err)
+		assert.Equal(t, []OrphanResource{
+			{Address: "aws_instance.old", Type: "aws_instance", Reason: "resource removed from configuration"},
+			{Address: `module.app["a"].aws_eip.web`, Type: "aws_eip", Reason: "resource removed from configuration"},
+			{Address: "module.legacy.aws_s3_bucket.logs", Type: "aws_s3_bucket", Reason: "module removed from configuration"},
+		}, orphans)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := parseOrphanResources([]byte("Error: plan file is invalid"))
+		assert.Error(t, err)
+	})
+}
+
+func TestPlanSavePlanJSON(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-plan-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/orphans.yaml")
+
+	config := Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+	}
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), config)
+	require.NoError(t, err)
+	result, err := session.Plan()
+	require.NoError(t, err)
+
+	// Plans are read as JSON either way, but only saved if requested
+	orphans, err := result.(*PlanResult).OrphanResources()
+	require.NoError(t, err)
+	assert.Len(t, orphans, 1)
+	assert.False(t, utils.FileExists(filepath.Join(tmpdir, "session", "sandbox", "app.plan.json")))
+
+	config.SavePlanJSON = true
+	session, err = NewTerraformSession("app", filepath.Join(tmpdir, "session-json"), config)
+	require.NoError(t, err)
+	result, err = session.Plan()
+	require.NoError(t, err)
+
+	orphans, err = result.(*PlanResult).OrphanResources()
+	require.NoError(t, err)
+	assert.Equal(t, []OrphanResource{
+		{Address: "aws_instance.old", Type: "aws_instance", Reason: "resource removed from configuration"},
+	}, orphans)
+	assert.True(t, utils.FileExists(filepath.Join(tmpdir, "session-json", "sandbox", "app.plan.json")))
+}
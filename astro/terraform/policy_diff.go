@@ -31,52 +31,71 @@ import (
 )
 
 var (
-	// Full path to differ will be stored here on init
+	// Full path to a differ to use instead of the built-in diff, from
+	// $ASTRO_POLICY_DIFFER, will be stored here on init
 	differPath string
-	// $PATH will be searched for these tools on init
-	differTools = []string{
-		"colordiff",
-		"diff",
-	}
-	newline = []byte("\n")
+	newline    = []byte("\n")
 	// regular expressions that matches a policy add/change in a Terraform diff.
 	terraformPolicyAddLine    = regexp.MustCompile(`\s*policy:\s+"(.*)"`)
 	terraformPolicyChangeLine = regexp.MustCompile(`\s*policy:\s+"(.*)" => "(.*)"`)
 )
 
 func init() {
-	differPath, _ = which(differTools)
+	if differ := os.Getenv("ASTRO_POLICY_DIFFER"); differ != "" {
+		differPath, _ = which([]string{differ})
+	}
 }
 
 // terraformPolicyChangeToDiff takes a Terraform policy change output line
-// (i.e. from a Terraform plan) parses the JSON and outputs a unified diff.
-func terraformPolicyChangeToDiff(differ, policyBefore, policyAfter string) ([]byte, error) {
+// (i.e. from a Terraform plan) parses the JSON and outputs a diff in the
+// format, without the file header. If differ is empty, unified and HTML
+// diffs are made in-process, and unified diffs are colored if colors is set.
+func terraformPolicyChangeToDiff(differ string, format PolicyDiffFormat, policyBefore, policyAfter string, colors bool) ([]byte, error) {
 	jsonBefore, err := jsonPretty(unescape(policyBefore))
 	if err != nil {
 		return nil, err
 	}
-	before, err := writeToTempFile(jsonBefore)
+	jsonAfter, err := jsonPretty(unescape(policyAfter))
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(before)
+	if format == PolicyDiffHTML {
+		return htmlDiff(tail(unifiedDiff(jsonBefore, jsonAfter, false), 2, true)), nil
+	}
+	if format == PolicyDiffSideBySide {
+		differ = sideBySideDiffer(differ)
+	}
+	if differ == "" {
+		return tail(unifiedDiff(jsonBefore, jsonAfter, colors), 2, true), nil
+	}
 
-	jsonAfter, err := jsonPretty(unescape(policyAfter))
+	before, err := writeToTempFile(jsonBefore)
 	if err != nil {
 		return nil, err
 	}
+	defer os.Remove(before)
+
 	after, err := writeToTempFile(jsonAfter)
 	if err != nil {
 		return nil, err
 	}
 	defer os.Remove(after)
 
-	return diff(differ, before, after)
+	if format == PolicyDiffSideBySide {
+		return runDiffer(differ, "-y", before, after)
+	}
+
+	out, err := runDiffer(differ, "-u", before, after)
+	if err != nil {
+		return nil, err
+	}
+	return tail(out, 2, true), nil
 }
 
-// diff invokes diff to output a diff of two files.
-func diff(differ, file1, file2 string) ([]byte, error) {
-	cmd := exec.Command(differ, "-u", file1, file2)
+// runDiffer invokes the differ with the arguments to output a diff of two
+// files.
+func runDiffer(differ string, args ...string) ([]byte, error) {
+	cmd := exec.Command(differ, args...)
 	out, err := cmd.Output()
 
 	// We only want to throw an error here if the exit status was 2 or
@@ -112,12 +131,18 @@ func jsonPretty(in []byte) ([]byte, error) {
 }
 
 // CanDisplayReadableTerraformPolicyChanges is true when the prerequisites for
-// ReadableTerraformPolicyChanges are fulfilled
-func CanDisplayReadableTerraformPolicyChanges() bool {
-	return differPath != ""
+// ReadableTerraformPolicyChanges in the format are fulfilled. Unified and
+// HTML diffs are made in-process, unless $ASTRO_POLICY_DIFFER names a
+// program, so they always are. Side-by-side diffs need
+// $ASTRO_POLICY_DIFFER, or else diff, to support -y.
+func CanDisplayReadableTerraformPolicyChanges(format PolicyDiffFormat) bool {
+	if format == PolicyDiffSideBySide {
+		return sideBySideDiffer(differPath) != ""
+	}
+	return true
 }
 
-func readableTerraformPolicyChangesWithDiffer(differ, terraformChanges string) (string, error) {
+func readableTerraformPolicyChangesWithDiffer(differ string, format PolicyDiffFormat, terraformChanges string, colors bool) (string, error) {
 	result := ""
 	var errs error
 	for _, line := range strings.Split(terraformChanges, "\n") {
@@ -135,9 +160,9 @@ func readableTerraformPolicyChangesWithDiffer(differ, terraformChanges string) (
 		var difftext []byte
 		var err error
 		if changeGroups != nil {
-			difftext, err = terraformPolicyChangeToDiff(differ, changeGroups[1], changeGroups[2])
+			difftext, err = terraformPolicyChangeToDiff(differ, format, changeGroups[1], changeGroups[2], colors)
 		} else {
-			difftext, err = terraformPolicyChangeToDiff(differ, "", addGroups[1])
+			difftext, err = terraformPolicyChangeToDiff(differ, format, "", addGroups[1], colors)
 		}
 		if err != nil {
 			errs = multierror.Append(errs, err)
@@ -148,7 +173,7 @@ func readableTerraformPolicyChangesWithDiffer(differ, terraformChanges string) (
 
 		// Output a readable diff
 		result += "\n"
-		result += string(tail(difftext, 2, true))
+		result += string(difftext)
 		result += "\n"
 	}
 
@@ -156,9 +181,20 @@ func readableTerraformPolicyChangesWithDiffer(differ, terraformChanges string) (
 }
 
 // ReadableTerraformPolicyChanges takes the output of `terraform plan` and
-// rewrites policy diff to be in unified diff format
-func ReadableTerraformPolicyChanges(terraformChanges string) (string, error) {
-	return readableTerraformPolicyChangesWithDiffer(differPath, terraformChanges)
+// rewrites policy diff to be in the format. Unified diffs are made
+// in-process, and colored if colors is set; set $ASTRO_POLICY_DIFFER to a
+// program like colordiff to use it instead, which only happens with colors,
+// as such programs color their output themselves. Side-by-side diffs fall
+// back to unified if they can't be displayed.
+func ReadableTerraformPolicyChanges(terraformChanges string, format PolicyDiffFormat, colors bool) (string, error) {
+	if !CanDisplayReadableTerraformPolicyChanges(format) {
+		format = PolicyDiffUnified
+	}
+	differ := differPath
+	if format == PolicyDiffUnified && !colors {
+		differ = ""
+	}
+	return readableTerraformPolicyChangesWithDiffer(differ, format, terraformChanges, colors)
 }
 
 // tail is an implementation of the unix tail command. If fromN is true, it is
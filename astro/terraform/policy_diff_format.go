@@ -0,0 +1,127 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"sync"
+)
+
+// PolicyDiffFormat is the format of the readable policy diffs in plans.
+type PolicyDiffFormat string
+
+const (
+	// PolicyDiffUnified is a unified diff, like `diff -u`. It is the
+	// default.
+	PolicyDiffUnified PolicyDiffFormat = "unified"
+	// PolicyDiffSideBySide shows the policies next to each other, like
+	// `diff -y`. It needs a differ that supports -y; see
+	// CanDisplayReadableTerraformPolicyChanges.
+	PolicyDiffSideBySide PolicyDiffFormat = "side-by-side"
+	// PolicyDiffHTML is a unified diff rendered as an HTML details block,
+	// for Markdown such as PR comments.
+	PolicyDiffHTML PolicyDiffFormat = "html"
+)
+
+// PolicyDiffFormats are the supported policy diff formats.
+var PolicyDiffFormats = []PolicyDiffFormat{PolicyDiffUnified, PolicyDiffSideBySide, PolicyDiffHTML}
+
+// ParsePolicyDiffFormat returns the policy diff format with the name. An
+// empty name is the default, unified.
+func ParsePolicyDiffFormat(name string) (PolicyDiffFormat, error) {
+	if name == "" {
+		return PolicyDiffUnified, nil
+	}
+	names := []string{}
+	for _, format := range PolicyDiffFormats {
+		if string(format) == name {
+			return format, nil
+		}
+		names = append(names, string(format))
+	}
+	return "", fmt.Errorf("unknown policy diff format: %q; must be one of: %s", name, strings.Join(names, ", "))
+}
+
+var (
+	// sideBySideSupport caches whether each differ supports -y
+	sideBySideSupport   = map[string]bool{}
+	sideBySideSupportMu sync.Mutex
+)
+
+// sideBySideDiffer returns the differ to make side-by-side diffs with:
+// differ, or else diff from $PATH. It returns an empty string if neither
+// supports -y.
+func sideBySideDiffer(differ string) string {
+	if differ == "" {
+		differ, _ = which([]string{"diff"})
+		if differ == "" {
+			return ""
+		}
+	}
+
+	sideBySideSupportMu.Lock()
+	defer sideBySideSupportMu.Unlock()
+
+	supported, ok := sideBySideSupport[differ]
+	if !ok {
+		supported = differSupportsSideBySide(differ)
+		sideBySideSupport[differ] = supported
+	}
+	if !supported {
+		return ""
+	}
+	return differ
+}
+
+// differSupportsSideBySide returns whether the differ accepts -y, by
+// diffing two empty files with it.
+func differSupportsSideBySide(differ string) bool {
+	empty, err := writeToTempFile(nil)
+	if err != nil {
+		return false
+	}
+	defer os.Remove(empty)
+
+	_, err = runDiffer(differ, "-y", empty, empty)
+	return err == nil
+}
+
+// htmlDiff renders a unified diff, without its header, as an HTML details
+// block. Removed and added lines are wrapped in <del> and <ins>.
+func htmlDiff(unified []byte) []byte {
+	var out bytes.Buffer
+	out.WriteString("<details>\n<summary>Policy diff</summary>\n\n<pre>\n")
+	for _, line := range splitDiffLines(unified) {
+		escaped := html.EscapeString(line)
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			fmt.Fprintf(&out, "<b>%s</b>\n", escaped)
+		case strings.HasPrefix(line, "-"):
+			fmt.Fprintf(&out, "<del>%s</del>\n", escaped)
+		case strings.HasPrefix(line, "+"):
+			fmt.Fprintf(&out, "<ins>%s</ins>\n", escaped)
+		default:
+			fmt.Fprintln(&out, escaped)
+		}
+	}
+	out.WriteString("</pre>\n</details>\n")
+	return out.Bytes()
+}
@@ -0,0 +1,112 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicyDiffFormat(t *testing.T) {
+	for name, expected := range map[string]PolicyDiffFormat{
+		"":             PolicyDiffUnified,
+		"unified":      PolicyDiffUnified,
+		"side-by-side": PolicyDiffSideBySide,
+		"html":         PolicyDiffHTML,
+	} {
+		format, err := ParsePolicyDiffFormat(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, format)
+	}
+
+	_, err := ParsePolicyDiffFormat("context")
+	assert.EqualError(t, err, `unknown policy diff format: "context"; must be one of: unified, side-by-side, html`)
+}
+
+func TestRewriteOutputHTML(t *testing.T) {
+	diffedPolicy, err := readableTerraformPolicyChangesWithDiffer("", PolicyDiffHTML, testPolicyChangePlan, false)
+	require.NoError(t, err)
+	assert.Contains(t, diffedPolicy, `~ module.policies.aws_iam_policy.billing
+
+<details>
+<summary>Policy diff</summary>
+
+<pre>
+<b>@@ -2,14 +2,13 @@</b>
+   &#34;Version&#34;: &#34;2012-10-17&#34;,
+   &#34;Statement&#34;: [
+     {
+<ins>+      &#34;Sid&#34;: &#34;&#34;,</ins>
+       &#34;Effect&#34;: &#34;Allow&#34;,
+       &#34;Action&#34;: [
+         &#34;budgets:*&#34;,
+         &#34;aws-portal:View*&#34;
+       ],
+<del>-      &#34;Resource&#34;: [</del>
+<del>-        &#34;*&#34;</del>
+<del>-      ]</del>
+<ins>+      &#34;Resource&#34;: &#34;*&#34;</ins>
+     }
+   ]
+ }
+</pre>
+</details>
+`)
+
+	diffedPolicy, err = readableTerraformPolicyChangesWithDiffer("", PolicyDiffHTML, testPolicyAddPlan, false)
+	require.NoError(t, err)
+	assert.Contains(t, diffedPolicy, "<b>@@ -0,0 +1,14 @@</b>\n<ins>+{</ins>\n")
+	assert.NotContains(t, diffedPolicy, "policy: ")
+}
+
+func TestRewriteOutputSideBySide(t *testing.T) {
+	if sideBySideDiffer(testDifferPath) == "" {
+		t.Skip("no diff that supports -y")
+	}
+
+	for _, inputText := range []string{testPolicyChangePlan, testPolicyAddPlan} {
+		diffedPolicy, err := readableTerraformPolicyChangesWithDiffer(testDifferPath, PolicyDiffSideBySide, inputText, false)
+		require.NoError(t, err)
+		assert.NotContains(t, diffedPolicy, "policy: ")
+		assert.NotContains(t, diffedPolicy, "@@")
+
+		// The added Sid is on the right, marked with >
+		found := false
+		for _, line := range strings.Split(diffedPolicy, "\n") {
+			if strings.Contains(line, ">") && strings.Contains(line, `"Sid": ""`) {
+				found = true
+			}
+		}
+		assert.True(t, found, diffedPolicy)
+	}
+}
+
+func TestRewriteOutputSideBySideFallback(t *testing.T) {
+	// sh doesn't support -y, so the diff falls back to unified
+	sh, err := which([]string{"sh"})
+	if err != nil {
+		t.Skip(err)
+	}
+	assert.Empty(t, sideBySideDiffer(sh))
+
+	diffedPolicy, err := readableTerraformPolicyChangesWithDiffer(sh, PolicyDiffSideBySide, testPolicyChangePlan, false)
+	require.NoError(t, err)
+	assert.Contains(t, diffedPolicy, "@@ -2,14 +2,13 @@\n")
+}
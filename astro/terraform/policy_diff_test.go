@@ -25,18 +25,23 @@ import (
 var (
 	// Full path to differ for tests will be stored here on init
 	testDifferPath string
-)
 
-func init() {
-	testDifferPath, _ = which([]string{"diff"})
-}
+	// Plans that change and add a policy
+	testPolicyChangePlan = `
+module.policies.data.aws_iam_policy_document.billing: Refreshing state...
 
-func TestRewriteOutputChange(t *testing.T) {
-	if testDifferPath == "" {
-		t.Skip("skipping test since there is no diff program")
-	}
+Your plan was also saved to the path below. Call the "apply" subcommand
+with this plan file and Terraform will exactly execute this execution
+plan.
 
-	inputText := `
+Path: mgmt.plan
+
+~ module.policies.aws_iam_policy.billing
+policy: "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": [\n    {\n      \"Effect\": \"Allow\",\n      \"Action\": [\n        \"budgets:*\",\n        \"aws-portal:View*\"\n      ],\n      \"Resource\": [\n        \"*\"\n      ]\n    }\n  ]\n}" => "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": [\n    {\n      \"Sid\": \"\",\n      \"Effect\": \"Allow\",\n      \"Action\": [\n        \"budgets:*\",\n        \"aws-portal:View*\"\n      ],\n      \"Resource\": \"*\"\n    }\n  ]\n}"
+
+Plan: 0 to add, 1 to change, 0 to destroy.
+`
+	testPolicyAddPlan = `
 module.policies.data.aws_iam_policy_document.billing: Refreshing state...
 
 Your plan was also saved to the path below. Call the "apply" subcommand
@@ -46,10 +51,28 @@ plan.
 Path: mgmt.plan
 
 ~ module.policies.aws_iam_policy.billing
-policy: "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": [\n    {\n      \"Effect\": \"Allow\",\n      \"Action\": [\n        \"budgets:*\",\n        \"aws-portal:View*\"\n      ],\n      \"Resource\": [\n        \"*\"\n      ]\n    }\n  ]\n}" => "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": [\n    {\n      \"Sid\": \"\",\n      \"Effect\": \"Allow\",\n      \"Action\": [\n        \"budgets:*\",\n        \"aws-portal:View*\"\n      ],\n      \"Resource\": \"*\"\n    }\n  ]\n}"
+policy: "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": [\n    {\n      \"Sid\": \"\",\n      \"Effect\": \"Allow\",\n      \"Action\": [\n        \"budgets:*\",\n        \"aws-portal:View*\"\n      ],\n      \"Resource\": \"*\"\n    }\n  ]\n}"
 
 Plan: 0 to add, 1 to change, 0 to destroy.
 `
+)
+
+func init() {
+	testDifferPath, _ = which([]string{"diff"})
+}
+
+// testDiffers returns the differs to test with: the built-in diff, and the
+// diff program if there is one.
+func testDiffers() []string {
+	differs := []string{""}
+	if testDifferPath != "" {
+		differs = append(differs, testDifferPath)
+	}
+	return differs
+}
+
+func TestRewriteOutputChange(t *testing.T) {
+	inputText := testPolicyChangePlan
 	expectedOutput := `
 module.policies.data.aws_iam_policy_document.billing: Refreshing state...
 
@@ -83,31 +106,16 @@ Path: mgmt.plan
 Plan: 0 to add, 1 to change, 0 to destroy.
 `
 
-	diffedPolicy, err := readableTerraformPolicyChangesWithDiffer(testDifferPath, inputText)
+	for _, differ := range testDiffers() {
+		diffedPolicy, err := readableTerraformPolicyChangesWithDiffer(differ, PolicyDiffUnified, inputText, false)
 
-	assert.NoError(t, err)
-	assert.Equal(t, strings.TrimSpace(expectedOutput), strings.TrimSpace(diffedPolicy))
+		assert.NoError(t, err)
+		assert.Equal(t, strings.TrimSpace(expectedOutput), strings.TrimSpace(diffedPolicy), "differ: %q", differ)
+	}
 }
 
 func TestRewriteOutputAdd(t *testing.T) {
-	if testDifferPath == "" {
-		t.Skip("skipping test since there is no diff program")
-	}
-
-	inputText := `
-module.policies.data.aws_iam_policy_document.billing: Refreshing state...
-
-Your plan was also saved to the path below. Call the "apply" subcommand
-with this plan file and Terraform will exactly execute this execution
-plan.
-
-Path: mgmt.plan
-
-~ module.policies.aws_iam_policy.billing
-policy: "{\n  \"Version\": \"2012-10-17\",\n  \"Statement\": [\n    {\n      \"Sid\": \"\",\n      \"Effect\": \"Allow\",\n      \"Action\": [\n        \"budgets:*\",\n        \"aws-portal:View*\"\n      ],\n      \"Resource\": \"*\"\n    }\n  ]\n}"
-
-Plan: 0 to add, 1 to change, 0 to destroy.
-`
+	inputText := testPolicyAddPlan
 	expectedOutput := `
 module.policies.data.aws_iam_policy_document.billing: Refreshing state...
 
@@ -139,8 +147,65 @@ Path: mgmt.plan
 Plan: 0 to add, 1 to change, 0 to destroy.
 `
 
-	diffedPolicy, err := readableTerraformPolicyChangesWithDiffer(testDifferPath, inputText)
+	for _, differ := range testDiffers() {
+		diffedPolicy, err := readableTerraformPolicyChangesWithDiffer(differ, PolicyDiffUnified, inputText, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, strings.TrimSpace(expectedOutput), strings.TrimSpace(diffedPolicy), "differ: %q", differ)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n")
+	after := []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n")
+
+	assert.Equal(t, `--- before
++++ after
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,4 +10,3 @@
+ j
+ k
+ l
+-m
+`, string(unifiedDiff(before, after, false)))
+
+	// Changes with overlapping context are in one hunk
+	after = []byte("a\nB\nc\nd\ne\nf\ng\nH\ni\nj\nk\nl\nm\n")
+	assert.Equal(t, `--- before
++++ after
+@@ -1,11 +1,11 @@
+ a
+-b
++B
+ c
+ d
+ e
+ f
+ g
+-h
++H
+ i
+ j
+ k
+`, string(unifiedDiff(before, after, false)))
+
+	// Identical inputs have no hunks
+	assert.Equal(t, "--- before\n+++ after\n", string(unifiedDiff(before, before, false)))
+}
+
+func TestReadableTerraformPolicyChangesColors(t *testing.T) {
+	colored, err := ReadableTerraformPolicyChanges(testPolicyChangePlan, PolicyDiffUnified, true)
+	assert.NoError(t, err)
+	assert.Contains(t, colored, "\x1b[")
 
+	plain, err := ReadableTerraformPolicyChanges(testPolicyChangePlan, PolicyDiffUnified, false)
 	assert.NoError(t, err)
-	assert.Equal(t, strings.TrimSpace(expectedOutput), strings.TrimSpace(diffedPolicy))
+	assert.NotContains(t, plain, "\x1b[")
+	assert.Contains(t, plain, `+      "Resource": "*"`)
 }
@@ -0,0 +1,59 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsMutatingCommand returns whether the Terraform command with the specified
+// arguments can write to remote state.
+func IsMutatingCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "apply", "destroy", "force-unlock", "import", "push", "refresh", "taint", "untaint":
+		return true
+	case "state":
+		if len(args) < 2 {
+			return false
+		}
+		switch args[1] {
+		case "mv", "push", "replace-provider", "rm":
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkReadOnly returns an error if the session is read-only and the
+// Terraform command with the specified arguments can write to remote state.
+func (s *Session) checkReadOnly(args []string) error {
+	if !s.config.ReadOnly || !IsMutatingCommand(args) {
+		return nil
+	}
+
+	command := args[0]
+	if command == "state" {
+		command = strings.Join(args[:2], " ")
+	}
+	return fmt.Errorf("refusing to run terraform %s in read-only mode", command)
+}
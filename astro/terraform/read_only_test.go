@@ -0,0 +1,109 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestIsMutatingCommand(t *testing.T) {
+	tt := []struct {
+		args     []string
+		mutating bool
+	}{
+		{nil, false},
+		{[]string{"init", "-input=false"}, false},
+		{[]string{"plan", "-detailed-exitcode"}, false},
+		{[]string{"apply", "app.plan"}, true},
+		{[]string{"destroy"}, true},
+		{[]string{"import", "aws_instance.foo", "i-123"}, true},
+		{[]string{"refresh"}, true},
+		{[]string{"state"}, false},
+		{[]string{"state", "pull"}, false},
+		{[]string{"state", "mv", "a", "b"}, true},
+		{[]string{"state", "push", "-force", "foo.tfstate"}, true},
+	}
+
+	for _, test := range tt {
+		assert.Equal(t, test.mutating, IsMutatingCommand(test.args), "%v", test.args)
+	}
+}
+
+func newReadOnlyTestSession(t *testing.T, tmpdir string) *Session {
+	codeRoot := filepath.Join(tmpdir, "code")
+	terraformTmpDir := filepath.Join(tmpdir, "tmp")
+	for _, dir := range []string{codeRoot, terraformTmpDir} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/args.yaml")
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+		TempDir:       terraformTmpDir,
+		ReadOnly:      true,
+	})
+	require.NoError(t, err)
+
+	return session
+}
+
+func TestReadOnlyPlanDisablesLocking(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-read-only-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	session := newReadOnlyTestSession(t, tmpdir)
+
+	_, err = session.Plan()
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadFile(filepath.Join(tmpdir, "tmp", "terraform-plan"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "-lock=false")
+}
+
+func TestReadOnlyRefusesMutatingCommands(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-read-only-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	session := newReadOnlyTestSession(t, tmpdir)
+
+	_, err = session.Apply()
+	assert.EqualError(t, err, "refusing to run terraform apply in read-only mode")
+
+	_, err = session.Detach()
+	assert.EqualError(t, err, "refusing to detach remote state in read-only mode")
+
+	_, _, err = session.MigrateState(map[string]string{"path": "old.tfstate"})
+	assert.EqualError(t, err, "refusing to migrate state in read-only mode")
+
+	_, err = os.Stat(filepath.Join(tmpdir, "tmp", "terraform-apply"))
+	assert.True(t, os.IsNotExist(err))
+}
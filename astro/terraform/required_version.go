@@ -0,0 +1,114 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+// matches the required_version setting of a terraform block, e.g.
+// `terraform { required_version = ">= 0.12" }`, skipping over blocks nested
+// up to two levels deep, such as backend or required_providers. It is used
+// for HCL2 code, which can't be parsed with HCL1.
+var terraformRequiredVersionRe = regexp.MustCompile(
+	// match `terraform {`, but not `some_terraform {`
+	`(?:^|[\s}])terraform\s*\{` +
+		// match other settings and nested blocks before required_version
+		`(?:[^{}]|\{(?:[^{}]|\{[^{}]*\})*\})*?` +
+		// match `required_version = "..."`, but not `some_required_version`
+		`\brequired_version\s*=\s*"([^"]*)"`,
+)
+
+// requiredVersionWithHCL1 returns the required_version constraints of the
+// terraform blocks in the config.
+func requiredVersionWithHCL1(in []byte) ([]string, error) {
+	config, err := parseTerraformConfigWithHCL1(in)
+	if err != nil {
+		return nil, err
+	}
+
+	constraints := []string{}
+	for _, item := range config.Filter("terraform").Items {
+		terraformConfigBlock, ok := item.Val.(*ast.ObjectType)
+		if !ok {
+			continue
+		}
+		literal, ok := astGet(terraformConfigBlock.List, "required_version").(*ast.LiteralType)
+		if !ok || literal.Token.Type != token.STRING {
+			continue
+		}
+		constraint, err := strconv.Unquote(literal.Token.Text)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	return constraints, nil
+}
+
+// requiredVersionWithHCL2 returns the required_version constraints of the
+// terraform blocks in the config. Like deleteTerraformBackendConfigWithHCL2,
+// it works on the text, as hcl2 doesn't provide a way to walk the AST.
+func requiredVersionWithHCL2(in []byte) []string {
+	constraints := []string{}
+	for _, match := range terraformRequiredVersionRe.FindAllSubmatch(in, -1) {
+		constraints = append(constraints, string(match[1]))
+	}
+	return constraints
+}
+
+// RequiredVersion returns the Terraform version constraint declared with
+// required_version in the .tf files of the module, e.g. ">= 0.11.7, < 0.12",
+// or an empty string if there is none. Constraints from several terraform
+// blocks are combined, as Terraform requires all of them to be met. Files
+// that HCL1 can't parse are assumed to be HCL2.
+func RequiredVersion(moduleDir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(moduleDir, "*.tf"))
+	if err != nil {
+		return "", err
+	}
+
+	constraints := []string{}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		fileConstraints, err := requiredVersionWithHCL1(b)
+		if err != nil {
+			fileConstraints = requiredVersionWithHCL2(b)
+		}
+		for _, constraint := range fileConstraints {
+			if strings.TrimSpace(constraint) == "" {
+				return "", fmt.Errorf("%v: required_version is empty", file)
+			}
+			constraints = append(constraints, constraint)
+		}
+	}
+
+	return strings.Join(constraints, ", "), nil
+}
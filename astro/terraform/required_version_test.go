@@ -0,0 +1,93 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredVersionWithHCL1(t *testing.T) {
+	constraints, err := requiredVersionWithHCL1([]byte(`
+terraform {
+  backend "s3" {}
+  required_version = "~> 0.11.7"
+}
+
+provider "aws" {
+  region = "${var.aws_region}"
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"~> 0.11.7"}, constraints)
+
+	constraints, err = requiredVersionWithHCL1([]byte(`provider "aws" {}`))
+	require.NoError(t, err)
+	assert.Empty(t, constraints)
+}
+
+func TestRequiredVersionWithHCL2(t *testing.T) {
+	tests := []struct {
+		config   string
+		expected []string
+	}{
+		{
+			config: `
+				provider "aws" {
+					region = var.aws_region
+				}`,
+			expected: []string{},
+		},
+		{
+			config:   `terraform {required_version = ">= 0.12"}`,
+			expected: []string{">= 0.12"},
+		},
+		{
+			config: `
+				terraform {
+					backend "s3" {
+						key = "app"
+					}
+					required_providers {
+						aws = {
+							version = "~> 2.0"
+						}
+					}
+					required_version = ">= 0.12, < 0.13"
+				}
+
+				terraform {
+					required_version = "!= 0.12.1"
+				}`,
+			expected: []string{">= 0.12, < 0.13", "!= 0.12.1"},
+		},
+		{
+			config: `
+				terraform {
+					not_required_version = "0.12.0"
+				}
+				variable "required_version" {
+					default = "0.12.0"
+				}`,
+			expected: []string{},
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, requiredVersionWithHCL2([]byte(tt.config)))
+	}
+}
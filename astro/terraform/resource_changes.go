@@ -0,0 +1,174 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// matches the summary line of `terraform plan`, e.g. "Plan: 1 to add, 0 to
+// change, 0 to destroy.", which is printed by all versions of Terraform.
+// Later versions also count resources to import first.
+var planSummaryRe = regexp.MustCompile(`(?m)^Plan: (?:\d+ to import, )?(\d+) to add, (\d+) to change, (\d+) to destroy\.`)
+
+// ResourceChanges is the number of resources a plan adds, changes and
+// destroys. Resources that are replaced count as added and destroyed.
+type ResourceChanges struct {
+	Add     int
+	Change  int
+	Destroy int
+}
+
+// Total returns the number of resources added, changed and destroyed.
+func (c ResourceChanges) Total() int {
+	return c.Add + c.Change + c.Destroy
+}
+
+// parseResourceChanges returns the resource changes in the output of
+// `terraform plan`, or false if the output has no summary line. Plans that
+// only change outputs have no summary line, but don't change resources.
+func parseResourceChanges(output string) (ResourceChanges, bool) {
+	match := planSummaryRe.FindStringSubmatch(output)
+	if match == nil {
+		return ResourceChanges{}, strings.Contains(output, "\nChanges to Outputs:")
+	}
+
+	counts := make([]int, 3)
+	for i := range counts {
+		counts[i], _ = strconv.Atoi(match[i+1])
+	}
+	return ResourceChanges{Add: counts[0], Change: counts[1], Destroy: counts[2]}, true
+}
+
+// resourceAddresses are the addresses of the resources a plan adds, changes
+// and destroys. Resources that are replaced are both added and destroyed.
+type resourceAddresses struct {
+	add     []string
+	change  []string
+	destroy []string
+}
+
+// counts returns the number of resources added, changed and destroyed.
+func (a *resourceAddresses) counts() ResourceChanges {
+	return ResourceChanges{Add: len(a.add), Change: len(a.change), Destroy: len(a.destroy)}
+}
+
+// parseResourceAddresses returns the addresses of the resources changed by
+// the plan in the output of `terraform show -json`, sorted. Data sources
+// that are read and resources without changes aren't included.
+func parseResourceAddresses(in []byte) (*resourceAddresses, error) {
+	var plan planJSON
+	if err := json.Unmarshal(in, &plan); err != nil {
+		return nil, fmt.Errorf("unable to parse JSON plan: %v", err)
+	}
+
+	addresses := &resourceAddresses{add: []string{}, change: []string{}, destroy: []string{}}
+	for _, change := range plan.ResourceChanges {
+		for _, action := range change.Change.Actions {
+			switch action {
+			case "create":
+				addresses.add = append(addresses.add, change.Address)
+			case "update":
+				addresses.change = append(addresses.change, change.Address)
+			case "delete":
+				addresses.destroy = append(addresses.destroy, change.Address)
+			}
+		}
+	}
+
+	for _, list := range [][]string{addresses.add, addresses.change, addresses.destroy} {
+		sort.Strings(list)
+	}
+	return addresses, nil
+}
+
+// matches the data sources that a plan reads, e.g. "<= data.aws_ami.web"
+// in the output of Terraform 0.11, or "# data.aws_ami.web will be read
+// during apply" in later versions.
+var planReadRe = regexp.MustCompile(`(?m)^\s*(?:<= |# \S+ will be read during apply)`)
+
+// ChangeKinds classifies the changes of a plan, e.g. so that plans that
+// only change outputs don't count as drift; see conf.ChangesPolicy.
+type ChangeKinds struct {
+	// Resources is whether the plan adds, changes or destroys resources.
+	Resources bool
+	// Outputs is whether the plan changes outputs.
+	Outputs bool
+	// Reads is whether the plan reads data sources, or has changes that
+	// are neither resources nor outputs, i.e. it only refreshes the state.
+	Reads bool
+	// Approximate is whether the changes were classified from the output
+	// of Terraform rather than the plan as JSON, e.g. before Terraform
+	// 0.12. Changes that can't be classified then count as resources.
+	Approximate bool
+}
+
+// parseChangeKinds classifies the changes of the plan in the output of
+// `terraform show -json`.
+func parseChangeKinds(in []byte) (ChangeKinds, error) {
+	var plan planJSON
+	if err := json.Unmarshal(in, &plan); err != nil {
+		return ChangeKinds{}, fmt.Errorf("unable to parse JSON plan: %v", err)
+	}
+
+	var kinds ChangeKinds
+	for _, change := range plan.ResourceChanges {
+		for _, action := range change.Change.Actions {
+			switch action {
+			case "create", "update", "delete":
+				kinds.Resources = true
+			case "read":
+				kinds.Reads = true
+			}
+		}
+	}
+	for _, change := range plan.OutputChanges {
+		for _, action := range change.Actions {
+			if action != "no-op" {
+				kinds.Outputs = true
+			}
+		}
+	}
+
+	if !kinds.Resources && !kinds.Outputs {
+		kinds.Reads = true
+	}
+	return kinds, nil
+}
+
+// parseChangeKindsFromOutput classifies the changes in the output of
+// `terraform plan` as best it can, from its summary line, the outputs it
+// changes, and the data sources it reads.
+func parseChangeKindsFromOutput(output string) ChangeKinds {
+	kinds := ChangeKinds{
+		Outputs:     strings.Contains(output, "\nChanges to Outputs:"),
+		Reads:       planReadRe.MatchString(output),
+		Approximate: true,
+	}
+
+	changes, ok := parseResourceChanges(output)
+	kinds.Resources = changes.Total() > 0 || (!ok && !kinds.Outputs && !kinds.Reads)
+	if !kinds.Resources && !kinds.Outputs {
+		kinds.Reads = true
+	}
+	return kinds
+}
@@ -17,6 +17,7 @@
 package terraform
 
 import (
+	"errors"
 	"strings"
 	"time"
 
@@ -29,6 +30,9 @@ type Result interface {
 	Runtime() string
 	Stdout() string
 	Stderr() string
+	// LogFile is the path of the log of the combined output of the
+	// command in the session.
+	LogFile() string
 }
 
 // terraformResult is returned by the Plan/Apply commands.
@@ -52,11 +56,54 @@ func (r *terraformResult) Stderr() string {
 	return r.process.Stderr().String()
 }
 
+// LogFile returns the path of the log of the combined output of this
+// execution.
+func (r *terraformResult) LogFile() string {
+	return r.process.CombinedOutputLogFile()
+}
+
 // PlanResult is the terraformResult of a Terraform plan.
 type PlanResult struct {
 	*terraformResult
 
-	changes string
+	changes      string
+	hasChanges   bool
+	parseWarning string
+	planJSON     []byte
+
+	// set if the plan was shown as JSON; see parseResourceAddresses
+	resourceAddresses *resourceAddresses
+
+	// set if secrets were scanned for; see redactSecrets
+	scanned         bool
+	redactedStdout  string
+	redactedSecrets []string
+}
+
+// Stdout returns the stdout of the plan, with secrets redacted if they
+// were scanned for.
+func (r *PlanResult) Stdout() string {
+	if r.scanned {
+		return r.redactedStdout
+	}
+	return r.terraformResult.Stdout()
+}
+
+// RedactedSecrets returns the names of the secret patterns that matched
+// the output of the plan, and were redacted from Changes and Stdout; see
+// Config.SecretPatterns.
+func (r *PlanResult) RedactedSecrets() []string {
+	return r.redactedSecrets
+}
+
+// redactSecrets redacts the secrets that match the patterns from the
+// changes and the output of the plan.
+func (r *PlanResult) redactSecrets(patterns []SecretPattern) {
+	var inChanges, inStdout []string
+	r.changes, inChanges = redactSecrets(r.changes, patterns)
+	r.redactedStdout, inStdout = redactSecrets(r.terraformResult.Stdout(), patterns)
+	r.redactedSecrets = mergeNames(inChanges, inStdout)
+	r.scanned = true
 }
 
 // Changes returns the changes for this plan.
@@ -64,7 +111,90 @@ func (r *PlanResult) Changes() string {
 	return strings.TrimSpace(r.changes)
 }
 
+// ParseWarning returns a warning if the changes could not be parsed from the
+// output of Terraform, in which case Changes returns the full output.
+func (r *PlanResult) ParseWarning() string {
+	return r.parseWarning
+}
+
+// ResourceChanges returns the number of resources the plan adds, changes
+// and destroys, or false if the plan has changes but they couldn't be
+// counted. With Terraform 0.12 and later, they are counted from the plan as
+// JSON; otherwise, from the output of Terraform.
+func (r *PlanResult) ResourceChanges() (ResourceChanges, bool) {
+	if !r.HasChanges() {
+		return ResourceChanges{}, true
+	}
+	if r.resourceAddresses != nil {
+		return r.resourceAddresses.counts(), true
+	}
+	return parseResourceChanges(r.Stdout())
+}
+
+// ChangeKinds classifies the changes of the plan: whether it changes
+// resources, outputs, or only reads data sources. With Terraform 0.12 and
+// later, they are classified from the plan as JSON; otherwise, from the
+// output of Terraform, which is approximate.
+func (r *PlanResult) ChangeKinds() ChangeKinds {
+	if !r.HasChanges() {
+		return ChangeKinds{}
+	}
+	if r.planJSON != nil {
+		if kinds, err := parseChangeKinds(r.planJSON); err == nil {
+			return kinds
+		}
+	}
+	return parseChangeKindsFromOutput(r.Stdout())
+}
+
+// addresses returns the addresses selected from the resource addresses of
+// the plan: none if it has no changes, or nil if they aren't known because
+// the plan couldn't be shown as JSON, which requires Terraform 0.12 or
+// later.
+func (r *PlanResult) addresses(selected func(*resourceAddresses) []string) []string {
+	if !r.HasChanges() {
+		return []string{}
+	}
+	if r.resourceAddresses == nil {
+		return nil
+	}
+	return selected(r.resourceAddresses)
+}
+
+// AddedResources returns the addresses of the resources the plan adds,
+// sorted, e.g. `module.app.aws_instance.web[0]`. Resources that are
+// replaced are both added and destroyed. It returns nil if the addresses
+// aren't known, because the plan couldn't be shown as JSON.
+func (r *PlanResult) AddedResources() []string {
+	return r.addresses(func(a *resourceAddresses) []string { return a.add })
+}
+
+// ChangedResources returns the addresses of the resources the plan changes
+// in place, sorted, or nil if they aren't known; see AddedResources.
+func (r *PlanResult) ChangedResources() []string {
+	return r.addresses(func(a *resourceAddresses) []string { return a.change })
+}
+
+// DestroyedResources returns the addresses of the resources the plan
+// destroys, sorted, or nil if they aren't known; see AddedResources.
+func (r *PlanResult) DestroyedResources() []string {
+	return r.addresses(func(a *resourceAddresses) []string { return a.destroy })
+}
+
+// OrphanResources returns the resources in the state that the plan destroys
+// because they are no longer in the configuration. It returns an error if
+// the plan has changes but couldn't be shown as JSON.
+func (r *PlanResult) OrphanResources() ([]OrphanResource, error) {
+	if !r.HasChanges() {
+		return nil, nil
+	}
+	if r.planJSON == nil {
+		return nil, errors.New("the plan couldn't be shown as JSON, which requires Terraform 0.12 or later")
+	}
+	return parseOrphanResources(r.planJSON)
+}
+
 // HasChanges returns whether this plan had changes or not.
 func (r *PlanResult) HasChanges() bool {
-	return r.process.ExitCode() == 2
+	return r.hasChanges
 }
@@ -0,0 +1,150 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+// matches relative module sources, e.g. `source = "../../modules/vpc"`,
+// including ones in single line module blocks.
+var terraformRelativeModuleSource = regexp.MustCompile(`\bsource\s*=\s*"(\.\.?/[^"]*)"`)
+
+// sandboxPath converts a sandbox include path to a clean path relative to the
+// code root. It resolves the path the same way filepath.Join does when it is
+// joined to the code root, so "/etc" becomes "etc" rather than referring to
+// the host's /etc.
+func sandboxPath(path string) string {
+	rel, _ := filepath.Rel("/", filepath.Join("/", path))
+	return rel
+}
+
+// sandboxPaths returns the list of paths, relative to the code root, that
+// should be cloned into the sandbox. The module path is always included. An
+// empty list means the whole code root should be cloned.
+func sandboxPaths(modulePath string, include []string) []string {
+	if len(include) == 0 {
+		return nil
+	}
+
+	candidates := []string{sandboxPath(modulePath)}
+	for _, path := range include {
+		candidates = append(candidates, sandboxPath(path))
+	}
+	sort.Strings(candidates)
+
+	// Drop any paths that are already covered by another path in the list,
+	// so that files aren't copied twice.
+	paths := []string{}
+	for _, candidate := range candidates {
+		covered := false
+		for _, path := range paths {
+			if path == "." || utils.IsWithinPath(path, candidate) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			paths = append(paths, candidate)
+		}
+	}
+
+	return paths
+}
+
+// ModuleSourcesOutsideSandbox inspects the Terraform files of the module for
+// relative module sources and returns those that would not be cloned into the
+// sandbox with the specified include paths. Sources of included local modules
+// are inspected recursively. The returned paths are relative to basePath.
+//
+// Only module sources are inspected; paths referenced in other ways, e.g. by
+// a terraform_remote_state data source using the local backend, are not
+// detected.
+func ModuleSourcesOutsideSandbox(basePath, modulePath string, include []string) ([]string, error) {
+	paths := sandboxPaths(modulePath, include)
+	if paths == nil {
+		return nil, nil
+	}
+
+	isIncluded := func(source string) bool {
+		for _, path := range paths {
+			if utils.IsWithinPath(path, source) {
+				return true
+			}
+		}
+		return false
+	}
+
+	missing := []string{}
+	visited := map[string]bool{}
+
+	var inspect func(dir string) error
+	inspect = func(dir string) error {
+		if visited[dir] {
+			return nil
+		}
+		visited[dir] = true
+
+		files, err := filepath.Glob(filepath.Join(basePath, dir, "*.tf"))
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			for _, match := range terraformRelativeModuleSource.FindAllSubmatch(b, -1) {
+				source := filepath.Join(dir, string(match[1]))
+
+				// Sources outside of the code root can't be cloned
+				// regardless of sandbox_include, so ignore them here.
+				if !utils.IsWithinPath(".", source) {
+					continue
+				}
+
+				if !isIncluded(source) {
+					if !utils.StringSliceContains(missing, source) {
+						missing = append(missing, source)
+					}
+					continue
+				}
+
+				if err := inspect(source); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if err := inspect(sandboxPath(modulePath)); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(missing)
+
+	return missing, nil
+}
@@ -0,0 +1,410 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestTree creates the files in tree, mapped from path to contents,
+// under a new temporary directory and returns the directory.
+func writeTestTree(t *testing.T, tree map[string]string) string {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+
+	for path, contents := range tree {
+		fullPath := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, ioutil.WriteFile(fullPath, []byte(contents), 0644))
+	}
+
+	return dir
+}
+
+func TestSandboxPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		modulePath string
+		include    []string
+		expected   []string
+	}{
+		{
+			name:       "no includes clones everything",
+			modulePath: "app",
+			include:    nil,
+			expected:   nil,
+		},
+		{
+			name:       "module path is always included",
+			modulePath: "app",
+			include:    []string{"modules"},
+			expected:   []string{"app", "modules"},
+		},
+		{
+			name:       "covered paths are removed",
+			modulePath: "app",
+			include:    []string{"modules", "modules/vpc", "app/files"},
+			expected:   []string{"app", "modules"},
+		},
+		{
+			name:       "code root covers everything",
+			modulePath: "app",
+			include:    []string{".", "modules"},
+			expected:   []string{"."},
+		},
+		{
+			name:       "sibling prefixes are kept",
+			modulePath: "app",
+			include:    []string{"modules", "modules-shared"},
+			expected:   []string{"app", "modules", "modules-shared"},
+		},
+		{
+			name:       "paths are cleaned",
+			modulePath: "./app/",
+			include:    []string{"modules/../shared", "/etc"},
+			expected:   []string{"app", "etc", "shared"},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, sandboxPaths(tt.modulePath, tt.include), tt.name)
+	}
+}
+
+func TestModuleSourcesOutsideSandbox(t *testing.T) {
+	dir := writeTestTree(t, map[string]string{
+		"app/main.tf": `
+module "a" { source = "../modules/a" }
+module "vpc" {
+  source = "../modules/vpc"
+}
+module "remote" {
+  source = "git::https://example.com/vpc.git"
+}
+`,
+		"app/outside.tf":      `module "outside" { source = "../../outside" }`,
+		"modules/a/main.tf":   `module "b" { source = "../b" }`,
+		"modules/b/main.tf":   `module "a" { source = "../a" }`,
+		"modules/vpc/main.tf": `module "subnet" { source = "./subnet" }`,
+	})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		include  []string
+		expected []string
+	}{
+		{
+			name:     "no includes clones everything",
+			include:  nil,
+			expected: nil,
+		},
+		{
+			name:     "all sources included",
+			include:  []string{"modules"},
+			expected: []string{},
+		},
+		{
+			name:     "sources of included modules are checked",
+			include:  []string{"modules/a", "modules/vpc"},
+			expected: []string{"modules/b"},
+		},
+		{
+			name:     "sources in included directories are checked",
+			include:  []string{"modules/a", "modules/b"},
+			expected: []string{"modules/vpc"},
+		},
+	}
+
+	for _, tt := range tests {
+		missing, err := ModuleSourcesOutsideSandbox(dir, "app", tt.include)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, missing, tt.name)
+	}
+}
+
+func TestCloneTreeWithPaths(t *testing.T) {
+	dir := writeTestTree(t, map[string]string{
+		"app/main.tf":              "",
+		"modules/vpc/main.tf":      "",
+		"modules/vpc/.terraform/x": "",
+		"modules-shared/main.tf":   "",
+		"other/main.tf":            "",
+		"-delete/main.tf":          "",
+	})
+	defer os.RemoveAll(dir)
+
+	sandbox, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(sandbox)
+
+	require.NoError(t, cloneTree(dir, sandbox, nil, "app", "modules/vpc", "-delete"))
+
+	// parent directories of included paths are created
+	assert.True(t, utils.FileExists(filepath.Join(sandbox, "app/main.tf")))
+	assert.True(t, utils.FileExists(filepath.Join(sandbox, "modules/vpc/main.tf")))
+	assert.True(t, utils.FileExists(filepath.Join(sandbox, "-delete/main.tf")))
+
+	assert.False(t, utils.FileExists(filepath.Join(sandbox, "modules/vpc/.terraform")))
+	assert.False(t, utils.FileExists(filepath.Join(sandbox, "modules-shared")))
+	assert.False(t, utils.FileExists(filepath.Join(sandbox, "other")))
+
+	// the source tree is left untouched
+	assert.True(t, utils.FileExists(filepath.Join(dir, "-delete/main.tf")))
+}
+
+func TestCloneTreeSymlinkedRoot(t *testing.T) {
+	dir := writeTestTree(t, map[string]string{
+		"app/main.tf": "",
+	})
+	defer os.RemoveAll(dir)
+
+	link := dir + "-link"
+	require.NoError(t, os.Symlink(dir, link))
+	defer os.Remove(link)
+	require.NoError(t, os.Symlink("main.tf", filepath.Join(dir, "app/alias.tf")))
+
+	sandbox, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(sandbox)
+
+	require.NoError(t, cloneTree(link, sandbox, nil))
+
+	// files are hard linked
+	src, err := os.Stat(filepath.Join(dir, "app/main.tf"))
+	require.NoError(t, err)
+	dst, err := os.Stat(filepath.Join(sandbox, "app/main.tf"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(src, dst))
+
+	// symlinks in the tree are copied as symlinks
+	target, err := os.Readlink(filepath.Join(sandbox, "app/alias.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "main.tf", target)
+}
+
+func TestCloneTreeExclusions(t *testing.T) {
+	dir := writeTestTree(t, map[string]string{
+		"app/main.tf":                   "",
+		"app/terraform.tfstate":         "",
+		"app/terraform.tfstate.backup":  "",
+		"app/.terraform/plugins/x":      "",
+		"app/.astro/plan.log":           "",
+		"modules/vpc/.terraform/x":      "",
+		"modules/vpc/files/.astro/x":    "",
+		"modules/vpc/files/keep.tfvars": "",
+	})
+	defer os.RemoveAll(dir)
+
+	sandbox, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(sandbox)
+
+	require.NoError(t, cloneTree(dir, sandbox, nil))
+
+	assert.True(t, utils.FileExists(filepath.Join(sandbox, "app/main.tf")))
+	assert.True(t, utils.FileExists(filepath.Join(sandbox, "modules/vpc/files/keep.tfvars")))
+	for _, excluded := range []string{
+		"app/terraform.tfstate",
+		"app/terraform.tfstate.backup",
+		"app/.terraform",
+		"app/.astro",
+		"modules/vpc/.terraform",
+		"modules/vpc/files/.astro",
+	} {
+		assert.False(t, utils.FileExists(filepath.Join(sandbox, excluded)), excluded)
+	}
+}
+
+func TestCloneTreePermissions(t *testing.T) {
+	dir := writeTestTree(t, map[string]string{
+		"app/main.tf":         "",
+		"app/secret.tfvars":   "",
+		"modules/ro/main.tf":  "",
+		"modules/bin/run.sh":  "",
+		"modules/bin/data.sh": "",
+	})
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Chmod(filepath.Join(dir, "app"), 0750))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "app/secret.tfvars"), 0600))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "modules/bin/run.sh"), 0755))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "modules/ro"), 0555))
+	defer os.Chmod(filepath.Join(dir, "modules/ro"), 0755)
+
+	sandbox, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(sandbox)
+	defer os.Chmod(filepath.Join(sandbox, "modules/ro"), 0755)
+
+	require.NoError(t, cloneTree(dir, sandbox, nil, "app", "modules"))
+
+	for path, mode := range map[string]os.FileMode{
+		"app":                0750,
+		"app/secret.tfvars":  0600,
+		"modules/ro":         0555,
+		"modules/ro/main.tf": 0644,
+		"modules/bin/run.sh": 0755,
+	} {
+		info, err := os.Stat(filepath.Join(sandbox, path))
+		require.NoError(t, err, path)
+		assert.Equal(t, mode, info.Mode().Perm(), path)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := writeTestTree(t, map[string]string{
+		"secret.tfvars": "password = \"hunter2\"",
+	})
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "secret.tfvars")
+	require.NoError(t, os.Chmod(src, 0600))
+	dst := filepath.Join(dir, "copy.tfvars")
+	require.NoError(t, copyFile(src, dst))
+
+	b, err := ioutil.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "password = \"hunter2\"", string(b))
+
+	srcInfo, err := os.Stat(src)
+	require.NoError(t, err)
+	dstInfo, err := os.Stat(dst)
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(srcInfo, dstInfo))
+	assert.Equal(t, os.FileMode(0600), dstInfo.Mode().Perm())
+}
+
+func TestSandboxExcludes(t *testing.T) {
+	excludes := newSandboxExcludes([]string{
+		"node_modules/",
+		"/docs",
+		"!docs/README.md",
+		"lambda/**/*.zip",
+		"vendor/**",
+	})
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		{path: "app/main.tf"},
+		{path: "node_modules", isDir: true, excluded: true},
+		{path: "lambda/fn/node_modules", isDir: true, excluded: true},
+		{path: "lambda/fn/node_modules"}, // not a directory
+		{path: "docs", isDir: true, excluded: true},
+		{path: "docs/README.md"},
+		{path: "app/docs", isDir: true},
+		{path: "lambda/fn.zip", excluded: true},
+		{path: "lambda/fn/build/fn.zip", excluded: true},
+		{path: "app/fn.zip"},
+		{path: "vendor", isDir: true},
+		{path: "vendor/module", isDir: true, excluded: true},
+		{path: "app/.terraform", isDir: true, excluded: true},
+		{path: "app/terraform.tfstate.backup", excluded: true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.excluded, excludes.excluded(tt.path, tt.isDir), tt.path)
+	}
+}
+
+func TestCloneTreeWithExcludes(t *testing.T) {
+	dir := writeTestTree(t, map[string]string{
+		"app/main.tf":                          "",
+		"docs/README.md":                       "",
+		"docs/guide/index.md":                  "",
+		"lambda/fn/index.js":                   "",
+		"lambda/fn/node_modules/left-pad/i.js": "",
+		".git/HEAD":                            "",
+		"modules/vpc/.git/HEAD":                "",
+	})
+	defer os.RemoveAll(dir)
+
+	sandbox, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(sandbox)
+
+	excludes := newSandboxExcludes([]string{".git", "node_modules/", "/docs/guide"})
+	require.NoError(t, cloneTree(dir, sandbox, excludes))
+
+	assert.True(t, utils.FileExists(filepath.Join(sandbox, "app/main.tf")))
+	assert.True(t, utils.FileExists(filepath.Join(sandbox, "docs/README.md")))
+	assert.True(t, utils.FileExists(filepath.Join(sandbox, "lambda/fn/index.js")))
+	for _, excluded := range []string{".git", "modules/vpc/.git", "lambda/fn/node_modules", "docs/guide"} {
+		assert.False(t, utils.FileExists(filepath.Join(sandbox, excluded)), excluded)
+	}
+}
+
+// BenchmarkSandboxLayouts compares creating the sessions of a module's
+// executions with a sandbox each and with a shared sandbox.
+func BenchmarkSandboxLayouts(b *testing.B) {
+	const executions = 6
+
+	codeRoot, err := ioutil.TempDir("", "")
+	require.NoError(b, err)
+	defer os.RemoveAll(codeRoot)
+
+	for i := 0; i < 50; i++ {
+		dir := filepath.Join(codeRoot, fmt.Sprintf("modules/module%d", i))
+		require.NoError(b, os.MkdirAll(dir, 0755))
+		for j := 0; j < 20; j++ {
+			require.NoError(b, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.tf", j)), []byte("\n"), 0644))
+		}
+	}
+
+	config := Config{
+		BasePath:      codeRoot,
+		ModulePath:    "modules/module0",
+		TerraformPath: "terraform",
+	}
+
+	for _, layout := range []string{"private", "shared"} {
+		b.Run(layout, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				sessionDir, err := ioutil.TempDir("", "")
+				require.NoError(b, err)
+				b.StartTimer()
+
+				executionConfig := config
+				if layout == "shared" {
+					executionConfig.SharedSandboxDir = filepath.Join(sessionDir, "shared")
+					require.NoError(b, CloneSandbox(executionConfig.SharedSandboxDir, config))
+				}
+				for j := 0; j < executions; j++ {
+					_, err := NewTerraformSession(fmt.Sprint(j), filepath.Join(sessionDir, fmt.Sprint(j)), executionConfig)
+					require.NoError(b, err)
+				}
+
+				b.StopTimer()
+				os.RemoveAll(sessionDir)
+				b.StartTimer()
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SandboxReport compares the files in a sandbox with the code they were
+// cloned from. Paths are relative to the code root. Only regular files are
+// compared; files Terraform creates, such as .terraform, state and plan
+// files, are ignored.
+type SandboxReport struct {
+	// Linked are files that are still hard links to the source.
+	Linked []string
+	// Copied are files that are no longer linked to the source, but have
+	// the same content, e.g. because the source was saved without changes.
+	Copied []string
+	// Diverged are files that are no longer linked to the source and have
+	// different content.
+	Diverged []string
+	// SourceOnly are files that exist in the source but not the sandbox.
+	SourceOnly []string
+	// SandboxOnly are files that exist in the sandbox but not the source.
+	SandboxOnly []string
+}
+
+// isClonedName returns whether files or directories with the name are
+// cloned into sandboxes.
+func isClonedName(name string) bool {
+	return name != ".terraform" && name != ".astro" && !strings.HasPrefix(name, "terraform.tfstate")
+}
+
+// listSandboxFiles returns the regular files under the paths in root,
+// relative to root, skipping those that aren't cloned into sandboxes. Paths
+// that don't exist are ignored.
+func listSandboxFiles(root string, paths []string, excludes sandboxExcludes) (map[string]os.FileInfo, error) {
+	files := map[string]os.FileInfo{}
+	for _, path := range paths {
+		err := filepath.Walk(filepath.Join(root, path), func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			if rel != "." && excludes.excluded(rel, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			files[rel] = info
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// sameContent returns whether the two files have the same content.
+func sameContent(a, b string) (bool, error) {
+	contentA, err := ioutil.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	contentB, err := ioutil.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(contentA, contentB), nil
+}
+
+// VerifySandbox compares the files of the sandbox with the code in basePath
+// they were cloned from, with the same module path, include paths and
+// exclude patterns that were used to clone it.
+func VerifySandbox(basePath, sandboxDir, modulePath string, include, exclude []string) (*SandboxReport, error) {
+	paths := sandboxPaths(modulePath, include)
+	if paths == nil {
+		paths = []string{"."}
+	}
+
+	excludes := newSandboxExcludes(exclude)
+	sourceFiles, err := listSandboxFiles(basePath, paths, excludes)
+	if err != nil {
+		return nil, err
+	}
+	sandboxFiles, err := listSandboxFiles(sandboxDir, paths, excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SandboxReport{}
+	for path, sourceInfo := range sourceFiles {
+		sandboxInfo, ok := sandboxFiles[path]
+		switch {
+		case !ok:
+			report.SourceOnly = append(report.SourceOnly, path)
+		case os.SameFile(sourceInfo, sandboxInfo):
+			report.Linked = append(report.Linked, path)
+		default:
+			same, err := sameContent(filepath.Join(basePath, path), filepath.Join(sandboxDir, path))
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				report.Copied = append(report.Copied, path)
+			} else {
+				report.Diverged = append(report.Diverged, path)
+			}
+		}
+	}
+	for path := range sandboxFiles {
+		if _, ok := sourceFiles[path]; !ok && filepath.Ext(path) != ".plan" {
+			report.SandboxOnly = append(report.SandboxOnly, path)
+		}
+	}
+
+	for _, paths := range [][]string{report.Linked, report.Copied, report.Diverged, report.SourceOnly, report.SandboxOnly} {
+		sort.Strings(paths)
+	}
+
+	return report, nil
+}
+
+// RepairSandbox replaces the files in the sandbox with hard links to the
+// same files in basePath. Files that can't be linked, e.g. because the
+// sandbox is on another device, are copied instead.
+func RepairSandbox(basePath, sandboxDir string, paths []string) error {
+	for _, path := range paths {
+		source := filepath.Join(basePath, path)
+		target := filepath.Join(sandboxDir, path)
+
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := linkOrCopy(source, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the source file to target, with the same mode.
+func copyFile(source, target string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,84 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// safeWrite replaces the file the way editors that save atomically do: by
+// writing a new file and renaming it over the old one.
+func safeWrite(t *testing.T, path, contents string) {
+	tmp := path + ".tmp"
+	require.NoError(t, ioutil.WriteFile(tmp, []byte(contents), 0644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestVerifyAndRepairSandbox(t *testing.T) {
+	codeRoot := writeTestTree(t, map[string]string{
+		"app/main.tf":      "main",
+		"app/outputs.tf":   "outputs",
+		"app/variables.tf": "variables",
+		"modules/vpc.tf":   "vpc",
+		"other/other.tf":   "other",
+	})
+	defer os.RemoveAll(codeRoot)
+
+	sandboxDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(sandboxDir)
+
+	require.NoError(t, cloneTree(codeRoot, sandboxDir, nil, sandboxPaths("app", []string{"modules"})...))
+
+	safeWrite(t, filepath.Join(codeRoot, "app/main.tf"), "main changed")
+	safeWrite(t, filepath.Join(codeRoot, "app/outputs.tf"), "outputs")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "app/new.tf"), nil, 0644))
+	require.NoError(t, os.Remove(filepath.Join(codeRoot, "modules/vpc.tf")))
+
+	// files created by Terraform are ignored
+	require.NoError(t, os.MkdirAll(filepath.Join(sandboxDir, "app/.terraform/plugins"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sandboxDir, "app/.terraform/plugins/lock.json"), nil, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sandboxDir, "app/app.plan"), nil, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sandboxDir, "app/terraform.tfstate"), nil, 0644))
+
+	report, err := VerifySandbox(codeRoot, sandboxDir, "app", []string{"modules"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &SandboxReport{
+		Linked:      []string{"app/variables.tf"},
+		Copied:      []string{"app/outputs.tf"},
+		Diverged:    []string{"app/main.tf"},
+		SourceOnly:  []string{"app/new.tf"},
+		SandboxOnly: []string{"modules/vpc.tf"},
+	}, report)
+
+	require.NoError(t, RepairSandbox(codeRoot, sandboxDir, report.Diverged))
+
+	b, err := ioutil.ReadFile(filepath.Join(sandboxDir, "app/main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "main changed", string(b))
+
+	report, err = VerifySandbox(codeRoot, sandboxDir, "app", []string{"modules"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app/main.tf", "app/variables.tf"}, report.Linked)
+	assert.Empty(t, report.Diverged)
+}
@@ -0,0 +1,104 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// redactedSecret replaces the secrets found in output.
+const redactedSecret = "[REDACTED]"
+
+// SecretPattern is a regular expression that matches a kind of secret in
+// the output of Terraform.
+type SecretPattern struct {
+	// Name identifies the pattern in messages, e.g. "aws_access_key_id".
+	Name string
+	// Regexp matches the secret.
+	Regexp *regexp.Regexp
+}
+
+// redactSecrets replaces the matches of the patterns in text, and returns
+// the names of the patterns that matched.
+func redactSecrets(text string, patterns []SecretPattern) (string, []string) {
+	matched := []string{}
+	for _, pattern := range patterns {
+		if !pattern.Regexp.MatchString(text) {
+			continue
+		}
+		text = pattern.Regexp.ReplaceAllLiteralString(text, redactedSecret)
+		matched = append(matched, pattern.Name)
+	}
+	return text, matched
+}
+
+// secretRedactingWriter redacts the secrets from each write before passing
+// it on to w. Secrets split between writes aren't redacted, so it expects
+// whole lines, as exec2.Cmd.LiveOutput gets.
+type secretRedactingWriter struct {
+	w        io.Writer
+	patterns []SecretPattern
+}
+
+func (r *secretRedactingWriter) Write(p []byte) (int, error) {
+	redacted, _ := redactSecrets(string(p), r.patterns)
+	if _, err := r.w.Write([]byte(redacted)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactSecretsInFile redacts the secrets in the file at path, if it
+// exists, keeping its mode.
+func redactSecretsInFile(path string, patterns []SecretPattern) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	redacted, matched := redactSecrets(string(b), patterns)
+	if len(matched) == 0 {
+		return nil
+	}
+	return ioutil.WriteFile(path, []byte(redacted), info.Mode())
+}
+
+// mergeNames returns the sorted union of the lists of names.
+func mergeNames(lists ...[]string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, list := range lists {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
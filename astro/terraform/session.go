@@ -19,12 +19,14 @@ package terraform
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
+	"time"
 
+	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/exec2"
-	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/utils"
 	version "github.com/burl/go-version"
 )
@@ -44,6 +46,16 @@ type Session struct {
 	moduleDir  string
 	sandboxDir string
 
+	// dataDir is the Terraform data directory, TF_DATA_DIR, of sessions
+	// with a shared sandbox; see Config.SharedSandboxDir
+	dataDir string
+
+	cloneTime time.Duration
+
+	localStateRestored bool
+	lockFileChanged    bool
+	initCached         bool
+
 	versionCachedValue *version.Version
 }
 
@@ -64,62 +76,182 @@ func NewTerraformSession(id, baseDir string, config Config) (*Session, error) {
 		return nil, err
 	}
 
-	sandboxDir, err := filepath.Abs(filepath.Join(baseDir, "sandbox"))
-	if err != nil {
-		return nil, err
+	if config.DirMode == 0 {
+		config.DirMode = 0755
 	}
 
-	for _, dir := range []string{baseDir, logDir, sandboxDir} {
-		logger.Trace.Printf("terraform: mkdir: %v\n", dir)
-		if err := os.Mkdir(dir, 0755); err != nil {
+	for _, dir := range []string{baseDir, logDir} {
+		config.log().Debugf("terraform: mkdir: %v\n", dir)
+		if err := os.Mkdir(dir, config.DirMode); err != nil {
 			return nil, err
 		}
 	}
 
-	// Copy the Terraform code tree into the sandbox
-	logger.Trace.Printf("terraform: copying tree from %v to %v", config.BasePath, sandboxDir)
-	if err := cloneTree(config.BasePath, sandboxDir); err != nil {
-		return nil, fmt.Errorf("unable to clone tree from %v to %v: %v", config.BasePath, sandboxDir, err)
+	session := &Session{
+		id:      id,
+		config:  &config,
+		baseDir: baseDir,
+		logDir:  logDir,
 	}
 
-	moduleDir, err := filepath.Abs(filepath.Join(sandboxDir, config.ModulePath))
+	if config.SharedSandboxDir != "" {
+		session.sandboxDir, err = filepath.Abs(config.SharedSandboxDir)
+		if err != nil {
+			return nil, err
+		}
+		session.dataDir, err = filepath.Abs(filepath.Join(baseDir, "terraform-data"))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		session.sandboxDir, err = filepath.Abs(filepath.Join(baseDir, "sandbox"))
+		if err != nil {
+			return nil, err
+		}
+		started := time.Now()
+		if err := CloneSandbox(session.sandboxDir, config); err != nil {
+			return nil, err
+		}
+		session.cloneTime = time.Since(started)
+	}
+
+	session.moduleDir, err = filepath.Abs(filepath.Join(session.sandboxDir, config.ModulePath))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Session{
-		id:         id,
-		config:     &config,
-		baseDir:    baseDir,
-		sandboxDir: sandboxDir,
-		moduleDir:  moduleDir,
-		logDir:     logDir,
-	}, nil
+	return session, nil
 }
 
+// CloneSandbox creates the directory and clones the Terraform code of the
+// config into it, as NewTerraformSession does for the sandbox of a session.
+// It is used to create sandboxes that are shared by several sessions; see
+// Config.SharedSandboxDir.
+func CloneSandbox(dir string, config Config) error {
+	if config.DirMode == 0 {
+		config.DirMode = 0755
+	}
+
+	config.log().Debugf("terraform: mkdir: %v\n", dir)
+	if err := os.Mkdir(dir, config.DirMode); err != nil {
+		return err
+	}
+
+	// Copy the Terraform code tree into the sandbox
+	includePaths := sandboxPaths(config.ModulePath, config.SandboxInclude)
+	config.log().Debugf("terraform: copying tree from %v to %v; paths: %v", config.BasePath, dir, includePaths)
+	if err := cloneTree(config.BasePath, dir, newSandboxExcludes(config.SandboxExclude), includePaths...); err != nil {
+		return fmt.Errorf("unable to clone tree from %v to %v: %v", config.BasePath, dir, err)
+	}
+
+	return nil
+}
+
+// SharesSandbox returns whether the session uses a sandbox that is shared
+// with other sessions; see Config.SharedSandboxDir.
+func (s *Session) SharesSandbox() bool {
+	return s.dataDir != ""
+}
+
+// LogDir returns the directory of the logs of the commands run in the
+// session.
+func (s *Session) LogDir() string {
+	return s.logDir
+}
+
+// CloneTime returns how long it took to clone the Terraform code into the
+// sandbox for this session. It is zero for sessions with a shared sandbox.
+func (s *Session) CloneTime() time.Duration {
+	return s.cloneTime
+}
+
+// ModuleSourcesOutsideSandbox returns the relative module sources referenced
+// by this module that were not cloned into the sandbox. See the package-level
+// ModuleSourcesOutsideSandbox for details.
+func (s *Session) ModuleSourcesOutsideSandbox() ([]string, error) {
+	return ModuleSourcesOutsideSandbox(s.config.BasePath, s.config.ModulePath, s.config.SandboxInclude)
+}
+
+// liveSubcommands are the Terraform subcommands whose output is streamed to
+// Config.LiveOutput: those that can take a while. The output of the others,
+// e.g. show, is read by astro or displayed once they are done.
+var liveSubcommands = []string{"apply", "get", "import", "init", "plan", "refresh"}
+
 // command returns an exec2.Process ready to be executed.
 func (s *Session) command(logfileName string, cmd string, args []string, expectedSuccessCodes []int) (*exec2.Process, error) {
+	if cmd == s.config.TerraformPath {
+		if err := s.checkReadOnly(args); err != nil {
+			return nil, err
+		}
+	}
+
 	env := os.Environ()
 
+	sensitiveValues := []string{}
+	for _, name := range s.config.SensitiveVariables {
+		sensitiveValues = append(sensitiveValues, variableItems(s.config.VariableTypes[name], s.config.Variables[name])...)
+	}
+
+	names := []string{}
+	for name := range s.config.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	logEnv := []string{}
+	for _, name := range names {
+		value := s.config.Env[name]
+		env = append(env, fmt.Sprintf("%s=%s", name, value))
+		logEnv = append(logEnv, fmt.Sprintf("%s=%s", name, value))
+		if utils.StringSliceContains(s.config.SensitiveEnv, name) {
+			sensitiveValues = append(sensitiveValues, value)
+		}
+	}
+
 	if s.config.SharedPluginDir != "" {
 		env = append(env, fmt.Sprintf("TF_PLUGIN_CACHE_DIR=%s", s.config.SharedPluginDir))
 	}
 
+	if s.config.CLIConfigFile != "" {
+		env = append(env, fmt.Sprintf("%s=%s", conf.CLIConfigFileEnv, s.config.CLIConfigFile))
+	}
+
+	if s.config.TempDir != "" {
+		env = append(env, fmt.Sprintf("TMPDIR=%s", s.config.TempDir))
+	}
+
+	if s.dataDir != "" {
+		env = append(env, fmt.Sprintf("TF_DATA_DIR=%s", s.dataDir))
+	}
+
+	var liveOutput io.Writer
+	if s.config.LiveOutput != nil && cmd == s.config.TerraformPath && len(args) > 0 && utils.StringSliceContains(liveSubcommands, args[0]) {
+		liveOutput = &secretRedactingWriter{w: s.config.LiveOutput, patterns: s.config.SecretPatterns}
+	}
+
 	return exec2.NewProcess(exec2.Cmd{
 		Command: cmd,
 		Args:    args,
 		Env:     env,
-		CombinedOutputLogFile: filepath.Join(s.logDir, fmt.Sprintf("%s.log", logfileName)),
-		ExpectedSuccessCodes:  expectedSuccessCodes,
-		WorkingDir:            s.moduleDir,
+		LogEnv:  logEnv,
+		Logger:  s.config.Logger,
+		CombinedOutputLogFile:     filepath.Join(s.logDir, fmt.Sprintf("%s.log", logfileName)),
+		CombinedOutputLogFileMode: s.config.FileMode,
+		ExpectedSuccessCodes:      expectedSuccessCodes,
+		LiveOutput:                liveOutput,
+		SensitiveValues:           sensitiveValues,
+		WorkingDir:                s.moduleDir,
 	}), nil
 }
 
-func (s *Session) terraformCommand(args []string, expectedSuccessCodes []int) (*exec2.Process, error) {
+// terraformCommand returns a Terraform command that succeeds with the exit
+// codes configured for its subcommand, the first argument; see
+// Config.ExitCodes.
+func (s *Session) terraformCommand(args []string) (*exec2.Process, error) {
 	if len(args) < 1 {
 		return nil, errors.New("missing args")
 	}
-	return s.command(args[0], s.config.TerraformPath, args, expectedSuccessCodes)
+	return s.command(args[0], s.config.TerraformPath, args, s.config.ExitCodes.SuccessCodes(args[0]))
 }
 
 // SetTerraformPath sets the path to Terraform.
@@ -127,43 +259,8 @@ func (s *Session) SetTerraformPath(path string) {
 	s.config.TerraformPath = path
 }
 
-// cloneTree copies the files in existingPath to newPath recursively,
-// using hard links.
-func cloneTree(existingPath string, newPath string) error {
-	existingPathDeref, err := filepath.EvalSymlinks(existingPath)
-	if err != nil {
-		return err
-	}
-
-	newPathDeref, err := filepath.EvalSymlinks(newPath)
-	if err != nil {
-		return err
-	}
-
-	find := exec.Command("find", ".",
-		"!", "-path", "*/.terraform/*",
-		"!", "-name", ".terraform",
-		"!", "-path", "*/.astro/*",
-		"!", "-name", ".astro",
-		"!", "-name", "terraform.tfstate*",
-	)
-	find.Dir = existingPathDeref
-	cpio := exec.Command("cpio", "-pl", newPathDeref)
-	cpio.Dir = existingPathDeref
-
-	cpio.Stdin, err = find.StdoutPipe()
-	if err != nil {
-		return err
-	}
-
-	if err := find.Start(); err != nil {
-		return err
-	}
-	if err := cpio.Start(); err != nil {
-		return err
-	}
-	if err := find.Wait(); err != nil {
-		return err
-	}
-	return cpio.Wait()
+// SetSavePlanJSON sets whether plans are saved as JSON too; see
+// Config.SavePlanJSON.
+func (s *Session) SetSavePlanJSON(save bool) {
+	s.config.SavePlanJSON = save
 }
@@ -0,0 +1,64 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SourceHash returns a hash of the code in basePath that a sandbox cloned
+// with the module path, include paths and exclude patterns contains: the
+// path and content of every regular file, so that changing, adding,
+// removing or renaming any of them changes the hash.
+func SourceHash(basePath, modulePath string, include, exclude []string) (string, error) {
+	paths := sandboxPaths(modulePath, include)
+	if paths == nil {
+		paths = []string{"."}
+	}
+
+	files, err := listSandboxFiles(basePath, paths, newSandboxExcludes(exclude))
+	if err != nil {
+		return "", err
+	}
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(basePath, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file=%s size=%d\n", filepath.ToSlash(name), files[name].Size())
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
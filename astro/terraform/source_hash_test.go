@@ -0,0 +1,57 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceHash(t *testing.T) {
+	codeRoot := writeTestTree(t, map[string]string{
+		"app/main.tf":    "main",
+		"app/notes.bak":  "notes",
+		"modules/vpc.tf": "vpc",
+		"other/other.tf": "other",
+	})
+	defer os.RemoveAll(codeRoot)
+
+	hash := func() string {
+		h, err := SourceHash(codeRoot, "app", []string{"modules"}, []string{"*.bak"})
+		require.NoError(t, err)
+		return h
+	}
+	original := hash()
+	assert.Equal(t, original, hash())
+
+	// files that aren't cloned into the sandbox don't change the hash
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "other/other.tf"), []byte("changed"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "app/notes.bak"), []byte("changed"), 0644))
+	assert.Equal(t, original, hash())
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "modules/vpc.tf"), []byte("changed"), 0644))
+	changed := hash()
+	assert.NotEqual(t, original, changed)
+
+	require.NoError(t, os.Rename(filepath.Join(codeRoot, "app/main.tf"), filepath.Join(codeRoot, "app/renamed.tf")))
+	assert.NotEqual(t, changed, hash())
+}
@@ -0,0 +1,84 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/uber/astro/astro/logger"
+
+	version "github.com/burl/go-version"
+)
+
+// matches the errors of Terraform about state written by a newer version,
+// e.g. "state snapshot was created by Terraform v0.12.6, which is newer
+// than current v0.11.14" or "it is written by Terraform '0.12.0'"
+var reNewerStateError = regexp.MustCompile(`(?:created|written) by Terraform '?v?(\d+\.\d+\.\d+[\w.+-]*)`)
+
+// StateVersion returns the version of Terraform that last wrote the state of
+// the module, from the terraform_version field of the state. It returns nil
+// if there is no state, or if it can't be read, e.g. with Terraform 0.8 and
+// earlier, which can't pull state. The session must have been initialized
+// with Init first.
+//
+// Older versions of Terraform refuse to pull state written by a newer
+// version, in which case the version is read from their error message.
+func (s *Session) StateVersion() (*version.Version, error) {
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return nil, err
+	}
+	if VersionMatches(terraformVersion, "< 0.9") {
+		return nil, nil
+	}
+
+	process, err := s.command("state-version-pull", s.config.TerraformPath, []string{"state", "pull"}, s.config.ExitCodes.SuccessCodes("state"))
+	if err != nil {
+		return nil, err
+	}
+	if err := process.Run(); err != nil {
+		if match := reNewerStateError.FindStringSubmatch(process.Stderr().String()); match != nil {
+			return version.NewVersion(match[1])
+		}
+		s.config.log().Debugf("terraform: unable to pull state to check its version: %v", err)
+		return nil, nil
+	}
+
+	return stateTerraformVersion(process.Stdout().Bytes(), s.config.log())
+}
+
+// stateTerraformVersion returns the terraform_version of the output of
+// `terraform state pull`, or nil if there is no state or it has no version.
+func stateTerraformVersion(state []byte, log logger.Logger) (*version.Version, error) {
+	if len(state) == 0 {
+		return nil, nil
+	}
+
+	var parsed struct {
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(state, &parsed); err != nil {
+		log.Debugf("terraform: unable to parse state to check its version: %v", err)
+		return nil, nil
+	}
+	if parsed.TerraformVersion == "" {
+		return nil, nil
+	}
+
+	return version.NewVersion(parsed.TerraformVersion)
+}
@@ -0,0 +1,71 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestStateVersion(t *testing.T) {
+	tt := []struct {
+		spec     string
+		expected string
+	}{
+		{"fixtures/mock-terraform/state-version.yaml", "0.12.6"},
+		{"fixtures/mock-terraform/state-version-refused.yaml", "0.12.6"},
+		// no state
+		{"fixtures/mock-terraform/args.yaml", ""},
+	}
+
+	for _, test := range tt {
+		t.Run(filepath.Base(test.spec), func(t *testing.T) {
+			tmpdir, err := ioutil.TempDir("", "astro-state-version-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(tmpdir)
+
+			codeRoot := filepath.Join(tmpdir, "code")
+			require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+			terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), test.spec)
+
+			session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+				Name:          "app",
+				BasePath:      codeRoot,
+				ModulePath:    ".",
+				TerraformPath: terraformPath,
+				TempDir:       tmpdir,
+			})
+			require.NoError(t, err)
+
+			stateVersion, err := session.StateVersion()
+			require.NoError(t, err)
+			if test.expected == "" {
+				assert.Nil(t, stateVersion)
+			} else if assert.NotNil(t, stateVersion) {
+				assert.Equal(t, test.expected, stateVersion.String())
+			}
+		})
+	}
+}
@@ -16,12 +16,26 @@
 
 package terraform
 
-import (
-	"fmt"
-)
+import "fmt"
 
 // Apply runs a `terraform apply`
 func (s *Session) Apply() (Result, error) {
+	return s.apply(false)
+}
+
+// ApplyPlan runs a `terraform apply` of the plan file that the last Plan of
+// the session wrote, so that exactly the changes of that plan are applied.
+// The variables and parameters of the session were already passed to the
+// plan, and Terraform doesn't accept them along with a plan file.
+func (s *Session) ApplyPlan() (Result, error) {
+	return s.apply(true)
+}
+
+func (s *Session) apply(savedPlan bool) (Result, error) {
+	if err := s.restoreLocalState(); err != nil {
+		return nil, err
+	}
+
 	if !s.Initialized() {
 		if result, err := s.Init(); err != nil {
 			return result, err
@@ -35,22 +49,38 @@ func (s *Session) Apply() (Result, error) {
 
 	args := []string{"apply"}
 
-	if VersionMatches(terraformVersion, ">= 0.11") {
-		args = append(args, "-auto-approve")
-	}
+	if savedPlan {
+		args = append(args, fmt.Sprintf("%s.plan", s.id))
+	} else {
+		if VersionMatches(terraformVersion, ">= 0.11") {
+			args = append(args, "-auto-approve")
+		}
 
-	for key, val := range s.config.Variables {
-		args = append(args, "-var", fmt.Sprintf("%s=%s", key, val))
-	}
+		variableArgs, err := s.variableArgs()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, variableArgs...)
+		args = append(args, s.replaceArgs()...)
 
-	args = append(args, s.config.TerraformParameters...)
+		args = append(args, s.config.TerraformParameters...)
+	}
 
-	process, err := s.terraformCommand(args, []int{0})
+	process, err := s.terraformCommand(args)
 	if err != nil {
 		return nil, err
 	}
 
-	err = process.Run()
+	err = s.runTerraform("apply", process)
+
+	// Terraform may have written state even if the apply failed
+	if persistErr := s.persistLocalState(); persistErr != nil {
+		if err != nil {
+			s.config.log().Errorf("%v", persistErr)
+		} else {
+			err = persistErr
+		}
+	}
 
 	return &terraformResult{
 		process: process,
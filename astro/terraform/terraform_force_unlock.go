@@ -0,0 +1,118 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// lockIDPattern matches the ID in the lock info that Terraform prints when
+// it can't acquire the state lock, e.g.:
+//
+//	Lock Info:
+//	  ID:        4a0d7c3e-4b3d-2b5a-6e5d-1e4f1c8c5a2b
+var lockIDPattern = regexp.MustCompile(`Lock Info:\s+ID:\s+(\S+)`)
+
+// lockErrorPattern matches the errors of Terraform commands that failed to
+// acquire the state lock.
+var lockErrorPattern = regexp.MustCompile(`Error acquiring the state lock|Error locking state`)
+
+// IsStateLockError returns whether the output is of a Terraform command
+// that failed because the state is locked.
+func IsStateLockError(output string) bool {
+	return lockErrorPattern.MatchString(output)
+}
+
+// lockInfoFieldPattern matches a field of the lock info, e.g.
+// "  Path:      states/app.tfstate".
+var lockInfoFieldPattern = regexp.MustCompile(`^\s+(ID|Path|Operation|Who):\s+(\S.*?)\s*$`)
+
+// LockInfo is the lock info that Terraform prints when it can't acquire the
+// state lock, about the holder of the lock.
+type LockInfo struct {
+	// ID is the ID of the lock, for force-unlock.
+	ID string
+	// Path is where the locked state is stored, e.g. "bucket/app.tfstate".
+	Path string
+	// Operation is what the holder is doing, e.g. "OperationTypeApply".
+	Operation string
+	// Who is the user and host of the holder, e.g. "deploy@ci-1".
+	Who string
+}
+
+// ParseLockInfo returns the lock info in the output of a Terraform command
+// that failed to acquire the state lock, and whether there was any.
+func ParseLockInfo(output string) (LockInfo, bool) {
+	start := strings.Index(output, "Lock Info:")
+	if start < 0 {
+		return LockInfo{}, false
+	}
+
+	info := LockInfo{}
+	for _, line := range strings.Split(output[start:], "\n")[1:] {
+		match := lockInfoFieldPattern.FindStringSubmatch(line)
+		if match == nil {
+			if strings.TrimSpace(line) == "" || !strings.HasPrefix(line, " ") {
+				break
+			}
+			continue
+		}
+		switch match[1] {
+		case "ID":
+			info.ID = match[2]
+		case "Path":
+			info.Path = match[2]
+		case "Operation":
+			info.Operation = match[2]
+		case "Who":
+			info.Who = match[2]
+		}
+	}
+	return info, info.ID != ""
+}
+
+// LockID returns the ID of the state lock in the output of a Terraform
+// command that failed to acquire it, or an empty string.
+func LockID(output string) string {
+	match := lockIDPattern.FindStringSubmatch(output)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// ForceUnlock runs a `terraform force-unlock` of the state lock with the
+// ID, e.g. a lock left behind by an apply that was killed.
+func (s *Session) ForceUnlock(lockID string) (Result, error) {
+	if !s.Initialized() {
+		if result, err := s.Init(); err != nil {
+			return result, err
+		}
+	}
+
+	process, err := s.terraformCommand([]string{"force-unlock", "-force", lockID})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.runTerraform("force-unlock", process)
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
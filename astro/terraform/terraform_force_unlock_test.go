@@ -0,0 +1,90 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockID(t *testing.T) {
+	stderr := `
+Error: Error acquiring the state lock
+
+Error message: ConditionalCheckFailedException: The conditional request
+failed
+Lock Info:
+  ID:        4a0d7c3e-4b3d-2b5a-6e5d-1e4f1c8c5a2b
+  Path:      states/app/dev.tfstate
+  Operation: OperationTypeApply
+  Who:       ci@runner
+`
+	assert.Equal(t, "4a0d7c3e-4b3d-2b5a-6e5d-1e4f1c8c5a2b", LockID(stderr))
+	assert.Equal(t, "", LockID("Error: Invalid provider configuration"))
+
+	info, ok := ParseLockInfo(stderr)
+	require.True(t, ok)
+	assert.Equal(t, LockInfo{
+		ID:        "4a0d7c3e-4b3d-2b5a-6e5d-1e4f1c8c5a2b",
+		Path:      "states/app/dev.tfstate",
+		Operation: "OperationTypeApply",
+		Who:       "ci@runner",
+	}, info)
+	_, ok = ParseLockInfo("Error: Invalid provider configuration")
+	assert.False(t, ok)
+
+	assert.True(t, IsStateLockError(stderr))
+	assert.True(t, IsStateLockError("Error locking state: Error acquiring the state lock: ConditionalCheckFailedException"))
+	assert.False(t, IsStateLockError("Error: Invalid provider configuration"))
+}
+
+func TestForceUnlock(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-force-unlock-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	terraformTmpDir := filepath.Join(tmpdir, "tmp")
+	for _, dir := range []string{codeRoot, terraformTmpDir} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/args.yaml")
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+		TempDir:       terraformTmpDir,
+	})
+	require.NoError(t, err)
+
+	_, err = session.ForceUnlock("4a0d7c3e")
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-force-unlock"))
+	require.NoError(t, err)
+	assert.Equal(t, "force-unlock -force 4a0d7c3e\n", string(b))
+}
@@ -18,12 +18,12 @@ package terraform
 
 // Get runs `terraform get`
 func (s *Session) Get() (Result, error) {
-	process, err := s.terraformCommand([]string{"get"}, []int{0})
+	process, err := s.terraformCommand([]string{"get"})
 	if err != nil {
 		return nil, err
 	}
 
-	err = process.Run()
+	err = s.runTerraform("get", process)
 
 	return &terraformResult{
 		process: process,
@@ -0,0 +1,62 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+// Import runs a `terraform import` of the existing resource with the id
+// into the resource address, e.g. "aws_instance.web".
+func (s *Session) Import(address, id string) (Result, error) {
+	if err := s.restoreLocalState(); err != nil {
+		return nil, err
+	}
+
+	if !s.Initialized() {
+		if result, err := s.Init(); err != nil {
+			return result, err
+		}
+	}
+
+	args := []string{"import"}
+
+	variableArgs, err := s.variableArgs()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, variableArgs...)
+
+	args = append(args, s.config.TerraformParameters...)
+	args = append(args, address, id)
+
+	process, err := s.terraformCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.runTerraform("import", process)
+
+	// Terraform may have written state even if the import failed
+	if persistErr := s.persistLocalState(); persistErr != nil {
+		if err != nil {
+			s.config.log().Errorf("%v", persistErr)
+		} else {
+			err = persistErr
+		}
+	}
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
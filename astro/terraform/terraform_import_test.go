@@ -0,0 +1,67 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-import-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	terraformTmpDir := filepath.Join(tmpdir, "tmp")
+	for _, dir := range []string{codeRoot, terraformTmpDir} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	// Mock Terraform that records the arguments of the import
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	spec := "version: 0.12.6\ncommands:\n  import:\n    write:\n      '{{env \"TMPDIR\"}}/terraform-args': \"{{join .Args \\\" \\\"}}\"\n"
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(spec), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:                "app",
+		BasePath:            codeRoot,
+		ModulePath:          ".",
+		TerraformPath:       terraformPath,
+		TempDir:             terraformTmpDir,
+		TerraformParameters: []string{"-lock=false"},
+		Variables: map[string]string{
+			"region": "us-east-1",
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = session.Import("aws_instance.web", "i-0123456789")
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-args"))
+	require.NoError(t, err)
+	assert.Equal(t, "import -var region=us-east-1 -lock=false aws_instance.web i-0123456789", string(b))
+}
@@ -17,11 +17,11 @@
 package terraform
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"path/filepath"
 
-	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/utils"
 )
 
@@ -47,8 +47,16 @@ func (s *Session) terraformInitArgsModern() ([]string, error) {
 	}
 
 	// Backend config parameters are permitted, however
-	for key, val := range s.config.Remote.BackendConfig {
-		args = append(args, fmt.Sprintf("-backend-config=%s=%s", key, val))
+	args = append(args, backendConfigArgs(s.config.Remote.BackendConfig)...)
+
+	if s.config.LockFileReadonly {
+		terraformVersion, err := s.versionCached()
+		if err != nil {
+			return nil, err
+		}
+		if VersionMatches(terraformVersion, ">= 0.14") {
+			args = append(args, "-lockfile=readonly")
+		}
 	}
 
 	// Input is a new option that means Terraform will return an
@@ -59,11 +67,20 @@ func (s *Session) terraformInitArgsModern() ([]string, error) {
 	return args, nil
 }
 
+// backendConfigArgs returns the command line arguments to pass the backend
+// configuration to `terraform init`.
+func backendConfigArgs(backendConfig map[string]string) (args []string) {
+	for key, val := range backendConfig {
+		args = append(args, fmt.Sprintf("-backend-config=%s=%s", key, val))
+	}
+	return args
+}
+
 // Init initializes a Terraform module. This needs to happen before other
 // commands like "plan" and "apply" can be called. See:
 // https://www.terraform.io/docs/commands/init.html
 func (s *Session) Init() (Result, error) {
-	logger.Trace.Printf("terraform: initializing module in directory: %v\n", s.moduleDir)
+	s.config.log().Debugf("terraform: initializing module in directory: %v\n", s.moduleDir)
 
 	terraformVersion, err := s.versionCached()
 	if err != nil {
@@ -90,23 +107,61 @@ func (s *Session) Init() (Result, error) {
 		}
 	}
 
-	process, err := s.terraformCommand(args, []int{0})
+	// Restore the result of an earlier init with the same inputs
+	var cacheKey string
+	if s.config.InitCacheDir != "" && VersionMatches(terraformVersion, ">= 0.9") {
+		cacheKey, err = s.initCacheKey(terraformVersion)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute init cache key: %v", err)
+		}
+		restored, err := s.restoreInitCache(cacheKey)
+		if err != nil {
+			s.config.log().Debugf("terraform: unable to restore init from cache: %v", err)
+		} else if restored {
+			s.initCached = true
+			return s.Get()
+		}
+	}
+
+	lockFile, err := s.readLockFile()
+	if err != nil {
+		return nil, err
+	}
+
+	process, err := s.terraformCommand(args)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := process.Run(); err != nil {
-		logger.Trace.Printf("terraform: init failed: %v\n", err)
+	if err := s.runTerraform("init", process); err != nil {
+		s.config.log().Debugf("terraform: init failed: %v\n", err)
 		return &terraformResult{
 			process: process,
 		}, err
 	}
 
+	newLockFile, err := s.readLockFile()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(lockFile, newLockFile) {
+		s.lockFileChanged = true
+	}
+
+	if cacheKey != "" {
+		if err := s.saveInitCache(cacheKey); err != nil {
+			s.config.log().Debugf("terraform: unable to save init to cache: %v", err)
+		}
+	}
+
 	return s.Get()
 }
 
 // Initialized returns whether or not `terraform init` has been run.
 func (s *Session) Initialized() bool {
+	if s.dataDir != "" {
+		return utils.IsDirectory(s.dataDir)
+	}
 	terraformSpecialDir := filepath.Join(s.moduleDir, ".terraform")
 	return utils.IsDirectory(terraformSpecialDir)
 }
@@ -18,11 +18,61 @@ package terraform
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
+
+	version "github.com/burl/go-version"
 )
 
+// planChangesFormats extracts the changes from the output of `terraform
+// plan` for versions of Terraform that don't support `terraform show` for
+// this. The first format whose version constraint matches and whose regexp
+// matches the output is used. To support a new output format, add it to the
+// list.
+var planChangesFormats = []struct {
+	versionConstraint string
+	regexp            *regexp.Regexp
+}{
+	// 0.12 to 0.14 end the changes with a line of dashes
+	{">= 0.12, < 0.15", regexp.MustCompile(`(?s)Terraform will perform the following actions:(.*)-{72}`)},
+	// 0.15 and later end the changes with a horizontal rule
+	{">= 0.15", regexp.MustCompile(`(?s)Terraform will perform the following actions:(.*)\n─{72,}`)},
+}
+
+// parsePlanChanges returns the changes in the output of `terraform plan`, or
+// false if the output doesn't match any known format.
+func parsePlanChanges(terraformVersion *version.Version, output string) (string, bool) {
+	for _, format := range planChangesFormats {
+		if !VersionMatches(terraformVersion, format.versionConstraint) {
+			continue
+		}
+		if match := format.regexp.FindStringSubmatch(output); len(match) == 2 {
+			return match[1], true
+		}
+	}
+	return "", false
+}
+
+// VerifyPlan runs a `terraform plan` after an apply, to check that the
+// apply left no changes. Resources in Config.Replace, which the apply
+// replaced, aren't replaced again.
+func (s *Session) VerifyPlan() (Result, error) {
+	replace := s.config.Replace
+	s.config.Replace = nil
+	defer func() {
+		s.config.Replace = replace
+	}()
+	return s.Plan()
+}
+
 // Plan runs a `terraform plan`
 func (s *Session) Plan() (Result, error) {
+	if err := s.restoreLocalState(); err != nil {
+		return nil, err
+	}
+
 	if !s.Initialized() {
 		if result, err := s.Init(); err != nil {
 			return result, err
@@ -31,28 +81,55 @@ func (s *Session) Plan() (Result, error) {
 
 	args := []string{"plan", "-detailed-exitcode", fmt.Sprintf("-out=%s.plan", s.id)}
 
-	for key, val := range s.config.Variables {
-		args = append(args, "-var", fmt.Sprintf("%s=%s", key, val))
+	// State locking was added in Terraform 0.9. Taking the lock writes to
+	// the backend, so it is skipped in read-only mode.
+	if s.config.ReadOnly {
+		terraformVersion, err := s.versionCached()
+		if err != nil {
+			return nil, err
+		}
+		if VersionMatches(terraformVersion, ">= 0.9") {
+			args = append(args, "-lock=false")
+		}
+	}
+
+	variableArgs, err := s.variableArgs()
+	if err != nil {
+		return nil, err
 	}
+	args = append(args, variableArgs...)
+	args = append(args, s.replaceArgs()...)
 
 	args = append(args, s.config.TerraformParameters...)
 
-	process, err := s.terraformCommand(args, []int{0, 2})
+	process, err := s.terraformCommand(args)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := process.Run(); err != nil {
+	if err := s.runTerraform("plan", process); err != nil {
 		return &terraformResult{
 			process: process,
 		}, err
 	}
 
-	var changes string
+	if s.config.FileMode != 0 {
+		planFile := filepath.Join(s.moduleDir, fmt.Sprintf("%s.plan", s.id))
+		if err := os.Chmod(planFile, s.config.FileMode); err != nil && !os.IsNotExist(err) {
+			return &terraformResult{
+				process: process,
+			}, fmt.Errorf("unable to set mode of plan file: %v", err)
+		}
+	}
+
+	var changes, parseWarning string
+	var planJSON []byte
+	var addresses *resourceAddresses
 
-	// With -detailed-exitcode, plans that return exit code 2 mean there
-	// are changes (so there's no error).
-	if process.ExitCode() == 2 {
+	// With -detailed-exitcode, plans that return exit code 2, unless it is
+	// remapped, mean there are changes (so there's no error).
+	hasChanges := process.ExitCode() == s.config.ExitCodes.PlanChangesCode()
+	if hasChanges {
 		// Fetch changes
 		terraformVersion, err := s.versionCached()
 		if err != nil {
@@ -65,22 +142,129 @@ func (s *Session) Plan() (Result, error) {
 			}
 			changes = result.Stdout()
 		} else {
-			rawPlanOutput := process.Stdout().String()
-			var re = regexp.MustCompile(`(?s)Terraform will perform the following actions:(.*)-{72}`)
-			if match := re.FindStringSubmatch(rawPlanOutput); len(match) == 2 {
-				changes = match[1]
-			} else {
+			// The changes are read from the plan as JSON, and the text of
+			// the changes is only used to show them
+			planJSON, err = s.showPlanJSON()
+			if err != nil && s.config.SavePlanJSON {
 				return &terraformResult{
 					process: process,
-				}, fmt.Errorf("unable to parse terraform plan output")
+				}, err
+			} else if err != nil {
+				s.config.log().Debugf("terraform: %v; counting changes from the plan output instead", err)
+			} else if addresses, err = parseResourceAddresses(planJSON); err != nil {
+				s.config.log().Debugf("terraform: %v; counting changes from the plan output instead", err)
+			}
+
+			rawPlanOutput := process.Stdout().String()
+			var ok bool
+			if changes, ok = parsePlanChanges(terraformVersion, rawPlanOutput); !ok {
+				// The plan itself succeeded, so show the whole output
+				// rather than failing the execution. Only the text is
+				// affected if the plan could be read as JSON.
+				changes = rawPlanOutput
+				if addresses == nil {
+					logFile := filepath.Join(s.logDir, "plan.log")
+					s.config.log().Debugf("terraform: unable to parse plan output for Terraform %v, see: %v", terraformVersion, logFile)
+					parseWarning = fmt.Sprintf("unable to parse the output of Terraform %v plan, showing it in full; please report this, including the output in %v", terraformVersion, logFile)
+				}
 			}
 		}
 	}
 
-	return &PlanResult{
+	result := &PlanResult{
 		terraformResult: &terraformResult{
 			process: process,
 		},
-		changes: changes,
-	}, nil
+		changes:           changes,
+		hasChanges:        hasChanges,
+		parseWarning:      parseWarning,
+		planJSON:          planJSON,
+		resourceAddresses: addresses,
+	}
+
+	if len(s.config.SecretPatterns) > 0 {
+		result.redactSecrets(s.config.SecretPatterns)
+		if s.config.RedactLogs {
+			if err := s.redactSessionFiles(); err != nil {
+				return result, fmt.Errorf("unable to redact secrets from session files: %v", err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// redactSessionFiles redacts secrets from the files of the plan in the
+// session: the logs of the commands that print it, and its JSON.
+func (s *Session) redactSessionFiles() error {
+	for _, path := range []string{
+		filepath.Join(s.logDir, "plan.log"),
+		filepath.Join(s.logDir, "show.log"),
+		filepath.Join(s.moduleDir, fmt.Sprintf("%s.plan.json", s.id)),
+	} {
+		if err := redactSecretsInFile(path, s.config.SecretPatterns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// showPlanJSON returns the plan of the session as JSON. If
+// Config.SavePlanJSON is set, it is also written next to the plan file.
+func (s *Session) showPlanJSON() ([]byte, error) {
+	planFile := fmt.Sprintf("%s.plan", s.id)
+	result, err := s.ShowJSON(planFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to show plan as JSON: %v", err)
+	}
+	planJSON := []byte(result.Stdout())
+	if !s.config.SavePlanJSON {
+		return planJSON, nil
+	}
+
+	mode := s.config.FileMode
+	if mode == 0 {
+		mode = 0666
+	}
+	if err := ioutil.WriteFile(filepath.Join(s.moduleDir, planFile+".json"), planJSON, mode); err != nil {
+		return nil, fmt.Errorf("unable to save JSON plan: %v", err)
+	}
+
+	return planJSON, nil
+}
+
+// WritePlanForCheck writes the plan of the result, which Plan returned, to
+// a file in the sandbox for plan checks: as JSON for Terraform 0.12 and
+// later, and as the text of `terraform show` otherwise. It returns the path
+// of the file.
+func (s *Session) WritePlanForCheck(result *PlanResult) (string, error) {
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return "", err
+	}
+
+	planFile := fmt.Sprintf("%s.plan", s.id)
+	var content []byte
+	if VersionMatches(terraformVersion, "<0.12") {
+		planFile += ".txt"
+		content = []byte(result.Changes())
+	} else {
+		planFile += ".json"
+		content = result.planJSON
+		if content == nil {
+			if content, err = s.showPlanJSON(); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	mode := s.config.FileMode
+	if mode == 0 {
+		mode = 0666
+	}
+	path := filepath.Join(s.moduleDir, planFile)
+	if err := ioutil.WriteFile(path, content, mode); err != nil {
+		return "", fmt.Errorf("unable to write the plan for plan_check: %v", err)
+	}
+	return path, nil
 }
@@ -0,0 +1,315 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	version "github.com/burl/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestParsePlanChanges(t *testing.T) {
+	tt := []struct {
+		fixture string
+		version string
+		ok      bool
+	}{
+		{fixture: "0.12.29.txt", version: "0.12.29", ok: true},
+		{fixture: "0.13.7.txt", version: "0.13.7", ok: true},
+		{fixture: "0.14.11.txt", version: "0.14.11", ok: true},
+		{fixture: "1.0.11.txt", version: "1.0.11", ok: true},
+		{fixture: "1.5.7.txt", version: "1.5.7", ok: true},
+		// plans that only change outputs have no list of actions
+		{fixture: "1.5.7-outputs-only.txt", version: "1.5.7", ok: false},
+		// output is parsed with the format for the version that produced it
+		{fixture: "1.5.7.txt", version: "0.12.29", ok: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.fixture+"@"+tc.version, func(t *testing.T) {
+			output, err := ioutil.ReadFile(filepath.Join("fixtures/plan-output", tc.fixture))
+			require.NoError(t, err)
+
+			terraformVersion, err := version.NewVersion(tc.version)
+			require.NoError(t, err)
+
+			changes, ok := parsePlanChanges(terraformVersion, string(output))
+			require.Equal(t, tc.ok, ok)
+			if !ok {
+				return
+			}
+
+			assert.True(t, strings.HasPrefix(strings.TrimSpace(changes), "# null_resource.foo will be created"))
+			assert.Contains(t, changes, "Plan: 1 to add, 0 to change, 0 to destroy.")
+			assert.NotContains(t, changes, "-----")
+			assert.NotContains(t, changes, "───")
+			assert.NotContains(t, changes, "test.plan")
+		})
+	}
+}
+
+func TestPlanWithUnparsedOutput(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-plan-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	terraformPath, err := filepath.Abs("fixtures/mock-terraform/unparsed-plan")
+	require.NoError(t, err)
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+	})
+	require.NoError(t, err)
+
+	result, err := session.Plan()
+	require.NoError(t, err)
+
+	planResult, ok := result.(*PlanResult)
+	require.True(t, ok)
+	assert.True(t, planResult.HasChanges())
+	assert.Contains(t, planResult.Changes(), `+ foo = "bar"`)
+	assert.Contains(t, planResult.ParseWarning(), "unable to parse the output of Terraform 1.5.7 plan")
+	assert.Contains(t, planResult.ParseWarning(), filepath.Join(tmpdir, "session", "logs", "plan.log"))
+}
+
+func TestPlanUsesTempDir(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-plan-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	terraformTmpDir := filepath.Join(tmpdir, "tmp")
+	for _, dir := range []string{codeRoot, terraformTmpDir} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/tmpdir.yaml")
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+		TempDir:       terraformTmpDir,
+	})
+	require.NoError(t, err)
+
+	_, err = session.Plan()
+	require.NoError(t, err)
+
+	for _, command := range []string{"init", "plan"} {
+		b, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-"+command))
+		require.NoError(t, err)
+		assert.Equal(t, terraformTmpDir+"\n", string(b))
+	}
+}
+
+func TestPlanFileMode(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-plan-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	terraformPath, err := filepath.Abs("fixtures/mock-terraform/plan-file")
+	require.NoError(t, err)
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+		DirMode:       0700,
+		FileMode:      0600,
+	})
+	require.NoError(t, err)
+
+	_, err = session.Plan()
+	require.NoError(t, err)
+
+	for path, mode := range map[string]os.FileMode{
+		"session":                  0700,
+		"session/logs":             0700,
+		"session/logs/plan.log":    0600,
+		"session/sandbox/app.plan": 0600,
+	} {
+		info, err := os.Stat(filepath.Join(tmpdir, path))
+		require.NoError(t, err)
+		assert.Equal(t, mode, info.Mode().Perm(), path)
+	}
+}
+
+func TestParseResourceChanges(t *testing.T) {
+	for _, fixture := range []string{"0.12.29.txt", "1.5.7.txt"} {
+		output, err := ioutil.ReadFile(filepath.Join("fixtures/plan-output", fixture))
+		require.NoError(t, err)
+
+		changes, ok := parseResourceChanges(string(output))
+		require.True(t, ok, fixture)
+		assert.Equal(t, ResourceChanges{Add: 1}, changes, fixture)
+	}
+
+	changes, ok := parseResourceChanges("Plan: 2 to import, 3 to add, 1 to change, 2 to destroy.\n")
+	require.True(t, ok)
+	assert.Equal(t, ResourceChanges{Add: 3, Change: 1, Destroy: 2}, changes)
+	assert.Equal(t, 6, changes.Total())
+
+	// plans that only change outputs don't change resources
+	output, err := ioutil.ReadFile("fixtures/plan-output/1.5.7-outputs-only.txt")
+	require.NoError(t, err)
+	changes, ok = parseResourceChanges(string(output))
+	require.True(t, ok)
+	assert.Equal(t, 0, changes.Total())
+
+	_, ok = parseResourceChanges("Error: something went wrong\n")
+	assert.False(t, ok)
+}
+
+func TestParseResourceAddresses(t *testing.T) {
+	tt := []struct {
+		fixture string
+		add     []string
+		change  []string
+		destroy []string
+	}{
+		{
+			fixture: "0.12.29.json",
+			add:     []string{"null_resource.bar", "null_resource.foo"},
+			change:  []string{},
+			destroy: []string{"null_resource.bar", "null_resource.old"},
+		},
+		{
+			fixture: "0.13.7.json",
+			add:     []string{"module.app.aws_security_group.web"},
+			change:  []string{"module.app.aws_instance.web[0]"},
+			destroy: []string{"module.app.aws_instance.web[1]", "module.app.aws_security_group.web"},
+		},
+		{
+			fixture: "1.5.7.json",
+			add:     []string{"null_resource.foo", "null_resource.tainted"},
+			change:  []string{},
+			destroy: []string{"null_resource.old", "null_resource.tainted"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.fixture, func(t *testing.T) {
+			planJSON, err := ioutil.ReadFile(filepath.Join("fixtures/plan-json", tc.fixture))
+			require.NoError(t, err)
+
+			addresses, err := parseResourceAddresses(planJSON)
+			require.NoError(t, err)
+			assert.Equal(t, tc.add, addresses.add)
+			assert.Equal(t, tc.change, addresses.change)
+			assert.Equal(t, tc.destroy, addresses.destroy)
+		})
+	}
+
+	_, err := parseResourceAddresses([]byte("Terraform v0.11.14"))
+	assert.Error(t, err)
+}
+
+func TestParseChangeKinds(t *testing.T) {
+	for fixture, expected := range map[string]ChangeKinds{
+		"0.12.29.json": {Resources: true},
+		"0.13.7.json":  {Resources: true, Reads: true},
+	} {
+		planJSON, err := ioutil.ReadFile(filepath.Join("fixtures/plan-json", fixture))
+		require.NoError(t, err)
+
+		kinds, err := parseChangeKinds(planJSON)
+		require.NoError(t, err, fixture)
+		assert.Equal(t, expected, kinds, fixture)
+	}
+
+	kinds, err := parseChangeKinds([]byte(`{"output_changes": {"foo": {"actions": ["create"]}, "bar": {"actions": ["no-op"]}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, ChangeKinds{Outputs: true}, kinds)
+
+	// changes that are neither resources nor outputs only refresh the state
+	kinds, err = parseChangeKinds([]byte(`{"resource_changes": [{"address": "null_resource.foo", "change": {"actions": ["no-op"]}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, ChangeKinds{Reads: true}, kinds)
+
+	_, err = parseChangeKinds([]byte("Terraform v0.11.14"))
+	assert.Error(t, err)
+}
+
+func TestParseChangeKindsFromOutput(t *testing.T) {
+	for fixture, expected := range map[string]ChangeKinds{
+		"0.12.29.txt":            {Resources: true, Approximate: true},
+		"1.5.7-outputs-only.txt": {Outputs: true, Approximate: true},
+	} {
+		output, err := ioutil.ReadFile(filepath.Join("fixtures/plan-output", fixture))
+		require.NoError(t, err)
+		assert.Equal(t, expected, parseChangeKindsFromOutput(string(output)), fixture)
+	}
+
+	assert.Equal(t, ChangeKinds{Reads: true, Approximate: true}, parseChangeKindsFromOutput(" <= data.aws_ami.web\n\nPlan: 0 to add, 0 to change, 0 to destroy.\n"))
+
+	// changes that can't be classified count as resources
+	assert.Equal(t, ChangeKinds{Resources: true, Approximate: true}, parseChangeKindsFromOutput("Error: something went wrong\n"))
+}
+
+func TestPlanReadsJSON(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-plan-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/plan-json.yaml")
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+	})
+	require.NoError(t, err)
+
+	result, err := session.Plan()
+	require.NoError(t, err)
+	planResult := result.(*PlanResult)
+
+	// The changes are counted from the JSON, not from the text, which
+	// can't be parsed because of the warning
+	assert.Empty(t, planResult.ParseWarning())
+	assert.Contains(t, planResult.Changes(), "Warning: Argument is deprecated")
+	changes, ok := planResult.ResourceChanges()
+	require.True(t, ok)
+	assert.Equal(t, ResourceChanges{Add: 2, Change: 0, Destroy: 1}, changes)
+	assert.Equal(t, []string{"null_resource.bar", "null_resource.foo"}, planResult.AddedResources())
+	assert.Equal(t, []string{}, planResult.ChangedResources())
+	assert.Equal(t, []string{"null_resource.bar"}, planResult.DestroyedResources())
+}
@@ -0,0 +1,80 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	version "github.com/burl/go-version"
+)
+
+// refreshArgs returns the arguments that update the state from the real
+// infrastructure without changing it. `terraform refresh` is deprecated in
+// Terraform 0.15.4 and later, in favor of `terraform apply -refresh-only`.
+func refreshArgs(terraformVersion *version.Version) []string {
+	if VersionMatches(terraformVersion, ">= 0.15.4") {
+		return []string{"apply", "-refresh-only", "-auto-approve"}
+	}
+	return []string{"refresh"}
+}
+
+// Refresh runs a `terraform refresh`, or `terraform apply -refresh-only` on
+// versions where refresh is deprecated.
+func (s *Session) Refresh() (Result, error) {
+	if err := s.restoreLocalState(); err != nil {
+		return nil, err
+	}
+
+	if !s.Initialized() {
+		if result, err := s.Init(); err != nil {
+			return result, err
+		}
+	}
+
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return nil, err
+	}
+
+	args := refreshArgs(terraformVersion)
+
+	variableArgs, err := s.variableArgs()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, variableArgs...)
+
+	args = append(args, s.config.TerraformParameters...)
+
+	process, err := s.terraformCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.runTerraform(args[0], process)
+
+	// Terraform may have written state even if the refresh failed
+	if persistErr := s.persistLocalState(); persistErr != nil {
+		if err != nil {
+			s.config.log().Errorf("%v", persistErr)
+		} else {
+			err = persistErr
+		}
+	}
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
@@ -0,0 +1,81 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefresh(t *testing.T) {
+	tt := []struct {
+		version string
+		args    string
+	}{
+		{"0.7.13", "refresh"},
+		{"0.11.7", "refresh"},
+		{"0.15.3", "refresh"},
+		{"0.15.4", "apply -refresh-only -auto-approve"},
+		{"1.5.7", "apply -refresh-only -auto-approve"},
+	}
+
+	for _, test := range tt {
+		t.Run(test.version, func(t *testing.T) {
+			tmpdir, err := ioutil.TempDir("", "astro-refresh-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(tmpdir)
+
+			codeRoot := filepath.Join(tmpdir, "code")
+			terraformTmpDir := filepath.Join(tmpdir, "tmp")
+			for _, dir := range []string{codeRoot, terraformTmpDir} {
+				require.NoError(t, os.Mkdir(dir, 0755))
+			}
+
+			// Mock Terraform of the version under test that records the
+			// arguments of the command that writes state
+			specPath := filepath.Join(tmpdir, "spec.yaml")
+			spec := fmt.Sprintf("version: %s\ndefault:\n  write:\n    '{{env \"TMPDIR\"}}/terraform-args': \"{{join .Args \\\" \\\"}}\"\n", test.version)
+			require.NoError(t, ioutil.WriteFile(specPath, []byte(spec), 0644))
+			terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+			session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+				Name:                "app",
+				BasePath:            codeRoot,
+				ModulePath:          ".",
+				TerraformPath:       terraformPath,
+				TempDir:             terraformTmpDir,
+				TerraformParameters: []string{"-lock=false"},
+			})
+			require.NoError(t, err)
+
+			_, err = session.Refresh()
+			require.NoError(t, err)
+
+			b, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-args"))
+			require.NoError(t, err)
+			assert.Equal(t, test.args+" -lock=false", string(b))
+		})
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/utils"
 )
 
@@ -31,7 +30,14 @@ import (
 // The purpose of Detach is to allow safe, local testing of changes to the
 // state file, without pushing anything to the remote.
 func (s *Session) Detach() (Result, error) {
-	logger.Trace.Printf("terraform: detaching remote state in %v", s.moduleDir)
+	s.config.log().Debugf("terraform: detaching remote state in %v", s.moduleDir)
+
+	if s.config.ReadOnly {
+		return nil, errors.New("refusing to detach remote state in read-only mode")
+	}
+	if s.SharesSandbox() {
+		return nil, errors.New("cannot detach remote state in a shared sandbox")
+	}
 
 	var res Result
 	var err error
@@ -66,7 +72,7 @@ func (s *Session) Detach() (Result, error) {
 }
 
 func (s *Session) detachLegacy() (Result, error) {
-	detachCmd, err := s.terraformCommand([]string{"remote", "config", "-disable"}, []int{0})
+	detachCmd, err := s.terraformCommand([]string{"remote", "config", "-disable"})
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +93,7 @@ func (s *Session) detachModern() (Result, error) {
 		return nil, err
 	}
 
-	reinit, err := s.terraformCommand([]string{"init", "-force-copy"}, []int{0})
+	reinit, err := s.terraformCommand([]string{"init", "-force-copy"})
 	if err != nil {
 		return nil, err
 	}
@@ -126,6 +132,7 @@ func (s *Session) deleteBackendConfig() error {
 	}
 
 	for _, f := range candidates {
+		s.config.log().Debugf("terraform: deleting backend config from %v", f)
 		if err := deleteTerraformBackendConfigFromFile(f, terraformVersion); err != nil {
 			return err
 		}
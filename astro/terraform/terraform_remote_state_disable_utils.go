@@ -24,8 +24,6 @@ import (
 	"os"
 	"regexp"
 
-	"github.com/uber/astro/astro/logger"
-
 	version "github.com/burl/go-version"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
@@ -123,7 +121,6 @@ func deleteTerraformBackendConfig(in []byte, v *version.Version) (updatedConfig
 }
 
 func deleteTerraformBackendConfigFromFile(file string, v *version.Version) error {
-	logger.Trace.Printf("terraform: deleting backend config from %v", file)
 	b, err := ioutil.ReadFile(file)
 	if err != nil {
 		return err
@@ -21,7 +21,22 @@ func (s *Session) Show(planFile string) (Result, error) {
 	args := []string{"show"}
 	args = append(args, planFile)
 
-	process, err := s.terraformCommand(args, []int{0})
+	process, err := s.terraformCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = process.Run()
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
+
+// ShowJSON runs a `terraform show -json`, which prints the plan as JSON. It
+// requires Terraform 0.12 or later.
+func (s *Session) ShowJSON(planFile string) (Result, error) {
+	process, err := s.terraformCommand([]string{"show", "-json", planFile})
 	if err != nil {
 		return nil, err
 	}
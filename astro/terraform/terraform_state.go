@@ -0,0 +1,55 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+// State runs `terraform state` with the arguments, starting with its
+// subcommand, e.g. "list" or "mv", and returns its output.
+func (s *Session) State(args ...string) (Result, error) {
+	if err := s.restoreLocalState(); err != nil {
+		return nil, err
+	}
+
+	if !s.Initialized() {
+		if result, err := s.Init(); err != nil {
+			return result, err
+		}
+	}
+
+	args = append([]string{"state"}, args...)
+
+	process, err := s.terraformCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = process.Run()
+
+	// Terraform may have written state even if the command failed
+	if IsMutatingCommand(args) {
+		if persistErr := s.persistLocalState(); persistErr != nil {
+			if err != nil {
+				s.config.log().Errorf("%v", persistErr)
+			} else {
+				err = persistErr
+			}
+		}
+	}
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
@@ -0,0 +1,178 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/uber/astro/astro/exec2"
+)
+
+// StateMigrationStatus is the outcome of migrating state with MigrateState.
+type StateMigrationStatus int
+
+const (
+	// StateMigrated means that the state was copied from the previous
+	// backend location to the current one.
+	StateMigrated StateMigrationStatus = iota
+	// StateAlreadyMigrated means that there already is state at the current
+	// backend location, so nothing was done.
+	StateAlreadyMigrated
+	// StateNothingToMigrate means that there is no state at either location.
+	StateNothingToMigrate
+)
+
+// MigrateState copies the state of the module from the backend location
+// described by previousBackendConfig to the current one, if there is no
+// state at the current location yet. The session must have been initialized
+// with Init first; it is initialized with the current backend configuration
+// again when MigrateState returns successfully.
+//
+// The state pulled from the previous location is kept in the session as
+// state-migration/previous.tfstate.
+func (s *Session) MigrateState(previousBackendConfig map[string]string) (StateMigrationStatus, Result, error) {
+	s.config.log().Debugf("terraform: checking whether state needs to be migrated in directory: %v\n", s.moduleDir)
+
+	if s.config.ReadOnly {
+		return 0, nil, errors.New("refusing to migrate state in read-only mode")
+	}
+
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return 0, nil, err
+	}
+	if VersionMatches(terraformVersion, "< 0.9") {
+		return 0, nil, fmt.Errorf("state migration requires Terraform 0.9 or later; using %v", terraformVersion)
+	}
+
+	process, err := s.stateMigrationCommand("state-migration-pull", "state", "pull")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := process.Run(); err != nil {
+		return 0, &terraformResult{process: process}, err
+	}
+	empty, err := stateIsEmpty(process.Stdout().Bytes())
+	if err != nil {
+		return 0, &terraformResult{process: process}, fmt.Errorf("unable to read current state: %v", err)
+	}
+	if !empty {
+		return StateAlreadyMigrated, &terraformResult{process: process}, nil
+	}
+
+	// Switch to the previous backend location to pull its state
+	args := append([]string{"init", "-reconfigure", "-input=false"}, backendConfigArgs(previousBackendConfig)...)
+	process, err = s.stateMigrationCommand("state-migration-init-previous", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := process.Run(); err != nil {
+		return 0, &terraformResult{process: process}, err
+	}
+
+	process, err = s.stateMigrationCommand("state-migration-pull-previous", "state", "pull")
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := process.Run(); err != nil {
+		return 0, &terraformResult{process: process}, err
+	}
+	previousState := process.Stdout().Bytes()
+
+	stateDir := filepath.Join(s.baseDir, "state-migration")
+	if err := os.MkdirAll(stateDir, s.config.DirMode); err != nil {
+		return 0, nil, err
+	}
+	previousStateFile, err := filepath.Abs(filepath.Join(stateDir, "previous.tfstate"))
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := ioutil.WriteFile(previousStateFile, previousState, 0600); err != nil {
+		return 0, nil, err
+	}
+
+	// Switch back to the current backend location
+	args = append([]string{"init", "-reconfigure", "-input=false"}, backendConfigArgs(s.config.Remote.BackendConfig)...)
+	process, err = s.stateMigrationCommand("state-migration-init", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := process.Run(); err != nil {
+		return 0, &terraformResult{process: process}, err
+	}
+
+	empty, err = stateIsEmpty(previousState)
+	if err != nil {
+		return 0, nil, fmt.Errorf("unable to read previous state: %v", err)
+	}
+	if empty {
+		return StateNothingToMigrate, &terraformResult{process: process}, nil
+	}
+
+	// The current state has no resources, but it may still exist with a
+	// different lineage, so the push has to be forced.
+	process, err = s.stateMigrationCommand("state-migration-push", "state", "push", "-force", previousStateFile)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := process.Run(); err != nil {
+		return 0, &terraformResult{process: process}, err
+	}
+
+	return StateMigrated, &terraformResult{process: process}, nil
+}
+
+// stateMigrationCommand returns a Terraform command that logs to its own log
+// file, so that the logs of the regular init aren't overwritten.
+func (s *Session) stateMigrationCommand(logfileName string, args ...string) (*exec2.Process, error) {
+	return s.command(logfileName, s.config.TerraformPath, args, s.config.ExitCodes.SuccessCodes(args[0]))
+}
+
+// stateIsEmpty returns whether the output of `terraform state pull` has no
+// resources in it. Terraform prints nothing when there is no state at all.
+func stateIsEmpty(state []byte) (bool, error) {
+	if len(state) == 0 {
+		return true, nil
+	}
+
+	var parsed struct {
+		// version 4 and later
+		Resources []json.RawMessage `json:"resources"`
+		// version 3 and earlier
+		Modules []struct {
+			Resources map[string]json.RawMessage `json:"resources"`
+		} `json:"modules"`
+	}
+	if err := json.Unmarshal(state, &parsed); err != nil {
+		return false, err
+	}
+
+	if len(parsed.Resources) > 0 {
+		return false, nil
+	}
+	for _, module := range parsed.Modules {
+		if len(module.Resources) > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
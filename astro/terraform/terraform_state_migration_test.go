@@ -0,0 +1,112 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+)
+
+const testState = `{"version": 4, "serial": 3, "resources": [{"type": "null_resource", "name": "app"}]}`
+
+func TestStateIsEmpty(t *testing.T) {
+	for state, expected := range map[string]bool{
+		``: true,
+		`{"version": 4, "serial": 1, "resources": []}`: true,
+		testState: false,
+		`{"version": 3, "modules": [{"path": ["root"], "resources": {}}]}`:               true,
+		`{"version": 3, "modules": [{"path": ["root"], "resources": {"null.app": {}}}]}`: false,
+	} {
+		empty, err := stateIsEmpty([]byte(state))
+		require.NoError(t, err)
+		assert.Equal(t, expected, empty, state)
+	}
+
+	_, err := stateIsEmpty([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestMigrateState(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-state-migration-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.Mkdir(codeRoot, 0755))
+
+	terraformPath, err := filepath.Abs("fixtures/mock-terraform/state-migration")
+	require.NoError(t, err)
+
+	previousStatePath := filepath.Join(tmpdir, "previous.tfstate")
+	currentStatePath := filepath.Join(tmpdir, "current.tfstate")
+
+	newSession := func(name string) *Session {
+		session, err := NewTerraformSession(name, filepath.Join(tmpdir, name), Config{
+			Name:          "app",
+			BasePath:      codeRoot,
+			ModulePath:    ".",
+			TerraformPath: terraformPath,
+			Remote: conf.Remote{
+				BackendConfig: map[string]string{"path": currentStatePath},
+			},
+		})
+		require.NoError(t, err)
+		_, err = session.Init()
+		require.NoError(t, err)
+		return session
+	}
+
+	previousBackendConfig := map[string]string{"path": previousStatePath}
+
+	// nothing at either location
+	status, _, err := newSession("empty").MigrateState(previousBackendConfig)
+	require.NoError(t, err)
+	assert.Equal(t, StateNothingToMigrate, status)
+	assert.False(t, utils.FileExists(currentStatePath))
+
+	// state at the previous location is copied to the current one
+	require.NoError(t, ioutil.WriteFile(previousStatePath, []byte(testState), 0644))
+
+	status, _, err = newSession("migrate").MigrateState(previousBackendConfig)
+	require.NoError(t, err)
+	assert.Equal(t, StateMigrated, status)
+
+	b, err := ioutil.ReadFile(currentStatePath)
+	require.NoError(t, err)
+	assert.Equal(t, testState, string(b))
+
+	b, err = ioutil.ReadFile(filepath.Join(tmpdir, "migrate", "state-migration", "previous.tfstate"))
+	require.NoError(t, err)
+	assert.Equal(t, testState, string(b))
+
+	// the session is left initialized with the current backend
+	b, err = ioutil.ReadFile(filepath.Join(tmpdir, "migrate", "sandbox", ".terraform", "backend-path"))
+	require.NoError(t, err)
+	assert.Equal(t, currentStatePath+"\n", string(b))
+
+	// once migrated, the current state is left alone
+	status, _, err = newSession("again").MigrateState(previousBackendConfig)
+	require.NoError(t, err)
+	assert.Equal(t, StateAlreadyMigrated, status)
+}
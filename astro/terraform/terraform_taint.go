@@ -0,0 +1,82 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+
+	version "github.com/burl/go-version"
+)
+
+// TaintDeprecated returns whether `terraform taint` is deprecated in the
+// version, in favor of `terraform apply -replace=<address>`.
+func TaintDeprecated(terraformVersion *version.Version) bool {
+	return VersionMatches(terraformVersion, ">= 0.15.2")
+}
+
+// replaceArgs returns the -replace arguments of plan and apply for the
+// resources in Config.Replace.
+func (s *Session) replaceArgs() []string {
+	args := []string{}
+	for _, address := range s.config.Replace {
+		args = append(args, fmt.Sprintf("-replace=%s", address))
+	}
+	return args
+}
+
+// Taint runs a `terraform taint` of the resource address, so that the
+// next apply replaces it.
+func (s *Session) Taint(address string) (Result, error) {
+	return s.taint("taint", address)
+}
+
+// Untaint runs a `terraform untaint` of the resource address.
+func (s *Session) Untaint(address string) (Result, error) {
+	return s.taint("untaint", address)
+}
+
+func (s *Session) taint(command, address string) (Result, error) {
+	if err := s.restoreLocalState(); err != nil {
+		return nil, err
+	}
+
+	if !s.Initialized() {
+		if result, err := s.Init(); err != nil {
+			return result, err
+		}
+	}
+
+	process, err := s.terraformCommand([]string{command, address})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.runTerraform(command, process)
+
+	// Terraform may have written state even if the command failed
+	if persistErr := s.persistLocalState(); persistErr != nil {
+		if err != nil {
+			s.config.log().Errorf("%v", persistErr)
+		} else {
+			err = persistErr
+		}
+	}
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
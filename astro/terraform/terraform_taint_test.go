@@ -0,0 +1,118 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	version "github.com/burl/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaintDeprecated(t *testing.T) {
+	for v, expected := range map[string]bool{
+		"0.12.6":  false,
+		"0.15.1":  false,
+		"0.15.2":  true,
+		"1.0.0":   true,
+		"1.5.7":   true,
+		"0.11.14": false,
+	} {
+		assert.Equal(t, expected, TaintDeprecated(version.Must(version.NewVersion(v))), v)
+	}
+}
+
+func TestTaint(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-taint-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	terraformTmpDir := filepath.Join(tmpdir, "tmp")
+	for _, dir := range []string{codeRoot, terraformTmpDir} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/args.yaml")
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+		TempDir:       terraformTmpDir,
+		Variables: map[string]string{
+			"region": "us-east-1",
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = session.Taint("null_resource.foo")
+	require.NoError(t, err)
+	_, err = session.Untaint("null_resource.bar")
+	require.NoError(t, err)
+
+	// Variables aren't passed, as taint and untaint don't take them
+	b, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-taint"))
+	require.NoError(t, err)
+	assert.Equal(t, "taint null_resource.foo\n", string(b))
+
+	b, err = ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-untaint"))
+	require.NoError(t, err)
+	assert.Equal(t, "untaint null_resource.bar\n", string(b))
+}
+
+func TestReplace(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-replace-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	terraformTmpDir := filepath.Join(tmpdir, "tmp")
+	for _, dir := range []string{codeRoot, terraformTmpDir} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/args.yaml")
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+		TempDir:       terraformTmpDir,
+		Replace:       []string{"null_resource.foo", "null_resource.bar"},
+	})
+	require.NoError(t, err)
+
+	_, err = session.Plan()
+	require.NoError(t, err)
+	_, err = session.Apply()
+	require.NoError(t, err)
+
+	for _, subcommand := range []string{"plan", "apply"} {
+		b, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-"+subcommand))
+		require.NoError(t, err)
+		assert.Contains(t, string(b), "-replace=null_resource.foo -replace=null_resource.bar", subcommand)
+	}
+}
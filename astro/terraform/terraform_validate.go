@@ -0,0 +1,140 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"strings"
+
+	version "github.com/burl/go-version"
+)
+
+// checklistItemPrefix starts the items of `terraform 0.12checklist` that
+// need to be addressed before upgrading.
+const checklistItemPrefix = "- [ ] "
+
+// initWithoutBackend initializes the module without configuring its
+// backend, so that remote state is neither read nor written. Before 0.9,
+// where init copies a module from a source, `terraform get` is used.
+func (s *Session) initWithoutBackend(terraformVersion *version.Version) (Result, error) {
+	args := []string{"get"}
+	if VersionMatches(terraformVersion, ">= 0.9") {
+		args = []string{"init", "-backend=false", "-input=false"}
+	}
+
+	process, err := s.terraformCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.runTerraform(args[0], process)
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
+
+// Validate checks the code of the module with `terraform validate`. The
+// module is initialized without its backend first, so it can be used to
+// check code against a different Terraform version than the state was
+// written with.
+func (s *Session) Validate() (Result, error) {
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return nil, err
+	}
+
+	if result, err := s.initWithoutBackend(terraformVersion); err != nil {
+		return result, err
+	}
+
+	args := []string{"validate"}
+	if VersionMatches(terraformVersion, ">= 0.11") {
+		args = append(args, "-no-color")
+	}
+	// Before 0.12, validate also requires values for all variables
+	if VersionMatches(terraformVersion, ">= 0.10, < 0.12") {
+		args = append(args, "-check-variables=false")
+	}
+
+	process, err := s.terraformCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.runTerraform(args[0], process)
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
+
+// HasChecklist012 returns whether the Terraform version of the session has
+// the `0.12checklist` command, which was added in 0.11.14.
+func (s *Session) HasChecklist012() (bool, error) {
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return false, err
+	}
+	return VersionMatches(terraformVersion, ">= 0.11.14, < 0.12"), nil
+}
+
+// Checklist012 runs `terraform 0.12checklist`, and returns the steps it
+// recommends before upgrading the module to Terraform 0.12, if any. The
+// module is initialized without its backend first.
+func (s *Session) Checklist012() ([]string, Result, error) {
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if result, err := s.initWithoutBackend(terraformVersion); err != nil {
+		return nil, result, err
+	}
+
+	process, err := s.terraformCommand([]string{"0.12checklist"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &terraformResult{
+		process: process,
+	}
+	if err := process.Run(); err != nil {
+		return nil, result, err
+	}
+
+	return parseChecklist012(process.Stdout().String()), result, nil
+}
+
+// parseChecklist012 returns the items of the output of `terraform
+// 0.12checklist`. Items can span several indented lines, which are joined.
+func parseChecklist012(output string) []string {
+	items := []string{}
+	inItem := false
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, checklistItemPrefix):
+			items = append(items, strings.TrimSpace(strings.TrimPrefix(line, checklistItemPrefix)))
+			inItem = true
+		case inItem && strings.HasPrefix(line, "  ") && strings.TrimSpace(line) != "":
+			items[len(items)-1] += " " + strings.TrimSpace(line)
+		default:
+			inItem = false
+		}
+	}
+	return items
+}
@@ -0,0 +1,124 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChecklist012(t *testing.T) {
+	assert.Empty(t, parseChecklist012(`Looks good! We did not detect any problems that ought to be
+addressed before upgrading to Terraform v0.12.
+`))
+
+	assert.Equal(t, []string{
+		`Upgrade provider "aws" to version 2.7.0 or newer.`,
+		`Provider "template" is not compatible with Terraform v0.12 and must be upgraded first.`,
+		`Remove the deprecated "vpc_id" argument.`,
+	}, parseChecklist012(`After analyzing this configuration and working directory, we have identified
+some necessary steps that we recommend you take before upgrading to Terraform
+v0.12:
+
+- [ ] Upgrade provider "aws" to version 2.7.0 or newer.
+- [ ] Provider "template" is not compatible with Terraform v0.12 and
+  must be upgraded first.
+- [ ] Remove the deprecated "vpc_id" argument.
+
+  Taking these steps before upgrading to Terraform v0.12 will simplify the
+upgrade process.
+`))
+}
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		version  string
+		exitCode int
+		commands []string
+	}{
+		{"0.8.8", 0, []string{"get", "validate"}},
+		{"0.10.8", 0, []string{"init -backend=false -input=false", "validate -check-variables=false"}},
+		{"0.11.14", 0, []string{"init -backend=false -input=false", "validate -no-color -check-variables=false"}},
+		{"0.12.31", 0, []string{"init -backend=false -input=false", "validate -no-color"}},
+		{"0.12.31", 1, []string{"init -backend=false -input=false", "validate -no-color"}},
+	}
+
+	for _, test := range tt {
+		t.Run(fmt.Sprintf("%s exit %d", test.version, test.exitCode), func(t *testing.T) {
+			tmpdir, err := ioutil.TempDir("", "astro-validate-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(tmpdir)
+
+			codeRoot := filepath.Join(tmpdir, "code")
+			terraformTmpDir := filepath.Join(tmpdir, "tmp")
+			for _, dir := range []string{codeRoot, terraformTmpDir} {
+				require.NoError(t, os.Mkdir(dir, 0755))
+			}
+
+			// Mock Terraform of the version under test that records the
+			// arguments of each command
+			specPath := filepath.Join(tmpdir, "spec.yaml")
+			spec := fmt.Sprintf(`version: %s
+commands:
+  validate:
+    exit_code: %d
+    stderr: "Error: Unsupported argument\n"
+    write:
+      '{{env "TMPDIR"}}/terraform-validate': "{{join .Args \" \"}}"
+default:
+  write:
+    '{{env "TMPDIR"}}/terraform-{{.Subcommand}}': "{{join .Args \" \"}}"
+`, test.version, test.exitCode)
+			require.NoError(t, ioutil.WriteFile(specPath, []byte(spec), 0644))
+			terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+			session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+				Name:          "app",
+				BasePath:      codeRoot,
+				ModulePath:    ".",
+				TerraformPath: terraformPath,
+				TempDir:       terraformTmpDir,
+				ReadOnly:      true,
+			})
+			require.NoError(t, err)
+
+			result, err := session.Validate()
+			if test.exitCode == 0 {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Equal(t, "Error: Unsupported argument\n", result.Stderr())
+			}
+
+			for _, command := range test.commands {
+				subcommand := strings.Fields(command)[0]
+				b, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-"+subcommand))
+				require.NoError(t, err)
+				assert.Equal(t, command, string(b))
+			}
+		})
+	}
+}
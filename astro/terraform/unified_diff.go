@@ -0,0 +1,173 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// diffContextLines is the number of unchanged lines around each change in
+// a unified diff, the same as the default of `diff -u`.
+const diffContextLines = 3
+
+// diffLine is a line in a diff: unchanged (' '), removed ('-') or added
+// ('+').
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// splitDiffLines splits text into lines for diffing. Empty text has no
+// lines.
+func splitDiffLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script from a to b, based on their
+// longest common subsequence. Like `diff`, removed lines come before the
+// lines added in their place.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []diffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+// hunkRange formats the start and length of a hunk the way `diff -u` does:
+// the length is left out when it is 1, and an empty range starts at the
+// line before it.
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	default:
+		return fmt.Sprintf("%d,%d", start, length)
+	}
+}
+
+// unifiedDiff returns a diff of before and after in the format of
+// `diff -u`, without the need for a diff program. If colors is set, removed
+// and added lines and hunk headers are colored like colordiff does.
+func unifiedDiff(before, after []byte, colors bool) []byte {
+	au := aurora.NewAurora(colors)
+	lines := diffLines(splitDiffLines(before), splitDiffLines(after))
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n%s\n", au.Red("--- before"), au.Green("+++ after"))
+
+	// The line numbers in before and after at the start of each line
+	aLine := make([]int, len(lines)+1)
+	bLine := make([]int, len(lines)+1)
+	aLine[0], bLine[0] = 1, 1
+	for n, line := range lines {
+		aLine[n+1], bLine[n+1] = aLine[n], bLine[n]
+		if line.op != '+' {
+			aLine[n+1]++
+		}
+		if line.op != '-' {
+			bLine[n+1]++
+		}
+	}
+
+	for n := 0; n < len(lines); n++ {
+		if lines[n].op == ' ' {
+			continue
+		}
+
+		// Start a hunk, and extend it for as long as the next change is
+		// close enough for their context lines to overlap
+		start := n - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		last := n
+		for m := n + 1; m < len(lines) && m <= last+2*diffContextLines+1; m++ {
+			if lines[m].op != ' ' {
+				last = m
+			}
+		}
+		end := last + diffContextLines + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		header := fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(aLine[start], aLine[end]-aLine[start]),
+			hunkRange(bLine[start], bLine[end]-bLine[start]))
+		fmt.Fprintln(&out, au.Cyan(header))
+		for _, line := range lines[start:end] {
+			text := string(line.op) + line.text
+			switch line.op {
+			case '-':
+				fmt.Fprintln(&out, au.Red(text))
+			case '+':
+				fmt.Fprintln(&out, au.Green(text))
+			default:
+				fmt.Fprintln(&out, text)
+			}
+		}
+		n = end - 1
+	}
+
+	return out.Bytes()
+}
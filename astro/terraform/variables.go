@@ -0,0 +1,145 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+
+	version "github.com/burl/go-version"
+)
+
+// variableArgs returns the -var arguments for the variables of the session,
+// sorted by name.
+func (s *Session) variableArgs() ([]string, error) {
+	names := []string{}
+	for name := range s.config.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var terraformVersion *version.Version
+	args := []string{}
+
+	for _, name := range names {
+		val := s.config.Variables[name]
+
+		if variableType := s.config.VariableTypes[name]; variableType == conf.VariableTypeList || variableType == conf.VariableTypeMap {
+			if terraformVersion == nil {
+				v, err := s.versionCached()
+				if err != nil {
+					return nil, err
+				}
+				terraformVersion = v
+			}
+
+			// List and map variables were added in Terraform 0.7.
+			if !VersionMatches(terraformVersion, ">= 0.7") {
+				return nil, fmt.Errorf("variable %q: %s variables require Terraform 0.7 or later", name, variableType)
+			}
+
+			hclVal, err := hclValue(variableType, val, VersionMatches(terraformVersion, ">= 0.12"))
+			if err != nil {
+				return nil, fmt.Errorf("variable %q: %v", name, err)
+			}
+			val = hclVal
+		}
+
+		args = append(args, "-var", fmt.Sprintf("%s=%s", name, val))
+	}
+
+	return args, nil
+}
+
+// hclValue converts the JSON encoded value of a list or map variable to the
+// HCL syntax Terraform expects on the command line. Terraform 0.12 and later
+// parse it as HCL2, which treats "${" and "%{" in strings as templates.
+func hclValue(variableType, val string, hcl2 bool) (string, error) {
+	switch variableType {
+	case conf.VariableTypeList:
+		items := []string{}
+		if err := json.Unmarshal([]byte(val), &items); err != nil {
+			return "", fmt.Errorf("invalid list value %q: %v", val, err)
+		}
+
+		quoted := []string{}
+		for _, item := range items {
+			quoted = append(quoted, hclString(item, hcl2))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(quoted, ", ")), nil
+	case conf.VariableTypeMap:
+		entries := map[string]string{}
+		if err := json.Unmarshal([]byte(val), &entries); err != nil {
+			return "", fmt.Errorf("invalid map value %q: %v", val, err)
+		}
+
+		keys := []string{}
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := []string{}
+		for _, key := range keys {
+			pairs = append(pairs, fmt.Sprintf("%s = %s", hclString(key, hcl2), hclString(entries[key], hcl2)))
+		}
+		return fmt.Sprintf("{%s}", strings.Join(pairs, ", ")), nil
+	}
+	return val, nil
+}
+
+// hclString returns s as a quoted HCL string.
+func hclString(s string, hcl2 bool) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	if hcl2 {
+		quoted = strings.NewReplacer("${", "$${", "%{", "%%{").Replace(quoted)
+	}
+	return quoted
+}
+
+// variableItems returns the individual values of a variable, e.g. each item
+// of a list variable, so that they can be masked in logs wherever they appear.
+func variableItems(variableType, val string) []string {
+	switch variableType {
+	case conf.VariableTypeList:
+		items := []string{}
+		if err := json.Unmarshal([]byte(val), &items); err == nil {
+			return items
+		}
+	case conf.VariableTypeMap:
+		entries := map[string]string{}
+		if err := json.Unmarshal([]byte(val), &entries); err == nil {
+			items := []string{}
+			for _, item := range entries {
+				items = append(items, item)
+			}
+			sort.Strings(items)
+			return items
+		}
+	}
+	return []string{val}
+}
@@ -0,0 +1,94 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func TestHCLValue(t *testing.T) {
+	tt := []struct {
+		variableType string
+		value        string
+		hcl2         bool
+		expected     string
+	}{
+		{"list", `["a","b"]`, false, `["a", "b"]`},
+		{"list", `[]`, true, `[]`},
+		{"list", `["say \"hi\"","${x}"]`, false, `["say \"hi\"", "${x}"]`},
+		{"list", `["${x}","%{y}"]`, true, `["$${x}", "%%{y}"]`},
+		{"map", `{"b":"2","a":"1"}`, false, `{"a" = "1", "b" = "2"}`},
+		{"map", `{"a":"${x}"}`, true, `{"a" = "$${x}"}`},
+		{"string", `["a"]`, true, `["a"]`},
+	}
+
+	for _, test := range tt {
+		val, err := hclValue(test.variableType, test.value, test.hcl2)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, val, "%s %s (hcl2: %v)", test.variableType, test.value, test.hcl2)
+	}
+
+	_, err := hclValue("list", `{"a":"b"}`, true)
+	assert.Error(t, err)
+}
+
+func TestPlanPassesListAndMapVariables(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-variables-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	terraformTmpDir := filepath.Join(tmpdir, "tmp")
+	for _, dir := range []string{codeRoot, terraformTmpDir} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), "fixtures/mock-terraform/args.yaml")
+
+	session, err := NewTerraformSession("app", filepath.Join(tmpdir, "session"), Config{
+		Name:          "app",
+		BasePath:      codeRoot,
+		ModulePath:    ".",
+		TerraformPath: terraformPath,
+		TempDir:       terraformTmpDir,
+		Variables: map[string]string{
+			"region": "us-east-1",
+			"tags":   `{"team":"core"}`,
+			"zones":  `["us-east-1a","us-east-1b"]`,
+		},
+		VariableTypes: map[string]string{
+			"tags":  "map",
+			"zones": "list",
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = session.Plan()
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadFile(filepath.Join(terraformTmpDir, "terraform-plan"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `-var region=us-east-1 -var tags={"team" = "core"} -var zones=["us-east-1a", "us-east-1b"]`)
+}
@@ -0,0 +1,167 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+)
+
+// cliConfigFile is the name of the Terraform CLI config file that astro
+// generates in each session from terraform_cli_config.
+const cliConfigFile = "terraformrc"
+
+// terraformCLIConfigFile returns the path to the Terraform CLI config file
+// of the session, and generates it the first time. It returns an empty
+// string if terraform_cli_config isn't set.
+func (session *Session) terraformCLIConfigFile() (string, error) {
+	config := session.repo.project.config.TerraformCLIConfig
+	if !config.IsSet() {
+		return "", nil
+	}
+
+	session.cliConfigOnce.Do(func() {
+		path := filepath.Join(session.path, cliConfigFile)
+
+		b, err := renderTerraformCLIConfig(config, os.Getenv(conf.CLIConfigFileEnv), os.LookupEnv, session.log())
+		if err != nil {
+			session.cliConfigErr = err
+			return
+		}
+
+		// The file has the credentials in it, so only the user can read it
+		if err := ioutil.WriteFile(path, b, 0600); err != nil {
+			session.cliConfigErr = err
+			return
+		}
+		session.cliConfigPath = path
+	})
+
+	return session.cliConfigPath, session.cliConfigErr
+}
+
+// renderTerraformCLIConfig returns the Terraform CLI config of config. If
+// basePath is set, e.g. to a config with a provider mirror that was
+// generated beforehand, its content comes first, and settings that it has
+// already are left out, so that they aren't overridden. Tokens are read
+// with lookupEnv, and warnings go to log. The output only depends on the
+// inputs, so that sessions are reproducible.
+func renderTerraformCLIConfig(config conf.TerraformCLIConfig, basePath string, lookupEnv func(string) (string, bool), log logger.Logger) ([]byte, error) {
+	var base []byte
+	if basePath != "" {
+		var err error
+		base, err = ioutil.ReadFile(basePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %v", conf.CLIConfigFileEnv, err)
+		}
+	}
+
+	// baseHas returns whether the base config has the setting, and warns
+	// that it is left out if so
+	baseHas := func(setting string, pattern string) bool {
+		if !regexp.MustCompile(`(?m)^\s*` + pattern).Match(base) {
+			return false
+		}
+		log.Warningf("astro: %s already sets %s; leaving out the one in terraform_cli_config", basePath, setting)
+		return true
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "# Generated by astro from terraform_cli_config")
+	if len(base) > 0 {
+		fmt.Fprintf(&out, "\n# From %s\n", basePath)
+		out.Write(base)
+		if !bytes.HasSuffix(base, []byte("\n")) {
+			fmt.Fprintln(&out)
+		}
+	}
+
+	if config.PluginCacheDir != "" && !baseHas("plugin_cache_dir", `plugin_cache_dir\s*=`) {
+		fmt.Fprintf(&out, "\nplugin_cache_dir = %s\n", hclString(config.PluginCacheDir))
+	}
+
+	hosts := []string{}
+	for host := range config.Credentials {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if baseHas("the credentials of "+host, `credentials\s+"`+regexp.QuoteMeta(host)+`"`) {
+			continue
+		}
+		tokenEnv := config.Credentials[host].TokenEnv
+		token, ok := lookupEnv(tokenEnv)
+		if !ok || token == "" {
+			return nil, fmt.Errorf("the token of %s: environment variable %s is not set", host, tokenEnv)
+		}
+		fmt.Fprintf(&out, "\ncredentials %s {\n  token = %s\n}\n", hclString(host), hclString(token))
+	}
+
+	if helper := config.CredentialsHelper; helper != nil && !baseHas("credentials_helper", `credentials_helper\s`) {
+		fmt.Fprintf(&out, "\ncredentials_helper %s {\n", hclString(helper.Name))
+		if len(helper.Args) > 0 {
+			fmt.Fprintf(&out, "  args = %s\n", hclList(helper.Args))
+		}
+		fmt.Fprintln(&out, "}")
+	}
+
+	if len(config.ProviderInstallation) > 0 && !baseHas("provider_installation", `provider_installation\s`) {
+		fmt.Fprintln(&out, "\nprovider_installation {")
+		for _, method := range config.ProviderInstallation {
+			fmt.Fprintf(&out, "  %s {\n", method.Method)
+			if method.Path != "" {
+				fmt.Fprintf(&out, "    path = %s\n", hclString(method.Path))
+			}
+			if method.URL != "" {
+				fmt.Fprintf(&out, "    url = %s\n", hclString(method.URL))
+			}
+			if len(method.Include) > 0 {
+				fmt.Fprintf(&out, "    include = %s\n", hclList(method.Include))
+			}
+			if len(method.Exclude) > 0 {
+				fmt.Fprintf(&out, "    exclude = %s\n", hclList(method.Exclude))
+			}
+			fmt.Fprintln(&out, "  }")
+		}
+		fmt.Fprintln(&out, "}")
+	}
+
+	return out.Bytes(), nil
+}
+
+// hclString returns s as an HCL string.
+func hclString(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+// hclList returns the strings as an HCL list.
+func hclList(strs []string) string {
+	quoted := []string{}
+	for _, s := range strs {
+		quoted = append(quoted, hclString(s))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
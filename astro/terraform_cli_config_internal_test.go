@@ -0,0 +1,169 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTerraformCLIConfig(t *testing.T) {
+	config := conf.TerraformCLIConfig{
+		Credentials: map[string]conf.TerraformCredentials{
+			"registry.example.com": {TokenEnv: "REGISTRY_TOKEN"},
+			"app.terraform.io":     {TokenEnv: "TFC_TOKEN"},
+		},
+		CredentialsHelper: &conf.TerraformCredentialsHelper{Name: "vault", Args: []string{"--path=tf"}},
+		ProviderInstallation: []conf.TerraformProviderInstallation{
+			{Method: "filesystem_mirror", Path: "/opt/providers", Include: []string{"example.com/*/*"}},
+			{Method: "direct", Exclude: []string{"example.com/*/*"}},
+		},
+		PluginCacheDir: "/var/cache/terraform",
+	}
+	env := map[string]string{
+		"REGISTRY_TOKEN": "registry-secret",
+		"TFC_TOKEN":      "tfc-secret",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	expected := `# Generated by astro from terraform_cli_config
+
+plugin_cache_dir = "/var/cache/terraform"
+
+credentials "app.terraform.io" {
+  token = "tfc-secret"
+}
+
+credentials "registry.example.com" {
+  token = "registry-secret"
+}
+
+credentials_helper "vault" {
+  args = ["--path=tf"]
+}
+
+provider_installation {
+  filesystem_mirror {
+    path = "/opt/providers"
+    include = ["example.com/*/*"]
+  }
+  direct {
+    exclude = ["example.com/*/*"]
+  }
+}
+`
+	// The output is the same every time
+	for i := 0; i < 3; i++ {
+		b, err := renderTerraformCLIConfig(config, "", lookupEnv, logger.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(b))
+	}
+
+	// Settings of a base config aren't overridden
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	basePath := filepath.Join(tmpdir, "mirror.tfrc")
+	require.NoError(t, ioutil.WriteFile(basePath, []byte("provider_installation {\n  network_mirror {\n    url = \"https://mirror.example.com/\"\n  }\n}\n"), 0644))
+
+	b, err := renderTerraformCLIConfig(config, basePath, lookupEnv, logger.Discard)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "# From "+basePath+"\nprovider_installation {\n  network_mirror {")
+	assert.Contains(t, string(b), `credentials "app.terraform.io"`)
+	assert.NotContains(t, string(b), "filesystem_mirror")
+
+	// Tokens must be set
+	delete(env, "TFC_TOKEN")
+	_, err = renderTerraformCLIConfig(config, "", lookupEnv, logger.Discard)
+	assert.EqualError(t, err, "the token of app.terraform.io: environment variable TFC_TOKEN is not set")
+}
+
+func TestTerraformCLIConfigFile(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+	// Terraform that records TF_CLI_CONFIG_FILE of each subcommand
+	envDir := filepath.Join(tmpdir, "env")
+	require.NoError(t, os.MkdirAll(envDir, 0755))
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(fmt.Sprintf(`
+default:
+  write:
+    '%s/{{.Subcommand}}': '{{env "TF_CLI_CONFIG_FILE"}}'
+`, envDir)), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+terraform_cli_config:
+  credentials:
+    registry.example.com:
+      token_env: ASTRO_TEST_REGISTRY_TOKEN
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+`, codeRoot, tmpdir, terraformPath)), 0644))
+
+	os.Setenv("ASTRO_TEST_REGISTRY_TOKEN", "registry-secret")
+	defer os.Unsetenv("ASTRO_TEST_REGISTRY_TOKEN")
+
+	c, err := NewProjectFromConfigFile(configPath)
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+	require.NoError(t, testReadResults(resultChan)["app"].Err())
+
+	// init and plan use the same file in the session
+	initConfigFile, err := ioutil.ReadFile(filepath.Join(envDir, "init"))
+	require.NoError(t, err)
+	planConfigFile, err := ioutil.ReadFile(filepath.Join(envDir, "plan"))
+	require.NoError(t, err)
+	assert.Equal(t, string(initConfigFile), string(planConfigFile))
+	assert.Equal(t, cliConfigFile, filepath.Base(string(planConfigFile)))
+
+	b, err := ioutil.ReadFile(string(planConfigFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "credentials \"registry.example.com\" {\n  token = \"registry-secret\"\n}\n")
+
+	info, err := os.Stat(string(planConfigFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -17,28 +17,42 @@
 package astro
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/uber/astro/astro/tests/mockterraform"
 	"github.com/uber/astro/astro/tvm"
+	"github.com/uber/astro/astro/utils"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// testVersionRepo returns a Terraform version repo with the mock Terraform
+// binaries in the fixtures.
+func testVersionRepo(t *testing.T) TerraformVersionResolver {
+	versionRepo, err := tvm.NewVersionRepo(absolutePath("fixtures/tvm"), "all", "all")
+	require.NoError(t, err)
+	return versionRepo
+}
+
+// versionResolverFunc is a TerraformVersionResolver implemented by a function.
+type versionResolverFunc func(version string) (string, error)
+
+func (f versionResolverFunc) Get(version string) (string, error) {
+	return f(version)
+}
+
 func TestProjectUsesDefaultTerraformVersion(t *testing.T) {
 	t.Parallel()
 
-	c, err := NewProjectFromConfigFile("fixtures/test-terraform-default-version/astro.yaml")
-	require.NoError(t, err)
-
-	// Init test Terraform version repo in fixtures
-	testVersionRepo, err := tvm.NewVersionRepo(absolutePath("fixtures/tvm"), "all", "all")
+	c, err := NewProjectFromConfigFile("fixtures/test-terraform-default-version/astro.yaml", WithTerraformVersionResolver(testVersionRepo(t)))
 	require.NoError(t, err)
 
-	// Install test/mock
-	c.terraformVersions = testVersionRepo
-
 	executions := c.executions(NoExecutionParameters())
 	require.NotEmpty(t, executions)
 
@@ -46,7 +60,7 @@ func TestProjectUsesDefaultTerraformVersion(t *testing.T) {
 	e := executions[0].(*unboundExecution)
 	b, err := e.bind(map[string]string{
 		"aws_region": "test1",
-	})
+	}, nil)
 	require.NoError(t, err)
 
 	// Init session
@@ -65,16 +79,9 @@ func TestProjectUsesDefaultTerraformVersion(t *testing.T) {
 func TestProjectUsesDefaultTerraformPath(t *testing.T) {
 	t.Parallel()
 
-	c, err := NewProjectFromConfigFile("fixtures/test-terraform-default-path/astro.yaml")
+	c, err := NewProjectFromConfigFile("fixtures/test-terraform-default-path/astro.yaml", WithTerraformVersionResolver(testVersionRepo(t)))
 	require.NoError(t, err)
 
-	// Init test Terraform version repo in fixtures
-	testVersionRepo, err := tvm.NewVersionRepo(absolutePath("fixtures/tvm"), "all", "all")
-	require.NoError(t, err)
-
-	// Install test/mock
-	c.terraformVersions = testVersionRepo
-
 	executions := c.executions(NoExecutionParameters())
 	require.NotEmpty(t, executions)
 
@@ -82,7 +89,7 @@ func TestProjectUsesDefaultTerraformPath(t *testing.T) {
 	e := executions[0].(*unboundExecution)
 	b, err := e.bind(map[string]string{
 		"aws_region": "test1",
-	})
+	}, nil)
 	require.NoError(t, err)
 
 	// Init session
@@ -98,6 +105,32 @@ func TestProjectUsesDefaultTerraformPath(t *testing.T) {
 	assert.Equal(t, "0.8.8", version.String())
 }
 
+func TestProjectUsesTerraformVersionResolver(t *testing.T) {
+	t.Parallel()
+
+	requestedVersions := []string{}
+	resolver := versionResolverFunc(func(version string) (string, error) {
+		requestedVersions = append(requestedVersions, version)
+		return "", errors.New("not available")
+	})
+
+	c, err := NewProjectFromConfigFile("fixtures/test-terraform-default-version/astro.yaml", WithTerraformVersionResolver(resolver))
+	require.NoError(t, err)
+
+	executions := c.executions(NoExecutionParameters())
+	require.NotEmpty(t, executions)
+
+	b, err := executions[0].(*unboundExecution).bind(map[string]string{}, nil)
+	require.NoError(t, err)
+
+	session, err := c.sessions.NewSession()
+	require.NoError(t, err)
+
+	_, err = session.newTerraformSession(b)
+	assert.EqualError(t, err, "unable to activate Terraform 0.11.6: not available")
+	assert.Equal(t, []string{"0.11.6"}, requestedVersions)
+}
+
 func TestSharedPluginCache(t *testing.T) {
 	oldVal := os.Getenv("TF_PLUGIN_CACHE_DIR")
 	defer os.Setenv("TF_PLUGIN_CACHE_DIR", oldVal)
@@ -117,6 +150,41 @@ func TestSharedPluginCache(t *testing.T) {
 	assert.Equal(t, map[string]error{
 		"test": nil,
 	}, testResultErrs(testReadResults(resultChan)))
+
+	// the cache is tagged so that archiving tools skip it
+	assert.True(t, utils.FileExists(filepath.Join(c.sessions.Path(), "plugins", cacheDirTagName)))
+}
+
+func TestSharedPluginCacheDir(t *testing.T) {
+	oldVal := os.Getenv("TF_PLUGIN_CACHE_DIR")
+	defer os.Setenv("TF_PLUGIN_CACHE_DIR", oldVal)
+
+	os.Unsetenv("TF_PLUGIN_CACHE_DIR")
+
+	pluginCacheDir, err := ioutil.TempDir("", "astro-plugin-cache-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(pluginCacheDir)
+
+	config, err := NewConfigFromFile("fixtures/test-terraform-shared-plugin-cache/astro.yaml")
+	require.NoError(t, err)
+	config.PluginCacheDir = pluginCacheDir
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+	// the fixture's session repo may have a cache from other tests
+	require.NoError(t, os.RemoveAll(filepath.Join(c.sessions.Path(), "plugins")))
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{
+		"test": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	// the relocated cache is used and tagged, instead of one in the
+	// session repo
+	assert.True(t, utils.FileExists(filepath.Join(pluginCacheDir, cacheDirTagName)))
+	_, err = os.Stat(filepath.Join(c.sessions.Path(), "plugins"))
+	assert.True(t, os.IsNotExist(err))
 }
 
 func TestSharedPluginCachePreservesExisting(t *testing.T) {
@@ -139,3 +207,153 @@ func TestSharedPluginCachePreservesExisting(t *testing.T) {
 		"test": nil,
 	}, testResultErrs(testReadResults(resultChan)))
 }
+
+func TestSandboxInclude(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-sandbox-include/astro.yaml")
+	require.NoError(t, err)
+
+	status, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	// The module source that was left out of the sandbox should be reported
+	var updates []string
+	for len(status) > 0 {
+		updates = append(updates, <-status)
+	}
+	assert.Contains(t, updates, "[app] WARNING: module sources not in sandbox_include: modules/other")
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	sandboxDir := filepath.Join(session.path, "app", "sandbox")
+
+	// Only the module and the included paths should be in the sandbox
+	assert.True(t, utils.FileExists(filepath.Join(sandboxDir, "app/main.tf")))
+	assert.True(t, utils.FileExists(filepath.Join(sandboxDir, "modules/shared/main.tf")))
+	assert.False(t, utils.FileExists(filepath.Join(sandboxDir, "modules/shared-extra")))
+	assert.False(t, utils.FileExists(filepath.Join(sandboxDir, "modules/other")))
+	assert.False(t, utils.FileExists(filepath.Join(sandboxDir, "sibling")))
+}
+
+func TestSandboxExclude(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-sandbox-exclude/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	sandboxDir := filepath.Join(session.path, "app", "sandbox")
+
+	// Paths excluded in the config and in .astroignore are left out
+	assert.True(t, utils.FileExists(filepath.Join(sandboxDir, "app/main.tf")))
+	assert.True(t, utils.FileExists(filepath.Join(sandboxDir, "lambda/index.js")))
+	assert.False(t, utils.FileExists(filepath.Join(sandboxDir, "docs")))
+	assert.False(t, utils.FileExists(filepath.Join(sandboxDir, "lambda/node_modules")))
+
+	// and aren't reported as missing from the sandbox
+	verifications, err := session.VerifySandboxes()
+	require.NoError(t, err)
+	require.Len(t, verifications, 1)
+	assert.Empty(t, verifications[0].SourceOnly)
+}
+
+func TestTerraformCodeRoots(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-code-roots/astro.yaml")
+	require.NoError(t, err)
+
+	status, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	// The module source in the other code root should be in the sandbox
+	for len(status) > 0 {
+		assert.NotContains(t, <-status, "module sources not in sandbox_include")
+	}
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	sandboxDir := filepath.Join(session.path, "app", "sandbox")
+
+	// Both roots are cloned at the same paths relative to each other
+	assert.True(t, utils.FileExists(filepath.Join(sandboxDir, "roots/app/main.tf")))
+	assert.True(t, utils.FileExists(filepath.Join(sandboxDir, "shared-modules/vpc/main.tf")))
+	assert.False(t, utils.FileExists(filepath.Join(sandboxDir, "unrelated")))
+
+	paths, err := session.ExecutionPaths("app")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(sandboxDir, "roots/app"), paths.Sandbox)
+}
+
+func TestLockFileChanged(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, "app"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(codeRoot, "locked"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(codeRoot, "locked", ".terraform.lock.hcl"), []byte("# locked\n"), 0644))
+
+	// init writes the lock file if it's missing
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(`version: 0.14.11
+commands:
+  init:
+    write:
+      "{{.Dir}}/.terraform.lock.hcl": "# locked\n"
+`), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  version: 0.14.11
+modules:
+  - name: app
+    path: app
+    local_state: ephemeral
+  - name: locked
+    path: locked
+    local_state: ephemeral
+`, codeRoot, tmpdir)), 0644))
+
+	resolver := versionResolverFunc(func(version string) (string, error) {
+		return terraformPath, nil
+	})
+	c, err := NewProjectFromConfigFile(configPath, WithTerraformVersionResolver(resolver))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(NoPlanExecutionParameters())
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	assert.Equal(t, map[string]error{
+		"app":    nil,
+		"locked": nil,
+	}, testResultErrs(results))
+	assert.True(t, results["app"].LockFileChanged())
+	assert.False(t, results["locked"].LockFileChanged())
+}
@@ -46,6 +46,7 @@ var (
 		"0.10.8",
 		"0.11.5",
 		"0.12.6",
+		"0.14.11",
 	}
 )
 
@@ -126,9 +127,12 @@ func RunTest(t *testing.T, args []string, fixtureBasePath string, version string
 	stdoutBytes := &bytes.Buffer{}
 	stderrBytes := &bytes.Buffer{}
 
+	// Output is colored as if stdout were a terminal, unless the test
+	// disables colors with --no-color or NO_COLOR
 	cli, err := cmd.NewAstroCLI(
 		cmd.WithStdout(stdoutBytes),
 		cmd.WithStderr(stderrBytes),
+		cmd.WithColors(true),
 	)
 	require.NoError(t, err)
 
@@ -0,0 +1,5 @@
+terraform {
+  backend "local" {}
+}
+
+resource "null_resource" "foo" {}
@@ -37,7 +37,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var noChangesRegexp = `(?s)foo: \x1b\[32mOK\x1b\[0m\x1b\[37m No changes\x1b\[0m\x1b\[37m \(\d{0,2}s\)\x1b\[0m\nDone\n`
+var noChangesRegexp = `(?s)foo: \x1b\[32mOK\x1b\[0m\x1b\[37m No changes\x1b\[0m\x1b\[37m \(\d{0,2}s\)\x1b\[0m\n\n\x1b\[1mSummary:\x1b\[0m\n.*\n1 ok, 0 failed, 0 skipped in \d+s\nDone\n`
 
 // getSessionDirs returns a list of the sessions inside a session repository.
 // This excludes other directories that might have been created in there, e.g.
@@ -146,6 +146,68 @@ func TestPlanInterrupted(t *testing.T) {
 	assert.NotRegexp(t, `bar\d{2}:`, stderr)
 }
 
+func TestPlanHangup(t *testing.T) {
+	fakeTerraformPath := "fixtures/terraform"
+	require.True(t, utils.FileExists(fakeTerraformPath))
+	fakeTerraformDir, err := filepath.Abs(filepath.Dir(fakeTerraformPath))
+	require.NoError(t, err)
+
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", fakeTerraformDir, oldPath))
+	defer os.Setenv("PATH", oldPath)
+
+	tmpdir, err := ioutil.TempDir("", "astro-binary")
+	defer os.RemoveAll(tmpdir)
+	require.NoError(t, err)
+
+	astroBinary, err := compileAstro(tmpdir, []string{})
+	require.NoError(t, err)
+	command := exec.Command(astroBinary, "plan")
+	// make running terraform processes finish shortly after the hangup
+	command.Env = append(os.Environ(), "FAKE_TERRAFORM_WORK_SECONDS=3")
+
+	fixtureAbsPath, err := filepath.Abs("fixtures/plan-interrupted")
+	require.NoError(t, err)
+	command.Dir = fixtureAbsPath
+
+	stdoutBytes := &bytes.Buffer{}
+	stderrBytes := &bytes.Buffer{}
+	command.Stdout = stdoutBytes
+	command.Stderr = stderrBytes
+
+	var cmdErr error
+	processChan := make(chan struct{}, 1)
+	go func() {
+		defer close(processChan)
+		cmdErr = command.Run()
+		processChan <- struct{}{}
+	}()
+
+	// let astro start terraform processes
+	time.Sleep(2000 * time.Millisecond)
+	require.NoError(t, command.Process.Signal(syscall.SIGHUP))
+
+	select {
+	case <-processChan:
+	case <-time.After(15 * time.Second):
+		// force kill the process after timeout
+		require.NoError(t, command.Process.Signal(syscall.SIGKILL))
+	}
+
+	require.Error(t, cmdErr)
+	require.Equal(t, 1, command.ProcessState.ExitCode())
+
+	stdout := stdoutBytes.String()
+	stderr := stderrBytes.String()
+	assert.Contains(t, stdout, "\nReceived signal: hangup, waiting for running operations to finish...\n")
+	assert.Contains(t, stderr, "Stopped; some modules may not have been planned")
+	// modules that were already running finish, but no new ones are started
+	assert.Regexp(t, `foo\d{2}:.*OK`, stdout)
+	assert.NotRegexp(t, `foo\d{2}:.*ERROR`, stderr)
+	assert.NotRegexp(t, `bar\d{2}:`, stdout)
+	assert.NotRegexp(t, `bar\d{2}:`, stderr)
+}
+
 func TestProjectApplyChangesSuccess(t *testing.T) {
 	for _, version := range terraformVersionsToTest {
 		t.Run(version, func(t *testing.T) {
@@ -163,6 +225,28 @@ func TestProjectApplyChangesSuccess(t *testing.T) {
 	}
 }
 
+func TestProjectRefreshSuccess(t *testing.T) {
+	for _, version := range terraformVersionsToTest {
+		t.Run(version, func(t *testing.T) {
+			err := os.RemoveAll("/tmp/terraform-tests/refresh-success")
+			require.NoError(t, err)
+
+			err = os.MkdirAll("/tmp/terraform-tests/refresh-success", 0775)
+			require.NoError(t, err)
+
+			// create the state to refresh
+			result := RunTest(t, []string{"apply"}, "fixtures/refresh-success", version)
+			require.Equal(t, 0, result.ExitCode, result.Stderr.String())
+
+			result = RunTest(t, []string{"refresh"}, "fixtures/refresh-success", version)
+			assert.Contains(t, result.Stdout.String(), "foo: [32mOK")
+			assert.Empty(t, result.Stderr.String())
+			assert.Equal(t, 0, result.ExitCode)
+			assert.FileExists(t, "/tmp/terraform-tests/refresh-success/terraform.tfstate")
+		})
+	}
+}
+
 func TestProjectPlanSuccessNoChanges(t *testing.T) {
 	for _, version := range terraformVersionsToTest {
 		t.Run(version, func(t *testing.T) {
@@ -188,6 +272,25 @@ func TestProjectPlanSuccessChanges(t *testing.T) {
 	}
 }
 
+func TestProjectPlanDetailedExitCode(t *testing.T) {
+	for _, version := range terraformVersionsToTest {
+		t.Run(version, func(t *testing.T) {
+			result := RunTest(t, []string{"plan", "--detailed-exitcode"}, "fixtures/plan-success-nochanges", version)
+			assert.Regexp(t, noChangesRegexp, result.Stdout.String())
+			assert.Equal(t, 0, result.ExitCode)
+
+			result = RunTest(t, []string{"plan", "--detailed-exitcode"}, "fixtures/plan-success-changes", version)
+			assert.Contains(t, result.Stdout.String(), "foo: [32mOK[0m[33m Changes[0m[37m")
+			assert.Contains(t, result.Stderr.String(), "Done; some plans have changes")
+			assert.Equal(t, 2, result.ExitCode)
+
+			result = RunTest(t, []string{"plan", "--detailed-exitcode"}, "fixtures/plan-error", version)
+			assert.Contains(t, result.Stderr.String(), "foo: [31mERROR")
+			assert.Equal(t, 1, result.ExitCode)
+		})
+	}
+}
+
 func TestProjectPlanError(t *testing.T) {
 	for _, version := range terraformVersionsToTest {
 		t.Run(version, func(t *testing.T) {
@@ -203,6 +306,25 @@ func TestProjectPlanError(t *testing.T) {
 	}
 }
 
+func TestProjectPlanLockFile(t *testing.T) {
+	for _, version := range terraformVersionsToTest {
+		if !stringVersionMatches(version, ">=0.14") {
+			continue
+		}
+		t.Run(version, func(t *testing.T) {
+			// Without a lock file in the code, init writes one
+			result := RunTest(t, []string{"plan"}, "fixtures/plan-lockfile", version)
+			assert.Contains(t, result.Stdout.String()+result.Stderr.String(), "WARNING: terraform init changed .terraform.lock.hcl")
+			assert.Equal(t, 0, result.ExitCode)
+
+			// which fails with lockfile: readonly
+			result = RunTest(t, []string{"plan"}, "fixtures/plan-lockfile-readonly", version)
+			assert.Contains(t, result.Stderr.String(), "foo: [31mERROR")
+			assert.Equal(t, 1, result.ExitCode)
+		})
+	}
+}
+
 func TestProjectPlanDetachSuccess(t *testing.T) {
 	for _, version := range terraformVersionsToTest {
 		t.Run(version, func(t *testing.T) {
@@ -0,0 +1,124 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mockterraform
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SpecFile is the name of the spec file the mock Terraform binary reads from
+// its own directory.
+const SpecFile = "mock-terraform.yaml"
+
+var (
+	buildOnce  sync.Once
+	binaryPath string
+	buildErr   error
+)
+
+// build compiles the mock Terraform binary. It is only built once per test
+// run; the binary is left in a temporary directory.
+func build() (string, error) {
+	buildOnce.Do(func() {
+		dir, err := ioutil.TempDir("", "astro-mock-terraform")
+		if err != nil {
+			buildErr = err
+			return
+		}
+
+		// Build from this package's directory, so that it works regardless
+		// of the working directory of the test.
+		_, file, _, _ := runtime.Caller(0)
+		binaryPath = filepath.Join(dir, "terraform")
+		build := exec.Command("go", "build", "-o", binaryPath, "./mock-terraform")
+		build.Dir = filepath.Dir(file)
+		if out, err := build.CombinedOutput(); err != nil {
+			buildErr = fmt.Errorf("unable to build mock Terraform: %v\n%s", err, out)
+		}
+	})
+	return binaryPath, buildErr
+}
+
+// Install puts a mock Terraform binary named "terraform" in dir, along with
+// a copy of the spec at specPath, and returns the path to the binary. The
+// binary is copied rather than linked, as it looks for the spec next to its
+// own path.
+func Install(dir, specPath string) (string, error) {
+	if _, err := LoadSpec(specPath); err != nil {
+		return "", err
+	}
+
+	builtPath, err := build()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	spec, err := ioutil.ReadFile(specPath)
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, SpecFile), spec, 0644); err != nil {
+		return "", err
+	}
+
+	terraformPath := filepath.Join(dir, "terraform")
+	if err := copyExecutable(builtPath, terraformPath); err != nil {
+		return "", err
+	}
+
+	return terraformPath, nil
+}
+
+// InstallForTest is like Install, but fails the test on errors.
+func InstallForTest(t *testing.T, dir, specPath string) string {
+	terraformPath, err := Install(dir, specPath)
+	require.NoError(t, err)
+	return terraformPath
+}
+
+// copyExecutable copies the file at src to dst and makes it executable.
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,44 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// The mock-terraform command is a mock Terraform binary for tests. It reads
+// its spec from the file named mockterraform.SpecFile next to it; use
+// mockterraform.Install to set it up.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+)
+
+func main() {
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mock terraform: %v\n", err)
+		os.Exit(127)
+	}
+
+	spec, err := mockterraform.LoadSpec(filepath.Join(filepath.Dir(executable), mockterraform.SpecFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mock terraform: %v\n", err)
+		os.Exit(127)
+	}
+
+	os.Exit(mockterraform.Run(spec, os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,126 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mockterraform
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+const testSpec = `
+version: 0.12.6
+commands:
+  plan:
+    exit_code: 2
+    stdout: "Plan: {{.Subcommand}} in {{.Dir}}\n"
+    stderr: "{{join .Args \" \"}}\n"
+    touch: ['{{flag "out"}}']
+  apply:
+    sleep: 10ms
+    write:
+      applied: "{{flag \"var\"}}"
+      timing: "{{.Start.UnixNano}} {{(now).UnixNano}}"
+default:
+  exit_code: 1
+`
+
+func writeTestSpec(t *testing.T, dir string) string {
+	specPath := filepath.Join(dir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(testSpec), 0644))
+	return specPath
+}
+
+func TestRun(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-mock-terraform-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	// the working directory the mock sees has symlinks resolved
+	tmpdir, err = filepath.EvalSymlinks(tmpdir)
+	require.NoError(t, err)
+
+	spec, err := LoadSpec(writeTestSpec(t, tmpdir))
+	require.NoError(t, err)
+
+	oldDir, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpdir))
+	defer os.Chdir(oldDir)
+
+	run := func(args ...string) (int, string, string) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		exitCode := Run(spec, args, stdout, stderr)
+		return exitCode, stdout.String(), stderr.String()
+	}
+
+	exitCode, stdout, stderr := run("plan", "-out=app.plan")
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "Plan: plan in "+tmpdir+"\n", stdout)
+	assert.Equal(t, "plan -out=app.plan\n", stderr)
+	assert.True(t, utils.FileExists(filepath.Join(tmpdir, "app.plan")))
+
+	exitCode, _, _ = run("apply", "-var", "region=us-east-1")
+	assert.Equal(t, 0, exitCode)
+	b, err := ioutil.ReadFile(filepath.Join(tmpdir, "applied"))
+	require.NoError(t, err)
+	assert.Equal(t, "region=us-east-1", string(b))
+	b, err = ioutil.ReadFile(filepath.Join(tmpdir, "timing"))
+	require.NoError(t, err)
+	var start, end int64
+	_, err = fmt.Sscan(string(b), &start, &end)
+	require.NoError(t, err)
+	assert.True(t, end-start >= int64(10*time.Millisecond), "%d", end-start)
+
+	exitCode, stdout, _ = run("version")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "Terraform v0.12.6\n", stdout)
+
+	exitCode, _, _ = run("init")
+	assert.Equal(t, 1, exitCode)
+
+	exitCode, _, stderr = run()
+	assert.Equal(t, 127, exitCode)
+	assert.Contains(t, stderr, "no subcommand")
+}
+
+func TestInstall(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-mock-terraform-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	terraformPath := InstallForTest(t, filepath.Join(tmpdir, "bin"), writeTestSpec(t, tmpdir))
+
+	out, err := exec.Command(terraformPath, "version").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "Terraform v0.12.6\n", string(out))
+
+	err = exec.Command(terraformPath, "plan").Run()
+	require.IsType(t, &exec.ExitError{}, err)
+	assert.Equal(t, 2, err.(*exec.ExitError).Sys().(syscall.WaitStatus).ExitStatus())
+}
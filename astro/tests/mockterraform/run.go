@@ -0,0 +1,106 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mockterraform
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Run responds to the arguments as the spec describes and returns the exit
+// code. It is the main function of the mock Terraform binary.
+func Run(spec *Spec, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "mock terraform: no subcommand")
+		return 127
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "mock terraform: %v\n", err)
+		return 127
+	}
+	invocation := Invocation{
+		Subcommand: args[0],
+		Args:       args,
+		Dir:        dir,
+		Start:      time.Now(),
+	}
+
+	respond := func(response Response) int {
+		if err := response.Respond(invocation, stdout, stderr); err != nil {
+			fmt.Fprintf(stderr, "mock terraform: %v\n", err)
+			return 127
+		}
+		return response.ExitCode
+	}
+
+	sleep := func(response Response) (os.Signal, error) {
+		duration, err := response.SleepDuration()
+		if err != nil || duration == 0 {
+			return nil, err
+		}
+		if spec.OnInterrupt == nil {
+			time.Sleep(duration)
+			return nil, nil
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		select {
+		case sig := <-signals:
+			return sig, nil
+		case <-time.After(duration):
+			return nil, nil
+		}
+	}
+
+	response := spec.Response(invocation.Subcommand)
+	sig, err := sleep(response)
+	if err != nil {
+		fmt.Fprintf(stderr, "mock terraform: %v\n", err)
+		return 127
+	}
+	if sig == nil {
+		return respond(response)
+	}
+
+	invocation.Signal = signalName(sig)
+	if _, err := sleep(*spec.OnInterrupt); err != nil {
+		fmt.Fprintf(stderr, "mock terraform: %v\n", err)
+		return 127
+	}
+	return respond(*spec.OnInterrupt)
+}
+
+// signalName returns the short name of the signal, e.g. "INT".
+func signalName(sig os.Signal) string {
+	switch sig {
+	case syscall.SIGINT:
+		return "INT"
+	case syscall.SIGTERM:
+		return "TERM"
+	}
+	return strings.ToUpper(sig.String())
+}
@@ -0,0 +1,232 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mockterraform provides a mock Terraform binary for tests. Its
+// behavior is described by a YAML spec instead of a hand-written script, e.g.
+//
+//	version: 0.11.7
+//	commands:
+//	  plan:
+//	    stdout: "No changes. Infrastructure is up-to-date.\n"
+//	    touch: ['{{flag "out"}}']
+//	  apply:
+//	    exit_code: 1
+//	    stderr: "Error applying plan\n"
+//
+// Outputs and file paths are Go templates; see Invocation for the data
+// and functions available to them.
+package mockterraform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// Spec describes how the mock Terraform responds to each subcommand.
+type Spec struct {
+	// Version is printed by `terraform version`, unless the version
+	// command has its own response. Defaults to 0.11.7.
+	Version string `json:"version"`
+	// Commands maps subcommands, e.g. "plan", to their responses.
+	Commands map[string]Response `json:"commands"`
+	// Default is the response to subcommands that aren't in Commands. If it
+	// isn't set, they succeed without output.
+	Default *Response `json:"default"`
+	// OnInterrupt is the response to SIGINT or SIGTERM received while a
+	// response sleeps. If it isn't set, signals aren't handled.
+	OnInterrupt *Response `json:"on_interrupt"`
+}
+
+// Response is what the mock Terraform does when it is run. It sleeps, then
+// creates files, writes its output and exits.
+type Response struct {
+	// ExitCode is the exit code of the mock.
+	ExitCode int `json:"exit_code"`
+	// Stdout is a template for the output on stdout.
+	Stdout string `json:"stdout"`
+	// Stderr is a template for the output on stderr.
+	Stderr string `json:"stderr"`
+	// Sleep is how long to wait before responding, e.g. "1.5s".
+	Sleep string `json:"sleep"`
+	// Touch is a list of templates of paths of files to create, or update
+	// the modification time of.
+	Touch []string `json:"touch"`
+	// Write maps templates of file paths to templates of their contents.
+	// Missing parent directories are created.
+	Write map[string]string `json:"write"`
+}
+
+// Invocation is the data available to templates in a response.
+//
+// Besides the standard functions, templates can use `env "NAME"` for the
+// value of an environment variable, `flag "name"` for the value of a
+// "-name=value" or "-name value" argument, `join .Args " "`, and `now` for
+// the current time, e.g. `{{(now).UnixNano}}`.
+type Invocation struct {
+	// Subcommand is the first argument, e.g. "plan".
+	Subcommand string
+	// Args are all the arguments, including the subcommand.
+	Args []string
+	// Dir is the working directory.
+	Dir string
+	// Start is when the mock started, before sleeping.
+	Start time.Time
+	// Signal is the name of the signal received, e.g. "INT", when
+	// responding to an interrupt.
+	Signal string
+}
+
+// LoadSpec reads a spec from a YAML file.
+func LoadSpec(path string) (*Spec, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	if err := yaml.Unmarshal(b, &spec); err != nil {
+		return nil, fmt.Errorf("unable to parse mock Terraform spec: %v: %v", path, err)
+	}
+	if spec.Version == "" {
+		spec.Version = "0.11.7"
+	}
+
+	return &spec, nil
+}
+
+// Response returns the response to the subcommand.
+func (s *Spec) Response(subcommand string) Response {
+	if response, ok := s.Commands[subcommand]; ok {
+		return response
+	}
+	if subcommand == "version" {
+		return Response{Stdout: fmt.Sprintf("Terraform v%s\n", s.Version)}
+	}
+	if s.Default != nil {
+		return *s.Default
+	}
+	return Response{}
+}
+
+// SleepDuration returns how long the response sleeps for.
+func (r Response) SleepDuration() (time.Duration, error) {
+	if r.Sleep == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.Sleep)
+}
+
+// Respond creates the files of the response and writes its output. It
+// doesn't sleep or exit.
+func (r Response) Respond(invocation Invocation, stdout, stderr io.Writer) error {
+	render := func(text string) (string, error) {
+		tmpl, err := template.New("").Funcs(invocation.funcs()).Parse(text)
+		if err != nil {
+			return "", err
+		}
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, invocation); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	resolve := func(path string) (string, error) {
+		rendered, err := render(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(rendered) {
+			rendered = filepath.Join(invocation.Dir, rendered)
+		}
+		return rendered, nil
+	}
+
+	for _, path := range r.Touch {
+		path, err := resolve(path)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); os.IsNotExist(err) {
+			err = ioutil.WriteFile(path, nil, 0644)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for path, content := range r.Write {
+		path, err := resolve(path)
+		if err != nil {
+			return err
+		}
+		content, err := render(content)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	for _, output := range []struct {
+		text string
+		w    io.Writer
+	}{{r.Stdout, stdout}, {r.Stderr, stderr}} {
+		rendered, err := render(output.text)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(output.w, rendered); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// funcs returns the functions available to templates.
+func (invocation Invocation) funcs() template.FuncMap {
+	return template.FuncMap{
+		"env":  os.Getenv,
+		"join": strings.Join,
+		"now":  time.Now,
+		"flag": func(name string) string {
+			for i, arg := range invocation.Args {
+				if strings.HasPrefix(arg, "-"+name+"=") {
+					return strings.TrimPrefix(arg, "-"+name+"=")
+				}
+				if arg == "-"+name && i+1 < len(invocation.Args) {
+					return invocation.Args[i+1]
+				}
+			}
+			return ""
+		},
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	version "github.com/burl/go-version"
+)
+
+// darwinArm64Since is the first Terraform version released for
+// darwin/arm64. Earlier versions run on Apple Silicon under Rosetta.
+var darwinArm64Since = version.Must(version.NewVersion("1.0.2"))
+
+// WithArch makes the repo use binaries for the architecture, e.g. "arm64",
+// without falling back to another one when a version isn't released for
+// it.
+func WithArch(arch string) VersionRepoOption {
+	return func(r *VersionRepo) {
+		r.arch = arch
+		r.archSet = true
+	}
+}
+
+// fallbackArch returns the architecture to use for versions that aren't
+// released for the repo's, or an empty string if there is none: darwin/arm64
+// falls back to darwin/amd64 for Terraform, unless WithArch was passed.
+func (r *VersionRepo) fallbackArch() string {
+	if r.archSet || r.flavor != FlavorTerraform {
+		return ""
+	}
+	if r.platform == "darwin" && r.arch == "arm64" {
+		return "amd64"
+	}
+	return ""
+}
+
+// needsFallback returns whether the version can't be downloaded for the
+// repo's architecture, given the error of downloading its zip file, and
+// should be downloaded for the fallback architecture instead.
+func (r *VersionRepo) needsFallback(v string, err error) bool {
+	if r.fallback == nil {
+		return false
+	}
+	statusErr, ok := err.(*httpStatusError)
+	if !ok || statusErr.statusCode != 404 {
+		return false
+	}
+	parsed, parseErr := version.NewVersion(v)
+	return parseErr == nil && parsed.LessThan(darwinArm64Since)
+}
@@ -0,0 +1,104 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestArchFallback gets versions from a fake release server that only has
+// darwin/amd64 builds of versions before 1.0.2.
+func TestArchFallback(t *testing.T) {
+	zipFile := fakeTerraformZip(t)
+	sum := sha256.Sum256(zipFile)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// paths are /terraform/<version>/<file>
+		parts := strings.Split(r.URL.Path, "/")
+		if len(parts) != 4 {
+			http.NotFound(w, r)
+			return
+		}
+		version, file := parts[2], parts[3]
+
+		switch file {
+		case fmt.Sprintf("terraform_%s_darwin_amd64.zip", version):
+			w.Write(zipFile)
+		case fmt.Sprintf("terraform_%s_SHA256SUMS", version):
+			fmt.Fprintf(w, "%s  terraform_%s_darwin_amd64.zip\n", hex.EncodeToString(sum[:]), version)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(zipURL, sumsURL string) {
+		terraformZipFileDownloadURL = zipURL
+		terraformSHA256SumsDownloadURL = sumsURL
+	}(terraformZipFileDownloadURL, terraformSHA256SumsDownloadURL)
+	terraformZipFileDownloadURL = server.URL + "/terraform/%s/terraform_%s_%s_%s.zip"
+	terraformSHA256SumsDownloadURL = server.URL + "/terraform/%s/terraform_%s_SHA256SUMS"
+
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	repo, err := NewVersionRepo(tmpdir, "arm64", "darwin")
+	require.NoError(t, err)
+
+	path, err := repo.Get("0.11.7")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpdir, "darwin", "amd64", "0.11.7", "terraform"), path)
+
+	// the fallback is remembered, without trying arm64 again
+	requestsBefore := atomic.LoadInt32(&requests)
+	path, err = repo.Get("0.11.7")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpdir, "darwin", "amd64", "0.11.7", "terraform"), path)
+	assert.Equal(t, requestsBefore, atomic.LoadInt32(&requests))
+
+	installed, err := repo.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"0.11.7": path}, installed)
+
+	// versions released for arm64 don't fall back
+	_, err = repo.Get("1.1.0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "terraform_1.1.0_darwin_arm64.zip: server returned 404 Not Found")
+
+	// nor does an explicit arch
+	repo, err = NewVersionRepo(tmpdir, "arm64", "darwin", WithArch("arm64"))
+	require.NoError(t, err)
+	_, err = repo.Get("0.11.8")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "terraform_0.11.8_darwin_arm64.zip: server returned 404 Not Found")
+}
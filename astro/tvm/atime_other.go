@@ -0,0 +1,31 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime returns the modification time of the file, as access times
+// aren't read on this platform.
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
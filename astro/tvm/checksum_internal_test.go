@@ -0,0 +1,116 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTerraformZip returns a zip file containing a fake Terraform binary.
+func fakeTerraformZip(t *testing.T) []byte {
+	zipBuffer := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(zipBuffer)
+	binary, err := zipWriter.Create("terraform")
+	require.NoError(t, err)
+	_, err = binary.Write([]byte("#!/bin/sh\necho Terraform v0.11.7\n"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+	return zipBuffer.Bytes()
+}
+
+// TestChecksumVerification downloads from a fake release server.
+func TestChecksumVerification(t *testing.T) {
+	zipFile := fakeTerraformZip(t)
+	sum := sha256.Sum256(zipFile)
+	checksums := map[string]string{
+		"0.11.7": hex.EncodeToString(sum[:]),
+		"0.11.8": "0000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// paths are /terraform/<version>/<file>
+		parts := strings.Split(r.URL.Path, "/")
+		if len(parts) != 4 {
+			http.NotFound(w, r)
+			return
+		}
+		version, file := parts[2], parts[3]
+
+		switch file {
+		case fmt.Sprintf("terraform_%s_linux_amd64.zip", version):
+			w.Write(zipFile)
+		case fmt.Sprintf("terraform_%s_SHA256SUMS", version):
+			checksum, ok := checksums[version]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, "%s  terraform_%s_darwin_amd64.zip\n%s  terraform_%s_linux_amd64.zip\n", checksum, version, checksum, version)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(zipURL, sumsURL string) {
+		terraformZipFileDownloadURL = zipURL
+		terraformSHA256SumsDownloadURL = sumsURL
+	}(terraformZipFileDownloadURL, terraformSHA256SumsDownloadURL)
+	terraformZipFileDownloadURL = server.URL + "/terraform/%s/terraform_%s_%s_%s.zip"
+	terraformSHA256SumsDownloadURL = server.URL + "/terraform/%s/terraform_%s_SHA256SUMS"
+
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	repo, err := NewVersionRepo(tmpdir, "amd64", "linux")
+	require.NoError(t, err)
+
+	_, err = repo.Get("0.11.7")
+	assert.NoError(t, err)
+	assert.True(t, repo.exists("0.11.7"))
+
+	_, err = repo.Get("0.11.8")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch for terraform_0.11.8_linux_amd64.zip")
+	assert.False(t, repo.exists("0.11.8"))
+
+	// no checksums are published for 0.11.9
+	_, err = repo.Get("0.11.9")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to download checksums for Terraform 0.11.9")
+	assert.False(t, repo.exists("0.11.9"))
+
+	unverifiedRepo, err := NewVersionRepo(tmpdir, "amd64", "linux", WithoutChecksumVerification())
+	require.NoError(t, err)
+	_, err = unverifiedRepo.Get("0.11.9")
+	assert.NoError(t, err)
+	assert.True(t, unverifiedRepo.exists("0.11.9"))
+}
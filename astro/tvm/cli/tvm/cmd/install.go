@@ -31,7 +31,9 @@ import (
 const defaultInstallPath = "/usr/local/bin/terraform"
 
 var (
-	installPath string
+	installPath  string
+	installFrom  string
+	skipChecksum bool
 )
 
 // installCmd represents the install command
@@ -40,13 +42,24 @@ var installCmd = &cobra.Command{
 	Short: "Download and link the specified version of Terraform",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		tvm, err := tvm.NewVersionRepoForCurrentSystem(repoPath)
+		var opts []tvm.VersionRepoOption
+		if skipChecksum {
+			opts = append(opts, tvm.WithoutChecksumVerification())
+		}
+
+		tvm, err := tvm.NewVersionRepoForCurrentSystem(repoPath, opts...)
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		version := args[0]
 
+		if installFrom != "" {
+			if err := tvm.Install(version, installFrom); err != nil {
+				log.Fatal(err)
+			}
+		}
+
 		if err := tvm.Link(version, viper.GetString("installPath"), true); err != nil {
 			log.Fatal(err)
 		}
@@ -59,6 +72,16 @@ func init() {
 		fmt.Sprintf("path to link Terraform binary to (default: %s )", defaultInstallPath),
 	)
 
+	installCmd.PersistentFlags().StringVar(
+		&installFrom, "from", "",
+		"add this existing Terraform binary to the repo as the version, instead of downloading it",
+	)
+
+	installCmd.PersistentFlags().BoolVar(
+		&skipChecksum, "skip-checksum", false,
+		"don't verify the download against the published SHA256 checksums, e.g. for mirrors that don't publish them",
+	)
+
 	viper.BindPFlag("path", installCmd.PersistentFlags().Lookup("path"))
 	viper.SetDefault("installPath", defaultInstallPath)
 
@@ -0,0 +1,188 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	version "github.com/burl/go-version"
+	"github.com/spf13/cobra"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/tvm"
+)
+
+var (
+	pruneKeepReferencedBy []string
+	pruneKeepVersions     []string
+	pruneOlderThan        time.Duration
+	pruneDryRun           bool
+)
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the versions of Terraform that aren't used",
+	Long: `Remove versions of Terraform from the repo, except the ones that
+the modules of astro projects are configured to use, the ones listed with
+--keep-versions, and the one the current Terraform binary links to. With
+--older-than, only versions that haven't been used for that long are
+removed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(pruneKeepReferencedBy) == 0 && len(pruneKeepVersions) == 0 && pruneOlderThan == 0 {
+			log.Fatal(errors.New("one of --keep-referenced-by, --keep-versions or --older-than is required"))
+		}
+
+		// Offline, versions like "latest" are only resolved to installed
+		// versions, which are the only ones that matter here.
+		repo, err := tvm.NewVersionRepoForCurrentSystem(repoPath, tvm.WithOffline())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		installed, err := repo.List()
+		if os.IsNotExist(err) {
+			return
+		} else if err != nil {
+			log.Fatal(err)
+		}
+
+		keep := pruneKeepVersions
+		for _, configFilePath := range pruneKeepReferencedBy {
+			versions, err := versionsUsedBy(configFilePath, installed, repo.ResolveVersion)
+			if err != nil {
+				log.Fatal(err)
+			}
+			keep = append(keep, versions...)
+		}
+
+		removed, err := repo.Prune(tvm.PruneOptions{
+			Keep:      keep,
+			LinkPath:  currentLinkPath(),
+			OlderThan: pruneOlderThan,
+			DryRun:    pruneDryRun,
+		})
+		for _, v := range removed {
+			if pruneDryRun {
+				fmt.Printf("Would remove %s\n", v)
+			} else {
+				fmt.Printf("Removed %s\n", v)
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// versionsUsedBy returns the Terraform versions that the astro project
+// config at configFilePath uses. Version specs, e.g. "latest", are resolved
+// with resolve. For modules with version_from_code and no configured
+// version, all installed versions that meet the required_version in their
+// code are used.
+func versionsUsedBy(configFilePath string, installed map[string]string, resolve func(string) (string, error)) ([]string, error) {
+	config, err := astro.NewConfigFromFile(configFilePath, astro.WithoutTerraformDetection())
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	if config.TerraformDefaults.Version != nil {
+		versions = append(versions, config.TerraformDefaults.Version.String())
+	}
+	if spec := config.TerraformDefaults.VersionSpec; spec != "" {
+		if v, err := resolve(spec); err == nil {
+			versions = append(versions, v)
+		}
+	}
+
+	for _, moduleConf := range config.Modules {
+		if moduleConf.Terraform.Version != nil {
+			versions = append(versions, moduleConf.Terraform.Version.String())
+			continue
+		}
+		if spec := moduleConf.Terraform.VersionSpec; spec != "" {
+			if v, err := resolve(spec); err == nil {
+				versions = append(versions, v)
+			}
+			continue
+		}
+		if !moduleConf.Terraform.VersionFromCode {
+			continue
+		}
+
+		constraint, err := terraform.RequiredVersion(filepath.Join(moduleConf.TerraformCodeRoot, moduleConf.Path))
+		if err != nil {
+			return nil, fmt.Errorf("module %v: unable to read required_version: %v", moduleConf.Name, err)
+		}
+		if constraint == "" {
+			continue
+		}
+		constraints, err := version.NewConstraint(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("module %v: invalid required_version %q: %v", moduleConf.Name, constraint, err)
+		}
+		for s := range installed {
+			if v, err := version.NewVersion(s); err == nil && constraints.Check(v) {
+				versions = append(versions, s)
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+func init() {
+	pruneCmd.PersistentFlags().StringSliceVar(
+		&pruneKeepReferencedBy, "keep-referenced-by", nil,
+		"paths to astro.yaml files; the versions they use are kept",
+	)
+	pruneCmd.PersistentFlags().StringSliceVar(
+		&pruneKeepReferencedBy, "keep-used-by", nil,
+		"",
+	)
+	pruneCmd.PersistentFlags().MarkDeprecated("keep-used-by", "use --keep-referenced-by instead")
+
+	pruneCmd.PersistentFlags().StringSliceVar(
+		&pruneKeepVersions, "keep-versions", nil,
+		"versions to keep",
+	)
+	pruneCmd.PersistentFlags().StringSliceVar(
+		&pruneKeepVersions, "keep", nil,
+		"",
+	)
+	pruneCmd.PersistentFlags().MarkDeprecated("keep", "use --keep-versions instead")
+
+	pruneCmd.PersistentFlags().DurationVar(
+		&pruneOlderThan, "older-than", 0,
+		"only remove versions that haven't been used for this long, e.g. 720h",
+	)
+
+	pruneCmd.PersistentFlags().BoolVar(
+		&pruneDryRun, "dry-run", false,
+		"print the versions that would be removed, without removing them",
+	)
+
+	rootCmd.AddCommand(pruneCmd)
+}
@@ -0,0 +1,61 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"log"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"github.com/uber/astro/astro/tvm"
+)
+
+var removeForce bool
+
+// rmCmd represents the rm command
+var rmCmd = &cobra.Command{
+	Use:   "rm",
+	Short: "Remove the specified version of Terraform from the repo",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tvm, err := tvm.NewVersionRepoForCurrentSystem(repoPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := tvm.Remove(args[0], currentLinkPath(), removeForce); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// currentLinkPath returns the path to the current Terraform binary,
+// according to $PATH, which may be a link into the repo.
+func currentLinkPath() string {
+	terraformPath, _ := exec.LookPath("terraform")
+	return terraformPath
+}
+
+func init() {
+	rmCmd.PersistentFlags().BoolVar(
+		&removeForce, "force", false,
+		"remove the version even if the current Terraform binary links to it, and remove the link too",
+	)
+
+	rootCmd.AddCommand(rmCmd)
+}
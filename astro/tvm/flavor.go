@@ -0,0 +1,132 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Flavor is a distribution of Terraform that tvm can install. Flavors have
+// compatible command line interfaces, but are released separately, under
+// their own names.
+type Flavor string
+
+const (
+	// FlavorTerraform is Terraform, released by Hashicorp.
+	FlavorTerraform Flavor = "terraform"
+	// FlavorOpenTofu is OpenTofu, the open source fork of Terraform.
+	FlavorOpenTofu Flavor = "opentofu"
+)
+
+// Flavors are the flavors that tvm can install.
+var Flavors = []Flavor{FlavorTerraform, FlavorOpenTofu}
+
+// openTofuZipFileDownloadURL is the path to download OpenTofu zip files
+// from its GitHub releases.
+var openTofuZipFileDownloadURL = "https://github.com/opentofu/opentofu/releases/download/v%s/tofu_%s_%s_%s.zip"
+
+// openTofuSHA256SumsDownloadURL is the path to download the SHA256 checksums
+// of the OpenTofu zip files of a version from its GitHub releases.
+var openTofuSHA256SumsDownloadURL = "https://github.com/opentofu/opentofu/releases/download/v%s/tofu_%s_SHA256SUMS"
+
+// WithFlavor makes the repo install the flavor instead of Terraform. Each
+// flavor is kept in its own part of the repo. TVM_MIRROR_URL only applies
+// to Terraform; use WithDownloadMirror for other flavors.
+func WithFlavor(flavor Flavor) VersionRepoOption {
+	return func(r *VersionRepo) {
+		r.flavor = flavor
+	}
+}
+
+// Validate checks that tvm knows the flavor.
+func (f Flavor) Validate() error {
+	for _, flavor := range Flavors {
+		if f == flavor {
+			return nil
+		}
+	}
+	names := []string{}
+	for _, flavor := range Flavors {
+		names = append(names, string(flavor))
+	}
+	return fmt.Errorf("unknown flavor: %q; must be one of: %s", string(f), strings.Join(names, ", "))
+}
+
+// String returns the name of the flavor for messages, e.g. "OpenTofu".
+func (f Flavor) String() string {
+	if f == FlavorOpenTofu {
+		return "OpenTofu"
+	}
+	return "Terraform"
+}
+
+// BinaryName returns the name of the binary of the flavor, e.g. "tofu".
+func (f Flavor) BinaryName() string {
+	if f == FlavorOpenTofu {
+		return "tofu"
+	}
+	return terraformBinaryFile
+}
+
+// repoDir returns the directory of the flavor in the repo. Terraform is at
+// the top, as it was the only flavor in earlier versions.
+func (f Flavor) repoDir(repoPath string) string {
+	if f == FlavorOpenTofu {
+		return filepath.Join(repoPath, string(f))
+	}
+	return repoPath
+}
+
+// zipFileName returns the name of the zip file of the version, which is
+// also how it is listed in SHA256SUMS.
+func (f Flavor) zipFileName(version, platform, arch string) string {
+	return fmt.Sprintf("%s_%s_%s_%s.zip", f.BinaryName(), version, platform, arch)
+}
+
+// sha256SumsFileName returns the name of the SHA256SUMS file of the version.
+func (f Flavor) sha256SumsFileName(version string) string {
+	return fmt.Sprintf("%s_%s_SHA256SUMS", f.BinaryName(), version)
+}
+
+// zipURL returns the URL of the zip file of the version on the official
+// download site of the flavor.
+func (f Flavor) zipURL(version, platform, arch string) string {
+	if f == FlavorOpenTofu {
+		return fmt.Sprintf(openTofuZipFileDownloadURL, version, version, platform, arch)
+	}
+	return fmt.Sprintf(terraformZipFileDownloadURL, version, version, platform, arch)
+}
+
+// sha256SumsURL returns the URL of the SHA256SUMS file of the version on the
+// official download site of the flavor.
+func (f Flavor) sha256SumsURL(version string) string {
+	if f == FlavorOpenTofu {
+		return fmt.Sprintf(openTofuSHA256SumsDownloadURL, version, version)
+	}
+	return fmt.Sprintf(terraformSHA256SumsDownloadURL, version, version)
+}
+
+// indexURL returns the URL of the index of releases of the flavor, or an
+// empty string if there is none that tvm can read.
+func (f Flavor) indexURL() string {
+	if f == FlavorOpenTofu {
+		return ""
+	}
+	return terraformIndexURL
+}
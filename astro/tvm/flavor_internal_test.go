@@ -0,0 +1,105 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlavorValidate(t *testing.T) {
+	assert.NoError(t, FlavorTerraform.Validate())
+	assert.NoError(t, FlavorOpenTofu.Validate())
+	assert.EqualError(t, Flavor("tofu").Validate(), `unknown flavor: "tofu"; must be one of: terraform, opentofu`)
+
+	_, err := NewVersionRepo("", "amd64", "linux", WithFlavor("tofu"))
+	assert.Error(t, err)
+}
+
+// TestOpenTofuFlavor installs OpenTofu from a fake release server.
+func TestOpenTofuFlavor(t *testing.T) {
+	zipBuffer := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(zipBuffer)
+	header := &zip.FileHeader{Name: "tofu", Method: zip.Deflate}
+	header.SetMode(0755)
+	binary, err := zipWriter.CreateHeader(header)
+	require.NoError(t, err)
+	_, err = binary.Write([]byte("#!/bin/sh\necho OpenTofu v1.6.0\n"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+	zipFile := zipBuffer.Bytes()
+	sum := sha256.Sum256(zipFile)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.6.0/tofu_1.6.0_linux_amd64.zip":
+			w.Write(zipFile)
+		case "/v1.6.0/tofu_1.6.0_SHA256SUMS":
+			fmt.Fprintf(w, "%s  tofu_1.6.0_linux_amd64.zip\n", hex.EncodeToString(sum[:]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(zipURL, sumsURL string) {
+		openTofuZipFileDownloadURL = zipURL
+		openTofuSHA256SumsDownloadURL = sumsURL
+	}(openTofuZipFileDownloadURL, openTofuSHA256SumsDownloadURL)
+	openTofuZipFileDownloadURL = server.URL + "/v%s/tofu_%s_%s_%s.zip"
+	openTofuSHA256SumsDownloadURL = server.URL + "/v%s/tofu_%s_SHA256SUMS"
+
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	repo, err := NewVersionRepo(tmpdir, "amd64", "linux", WithFlavor(FlavorOpenTofu))
+	require.NoError(t, err)
+
+	path, err := repo.Get("1.6.0")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpdir, "opentofu", "linux", "amd64", "1.6.0", "tofu"), path)
+
+	v, err := InspectVersion(path)
+	require.NoError(t, err)
+	assert.Equal(t, "1.6.0", v.String())
+
+	// Terraform versions are kept apart
+	terraformRepo, err := NewVersionRepo(tmpdir, "amd64", "linux")
+	require.NoError(t, err)
+	assert.False(t, terraformRepo.exists("1.6.0"))
+
+	// partial versions only resolve against installed ones
+	resolved, err := repo.ResolveVersion("1.6")
+	require.NoError(t, err)
+	assert.Equal(t, "1.6.0", resolved)
+	_, err = repo.ResolveVersion("1.7")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "OpenTofu releases can't be listed")
+}
@@ -18,32 +18,104 @@ package tvm
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os/exec"
+	"regexp"
 
 	"github.com/burl/go-version"
 )
 
-// InspectVersion will find out what version the Terraform binary at the
-// given location is.
+// matches the version line of `terraform version` or `tofu version`, e.g.
+// "Terraform v0.7.13" or "OpenTofu v1.6.0"
+var versionBannerRe = regexp.MustCompile(`^(?:Terraform|OpenTofu) v(\S+)`)
+
+// versionJSON is the output of `terraform version -json`, which Terraform
+// 0.13 and later and OpenTofu support.
+type versionJSON struct {
+	TerraformVersion string `json:"terraform_version"`
+}
+
+// InspectVersion will find out what version the Terraform or OpenTofu binary
+// at the given location is. It runs `version -json` first, and falls back to
+// the version line of `version` for binaries that don't support it. Anything
+// else the binary prints, e.g. upgrade notices or banners from wrapper
+// scripts, is ignored.
 func InspectVersion(binaryPath string) (*version.Version, error) {
-	stdout, err := exec.Command(binaryPath, "version").Output()
+	stdout, _, err := runVersionCommand(binaryPath, "version", "-json")
+	if err == nil {
+		if v := parseVersionJSON(stdout); v != nil {
+			return v, nil
+		}
+		if v := parseVersionBanner(stdout); v != nil {
+			return v, nil
+		}
+	}
+
+	stdout, stderr, err := runVersionCommand(binaryPath, "version")
 	if err != nil {
+		if len(stderr) > 0 {
+			return nil, fmt.Errorf("%v: %s", err, bytes.TrimSpace(stderr))
+		}
 		return nil, err
 	}
 
-	s := bytes.SplitN(stdout, []byte("\n"), 2)
-	if len(s) < 2 {
-		return nil, fmt.Errorf("unable to read lines from data: %s", s)
+	if len(bytes.TrimSpace(stdout)) == 0 {
+		return nil, fmt.Errorf("unable to read lines from data: %q (stderr: %q)", stdout, stderr)
 	}
 
-	// e.g. "Terraform v0.7.13"
-	versionLine := s[0]
+	if v := parseVersionBanner(stdout); v != nil {
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("unable to parse version from data: %q (stderr: %q)", stdout, stderr)
+}
 
-	v := bytes.Split(versionLine, []byte("v"))
-	if len(v) != 2 {
-		return nil, fmt.Errorf("unable to parse version from data: %s", v)
+// runVersionCommand runs the binary with the arguments, and returns what it
+// wrote to stdout and stderr separately.
+func runVersionCommand(binaryPath string, args ...string) (stdout, stderr []byte, err error) {
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+	err = cmd.Run()
+	return stdoutBuf.Bytes(), stderrBuf.Bytes(), err
+}
+
+// parseVersionJSON returns the version from the output of `version -json`,
+// or nil if the output doesn't contain one. Lines before the JSON object are
+// skipped.
+func parseVersionJSON(stdout []byte) *version.Version {
+	start := bytes.IndexByte(stdout, '{')
+	if start < 0 {
+		return nil
+	}
+
+	var out versionJSON
+	if err := json.NewDecoder(bytes.NewReader(stdout[start:])).Decode(&out); err != nil || out.TerraformVersion == "" {
+		return nil
+	}
+
+	v, err := version.NewVersion(out.TerraformVersion)
+	if err != nil {
+		return nil
 	}
+	return v
+}
 
-	return version.NewVersion(string(v[1]))
+// parseVersionBanner returns the version from the first version line in the
+// output of `version`, or nil if there is none.
+func parseVersionBanner(stdout []byte) *version.Version {
+	for _, line := range bytes.Split(stdout, []byte("\n")) {
+		m := versionBannerRe.FindSubmatch(bytes.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		v, err := version.NewVersion(string(m[1]))
+		if err != nil {
+			continue
+		}
+		return v
+	}
+	return nil
 }
@@ -30,6 +30,13 @@ func TestInspectOK(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "0.7.13", version.String())
 }
+
+func TestInspectOpenTofu(t *testing.T) {
+	version, err := tvm.InspectVersion("test/tofu-version-ok")
+	require.NoError(t, err)
+	assert.Equal(t, "1.6.0", version.String())
+}
+
 func TestInspectFail(t *testing.T) {
 	version, err := tvm.InspectVersion("test/terraform-fail")
 	assert.Nil(t, version)
@@ -42,6 +49,7 @@ func TestInspectBadVersion(t *testing.T) {
 	assert.Nil(t, version)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unable to parse version from data")
+	assert.Contains(t, err.Error(), "of junk")
 }
 
 func TestInspectEmptyVersion(t *testing.T) {
@@ -50,3 +58,15 @@ func TestInspectEmptyVersion(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unable to read lines from data")
 }
+
+func TestInspectNoisyVersion(t *testing.T) {
+	version, err := tvm.InspectVersion("test/terraform-version-noisy")
+	require.NoError(t, err)
+	assert.Equal(t, "0.11.14", version.String())
+}
+
+func TestInspectVersionJSON(t *testing.T) {
+	version, err := tvm.InspectVersion("test/terraform-version-json")
+	require.NoError(t, err)
+	assert.Equal(t, "1.5.7", version.String())
+}
@@ -0,0 +1,99 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MirrorURLEnvVar is the environment variable that sets the download mirror
+// when none is passed with WithDownloadMirror.
+const MirrorURLEnvVar = "TVM_MIRROR_URL"
+
+// mirrorPlaceholders are the placeholders that are replaced in a mirror URL
+// template.
+var mirrorPlaceholders = []string{"{version}", "{platform}", "{arch}"}
+
+// matches anything that looks like a placeholder in a mirror URL template
+var reMirrorPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// WithDownloadMirror makes the repo download zip files from a mirror
+// instead of the Hashicorp website, or the download site of its flavor. The
+// URL is a template that can contain the placeholders {version}, {platform}
+// and {arch}, e.g.
+// "https://artifacts.example.com/terraform/{version}/terraform_{version}_{platform}_{arch}.zip".
+// The SHA256SUMS file of the version is expected in the same directory as
+// the zip file, under its usual name. An empty URL uses the Hashicorp
+// website.
+func WithDownloadMirror(urlTemplate string) VersionRepoOption {
+	return func(r *VersionRepo) {
+		r.mirror = urlTemplate
+		r.mirrorSet = true
+	}
+}
+
+// ValidateMirrorURL checks that a mirror URL template only contains known
+// placeholders, and contains {version} so that versions don't overwrite each
+// other.
+func ValidateMirrorURL(urlTemplate string) error {
+	for _, placeholder := range reMirrorPlaceholder.FindAllString(urlTemplate, -1) {
+		known := false
+		for _, p := range mirrorPlaceholders {
+			if placeholder == p {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown placeholder %s in mirror URL %q; must be one of: %s", placeholder, urlTemplate, strings.Join(mirrorPlaceholders, ", "))
+		}
+	}
+	if !strings.Contains(urlTemplate, "{version}") {
+		return fmt.Errorf("mirror URL %q must contain {version}", urlTemplate)
+	}
+	return nil
+}
+
+// zipFileName returns the name of the zip file of the version on the
+// download site of the flavor, which is also how it is listed in
+// SHA256SUMS.
+func (r *VersionRepo) zipFileName(version string) string {
+	return r.flavor.zipFileName(version, r.platform, r.arch)
+}
+
+// zipURL returns the URL to download the zip file of the version from.
+func (r *VersionRepo) zipURL(version string) string {
+	if r.mirror == "" {
+		return r.flavor.zipURL(version, r.platform, r.arch)
+	}
+	return strings.NewReplacer(
+		"{version}", version,
+		"{platform}", r.platform,
+		"{arch}", r.arch,
+	).Replace(r.mirror)
+}
+
+// sha256SumsURL returns the URL to download the SHA256SUMS file of the
+// version from. For mirrors, it is next to the zip file.
+func (r *VersionRepo) sha256SumsURL(version string) string {
+	if r.mirror == "" {
+		return r.flavor.sha256SumsURL(version)
+	}
+	zipURL := r.zipURL(version)
+	return zipURL[:strings.LastIndex(zipURL, "/")+1] + r.flavor.sha256SumsFileName(version)
+}
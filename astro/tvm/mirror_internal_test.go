@@ -0,0 +1,100 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMirrorURL(t *testing.T) {
+	assert.NoError(t, ValidateMirrorURL("https://mirror/terraform/{version}/terraform_{version}_{platform}_{arch}.zip"))
+	assert.NoError(t, ValidateMirrorURL("https://mirror/terraform-{version}.zip"))
+
+	err := ValidateMirrorURL("https://mirror/terraform-{verison}.zip")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown placeholder {verison}")
+
+	err = ValidateMirrorURL("https://mirror/terraform.zip")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must contain {version}")
+}
+
+func TestMirrorURLs(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	repo, err := NewVersionRepo(tmpdir, "arm64", "darwin", WithDownloadMirror("https://mirror/tf/{version}/{platform}-{arch}.zip"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://mirror/tf/0.11.7/darwin-arm64.zip", repo.zipURL("0.11.7"))
+	assert.Equal(t, "https://mirror/tf/0.11.7/terraform_0.11.7_SHA256SUMS", repo.sha256SumsURL("0.11.7"))
+
+	defer os.Setenv(MirrorURLEnvVar, os.Getenv(MirrorURLEnvVar))
+	os.Setenv(MirrorURLEnvVar, "https://env-mirror/{version}.zip")
+
+	repo, err = NewVersionRepo(tmpdir, "arm64", "darwin")
+	require.NoError(t, err)
+	assert.Equal(t, "https://env-mirror/0.11.7.zip", repo.zipURL("0.11.7"))
+
+	repo, err = NewVersionRepo(tmpdir, "arm64", "darwin", WithDownloadMirror("https://mirror/{version}.zip"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://mirror/0.11.7.zip", repo.zipURL("0.11.7"))
+}
+
+// TestDownloadMirror downloads from a fake mirror with its own layout.
+func TestDownloadMirror(t *testing.T) {
+	zipFile := fakeTerraformZip(t)
+	sum := sha256.Sum256(zipFile)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifacts/terraform/0.11.7/linux-amd64.zip":
+			w.Write(zipFile)
+		case "/artifacts/terraform/0.11.7/terraform_0.11.7_SHA256SUMS":
+			fmt.Fprintf(w, "%s  terraform_0.11.7_linux_amd64.zip\n", hex.EncodeToString(sum[:]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	repo, err := NewVersionRepo(tmpdir, "amd64", "linux", WithDownloadMirror(server.URL+"/artifacts/terraform/{version}/{platform}-{arch}.zip"))
+	require.NoError(t, err)
+
+	_, err = repo.Get("0.11.7")
+	assert.NoError(t, err)
+	assert.True(t, repo.exists("0.11.7"))
+
+	_, err = repo.Get("0.11.8")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to download Terraform 0.11.8 from "+server.URL+"/artifacts/terraform/0.11.8/linux-amd64.zip: server returned 404 Not Found")
+	assert.False(t, repo.exists("0.11.8"))
+}
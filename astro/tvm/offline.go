@@ -0,0 +1,104 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// OfflineEnvVar is the environment variable that enables offline mode when
+// set to a true value, e.g. "1".
+const OfflineEnvVar = "TVM_OFFLINE"
+
+// matches a complete Terraform version, e.g. "0.11.7"
+var reVersion = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// WithOffline makes Get fail immediately for versions that aren't in the
+// repo, instead of downloading them, e.g. in CI containers without network
+// access.
+func WithOffline() VersionRepoOption {
+	return func(r *VersionRepo) {
+		r.offline = true
+	}
+}
+
+// offlineFromEnv returns whether offline mode is enabled with TVM_OFFLINE.
+func offlineFromEnv() bool {
+	offline, _ := strconv.ParseBool(os.Getenv(OfflineEnvVar))
+	return offline
+}
+
+// Install adds an existing Terraform binary to the repo as the specified
+// version, e.g. to seed the repo from a local artifact where it can't
+// download. The binary is copied, and replaces the version if it is already
+// in the repo. Its version isn't checked, as it may be for another platform
+// than the current one.
+func (r *VersionRepo) Install(version string, binaryPath string) error {
+	if !reVersion.MatchString(version) {
+		return fmt.Errorf("invalid Terraform version: %q", version)
+	}
+
+	info, err := os.Stat(binaryPath)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%v is not a regular file", binaryPath)
+	}
+
+	lock := r.getLock(version)
+	lock.Lock()
+	defer lock.Unlock()
+
+	targetDir := r.dir(version)
+	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// Copy to a temporary file first, so that a partial copy is never
+	// picked up as the binary.
+	tmp, err := ioutil.TempFile(targetDir, ".terraform-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	in, err := os.Open(binaryPath)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	defer in.Close()
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), r.terraformPath(version))
+}
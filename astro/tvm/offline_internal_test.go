@@ -0,0 +1,80 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected download in offline mode: %s", r.URL)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	binaryPath := filepath.Join(tmpdir, "terraform-artifact")
+	require.NoError(t, ioutil.WriteFile(binaryPath, []byte("#!/bin/sh\necho Terraform v0.11.7\n"), 0644))
+
+	repo, err := NewVersionRepo(filepath.Join(tmpdir, "repo"), "amd64", "linux", WithOffline(), WithDownloadMirror(server.URL+"/{version}.zip"))
+	require.NoError(t, err)
+
+	_, err = repo.Get("0.11.7")
+	require.Error(t, err)
+	assert.Equal(t, "Terraform 0.11.7 is not installed and offline mode is enabled", err.Error())
+
+	require.NoError(t, repo.Install("0.11.7", binaryPath))
+	path, err := repo.Get("0.11.7")
+	require.NoError(t, err)
+	assert.Equal(t, repo.terraformPath("0.11.7"), path)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	versions, err := repo.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"0.11.7": path}, versions)
+
+	err = repo.Install("latest", binaryPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid Terraform version: "latest"`)
+
+	err = repo.Install("0.11.8", tmpdir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not a regular file")
+
+	defer os.Setenv(OfflineEnvVar, os.Getenv(OfflineEnvVar))
+	os.Setenv(OfflineEnvVar, "1")
+	envRepo, err := NewVersionRepo(filepath.Join(tmpdir, "repo"), "amd64", "linux", WithDownloadMirror(server.URL+"/{version}.zip"))
+	require.NoError(t, err)
+	_, err = envRepo.Get("0.11.8")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "offline mode is enabled")
+}
@@ -0,0 +1,93 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// downloadAttempts is how many times a file is downloaded before giving up.
+const downloadAttempts = 3
+
+// downloadRetryDelay is how long to wait before downloading a file again.
+// It doubles after every attempt.
+var downloadRetryDelay = time.Second
+
+// DownloadProgress is called as a Terraform version is downloaded, with the
+// number of bytes downloaded so far and the size of the download, which is
+// -1 if it isn't known. It is called with 0 bytes when a download starts,
+// including when it is retried.
+type DownloadProgress func(version string, downloaded, total int64)
+
+// WithDownloadProgress makes the repo report the progress of downloads to
+// progress, e.g. to show it to the user. It may be called from several
+// goroutines at once when different versions are downloaded.
+func WithDownloadProgress(progress DownloadProgress) VersionRepoOption {
+	return func(r *VersionRepo) {
+		r.progress = progress
+	}
+}
+
+// progressWriter counts the bytes written to w and reports them.
+type progressWriter struct {
+	w          io.Writer
+	downloaded int64
+	total      int64
+	progress   func(downloaded, total int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.downloaded += int64(n)
+	pw.progress(pw.downloaded, pw.total)
+	return n, err
+}
+
+// retryable returns whether a failed download may succeed if it is tried
+// again. Only server errors and connection problems are retried; e.g. a
+// version that doesn't exist is not.
+func retryable(err error) bool {
+	if statusErr, ok := err.(*httpStatusError); ok {
+		return statusErr.statusCode >= 500
+	}
+	return true
+}
+
+// downloadFileWithRetry is like downloadFile, but tries up to
+// downloadAttempts times, with exponential backoff. The partial file of a
+// failed attempt is removed.
+func downloadFileWithRetry(url string, path string, progress func(downloaded, total int64)) (err error) {
+	delay := downloadRetryDelay
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		if err = downloadFile(url, path, progress); err == nil {
+			return nil
+		}
+		os.Remove(path)
+
+		if !retryable(err) {
+			return err
+		}
+		if attempt < downloadAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("%v (tried %d times)", err, downloadAttempts)
+}
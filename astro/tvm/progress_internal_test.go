@@ -0,0 +1,109 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryAndProgress(t *testing.T) {
+	defer func(delay time.Duration) { downloadRetryDelay = delay }(downloadRetryDelay)
+	downloadRetryDelay = time.Millisecond
+
+	zipFile := fakeTerraformZip(t)
+	sum := sha256.Sum256(zipFile)
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/0.11.7/terraform.zip":
+			// fails twice, then succeeds on the last attempt
+			if n < downloadAttempts {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write(zipFile)
+		case "/0.11.7/terraform_0.11.7_SHA256SUMS":
+			if n == 1 {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+				return
+			}
+			fmt.Fprintf(w, "%s  terraform_0.11.7_linux_amd64.zip\n", hex.EncodeToString(sum[:]))
+		case "/0.11.8/terraform.zip":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	type update struct {
+		version           string
+		downloaded, total int64
+	}
+	updates := []update{}
+	progress := func(version string, downloaded, total int64) {
+		updates = append(updates, update{version, downloaded, total})
+	}
+
+	repo, err := NewVersionRepo(tmpdir, "amd64", "linux", WithDownloadMirror(server.URL+"/{version}/terraform.zip"), WithDownloadProgress(progress))
+	require.NoError(t, err)
+
+	_, err = repo.Get("0.11.7")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		"/0.11.7/terraform.zip":               3,
+		"/0.11.7/terraform_0.11.7_SHA256SUMS": 2,
+	}, requests)
+
+	require.NotEmpty(t, updates)
+	size := int64(len(zipFile))
+	assert.Equal(t, update{"0.11.7", 0, size}, updates[0])
+	assert.Equal(t, update{"0.11.7", size, size}, updates[len(updates)-1])
+
+	// gives up after the last attempt
+	_, err = repo.Get("0.11.8")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server returned 503 Service Unavailable (tried 3 times)")
+
+	// missing versions aren't retried
+	_, err = repo.Get("0.11.9")
+	require.Error(t, err)
+	assert.Equal(t, 1, requests["/0.11.9/terraform.zip"])
+}
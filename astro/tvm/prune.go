@@ -0,0 +1,122 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// PruneOptions selects the versions that Prune deletes.
+type PruneOptions struct {
+	// Keep is a list of versions not to delete.
+	Keep []string
+
+	// LinkPath is the symlink to check, e.g. the `terraform` binary in
+	// PATH. The version it points at is always kept.
+	LinkPath string
+
+	// OlderThan only deletes versions whose binary hasn't been modified or
+	// accessed for this long. If 0, versions are deleted regardless of
+	// when they were last used.
+	OlderThan time.Duration
+
+	// DryRun returns the versions that would be deleted, without deleting
+	// them.
+	DryRun bool
+}
+
+// Prune deletes the versions in the repo that the options don't keep, and
+// returns the versions that were deleted, sorted.
+func (r *VersionRepo) Prune(options PruneOptions) ([]string, error) {
+	installed, err := r.List()
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	kept := map[string]bool{}
+	for _, version := range options.Keep {
+		kept[version] = true
+	}
+
+	candidates := []string{}
+	for version, path := range installed {
+		linked := r.linksTo(options.LinkPath, version) || (r.fallback != nil && r.fallback.linksTo(options.LinkPath, version))
+		if kept[version] || linked {
+			continue
+		}
+		if options.OlderThan > 0 {
+			lastUsed, err := LastUsed(path)
+			if err != nil {
+				return nil, err
+			}
+			if time.Since(lastUsed) < options.OlderThan {
+				continue
+			}
+		}
+		candidates = append(candidates, version)
+	}
+	sort.Strings(candidates)
+
+	if options.DryRun {
+		return candidates, nil
+	}
+
+	removed := []string{}
+	for _, version := range candidates {
+		if err := r.Remove(version, options.LinkPath, false); err != nil {
+			return removed, err
+		}
+		removed = append(removed, version)
+	}
+
+	return removed, nil
+}
+
+// LastUsed returns when the file at path was last modified or accessed,
+// whichever is later. Access times aren't updated on every read on all
+// file systems, but they are never earlier than the last use.
+func LastUsed(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if accessed := accessTime(info); accessed.After(info.ModTime()) {
+		return accessed, nil
+	}
+	return info.ModTime(), nil
+}
+
+// DiskUsage returns the total size, in bytes, of the files in the repo,
+// including the versions of all platforms and flavors.
+func (r *VersionRepo) DiskUsage() (int64, error) {
+	var size int64
+	err := filepath.Walk(r.repoPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
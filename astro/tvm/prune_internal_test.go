@@ -0,0 +1,74 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	binaryPath := filepath.Join(tmpdir, "terraform-artifact")
+	require.NoError(t, ioutil.WriteFile(binaryPath, []byte("#!/bin/sh\n"), 0755))
+
+	repo, err := NewVersionRepo(filepath.Join(tmpdir, "repo"), "amd64", "linux", WithOffline())
+	require.NoError(t, err)
+	for _, version := range []string{"0.11.7", "0.11.8", "0.12.6", "0.12.7"} {
+		require.NoError(t, repo.Install(version, binaryPath))
+	}
+
+	// 0.11.x were last used a month ago
+	monthAgo := time.Now().Add(-30 * 24 * time.Hour)
+	for _, version := range []string{"0.11.7", "0.11.8"} {
+		require.NoError(t, os.Chtimes(repo.terraformPath(version), monthAgo, monthAgo))
+	}
+
+	size, err := repo.DiskUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4*len("#!/bin/sh\n")), size)
+
+	// a dry run deletes nothing
+	removed, err := repo.Prune(PruneOptions{Keep: []string{"0.12.6"}, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.11.7", "0.11.8", "0.12.7"}, removed)
+	installed, err := repo.List()
+	require.NoError(t, err)
+	assert.Len(t, installed, 4)
+
+	// only versions unused for a week
+	removed, err = repo.Prune(PruneOptions{Keep: []string{"0.11.7"}, OlderThan: 7 * 24 * time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.11.8"}, removed)
+
+	installed, err = repo.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"0.11.7": repo.terraformPath("0.11.7"),
+		"0.12.6": repo.terraformPath("0.12.6"),
+		"0.12.7": repo.terraformPath("0.12.7"),
+	}, installed)
+}
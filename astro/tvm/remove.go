@@ -0,0 +1,76 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// linksTo returns whether linkPath is a symlink to the binary of the
+// specified version, like the ones created by Link.
+func (r *VersionRepo) linksTo(linkPath string, version string) bool {
+	if linkPath == "" {
+		return false
+	}
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(linkPath), target)
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(r.terraformPath(version))
+	if err != nil {
+		return false
+	}
+	return absTarget == absPath
+}
+
+// Remove deletes the specified version from the repo. linkPath is the
+// symlink to check, e.g. the `terraform` binary in PATH. If it points at the
+// version, Remove refuses to delete it, unless force is set, in which case
+// the symlink is deleted too, instead of being left dangling.
+func (r *VersionRepo) Remove(version string, linkPath string, force bool) error {
+	if !r.exists(version) && r.fallback != nil && r.fallback.exists(version) {
+		return r.fallback.Remove(version, linkPath, force)
+	}
+
+	lock := r.getLock(version)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !r.exists(version) {
+		return fmt.Errorf("%s %s is not installed", r.flavor, version)
+	}
+
+	if r.linksTo(linkPath, version) {
+		if !force {
+			return fmt.Errorf("%s %s is linked at %s; link another version first, or force its removal", r.flavor, version, linkPath)
+		}
+		if err := os.Remove(linkPath); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(r.dir(version))
+}
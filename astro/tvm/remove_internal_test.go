@@ -0,0 +1,81 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+func TestRemove(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	binaryPath := filepath.Join(tmpdir, "terraform-artifact")
+	require.NoError(t, ioutil.WriteFile(binaryPath, []byte("#!/bin/sh\n"), 0755))
+
+	repo, err := NewVersionRepo(filepath.Join(tmpdir, "repo"), "amd64", "linux", WithOffline())
+	require.NoError(t, err)
+	for _, version := range []string{"0.11.7", "0.11.8", "0.12.6", "0.12.7"} {
+		require.NoError(t, repo.Install(version, binaryPath))
+	}
+
+	linkPath := filepath.Join(tmpdir, "terraform")
+	require.NoError(t, repo.Link("0.11.8", linkPath, false))
+
+	err = repo.Remove("0.10.0", linkPath, false)
+	require.Error(t, err)
+	assert.Equal(t, "Terraform 0.10.0 is not installed", err.Error())
+
+	// the linked version is refused without force
+	err = repo.Remove("0.11.8", linkPath, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Terraform 0.11.8 is linked at "+linkPath)
+	assert.True(t, repo.exists("0.11.8"))
+
+	// other versions are removed
+	require.NoError(t, repo.Remove("0.12.6", linkPath, false))
+	assert.False(t, repo.exists("0.12.6"))
+
+	// the linked version is always kept by Prune
+	require.NoError(t, repo.Link("0.12.7", linkPath, true))
+	removed, err := repo.Prune(PruneOptions{Keep: []string{"0.11.7", "0.13.0"}, LinkPath: linkPath})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.11.8"}, removed)
+
+	versions, err := repo.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"0.11.7": repo.terraformPath("0.11.7"),
+		"0.12.7": repo.terraformPath("0.12.7"),
+	}, versions)
+
+	// with force, the link is removed rather than left dangling
+	require.NoError(t, repo.Remove("0.12.7", linkPath, true))
+	assert.False(t, repo.exists("0.12.7"))
+	_, err = os.Lstat(linkPath)
+	assert.True(t, os.IsNotExist(err))
+	assert.True(t, utils.FileExists(repo.terraformPath("0.11.7")))
+}
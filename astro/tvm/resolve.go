@@ -0,0 +1,149 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	version "github.com/burl/go-version"
+)
+
+// LatestVersion is the version spec that resolves to the newest release.
+const LatestVersion = "latest"
+
+// terraformIndexURL is the index of Terraform releases on the Hashicorp
+// website.
+var terraformIndexURL = "https://releases.hashicorp.com/terraform/index.json"
+
+// matches a partial Terraform version, e.g. "0.12" or "1"
+var rePartialVersion = regexp.MustCompile(`^\d+(\.\d+)?$`)
+
+// IsVersionSpec returns whether the string is a version that has to be
+// resolved with ResolveVersion, i.e. "latest" or a partial version, e.g.
+// "0.12".
+func IsVersionSpec(spec string) bool {
+	return spec == LatestVersion || rePartialVersion.MatchString(spec)
+}
+
+// ResolveVersion returns the newest release that matches the spec: any
+// release for "latest", or the releases that start with a partial version,
+// e.g. "0.12" matches 0.12.31 but not 0.13.0. Installed versions are
+// preferred; the index of releases on the Hashicorp website is only
+// consulted if none matches, and not in offline mode. Pre-releases never
+// match. Full versions are returned as they are.
+func (r *VersionRepo) ResolveVersion(spec string) (string, error) {
+	if !IsVersionSpec(spec) {
+		return spec, nil
+	}
+
+	installed, err := r.List()
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	versions := []string{}
+	for v := range installed {
+		versions = append(versions, v)
+	}
+	if v := newestMatchingVersion(spec, versions); v != "" {
+		return v, nil
+	}
+
+	if r.offline {
+		return "", fmt.Errorf("no installed %s version matches %q and offline mode is enabled", r.flavor, spec)
+	}
+
+	indexURL := r.flavor.indexURL()
+	if indexURL == "" {
+		return "", fmt.Errorf("no installed %s version matches %q; install one with tvm, as %s releases can't be listed", r.flavor, spec, r.flavor)
+	}
+	released, err := releasedVersions(indexURL)
+	if err != nil {
+		return "", fmt.Errorf("unable to list %s releases to resolve %q: %v", r.flavor, spec, err)
+	}
+	if v := newestMatchingVersion(spec, released); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("no %s release matches %q", r.flavor, spec)
+}
+
+// newestMatchingVersion returns the newest of the versions that match the
+// spec, or an empty string if none does.
+func newestMatchingVersion(spec string, versions []string) string {
+	prefix := strings.Split(spec, ".")
+
+	candidates := version.Collection{}
+	for _, s := range versions {
+		v, err := version.NewVersion(s)
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if spec != LatestVersion && !versionHasPrefix(v, prefix) {
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.Sort(candidates)
+	return candidates[len(candidates)-1].String()
+}
+
+// versionHasPrefix returns whether the leading segments of the version are
+// the ones in prefix.
+func versionHasPrefix(v *version.Version, prefix []string) bool {
+	segments := v.Segments()
+	for i, s := range prefix {
+		if i >= len(segments) || fmt.Sprint(segments[i]) != s {
+			return false
+		}
+	}
+	return true
+}
+
+// releasedVersions returns the versions in the index of releases at url.
+func releasedVersions(url string) ([]string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var index struct {
+		Versions map[string]json.RawMessage `json:"versions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("unable to parse release index: %v", err)
+	}
+
+	versions := []string{}
+	for v := range index.Versions {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
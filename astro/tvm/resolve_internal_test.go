@@ -0,0 +1,86 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "terraform", "versions": {"0.12.31": {}, "0.14.11": {}, "0.15.0-beta1": {}, "0.15.0-rc2": {}, "0.9.11": {}}}`))
+	}))
+	defer server.Close()
+
+	defer func(url string) { terraformIndexURL = url }(terraformIndexURL)
+	terraformIndexURL = server.URL
+
+	tmpdir, err := ioutil.TempDir("", "astro-tvm-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	binaryPath := filepath.Join(tmpdir, "terraform-artifact")
+	require.NoError(t, ioutil.WriteFile(binaryPath, []byte("#!/bin/sh\n"), 0755))
+
+	repo, err := NewVersionRepo(filepath.Join(tmpdir, "repo"), "amd64", "linux")
+	require.NoError(t, err)
+	for _, version := range []string{"0.11.7", "0.12.6", "0.12.29"} {
+		require.NoError(t, repo.Install(version, binaryPath))
+	}
+
+	for _, tt := range []struct {
+		spec     string
+		expected string
+	}{
+		// installed versions are preferred
+		{"latest", "0.12.29"},
+		{"0.12", "0.12.29"},
+		{"0", "0.12.29"},
+		{"0.11", "0.11.7"},
+		// then releases, without pre-releases
+		{"0.14", "0.14.11"},
+		{"0.9", "0.9.11"},
+		// full versions are left as they are
+		{"0.12.6", "0.12.6"},
+		{"0.10.8", "0.10.8"},
+	} {
+		v, err := repo.ResolveVersion(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.expected, v, tt.spec)
+	}
+
+	_, err = repo.ResolveVersion("0.15")
+	require.Error(t, err)
+	assert.Equal(t, `no Terraform release matches "0.15"`, err.Error())
+
+	offlineRepo, err := NewVersionRepo(filepath.Join(tmpdir, "repo"), "amd64", "linux", WithOffline())
+	require.NoError(t, err)
+	_, err = offlineRepo.ResolveVersion("0.14")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "offline mode is enabled")
+
+	assert.True(t, IsVersionSpec("0.12"))
+	assert.False(t, IsVersionSpec("0.12.6"))
+}
@@ -26,8 +26,21 @@ import (
 	"strings"
 )
 
+// httpStatusError is returned by downloadFile when the server doesn't
+// return the file.
+type httpStatusError struct {
+	status     string
+	statusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("server returned %s", e.status)
+}
+
 // downloadFile will download the specified file to the specified path.
-func downloadFile(url string, path string) error {
+// progress, if not nil, is called with the number of bytes downloaded so
+// far and the total, which is -1 if the server didn't send it.
+func downloadFile(url string, path string, progress func(downloaded, total int64)) error {
 	// Create file
 	out, err := os.Create(path)
 	if err != nil {
@@ -42,11 +55,24 @@ func downloadFile(url string, path string) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{status: resp.Status, statusCode: resp.StatusCode}
+	}
+
+	var w io.Writer = out
+	if progress != nil {
+		w = &progressWriter{w: out, total: resp.ContentLength, progress: progress}
+		progress(0, resp.ContentLength)
+	}
+
 	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
+	n, err := io.Copy(w, resp.Body)
 	if err != nil {
 		return err
 	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return fmt.Errorf("download was cut short: got %d of %d bytes", n, resp.ContentLength)
+	}
 
 	return nil
 }
@@ -19,7 +19,6 @@
 package tvm
 
 import (
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -29,6 +28,7 @@ import (
 	"runtime"
 	"sync"
 
+	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/utils"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -41,6 +41,10 @@ const terraformBinaryFile = "terraform"
 // files from the Hashicorp website.
 var terraformZipFileDownloadURL = "https://releases.hashicorp.com/terraform/%s/terraform_%s_%s_%s.zip"
 
+// terraformSHA256SumsDownloadURL is the path to download the SHA256 checksums
+// of the Terraform zip files of a version from the Hashicorp website.
+var terraformSHA256SumsDownloadURL = "https://releases.hashicorp.com/terraform/%s/terraform_%s_SHA256SUMS"
+
 // versionDirectoryFormat is a regexp that matches Terraform semver,
 // e.g. "1.2.30"
 var versionDirectoryFormat = regexp.MustCompile(`\d+\.\d+\.\d+`)
@@ -59,11 +63,67 @@ type VersionRepo struct {
 	// trigger the download and the rest will block until the download is
 	// complete.
 	locks *sync.Map
+
+	// skipChecksums disables the verification of downloads against the
+	// published SHA256 checksums
+	skipChecksums bool
+
+	// flavor is the distribution of Terraform the repo installs; see
+	// WithFlavor
+	flavor Flavor
+
+	// mirror is the URL template to download zip files from instead of the
+	// Hashicorp website; see WithDownloadMirror. mirrorSet is whether it
+	// was passed as an option, rather than taken from TVM_MIRROR_URL.
+	mirror    string
+	mirrorSet bool
+
+	// archSet is whether the architecture was passed with WithArch, which
+	// disables the fallback
+	archSet bool
+
+	// fallback is the repo of the architecture to use for versions that
+	// aren't released for arch; see fallbackArch
+	fallback *VersionRepo
+
+	// offline disables downloads; see WithOffline
+	offline bool
+
+	// progress is called as versions are downloaded; see
+	// WithDownloadProgress
+	progress DownloadProgress
+
+	// logger receives warnings, e.g. about fallbacks; see WithLogger
+	logger logger.Logger
+}
+
+// VersionRepoOption is an option for NewVersionRepo.
+type VersionRepoOption func(*VersionRepo)
+
+// WithoutChecksumVerification disables verifying downloaded zip files
+// against the SHA256SUMS file published with them, e.g. for mirrors that
+// don't publish checksums.
+func WithoutChecksumVerification() VersionRepoOption {
+	return func(r *VersionRepo) {
+		r.skipChecksums = true
+	}
+}
+
+// WithLogger makes the repo log to l instead of logger.Default().
+func WithLogger(l logger.Logger) VersionRepoOption {
+	return func(r *VersionRepo) {
+		r.logger = l
+	}
 }
 
 // NewVersionRepo creates a new VersionRepo. The arch will
-// be appended to the provided path for all downloaded binaries.
-func NewVersionRepo(repoPath string, arch string, platform string) (*VersionRepo, error) {
+// be appended to the provided path for all downloaded binaries. If
+// TVM_MIRROR_URL is set, it is used as the download mirror for Terraform
+// unless WithDownloadMirror is passed, and if TVM_OFFLINE is set to a true
+// value, offline mode is enabled. On darwin/arm64, Terraform versions that
+// weren't released for it fall back to darwin/amd64, unless WithArch is
+// passed.
+func NewVersionRepo(repoPath string, arch string, platform string, opts ...VersionRepoOption) (*VersionRepo, error) {
 	if repoPath == "" {
 		home, err := homedir.Dir()
 		if err != nil {
@@ -78,32 +138,53 @@ func NewVersionRepo(repoPath string, arch string, platform string) (*VersionRepo
 		return nil, err
 	}
 
-	return &VersionRepo{
+	r := &VersionRepo{
 		locks:    &sync.Map{},
 		repoPath: repoPath,
 		arch:     arch,
 		platform: platform,
-	}, nil
+		flavor:   FlavorTerraform,
+		offline:  offlineFromEnv(),
+		logger:   logger.Default(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := r.flavor.Validate(); err != nil {
+		return nil, err
+	}
+	if !r.mirrorSet && r.flavor == FlavorTerraform {
+		r.mirror = os.Getenv(MirrorURLEnvVar)
+	}
+	if fallbackArch := r.fallbackArch(); fallbackArch != "" {
+		fallback, err := NewVersionRepo(repoPath, arch, platform, append(opts[:len(opts):len(opts)], WithArch(fallbackArch))...)
+		if err != nil {
+			return nil, err
+		}
+		r.fallback = fallback
+	}
+
+	return r, nil
 }
 
 // NewVersionRepoForCurrentSystem returns a new VersionRepo instance
 // with platform and architecture information retrieve from the current
 // system.
-func NewVersionRepoForCurrentSystem(repoPath string) (*VersionRepo, error) {
-	return NewVersionRepo(repoPath, runtime.GOARCH, runtime.GOOS)
+func NewVersionRepoForCurrentSystem(repoPath string, opts ...VersionRepoOption) (*VersionRepo, error) {
+	return NewVersionRepo(repoPath, runtime.GOARCH, runtime.GOOS, opts...)
 }
 
 // dir returns the directory in the repository that contains the
 // specified version.
 func (r *VersionRepo) dir(version string) string {
-	return filepath.Join(r.repoPath, r.platform, r.arch, version)
+	return filepath.Join(r.flavor.repoDir(r.repoPath), r.platform, r.arch, version)
 }
 
-// download gets the Terraform binary from the Terraform website. It
-// returns the path to the downloaded file or an error if there was a
-// problem.
+// download gets the binary from the download site of the flavor, or the
+// mirror. It returns the path to the downloaded file or an error if there
+// was a problem.
 func (r *VersionRepo) download(version string) (string, error) {
-	url := fmt.Sprintf(terraformZipFileDownloadURL, version, version, r.platform, r.arch)
+	url := r.zipURL(version)
 
 	// Temporary directory for downloading Terraform and extracting the zip file
 	tmpDir, err := ioutil.TempDir("", "terraform")
@@ -114,9 +195,36 @@ func (r *VersionRepo) download(version string) (string, error) {
 
 	zipFilePath := path.Join(tmpDir, "terraform.zip")
 
-	// Download Terraform zip file
-	if err := downloadFile(url, zipFilePath); err != nil {
-		return "", err
+	var progress func(downloaded, total int64)
+	if r.progress != nil {
+		progress = func(downloaded, total int64) {
+			r.progress(version, downloaded, total)
+		}
+	}
+
+	// Download Terraform zip file. Versions that aren't released for the
+	// architecture are stored under the fallback one, so that Get finds
+	// them there next time without trying again.
+	if err := downloadFileWithRetry(url, zipFilePath, progress); err != nil {
+		if r.needsFallback(version, err) {
+			r.logger.Warningf("tvm: %s %s isn't released for %s/%s; using %s/%s, which runs under Rosetta", r.flavor, version, r.platform, r.arch, r.platform, r.fallback.arch)
+			return r.fallback.download(version)
+		}
+		return "", fmt.Errorf("unable to download %s %s from %s: %v", r.flavor, version, url, err)
+	}
+
+	// Verify it against the published checksums before extracting it
+	if !r.skipChecksums {
+		sumsURL := r.sha256SumsURL(version)
+		sumsFilePath := path.Join(tmpDir, "SHA256SUMS")
+		if err := downloadFileWithRetry(sumsURL, sumsFilePath, nil); err != nil {
+			os.Remove(zipFilePath)
+			return "", fmt.Errorf("unable to download checksums for %s %s from %s: %v", r.flavor, version, sumsURL, err)
+		}
+		if err := verifyChecksum(zipFilePath, r.zipFileName(version), sumsFilePath); err != nil {
+			os.Remove(zipFilePath)
+			return "", fmt.Errorf("unable to verify download of %s %s from %s: %v", r.flavor, version, url, err)
+		}
 	}
 
 	// Extract contents of zip file
@@ -124,11 +232,11 @@ func (r *VersionRepo) download(version string) (string, error) {
 		return "", err
 	}
 
-	terraformBinaryPath := path.Join(tmpDir, "terraform")
+	terraformBinaryPath := path.Join(tmpDir, r.flavor.BinaryName())
 
 	// Check the binary is there
 	if !utils.FileExists(terraformBinaryPath) {
-		return "", errors.New("Terraform binary missing from zip file")
+		return "", fmt.Errorf("%s binary missing from zip file", r.flavor)
 	}
 
 	targetDir := r.dir(version)
@@ -139,7 +247,7 @@ func (r *VersionRepo) download(version string) (string, error) {
 	}
 
 	// Move binary to repo path
-	if err := os.Rename(terraformBinaryPath, path.Join(targetDir, "terraform")); err != nil {
+	if err := os.Rename(terraformBinaryPath, r.terraformPath(version)); err != nil {
 		return "", err
 	}
 
@@ -162,7 +270,7 @@ func (r *VersionRepo) getLock(version string) *sync.Mutex {
 
 // Get takes a version and returns the path to the Terraform binary for
 // that version. If the binary doesn't exist, it will be downloaded from
-// the Terraform website automatically.
+// the Terraform website automatically, unless offline mode is enabled.
 func (r *VersionRepo) Get(version string) (string, error) {
 	lock := r.getLock(version)
 
@@ -173,6 +281,12 @@ func (r *VersionRepo) Get(version string) (string, error) {
 
 	path := r.terraformPath(version)
 	if !utils.FileExists(path) {
+		if r.fallback != nil && r.fallback.exists(version) {
+			return r.fallback.terraformPath(version), nil
+		}
+		if r.offline {
+			return "", fmt.Errorf("%s %s is not installed and offline mode is enabled", r.flavor, version)
+		}
 		return r.download(version)
 	}
 	return path, nil
@@ -203,6 +317,9 @@ func (r *VersionRepo) List() (map[string]string, error) {
 	repoBaseDir := r.dir("")
 	f, err := os.Open(repoBaseDir)
 	defer f.Close()
+	if os.IsNotExist(err) && r.fallback != nil {
+		return r.fallback.List()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -219,11 +336,24 @@ func (r *VersionRepo) List() (map[string]string, error) {
 		}
 	}
 
+	// Versions installed for the fallback architecture are used by Get too
+	if r.fallback != nil {
+		fallbackDirs, err := r.fallback.List()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for terraformVersion, path := range fallbackDirs {
+			if _, ok := dirs[terraformVersion]; !ok {
+				dirs[terraformVersion] = path
+			}
+		}
+	}
+
 	return dirs, nil
 }
 
 // terraformPath returns the path to the Terraform binary file with the
-// specified version.
+// specified version, which is named after the flavor's binary.
 func (r *VersionRepo) terraformPath(version string) string {
-	return filepath.Join(r.dir(version), terraformBinaryFile)
+	return filepath.Join(r.dir(version), r.flavor.BinaryName())
 }
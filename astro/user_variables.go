@@ -22,6 +22,18 @@ type UserVariables struct {
 	Values map[string]string
 	// Filters is the subset of values that act as module filters, as described by the README
 	Filters map[string]bool
+	// Sources is where each value came from, e.g. the flag "--region" or
+	// ASTRO_FLAGS. Values without a source are from the flag with the name
+	// of the variable.
+	Sources map[string]VariableSource
+}
+
+// VariableSource is where the value of a variable came from.
+type VariableSource struct {
+	// Kind is the kind of source, e.g. ValueFromFlag.
+	Kind string
+	// Name is the name of the source, e.g. "--region".
+	Name string
 }
 
 // NoUserVariables returns an empty UserVariables value, used by tests
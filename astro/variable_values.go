@@ -0,0 +1,196 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// valuesCommandTimeout is how long a values_command may run for.
+const valuesCommandTimeout = 30 * time.Second
+
+// variableValuesCacheFile is the name of the file in the session repo where
+// the output of values commands is cached for --offline-variables.
+const variableValuesCacheFile = "variable-values.json"
+
+// resolveVariableValues fills in the values of variables that have a
+// values_command, running each distinct command once. The values are cached
+// in the session repo; if offline is set, the cached values are used instead
+// of running the commands.
+func resolveVariableValues(config *conf.Project, workingDir string, offline bool, log logger.Logger) error {
+	cachePath := filepath.Join(config.SessionRepoDir, ".astro", variableValuesCacheFile)
+
+	cache := map[string][]string{}
+	if utils.FileExists(cachePath) {
+		b, err := ioutil.ReadFile(cachePath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &cache); err != nil {
+			return fmt.Errorf("unable to read cached variable values: %v: %v", cachePath, err)
+		}
+	}
+
+	// values from commands that were run during this load
+	results := map[string][]string{}
+
+	resolve := func(variable *conf.Variable, location string) error {
+		if variable.ValuesCommand == "" {
+			return nil
+		}
+		if len(variable.Values) > 0 {
+			return fmt.Errorf("variable %q of %s: values and values_command cannot both be set", variable.Name, location)
+		}
+
+		if values, ok := results[variable.ValuesCommand]; ok {
+			variable.Values = values
+			return nil
+		}
+
+		if offline {
+			values, ok := cache[variable.ValuesCommand]
+			if !ok {
+				return fmt.Errorf("variable %q of %s: no cached values for values_command; run once without --offline-variables", variable.Name, location)
+			}
+			variable.Values = values
+			return nil
+		}
+
+		values, err := runValuesCommand(variable.ValuesCommand, workingDir, log)
+		if err != nil {
+			return fmt.Errorf("variable %q of %s: unable to get values from values_command: %v; consider setting static values instead", variable.Name, location, err)
+		}
+
+		results[variable.ValuesCommand] = values
+		variable.Values = values
+		return nil
+	}
+
+	for i := range config.Variables {
+		if err := resolve(&config.Variables[i], "project"); err != nil {
+			return err
+		}
+	}
+	for i := range config.Modules {
+		for j := range config.Modules[i].Variables {
+			if err := resolve(&config.Modules[i].Variables[j], fmt.Sprintf("module %q", config.Modules[i].Name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	for command, values := range results {
+		cache[command] = values
+	}
+	return writeVariableValuesCache(config, cachePath, cache)
+}
+
+// runValuesCommand runs a values_command and returns the non-empty lines of
+// its output.
+func runValuesCommand(command, workingDir string, log logger.Logger) ([]string, error) {
+	log.Infof("astro: running values command: %v", command)
+
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), valuesCommandTimeout)
+	defer cancel()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = workingDir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %v", valuesCommandTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	values := []string{}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if value := strings.TrimSpace(scanner.Text()); value != "" {
+			values = append(values, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("command produced no values")
+	}
+
+	return values, nil
+}
+
+// writeVariableValuesCache writes the cached values to the session repo,
+// creating it if needed.
+func writeVariableValuesCache(config *conf.Project, cachePath string, cache map[string][]string) error {
+	dirMode := os.FileMode(conf.DefaultSessionDirMode)
+	if config.SessionDirMode != 0 {
+		dirMode = os.FileMode(config.SessionDirMode)
+	}
+	fileMode := os.FileMode(0666)
+	if config.SessionFileMode != 0 {
+		fileMode = os.FileMode(config.SessionFileMode)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), dirMode); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(cachePath, b, fileMode)
+}
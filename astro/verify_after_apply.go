@@ -0,0 +1,132 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// Verification is the outcome of the plan run after the apply of an
+// execution with ApplyExecutionParameters.VerifyAfterApply, which checks
+// that the apply left no changes, e.g. because of providers that are
+// eventually consistent or other writers of the same resources. Nothing is
+// rolled back when it fails.
+type Verification struct {
+	hasChanges bool
+	changes    string
+	err        error
+	duration   time.Duration
+}
+
+// Failed returns whether the plan still has changes, or couldn't be run.
+func (v *Verification) Failed() bool {
+	return v.err != nil || v.hasChanges
+}
+
+// Changes returns the changes that the plan still has, if any.
+func (v *Verification) Changes() string {
+	return v.changes
+}
+
+// Err returns why the plan couldn't be run, if it couldn't.
+func (v *Verification) Err() error {
+	return v.err
+}
+
+// Duration returns how long the plan took. It isn't part of the duration of
+// the result.
+func (v *Verification) Duration() time.Duration {
+	return v.duration
+}
+
+// verifyAfterApply returns whether the apply of the execution is verified:
+// as set with verify_after_apply in the configuration of its module, or
+// otherwise in the parameters.
+func verifyAfterApply(b *boundExecution, parameters ApplyExecutionParameters) bool {
+	if verify := b.ModuleConfig().VerifyAfterApply; verify != nil {
+		return *verify
+	}
+	return parameters.VerifyAfterApply
+}
+
+// verifyApply runs a plan in the session of an execution that was just
+// applied.
+func (s *Session) verifyApply(status chan<- string, b *boundExecution, session *terraform.Session) *Verification {
+	status <- fmt.Sprintf("[%s] Verifying apply...", b.ID())
+
+	started := time.Now()
+	result, err := s.retryOnLock(status, b, session.VerifyPlan)
+	verification := &Verification{
+		err:      err,
+		duration: time.Since(started),
+	}
+	if plan, ok := result.(*terraform.PlanResult); ok && err == nil && plan.HasChanges() {
+		verification.hasChanges = true
+		verification.changes = plan.Changes()
+	}
+
+	if verification.Failed() {
+		status <- fmt.Sprintf("[%s] WARNING: verification failed; the plan after apply has changes", b.ID())
+	}
+
+	return verification
+}
+
+// recordVerifications passes results through, and records the outcome of
+// the verified applies in the session manifest once all of them are done,
+// before the returned channel is closed.
+func (s *Session) recordVerifications(results <-chan *Result) <-chan *Result {
+	out := make(chan *Result, cap(results))
+
+	go func() {
+		defer close(out)
+
+		verifications := []ExecutionVerification{}
+		for result := range results {
+			if v := result.Verification(); v != nil {
+				verification := ExecutionVerification{
+					ID:       result.ID(),
+					Module:   result.Module(),
+					Verified: !v.Failed(),
+					Changes:  v.Changes(),
+					Seconds:  roundSeconds(v.Duration()),
+				}
+				if v.Err() != nil {
+					verification.Error = v.Err().Error()
+				}
+				verifications = append(verifications, verification)
+			}
+			out <- result
+		}
+
+		if len(verifications) == 0 {
+			return
+		}
+		err := s.updateManifest(func(manifest *Manifest) error {
+			manifest.Verifications = append(manifest.Verifications, verifications...)
+			return nil
+		})
+		if err != nil {
+			s.log().Errorf("astro: unable to record verifications in session %v: %v", s.id, err)
+		}
+	}()
+
+	return out
+}
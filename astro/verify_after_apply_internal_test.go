@@ -0,0 +1,111 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAfterApply(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+	// plans of modules with CONVERGED unset still have changes
+	terraformPath := filepath.Join(tmpdir, "terraform")
+	require.NoError(t, ioutil.WriteFile(terraformPath, []byte(`#!/bin/sh
+case "$1" in
+version)
+  echo "Terraform v0.12.6"
+  ;;
+plan)
+  touch "$(echo "$@" | sed -n 's/.*-out=\([^ ]*\).*/\1/p')"
+  if [ -z "$CONVERGED" ]; then
+    echo "  ~ aws_s3_bucket.state"
+    exit 2
+  fi
+  ;;
+esac
+`), 0755))
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  path: %s
+modules:
+  - name: app
+    path: .
+    local_state: ephemeral
+    env:
+      CONVERGED: "true"
+  - name: bucket
+    path: .
+    local_state: ephemeral
+  - name: eventual
+    path: .
+    local_state: ephemeral
+    verify_after_apply: false
+`, codeRoot, tmpdir, terraformPath)), 0644))
+
+	c, err := NewProjectFromConfigFile(configPath)
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		VerifyAfterApply:    true,
+	})
+	require.NoError(t, err)
+	results := testReadResults(resultChan)
+	for id, result := range results {
+		assert.NoError(t, result.Err(), id)
+	}
+
+	require.NotNil(t, results["app"].Verification())
+	assert.False(t, results["app"].Verification().Failed())
+
+	require.NotNil(t, results["bucket"].Verification())
+	assert.True(t, results["bucket"].Verification().Failed())
+	assert.NoError(t, results["bucket"].Verification().Err())
+	assert.Contains(t, results["bucket"].Verification().Changes(), "aws_s3_bucket.state")
+
+	assert.Nil(t, results["eventual"].Verification())
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	manifest, err := session.Manifest()
+	require.NoError(t, err)
+	verifications := map[string]ExecutionVerification{}
+	for _, verification := range manifest.Verifications {
+		verifications[verification.ID] = verification
+	}
+	assert.Len(t, verifications, 2)
+	assert.True(t, verifications["app"].Verified)
+	assert.False(t, verifications["bucket"].Verified)
+	assert.Contains(t, verifications["bucket"].Changes, "aws_s3_bucket.state")
+}
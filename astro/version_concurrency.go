@@ -0,0 +1,106 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
+
+	version "github.com/burl/go-version"
+	"golang.org/x/sync/semaphore"
+)
+
+// versionConcurrency limits how many executions initialize Terraform at
+// the same time, with a semaphore for each version constraint, or family,
+// in the version_concurrency configuration.
+type versionConcurrency struct {
+	limits map[string]int
+	logger logger.Logger
+
+	mu         sync.Mutex
+	semaphores map[string]*semaphore.Weighted
+}
+
+// newVersionConcurrency returns the semaphores for the limits, which map
+// version constraints to the number of concurrent initializations.
+func newVersionConcurrency(limits map[string]int, log logger.Logger) *versionConcurrency {
+	return &versionConcurrency{
+		limits:     limits,
+		logger:     log,
+		semaphores: map[string]*semaphore.Weighted{},
+	}
+}
+
+// family returns the constraint whose limit applies to the version and the
+// limit, or false if there is none. If several constraints match, the
+// lowest limit applies.
+func (c *versionConcurrency) family(v *version.Version) (string, int, bool) {
+	if v == nil {
+		return "", 0, false
+	}
+
+	constraints := []string{}
+	for constraint := range c.limits {
+		constraints = append(constraints, constraint)
+	}
+	sort.Strings(constraints)
+
+	family, limit := "", 0
+	for _, constraint := range constraints {
+		if !terraform.VersionMatches(v, constraint) {
+			continue
+		}
+		if family == "" || c.limits[constraint] < limit {
+			family, limit = constraint, c.limits[constraint]
+		}
+	}
+	return family, limit, family != ""
+}
+
+// acquire waits until the execution can initialize Terraform, sending a
+// status update if it has to wait, and returns a function that releases
+// its slot.
+func (c *versionConcurrency) acquire(ctx context.Context, status chan<- string, id string, v *version.Version) (func(), error) {
+	family, limit, ok := c.family(v)
+	if !ok {
+		return func() {}, nil
+	}
+
+	c.mu.Lock()
+	sem, exists := c.semaphores[family]
+	if !exists {
+		sem = semaphore.NewWeighted(int64(limit))
+		c.semaphores[family] = sem
+		c.logger.Debugf("astro: limiting Terraform init for versions %q to %d at a time", family, limit)
+	}
+	c.mu.Unlock()
+
+	if !sem.TryAcquire(1) {
+		status <- fmt.Sprintf("[%s] Waiting for other executions to initialize Terraform %v (version_concurrency %q: %d)...", id, v, family, limit)
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+	c.logger.Debugf("astro: [%s] initializing Terraform %v under version_concurrency %q: %d", id, v, family, limit)
+
+	return func() { sem.Release(1) }, nil
+}
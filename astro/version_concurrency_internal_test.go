@@ -0,0 +1,101 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+
+	version "github.com/burl/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionConcurrencyFamily(t *testing.T) {
+	c := newVersionConcurrency(map[string]int{"< 0.13": 1, ">= 0.13": 10, "< 0.11": 2}, logger.Discard)
+
+	tests := []struct {
+		version string
+		family  string
+		limit   int
+	}{
+		{"0.10.8", "< 0.13", 1},
+		{"0.12.6", "< 0.13", 1},
+		{"0.13.0", ">= 0.13", 10},
+	}
+	for _, tt := range tests {
+		family, limit, ok := c.family(version.Must(version.NewVersion(tt.version)))
+		assert.True(t, ok, tt.version)
+		assert.Equal(t, tt.family, family, tt.version)
+		assert.Equal(t, tt.limit, limit, tt.version)
+	}
+
+	_, _, ok := c.family(nil)
+	assert.False(t, ok)
+
+	defaults := newVersionConcurrency((&conf.Project{}).VersionConcurrencyLimits(), logger.Discard)
+	_, _, ok = defaults.family(version.Must(version.NewVersion("1.5.7")))
+	assert.False(t, ok)
+}
+
+func TestVersionConcurrencyAcquire(t *testing.T) {
+	c := newVersionConcurrency(map[string]int{"< 0.13": 1}, logger.Discard)
+	v := version.Must(version.NewVersion("0.12.6"))
+	status := make(chan string, 10)
+
+	release, err := c.acquire(context.Background(), status, "app", v)
+	require.NoError(t, err)
+	assert.Empty(t, status)
+
+	acquired := make(chan struct{})
+	go func() {
+		release, err := c.acquire(context.Background(), status, "network", v)
+		require.NoError(t, err)
+		close(acquired)
+		release()
+	}()
+
+	assert.Equal(t, `[network] Waiting for other executions to initialize Terraform 0.12.6 (version_concurrency "< 0.13": 1)...`, <-status)
+	select {
+	case <-acquired:
+		t.Fatal("acquired a slot over the limit")
+	default:
+	}
+
+	release()
+	<-acquired
+
+	// versions without a limit don't wait
+	release, err = c.acquire(context.Background(), status, "app", version.Must(version.NewVersion("0.13.0")))
+	require.NoError(t, err)
+	release()
+	assert.Empty(t, status)
+}
+
+func TestVersionConcurrencyValidation(t *testing.T) {
+	project := &conf.Project{
+		TerraformDefaults:  conf.Terraform{Version: version.Must(version.NewVersion("0.12.6"))},
+		VersionConcurrency: map[string]int{"<< 0.13": 1, ">= 0.13": 0},
+	}
+	err := project.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid version constraint "<< 0.13"`)
+	assert.Contains(t, err.Error(), `limit for ">= 0.13" must be at least 1`)
+}
@@ -0,0 +1,156 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/tvm"
+
+	version "github.com/burl/go-version"
+)
+
+// installedTerraformVersions returns the versions of the flavor that tvm has
+// already downloaded.
+func installedTerraformVersions(flavor tvm.Flavor) ([]string, error) {
+	repo, err := tvm.NewVersionRepoForCurrentSystem("", tvm.WithFlavor(flavor))
+	if err != nil {
+		return nil, err
+	}
+	installed, err := repo.List()
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for v := range installed {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// resolveRequiredVersion returns the newest of the installed versions that
+// meets the constraint. If none does, a constraint that allows only one
+// version, e.g. "0.11.7" or "= 0.11.7", resolves to that version, which tvm
+// downloads when it is used.
+func resolveRequiredVersion(constraint string, installed []string) (*version.Version, error) {
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := version.Collection{}
+	for _, s := range installed {
+		v, err := version.NewVersion(s)
+		if err != nil {
+			continue
+		}
+		if constraints.Check(v) {
+			candidates = append(candidates, v)
+		}
+	}
+	if len(candidates) > 0 {
+		sort.Sort(candidates)
+		return candidates[len(candidates)-1], nil
+	}
+
+	if v, err := version.NewVersion(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(constraint), "="))); err == nil {
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("no installed Terraform version meets %q; install one with tvm, or set terraform.version", constraint)
+}
+
+// setTerraformVersionsFromCode sets the Terraform version of modules with
+// version_from_code to one that meets the required_version in their code.
+// A version set in the configuration is kept, but must meet it. pathFromEnv
+// is the Terraform path found in PATH, if any, which the resolved version
+// takes precedence over.
+func setTerraformVersionsFromCode(config *conf.Project, pathFromEnv string, listInstalled func(tvm.Flavor) ([]string, error), log logger.Logger) error {
+	installedByFlavor := map[tvm.Flavor][]string{}
+
+	for i := range config.Modules {
+		moduleConf := &config.Modules[i]
+		if !moduleConf.Terraform.VersionFromCode {
+			continue
+		}
+
+		constraint, err := terraform.RequiredVersion(filepath.Join(moduleConf.TerraformCodeRoot, moduleConf.Path))
+		if err != nil {
+			return fmt.Errorf("module %v: unable to read required_version: %v", moduleConf.Name, err)
+		}
+		if constraint == "" {
+			log.Debugf("config: module %v has no required_version in its code", moduleConf.Name)
+			if moduleConf.Terraform.Path == "" && moduleConf.Terraform.Version == nil {
+				if err := moduleConf.Terraform.SetDefaultPath(); err != nil {
+					return fmt.Errorf("module %v: no required_version in its code and %v", moduleConf.Name, err)
+				}
+			}
+			continue
+		}
+		constraints, err := version.NewConstraint(constraint)
+		if err != nil {
+			return fmt.Errorf("module %v: invalid required_version %q: %v", moduleConf.Name, constraint, err)
+		}
+
+		if moduleConf.Terraform.Path != "" && moduleConf.Terraform.Path == pathFromEnv {
+			moduleConf.Terraform.Path = ""
+		}
+
+		// A configured binary is used as is, so its version must meet the
+		// requirement too.
+		if moduleConf.Terraform.Path != "" && moduleConf.Terraform.Version == nil {
+			if err := moduleConf.Terraform.SetVersionFromBinary(); err != nil {
+				return fmt.Errorf("module %v: %v", moduleConf.Name, err)
+			}
+		}
+
+		if moduleConf.Terraform.Version != nil {
+			if !constraints.Check(moduleConf.Terraform.Version) {
+				return fmt.Errorf("module %v: Terraform version %v conflicts with required_version %q in its code", moduleConf.Name, moduleConf.Terraform.Version, constraint)
+			}
+			continue
+		}
+
+		flavor := moduleConf.Terraform.TVMFlavor()
+		installed, listed := installedByFlavor[flavor]
+		if !listed {
+			if installed, err = listInstalled(flavor); err != nil {
+				return fmt.Errorf("unable to list installed %s versions: %v", flavor, err)
+			}
+			installedByFlavor[flavor] = installed
+		}
+
+		v, err := resolveRequiredVersion(constraint, installed)
+		if err != nil {
+			return fmt.Errorf("module %v: %v", moduleConf.Name, err)
+		}
+		log.Debugf("config: module %v requires Terraform %q; using %v", moduleConf.Name, constraint, v)
+		moduleConf.Terraform.Version = v
+	}
+
+	return nil
+}
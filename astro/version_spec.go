@@ -0,0 +1,93 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/tvm"
+
+	version "github.com/burl/go-version"
+)
+
+// tvmVersionResolver returns a function that resolves version specs with
+// tvm, set up like the project's, so that offline mode is respected. Specs
+// are only resolved once per flavor.
+func tvmVersionResolver(config *conf.Project) func(tvm.Flavor, string) (string, error) {
+	resolved := map[string]string{}
+
+	return func(flavor tvm.Flavor, spec string) (string, error) {
+		key := string(flavor) + " " + spec
+		if v, ok := resolved[key]; ok {
+			return v, nil
+		}
+
+		opts := []tvm.VersionRepoOption{tvm.WithFlavor(flavor)}
+		if config.TerraformDefaults.Offline {
+			opts = append(opts, tvm.WithOffline())
+		}
+		repo, err := tvm.NewVersionRepoForCurrentSystem("", opts...)
+		if err != nil {
+			return "", err
+		}
+		v, err := repo.ResolveVersion(spec)
+		if err != nil {
+			return "", err
+		}
+
+		resolved[key] = v
+		return v, nil
+	}
+}
+
+// resolveTerraformVersionSpecs sets the Terraform version of the project
+// and its modules to the release their version spec, e.g. "latest",
+// resolves to, so that the rest of astro only sees full versions.
+func resolveTerraformVersionSpecs(config *conf.Project, resolve func(tvm.Flavor, string) (string, error), log logger.Logger) error {
+	if err := resolveTerraformVersionSpec(&config.TerraformDefaults, resolve, "project", log); err != nil {
+		return err
+	}
+	for i := range config.Modules {
+		if err := resolveTerraformVersionSpec(&config.Modules[i].Terraform, resolve, "module "+config.Modules[i].Name, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveTerraformVersionSpec sets the version of the Terraform
+// configuration from its version spec, if it has one.
+func resolveTerraformVersionSpec(terraformConf *conf.Terraform, resolve func(tvm.Flavor, string) (string, error), location string, log logger.Logger) error {
+	if terraformConf.Version != nil || terraformConf.VersionSpec == "" {
+		return nil
+	}
+
+	resolved, err := resolve(terraformConf.TVMFlavor(), terraformConf.VersionSpec)
+	if err != nil {
+		return fmt.Errorf("%v: unable to resolve Terraform version %q: %v", location, terraformConf.VersionSpec, err)
+	}
+	v, err := version.NewVersion(resolved)
+	if err != nil {
+		return fmt.Errorf("%v: Terraform version %q resolved to invalid version %q: %v", location, terraformConf.VersionSpec, resolved, err)
+	}
+
+	log.Debugf("config: %v: resolved Terraform version %q to %v", location, terraformConf.VersionSpec, v)
+	terraformConf.Version = v
+	return nil
+}
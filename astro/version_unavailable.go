@@ -0,0 +1,73 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/uber/astro/astro/tvm"
+)
+
+// versionUnavailableError is the error of an execution whose Terraform
+// binary couldn't be fetched, e.g. because its download URL returns a 404.
+type versionUnavailableError struct {
+	flavor  tvm.Flavor
+	version string
+	err     error
+}
+
+func (e *versionUnavailableError) Error() string {
+	return fmt.Sprintf("unable to activate %s %v: %v", e.flavor, e.version, e.err)
+}
+
+// unavailableVersions remembers the Terraform versions that couldn't be
+// fetched, so that each is only tried once per run: the other executions
+// that need it fail straight away, and the rest of the run carries on.
+type unavailableVersions struct {
+	mu     sync.Mutex
+	errors map[string]*versionUnavailableError
+}
+
+// terraformBinary returns the path to the binary of the version of the
+// flavor, or a versionUnavailableError if it can't be fetched.
+func (project *Project) terraformBinary(flavor tvm.Flavor, version string) (string, error) {
+	key := fmt.Sprintf("%s %s", flavor, version)
+
+	u := &project.unavailableVersions
+	u.mu.Lock()
+	err, ok := u.errors[key]
+	u.mu.Unlock()
+	if ok {
+		return "", err
+	}
+
+	path, getErr := project.versionResolver(flavor).Get(version)
+	if getErr == nil {
+		return path, nil
+	}
+
+	err = &versionUnavailableError{flavor: flavor, version: version, err: getErr}
+	u.mu.Lock()
+	if u.errors == nil {
+		u.errors = map[string]*versionUnavailableError{}
+	}
+	u.errors[key] = err
+	u.mu.Unlock()
+
+	return "", err
+}
@@ -0,0 +1,123 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/uber/astro/astro/tests/mockterraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionUnavailable(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	codeRoot := filepath.Join(tmpdir, "code")
+	require.NoError(t, os.MkdirAll(codeRoot, 0755))
+
+	specPath := filepath.Join(tmpdir, "spec.yaml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte("version: 0.12.6\n"), 0644))
+	terraformPath := mockterraform.InstallForTest(t, filepath.Join(tmpdir, "bin"), specPath)
+
+	// 0.11.7 can't be downloaded
+	var mu sync.Mutex
+	requests := map[string]int{}
+	resolver := versionResolverFunc(func(version string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[version]++
+		if version == "0.11.7" {
+			return "", errors.New("404 Not Found")
+		}
+		return terraformPath, nil
+	})
+
+	configPath := filepath.Join(tmpdir, "astro.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+terraform_code_root: %s
+session_repo_dir: %s
+terraform:
+  version: 0.12.6
+modules:
+  - name: legacy
+    path: .
+    local_state: ephemeral
+    terraform:
+      version: 0.11.7
+    variables:
+      - name: region
+        values: [east, west]
+  - name: app
+    path: .
+    local_state: ephemeral
+    deps:
+      - module: legacy
+        variables:
+          region: east
+  - name: network
+    path: .
+    local_state: ephemeral
+`, codeRoot, tmpdir)), 0644))
+
+	c, err := NewProjectFromConfigFile(configPath, WithTerraformVersionResolver(resolver))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{ExecutionParameters: NoExecutionParameters()})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+
+	// the executions that need the version fail, the one that depends on
+	// them is skipped, and the rest of the run goes ahead
+	require.Len(t, results, 4)
+	for _, id := range []string{"legacy-east", "legacy-west"} {
+		require.Contains(t, results, id)
+		assert.True(t, results[id].VersionUnavailable(), id)
+		assert.EqualError(t, results[id].Err(), "unable to activate Terraform 0.11.7: 404 Not Found", id)
+	}
+	require.Contains(t, results, "app")
+	assert.True(t, results["app"].Skipped())
+	assert.Equal(t, &SkippedError{FailedDependency: "legacy-east"}, results["app"].Err())
+	require.Contains(t, results, "network")
+	assert.NoError(t, results["network"].Err())
+	assert.False(t, results["network"].VersionUnavailable())
+
+	// the version is only tried once
+	assert.Equal(t, 1, requests["0.11.7"])
+
+	// the skipped execution is recorded in the session manifest
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	manifest, err := session.Manifest()
+	require.NoError(t, err)
+	assert.Equal(t, []ExecutionSkip{{
+		ID:               "app",
+		Module:           "app",
+		Reason:           "skipped: dependency legacy-east failed",
+		FailedDependency: "legacy-east",
+	}}, manifest.Skipped)
+}